@@ -1,13 +1,20 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type DBConfig struct {
@@ -17,10 +24,142 @@ type DBConfig struct {
 	Password string
 	Name     string
 	Driver   string
+	// ConnectRetries is how many times the startup ping is retried before the server gives up, ConnectBackoff the
+	// wait before the first retry, doubled for each next one.
+	ConnectRetries int
+	ConnectBackoff time.Duration
 }
 
+// ApiConfig is where the server listens. ApiHost empty binds every interface, the server speaks HTTPS when
+// TLSCertFile and TLSKeyFile are set and plain HTTP otherwise. TLSRedirectAddr is a plain HTTP address, such as
+// ":80", that redirects every request to HTTPS, empty does not listen on it.
 type ApiConfig struct {
-	ApiPort string
+	ApiHost         string
+	ApiPort         string
+	ApiBasePath     string
+	ApiV2BasePath   string
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSRedirectAddr string
+	// TrustedProxies are the IPs and CIDRs of the proxies whose X-Forwarded-For and X-Real-IP are believed. A request
+	// from anywhere else is the client IP it came from, so a client cannot pick another IP for the login lockout,
+	// the rate limits and the audit trail. Empty trusts none.
+	TrustedProxies []string
+}
+
+// Address is the host:port the server listens on, IPv6 hosts are bracketed.
+func (a ApiConfig) Address() string {
+	return net.JoinHostPort(a.ApiHost, a.ApiPort)
+}
+
+func (a ApiConfig) TLSEnabled() bool {
+	return a.TLSCertFile != ""
+}
+
+type RateLimitConfig struct {
+	PublicRateLimit int
+	// RequestRateLimit is the number of requests per minute of every user, or client IP without a token. The
+	// expensive routes also have a limit of their own in their handler. Zero disables it.
+	RequestRateLimit int
+	// UserRateLimit is the number of mutating requests per minute of an authenticated user, UserRateBurst how many
+	// of them can come at once. Zero disables the per-user limit.
+	UserRateLimit int
+	UserRateBurst int
+	// MaxConcurrentRequests is how many requests are served at once before the others get 503, zero is no limit.
+	MaxConcurrentRequests int
+}
+
+type BodyLimitConfig struct {
+	MaxBodyBytes int64
+	// UploadMaxBodyBytes is the limit of the routes in UploadRoutes, files take more than the JSON bodies.
+	UploadMaxBodyBytes int64
+}
+
+// HTTPTimeoutConfig bounds how long a client can hold a connection of the API listener, so slow clients cannot
+// tie them all up. Zero leaves a timeout out.
+type HTTPTimeoutConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+}
+
+type LogConfig struct {
+	// LogOutput is "stdout", "stderr" or the path of a log file.
+	LogOutput string
+	// AccessLogEnabled writes a line for every request, ACCESS_LOG_ENABLED=false leaves only the application logs.
+	AccessLogEnabled bool
+	// AccessLogSkipPaths are the paths left out of the access log, the probes and the scrape hit them every few
+	// seconds.
+	AccessLogSkipPaths []string
+}
+
+type GzipConfig struct {
+	GzipEnabled bool
+	GzipMinSize int
+}
+
+type MetricsConfig struct {
+	// MetricsAddr is a separate host:port for /metrics so it is not exposed with the API, empty serves it on the API
+	// listener.
+	MetricsAddr string
+}
+
+type DocsConfig struct {
+	// DocsEnabled serves the OpenAPI document and the Swagger UI, production can turn them off with DOCS_ENABLED=false.
+	DocsEnabled bool
+}
+
+type ValidationConfig struct {
+	// StrictJSON rejects request bodies with fields the payload does not have, STRICT_JSON=true turns it on.
+	StrictJSON bool
+}
+
+type LoginThrottleConfig struct {
+	// LoginMaxFailures failed logins within LoginFailureWindow lock the username or client IP, zero disables it.
+	LoginMaxFailures   int
+	LoginFailureWindow time.Duration
+}
+
+type PasswordPolicyConfig struct {
+	PasswordMinLength     int
+	PasswordRequireLetter bool
+	PasswordRequireDigit  bool
+	// PasswordBlocklist are refused on top of the built-in list of common passwords.
+	PasswordBlocklist []string
+}
+
+type PasswordHashConfig struct {
+	// PasswordHashAlgorithm is bcrypt or argon2id, stored hashes of the other one are upgraded on the next login.
+	PasswordHashAlgorithm string
+	// BcryptCost must be within bcrypt.MinCost and bcrypt.MaxCost, each step doubles the time to hash.
+	BcryptCost int
+	Argon2Time uint32
+	// Argon2Memory is in KiB.
+	Argon2Memory  uint32
+	Argon2Threads uint8
+}
+
+type SupplierConfig struct {
+	PriceListURL   string
+	SupplierId     string
+	RequestTimeout time.Duration
+	SyncInterval   time.Duration
+}
+
+type ReadinessConfig struct {
+	// ProviderHealthURL and SmsHealthURL are only checked when set, the provider one defaults to the price list.
+	ProviderHealthURL string
+	SmsHealthURL      string
+	ReadinessTimeout  time.Duration
+	// ReadinessCacheTTL is how long a readiness result is answered without checking again, zero always checks.
+	ReadinessCacheTTL time.Duration
+	// CriticalDependencies are the dependencies that make /ready answer 503 when down, the others are only reported.
+	CriticalDependencies []string
+	// ShutdownDrainDelay is how long readiness answers 503 on SIGTERM before the server stops accepting
+	// connections, ShutdownTimeout how long the open requests then get to finish.
+	ShutdownDrainDelay time.Duration
+	ShutdownTimeout    time.Duration
 }
 
 type TokenConfig struct {
@@ -28,58 +167,334 @@ type TokenConfig struct {
 	JwtSignatureKy   []byte `json:"JwtSignatureKy"`
 	JwtSigningMethod *jwt.SigningMethodHMAC
 	JwtExpiresTime   time.Duration
+	PasswordResetTTL time.Duration
+	RefreshTokenTTL  time.Duration
+	// JwtExpiresTimeRemember is the access token lifetime of a login with remember me, RefreshTokenTTLRemember is
+	// RefreshTokenTTL scaled by the same factor.
+	JwtExpiresTimeRemember  time.Duration
+	RefreshTokenTTLRemember time.Duration
+	// UserStatusCacheTTL is how long a token of a deactivated user can still be used, zero checks every request.
+	UserStatusCacheTTL time.Duration
+	// TwoFactorIssuer is the account name authenticator apps show next to the codes.
+	TwoFactorIssuer string
 }
 
 type Config struct {
 	DBConfig
 	ApiConfig
+	GzipConfig
+	MetricsConfig
+	DocsConfig
+	ValidationConfig
+	LogConfig
+	BodyLimitConfig
+	HTTPTimeoutConfig
+	RateLimitConfig
+	LoginThrottleConfig
+	PasswordPolicyConfig
+	PasswordHashConfig
+	SupplierConfig
+	ReadinessConfig
 	TokenConfig
+
+	effective map[string]string
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
+// Effective is every setting the config was read from by its variable name, after the config file, the environment
+// and the flags were applied, with the secrets redacted. It is logged at startup.
+func (c *Config) Effective() map[string]string {
+	return c.effective
 }
 
-func (c *Config) readConfig() error {
-	err := godotenv.Load()
+func (c *Config) readConfig(args []string) error {
+	// a container or a service manager passes the environment itself, the .env file is only needed without it. A
+	// required variable that neither sets is reported by name below.
+	envErr := godotenv.Load()
+	settings, err := newConfigReader(args)
 	if err != nil {
-		return fmt.Errorf("missing env file %v", err.Error())
+		return err
+	}
+	if envErr != nil && !errors.Is(envErr, fs.ErrNotExist) {
+		settings.add("read .env: %v", envErr)
 	}
 	c.DBConfig = DBConfig{
-		Host:     getEnv("DB_HOST", "167.172.91.111"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", "rahasia"),
-		Name:     getEnv("DB_NAME", "server_pulsa_db"),
-		Driver:   getEnv("DB_DRIVER", "postgres"),
+		Host:     settings.required("DB_HOST"),
+		Port:     settings.required("DB_PORT"),
+		User:     settings.required("DB_USER"),
+		Password: settings.get("DB_PASSWORD", ""),
+		Name:     settings.required("DB_NAME"),
+		Driver:   settings.required("DB_DRIVER"),
+		// seconds, a database that starts with the server gets 1+2+4+8+16 of them to accept connections
+		ConnectRetries: settings.int("DB_CONNECT_RETRIES", "5"),
+		ConnectBackoff: time.Duration(settings.int("DB_CONNECT_BACKOFF", "1")) * time.Second,
+	}
+
+	c.ApiConfig = ApiConfig{
+		ApiHost:         strings.TrimSpace(settings.get("API_HOST", "")),
+		ApiPort:         settings.required("API_PORT"),
+		ApiBasePath:     normalizeBasePath(settings.get("API_BASE_PATH", ApiGroup)),
+		ApiV2BasePath:   normalizeBasePath(settings.get("API_V2_BASE_PATH", ApiGroupV2)),
+		TLSCertFile:     settings.get("TLS_CERT_FILE", ""),
+		TLSKeyFile:      settings.get("TLS_KEY_FILE", ""),
+		TLSRedirectAddr: strings.TrimSpace(settings.get("TLS_REDIRECT_ADDR", "")),
+	}
+	for _, proxy := range strings.Split(settings.get("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			c.TrustedProxies = append(c.TrustedProxies, proxy)
+		}
+	}
+
+	c.MetricsConfig = MetricsConfig{MetricsAddr: strings.TrimSpace(settings.get("METRICS_ADDR", ""))}
+
+	gzipEnabled := settings.bool("GZIP_ENABLED", "true")
+	gzipMinSize := settings.int("GZIP_MIN_SIZE", "1024")
+	c.GzipConfig = GzipConfig{
+		GzipEnabled: gzipEnabled,
+		GzipMinSize: gzipMinSize,
+	}
+
+	docsEnabled := settings.bool("DOCS_ENABLED", "true")
+	c.DocsConfig = DocsConfig{DocsEnabled: docsEnabled}
+
+	strictJSON := settings.bool("STRICT_JSON", "false")
+	c.ValidationConfig = ValidationConfig{StrictJSON: strictJSON}
+
+	var accessLogSkipPaths []string
+	for _, path := range strings.Split(settings.get("ACCESS_LOG_SKIP_PATHS", "/healthz,/livez,/readyz,/ready,/metrics"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			accessLogSkipPaths = append(accessLogSkipPaths, path)
+		}
+	}
+	accessLogEnabled := settings.bool("ACCESS_LOG_ENABLED", "true")
+	c.LogConfig = LogConfig{
+		LogOutput:          settings.get("LOG_OUTPUT", "server-pulsa-app.log"),
+		AccessLogEnabled:   accessLogEnabled,
+		AccessLogSkipPaths: accessLogSkipPaths,
+	}
+
+	maxBodyBytes := settings.int64("MAX_BODY_BYTES", "1048576")
+	uploadMaxBodyBytes := settings.int64("UPLOAD_MAX_BODY_BYTES", "10485760")
+	c.BodyLimitConfig = BodyLimitConfig{
+		MaxBodyBytes:       maxBodyBytes,
+		UploadMaxBodyBytes: uploadMaxBodyBytes,
+	}
+
+	readHeaderTimeout := settings.int("HTTP_READ_HEADER_TIMEOUT", "10")
+	readTimeout := settings.int("HTTP_READ_TIMEOUT", "30")
+	writeTimeout := settings.int("HTTP_WRITE_TIMEOUT", "60")
+	idleTimeout := settings.int("HTTP_IDLE_TIMEOUT", "120")
+	c.HTTPTimeoutConfig = HTTPTimeoutConfig{
+		// seconds, the write timeout has to outlast the supplier calls some requests wait for
+		ReadHeaderTimeout: time.Duration(readHeaderTimeout) * time.Second,
+		ReadTimeout:       time.Duration(readTimeout) * time.Second,
+		WriteTimeout:      time.Duration(writeTimeout) * time.Second,
+		IdleTimeout:       time.Duration(idleTimeout) * time.Second,
+	}
+
+	publicRateLimit := settings.int("PUBLIC_RATE_LIMIT", "60")
+	requestRateLimit := settings.int("REQUEST_RATE_LIMIT", "300")
+	userRateLimit := settings.int("USER_RATE_LIMIT", "60")
+	userRateBurst := settings.int("USER_RATE_BURST", "10")
+	maxConcurrentRequests := settings.int("MAX_CONCURRENT_REQUESTS", "0")
+	c.RateLimitConfig = RateLimitConfig{
+		PublicRateLimit:       publicRateLimit,
+		RequestRateLimit:      requestRateLimit,
+		UserRateLimit:         userRateLimit,
+		UserRateBurst:         userRateBurst,
+		MaxConcurrentRequests: maxConcurrentRequests,
+	}
+
+	loginMaxFailures := settings.int("LOGIN_MAX_FAILURES", "5")
+	loginFailureWindow := settings.int("LOGIN_FAILURE_WINDOW", "15")
+	c.LoginThrottleConfig = LoginThrottleConfig{
+		LoginMaxFailures:   loginMaxFailures,
+		LoginFailureWindow: time.Duration(loginFailureWindow) * time.Minute,
+	}
+
+	passwordMinLength := settings.int("PASSWORD_MIN_LENGTH", "8")
+	passwordRequireLetter := settings.bool("PASSWORD_REQUIRE_LETTER", "true")
+	passwordRequireDigit := settings.bool("PASSWORD_REQUIRE_DIGIT", "true")
+	var passwordBlocklist []string
+	for _, password := range strings.Split(settings.get("PASSWORD_BLOCKLIST", ""), ",") {
+		if password = strings.TrimSpace(password); password != "" {
+			passwordBlocklist = append(passwordBlocklist, password)
+		}
+	}
+	c.PasswordPolicyConfig = PasswordPolicyConfig{
+		PasswordMinLength:     passwordMinLength,
+		PasswordRequireLetter: passwordRequireLetter,
+		PasswordRequireDigit:  passwordRequireDigit,
+		PasswordBlocklist:     passwordBlocklist,
+	}
+
+	bcryptCost := settings.int("BCRYPT_COST", strconv.Itoa(bcrypt.DefaultCost))
+	argon2Time := settings.uint("ARGON2_TIME", "1", 32)
+	argon2Memory := settings.uint("ARGON2_MEMORY", "65536", 32)
+	argon2Threads := settings.uint("ARGON2_THREADS", "4", 8)
+	c.PasswordHashConfig = PasswordHashConfig{
+		PasswordHashAlgorithm: settings.get("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+		BcryptCost:            bcryptCost,
+		Argon2Time:            uint32(argon2Time),
+		Argon2Memory:          uint32(argon2Memory),
+		Argon2Threads:         uint8(argon2Threads),
+	}
+
+	supplierTimeout := settings.int("SUPPLIER_REQUEST_TIMEOUT", "30")
+	supplierSyncInterval := settings.int("SUPPLIER_SYNC_INTERVAL", "0")
+	c.SupplierConfig = SupplierConfig{
+		PriceListURL:   settings.get("SUPPLIER_PRICE_LIST_URL", ""),
+		SupplierId:     settings.get("SUPPLIER_ID", ""),
+		RequestTimeout: time.Duration(supplierTimeout) * time.Second,
+		SyncInterval:   time.Duration(supplierSyncInterval) * time.Minute,
 	}
 
-	c.ApiConfig = ApiConfig{ApiPort: getEnv("API_PORT", "8080")}
+	readinessTimeout := settings.positiveInt("READY_TIMEOUT", "2")
+	readinessCacheTTL := settings.int("READY_CACHE_TTL", "1")
+	shutdownDrainDelay := settings.int("SHUTDOWN_DRAIN_DELAY", "5")
+	shutdownTimeout := settings.int("SHUTDOWN_TIMEOUT", "15")
+	var criticalDependencies []string
+	for _, dependency := range strings.Split(settings.get("READY_CRITICAL_DEPENDENCIES", "database,migrations"), ",") {
+		if dependency = strings.TrimSpace(dependency); dependency != "" {
+			criticalDependencies = append(criticalDependencies, dependency)
+		}
+	}
+	c.ReadinessConfig = ReadinessConfig{
+		ProviderHealthURL: settings.get("PROVIDER_HEALTH_URL", c.PriceListURL),
+		SmsHealthURL:      settings.get("SMS_HEALTH_URL", ""),
+		// seconds, a probe must answer before the load balancer gives up on it
+		ReadinessTimeout:     time.Duration(readinessTimeout) * time.Second,
+		ReadinessCacheTTL:    time.Duration(readinessCacheTTL) * time.Second,
+		CriticalDependencies: criticalDependencies,
+		// seconds too, the drain delay should outlast a few probe intervals of the load balancer
+		ShutdownDrainDelay: time.Duration(shutdownDrainDelay) * time.Second,
+		ShutdownTimeout:    time.Duration(shutdownTimeout) * time.Second,
+	}
 
-	tokenExpire, _ := strconv.Atoi(getEnv("TOKEN_EXPIRE", "120"))
+	tokenExpire := settings.positiveInt("TOKEN_EXPIRE", "120")
+	tokenExpireRemember := settings.int("TOKEN_EXPIRE_REMEMBER", "720")
+	passwordResetTTL := settings.int("PASSWORD_RESET_TTL", "30")
+	refreshTokenTTL := settings.int("REFRESH_TOKEN_TTL", "10080")
+	userStatusCacheTTL := settings.int("USER_STATUS_CACHE_TTL", "30")
+	// a remembered login keeps its refresh tokens longer by as much as its access tokens
+	refreshTokenTTLRemember := 0
+	if tokenExpire > 0 {
+		refreshTokenTTLRemember = refreshTokenTTL * tokenExpireRemember / tokenExpire
+	}
 	c.TokenConfig = TokenConfig{
-		IssuerName:       getEnv("TOKEN_ISSUE", "Enigma Camp Incubation Class"),
-		JwtSignatureKy:   []byte(getEnv("TOKEN_SECRET", "Golang Incubation Class")),
-		JwtSigningMethod: jwt.SigningMethodHS256,
-		JwtExpiresTime:   time.Duration(tokenExpire) * time.Minute,
+		IssuerName:              settings.required("TOKEN_ISSUE"),
+		JwtSignatureKy:          []byte(settings.secret("TOKEN_SECRET")),
+		JwtSigningMethod:        jwt.SigningMethodHS256,
+		JwtExpiresTime:          time.Duration(tokenExpire) * time.Minute,
+		PasswordResetTTL:        time.Duration(passwordResetTTL) * time.Minute,
+		RefreshTokenTTL:         time.Duration(refreshTokenTTL) * time.Minute,
+		JwtExpiresTimeRemember:  time.Duration(tokenExpireRemember) * time.Minute,
+		RefreshTokenTTLRemember: time.Duration(refreshTokenTTLRemember) * time.Minute,
+		// seconds, unlike the other token settings
+		UserStatusCacheTTL: time.Duration(userStatusCacheTTL) * time.Second,
+		TwoFactorIssuer:    settings.get("TOTP_ISSUER", "Server Pulsa"),
 	}
 
-	if c.Host == "" || c.Port == "" || c.User == "" || c.Name == "" || c.Driver == "" || c.ApiPort == "" ||
-		c.IssuerName == "" || c.JwtExpiresTime < 0 || len(c.JwtSignatureKy) == 0 {
-		return fmt.Errorf("missing required environment")
+	if c.JwtExpiresTimeRemember < c.JwtExpiresTime {
+		settings.add("TOKEN_EXPIRE_REMEMBER must not be shorter than TOKEN_EXPIRE")
 	}
 
+	if c.PasswordHashAlgorithm != "bcrypt" && c.PasswordHashAlgorithm != "argon2id" {
+		settings.add("PASSWORD_HASH_ALGORITHM must be bcrypt or argon2id")
+	}
+
+	settings.check(validateBcryptCost(c.BcryptCost))
+	settings.check(validateTLSFiles(c.TLSCertFile, c.TLSKeyFile, c.TLSRedirectAddr))
+	settings.check(validateSupplier(c.PriceListURL, c.SupplierId))
+	settings.check(validateTrustedProxies(c.TrustedProxies))
+	settings.check(validateReadiness(c.ReadinessConfig))
+
+	c.effective = settings.redacted()
+	return settings.err()
+}
+
+// validateBcryptCost refuses the costs bcrypt would silently replace with its default.
+func validateBcryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("BCRYPT_COST must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
 	return nil
+}
 
+// validateTLSFiles refuses a certificate without its key and the other way round, serving plain HTTP when only one
+// of them was configured would hide the mistake. A redirect to HTTPS needs the server to speak it.
+func validateTLSFiles(certFile, keyFile, redirectAddr string) error {
+	if (certFile == "") != (keyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if redirectAddr != "" && certFile == "" {
+		return fmt.Errorf("TLS_REDIRECT_ADDR needs TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	return nil
+}
+
+// validateTrustedProxies refuses what is neither an IP nor a CIDR, gin would only refuse it when the server starts.
+func validateTrustedProxies(proxies []string) error {
+	for _, proxy := range proxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("TRUSTED_PROXIES must be IPs or CIDRs, not %q", proxy)
+		}
+	}
+	return nil
+}
+
+// readinessDependencies are the names of the dependency checkers of the service package.
+var readinessDependencies = []string{"database", "migrations", "provider", "sms"}
+
+// validateReadiness refuses a critical dependency no checker has, a typo would otherwise leave the dependency
+// uncritical without a word.
+func validateReadiness(readiness ReadinessConfig) error {
+	if readiness.ReadinessCacheTTL < 0 {
+		return fmt.Errorf("READY_CACHE_TTL must not be negative")
+	}
+	for _, dependency := range readiness.CriticalDependencies {
+		if !slices.Contains(readinessDependencies, dependency) {
+			return fmt.Errorf("READY_CRITICAL_DEPENDENCIES must only name %s, not %q", strings.Join(readinessDependencies, ", "), dependency)
+		}
+	}
+	return nil
 }
 
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validateSupplier refuses a price list without the supplier the synced products are stored under, the id is the
+// uuid of a mst_supliyer row and Postgres would refuse anything else on the first new product.
+func validateSupplier(priceListURL, supplierId string) error {
+	if priceListURL == "" {
+		return nil
+	}
+	if supplierId == "" {
+		return fmt.Errorf("SUPPLIER_ID is required with SUPPLIER_PRICE_LIST_URL")
+	}
+	if !uuidPattern.MatchString(supplierId) {
+		return fmt.Errorf("SUPPLIER_ID must be a UUID")
+	}
+	return nil
+}
+
+// normalizeBasePath makes sure the base path has a single leading slash and no trailing slash,
+// so the route constants (which all start with "/") can be appended to it.
+func normalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+// NewConfig reads the config from the config file, the environment and the command-line flags, see configReader for
+// which wins.
 func NewConfig() (*Config, error) {
+	return newConfig(os.Args[1:])
+}
+
+func newConfig(args []string) (*Config, error) {
 	cfg := &Config{}
-	if err := cfg.readConfig(); err != nil {
+	if err := cfg.readConfig(args); err != nil {
 		return nil, err
 	}
 	return cfg, nil
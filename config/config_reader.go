@@ -0,0 +1,226 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// requiredEnvironment are the variables the server cannot run without, none of them has a default. Every one left
+// unset in all the layers is reported by name.
+var requiredEnvironment = []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_NAME", "DB_DRIVER", "API_PORT", "TOKEN_ISSUE", "TOKEN_SECRET"}
+
+// secretEnvironment are left out of the effective config that is logged.
+var secretEnvironment = map[string]bool{"DB_PASSWORD": true, "TOKEN_SECRET": true}
+
+// defaultConfigFile is read when it exists and neither --config nor CONFIG_FILE names another file.
+const defaultConfigFile = "config.yaml"
+
+// configFlags are the command-line overrides and the variable each one sets, for a quick change without editing
+// the config file or the environment.
+var configFlags = map[string]string{
+	"host":       "API_HOST",
+	"port":       "API_PORT",
+	"base-path":  "API_BASE_PATH",
+	"db-host":    "DB_HOST",
+	"db-port":    "DB_PORT",
+	"db-name":    "DB_NAME",
+	"log-output": "LOG_OUTPUT",
+}
+
+// configReader resolves every setting by its environment variable name through the layers of config, and collects
+// everything wrong with them so one start reports all of it by name instead of the first one.
+//
+// The layers, from the lowest precedence to the highest:
+//  1. the default in readConfig
+//  2. the config file, config.yaml or the file of --config or CONFIG_FILE, keyed by the variable names
+//  3. the environment, including what the .env file sets
+//  4. the command-line flags in configFlags
+//
+// An empty value in a layer is the same as none, the layer below it applies.
+type configReader struct {
+	file      map[string]string
+	flags     map[string]string
+	problems  []string
+	effective map[string]string
+}
+
+// newConfigReader parses args and reads the config file they or the environment name. A flag that does not parse
+// is reported with the other problems, -h and --help return flag.ErrHelp.
+func newConfigReader(args []string) (*configReader, error) {
+	r := &configReader{file: map[string]string{}, flags: map[string]string{}, effective: map[string]string{}}
+
+	flagSet := flag.NewFlagSet("server-pulsa-app", flag.ContinueOnError)
+	configFile := flagSet.String("config", "", "path of the YAML or JSON config file, overrides CONFIG_FILE")
+	values := make(map[string]*string, len(configFlags))
+	for name, key := range configFlags {
+		values[name] = flagSet.String(name, "", "overrides "+key)
+	}
+	if err := flagSet.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		r.add("%v", err)
+	}
+	// only the flags given on the command line, an unset one leaves the layers below alone
+	flagSet.Visit(func(f *flag.Flag) {
+		if key, ok := configFlags[f.Name]; ok {
+			r.flags[key] = *values[f.Name]
+		}
+	})
+
+	path, explicit := *configFile, true
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		path, explicit = defaultConfigFile, false
+	}
+	r.readFile(path, explicit)
+	return r, nil
+}
+
+// readFile loads the settings of path, a YAML mapping of variable names to values. JSON is valid YAML, so a JSON
+// object works too. Keys are case-insensitive and a list becomes the comma-separated value the variable takes.
+func (r *configReader) readFile(path string, explicit bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			r.add("config file %s: %v", path, err)
+		}
+		return
+	}
+
+	var settings map[string]any
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		r.add("config file %s: %v", path, err)
+		return
+	}
+	for key, value := range settings {
+		key = strings.ToUpper(key)
+		switch value := value.(type) {
+		case nil:
+		case map[string]any:
+			r.add("config file %s: %s must be a value or a list, not a mapping", path, key)
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			r.file[key] = strings.Join(items, ",")
+		default:
+			r.file[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// get resolves key through the layers and records the value in the effective config.
+func (r *configReader) get(key, defaultValue string) string {
+	value := defaultValue
+	for _, layer := range []string{r.file[key], os.Getenv(key), r.flags[key]} {
+		if layer != "" {
+			value = layer
+		}
+	}
+	r.effective[key] = value
+	return value
+}
+
+// redacted is the effective config with the values of secretEnvironment hidden.
+func (r *configReader) redacted() map[string]string {
+	settings := make(map[string]string, len(r.effective))
+	for key, value := range r.effective {
+		if secretEnvironment[key] && value != "" {
+			value = "[REDACTED]"
+		}
+		settings[key] = value
+	}
+	return settings
+}
+
+func (r *configReader) add(format string, args ...any) {
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+func (r *configReader) check(err error) {
+	if err != nil {
+		r.add("%s", err.Error())
+	}
+}
+
+func (r *configReader) err() error {
+	if len(r.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config: %s", strings.Join(r.problems, "; "))
+}
+
+// required reads key, a value of only spaces is as missing as none.
+func (r *configReader) required(key string) string {
+	value := r.get(key, "")
+	if strings.TrimSpace(value) == "" {
+		r.add("%s missing", key)
+	}
+	return value
+}
+
+func (r *configReader) secret(key string) string {
+	value := r.get(key, "")
+	if strings.TrimSpace(value) == "" {
+		r.add("%s must not be empty", key)
+	}
+	return value
+}
+
+// int reads key as a number. A value that is not one is reported and the default is used in its place, so the
+// checks that depend on it do not report it again.
+func (r *configReader) int(key, defaultValue string) int {
+	value, err := strconv.Atoi(strings.TrimSpace(r.get(key, defaultValue)))
+	if err != nil {
+		r.add("%s must be a number", key)
+		value, _ = strconv.Atoi(defaultValue)
+	}
+	return value
+}
+
+func (r *configReader) positiveInt(key, defaultValue string) int {
+	value, err := strconv.Atoi(strings.TrimSpace(r.get(key, defaultValue)))
+	if err != nil || value <= 0 {
+		r.add("%s must be a positive integer", key)
+		value, _ = strconv.Atoi(defaultValue)
+	}
+	return value
+}
+
+func (r *configReader) int64(key, defaultValue string) int64 {
+	value, err := strconv.ParseInt(strings.TrimSpace(r.get(key, defaultValue)), 10, 64)
+	if err != nil {
+		r.add("%s must be a number", key)
+		value, _ = strconv.ParseInt(defaultValue, 10, 64)
+	}
+	return value
+}
+
+func (r *configReader) uint(key, defaultValue string, bitSize int) uint64 {
+	value, err := strconv.ParseUint(strings.TrimSpace(r.get(key, defaultValue)), 10, bitSize)
+	if err != nil {
+		r.add("%s must be a number from 0 to %d", key, uint64(1)<<bitSize-1)
+		value, _ = strconv.ParseUint(defaultValue, 10, bitSize)
+	}
+	return value
+}
+
+func (r *configReader) bool(key, defaultValue string) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(r.get(key, defaultValue)))
+	if err != nil {
+		r.add("%s must be true or false", key)
+		value, _ = strconv.ParseBool(defaultValue)
+	}
+	return value
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	assert.Equal(t, "/api/v1", normalizeBasePath("/api/v1"))
+	assert.Equal(t, "/pulsa/api", normalizeBasePath("pulsa/api/"))
+	assert.Equal(t, "/v2", normalizeBasePath(" /v2/ "))
+	assert.Equal(t, "", normalizeBasePath("/"))
+}
+
+func TestValidateBcryptCost(t *testing.T) {
+	assert.NoError(t, validateBcryptCost(bcrypt.MinCost))
+	assert.NoError(t, validateBcryptCost(bcrypt.DefaultCost))
+	assert.NoError(t, validateBcryptCost(bcrypt.MaxCost))
+	assert.Error(t, validateBcryptCost(bcrypt.MinCost-1))
+	assert.Error(t, validateBcryptCost(bcrypt.MaxCost+1))
+}
+
+func TestApiConfigAddress(t *testing.T) {
+	assert.Equal(t, ":8080", ApiConfig{ApiPort: "8080"}.Address())
+	assert.Equal(t, "127.0.0.1:8443", ApiConfig{ApiHost: "127.0.0.1", ApiPort: "8443"}.Address())
+	assert.Equal(t, "[::1]:8080", ApiConfig{ApiHost: "::1", ApiPort: "8080"}.Address())
+}
+
+func TestValidateTLSFiles(t *testing.T) {
+	assert.NoError(t, validateTLSFiles("", "", ""))
+	assert.NoError(t, validateTLSFiles("cert.pem", "key.pem", ""))
+	assert.NoError(t, validateTLSFiles("cert.pem", "key.pem", ":80"))
+	assert.Error(t, validateTLSFiles("cert.pem", "", ""))
+	assert.Error(t, validateTLSFiles("", "key.pem", ""))
+	assert.Error(t, validateTLSFiles("", "", ":80"))
+
+	assert.True(t, ApiConfig{TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}.TLSEnabled())
+	assert.False(t, ApiConfig{}.TLSEnabled())
+}
+
+func TestValidateSupplier(t *testing.T) {
+	assert.NoError(t, validateSupplier("", ""))
+	assert.NoError(t, validateSupplier("https://supplier.test/prices", "5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60"))
+	assert.EqualError(t, validateSupplier("https://supplier.test/prices", ""), "SUPPLIER_ID is required with SUPPLIER_PRICE_LIST_URL")
+	assert.EqualError(t, validateSupplier("https://supplier.test/prices", "supplier-1"), "SUPPLIER_ID must be a UUID")
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	assert.NoError(t, validateTrustedProxies(nil))
+	assert.NoError(t, validateTrustedProxies([]string{"10.0.0.1", "172.16.0.0/12", "::1"}))
+	assert.EqualError(t, validateTrustedProxies([]string{"10.0.0.1", "load-balancer"}), `TRUSTED_PROXIES must be IPs or CIDRs, not "load-balancer"`)
+}
+
+func TestValidateReadiness(t *testing.T) {
+	assert.NoError(t, validateReadiness(ReadinessConfig{CriticalDependencies: []string{"database", "migrations", "sms"}}))
+	assert.EqualError(t, validateReadiness(ReadinessConfig{CriticalDependencies: []string{"database", "datbase"}}),
+		`READY_CRITICAL_DEPENDENCIES must only name database, migrations, provider, sms, not "datbase"`)
+	assert.EqualError(t, validateReadiness(ReadinessConfig{ReadinessCacheTTL: -time.Second}), "READY_CACHE_TTL must not be negative")
+}
+
+// clearRequiredEnvironment leaves the test without the settings of the machine running it.
+func clearRequiredEnvironment(t *testing.T) {
+	for _, key := range append(requiredEnvironment, "CONFIG_FILE", "DB_PASSWORD") {
+		t.Setenv(key, "")
+	}
+}
+
+// setRequiredEnvironment sets every required variable but the ones in except, which are cleared.
+func setRequiredEnvironment(t *testing.T, except ...string) {
+	clearRequiredEnvironment(t)
+	values := map[string]string{
+		"DB_HOST": "localhost", "DB_PORT": "5432", "DB_USER": "postgres", "DB_NAME": "server_pulsa_db",
+		"DB_DRIVER": "postgres", "API_PORT": "8080", "TOKEN_ISSUE": "Server Pulsa", "TOKEN_SECRET": "test-signing-key",
+	}
+	for _, key := range except {
+		delete(values, key)
+	}
+	for key, value := range values {
+		t.Setenv(key, value)
+	}
+}
+
+func TestNewConfig_ReportsEveryProblem(t *testing.T) {
+	setRequiredEnvironment(t)
+	t.Setenv("DB_HOST", " ")
+	t.Setenv("TOKEN_SECRET", " ")
+	t.Setenv("TOKEN_EXPIRE", "two hours")
+	t.Setenv("GZIP_ENABLED", "yes please")
+	t.Setenv("ARGON2_THREADS", "300")
+
+	_, err := newConfig(nil)
+
+	assert.EqualError(t, err, "invalid config: DB_HOST missing; GZIP_ENABLED must be true or false; "+
+		"ARGON2_THREADS must be a number from 0 to 255; TOKEN_EXPIRE must be a positive integer; TOKEN_SECRET must not be empty")
+}
+
+func TestNewConfig_TokenExpireMustBePositive(t *testing.T) {
+	setRequiredEnvironment(t)
+	t.Setenv("TOKEN_EXPIRE", "0")
+
+	_, err := newConfig(nil)
+
+	assert.EqualError(t, err, "invalid config: TOKEN_EXPIRE must be a positive integer")
+}
+
+func TestNewConfig_ReadyTimeoutMustBePositive(t *testing.T) {
+	setRequiredEnvironment(t)
+	t.Setenv("READY_TIMEOUT", "0")
+
+	_, err := newConfig(nil)
+
+	assert.EqualError(t, err, "invalid config: READY_TIMEOUT must be a positive integer")
+}
+
+func TestNewConfig_EnvironmentWithoutEnvFile(t *testing.T) {
+	setRequiredEnvironment(t)
+
+	cfg, err := newConfig(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", cfg.Host)
+	assert.Equal(t, []byte("test-signing-key"), cfg.JwtSignatureKy)
+}
+
+func TestNewConfig_RequiredWithoutDefault(t *testing.T) {
+	setRequiredEnvironment(t, "TOKEN_SECRET")
+
+	_, err := newConfig(nil)
+
+	assert.EqualError(t, err, "invalid config: TOKEN_SECRET must not be empty")
+}
+
+func TestNewConfig_ReportsEveryUnsetVariable(t *testing.T) {
+	setRequiredEnvironment(t, "DB_PORT", "API_PORT")
+
+	_, err := newConfig(nil)
+
+	assert.EqualError(t, err, "invalid config: DB_PORT missing; API_PORT missing")
+}
+
+func TestNewConfig_NeitherEnvFileNorEnvironment(t *testing.T) {
+	clearRequiredEnvironment(t)
+
+	_, err := newConfig(nil)
+
+	assert.EqualError(t, err, "invalid config: DB_HOST missing; DB_PORT missing; DB_USER missing; DB_NAME missing; "+
+		"DB_DRIVER missing; API_PORT missing; TOKEN_ISSUE missing; TOKEN_SECRET must not be empty")
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestNewConfig_Layers(t *testing.T) {
+	file := writeConfigFile(t, "config.yaml", "db_host: db.internal\nAPI_PORT: 9000\n")
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		wantHost string
+		wantPort string
+	}{
+		{name: "file", args: []string{"--config", file}, wantHost: "db.internal", wantPort: "9000"},
+		{name: "environment over file", env: map[string]string{"API_PORT": "9100"}, args: []string{"--config", file},
+			wantHost: "db.internal", wantPort: "9100"},
+		{name: "flag over environment and file", env: map[string]string{"API_PORT": "9100"},
+			args: []string{"--config", file, "--port", "9200"}, wantHost: "db.internal", wantPort: "9200"},
+		{name: "flag over environment", env: map[string]string{"DB_HOST": "env.internal", "API_PORT": "8080"},
+			args: []string{"--db-host", "flag.internal"}, wantHost: "flag.internal", wantPort: "8080"},
+		{name: "flags alone", args: []string{"--db-host", "flag.internal", "--port", "9200"},
+			wantHost: "flag.internal", wantPort: "9200"},
+		{name: "CONFIG_FILE", env: map[string]string{"CONFIG_FILE": file}, wantHost: "db.internal", wantPort: "9000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnvironment(t, "DB_HOST", "API_PORT")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := newConfig(tt.args)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantHost, cfg.Host)
+			assert.Equal(t, tt.wantPort, cfg.ApiPort)
+		})
+	}
+}
+
+func TestNewConfig_ConfigFlagOverConfigFile(t *testing.T) {
+	setRequiredEnvironment(t, "DB_HOST")
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "env.yaml", "DB_HOST: from-env-file\n"))
+
+	cfg, err := newConfig([]string{"--config", writeConfigFile(t, "flag.json", `{"DB_HOST": "from-flag-file"}`)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "from-flag-file", cfg.Host)
+}
+
+func TestNewConfig_FileLists(t *testing.T) {
+	setRequiredEnvironment(t, "DB_HOST")
+	file := writeConfigFile(t, "config.yaml", "DB_HOST: localhost\nACCESS_LOG_SKIP_PATHS:\n  - /healthz\n  - /metrics\n")
+
+	cfg, err := newConfig([]string{"--config", file})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/healthz", "/metrics"}, cfg.AccessLogSkipPaths)
+}
+
+func TestNewConfig_FileProblems(t *testing.T) {
+	setRequiredEnvironment(t)
+
+	_, err := newConfig([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.ErrorContains(t, err, "missing.yaml")
+
+	_, err = newConfig([]string{"--config", writeConfigFile(t, "nested.yaml", "DB:\n  HOST: localhost\n")})
+	assert.ErrorContains(t, err, "DB must be a value or a list, not a mapping")
+
+	_, err = newConfig([]string{"--verbose"})
+	assert.ErrorContains(t, err, "flag provided but not defined: -verbose")
+}
+
+func TestNewConfig_EffectiveRedactsSecrets(t *testing.T) {
+	setRequiredEnvironment(t)
+	t.Setenv("DB_PASSWORD", "hunter2")
+
+	cfg, err := newConfig([]string{"--port", "9300"})
+
+	assert.NoError(t, err)
+	effective := cfg.Effective()
+	assert.Equal(t, "localhost", effective["DB_HOST"])
+	assert.Equal(t, "9300", effective["API_PORT"])
+	assert.Equal(t, "[REDACTED]", effective["DB_PASSWORD"])
+	assert.Equal(t, "[REDACTED]", effective["TOKEN_SECRET"])
+}
+
+func TestNewConfig_HTTPTimeouts(t *testing.T) {
+	setRequiredEnvironment(t)
+	t.Setenv("HTTP_WRITE_TIMEOUT", "90")
+	t.Setenv("HTTP_IDLE_TIMEOUT", "0")
+
+	cfg, err := newConfig(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, HTTPTimeoutConfig{
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      90 * time.Second,
+	}, cfg.HTTPTimeoutConfig)
+	assert.Equal(t, int64(10485760), cfg.UploadMaxBodyBytes)
+}
@@ -1,35 +1,82 @@
 package config
 
 const (
-	ApiGroup = "/api/v1"
+	ApiGroup   = "/api/v1"
+	ApiGroupV2 = "/api/v2"
 	// merchant route
-	PostMerchant    = "/merchant"
-	GetMerchantList = "/merchants"
-	GetMerchant     = "/merchant/:id"
-	PutMerchant     = "/merchant/:id"
-	DeleteMerchant  = "/merchant/:id"
+	PostMerchant        = "/merchant"
+	PostMerchantOnboard = "/merchant/onboard"
+	GetMerchantList     = "/merchants"
+	GetMerchant         = "/merchant/:id"
+	PutMerchant         = "/merchant/:id"
+	DeleteMerchant      = "/merchant/:id"
+	GetMerchantBalance  = "/merchant/:id/balance"
+	PostBalanceAdjust   = "/admin/merchants/balance-adjust"
+	PutMerchantPrice    = "/admin/merchants/:id/prices/:productId"
+	DeleteMerchantPrice = "/admin/merchants/:id/prices/:productId"
+	GetMerchantCatalog  = "/merchant/:id/products"
 
 	// product route
 	PostProduct    = "/product"
 	GetProductList = "/products"
 	GetProduct     = "/product/:id"
+	GetProductCode = "/product/code/:code"
+	PostProductIds = "/products/batch"
 	PutProduct     = "/product/:id"
 	DeleteProduct  = "/product/:id"
 
+	PostProductSync   = "/admin/products/sync"
+	GetPublicProducts = "/public/products"
+
+	// provider route
+	PostProvider    = "/provider"
+	GetProviderList = "/providers"
+	GetProvider     = "/provider/:id"
+	PutProvider     = "/provider/:id"
+	DeleteProvider  = "/provider/:id"
+
 	//transaction route
-	PostTransaction   = "/transaction"
-	ListTransactions  = "/transactions"
-	DetailTransaction = "/transaction/:id"
+	PostTransaction    = "/transaction"
+	QuoteTransaction   = "/transaction/quote"
+	ListTransactions   = "/transactions"
+	DetailTransaction  = "/transaction/:id"
+	ReceiptTransaction = "/transaction/history/:id/receipt"
+	ReceiptPdf         = "/transaction/history/:id/receipt.pdf"
+	RefundTransaction  = "/transaction/history/:id/detail/:detailId/refund"
 
 	// user route
-	GetUserList = "/users"
-	GetUser     = "/user/:id"
-	PutUser     = "/user/:id"
-	DeleteUser  = "/user/:id"
+	PostUser        = "/user"
+	GetUserList     = "/users"
+	GetUser         = "/user/:id"
+	PutUser         = "/user/:id"
+	DeleteUser      = "/user/:id"
+	PatchUserActive = "/user/:id/activate"
+	PutUserPassword = "/user/password"
+	GetMe           = "/me"
+
+	PostUserResetPassword = "/admin/user/:id/reset-password"
 
 	// auth route
-	Login    = "/auth/login"
-	Register = "/auth/register"
+	Login          = "/auth/login"
+	ForgotPassword = "/auth/forgot-password"
+	ResetPassword  = "/auth/reset-password"
+	Register       = "/auth/register"
+	RefreshToken   = "/auth/refresh"
+	Logout         = "/auth/logout"
+	TwoFactorLogin = "/auth/2fa/verify"
+
+	// session route
+	GetMySessions      = "/me/sessions"
+	DeleteMySession    = "/me/sessions/:id"
+	DeleteUserSessions = "/admin/user/:id/sessions"
+
+	// two-factor route
+	PostTwoFactorSetup  = "/me/2fa/setup"
+	PostTwoFactorEnable = "/me/2fa/enable"
+
+	// role permission route
+	GetRolePermissions = "/admin/roles/permissions"
+	PutRolePermissions = "/admin/roles/:role/permissions"
 
 	// topup route
 	PostTopup            = "/topup"
@@ -40,5 +87,39 @@ const (
 	PostCallback = "/topup/callback"
 
 	//report route
-	GetReport = "/report"
+	GetReport              = "/report"
+	GetProductMarginReport = "/admin/products/report/margin"
+	GetStatsOverview       = "/stats/overview"
+
+	// audit route
+	GetAuditLog     = "/admin/audit"
+	GetAuthEvents   = "/admin/auth-events"
+	GetMyAuthEvents = "/me/auth-events"
+
+	// admin diagnostics route
+	GetDbStats = "/admin/db-stats"
+
+	// readiness and liveness routes, served outside the api base path for load balancers. /ready and /readyz are
+	// the same check, /livez checks nothing but the process itself.
+	GetReady  = "/ready"
+	GetReadyz = "/readyz"
+	GetLivez  = "/livez"
+	GetHealth = "/healthz"
+
+	// prometheus scrape route, served without auth outside the api base path or on METRICS_ADDR when that is set
+	GetMetrics = "/metrics"
+
+	// OpenAPI document generated by swag and the Swagger UI reading it, both under the api base path and only
+	// registered when DOCS_ENABLED is set
+	GetOpenAPI = "/openapi.json"
+	GetDocs    = "/docs"
+
+	// route that always panics, only registered in builds with the panicroute tag so integration tests can exercise
+	// the panic recovery
+	GetDebugPanic = "/debug/panic"
 )
+
+// UploadRoutes are the api routes that take files, keyed by the route path under the api base path. They get
+// UPLOAD_MAX_BODY_BYTES instead of MAX_BODY_BYTES and skip the JSON content type check. No route takes a file yet,
+// one that does opts in here.
+var UploadRoutes = map[string]bool{}
@@ -15,157 +15,175 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/login": {
-            "post": {
-                "description": "Authenticate a user and get JWT token",
-                "consumes": [
-                    "application/json"
+        "/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Sensitive admin actions (balance adjustments, role changes, product deletions), newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "authentication"
+                    "audit"
                 ],
-                "summary": "Login user",
+                "summary": "List audit log",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.AuthRequest"
-                        }
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully authenticated",
+                        "description": "Audit log entries",
                         "schema": {
-                            "$ref": "#/definitions/dto.AuthResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.AuditLog"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid input",
+                        "description": "Invalid pagination",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "401": {
-                        "description": "Authentication failed",
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Create a new user",
-                "consumes": [
-                    "application/json"
+        "/admin/auth-events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Logins, failed logins, logouts, password changes and lockouts of every user, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "authentication"
+                    "audit"
                 ],
-                "summary": "Register user",
+                "summary": "List authentication events",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.AuthRequest"
-                        }
+                        "type": "string",
+                        "description": "Only events of this user",
+                        "name": "userId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day, YYYY-MM-DD",
+                        "name": "startDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day, YYYY-MM-DD",
+                        "name": "endDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Successfully registered",
+                    "200": {
+                        "description": "Authentication events",
                         "schema": {
-                            "$ref": "#/definitions/dto.AuthRegisterRes"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.AuthEvent"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid input",
+                        "description": "Invalid filter or pagination",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "401": {
-                        "description": "Authentication failed",
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/merchant": {
-            "post": {
+        "/admin/db-stats": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new merchant in the system",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Open, in use and idle connections and how often requests had to wait for one",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "merchants"
-                ],
-                "summary": "Create new merchant",
-                "parameters": [
-                    {
-                        "description": "Merchant details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/entity.MerchantRequest"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Database connection pool stats",
                 "responses": {
-                    "201": {
-                        "description": "Successfully created",
-                        "schema": {
-                            "$ref": "#/definitions/entity.MerchantResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid input",
+                    "200": {
+                        "description": "Connection pool stats",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
+                            "$ref": "#/definitions/entity.DbStats"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/merchant/{id}": {
-            "get": {
+        "/admin/merchants/balance-adjust": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve a merchant by its ID",
+                "description": "Credit or debit many merchants at once, all adjustments are applied or none",
                 "consumes": [
                     "application/json"
                 ],
@@ -175,44 +193,48 @@ const docTemplate = `{
                 "tags": [
                     "merchants"
                 ],
-                "summary": "Get merchant by ID",
+                "summary": "Adjust merchant balances",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Merchant ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Balance delta per merchant id",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.BalanceAdjustmentRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Merchant found",
+                        "description": "Balances adjusted",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantResponse"
+                            "$ref": "#/definitions/entity.BalanceAdjustmentRequest"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Invalid input",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "404": {
-                        "description": "Merchant not found",
+                        "description": "Unknown merchant",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/merchants/{id}/prices/{productId}": {
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing merchant",
+                "description": "Override the catalog price of a product for one merchant",
                 "consumes": [
                     "application/json"
                 ],
@@ -222,7 +244,7 @@ const docTemplate = `{
                 "tags": [
                     "merchants"
                 ],
-                "summary": "Update merchant",
+                "summary": "Set a merchant product price",
                 "parameters": [
                     {
                         "type": "string",
@@ -232,38 +254,39 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Updated merchant details",
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "productId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Price the merchant pays",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantRequest"
+                            "$ref": "#/definitions/entity.MerchantPriceRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully updated merchant",
+                        "description": "Price override saved",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantResponse"
+                            "$ref": "#/definitions/entity.MerchantProductPrice"
                         }
                     },
                     "400": {
                         "description": "Invalid input",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "404": {
-                        "description": "Merchant not found",
+                        "description": "Merchant or product not found",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
@@ -274,17 +297,14 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a merchant by its ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Remove the price override so the merchant pays the catalog price again",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "merchants"
                 ],
-                "summary": "Delete merchant",
+                "summary": "Clear a merchant product price",
                 "parameters": [
                     {
                         "type": "string",
@@ -292,187 +312,175 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "productId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "Successfully deleted"
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
-                        }
+                        "description": "Price override cleared"
                     },
                     "404": {
-                        "description": "Merchant not found",
+                        "description": "No override for this product",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/merchants": {
+        "/admin/products/report/margin": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get a list of all merchants",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Units sold, revenue, cost (nominal x units) and margin per product across all merchants",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "merchants"
+                    "reports"
                 ],
-                "summary": "List all merchants",
+                "summary": "Product margin report",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "startDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (YYYY-MM-DD)",
+                        "name": "endDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "margin",
+                        "description": "margin or units",
+                        "name": "sortBy",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "Items per page",
+                        "default": 50,
+                        "description": "Maximum number of products",
                         "name": "limit",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of merchants",
+                        "description": "Product margins",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/entity.MerchantResponse"
-                                }
+                                "$ref": "#/definitions/custom.ProductMarginResp"
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Invalid filter",
                         "schema": {
-                            "$ref": "#/definitions/entity.MerchantErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/product": {
+        "/admin/products/sync": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new product in the system",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Fetch the supplier price list and apply it in one transaction, unknown codes are created as draft products",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "products"
                 ],
-                "summary": "Create new product",
-                "parameters": [
-                    {
-                        "description": "Product details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/entity.ProductRequest"
-                        }
-                    }
-                ],
+                "summary": "Sync product prices from the supplier",
                 "responses": {
-                    "201": {
-                        "description": "Successfully created product",
+                    "200": {
+                        "description": "Sync report",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductResponse"
+                            "$ref": "#/definitions/entity.ProductSyncReport"
                         }
                     },
-                    "400": {
-                        "description": "Invalid input",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "502": {
+                        "description": "Supplier price list could not be applied",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/product/{id}": {
+        "/admin/roles/permissions": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve a product by its ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get the permissions of every role, logins put them in the permissions claim of the access token",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
-                ],
-                "summary": "Get product by ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "role-permissions"
                 ],
+                "summary": "List role permissions",
                 "responses": {
                     "200": {
-                        "description": "Product found",
+                        "description": "Permissions of each role",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.RolePermissions"
+                            }
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
-                    "404": {
-                        "description": "Product not found",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/roles/{role}/permissions": {
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing product",
+                "description": "Replace the permissions of a role. Tokens that were already issued keep their permissions until they are refreshed",
                 "consumes": [
                     "application/json"
                 ],
@@ -480,61 +488,67 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "role-permissions"
                 ],
-                "summary": "Update product",
+                "summary": "Update role permissions",
                 "parameters": [
                     {
+                        "enum": [
+                            "admin",
+                            "employee"
+                        ],
                         "type": "string",
-                        "description": "Product ID",
-                        "name": "id",
+                        "description": "Role",
+                        "name": "role",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated product details",
+                        "description": "New permissions of the role",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductRequest"
+                            "$ref": "#/definitions/entity.RolePermissionsRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully updated product",
+                        "description": "Updated permissions",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductResponse"
+                            "$ref": "#/definitions/entity.RolePermissions"
                         }
                     },
                     "400": {
-                        "description": "Invalid input",
+                        "description": "Invalid input, unknown role or unknown permission",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
-                    "404": {
-                        "description": "Product not found",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/user/{id}/reset-password": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a product by its ID",
+                "description": "Set the password of a user who lost it, without the old one. Without a new password a temporary one is generated and returned, only in this response. The user must change it before doing anything else, every session of the user ends with the reset.",
                 "consumes": [
                     "application/json"
                 ],
@@ -542,99 +556,118 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "users"
                 ],
-                "summary": "Delete product",
+                "summary": "Reset a user's password",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Product ID",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "New password, empty to generate one",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/entity.AdminResetPasswordRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Successfully deleted"
+                    "200": {
+                        "description": "Password reset",
+                        "schema": {
+                            "$ref": "#/definitions/entity.AdminResetPasswordResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, or the password rules that failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "404": {
-                        "description": "Product not found",
+                        "description": "User not found",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/products": {
-            "get": {
+        "/admin/user/{id}/sessions": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get a list of all products",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Revoke every session of a user, e.g. a compromised account. The tokens stop working on the next request",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "sessions"
                 ],
-                "summary": "List all products",
+                "summary": "Force logout a user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of products",
+                        "description": "Number of sessions revoked",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/entity.ProductResponse"
-                                }
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
                             }
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.ProductErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Invalid user id",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/transaction": {
+        "/auth/2fa/verify": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Create a new transaction in the system",
+                "description": "Exchange the challenge token of /auth/login and a code of the authenticator app or an unused backup code for the JWT. A challenge expires after 5 minutes or 5 wrong codes",
                 "consumes": [
                     "application/json"
                 ],
@@ -642,50 +675,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "authentication"
                 ],
-                "summary": "Create new transaction",
+                "summary": "Complete a two-factor login",
                 "parameters": [
                     {
-                        "description": "Transaction details",
+                        "description": "Challenge token and code",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entity.TransactionReq"
+                            "$ref": "#/definitions/dto.TwoFactorVerifyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Successfully created transaction",
+                    "200": {
+                        "description": "Successfully authenticated",
                         "schema": {
-                            "$ref": "#/definitions/entity.Transactions"
+                            "$ref": "#/definitions/dto.AuthResponse"
                         }
                     },
                     "400": {
                         "description": "Invalid input",
                         "schema": {
-                            "$ref": "#/definitions/entity.TransactionErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "Invalid code or invalid, expired or exhausted challenge",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "The account has been deactivated",
                         "schema": {
-                            "$ref": "#/definitions/entity.TransactionErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/transaction/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Retrieve a transaction by its ID",
+        "/auth/forgot-password": {
+            "post": {
+                "description": "Send a single-use reset token to the user, the response is the same whether the username exists or not",
                 "consumes": [
                     "application/json"
                 ],
@@ -693,48 +727,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "authentication"
                 ],
-                "summary": "Get transaction by ID",
+                "summary": "Request a password reset",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Transaction ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Username",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ForgotPasswordRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Transaction found",
+                    "202": {
+                        "description": "Reset requested",
                         "schema": {
-                            "$ref": "#/definitions/entity.Transactions"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/entity.TransactionErrorResponse"
+                            "$ref": "#/definitions/dto.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Transaction not found",
+                    "400": {
+                        "description": "Invalid input",
                         "schema": {
-                            "$ref": "#/definitions/entity.TransactionErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/transactions": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get a list of all transactions",
+        "/auth/login": {
+            "post": {
+                "description": "Authenticate a user and get JWT token. With two-factor authentication enabled the response only has twoFactorRequired and a challengeToken for /auth/2fa/verify",
                 "consumes": [
                     "application/json"
                 ],
@@ -742,53 +767,62 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "authentication"
                 ],
-                "summary": "List all transactions",
+                "summary": "Login user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Login credentials, the identifier is a username or an email",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LoginRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of transactions",
+                        "description": "Successfully authenticated",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/entity.Transactions"
-                                }
-                            }
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "Authentication failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "The account has been deactivated",
                         "schema": {
-                            "$ref": "#/definitions/entity.TransactionErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts for the username or client IP",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/user/{id}": {
-            "get": {
+        "/auth/logout": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve a user by its ID",
+                "description": "Revoke the current access token until it expires. When a refresh token is given, every refresh token of that login is revoked too",
                 "consumes": [
                     "application/json"
                 ],
@@ -796,46 +830,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "authentication"
                 ],
-                "summary": "Get user by ID",
+                "summary": "Logout user",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Refresh token to revoke",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LogoutRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "User found",
+                    "204": {
+                        "description": "Successfully logged out"
+                    },
+                    "400": {
+                        "description": "Invalid input",
                         "schema": {
-                            "$ref": "#/definitions/entity.UserResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.UserErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "User not found",
-                        "schema": {
-                            "$ref": "#/definitions/entity.UserErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
-            },
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Update an existing user",
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access token. The refresh token is rotated, presenting an already used refresh token revokes every token of that login",
                 "consumes": [
                     "application/json"
                 ],
@@ -843,61 +872,225 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "authentication"
                 ],
-                "summary": "Update user",
+                "summary": "Refresh access token",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated user details",
+                        "description": "Refresh token",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entity.UserReqUpdate"
+                            "$ref": "#/definitions/dto.RefreshTokenRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully updated user",
+                        "description": "Successfully refreshed",
                         "schema": {
-                            "$ref": "#/definitions/entity.UserResponse"
+                            "$ref": "#/definitions/dto.AuthResponse"
                         }
                     },
                     "400": {
                         "description": "Invalid input",
                         "schema": {
-                            "$ref": "#/definitions/entity.UserErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/entity.UserErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "User not found",
+                        "description": "Invalid, expired or reused refresh token",
                         "schema": {
-                            "$ref": "#/definitions/entity.UserErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Create a new user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "authentication"
+                ],
+                "summary": "Register user",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully registered",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthRegisterRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or email, or the password rules that failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Authentication failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Username or email already taken",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to register the user",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reset-password": {
+            "post": {
+                "description": "Set a new password with a reset token, every token works once and only before it expires",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "authentication"
+                ],
+                "summary": "Reset a password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Password reset"
+                    },
+                    "400": {
+                        "description": "Invalid, expired or used token, or weak password",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Ping the database and report the uptime in seconds and the version, commit and build time of the server",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "The database answers",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Health"
+                        }
+                    },
+                    "503": {
+                        "description": "The database does not answer",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Health"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "Answer as long as the process serves requests, no dependency is checked so a database outage does not get the pod restarted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness",
+                "responses": {
+                    "200": {
+                        "description": "The process is up",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Profile of the logged in user with the merchants they own, for every role",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get own profile",
+                "responses": {
+                    "200": {
+                        "description": "Profile of the logged in user",
+                        "schema": {
+                            "$ref": "#/definitions/entity.UserProfile"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized or the user no longer exists",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/enable": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a user by its ID",
+                "description": "Confirm the secret of /me/2fa/setup with a code of the authenticator app. The backup codes are only shown in this response, each one can be used once instead of a code",
                 "consumes": [
                     "application/json"
                 ],
@@ -905,269 +1098,3555 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "two-factor"
                 ],
-                "summary": "Delete user",
+                "summary": "Enable two-factor authentication",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Code of the authenticator app",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.TwoFactorCodeRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Successfully deleted"
+                    "200": {
+                        "description": "Backup codes",
+                        "schema": {
+                            "$ref": "#/definitions/entity.TwoFactorBackupCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, invalid code or no secret set up",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.UserErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "$ref": "#/definitions/entity.UserErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
         },
-        "/users": {
-            "get": {
+        "/me/2fa/setup": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get a list of all users",
-                "consumes": [
+                "description": "Generate a TOTP secret for the logged in admin. Logins only ask for a code once it is confirmed at /me/2fa/enable, setting up again replaces an unconfirmed secret",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "two-factor"
+                ],
+                "summary": "Set up two-factor authentication",
+                "responses": {
+                    "200": {
+                        "description": "Secret and otpauth URL for the authenticator app",
+                        "schema": {
+                            "$ref": "#/definitions/entity.TwoFactorSetup"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/auth-events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Recent logins, failed logins, logouts, password changes and lockouts of the logged in user, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "audit"
                 ],
-                "summary": "List all Users",
+                "summary": "List my authentication events",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day, YYYY-MM-DD",
+                        "name": "startDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day, YYYY-MM-DD",
+                        "name": "endDate",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
+                        "default": 1,
                         "description": "Page number",
                         "name": "page",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "Items per page",
+                        "default": 20,
+                        "description": "Items per page, at most 100",
                         "name": "limit",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of users",
+                        "description": "Authentication events",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/entity.User"
-                                }
+                                "$ref": "#/definitions/entity.AuthEvent"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Invalid filter or pagination",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/entity.UserErrorResponse"
+                            "$ref": "#/definitions/apierror.Response"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.AuthRegisterRes": {
-            "type": "object",
-            "properties": {
-                "password": {
-                    "type": "string",
-                    "example": "Hashed Password"
+        },
+        "/me/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Active logins of the logged in user, most recently used first. The last use is the last login or token refresh",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "List my sessions",
+                "responses": {
+                    "200": {
+                        "description": "Active sessions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.Session"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Log one of the own sessions out, its access and refresh tokens stop working on the next request",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Revoke one of my sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Session revoked"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "No active session with this id",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchant": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new merchant in the system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Create new merchant",
+                "parameters": [
+                    {
+                        "description": "Merchant details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.MerchantRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created",
+                        "schema": {
+                            "$ref": "#/definitions/entity.MerchantResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchant/onboard": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a user and the merchant they own in one step, neither is created when the other fails. The merchant starts with a zero balance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Onboard a merchant",
+                "parameters": [
+                    {
+                        "description": "Owner and merchant details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.MerchantOnboardRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully onboarded",
+                        "schema": {
+                            "$ref": "#/definitions/entity.MerchantOnboarding"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, role or email, or the password rules that failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Default product not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Username or email already taken",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchant/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a merchant by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get merchant by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Merchant found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.MerchantResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Merchant not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing merchant",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Update merchant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The fields to change, a limit of 0 removes it",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.MerchantUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated merchant",
+                        "schema": {
+                            "$ref": "#/definitions/entity.MerchantResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Merchant not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a merchant by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Delete merchant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successfully deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Merchant not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchant/{id}/balance": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the current balance of a merchant, only for the owner or an admin",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get merchant balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Merchant balance",
+                        "schema": {
+                            "$ref": "#/definitions/entity.MerchantBalance"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Merchant not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchant/{id}/products": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the active products with the price this merchant pays, only for the owner or an admin",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get the merchant catalog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Merchant catalog",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.MerchantCatalogItem"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Merchant not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a list of all merchants",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "List all merchants",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of merchants",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/entity.MerchantResponse"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/product": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new product in the system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Create new product",
+                "parameters": [
+                    {
+                        "description": "Product details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created product",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProductResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Code already used by another product",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/product/code/{code}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a product by the code its provider knows it by, used to map provider webhooks to products",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product by provider code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider product code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProductResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "No product has the code",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/product/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a product by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProductResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Product not modified"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing product",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated product details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated product",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProductResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Product was modified since it was read, or its code is used by another product",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a product by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successfully deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Product still used by transactions",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/products": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a list of all products. Admins see every product, other users see the global products plus the private products of their merchants",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List all products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "nominal, price, provider or updated_at, defaults to provider then nominal",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "asc",
+                        "description": "asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of products",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/entity.ProductResponse"
+                                }
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Product list not modified"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve up to 100 products with one request, for example to validate a cart. Ids without a product the caller can see are listed as missing",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get products by ids",
+                "parameters": [
+                    {
+                        "description": "Product ids",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProductBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Products found and the missing ids",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProductBatch"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new provider in the system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "providers"
+                ],
+                "summary": "Create new provider",
+                "parameters": [
+                    {
+                        "description": "Provider details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProviderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created provider",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProviderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Provider already exists",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a provider by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "providers"
+                ],
+                "summary": "Get provider by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Provider found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProviderResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Provider not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Rename an existing provider, products keep pointing to it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "providers"
+                ],
+                "summary": "Update provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated provider details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProviderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated provider",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ProviderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Provider already exists",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a provider that is not used by any product",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "providers"
+                ],
+                "summary": "Delete provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successfully deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Provider not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Provider still used by products",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/providers": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a list of all providers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "providers"
+                ],
+                "summary": "List all providers",
+                "responses": {
+                    "200": {
+                        "description": "List of providers",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/entity.ProviderResponse"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/public/products": {
+            "get": {
+                "description": "Active products with their price, no authentication required",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "Public product catalog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Active products",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.PublicProduct"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Catalog not modified"
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Check the database, its pending migrations and the configured provider and SMS integrations in parallel. Every dependency is reported, only a critical one that is down makes the server not ready",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness",
+                "responses": {
+                    "200": {
+                        "description": "Every critical dependency is up",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Readiness"
+                        }
+                    },
+                    "503": {
+                        "description": "A critical dependency is down or the server is shutting down",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Readiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/overview": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Number of active products, merchants, active users and transactions created today, counted without loading any rows",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Dashboard totals",
+                "responses": {
+                    "200": {
+                        "description": "Totals",
+                        "schema": {
+                            "$ref": "#/definitions/custom.StatsOverview"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new transaction in the system. A detail line can be sold at overridePrice instead of the\ncatalog price by a caller with the transaction:price_override permission, the override and its reason\nare audited. The merchant balance is debited by the nominal either way.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Create new transaction",
+                "parameters": [
+                    {
+                        "description": "Transaction details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.TransactionReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created transaction",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Transactions"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or unknown user",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Merchant of another user or price override without the permission",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Merchant or product not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Insufficient balance, merchant daily transaction limit exceeded or product not available",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction/history/{id}/detail/{detailId}/refund": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Give the nominal of one detail line back to the merchant balance, for example when that product failed delivery. The other lines are not refunded",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Refund a transaction detail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transaction detail ID",
+                        "name": "detailId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction detail refunded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction or detail not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Detail already refunded",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction/history/{id}/receipt": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a flat, print-ready receipt of a transaction for POS printers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction receipt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction receipt",
+                        "schema": {
+                            "$ref": "#/definitions/custom.TransactionReceipt"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction of a merchant of another user",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction/history/{id}/receipt.pdf": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download the receipt of a transaction as a printable PDF for the customer, with the same content as the receipt endpoint",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction receipt as PDF",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Receipt PDF",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction of a merchant of another user",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction/quote": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sum the nominal of the products like a created transaction and tell whether the merchant balance\ncovers it, nothing is inserted or debited. The balance can still change before the transaction is created.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Quote a transaction",
+                "parameters": [
+                    {
+                        "description": "Merchant and products",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.TransactionQuoteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Total and whether the balance is sufficient",
+                        "schema": {
+                            "$ref": "#/definitions/custom.TransactionQuote"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Merchant of another user",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Merchant or product not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Product not available",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a transaction by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Transactions"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction of a merchant of another user",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a list of all transactions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "List all transactions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number, ignored with a cursor",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page, 20 with a cursor and no limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page, date sorts only",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "date_desc",
+                            "date_asc",
+                            "customer"
+                        ],
+                        "type": "string",
+                        "default": "date_desc",
+                        "description": "Order of the history",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "success",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Only the transactions with this status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of transactions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/entity.Transactions"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid sort, status, paging or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a user with a given role, unlike the public registration which always creates employees",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create user",
+                "parameters": [
+                    {
+                        "description": "New user details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.UserCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created user",
+                        "schema": {
+                            "$ref": "#/definitions/entity.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, role or email, or the password rules that failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "The admin role given by a caller who is not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Username or email already taken",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change the password of the logged in user, the current password is required. Every session of the user ends with the change, the one of this request included, so the user logs in again with the new password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change own password",
+                "parameters": [
+                    {
+                        "description": "Current and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Password changed"
+                    },
+                    "400": {
+                        "description": "Invalid input, or the password rules that failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a user by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.UserResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated user details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.UserReqUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated user",
+                        "schema": {
+                            "$ref": "#/definitions/entity.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or email",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "The admin role given, or an admin changed, by a caller who is not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Username or email already taken",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deactivate a user by its ID, the user is kept for the transaction history and can no longer log in or use issued tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully deleted",
+                        "schema": {
+                            "$ref": "#/definitions/entity.UserErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Deleting yourself or the last admin",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/activate": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reinstate a deleted user, they can log in again right away",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Activate user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully activated",
+                        "schema": {
+                            "$ref": "#/definitions/entity.UserErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get one page of the users ordered by username, optionally filtered by a part of the username or email, a part of the username only and by role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Users per page, at most 100",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Older name of pageSize, used when pageSize is not given",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of the username or email, case-insensitive",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of the username, case-insensitive",
+                        "name": "username",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "admin",
+                            "employee"
+                        ],
+                        "type": "string",
+                        "description": "Only users with this role",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "List deleted users too",
+                        "name": "include_inactive",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only users without a login for this many days, such as 30d, users that never logged in included",
+                        "name": "inactive_since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of users",
+                        "schema": {
+                            "$ref": "#/definitions/custom.UserPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page, pageSize, role, include_inactive or inactive_since",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/apierror.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apierror.Response": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "not_found"
+                },
+                "details": {},
+                "message": {
+                    "type": "string",
+                    "example": "merchant not found"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "4f1c2a9e-0b7d-4d3a-9d55-2f0f6a7c9e21"
+                }
+            }
+        },
+        "custom.ProductMarginResp": {
+            "type": "object",
+            "properties": {
+                "idProduct": {
+                    "type": "string"
+                },
+                "margin": {
+                    "type": "number"
+                },
+                "nameProvider": {
+                    "type": "string"
+                },
+                "nominal": {
+                    "type": "number"
+                },
+                "totalCost": {
+                    "type": "number"
+                },
+                "totalRevenue": {
+                    "type": "number"
+                },
+                "unitsSold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "custom.ReceiptLine": {
+            "type": "object",
+            "properties": {
+                "nominal": {
+                    "type": "number",
+                    "example": 10000
+                },
+                "price": {
+                    "type": "number",
+                    "example": 10900
+                },
+                "product": {
+                    "type": "string",
+                    "example": "Telkomsel"
+                }
+            }
+        },
+        "custom.StatsOverview": {
+            "type": "object",
+            "properties": {
+                "merchants": {
+                    "type": "integer",
+                    "example": 35
+                },
+                "products": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "transactionsToday": {
+                    "type": "integer",
+                    "example": 210
+                },
+                "users": {
+                    "type": "integer",
+                    "example": 48
+                }
+            }
+        },
+        "custom.TransactionQuote": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "merchantId": {
+                    "type": "string",
+                    "example": "5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60"
+                },
+                "sufficient": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "totalNominal": {
+                    "type": "number",
+                    "example": 15000
+                }
+            }
+        },
+        "custom.TransactionReceipt": {
+            "type": "object",
+            "properties": {
+                "cashier": {
+                    "type": "string",
+                    "example": "john_doe"
+                },
+                "customerName": {
+                    "type": "string",
+                    "example": "customer a"
+                },
+                "date": {
+                    "type": "string",
+                    "example": "25-10-2024"
+                },
+                "destinationNumber": {
+                    "type": "string",
+                    "example": "08123456789"
+                },
+                "grandTotal": {
+                    "type": "number",
+                    "example": 16900
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/custom.ReceiptLine"
+                    }
+                },
+                "merchantAddress": {
+                    "type": "string",
+                    "example": "Jombang"
+                },
+                "merchantId": {
+                    "type": "string",
+                    "example": "5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60"
+                },
+                "merchantName": {
+                    "type": "string",
+                    "example": "Konter Pak Eko"
+                },
+                "time": {
+                    "type": "string",
+                    "example": "14:05:09"
+                },
+                "transactionId": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                }
+            }
+        },
+        "custom.UserListItem": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "email": {
+                    "type": "string",
+                    "example": "eko@example.com"
+                },
+                "id_user": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "last_login_at": {
+                    "description": "LastLoginAt is null for users that never logged in.",
+                    "type": "string",
+                    "example": "2024-07-01T08:30:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "eko"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "employee"
+                }
+            }
+        },
+        "custom.UserPage": {
+            "type": "object",
+            "properties": {
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/custom.UserListItem"
+                    }
+                }
+            }
+        },
+        "dto.AuthRegisterRes": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "Hashed Password"
+                }
+            }
+        },
+        "dto.AuthRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "secret123"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "dto.AuthResponse": {
+            "type": "object",
+            "properties": {
+                "challengeToken": {
+                    "type": "string",
+                    "example": "c3a1f09b7e2d4458..."
+                },
+                "refreshToken": {
+                    "type": "string",
+                    "example": "4f2d9c8e1b7a6035..."
+                },
+                "token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "twoFactorRequired": {
+                    "description": "TwoFactorRequired and ChallengeToken replace the tokens for accounts with two-factor authentication.",
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "dto.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid credentials"
+                }
+            }
+        },
+        "dto.ForgotPasswordRequest": {
+            "type": "object",
+            "required": [
+                "username"
+            ],
+            "properties": {
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "dto.LoginRequest": {
+            "type": "object",
+            "required": [
+                "identifier",
+                "password"
+            ],
+            "properties": {
+                "identifier": {
+                    "type": "string",
+                    "example": "john_doe or john@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "secret123"
+                },
+                "remember_me": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "dto.LogoutRequest": {
+            "type": "object",
+            "properties": {
+                "refreshToken": {
+                    "type": "string",
+                    "example": "4f2d9c8e1b7a6035..."
+                }
+            }
+        },
+        "dto.RefreshTokenRequest": {
+            "type": "object",
+            "required": [
+                "refreshToken"
+            ],
+            "properties": {
+                "refreshToken": {
+                    "type": "string",
+                    "example": "4f2d9c8e1b7a6035..."
+                }
+            }
+        },
+        "dto.ResetPasswordRequest": {
+            "type": "object",
+            "required": [
+                "newPassword",
+                "token"
+            ],
+            "properties": {
+                "newPassword": {
+                    "type": "string",
+                    "example": "n3wSecret123"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015..."
+                }
+            }
+        },
+        "dto.TwoFactorVerifyRequest": {
+            "type": "object",
+            "required": [
+                "challengeToken",
+                "code"
+            ],
+            "properties": {
+                "challengeToken": {
+                    "type": "string",
+                    "example": "c3a1f09b7e2d4458..."
+                },
+                "code": {
+                    "description": "Code is the current code of the authenticator app or an unused backup code.",
+                    "type": "string",
+                    "example": "287082"
+                }
+            }
+        },
+        "entity.AdminResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "newPassword": {
+                    "type": "string",
+                    "example": "n3wSecret123"
+                }
+            }
+        },
+        "entity.AdminResetPasswordResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Password reset, it must be changed at the next login"
+                },
+                "temporaryPassword": {
+                    "type": "string",
+                    "example": "k7QmZp2xVt9sRw4e"
+                }
+            }
+        },
+        "entity.AuditLog": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "merchant.balance_adjust"
+                },
+                "actorId": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2024-08-01T10:00:00Z"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "price 5500 instead of 6000: loyal customer"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "targetId": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                }
+            }
+        },
+        "entity.AuthEvent": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2024-08-01T10:00:00Z"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "login_failure"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "identifier": {
+                    "type": "string",
+                    "example": "john_doe"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "10.0.0.1"
+                },
+                "userAgent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0"
+                },
+                "userId": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                }
+            }
+        },
+        "entity.BalanceAdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "adjustments"
+            ],
+            "properties": {
+                "adjustments": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    },
+                    "example": {
+                        "5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60": 150000
+                    }
+                }
+            }
+        },
+        "entity.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "currentPassword",
+                "newPassword"
+            ],
+            "properties": {
+                "currentPassword": {
+                    "type": "string",
+                    "example": "secret123"
+                },
+                "newPassword": {
+                    "type": "string",
+                    "example": "n3wSecret123"
+                }
+            }
+        },
+        "entity.DbStats": {
+            "type": "object",
+            "properties": {
+                "idle": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "inUse": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "maxIdleClosed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "maxLifetimeClosed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "maxOpenConnections": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "openConnections": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "waitCount": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "waitDurationMs": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "entity.DependencyStatus": {
+            "type": "object",
+            "properties": {
+                "critical": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "latencyMs": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "name": {
+                    "type": "string",
+                    "example": "provider"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                }
+            }
+        },
+        "entity.Health": {
+            "type": "object",
+            "properties": {
+                "buildTime": {
+                    "type": "string",
+                    "example": "2024-11-02T08:15:00Z"
+                },
+                "commit": {
+                    "type": "string",
+                    "example": "3f2c1ab"
+                },
+                "db": {
+                    "type": "string",
+                    "example": "up"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                },
+                "uptime": {
+                    "type": "integer",
+                    "example": 3600
+                },
+                "version": {
+                    "type": "string",
+                    "example": "1.4.0"
+                }
+            }
+        },
+        "entity.Merchant": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "balance": {
+                    "type": "number"
+                },
+                "dailyLimitAmount": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "dailyLimitCount": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "idMerchant": {
+                    "type": "string"
+                },
+                "idProduct": {
+                    "type": "string"
+                },
+                "idUser": {
+                    "type": "string"
+                },
+                "lowBalanceThreshold": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "nameMerchant": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "entity.MerchantBalance": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "checkedAt": {
+                    "type": "string",
+                    "example": "2024-10-27T10:00:00Z"
+                },
+                "idMerchant": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                }
+            }
+        },
+        "entity.MerchantCatalogItem": {
+            "type": "object",
+            "properties": {
+                "idProduct": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "nameProvider": {
+                    "type": "string",
+                    "example": "Telkomsel"
+                },
+                "nominal": {
+                    "type": "number",
+                    "example": 10000
+                },
+                "price": {
+                    "type": "number",
+                    "example": 10500
+                },
+                "priceSource": {
+                    "type": "string",
+                    "example": "merchant"
+                }
+            }
+        },
+        "entity.MerchantOnboardDetail": {
+            "type": "object",
+            "required": [
+                "address",
+                "idProduct",
+                "nameMerchant"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Jombang"
+                },
+                "dailyLimitAmount": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 5000000
+                },
+                "dailyLimitCount": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 200
+                },
+                "idProduct": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "lowBalanceThreshold": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 50000
+                },
+                "nameMerchant": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Konter Pak Eko"
+                }
+            }
+        },
+        "entity.MerchantOnboardRequest": {
+            "type": "object",
+            "properties": {
+                "merchant": {
+                    "$ref": "#/definitions/entity.MerchantOnboardDetail"
+                },
+                "user": {
+                    "$ref": "#/definitions/entity.UserCreateRequest"
+                }
+            }
+        },
+        "entity.MerchantOnboarding": {
+            "type": "object",
+            "properties": {
+                "merchant": {
+                    "$ref": "#/definitions/entity.Merchant"
+                },
+                "user": {
+                    "$ref": "#/definitions/entity.UserResponse"
+                }
+            }
+        },
+        "entity.MerchantPriceRequest": {
+            "type": "object",
+            "required": [
+                "price"
+            ],
+            "properties": {
+                "price": {
+                    "type": "number",
+                    "example": 10500
+                }
+            }
+        },
+        "entity.MerchantProductPrice": {
+            "type": "object",
+            "properties": {
+                "idMerchant": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idProduct": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "price": {
+                    "type": "number",
+                    "example": 10500
+                }
+            }
+        },
+        "entity.MerchantRequest": {
+            "type": "object",
+            "required": [
+                "address",
+                "idProduct",
+                "idUser",
+                "nameMerchant"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Jombang"
+                },
+                "dailyLimitAmount": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 5000000
+                },
+                "dailyLimitCount": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 200
+                },
+                "idProduct": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idUser": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "lowBalanceThreshold": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 50000
+                },
+                "nameMerchant": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Konter Pak Eko"
+                }
+            }
+        },
+        "entity.MerchantResponse": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "example": "Jombang"
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "dailyLimitAmount": {
+                    "type": "number",
+                    "example": 5000000
+                },
+                "dailyLimitCount": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "idMerchant": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idProduct": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idUser": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "lowBalanceThreshold": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "nameMerchant": {
+                    "type": "string",
+                    "example": "Toko Pak Eko"
+                }
+            }
+        },
+        "entity.MerchantUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Jombang"
+                },
+                "dailyLimitAmount": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 5000000
+                },
+                "dailyLimitCount": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
+                "idProduct": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idUser": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "lowBalanceThreshold": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 50000
+                },
+                "nameMerchant": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Konter Pak Eko"
+                }
+            }
+        },
+        "entity.Product": {
+            "type": "object",
+            "required": [
+                "idSupliyer",
+                "nominal",
+                "price"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "idMerchant": {
+                    "description": "IdMerchant is empty for global products, otherwise only that merchant sees and sells the product.",
+                    "type": "string"
+                },
+                "idProduct": {
+                    "type": "string"
+                },
+                "idProvider": {
+                    "type": "string"
+                },
+                "idSupliyer": {
+                    "type": "string"
+                },
+                "nameProvider": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "nominal": {
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "entity.ProductBatch": {
+            "type": "object",
+            "properties": {
+                "missing": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "products": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Product"
+                    }
+                }
+            }
+        },
+        "entity.ProductBatchRequest": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "eyJhbGciOiJIUzI1NiIs..."
+                    ]
+                }
+            }
+        },
+        "entity.ProductRequest": {
+            "type": "object",
+            "required": [
+                "idSupliyer",
+                "nominal",
+                "price"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "TSEL10"
+                },
+                "idMerchant": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idProvider": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idSupliyer": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "nameProvider": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Indosat"
+                },
+                "nominal": {
+                    "type": "number",
+                    "example": 5000
+                },
+                "price": {
+                    "type": "number",
+                    "example": 6000
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                }
+            }
+        },
+        "entity.ProductResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "TSEL10"
+                },
+                "idMerchant": {
+                    "type": "string",
+                    "example": ""
+                },
+                "idProduct": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idProvider": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "idSupliyer": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "nameProvider": {
+                    "type": "string",
+                    "example": "Indosat"
+                },
+                "nominal": {
+                    "type": "number",
+                    "example": 5000
+                },
+                "price": {
+                    "type": "number",
+                    "example": 6000
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
                 }
             }
         },
-        "dto.AuthRequest": {
+        "entity.ProductSyncItem": {
             "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
             "properties": {
-                "password": {
-                    "type": "string",
-                    "example": "secret123"
+                "code": {
+                    "type": "string"
                 },
-                "username": {
-                    "type": "string",
-                    "example": "john_doe"
+                "idProduct": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
                 }
             }
         },
-        "dto.AuthResponse": {
+        "entity.ProductSyncReport": {
             "type": "object",
             "properties": {
-                "token": {
-                    "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.ProductSyncItem"
+                    }
+                },
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.ProductSyncItem"
+                    }
+                },
+                "unchanged": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.ProductSyncItem"
+                    }
+                },
+                "updated": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.ProductSyncItem"
+                    }
                 }
             }
         },
-        "dto.ErrorResponse": {
+        "entity.ProviderRequest": {
             "type": "object",
+            "required": [
+                "nameProvider"
+            ],
             "properties": {
-                "error": {
+                "nameProvider": {
                     "type": "string",
-                    "example": "Invalid credentials"
+                    "example": "Indosat"
                 }
             }
         },
-        "entity.MerchantErrorResponse": {
+        "entity.ProviderResponse": {
             "type": "object",
             "properties": {
-                "error": {
+                "idProvider": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "nameProvider": {
                     "type": "string",
-                    "example": "Invalid merchant"
+                    "example": "Indosat"
                 }
             }
         },
-        "entity.MerchantRequest": {
+        "entity.PublicProduct": {
             "type": "object",
-            "required": [
-                "address",
-                "idProduct",
-                "idUser",
-                "nameMerchant"
-            ],
             "properties": {
-                "address": {
+                "category": {
                     "type": "string",
-                    "example": "Jombang"
+                    "example": "pulsa"
                 },
-                "idProduct": {
+                "code": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                    "example": "TSEL10"
                 },
-                "idUser": {
-                    "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                "nominal": {
+                    "type": "number",
+                    "example": 10000
                 },
-                "nameMerchant": {
+                "price": {
+                    "type": "number",
+                    "example": 10900
+                },
+                "provider": {
                     "type": "string",
-                    "example": "Konter Pak Eko"
+                    "example": "Telkomsel"
                 }
             }
         },
-        "entity.MerchantResponse": {
+        "entity.Readiness": {
             "type": "object",
             "properties": {
-                "address": {
-                    "type": "string",
-                    "example": "Jombang"
-                },
-                "balance": {
-                    "type": "number",
-                    "example": 500000
-                },
-                "idMerchant": {
-                    "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIs..."
-                },
-                "idProduct": {
-                    "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                "dependencies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.DependencyStatus"
+                    }
                 },
-                "idUser": {
-                    "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                "draining": {
+                    "type": "boolean",
+                    "example": false
                 },
-                "nameMerchant": {
-                    "type": "string",
-                    "example": "Toko Pak Eko"
+                "ready": {
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
-        "entity.ProductErrorResponse": {
+        "entity.RolePermissions": {
             "type": "object",
             "properties": {
-                "error": {
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "product:write"
+                    ]
+                },
+                "role": {
                     "type": "string",
-                    "example": "Invalid product"
+                    "example": "employee"
                 }
             }
         },
-        "entity.ProductRequest": {
+        "entity.RolePermissionsRequest": {
             "type": "object",
             "required": [
-                "idSupliyer",
-                "nameProvider",
-                "nominal",
-                "price"
+                "permissions"
             ],
             "properties": {
-                "idSupliyer": {
-                    "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIs..."
-                },
-                "nameProvider": {
-                    "type": "string",
-                    "example": "Indosat"
-                },
-                "nominal": {
-                    "type": "number",
-                    "example": 5000
-                },
-                "price": {
-                    "type": "number",
-                    "example": 6000
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "product:write"
+                    ]
                 }
             }
         },
-        "entity.ProductResponse": {
+        "entity.Session": {
             "type": "object",
             "properties": {
-                "idProduct": {
+                "current": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "id": {
                     "type": "string",
                     "example": "eyJhbGciOiJIUzI1NiIs..."
                 },
-                "idSupliyer": {
+                "ip": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                    "example": "10.0.0.1"
                 },
-                "nameProvider": {
+                "issuedAt": {
                     "type": "string",
-                    "example": "Indosat"
+                    "example": "2024-08-01T10:00:00Z"
                 },
-                "nominal": {
-                    "type": "number",
-                    "example": 5000
+                "lastUsedAt": {
+                    "type": "string",
+                    "example": "2024-08-01T12:00:00Z"
                 },
-                "price": {
-                    "type": "number",
-                    "example": 6000
+                "rememberMe": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "userAgent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0"
                 }
             }
         },
         "entity.TransactionDetail": {
             "type": "object",
+            "required": [
+                "productId"
+            ],
             "properties": {
                 "Price": {
                     "type": "number"
                 },
+                "nominal": {
+                    "type": "number"
+                },
+                "overridePrice": {
+                    "type": "number"
+                },
+                "overrideReason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "priceSource": {
+                    "type": "string"
+                },
                 "productId": {
                     "type": "string"
                 },
@@ -1185,18 +4664,50 @@ const docTemplate = `{
                 "productId"
             ],
             "properties": {
+                "overridePrice": {
+                    "type": "number",
+                    "example": 5500
+                },
+                "overrideReason": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "loyal customer"
+                },
                 "productId": {
                     "type": "string",
                     "example": "eyJhbGciOiJIUzI1NiIs..."
                 }
             }
         },
-        "entity.TransactionErrorResponse": {
+        "entity.TransactionQuoteLine": {
             "type": "object",
+            "required": [
+                "productId"
+            ],
             "properties": {
-                "error": {
+                "productId": {
                     "type": "string",
-                    "example": "Invalid transaction"
+                    "example": "8d3e1f2a-6b7c-4d5e-8f90-a1b2c3d4e5f6"
+                }
+            }
+        },
+        "entity.TransactionQuoteReq": {
+            "type": "object",
+            "required": [
+                "merchantId",
+                "transactionDetail"
+            ],
+            "properties": {
+                "merchantId": {
+                    "type": "string",
+                    "example": "5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60"
+                },
+                "transactionDetail": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/entity.TransactionQuoteLine"
+                    }
                 }
             }
         },
@@ -1206,18 +4717,19 @@ const docTemplate = `{
                 "customerName",
                 "destinationNumber",
                 "merchantId",
-                "transactionDate",
                 "transactionDetail",
                 "userId"
             ],
             "properties": {
                 "customerName": {
                     "type": "string",
+                    "maxLength": 255,
                     "example": "customer a"
                 },
                 "destinationNumber": {
                     "type": "string",
-                    "example": "08...."
+                    "minLength": 8,
+                    "example": "081234567890"
                 },
                 "merchantId": {
                     "type": "string",
@@ -1229,6 +4741,7 @@ const docTemplate = `{
                 },
                 "transactionDetail": {
                     "type": "array",
+                    "minItems": 1,
                     "items": {
                         "$ref": "#/definitions/entity.TransactionDetailReq"
                     }
@@ -1241,21 +4754,40 @@ const docTemplate = `{
         },
         "entity.Transactions": {
             "type": "object",
+            "required": [
+                "customerName",
+                "destinationNumber",
+                "merchantId",
+                "transactionDetail",
+                "userId"
+            ],
             "properties": {
-                "customerName": {
+                "balance": {
+                    "type": "number"
+                },
+                "createdAt": {
                     "type": "string"
                 },
+                "customerName": {
+                    "type": "string",
+                    "maxLength": 255
+                },
                 "destinationNumber": {
-                    "type": "string"
+                    "type": "string",
+                    "minLength": 8
                 },
                 "merchantId": {
                     "type": "string"
                 },
+                "status": {
+                    "type": "string"
+                },
                 "transactionDate": {
                     "type": "string"
                 },
                 "transactionDetail": {
                     "type": "array",
+                    "minItems": 1,
                     "items": {
                         "$ref": "#/definitions/entity.TransactionDetail"
                     }
@@ -1263,25 +4795,80 @@ const docTemplate = `{
                 "transactionId": {
                     "type": "string"
                 },
+                "updatedAt": {
+                    "type": "string"
+                },
                 "userId": {
                     "type": "string"
                 }
             }
         },
-        "entity.User": {
+        "entity.TwoFactorBackupCodes": {
             "type": "object",
             "properties": {
-                "id_user": {
-                    "type": "string"
+                "backupCodes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "4f2d9-c8e1b",
+                        "7a603-5d2e4"
+                    ]
+                }
+            }
+        },
+        "entity.TwoFactorCodeRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "287082"
+                }
+            }
+        },
+        "entity.TwoFactorSetup": {
+            "type": "object",
+            "properties": {
+                "otpauthUrl": {
+                    "type": "string",
+                    "example": "otpauth://totp/Server%20Pulsa:budi?secret=JBSWY3DPEHPK3PXP\u0026issuer=Server+Pulsa"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                }
+            }
+        },
+        "entity.UserCreateRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "password",
+                "role"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "eko@example.com"
                 },
                 "name": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 3,
+                    "example": "eko"
                 },
                 "password": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "secret123"
                 },
                 "role": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "employee"
                 }
             }
         },
@@ -1294,9 +4881,39 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.UserProfile": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "eko@example.com"
+                },
+                "id_user": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIs..."
+                },
+                "merchants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.Merchant"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "eko"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "employee"
+                }
+            }
+        },
         "entity.UserReqUpdate": {
             "type": "object",
             "properties": {
+                "email": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
                 },
@@ -1311,6 +4928,9 @@ const docTemplate = `{
         "entity.UserResponse": {
             "type": "object",
             "properties": {
+                "email": {
+                    "type": "string"
+                },
                 "id_user": {
                     "type": "string"
                 },
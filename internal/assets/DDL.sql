@@ -10,19 +10,132 @@ CREATE TABLE mst_supliyer(
     balance DOUBLE PRECISION NOT NULL
 );
 
+CREATE TABLE mst_provider(
+    id_provider uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name_provider VARCHAR(255) NOT NULL UNIQUE
+);
+
 CREATE TABLE mst_product(
     id_product uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
-    name_provider VARCHAR(255) NOT NULL,
+    id_provider uuid NOT NULL REFERENCES mst_provider(id_provider),
     nominal DOUBLE PRECISION NOT NULL,
-    price DECIMAL(10, 2) NOT NULL
-    id_supliyer uuid REFERENCES mst_supliyer(id_supliyer)
+    price DECIMAL(10, 2) NOT NULL,
+    id_supliyer uuid REFERENCES mst_supliyer(id_supliyer),
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    code VARCHAR(64) UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    category VARCHAR(50) NOT NULL DEFAULT 'pulsa',
+    -- products with an id_merchant are private to that merchant, the others make up the global catalog
+    id_merchant uuid
+);
+
+CREATE TABLE product_price_history(
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    id_product uuid REFERENCES mst_product(id_product),
+    old_nominal DOUBLE PRECISION NOT NULL,
+    old_price DECIMAL(10, 2) NOT NULL,
+    new_nominal DOUBLE PRECISION NOT NULL,
+    new_price DECIMAL(10, 2) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
 CREATE TABLE mst_user(
     id_user uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
     username VARCHAR(255) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
-    role roles NOT NULL
+    role roles NOT NULL,
+    email VARCHAR(255),
+    deleted_at TIMESTAMP,
+    last_login_at TIMESTAMP,
+    -- set when an admin reset the password, the user can only change it until they do
+    must_change_password BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- usernames are stored lowercase, the index also covers rows saved before that and is checked for duplicates at startup
+CREATE UNIQUE INDEX mst_user_username_lower_idx ON mst_user (LOWER(username));
+-- same for emails, users without an email are not part of the index
+CREATE UNIQUE INDEX mst_user_email_lower_idx ON mst_user (LOWER(email)) WHERE email IS NOT NULL;
+
+-- permissions granted to each role, written into the access tokens. The seed is what the roles could do before
+-- permissions existed, employees have none of them by default
+CREATE TABLE role_permission(
+    role roles NOT NULL,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role_permission (role, permission) VALUES
+    ('admin', 'product:write'),
+    ('admin', 'provider:write'),
+    ('admin', 'user:read'),
+    ('admin', 'user:write'),
+    ('admin', 'transaction:price_override');
+
+CREATE TABLE password_reset_token(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    id_user uuid NOT NULL REFERENCES mst_user(id_user) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+-- one row per login, access tokens carry the id_session so a revoked session is rejected right away
+CREATE TABLE user_session(
+    id_session uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    id_user uuid NOT NULL REFERENCES mst_user(id_user) ON DELETE CASCADE,
+    ip VARCHAR(45),
+    user_agent TEXT,
+    issued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP,
+    remember_me BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX user_session_user_idx ON user_session (id_user);
+
+-- refresh tokens are rotated on every use, all tokens issued from one login share a family_id, the id_session of the login
+CREATE TABLE refresh_token(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    id_user uuid NOT NULL REFERENCES mst_user(id_user) ON DELETE CASCADE,
+    family_id uuid NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX refresh_token_family_idx ON refresh_token (family_id);
+
+-- TOTP secrets, enabled_at stays NULL from the setup until a first code was verified. last_used_step keeps a code
+-- from being used twice
+CREATE TABLE user_totp(
+    id_user uuid PRIMARY KEY REFERENCES mst_user(id_user) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0
+);
+
+-- single use codes for a lost authenticator, generated again on every enablement
+CREATE TABLE totp_backup_code(
+    id_user uuid NOT NULL REFERENCES mst_user(id_user) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    PRIMARY KEY (id_user, code_hash)
+);
+
+-- handed out by the login of a user with 2FA, exchanged for the real tokens at /auth/2fa/verify
+CREATE TABLE login_challenge(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    id_user uuid NOT NULL REFERENCES mst_user(id_user) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    remember_me BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- logged out access tokens, rows can be deleted once expires_at has passed
+CREATE TABLE revoked_token(
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
 );
 
 CREATE TABLE mst_merchant(
@@ -31,7 +144,31 @@ CREATE TABLE mst_merchant(
     name_merchant VARCHAR(255) NOT NULL,
     address VARCHAR(255) NOT NULL,
     id_product uuid REFERENCES mst_product(id_product),
-    balance DOUBLE PRECISION
+    balance DOUBLE PRECISION,
+    low_balance_threshold DOUBLE PRECISION NOT NULL DEFAULT 0,
+    -- caps on the transactions of one day, 0 is no limit
+    daily_limit_count INT NOT NULL DEFAULT 0,
+    daily_limit_amount DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+-- mst_merchant references mst_product too, so the key of a private product is added once both tables exist
+ALTER TABLE mst_product ADD CONSTRAINT mst_product_id_merchant_fkey FOREIGN KEY (id_merchant) REFERENCES mst_merchant(id_merchant);
+
+CREATE TABLE merchant_balance_ledger(
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    id_merchant uuid REFERENCES mst_merchant(id_merchant),
+    amount DOUBLE PRECISION NOT NULL,
+    balance_after DOUBLE PRECISION NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE merchant_product_price(
+    id_merchant uuid NOT NULL REFERENCES mst_merchant(id_merchant) ON DELETE CASCADE,
+    id_product uuid NOT NULL REFERENCES mst_product(id_product) ON DELETE CASCADE,
+    price DECIMAL(10, 2) NOT NULL CHECK (price > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id_merchant, id_product)
 );
 
 CREATE TABLE transactions(
@@ -40,14 +177,27 @@ CREATE TABLE transactions(
     id_user UUID REFERENCES mst_user(id_user),
     customer_name VARCHAR(255) NOT NULL,
     destination_number VARCHAR(15) NOT NULL,
-    transaction_date DATE
+    -- transaction_date is the business date sent by the client, created_at is when the row was really inserted
+    transaction_date DATE,
+    -- pending, success or failed
+    status VARCHAR(20) NOT NULL DEFAULT 'success',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
+-- serves the history pages, a cursor seeks straight to the last transaction of the previous page
+CREATE INDEX transactions_merchant_date_idx ON transactions (id_merchant, transaction_date, transaction_id);
+
 CREATE TABLE transaction_detail(
     transaction_detail_id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
     transaction_id UUID REFERENCES transactions(transaction_id),
     id_product UUID REFERENCES mst_product(id_product),
-    price DECIMAL(10, 2) NOT NULL
+    -- nominal and price are copied from the product at purchase, later product changes do not touch them
+    nominal DECIMAL(10, 2) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    price_source VARCHAR(20) NOT NULL DEFAULT 'catalog',
+    -- set once the nominal of this line went back to the merchant balance, a line is refunded at most once
+    refunded_at TIMESTAMP
 );
 
 CREATE TABLE tx_topup (
@@ -60,3 +210,30 @@ CREATE TABLE tx_topup (
     status VARCHAR(255),
     created_at TIMESTAMP DEFAULT NOW()
 );
+
+-- sensitive admin actions, target_id holds the id of the changed merchant, user, product or transaction detail,
+-- detail what the action cannot tell on its own such as the reason of a price override
+CREATE TABLE audit_log(
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    actor_id uuid NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at DESC);
+
+-- logins, logouts, password changes and lockouts, user_id is NULL when the login identifier matched no user
+CREATE TABLE auth_events(
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id uuid,
+    event VARCHAR(30) NOT NULL,
+    identifier VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX auth_events_user_created_at_idx ON auth_events (user_id, created_at DESC);
+CREATE INDEX auth_events_created_at_idx ON auth_events (created_at DESC);
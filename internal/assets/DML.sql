@@ -1,8 +1,13 @@
 INSERT INTO mst_supliyer (id_supliyer, name_supliyer, balance) VALUES
 (uuid_generate_v4(), 'All Operator', 1000000);
 
-INSERT INTO mst_product (id_product, name_provider, nominal, price, id_supliyer) VALUES
-(uuid_generate_v4(), 'Indosat', 10000, 10500, 'supliyer_uuid_value_here'),
-(uuid_generate_v4(), 'Telkomsel', 10000, 10900, 'supliyer_uuid_value_here'),
-(uuid_generate_v4(), 'Xl', 10000, 10700, 'supliyer_uuid_value_here'),
-(uuid_generate_v4(), 'Tri', 10000, 10400, 'supliyer_uuid_value_here');
\ No newline at end of file
+INSERT INTO mst_provider (id_provider, name_provider) VALUES
+(uuid_generate_v4(), 'Indosat'),
+(uuid_generate_v4(), 'Telkomsel'),
+(uuid_generate_v4(), 'Xl'),
+(uuid_generate_v4(), 'Tri');
+
+INSERT INTO mst_product (id_product, id_provider, nominal, price, id_supliyer)
+SELECT uuid_generate_v4(), pv.id_provider, 10000, v.price, 'supliyer_uuid_value_here'
+FROM (VALUES ('Indosat', 10500), ('Telkomsel', 10900), ('Xl', 10700), ('Tri', 10400)) AS v(name_provider, price)
+JOIN mst_provider pv ON pv.name_provider = v.name_provider;
\ No newline at end of file
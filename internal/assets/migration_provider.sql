@@ -0,0 +1,22 @@
+-- Move the free-text name_provider of mst_product into its own mst_provider table.
+-- Providers that only differ by case or surrounding spaces are merged into one.
+
+CREATE TABLE mst_provider(
+    id_provider uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name_provider VARCHAR(255) NOT NULL UNIQUE
+);
+
+INSERT INTO mst_provider (name_provider)
+SELECT DISTINCT ON (LOWER(TRIM(name_provider))) TRIM(name_provider)
+FROM mst_product
+ORDER BY LOWER(TRIM(name_provider)), name_provider;
+
+ALTER TABLE mst_product ADD COLUMN id_provider uuid REFERENCES mst_provider(id_provider);
+
+UPDATE mst_product p
+SET id_provider = pv.id_provider
+FROM mst_provider pv
+WHERE LOWER(pv.name_provider) = LOWER(TRIM(p.name_provider));
+
+ALTER TABLE mst_product ALTER COLUMN id_provider SET NOT NULL;
+ALTER TABLE mst_product DROP COLUMN name_provider;
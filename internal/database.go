@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"server-pulsa-app/config"
+	"time"
+)
+
+// databasePingTimeout bounds a single startup ping, a host that drops the packets would otherwise hang it.
+const databasePingTimeout = 5 * time.Second
+
+// pinger is satisfied by *sql.DB.
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// openDatabase opens the pool of cfg and pings it, so a wrong address or password stops the server at startup
+// instead of failing its first request.
+func openDatabase(cfg config.DBConfig) (*sql.DB, error) {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name)
+
+	db, err := sql.Open(cfg.Driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open the database: %w", err)
+	}
+	if err := pingDatabase(db, cfg.ConnectRetries, cfg.ConnectBackoff); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// pingDatabase pings db until it answers, retrying retries times with a backoff that doubles after each attempt.
+// The database often starts together with the server and takes a few seconds to accept connections.
+func pingDatabase(db pinger, retries int, backoff time.Duration) error {
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), databasePingTimeout)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return fmt.Errorf("database unreachable after %d attempts: %w", attempt+1, err)
+		}
+
+		log.Error("Database not reachable yet, retrying in "+backoff.String(), err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,72 @@
+package entity
+
+import "time"
+
+// Audited admin actions.
+const (
+	AuditActionBalanceAdjust   = "merchant.balance_adjust"
+	AuditActionUserRole        = "user.role_change"
+	AuditActionProductDelete   = "product.delete"
+	AuditActionUserSessions    = "user.sessions_revoke"
+	AuditActionRolePermissions = "role.permissions_change"
+	AuditActionUserPassword    = "user.password_reset"
+	AuditActionPriceOverride   = "transaction.price_override"
+)
+
+type (
+	// AuditLog records who performed a sensitive admin action on which record, Detail is only set by the actions
+	// that need more, such as the prices and the reason of a price override.
+	AuditLog struct {
+		Id        string    `json:"id" example:"eyJhbGciOiJIUzI1NiIs..."`
+		ActorId   string    `json:"actorId" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Action    string    `json:"action" example:"merchant.balance_adjust"`
+		TargetId  string    `json:"targetId" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Detail    string    `json:"detail,omitempty" example:"price 5500 instead of 6000: loyal customer"`
+		CreatedAt time.Time `json:"createdAt" example:"2024-08-01T10:00:00Z"`
+	}
+
+	// AuditQuery holds the pagination of the audit log listing, newest entries come first.
+	AuditQuery struct {
+		Page  int
+		Limit int
+	}
+)
+
+// Recorded authentication events.
+const (
+	AuthEventLoginSuccess   = "login_success"
+	AuthEventLoginFailure   = "login_failure"
+	AuthEventLogout         = "logout"
+	AuthEventPasswordChange = "password_change"
+	AuthEventLockout        = "lockout"
+)
+
+type (
+	// ClientInfo is where a request came from, it is recorded with every authentication event.
+	ClientInfo struct {
+		Ip        string
+		UserAgent string
+	}
+
+	// AuthEvent records a login, logout, password change or lockout. UserId is empty when the identifier
+	// did not belong to any user.
+	AuthEvent struct {
+		Id         string    `json:"id" example:"eyJhbGciOiJIUzI1NiIs..."`
+		UserId     string    `json:"userId,omitempty" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Event      string    `json:"event" example:"login_failure"`
+		Identifier string    `json:"identifier,omitempty" example:"john_doe"`
+		Ip         string    `json:"ip" example:"10.0.0.1"`
+		UserAgent  string    `json:"userAgent" example:"Mozilla/5.0"`
+		CreatedAt  time.Time `json:"createdAt" example:"2024-08-01T10:00:00Z"`
+	}
+
+	// AuthEventQuery filters the authentication events, empty fields are not filtered on. The dates are YYYY-MM-DD
+	// and both days are included.
+	AuthEventQuery struct {
+		UserId    string
+		StartDate string
+		EndDate   string
+		Page      int
+		Limit     int
+	}
+)
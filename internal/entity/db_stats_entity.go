@@ -0,0 +1,14 @@
+package entity
+
+// DbStats is a snapshot of the database connection pool, WaitDurationMs is the total time spent waiting for a
+// connection since the server started.
+type DbStats struct {
+	MaxOpenConnections int   `json:"maxOpenConnections" example:"0"`
+	OpenConnections    int   `json:"openConnections" example:"4"`
+	InUse              int   `json:"inUse" example:"1"`
+	Idle               int   `json:"idle" example:"3"`
+	WaitCount          int64 `json:"waitCount" example:"0"`
+	WaitDurationMs     int64 `json:"waitDurationMs" example:"0"`
+	MaxIdleClosed      int64 `json:"maxIdleClosed" example:"0"`
+	MaxLifetimeClosed  int64 `json:"maxLifetimeClosed" example:"0"`
+}
@@ -1,28 +1,112 @@
 package dto
 
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
 type AuthRequestDto struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" binding:"required,min=3,max=255"`
+	Password string `json:"password" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email,max=255"`
+}
+
+// LoginRequestDto takes either a username or an email in Identifier. RememberMe asks for the longer token lifetime.
+type LoginRequestDto struct {
+	Identifier string `json:"identifier" binding:"required,max=255"`
+	Password   string `json:"password" binding:"required"`
+	RememberMe bool   `json:"remember_me"`
+}
+
+// UnmarshalJSON still accepts the "username" field of clients that do not send "identifier" yet. It decodes on its
+// own, so it rejects unknown fields itself when the binding does.
+func (l *LoginRequestDto) UnmarshalJSON(data []byte) error {
+	type loginRequest LoginRequestDto
+	var body struct {
+		loginRequest
+		Username string `json:"username"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if binding.EnableDecoderDisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&body); err != nil {
+		return err
+	}
+
+	*l = LoginRequestDto(body.loginRequest)
+	if l.Identifier == "" {
+		l.Identifier = body.Username
+	}
+	return nil
 }
 
+// AuthResponseDto has no tokens yet when TwoFactorRequired, the ChallengeToken is exchanged for them together with
+// a code at /auth/2fa/verify.
 type AuthResponseDto struct {
-	Token string `json:"token"`
+	Token             string `json:"token"`
+	RefreshToken      string `json:"refreshToken,omitempty"`
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+	// MustChangePassword tells the client to ask for a new password first, the token is only accepted to change it.
+	MustChangePassword bool `json:"mustChangePassword,omitempty"`
+}
+
+type TwoFactorVerifyRequestDto struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
 }
 
 type (
 	AuthRequest struct {
 		Username string `json:"username" binding:"required" example:"john_doe"`
 		Password string `json:"password" binding:"required" example:"secret123"`
+		Email    string `json:"email" example:"john@example.com"`
+	}
+
+	LoginRequest struct {
+		Identifier string `json:"identifier" binding:"required" example:"john_doe or john@example.com"`
+		Password   string `json:"password" binding:"required" example:"secret123"`
+		RememberMe bool   `json:"remember_me" example:"false"`
 	}
 
 	AuthResponse struct {
-		Token string `json:"token" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Token        string `json:"token" example:"eyJhbGciOiJIUzI1NiIs..."`
+		RefreshToken string `json:"refreshToken" example:"4f2d9c8e1b7a6035..."`
+		// TwoFactorRequired and ChallengeToken replace the tokens for accounts with two-factor authentication.
+		TwoFactorRequired bool   `json:"twoFactorRequired" example:"false"`
+		ChallengeToken    string `json:"challengeToken" example:"c3a1f09b7e2d4458..."`
+	}
+
+	TwoFactorVerifyRequest struct {
+		ChallengeToken string `json:"challengeToken" binding:"required" example:"c3a1f09b7e2d4458..."`
+		// Code is the current code of the authenticator app or an unused backup code.
+		Code string `json:"code" binding:"required" example:"287082"`
+	}
+
+	RefreshTokenRequest struct {
+		RefreshToken string `json:"refreshToken" binding:"required" example:"4f2d9c8e1b7a6035..."`
+	}
+
+	LogoutRequest struct {
+		RefreshToken string `json:"refreshToken" example:"4f2d9c8e1b7a6035..."`
 	}
 
 	AuthRegisterRes struct {
 		Password string `json:"password" example:"Hashed Password"`
 	}
 
+	ForgotPasswordRequest struct {
+		Username string `json:"username" binding:"required" example:"john_doe"`
+	}
+
+	ResetPasswordRequest struct {
+		Token       string `json:"token" binding:"required" example:"9f86d081884c7d659a2feaa0c55ad015..."`
+		NewPassword string `json:"newPassword" binding:"required" example:"n3wSecret123"`
+	}
+
 	ErrorResponse struct {
 		Error string `json:"error" example:"Invalid credentials"`
 	}
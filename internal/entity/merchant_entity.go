@@ -1,32 +1,105 @@
 package entity
 
+import "time"
+
 type (
+	// Merchant caps its transactions per day with DailyLimitCount transactions and DailyLimitAmount of nominal,
+	// zero means no limit.
 	Merchant struct {
-		IdMerchant   string  `json:"idMerchant"`
-		IdUser       string  `json:"idUser"`
-		NameMerchant string  `json:"nameMerchant"`
-		Address      string  `json:"address"`
-		IdProduct    string  `json:"idProduct"`
-		Balance      float64 `json:"balance"`
+		IdMerchant          string  `db:"id_merchant" json:"idMerchant"`
+		IdUser              string  `db:"id_user" json:"idUser"`
+		NameMerchant        string  `db:"name_merchant" json:"nameMerchant" binding:"max=255"`
+		Address             string  `db:"address" json:"address" binding:"max=255"`
+		IdProduct           string  `db:"id_product" json:"idProduct"`
+		Balance             float64 `db:"balance" json:"balance"`
+		LowBalanceThreshold float64 `db:"low_balance_threshold" json:"lowBalanceThreshold" binding:"gte=0"`
+		DailyLimitCount     int     `db:"daily_limit_count" json:"dailyLimitCount" binding:"gte=0"`
+		DailyLimitAmount    float64 `db:"daily_limit_amount" json:"dailyLimitAmount" binding:"gte=0"`
 	}
 
 	MerchantRequest struct {
-		IdUser       string `json:"idUser" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
-		NameMerchant string `json:"nameMerchant" binding:"required" example:"Konter Pak Eko"`
-		Address      string `json:"address" binding:"required" example:"Jombang"`
-		IdProduct    string `json:"idProduct" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+		IdUser              string  `json:"idUser" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+		NameMerchant        string  `json:"nameMerchant" binding:"required,max=255" example:"Konter Pak Eko"`
+		Address             string  `json:"address" binding:"required,max=255" example:"Jombang"`
+		IdProduct           string  `json:"idProduct" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+		LowBalanceThreshold float64 `json:"lowBalanceThreshold" binding:"gte=0" example:"50000"`
+		DailyLimitCount     int     `json:"dailyLimitCount" binding:"gte=0" example:"200"`
+		DailyLimitAmount    float64 `json:"dailyLimitAmount" binding:"gte=0" example:"5000000"`
+	}
+
+	// MerchantUpdateRequest changes only what it sets, an empty string keeps the field. The threshold and the limits
+	// are pointers, so a zero that removes them is told apart from leaving them out.
+	MerchantUpdateRequest struct {
+		IdUser              string   `json:"idUser" example:"eyJhbGciOiJIUzI1NiIs..."`
+		NameMerchant        string   `json:"nameMerchant" binding:"max=255" example:"Konter Pak Eko"`
+		Address             string   `json:"address" binding:"max=255" example:"Jombang"`
+		IdProduct           string   `json:"idProduct" example:"eyJhbGciOiJIUzI1NiIs..."`
+		LowBalanceThreshold *float64 `json:"lowBalanceThreshold" binding:"omitempty,gte=0" example:"50000"`
+		DailyLimitCount     *int     `json:"dailyLimitCount" binding:"omitempty,gte=0" example:"0"`
+		DailyLimitAmount    *float64 `json:"dailyLimitAmount" binding:"omitempty,gte=0" example:"5000000"`
+	}
+
+	// MerchantOnboardRequest registers a merchant owner and their merchant at once, the merchant belongs to the new
+	// user so it has no idUser of its own.
+	MerchantOnboardRequest struct {
+		User     UserCreateRequest     `json:"user"`
+		Merchant MerchantOnboardDetail `json:"merchant"`
+	}
+
+	MerchantOnboardDetail struct {
+		NameMerchant        string  `json:"nameMerchant" binding:"required,max=255" example:"Konter Pak Eko"`
+		Address             string  `json:"address" binding:"required,max=255" example:"Jombang"`
+		IdProduct           string  `json:"idProduct" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+		LowBalanceThreshold float64 `json:"lowBalanceThreshold" binding:"gte=0" example:"50000"`
+		DailyLimitCount     int     `json:"dailyLimitCount" binding:"gte=0" example:"200"`
+		DailyLimitAmount    float64 `json:"dailyLimitAmount" binding:"gte=0" example:"5000000"`
+	}
+
+	// MerchantOnboarding is the user and the merchant created by an onboarding, the password is left out.
+	MerchantOnboarding struct {
+		User     UserResponse `json:"user"`
+		Merchant Merchant     `json:"merchant"`
 	}
 
 	MerchantResponse struct {
-		IdMerchant   string  `json:"idMerchant" example:"eyJhbGciOiJIUzI1NiIs..."`
-		IdUser       string  `json:"idUser" example:"eyJhbGciOiJIUzI1NiIs..."`
-		NameMerchant string  `json:"nameMerchant" example:"Toko Pak Eko"`
-		Address      string  `json:"address" example:"Jombang"`
-		IdProduct    string  `json:"idProduct" example:"eyJhbGciOiJIUzI1NiIs..."`
-		Balance      float64 `json:"balance" example:"500000"`
+		IdMerchant          string  `json:"idMerchant" example:"eyJhbGciOiJIUzI1NiIs..."`
+		IdUser              string  `json:"idUser" example:"eyJhbGciOiJIUzI1NiIs..."`
+		NameMerchant        string  `json:"nameMerchant" example:"Toko Pak Eko"`
+		Address             string  `json:"address" example:"Jombang"`
+		IdProduct           string  `json:"idProduct" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Balance             float64 `json:"balance" example:"500000"`
+		LowBalanceThreshold float64 `json:"lowBalanceThreshold" example:"50000"`
+		DailyLimitCount     int     `json:"dailyLimitCount" example:"200"`
+		DailyLimitAmount    float64 `json:"dailyLimitAmount" example:"5000000"`
 	}
 
-	MerchantErrorResponse struct {
-		Error string `json:"error" example:"Invalid merchant"`
+	MerchantBalance struct {
+		IdMerchant string    `json:"idMerchant" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Balance    float64   `json:"balance" example:"500000"`
+		CheckedAt  time.Time `json:"checkedAt" example:"2024-10-27T10:00:00Z"`
+	}
+
+	BalanceAdjustmentRequest struct {
+		Adjustments map[string]float64 `json:"adjustments" binding:"required,dive,keys,uuid,endkeys" example:"5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60:150000"`
+	}
+
+	// MerchantProductPrice overrides the catalog price of a product for a single merchant.
+	MerchantProductPrice struct {
+		IdMerchant string  `json:"idMerchant" example:"eyJhbGciOiJIUzI1NiIs..."`
+		IdProduct  string  `json:"idProduct" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Price      float64 `json:"price" example:"10500"`
+	}
+
+	MerchantPriceRequest struct {
+		Price float64 `json:"price" binding:"required,gt=0" example:"10500"`
+	}
+
+	// MerchantCatalogItem is a product with the price the merchant actually pays.
+	MerchantCatalogItem struct {
+		IdProduct    string  `json:"idProduct" example:"eyJhbGciOiJIUzI1NiIs..."`
+		NameProvider string  `json:"nameProvider" example:"Telkomsel"`
+		Nominal      float64 `json:"nominal" example:"10000"`
+		Price        float64 `json:"price" example:"10500"`
+		PriceSource  string  `json:"priceSource" example:"merchant"`
 	}
 )
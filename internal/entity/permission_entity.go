@@ -0,0 +1,37 @@
+package entity
+
+// Permissions granted to roles, routes guarded with RequirePermission need the permission instead of a fixed role.
+// A write permission includes the admin lookups of the same records that editing them needs.
+const (
+	PermissionProductWrite  = "product:write"
+	PermissionProviderWrite = "provider:write"
+	PermissionUserRead      = "user:read"
+	PermissionUserWrite     = "user:write"
+	// PermissionPriceOverride lets the cashier of a transaction charge another price than the catalog one.
+	PermissionPriceOverride = "transaction:price_override"
+)
+
+// Permissions are all the permissions a role can be granted.
+var Permissions = []string{PermissionProductWrite, PermissionProviderWrite, PermissionUserRead, PermissionUserWrite, PermissionPriceOverride}
+
+// Roles are the roles a user can have, the roles enum of mst_user.
+var Roles = []string{"admin", "employee"}
+
+// DefaultRolePermissions is what each role could do before the permissions existed. The role_permission table is
+// seeded with it, and tokens issued without a permissions claim are checked against it.
+var DefaultRolePermissions = map[string][]string{
+	"admin":    {PermissionProductWrite, PermissionProviderWrite, PermissionUserRead, PermissionUserWrite, PermissionPriceOverride},
+	"employee": {},
+}
+
+type (
+	RolePermissions struct {
+		Role        string   `json:"role" example:"employee"`
+		Permissions []string `json:"permissions" example:"product:write"`
+	}
+
+	// RolePermissionsRequest replaces every permission of a role, an empty list takes them all away.
+	RolePermissionsRequest struct {
+		Permissions []string `json:"permissions" binding:"required" example:"product:write"`
+	}
+)
@@ -3,28 +3,71 @@ package entity
 type (
 	Product struct {
 		IdProduct    string  `db:"id_product" json:"idProduct"`
-		NameProvider string  `db:"name_provider" json:"nameProvider"`
-		Nominal      float64 `db:"nominal" json:"nominal"`
-		Price        float64 `db:"price" json:"price"`
-		IdSupliyer   string  `db:"id_supliyer" json:"idSupliyer"`
+		IdProvider   string  `db:"id_provider" json:"idProvider" binding:"required_without=NameProvider"`
+		NameProvider string  `db:"name_provider" json:"nameProvider" binding:"max=255"`
+		Nominal      float64 `db:"nominal" json:"nominal" binding:"required,gt=0"`
+		Price        float64 `db:"price" json:"price" binding:"required,gt=0"`
+		IdSupliyer   string  `db:"id_supliyer" json:"idSupliyer" binding:"required"`
+		Version      int     `db:"version" json:"version" binding:"gte=0"`
+		Code         string  `db:"code" json:"code" binding:"max=64"`
+		Status       string  `db:"status" json:"status"`
+		// IdMerchant is empty for global products, otherwise only that merchant sees and sells the product.
+		IdMerchant string `db:"id_merchant" json:"idMerchant"`
 	}
 
 	ProductRequest struct {
-		NameProvider string  `json:"nameProvider" binding:"required" example:"Indosat"`
-		Nominal      float64 `json:"nominal" binding:"required" example:"5000"`
-		Price        float64 `json:"price" binding:"required" example:"6000"`
+		IdProvider   string  `json:"idProvider" binding:"required_without=NameProvider" example:"eyJhbGciOiJIUzI1NiIs..."`
+		NameProvider string  `json:"nameProvider" binding:"max=255" example:"Indosat"`
+		Nominal      float64 `json:"nominal" binding:"required,gt=0" example:"5000"`
+		Price        float64 `json:"price" binding:"required,gt=0" example:"6000"`
 		IdSupliyer   string  `json:"idSupliyer" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Version      int     `json:"version" binding:"gte=0" example:"1"`
+		Code         string  `json:"code" binding:"max=64" example:"TSEL10"`
+		IdMerchant   string  `json:"idMerchant" example:"eyJhbGciOiJIUzI1NiIs..."`
 	}
 
 	ProductResponse struct {
 		IdProduct    string  `json:"idProduct" example:"eyJhbGciOiJIUzI1NiIs..."`
+		IdProvider   string  `json:"idProvider" example:"eyJhbGciOiJIUzI1NiIs..."`
 		NameProvider string  `son:"nameProvider" example:"Indosat"`
 		Nominal      float64 `json:"nominal" example:"5000"`
 		Price        float64 `json:"price" example:"6000"`
 		IdSupliyer   string  `json:"idSupliyer" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Version      int     `json:"version" example:"1"`
+		Code         string  `json:"code" example:"TSEL10"`
+		Status       string  `json:"status" example:"active"`
+		IdMerchant   string  `json:"idMerchant" example:""`
 	}
 
-	ProductErrorResponse struct {
-		Error string `json:"error" example:"Invalid product"`
+	// PublicProduct is the product shape shown on the unauthenticated catalog, it carries no internal ids.
+	PublicProduct struct {
+		Code     string  `json:"code" example:"TSEL10"`
+		Provider string  `json:"provider" example:"Telkomsel"`
+		Category string  `json:"category" example:"pulsa"`
+		Nominal  float64 `json:"nominal" example:"10000"`
+		Price    float64 `json:"price" example:"10900"`
+	}
+
+	// ProductBatchRequest lists the ids of a batch lookup, such as the products of a cart.
+	ProductBatchRequest struct {
+		Ids []string `json:"ids" binding:"required,min=1,max=100" example:"eyJhbGciOiJIUzI1NiIs..."`
+	}
+
+	// ProductBatch holds the products found by a batch lookup in the requested order, and the requested ids that
+	// did not match a product the caller can see.
+	ProductBatch struct {
+		Products []Product `json:"products"`
+		Missing  []string  `json:"missing"`
+	}
+
+	// ProductQuery holds the sort and pagination options of the product listing. Limit 0 returns every product.
+	// UserId limits the listing to global products plus the private products of the merchants owned by that user,
+	// empty lists every product.
+	ProductQuery struct {
+		Sort   string
+		Order  string
+		Page   int
+		Limit  int
+		UserId string
 	}
 )
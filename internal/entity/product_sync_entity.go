@@ -0,0 +1,30 @@
+package entity
+
+const (
+	ProductStatusActive   = "active"
+	ProductStatusDraft    = "draft"
+	ProductStatusInactive = "inactive"
+)
+
+type (
+	// SupplierPriceItem is one entry of the supplier's published price list.
+	SupplierPriceItem struct {
+		Code     string  `json:"code"`
+		Provider string  `json:"provider"`
+		Nominal  float64 `json:"nominal"`
+		Price    float64 `json:"price"`
+	}
+
+	ProductSyncItem struct {
+		Code      string `json:"code"`
+		IdProduct string `json:"idProduct,omitempty"`
+		Reason    string `json:"reason,omitempty"`
+	}
+
+	ProductSyncReport struct {
+		Created   []ProductSyncItem `json:"created"`
+		Updated   []ProductSyncItem `json:"updated"`
+		Unchanged []ProductSyncItem `json:"unchanged"`
+		Failed    []ProductSyncItem `json:"failed"`
+	}
+)
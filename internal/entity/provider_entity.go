@@ -0,0 +1,17 @@
+package entity
+
+type (
+	Provider struct {
+		IdProvider   string `db:"id_provider" json:"idProvider"`
+		NameProvider string `db:"name_provider" json:"nameProvider"`
+	}
+
+	ProviderRequest struct {
+		NameProvider string `json:"nameProvider" binding:"required" example:"Indosat"`
+	}
+
+	ProviderResponse struct {
+		IdProvider   string `json:"idProvider" example:"eyJhbGciOiJIUzI1NiIs..."`
+		NameProvider string `json:"nameProvider" example:"Indosat"`
+	}
+)
@@ -0,0 +1,36 @@
+package entity
+
+// Dependency states of the readiness check.
+const (
+	DependencyUp   = "up"
+	DependencyDown = "down"
+)
+
+type (
+	// DependencyStatus is the result of checking one dependency. Why a dependency is down is only logged, the
+	// errors can name hosts and addresses the probe has no business seeing.
+	DependencyStatus struct {
+		Name      string `json:"name" example:"provider"`
+		Status    string `json:"status" example:"up"`
+		Critical  bool   `json:"critical" example:"false"`
+		LatencyMs int64  `json:"latencyMs" example:"12"`
+	}
+
+	// Readiness is not ready as soon as one critical dependency is down, other dependencies are only reported.
+	// A draining server is shutting down and not ready whatever its dependencies, they are not checked then.
+	Readiness struct {
+		Ready        bool               `json:"ready" example:"true"`
+		Draining     bool               `json:"draining,omitempty" example:"false"`
+		Dependencies []DependencyStatus `json:"dependencies"`
+	}
+
+	// Health is the answer of /healthz, Status is "ok" unless the database is down. Uptime is in seconds.
+	Health struct {
+		Status    string `json:"status" example:"ok"`
+		Db        string `json:"db" example:"up"`
+		Uptime    int64  `json:"uptime" example:"3600"`
+		Version   string `json:"version" example:"1.4.0"`
+		Commit    string `json:"commit" example:"3f2c1ab"`
+		BuildTime string `json:"buildTime" example:"2024-11-02T08:15:00Z"`
+	}
+)
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// Session is one login of a user, it lasts as long as its refresh tokens. LastUsedAt is the last login or refresh,
+// Current marks the session of the token that asked for the list. RememberMe marks a login with remember me, whose
+// tokens last longer.
+type Session struct {
+	Id         string    `json:"id" example:"eyJhbGciOiJIUzI1NiIs..."`
+	IssuedAt   time.Time `json:"issuedAt" example:"2024-08-01T10:00:00Z"`
+	LastUsedAt time.Time `json:"lastUsedAt" example:"2024-08-01T12:00:00Z"`
+	Ip         string    `json:"ip" example:"10.0.0.1"`
+	UserAgent  string    `json:"userAgent" example:"Mozilla/5.0"`
+	Current    bool      `json:"current" example:"true"`
+	RememberMe bool      `json:"rememberMe" example:"false"`
+}
@@ -1,37 +1,77 @@
 package entity
 
+import "time"
+
+// Price sources recorded on every transaction detail.
+const (
+	PriceSourceCatalog  = "catalog"
+	PriceSourceMerchant = "merchant"
+	// PriceSourceOverride is a price the cashier entered for the line, such as a discount the merchant gave.
+	PriceSourceOverride = "override"
+)
+
+// Statuses of a transaction. Transactions are settled when they are created, so they are stored as success unless a
+// later step marks them otherwise.
+const (
+	TransactionStatusPending = "pending"
+	TransactionStatusSuccess = "success"
+	TransactionStatusFailed  = "failed"
+)
+
 type (
+	// Transactions is also the create payload. Status and Balance are only set by Create, Balance to the merchant
+	// balance the transaction left once it was committed, values sent by the client are ignored. TransactionDate is
+	// dd-mm-yyyy, Create uses the current date of the server when it is omitted.
 	Transactions struct {
 		TransactionsId    string              `json:"transactionId"`
-		MerchantId        string              `json:"merchantId"`
-		UserId            string              `json:"userId"`
-		CustomerName      string              `json:"customerName"`
-		DestinationNumber string              `json:"destinationNumber"`
-		TransactionDate   string              `json:"transactionDate"`
-		TransactionDetail []TransactionDetail `json:"transactionDetail"`
+		MerchantId        string              `json:"merchantId" binding:"required"`
+		UserId            string              `json:"userId" binding:"required"`
+		CustomerName      string              `json:"customerName" binding:"required,max=255"`
+		DestinationNumber string              `json:"destinationNumber" binding:"required,min=8,phone"`
+		TransactionDate   string              `json:"transactionDate" binding:"omitempty,ddmmyyyy"`
+		CreatedAt         time.Time           `json:"createdAt"`
+		UpdatedAt         time.Time           `json:"updatedAt"`
+		Status            string              `json:"status"`
+		TransactionDetail []TransactionDetail `json:"transactionDetail" binding:"required,min=1,dive"`
+		Balance           float64             `json:"balance"`
 	}
 
+	// TransactionDetail is charged Price, the catalog or merchant price unless OverridePrice is sent by a caller
+	// allowed to, then the override is stored and audited with OverrideReason. The balance is always debited by
+	// Nominal.
 	TransactionDetail struct {
-		TransactionDetailId string  `json:"transactionDetailId"`
-		TransactionsId      string  `json:"transactionId"`
-		ProductId           string  `json:"productId"`
-		Price               float64 `json:"Price"`
+		TransactionDetailId string   `json:"transactionDetailId"`
+		TransactionsId      string   `json:"transactionId"`
+		ProductId           string   `json:"productId" binding:"required"`
+		Nominal             float64  `json:"nominal"`
+		Price               float64  `json:"Price"`
+		PriceSource         string   `json:"priceSource"`
+		OverridePrice       *float64 `json:"overridePrice,omitempty" binding:"omitempty,gt=0"`
+		OverrideReason      string   `json:"overrideReason,omitempty" binding:"required_with=OverridePrice,max=255"`
 	}
 
 	TransactionReq struct {
 		MerchantId        string                 `json:"merchantId" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
 		UserId            string                 `json:"userId" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
-		CustomerName      string                 `json:"customerName" binding:"required" example:"customer a"`
-		DestinationNumber string                 `json:"destinationNumber" binding:"required" example:"08...."`
-		TransactionDate   string                 `json:"transactionDate" binding:"required" example:"27-10-2024"`
-		TransactionDetail []TransactionDetailReq `json:"transactionDetail" binding:"required"`
+		CustomerName      string                 `json:"customerName" binding:"required,max=255" example:"customer a"`
+		DestinationNumber string                 `json:"destinationNumber" binding:"required,min=8,phone" example:"081234567890"`
+		TransactionDate   string                 `json:"transactionDate" binding:"omitempty,ddmmyyyy" example:"27-10-2024"`
+		TransactionDetail []TransactionDetailReq `json:"transactionDetail" binding:"required,min=1,dive"`
 	}
 
-	TransactionDetailReq struct {
-		ProductId string `json:"productId" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+	// TransactionQuoteReq asks what a transaction of the products would cost the merchant, nothing is charged.
+	TransactionQuoteReq struct {
+		MerchantId        string                 `json:"merchantId" binding:"required" example:"5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60"`
+		TransactionDetail []TransactionQuoteLine `json:"transactionDetail" binding:"required,min=1,dive"`
+	}
+
+	TransactionQuoteLine struct {
+		ProductId string `json:"productId" binding:"required" example:"8d3e1f2a-6b7c-4d5e-8f90-a1b2c3d4e5f6"`
 	}
 
-	TransactionErrorResponse struct {
-		Error string `json:"error" example:"Invalid transaction"`
+	TransactionDetailReq struct {
+		ProductId      string   `json:"productId" binding:"required" example:"eyJhbGciOiJIUzI1NiIs..."`
+		OverridePrice  *float64 `json:"overridePrice,omitempty" binding:"omitempty,gt=0" example:"5500"`
+		OverrideReason string   `json:"overrideReason,omitempty" binding:"required_with=OverridePrice,max=255" example:"loyal customer"`
 	}
 )
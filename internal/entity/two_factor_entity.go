@@ -0,0 +1,25 @@
+package entity
+
+type (
+	// TwoFactor is the TOTP secret of a user, it is not Enabled until a first code was verified.
+	TwoFactor struct {
+		Secret       string
+		Enabled      bool
+		LastUsedStep int64
+	}
+
+	// TwoFactorSetup is shown once to add the account to an authenticator app.
+	TwoFactorSetup struct {
+		Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+		OtpauthURL string `json:"otpauthUrl" example:"otpauth://totp/Server%20Pulsa:budi?secret=JBSWY3DPEHPK3PXP&issuer=Server+Pulsa"`
+	}
+
+	TwoFactorCodeRequest struct {
+		Code string `json:"code" binding:"required" example:"287082"`
+	}
+
+	// TwoFactorBackupCodes are only shown at enablement, each one can replace a code once.
+	TwoFactorBackupCodes struct {
+		BackupCodes []string `json:"backupCodes" example:"4f2d9-c8e1b,7a603-5d2e4"`
+	}
+)
@@ -1,17 +1,50 @@
 package entity
 
+import "time"
+
 type (
 	User struct {
 		Id_user  string `json:"id_user"`
-		Username string `json:"name"`
+		Username string `json:"name" binding:"omitempty,min=3,max=255"`
 		Password string `json:"password"`
-		Role     string `json:"role"`
+		Role     string `json:"role" binding:"omitempty,role"`
+		Email    string `json:"email" binding:"omitempty,email,max=255"`
+		// Active is false once the user was deleted, only the lookups used by login return such users.
+		Active bool `json:"-"`
+		// LastLoginAt is nil for users that never logged in.
+		LastLoginAt *time.Time `json:"last_login_at"`
+		// MustChangePassword is set once an admin reset the password, until the user changes it.
+		MustChangePassword bool `json:"-"`
+	}
+
+	UserCreateRequest struct {
+		Username string `json:"name" binding:"required,min=3,max=255" example:"eko"`
+		Password string `json:"password" binding:"required" example:"secret123"`
+		Role     string `json:"role" binding:"required,role" example:"employee"`
+		Email    string `json:"email" binding:"omitempty,email,max=255" example:"eko@example.com"`
 	}
 
 	UserReqUpdate struct {
 		Username string `json:"name"`
 		Password string `json:"password"`
 		Role     string `json:"role"`
+		Email    string `json:"email"`
+	}
+
+	ChangePasswordRequest struct {
+		CurrentPassword string `json:"currentPassword" binding:"required" example:"secret123"`
+		NewPassword     string `json:"newPassword" binding:"required" example:"n3wSecret123"`
+	}
+
+	// AdminResetPasswordRequest sets the password of another user, an empty NewPassword has a temporary one generated.
+	AdminResetPasswordRequest struct {
+		NewPassword string `json:"newPassword" example:"n3wSecret123"`
+	}
+
+	// AdminResetPasswordResponse carries the generated password, it is only ever shown in this response.
+	AdminResetPasswordResponse struct {
+		Message           string `json:"message" example:"Password reset, it must be changed at the next login"`
+		TemporaryPassword string `json:"temporaryPassword,omitempty" example:"k7QmZp2xVt9sRw4e"`
 	}
 
 	UserResponse struct {
@@ -19,6 +52,15 @@ type (
 		Username string `json:"name"`
 		Password string `json:"password,omitempty"`
 		Role     string `json:"role"`
+		Email    string `json:"email"`
+	}
+	// UserProfile is the logged in user with the merchants they own, the password hash is never part of it.
+	UserProfile struct {
+		Id_user   string     `json:"id_user" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Username  string     `json:"name" example:"eko"`
+		Role      string     `json:"role" example:"employee"`
+		Email     string     `json:"email" example:"eko@example.com"`
+		Merchants []Merchant `json:"merchants"`
 	}
 	UserErrorResponse struct {
 		Error string `json:"error" example:"Invalid product"`
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/usecase"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditUc        usecase.AuditUseCase
+	rg             *gin.RouterGroup
+	authMiddleware middleware.AuthMiddleware
+	log            *logger.Logger
+}
+
+// listHandler godoc
+// @Summary List audit log
+// @Description Sensitive admin actions (balance adjustments, role changes, product deletions), newest first
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page, at most 100" default(20)
+// @Success 200 {array} entity.AuditLog "Audit log entries"
+// @Failure 400 {object} apierror.Response "Invalid pagination"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /admin/audit [get]
+func (a *AuditHandler) listHandler(ctx *gin.Context) {
+	log := a.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to retrieve the audit log in the handler layer", nil)
+
+	page, errPage := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, errLimit := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if errPage != nil || errLimit != nil || page < 1 || limit < 0 {
+		ctx.Error(apierror.Validation("page and limit must be positive numbers", nil))
+		return
+	}
+
+	entries, err := a.auditUc.FindAuditLog(entity.AuditQuery{Page: page, Limit: limit})
+	if err != nil {
+		log.Error("Failed to retrieve the audit log: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    []entity.AuditLog
+	}{
+		Message: "Audit Log",
+		Data:    entries,
+	}
+
+	log.Info("Audit log retrieved successfully", nil)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// authEventsHandler godoc
+// @Summary List authentication events
+// @Description Logins, failed logins, logouts, password changes and lockouts of every user, newest first
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Param userId query string false "Only events of this user"
+// @Param startDate query string false "First day, YYYY-MM-DD"
+// @Param endDate query string false "Last day, YYYY-MM-DD"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page, at most 100" default(20)
+// @Success 200 {array} entity.AuthEvent "Authentication events"
+// @Failure 400 {object} apierror.Response "Invalid filter or pagination"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /admin/auth-events [get]
+func (a *AuditHandler) authEventsHandler(ctx *gin.Context) {
+	a.log.WithContext(ctx.Request.Context()).Info("Starting to retrieve the auth events in the handler layer", nil)
+	a.respondAuthEvents(ctx, ctx.Query("userId"))
+}
+
+// myAuthEventsHandler godoc
+// @Summary List my authentication events
+// @Description Recent logins, failed logins, logouts, password changes and lockouts of the logged in user, newest first
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Param startDate query string false "First day, YYYY-MM-DD"
+// @Param endDate query string false "Last day, YYYY-MM-DD"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page, at most 100" default(20)
+// @Success 200 {array} entity.AuthEvent "Authentication events"
+// @Failure 400 {object} apierror.Response "Invalid filter or pagination"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /me/auth-events [get]
+func (a *AuditHandler) myAuthEventsHandler(ctx *gin.Context) {
+	a.log.WithContext(ctx.Request.Context()).Info("Starting to retrieve the own auth events in the handler layer", nil)
+	a.respondAuthEvents(ctx, ctx.GetString("employee"))
+}
+
+func (a *AuditHandler) respondAuthEvents(ctx *gin.Context, userId string) {
+	log := a.log.WithContext(ctx.Request.Context())
+	page, errPage := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, errLimit := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if errPage != nil || errLimit != nil || page < 1 || limit < 0 {
+		ctx.Error(apierror.Validation("page and limit must be positive numbers", nil))
+		return
+	}
+
+	events, err := a.auditUc.FindAuthEvents(entity.AuthEventQuery{
+		UserId:    userId,
+		StartDate: ctx.Query("startDate"),
+		EndDate:   ctx.Query("endDate"),
+		Page:      page,
+		Limit:     limit,
+	})
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidAuthEventFilter) {
+			ctx.Error(apierror.Validation(err.Error(), nil))
+			return
+		}
+		log.Error("Failed to retrieve the auth events: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    []entity.AuthEvent
+	}{
+		Message: "Auth Events",
+		Data:    events,
+	}
+
+	log.Info("Auth events retrieved successfully", nil)
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (a *AuditHandler) Route() {
+	a.rg.GET(config.GetAuditLog, a.authMiddleware.RequireToken(), a.authMiddleware.RequireRoles("admin"), a.listHandler)
+	a.rg.GET(config.GetAuthEvents, a.authMiddleware.RequireToken(), a.authMiddleware.RequireRoles("admin"), a.authEventsHandler)
+	a.rg.GET(config.GetMyAuthEvents, a.authMiddleware.RequireToken(), a.authMiddleware.RequireRoles("admin", "employee"), a.myAuthEventsHandler)
+}
+
+func NewAuditHandler(auditUc usecase.AuditUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *AuditHandler {
+	return &AuditHandler{auditUc: auditUc, authMiddleware: authMiddleware, rg: rg, log: log}
+}
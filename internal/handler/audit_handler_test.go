@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/middleware_mock"
+	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/usecase"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditHandlerTest struct {
+	suite.Suite
+	auditUc      *usecase_mock.AuditUseCaseMock
+	router       *gin.Engine
+	auditHandler *AuditHandler
+	log          logger.Logger
+}
+
+func (a *AuditHandlerTest) SetupTest() {
+	a.auditUc = new(usecase_mock.AuditUseCaseMock)
+
+	gin.SetMode(gin.TestMode)
+	a.router = newTestRouter()
+
+	a.log = logger.NewLogger()
+	a.auditHandler = NewAuditHandler(a.auditUc, new(middleware_mock.AuthMiddlewareMock), a.router.Group("/api/v1"), &a.log)
+	a.router.GET("/api/v1/admin/auth-events", a.auditHandler.authEventsHandler)
+	a.router.GET("/api/v1/me/auth-events", func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-user-test")
+	}, a.auditHandler.myAuthEventsHandler)
+}
+
+func (a *AuditHandlerTest) serve(url string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, request)
+	return w
+}
+
+func (a *AuditHandlerTest) TestAuthEvents_Filters() {
+	query := entity.AuthEventQuery{UserId: "uuid-other", StartDate: "2024-08-01", EndDate: "2024-08-31", Page: 2, Limit: 10}
+	a.auditUc.On("FindAuthEvents", query).Return([]entity.AuthEvent{{Event: entity.AuthEventLoginFailure}}, nil).Once()
+
+	w := a.serve("/api/v1/admin/auth-events?userId=uuid-other&startDate=2024-08-01&endDate=2024-08-31&page=2&limit=10")
+
+	a.Equal(http.StatusOK, w.Code)
+	a.Contains(w.Body.String(), `"event":"login_failure"`)
+	a.auditUc.AssertExpectations(a.T())
+}
+
+func (a *AuditHandlerTest) TestAuthEvents_InvalidFilter() {
+	a.auditUc.On("FindAuthEvents", mock.Anything).Return([]entity.AuthEvent(nil), usecase.ErrInvalidAuthEventFilter).Once()
+
+	w := a.serve("/api/v1/admin/auth-events?startDate=yesterday")
+
+	a.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (a *AuditHandlerTest) TestAuthEvents_UseCaseError() {
+	a.auditUc.On("FindAuthEvents", mock.Anything).Return([]entity.AuthEvent(nil), errors.New("db down")).Once()
+
+	w := a.serve("/api/v1/admin/auth-events")
+
+	a.Equal(http.StatusInternalServerError, w.Code)
+	a.NotContains(w.Body.String(), "db down")
+}
+
+func (a *AuditHandlerTest) TestMyAuthEvents_OnlyOwnEvents() {
+	a.auditUc.On("FindAuthEvents", entity.AuthEventQuery{UserId: "uuid-user-test", Page: 1}).Return([]entity.AuthEvent{}, nil).Once()
+
+	w := a.serve("/api/v1/me/auth-events?userId=uuid-other")
+
+	a.Equal(http.StatusOK, w.Code)
+	a.auditUc.AssertExpectations(a.T())
+}
+
+func TestAuditHandlerSuite(t *testing.T) {
+	suite.Run(t, new(AuditHandlerTest))
+}
@@ -1,52 +1,123 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/entity/dto"
 	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
 	"server-pulsa-app/internal/usecase"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthController struct {
-	authUsecase usecase.AuthUseCase
-	rg          *gin.RouterGroup
-	log         *logger.Logger
+	authUsecase    usecase.AuthUseCase
+	authMiddleware middleware.AuthMiddleware
+	rg             *gin.RouterGroup
+	log            *logger.Logger
 }
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate a user and get JWT token
+// @Description Authenticate a user and get JWT token. With two-factor authentication enabled the response only has twoFactorRequired and a challengeToken for /auth/2fa/verify
 // @Tags authentication
 // @Accept json
 // @Produce json
-// @Param request body dto.AuthRequest true "Login credentials"
+// @Param request body dto.LoginRequest true "Login credentials, the identifier is a username or an email"
 // @Success 200 {object} dto.AuthResponse "Successfully authenticated"
-// @Failure 400 {object} dto.ErrorResponse "Invalid input"
-// @Failure 401 {object} dto.ErrorResponse "Authentication failed"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Authentication failed"
+// @Failure 403 {object} apierror.Response "The account has been deactivated"
+// @Failure 429 {object} apierror.Response "Too many failed attempts for the username or client IP"
 // @Router /auth/login [post]
 func (a *AuthController) loginHandler(ctx *gin.Context) {
-	var payload dto.AuthRequestDto
+	log := a.log.WithContext(ctx.Request.Context())
+	var payload dto.LoginRequestDto
 
-	a.log.Info("Starting to login a user in the handler layer", nil)
+	log.Info("Starting to login a user in the handler layer", nil)
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		a.log.Error("Invalid payload for login", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		log.Error("Invalid payload for login", err)
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
 		return
 	}
 
-	a.log.Info("Starting login", nil)
-	token, err := a.authUsecase.Login(payload)
+	log.Info("Starting login", nil)
+	token, err := a.authUsecase.Login(payload, clientInfo(ctx))
+	var locked *usecase.LoginLockedError
+	if errors.As(err, &locked) {
+		retryAfter := int(locked.RetryAfter.Seconds()) + 1
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		ctx.Error(apierror.New(http.StatusTooManyRequests, "login_locked", err.Error()).WithDetails(gin.H{"retryAfter": retryAfter}))
+		return
+	}
+	if errors.Is(err, usecase.ErrUserDeactivated) {
+		ctx.Error(apierror.Forbidden(err.Error()))
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidCredentials) {
+		log.Error("Failed to authenticate user: ", err)
+		ctx.Error(apierror.Unauthorized(err.Error()))
+		return
+	}
 	if err != nil {
-		a.log.Error("Failed to authenticate user: ", err)
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		log.Error("Failed to authenticate user: ", err)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
 
-	a.log.Info("User has been authenticated successfully", nil)
+	log.Info("User has been authenticated successfully", nil)
+	ctx.JSON(http.StatusOK, token)
+}
+
+// VerifyTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token of /auth/login and a code of the authenticator app or an unused backup code for the JWT. A challenge expires after 5 minutes or 5 wrong codes
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body dto.TwoFactorVerifyRequest true "Challenge token and code"
+// @Success 200 {object} dto.AuthResponse "Successfully authenticated"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Invalid code or invalid, expired or exhausted challenge"
+// @Failure 403 {object} apierror.Response "The account has been deactivated"
+// @Router /auth/2fa/verify [post]
+func (a *AuthController) verifyTwoFactorHandler(ctx *gin.Context) {
+	log := a.log.WithContext(ctx.Request.Context())
+	var payload dto.TwoFactorVerifyRequestDto
+
+	log.Info("Starting to verify a two-factor code in the handler layer", nil)
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for two-factor verification", err)
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	token, err := a.authUsecase.VerifyTwoFactor(payload, clientInfo(ctx))
+	if errors.Is(err, repository.ErrInvalidChallenge) || errors.Is(err, usecase.ErrInvalidTwoFactorCode) {
+		log.Error("Two-factor verification rejected: ", err)
+		ctx.Error(apierror.Unauthorized(err.Error()))
+		return
+	}
+	if errors.Is(err, usecase.ErrUserDeactivated) {
+		ctx.Error(apierror.Forbidden(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to verify the two-factor code: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("User has been authenticated successfully", nil)
 	ctx.JSON(http.StatusOK, token)
 }
 
@@ -58,36 +129,130 @@ func (a *AuthController) loginHandler(ctx *gin.Context) {
 // @Produce json
 // @Param request body dto.AuthRequest true "Login credentials"
 // @Success 201 {object} dto.AuthRegisterRes "Successfully registered"
-// @Failure 400 {object} dto.ErrorResponse "Invalid input"
-// @Failure 401 {object} dto.ErrorResponse "Authentication failed"
+// @Failure 400 {object} apierror.Response "Invalid input or email, or the password rules that failed"
+// @Failure 401 {object} apierror.Response "Authentication failed"
+// @Failure 409 {object} apierror.Response "Username or email already taken"
+// @Failure 500 {object} apierror.Response "Failed to register the user"
 // @Router /auth/register [post]
 func (a *AuthController) registerHandler(ctx *gin.Context) {
+	log := a.log.WithContext(ctx.Request.Context())
 	var payload dto.AuthRequestDto
 
-	a.log.Info("Starting to register a new user in the handler layer", nil)
+	log.Info("Starting to register a new user in the handler layer", nil)
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		a.log.Error("Invalid payload for register", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		log.Error("Invalid payload for register", err)
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
 		return
 	}
 
-	a.log.Info("Starting to register new user", nil)
+	log.Info("Starting to register new user", nil)
 	user, err := a.authUsecase.Register(payload)
+	if respondWeakPassword(ctx, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrInvalidEmail) {
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	}
+	// the unique violations of a concurrent registration are mapped to the same errors by the repository
+	if respondTaken(ctx, err) {
+		log.Error("Username or email already taken: ", err)
+		return
+	}
 	if err != nil {
-		a.log.Error("Failed to register user: ", err)
-		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		log.Error("Failed to register user: ", err)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
 
-	a.log.Info("User has been registered successfully", nil)
+	log.Info("User has been registered successfully", nil)
 	ctx.JSON(http.StatusCreated, user)
 }
 
+// Refresh godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated, presenting an already used refresh token revokes every token of that login
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body dto.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} dto.AuthResponse "Successfully refreshed"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Invalid, expired or reused refresh token"
+// @Router /auth/refresh [post]
+func (a *AuthController) refreshHandler(ctx *gin.Context) {
+	log := a.log.WithContext(ctx.Request.Context())
+	var payload dto.RefreshTokenRequest
+
+	log.Info("Starting to refresh a token in the handler layer", nil)
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for refresh", err)
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	token, err := a.authUsecase.Refresh(payload.RefreshToken)
+	if errors.Is(err, repository.ErrInvalidRefreshToken) || errors.Is(err, repository.ErrRefreshTokenReused) {
+		log.Error("Refresh token rejected: ", err)
+		ctx.Error(apierror.Unauthorized(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to refresh token: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("Token has been refreshed successfully", nil)
+	ctx.JSON(http.StatusOK, token)
+}
+
+// Logout godoc
+// @Summary Logout user
+// @Description Revoke the current access token until it expires. When a refresh token is given, every refresh token of that login is revoked too
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.LogoutRequest false "Refresh token to revoke"
+// @Success 204 "Successfully logged out"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /auth/logout [post]
+func (a *AuthController) logoutHandler(ctx *gin.Context) {
+	log := a.log.WithContext(ctx.Request.Context())
+	var payload dto.LogoutRequest
+
+	log.Info("Starting to logout a user in the handler layer", nil)
+
+	// the body is optional, an empty body only revokes the access token
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+			log.Error("Invalid payload for logout", err)
+			ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+			return
+		}
+	}
+
+	if err := a.authUsecase.Logout(ctx.GetString("employee"), ctx.GetString("jti"), ctx.GetTime("tokenExpiresAt"), payload.RefreshToken, clientInfo(ctx)); err != nil {
+		log.Error("Failed to logout user: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("User has been logged out successfully", nil)
+	ctx.Status(http.StatusNoContent)
+}
+
 func (a *AuthController) Route() {
 	a.rg.POST(config.Login, a.loginHandler)
+	a.rg.POST(config.TwoFactorLogin, a.verifyTwoFactorHandler)
 	a.rg.POST(config.Register, a.registerHandler)
+	a.rg.POST(config.RefreshToken, a.refreshHandler)
+	a.rg.POST(config.Logout, a.authMiddleware.RequireToken(), a.authMiddleware.RequireRoles("admin", "employee"), a.logoutHandler)
 }
 
-func NewAuthController(authUc usecase.AuthUseCase, rg *gin.RouterGroup, log *logger.Logger) *AuthController {
-	return &AuthController{authUsecase: authUc, rg: rg, log: log}
+func NewAuthController(authUc usecase.AuthUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *AuthController {
+	return &AuthController{authUsecase: authUc, authMiddleware: authMiddleware, rg: rg, log: log}
 }
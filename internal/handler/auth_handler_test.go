@@ -3,15 +3,22 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/entity/dto"
 	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/middleware_mock"
 	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/usecase"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -26,19 +33,19 @@ type AuthHandlerTest struct {
 func (a *AuthHandlerTest) SetupTest() {
 	a.authUc = new(usecase_mock.AuthUseCaseMock)
 
-	a.router = gin.Default()
+	a.router = newTestRouter()
 	gin.SetMode(gin.TestMode)
 
 	rg := a.router.Group("/api/v1")
 
-	a.AuthController = NewAuthController(a.authUc, rg, a.log)
+	a.AuthController = NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), rg, a.log)
 
 	a.AuthController.Route()
 }
 
 func (a *AuthHandlerTest) TestLogin() {
 	user := entity.User{Username: "testuser", Password: "password"}
-	a.authUc.On("Login", user).Return(dto.AuthResponseDto{Token: "some-token"}, nil)
+	a.authUc.On("Login", user, entity.ClientInfo{}).Return(dto.AuthResponseDto{Token: "some-token"}, nil)
 
 	request, err := http.NewRequest("POST", "/auth/login", bytes.NewBuffer([]byte(`{"username": "testuser", "password": "password"}`)))
 	if err != nil {
@@ -74,6 +81,269 @@ func (a *AuthHandlerTest) TestRegister() {
 	a.Equal("", response.Username)
 }
 
+func (a *AuthHandlerTest) TestLogin_ValidationErrors() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusBadRequest, recorder.Code)
+	var response struct {
+		Code    string            `json:"code"`
+		Details map[string]string `json:"details"`
+	}
+	a.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	a.Equal(apierror.CodeValidation, response.Code)
+	a.Equal("identifier is required (required)", response.Details["identifier"])
+	a.Equal("password is required (required)", response.Details["password"])
+	a.authUc.AssertNotCalled(a.T(), "Login")
+}
+
+func (a *AuthHandlerTest) TestLogin_LockedOut() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	a.authUc.On("Login", dto.LoginRequestDto{Identifier: "testuser", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.1"}).
+		Return(dto.AuthResponseDto{}, &usecase.LoginLockedError{RetryAfter: 90 * time.Second})
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{"username": "testuser", "password": "password"}`))
+	request.RemoteAddr = "10.0.0.1:52000"
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusTooManyRequests, recorder.Code)
+	a.Equal("91", recorder.Header().Get("Retry-After"))
+	a.Contains(recorder.Body.String(), `"retryAfter":91`)
+}
+
+func (a *AuthHandlerTest) TestLogin_Deactivated() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	a.authUc.On("Login", dto.LoginRequestDto{Identifier: "testuser", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.1"}).
+		Return(dto.AuthResponseDto{}, usecase.ErrUserDeactivated)
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{"username": "testuser", "password": "password"}`))
+	request.RemoteAddr = "10.0.0.1:52000"
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusForbidden, recorder.Code)
+	a.Contains(recorder.Body.String(), usecase.ErrUserDeactivated.Error())
+}
+
+func (a *AuthHandlerTest) TestLogin_WithEmailIdentifier() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	a.authUc.On("Login", dto.LoginRequestDto{Identifier: "eko@example.com", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.1", UserAgent: "curl/8.0"}).
+		Return(dto.AuthResponseDto{Token: "some-token"}, nil)
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{"identifier": "eko@example.com", "password": "password"}`))
+	request.RemoteAddr = "10.0.0.1:52000"
+	request.Header.Set("User-Agent", "curl/8.0")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusOK, recorder.Code)
+	a.Contains(recorder.Body.String(), `"token":"some-token"`)
+}
+
+func (a *AuthHandlerTest) TestRegister_WeakPasswordListsRules() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	a.authUc.On("Register", dto.AuthRequestDto{Username: "testuser", Password: "123"}).
+		Return(entity.User{}, &usecase.WeakPasswordError{Rules: []string{"must be at least 8 characters", "must contain a letter"}})
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBufferString(`{"username": "testuser", "password": "123"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusBadRequest, recorder.Code)
+	var response struct {
+		Code    string `json:"code"`
+		Details struct {
+			Rules []string `json:"rules"`
+		} `json:"details"`
+	}
+	a.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	a.Equal("weak_password", response.Code)
+	a.Equal([]string{"must be at least 8 characters", "must contain a letter"}, response.Details.Rules)
+}
+
+func (a *AuthHandlerTest) TestRegister_DuplicateUsername() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	payload := dto.AuthRequestDto{Username: "testuser", Password: "password1"}
+	a.authUc.On("Register", payload).Return(entity.User{Id_user: "uuid-user", Username: "testuser"}, nil).Once()
+	a.authUc.On("Register", payload).Return(entity.User{}, repository.ErrUsernameTaken).Once()
+
+	register := func() *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBufferString(`{"username": "testuser", "password": "password1"}`))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	a.Equal(http.StatusCreated, register().Code)
+	recorder := register()
+	a.Equal(http.StatusConflict, recorder.Code)
+	var response apierror.Response
+	a.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	a.Equal(apierror.Response{Message: repository.ErrUsernameTaken.Error(), Code: "username_taken", Details: map[string]any{"field": "username"}}, response)
+}
+
+func (a *AuthHandlerTest) TestRegister_DuplicateEmail() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	payload := dto.AuthRequestDto{Username: "testuser", Password: "password1", Email: "test@example.com"}
+	a.authUc.On("Register", payload).Return(entity.User{}, repository.ErrEmailTaken).Once()
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/register",
+		bytes.NewBufferString(`{"username": "testuser", "password": "password1", "email": "test@example.com"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusConflict, recorder.Code)
+	var response apierror.Response
+	a.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	a.Equal(apierror.Response{Message: repository.ErrEmailTaken.Error(), Code: "email_taken", Details: map[string]any{"field": "email"}}, response)
+}
+
+func (a *AuthHandlerTest) TestRegister_DatabaseError() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	a.authUc.On("Register", dto.AuthRequestDto{Username: "testuser", Password: "password1"}).Return(entity.User{}, errors.New("connection refused")).Once()
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBufferString(`{"username": "testuser", "password": "password1"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusInternalServerError, recorder.Code)
+	a.NotContains(recorder.Body.String(), "connection refused")
+}
+
+func (a *AuthHandlerTest) TestRefresh() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	a.authUc.On("Refresh", "refresh-token").Return(dto.AuthResponseDto{Token: "access-token", RefreshToken: "new-refresh-token"}, nil)
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBufferString(`{"refreshToken": "refresh-token"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusOK, recorder.Code)
+	var response dto.AuthResponseDto
+	a.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	a.Equal("access-token", response.Token)
+	a.Equal("new-refresh-token", response.RefreshToken)
+}
+
+func (a *AuthHandlerTest) TestRefresh_ReusedToken() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	a.authUc.On("Refresh", "rotated-token").Return(dto.AuthResponseDto{}, repository.ErrRefreshTokenReused)
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBufferString(`{"refreshToken": "rotated-token"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusUnauthorized, recorder.Code)
+	a.Contains(recorder.Body.String(), "login again")
+}
+
+func (a *AuthHandlerTest) TestVerifyTwoFactor() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	payload := dto.TwoFactorVerifyRequestDto{ChallengeToken: "challenge", Code: "287082"}
+	a.authUc.On("VerifyTwoFactor", payload, entity.ClientInfo{}).Return(dto.AuthResponseDto{Token: "access-token", RefreshToken: "refresh-token"}, nil)
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/2fa/verify", bytes.NewBufferString(`{"challengeToken": "challenge", "code": "287082"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusOK, recorder.Code)
+	var response dto.AuthResponseDto
+	a.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	a.Equal("access-token", response.Token)
+}
+
+func (a *AuthHandlerTest) TestVerifyTwoFactor_InvalidCode() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+	payload := dto.TwoFactorVerifyRequestDto{ChallengeToken: "challenge", Code: "000000"}
+	a.authUc.On("VerifyTwoFactor", payload, entity.ClientInfo{}).Return(dto.AuthResponseDto{}, usecase.ErrInvalidTwoFactorCode)
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/2fa/verify", bytes.NewBufferString(`{"challengeToken": "challenge", "code": "000000"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusUnauthorized, recorder.Code)
+}
+
+func (a *AuthHandlerTest) TestVerifyTwoFactor_MissingChallenge() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log).Route()
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/2fa/verify", bytes.NewBufferString(`{"code": "287082"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusBadRequest, recorder.Code)
+	a.authUc.AssertNotCalled(a.T(), "VerifyTwoFactor", mock.Anything, mock.Anything)
+}
+
+func (a *AuthHandlerTest) TestLogout() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	controller := NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log)
+	expiresAt := time.Now().Add(time.Hour)
+	router.POST("/api/v1/auth/logout", func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-user")
+		ctx.Set("jti", "token-jti")
+		ctx.Set("tokenExpiresAt", expiresAt)
+	}, controller.logoutHandler)
+	a.authUc.On("Logout", "uuid-user", "token-jti", expiresAt, "refresh-token", entity.ClientInfo{}).Return(nil)
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/logout", bytes.NewBufferString(`{"refreshToken": "refresh-token"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusNoContent, recorder.Code)
+	a.authUc.AssertExpectations(a.T())
+}
+
+func (a *AuthHandlerTest) TestLogout_WithoutBody() {
+	log := logger.NewLogger()
+	router := newTestRouter()
+	controller := NewAuthController(a.authUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &log)
+	expiresAt := time.Now().Add(time.Hour)
+	router.POST("/api/v1/auth/logout", func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-user")
+		ctx.Set("jti", "token-jti")
+		ctx.Set("tokenExpiresAt", expiresAt)
+	}, controller.logoutHandler)
+	a.authUc.On("Logout", "uuid-user", "token-jti", expiresAt, "", entity.ClientInfo{}).Return(nil)
+
+	request, _ := http.NewRequest("POST", "/api/v1/auth/logout", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	a.Equal(http.StatusNoContent, recorder.Code)
+}
+
 func TestAuthHandlerSuite(t *testing.T) {
 	suite.Run(t, new(AuthHandlerTest))
 }
@@ -0,0 +1,12 @@
+package handler
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientInfo is the caller of the request as recorded with the auth events.
+func clientInfo(ctx *gin.Context) entity.ClientInfo {
+	return entity.ClientInfo{Ip: ctx.ClientIP(), UserAgent: ctx.Request.UserAgent()}
+}
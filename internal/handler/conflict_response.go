@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"errors"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondTaken answers 409 with a code and, in the details, the field that clients can show the error next to. It
+// reports false for any other error than a taken username or email.
+func respondTaken(ctx *gin.Context, err error) bool {
+	var code, field string
+	switch {
+	case errors.Is(err, repository.ErrUsernameTaken):
+		code, field = "username_taken", "username"
+	case errors.Is(err, repository.ErrEmailTaken):
+		code, field = "email_taken", "email"
+	default:
+		return false
+	}
+
+	ctx.Error(apierror.Conflict(err.Error()).WithCode(code).WithDetails(gin.H{"field": field}))
+	return true
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DbStatsHandler exposes the connection pool to diagnose pool exhaustion. The stats are read from the pool
+// itself, so there is no usecase in between.
+type DbStatsHandler struct {
+	statsRepo      repository.DbStatsRepository
+	rg             *gin.RouterGroup
+	authMiddleware middleware.AuthMiddleware
+	log            *logger.Logger
+}
+
+// statsHandler godoc
+// @Summary Database connection pool stats
+// @Description Open, in use and idle connections and how often requests had to wait for one
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.DbStats "Connection pool stats"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /admin/db-stats [get]
+func (d *DbStatsHandler) statsHandler(ctx *gin.Context) {
+	d.log.WithContext(ctx.Request.Context()).Info("Starting to retrieve the database stats in the handler layer", nil)
+
+	ctx.JSON(http.StatusOK, d.statsRepo.Stats())
+}
+
+func (d *DbStatsHandler) Route() {
+	d.rg.GET(config.GetDbStats, d.authMiddleware.RequireToken(), d.authMiddleware.RequireRoles("admin"), d.statsHandler)
+}
+
+func NewDbStatsHandler(statsRepo repository.DbStatsRepository, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *DbStatsHandler {
+	return &DbStatsHandler{statsRepo: statsRepo, authMiddleware: authMiddleware, rg: rg, log: log}
+}
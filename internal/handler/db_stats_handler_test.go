@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/middleware_mock"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type DbStatsHandlerTest struct {
+	suite.Suite
+	statsRepo *repositorymock.MockDbStatsRepository
+	router    *gin.Engine
+	log       logger.Logger
+}
+
+func (d *DbStatsHandlerTest) SetupTest() {
+	d.statsRepo = new(repositorymock.MockDbStatsRepository)
+
+	gin.SetMode(gin.TestMode)
+	d.router = gin.New()
+
+	d.log = logger.NewLogger()
+	NewDbStatsHandler(d.statsRepo, new(middleware_mock.AuthMiddlewareMock), d.router.Group("/api/v1"), &d.log).Route()
+}
+
+func (d *DbStatsHandlerTest) TestStats() {
+	d.statsRepo.On("Stats").Return(entity.DbStats{MaxOpenConnections: 25, OpenConnections: 10, InUse: 7, Idle: 3, WaitCount: 42, WaitDurationMs: 1500})
+
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/admin/db-stats", nil)
+	w := httptest.NewRecorder()
+	d.router.ServeHTTP(w, request)
+
+	d.Equal(http.StatusOK, w.Code)
+	var body map[string]any
+	d.NoError(json.Unmarshal(w.Body.Bytes(), &body))
+	for field, expected := range map[string]float64{
+		"maxOpenConnections": 25, "openConnections": 10, "inUse": 7, "idle": 3,
+		"waitCount": 42, "waitDurationMs": 1500, "maxIdleClosed": 0, "maxLifetimeClosed": 0,
+	} {
+		d.Equal(expected, body[field], field)
+	}
+}
+
+func TestDbStatsHandlerSuite(t *testing.T) {
+	suite.Run(t, new(DbStatsHandlerTest))
+}
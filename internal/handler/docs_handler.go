@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/docs"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+// DocsHandler serves the OpenAPI document swag generates into the docs package from the godoc comments of the
+// handlers, regenerate it with `swag init --parseDependency --parseInternal` after changing them.
+type DocsHandler struct {
+	rg *gin.RouterGroup
+}
+
+// openAPIHandler serves the document with the base path the routes are actually registered under, and without a
+// host so the Swagger UI calls the server it was loaded from.
+func (d *DocsHandler) openAPIHandler(ctx *gin.Context) {
+	spec := *docs.SwaggerInfo
+	spec.Host = ""
+	spec.BasePath = d.rg.BasePath()
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", []byte(spec.ReadDoc()))
+}
+
+func (d *DocsHandler) docsHandler(ctx *gin.Context) {
+	ctx.Redirect(http.StatusMovedPermanently, d.rg.BasePath()+config.GetDocs+"/index.html")
+}
+
+func (d *DocsHandler) Route() {
+	d.rg.GET(config.GetOpenAPI, d.openAPIHandler)
+	d.rg.GET(config.GetDocs, d.docsHandler)
+	d.rg.GET(config.GetDocs+"/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL(d.rg.BasePath()+config.GetOpenAPI)))
+}
+
+func NewDocsHandler(rg *gin.RouterGroup) *DocsHandler {
+	return &DocsHandler{rg: rg}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type DocsHandlerTest struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func (d *DocsHandlerTest) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	d.router = gin.New()
+	NewDocsHandler(d.router.Group("/pulsa/api")).Route()
+}
+
+func (d *DocsHandlerTest) get(path string) *httptest.ResponseRecorder {
+	// httptest sets the RequestURI the Swagger UI handler matches on
+	w := httptest.NewRecorder()
+	d.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func (d *DocsHandlerTest) TestOpenAPI_IsValidSpec() {
+	w := d.get("/pulsa/api/openapi.json")
+
+	d.Equal(http.StatusOK, w.Code)
+	d.Contains(w.Header().Get("Content-Type"), "application/json")
+
+	var spec struct {
+		Swagger     string                            `json:"swagger"`
+		Host        string                            `json:"host"`
+		BasePath    string                            `json:"basePath"`
+		Paths       map[string]map[string]interface{} `json:"paths"`
+		Definitions map[string]interface{}            `json:"definitions"`
+	}
+	d.Require().NoError(json.Unmarshal(w.Body.Bytes(), &spec), "the generated docs must stay valid JSON")
+	d.Equal("2.0", spec.Swagger)
+	d.Empty(spec.Host)
+	d.Equal("/pulsa/api", spec.BasePath)
+	for _, path := range []string{"/auth/login", "/user", "/merchants", "/products", "/transaction", "/transactions"} {
+		d.Contains(spec.Paths, path)
+	}
+
+	// every schema an operation points to has to be generated too
+	for _, ref := range regexp.MustCompile(`"\$ref":\s*"([^"]+)"`).FindAllStringSubmatch(w.Body.String(), -1) {
+		d.Contains(spec.Definitions, strings.TrimPrefix(ref[1], "#/definitions/"), ref[1])
+	}
+}
+
+func (d *DocsHandlerTest) TestDocs_RedirectsToUI() {
+	w := d.get("/pulsa/api/docs")
+
+	d.Equal(http.StatusMovedPermanently, w.Code)
+	d.Equal("/pulsa/api/docs/index.html", w.Header().Get("Location"))
+
+	w = d.get("/pulsa/api/docs/index.html")
+	d.Equal(http.StatusOK, w.Code)
+	d.Contains(w.Body.String(), `url: "\/pulsa\/api\/openapi.json"`)
+}
+
+func TestDocsHandlerTest(t *testing.T) {
+	suite.Run(t, new(DocsHandlerTest))
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/mock/middleware_mock"
+	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/shared/apierror"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/suite"
+)
+
+// newTestRouter is the router of the handler tests, with the error middleware the server installs so the error
+// bodies are the ones clients get.
+func newTestRouter() *gin.Engine {
+	log := logger.NewLogger()
+	router := gin.New()
+	router.Use(middleware.NewErrorMiddleware(&log))
+	return router
+}
+
+type ErrorResponseTest struct {
+	suite.Suite
+	router     *gin.Engine
+	merchantUc *usecase_mock.MerchantUsecaseMock
+	productUc  *usecase_mock.ProductUseCaseMock
+}
+
+func (e *ErrorResponseTest) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	e.router = newTestRouter()
+	e.merchantUc = new(usecase_mock.MerchantUsecaseMock)
+	e.productUc = new(usecase_mock.ProductUseCaseMock)
+
+	log := logger.NewLogger()
+	merchantHandler := NewMerchantHandler(e.merchantUc, new(middleware_mock.AuthMiddlewareMock), e.router.Group("/api/v1"), &log)
+	productHandler := NewProductController(e.productUc, e.router.Group("/api/v1"), new(middleware_mock.AuthMiddlewareMock), &log)
+	e.router.GET("/api/v1/merchants/:id", merchantHandler.getHandler)
+	e.router.GET("/api/v1/merchants", merchantHandler.listHandler)
+	e.router.DELETE("/api/v1/product/:id", productHandler.DeleteProduct)
+}
+
+func (e *ErrorResponseTest) get(method, url string) (*httptest.ResponseRecorder, apierror.Response) {
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+
+	var body apierror.Response
+	e.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+	return w, body
+}
+
+// the text of a database error is logged, the client only learns the request failed
+func (e *ErrorResponseTest) TestDatabaseErrorsNeverReachTheClient() {
+	dbErrors := []error{
+		fmt.Errorf("retrieve the merchants: %w", &pq.Error{Code: "42P01", Message: `relation "mst_merchant" does not exist`}),
+		fmt.Errorf("retrieve the merchants: %w", sql.ErrConnDone),
+		errors.New("pq: password authentication failed for user \"postgres\""),
+	}
+
+	for _, dbErr := range dbErrors {
+		e.SetupTest()
+		e.merchantUc.On("FindAllMerchant").Return([]entity.Merchant(nil), dbErr)
+
+		w, body := e.get(http.MethodGet, "/api/v1/merchants")
+
+		e.Equal(http.StatusInternalServerError, w.Code)
+		e.Equal(apierror.CodeInternal, body.Code)
+		e.Equal("internal server error", body.Message)
+		for _, leaked := range []string{"pq:", "sql:", "relation", "mst_merchant", "postgres"} {
+			e.NotContains(w.Body.String(), leaked)
+		}
+	}
+}
+
+func (e *ErrorResponseTest) TestNoRowsIsNotFound() {
+	e.merchantUc.On("FindMerchantByID", "uuid-merchant-test").Return(entity.Merchant{}, fmt.Errorf("retrieve the merchant: %w", sql.ErrNoRows))
+
+	w, body := e.get(http.MethodGet, "/api/v1/merchants/uuid-merchant-test")
+
+	e.Equal(http.StatusNotFound, w.Code)
+	e.Equal(apierror.CodeNotFound, body.Code)
+	e.NotContains(w.Body.String(), "sql: no rows")
+}
+
+func (e *ErrorResponseTest) TestUnexpectedErrorOfANotFoundRoute() {
+	// only a missing product is a 404, a failing delete is a 500 without the constraint in the body
+	e.productUc.On("DeleteProduct", "uuid-product-test", "").Return(fmt.Errorf("delete the product: %w", &pq.Error{Code: "23503", Message: "update or delete on table \"mst_product\" violates foreign key constraint"}))
+
+	w, body := e.get(http.MethodDelete, "/api/v1/product/uuid-product-test")
+
+	e.Equal(http.StatusInternalServerError, w.Code)
+	e.Equal(apierror.CodeInternal, body.Code)
+	e.NotContains(w.Body.String(), "constraint")
+}
+
+func (e *ErrorResponseTest) TestResponseShape() {
+	e.merchantUc.On("FindMerchantByID", "uuid-merchant-test").Return(entity.Merchant{}, sql.ErrNoRows)
+
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/merchants/uuid-merchant-test", nil)
+	e.router.ServeHTTP(w, request)
+
+	var body map[string]any
+	e.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+	e.Equal(map[string]any{"code": "not_found", "message": "Merchant of Id uuid-merchant-test Not Found"}, body)
+}
+
+func TestErrorResponseTest(t *testing.T) {
+	suite.Run(t, new(ErrorResponseTest))
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/service"
+	"server-pulsa-app/internal/version"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthPingTimeout keeps a hanging database from holding the probe of the load balancer.
+const healthPingTimeout = time.Second
+
+// Health states of /healthz.
+const (
+	HealthOk          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthHandler is the cheap probe next to /ready, it only pings the database.
+type HealthHandler struct {
+	database  service.DependencyChecker
+	startedAt time.Time
+	rg        *gin.RouterGroup
+	log       *logger.Logger
+}
+
+// healthHandler godoc
+// @Summary Health check
+// @Description Ping the database and report the uptime in seconds and the version, commit and build time of the server
+// @Tags health
+// @Produce json
+// @Success 200 {object} entity.Health "The database answers"
+// @Failure 503 {object} entity.Health "The database does not answer"
+// @Router /healthz [get]
+func (h *HealthHandler) healthHandler(ctx *gin.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx.Request.Context(), healthPingTimeout)
+	defer cancel()
+
+	health := entity.Health{
+		Status:    HealthOk,
+		Db:        entity.DependencyUp,
+		Uptime:    int64(time.Since(h.startedAt).Seconds()),
+		Version:   version.Version,
+		Commit:    version.Commit,
+		BuildTime: version.BuildTime,
+	}
+	if err := h.database.Check(pingCtx); err != nil {
+		h.log.WithContext(ctx.Request.Context()).Error("Health check failed to ping the database: ", err)
+		health.Status = HealthUnavailable
+		health.Db = entity.DependencyDown
+		ctx.JSON(http.StatusServiceUnavailable, health)
+		return
+	}
+	ctx.JSON(http.StatusOK, health)
+}
+
+// livezHandler godoc
+// @Summary Liveness
+// @Description Answer as long as the process serves requests, no dependency is checked so a database outage does not get the pod restarted
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string "The process is up"
+// @Router /livez [get]
+func (h *HealthHandler) livezHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": HealthOk})
+}
+
+func (h *HealthHandler) Route() {
+	h.rg.GET(config.GetHealth, h.healthHandler)
+	h.rg.GET(config.GetLivez, h.livezHandler)
+}
+
+// NewHealthHandler reports the uptime since startedAt, database is normally service.NewDatabaseChecker.
+func NewHealthHandler(database service.DependencyChecker, startedAt time.Time, rg *gin.RouterGroup, log *logger.Logger) *HealthHandler {
+	return &HealthHandler{database: database, startedAt: startedAt, rg: rg, log: log}
+}
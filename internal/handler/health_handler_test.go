@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/service_mock"
+	"server-pulsa-app/internal/shared/service"
+	"server-pulsa-app/internal/version"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type HealthHandlerTest struct {
+	suite.Suite
+	database *service_mock.DependencyCheckerMock
+	log      logger.Logger
+}
+
+func (h *HealthHandlerTest) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	h.database = service_mock.NewDependencyCheckerMock(service.DependencyDatabase)
+	h.log = logger.NewLogger()
+}
+
+func (h *HealthHandlerTest) serve() (int, entity.Health) {
+	router := gin.New()
+	NewHealthHandler(h.database, time.Now().Add(-90*time.Second), &router.RouterGroup, &h.log).Route()
+
+	request, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+
+	var health entity.Health
+	h.NoError(json.Unmarshal(w.Body.Bytes(), &health))
+	return w.Code, health
+}
+
+func (h *HealthHandlerTest) TestHealth_DatabaseUp() {
+	h.database.On("Check", mock.Anything).Return(nil)
+
+	code, health := h.serve()
+
+	h.Equal(http.StatusOK, code)
+	h.Equal(HealthOk, health.Status)
+	h.Equal(entity.DependencyUp, health.Db)
+	h.GreaterOrEqual(health.Uptime, int64(90))
+	h.Equal(version.Version, health.Version)
+}
+
+func (h *HealthHandlerTest) TestHealth_ReportsBuild() {
+	defer func(v, c, b string) { version.Version, version.Commit, version.BuildTime = v, c, b }(version.Version, version.Commit, version.BuildTime)
+	version.Version, version.Commit, version.BuildTime = "1.4.0", "3f2c1ab", "2024-11-02T08:15:00Z"
+	h.database.On("Check", mock.Anything).Return(nil)
+
+	_, health := h.serve()
+
+	h.Equal("1.4.0", health.Version)
+	h.Equal("3f2c1ab", health.Commit)
+	h.Equal("2024-11-02T08:15:00Z", health.BuildTime)
+}
+
+func (h *HealthHandlerTest) TestHealth_DatabaseDown() {
+	h.database.On("Check", mock.Anything).Return(errors.New("connection refused"))
+
+	code, health := h.serve()
+
+	h.Equal(http.StatusServiceUnavailable, code)
+	h.Equal(HealthUnavailable, health.Status)
+	h.Equal(entity.DependencyDown, health.Db)
+}
+
+func (h *HealthHandlerTest) TestLivez_ChecksNoDependency() {
+	router := gin.New()
+	NewHealthHandler(h.database, time.Now(), &router.RouterGroup, &h.log).Route()
+
+	request, _ := http.NewRequest(http.MethodGet, "/livez", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+
+	h.Equal(http.StatusOK, w.Code)
+	h.JSONEq(`{"status": "ok"}`, w.Body.String())
+	h.database.AssertNotCalled(h.T(), "Check", mock.Anything)
+}
+
+func TestHealthHandlerTest(t *testing.T) {
+	suite.Run(t, new(HealthHandlerTest))
+}
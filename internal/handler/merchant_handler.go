@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
 	"server-pulsa-app/internal/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -30,39 +34,32 @@ type MerchantHandler struct {
 // @Security BearerAuth
 // @Param request body entity.MerchantRequest true "Merchant details"
 // @Success 201 {object} entity.MerchantResponse "Successfully created"
-// @Failure 400 {object} entity.MerchantErrorResponse "Invalid input"
-// @Failure 401 {object} entity.MerchantErrorResponse "Unauthorized"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
 // @Router /merchant [post]
 func (m *MerchantHandler) createHandler(ctx *gin.Context) {
-	var payload entity.Merchant
+	log := m.log.WithContext(ctx.Request.Context())
+	var payload entity.MerchantRequest
 
-	m.log.Info("Starting to create a new merchant in the handler layer", nil)
+	log.Info("Starting to create a new merchant in the handler layer", nil)
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		response := struct {
-			Message string
-			Data    entity.Merchant
-		}{
-			Message: "Invalid Payload for Merchant",
-			Data:    entity.Merchant{},
-		}
-
-		m.log.Error("Invalid payload for merchant: ", err)
-		ctx.JSON(http.StatusBadRequest, response)
+		log.Error("Invalid payload for merchant: ", err)
+		ctx.Error(apierror.Validation("Invalid Payload for Merchant", common.ValidationErrors(err)))
 		return
 	}
-	merchant, err := m.merchantUc.RegisterNewMerchant(payload)
+	merchant, err := m.merchantUc.RegisterNewMerchant(entity.Merchant{
+		IdUser:              payload.IdUser,
+		NameMerchant:        payload.NameMerchant,
+		Address:             payload.Address,
+		IdProduct:           payload.IdProduct,
+		LowBalanceThreshold: payload.LowBalanceThreshold,
+		DailyLimitCount:     payload.DailyLimitCount,
+		DailyLimitAmount:    payload.DailyLimitAmount,
+	})
 	if err != nil {
-		response := struct {
-			Message string
-			Data    entity.Merchant
-		}{
-			Message: err.Error(),
-			Data:    entity.Merchant{},
-		}
-
-		m.log.Error("Merchant creation failed", response)
-		ctx.JSON(http.StatusInternalServerError, response)
+		log.Error("Merchant creation failed", err)
+		ctx.Error(apierror.FromRepository(err, "Merchant not found"))
 		return
 	}
 
@@ -74,7 +71,7 @@ func (m *MerchantHandler) createHandler(ctx *gin.Context) {
 		Data:    merchant,
 	}
 
-	m.log.Info("Merchant created successfully", response)
+	log.Info("Merchant created successfully", response)
 	ctx.JSON(http.StatusCreated, response)
 }
 
@@ -88,21 +85,15 @@ func (m *MerchantHandler) createHandler(ctx *gin.Context) {
 // @Param page query int false "Page number"
 // @Param limit query int false "Items per page"
 // @Success 200 {array} []entity.MerchantResponse "List of merchants"
-// @Failure 401 {object} entity.MerchantErrorResponse "Unauthorized"
+// @Failure 401 {object} apierror.Response "Unauthorized"
 // @Router /merchants [get]
 func (m *MerchantHandler) listHandler(ctx *gin.Context) {
-	m.log.Info("Starting to retrieve all merchant in the handler layer", nil)
+	log := m.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to retrieve all merchant in the handler layer", nil)
 
 	merchants, err := m.merchantUc.FindAllMerchant()
 	if err != nil {
-		response := struct {
-			Message string
-			Data    entity.Merchant
-		}{
-			Message: err.Error(),
-			Data:    entity.Merchant{},
-		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
 
@@ -115,7 +106,7 @@ func (m *MerchantHandler) listHandler(ctx *gin.Context) {
 			Data:    merchants,
 		}
 
-		m.log.Info("Merchant found successfully", nil)
+		log.Info("Merchant found successfully", nil)
 		ctx.JSON(http.StatusOK, response)
 		return
 	}
@@ -127,7 +118,7 @@ func (m *MerchantHandler) listHandler(ctx *gin.Context) {
 		Data:    entity.Merchant{},
 	}
 
-	m.log.Info("Merchant not found", response)
+	log.Info("Merchant not found", response)
 	ctx.JSON(http.StatusOK, response)
 }
 
@@ -140,25 +131,18 @@ func (m *MerchantHandler) listHandler(ctx *gin.Context) {
 // @Security BearerAuth
 // @Param id path string true "Merchant ID"
 // @Success 200 {object} entity.MerchantResponse "Merchant found"
-// @Failure 404 {object} entity.MerchantErrorResponse "Merchant not found"
-// @Failure 401 {object} entity.MerchantErrorResponse "Unauthorized"
+// @Failure 404 {object} apierror.Response "Merchant not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
 // @Router /merchant/{id} [get]
 func (m *MerchantHandler) getHandler(ctx *gin.Context) {
+	log := m.log.WithContext(ctx.Request.Context())
 	id := ctx.Param("id")
 
-	m.log.Info("Starting to retrieve merchant with id in the handler layer", nil)
+	log.Info("Starting to retrieve merchant with id in the handler layer", nil)
 	merchant, err := m.merchantUc.FindMerchantByID(id)
 	if err != nil {
-		response := struct {
-			Message string
-			Data    entity.Merchant
-		}{
-			Message: err.Error(),
-			Data:    entity.Merchant{},
-		}
-
-		m.log.Error("Merchant ID %s not found: ", response)
-		ctx.JSON(http.StatusNotFound, response)
+		log.Error("Merchant ID %s not found: ", err)
+		ctx.Error(apierror.FromRepository(err, "Merchant of Id "+id+" Not Found"))
 		return
 	}
 	response := struct {
@@ -169,7 +153,7 @@ func (m *MerchantHandler) getHandler(ctx *gin.Context) {
 		Data:    merchant,
 	}
 
-	m.log.Info("Merchant found successfully", nil)
+	log.Info("Merchant found successfully", nil)
 	ctx.JSON(http.StatusOK, response)
 }
 
@@ -181,45 +165,28 @@ func (m *MerchantHandler) getHandler(ctx *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Merchant ID"
-// @Param request body entity.MerchantRequest true "Updated merchant details"
+// @Param request body entity.MerchantUpdateRequest true "The fields to change, a limit of 0 removes it"
 // @Success 200 {object} entity.MerchantResponse "Successfully updated merchant"
-// @Failure 400 {object} entity.MerchantErrorResponse "Invalid input"
-// @Failure 401 {object} entity.MerchantErrorResponse "Unauthorized"
-// @Failure 404 {object} entity.MerchantErrorResponse "Merchant not found"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "Merchant not found"
 // @Router /merchant/{id} [put]
 func (m *MerchantHandler) updateHandler(ctx *gin.Context) {
+	log := m.log.WithContext(ctx.Request.Context())
 	id := ctx.Param("id")
-	var payload entity.Merchant
+	var payload entity.MerchantUpdateRequest
 
-	m.log.Info("Starting to update merchant with id in the handler layer", nil)
+	log.Info("Starting to update merchant with id in the handler layer", nil)
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		response := struct {
-			Message string
-			Data    entity.Merchant
-		}{
-			Message: "Invalid Payload for Merchant",
-			Data:    entity.Merchant{},
-		}
-
-		m.log.Error("Invalid payload for merchant: ", err)
-		ctx.JSON(http.StatusBadRequest, response)
+		log.Error("Invalid payload for merchant: ", err)
+		ctx.Error(apierror.Validation("Invalid Payload for Merchant", common.ValidationErrors(err)))
 		return
 	}
 
-	payload.IdMerchant = id
-
-	merchant, err := m.merchantUc.UpdateMerchant(payload)
+	merchant, err := m.merchantUc.UpdateMerchant(id, payload)
 	if err != nil {
-		response := struct {
-			Message string
-			Data    entity.Merchant
-		}{
-			Message: "Merchant of Id " + id + " Not Found",
-			Data:    entity.Merchant{},
-		}
-
-		m.log.Error("Merchant ID %s not found: ", response)
-		ctx.JSON(http.StatusNotFound, response)
+		log.Error("Merchant ID %s not found: ", err)
+		ctx.Error(merchantError(err, id))
 		return
 	}
 	response := struct {
@@ -230,7 +197,7 @@ func (m *MerchantHandler) updateHandler(ctx *gin.Context) {
 		Data:    merchant,
 	}
 
-	m.log.Info("Merchant updated successfully", response)
+	log.Info("Merchant updated successfully", response)
 	ctx.JSON(http.StatusOK, response)
 }
 
@@ -243,43 +210,261 @@ func (m *MerchantHandler) updateHandler(ctx *gin.Context) {
 // @Security BearerAuth
 // @Param id path string true "Merchant ID"
 // @Success 204 "Successfully deleted"
-// @Failure 401 {object} entity.MerchantErrorResponse "Unauthorized"
-// @Failure 404 {object} entity.MerchantErrorResponse "Merchant not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "Merchant not found"
 // @Router /merchant/{id} [delete]
 func (m *MerchantHandler) deleteHandler(ctx *gin.Context) {
+	log := m.log.WithContext(ctx.Request.Context())
 	id := ctx.Param("id")
 
-	m.log.Info("Starting to delete merchant with id in the handler layer", nil)
+	log.Info("Starting to delete merchant with id in the handler layer", nil)
 	err := m.merchantUc.DeleteMerchant(id)
 	if err != nil {
-		response := struct {
-			Message string
-			Data    entity.Merchant
-		}{
-			Message: "Merchant of Id " + id + " Not Found",
-			Data:    entity.Merchant{},
+		log.Error("Merchant ID %s not found: ", err)
+		ctx.Error(merchantError(err, id))
+		return
+	}
+	response := struct {
+		Message string
+	}{
+		Message: "Merchant of Id " + id + " Deleted",
+	}
+
+	log.Info("Merchant deleted successfully", response)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetMerchantBalance godoc
+// @Summary Get merchant balance
+// @Description Retrieve the current balance of a merchant, only for the owner or an admin
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Merchant ID"
+// @Success 200 {object} entity.MerchantBalance "Merchant balance"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Forbidden"
+// @Failure 404 {object} apierror.Response "Merchant not found"
+// @Router /merchant/{id}/balance [get]
+func (m *MerchantHandler) balanceHandler(ctx *gin.Context) {
+	log := m.log.WithContext(ctx.Request.Context())
+	id := ctx.Param("id")
+	merchantIds := ctx.GetStringSlice("merchantIds")
+	role := ctx.GetString("role")
+
+	log.Info("Starting to retrieve merchant balance with id in the handler layer", nil)
+	balance, err := m.merchantUc.FindMerchantBalance(id, merchantIds, role)
+	if err != nil {
+		log.Error("Failed to retrieve the merchant balance: ", err)
+		ctx.Error(merchantError(err, id))
+		return
+	}
+	response := struct {
+		Message string
+		Data    entity.MerchantBalance
+	}{
+		Message: "Merchant Balance Found",
+		Data:    balance,
+	}
+
+	log.Info("Merchant balance found successfully", nil)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// AdjustBalances godoc
+// @Summary Adjust merchant balances
+// @Description Credit or debit many merchants at once, all adjustments are applied or none
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.BalanceAdjustmentRequest true "Balance delta per merchant id"
+// @Success 200 {object} entity.BalanceAdjustmentRequest "Balances adjusted"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 404 {object} apierror.Response "Unknown merchant"
+// @Router /admin/merchants/balance-adjust [post]
+func (m *MerchantHandler) adjustBalanceHandler(ctx *gin.Context) {
+	log := m.log.WithContext(ctx.Request.Context())
+	var payload entity.BalanceAdjustmentRequest
+
+	log.Info("Starting to adjust merchant balances in the handler layer", nil)
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for balance adjustment: ", err)
+		ctx.Error(apierror.Validation("Invalid Payload for Balance Adjustment", common.ValidationErrors(err)))
+		return
+	}
+
+	if err := m.merchantUc.AdjustMerchantBalances(payload.Adjustments, ctx.GetString("employee")); err != nil {
+		var unknown *repository.ErrUnknownMerchant
+		switch {
+		case errors.Is(err, usecase.ErrInvalidBalanceAdjust):
+			ctx.Error(apierror.Validation(err.Error(), nil))
+		case errors.As(err, &unknown):
+			ctx.Error(apierror.NotFound(err.Error()))
+		default:
+			ctx.Error(apierror.Internal(err))
 		}
 
-		m.log.Error("Merchant ID %s not found: ", response)
-		ctx.JSON(http.StatusNotFound, response)
+		log.Error("Failed to adjust merchant balances: ", err)
 		return
 	}
+
 	response := struct {
 		Message string
+		Data    map[string]float64
 	}{
-		Message: "Merchant of Id " + id + " Deleted",
+		Message: "Merchant Balances Adjusted",
+		Data:    payload.Adjustments,
+	}
+
+	log.Info("Merchant balances adjusted successfully", response)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// SetMerchantPrice godoc
+// @Summary Set a merchant product price
+// @Description Override the catalog price of a product for one merchant
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Merchant ID"
+// @Param productId path string true "Product ID"
+// @Param request body entity.MerchantPriceRequest true "Price the merchant pays"
+// @Success 200 {object} entity.MerchantProductPrice "Price override saved"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 404 {object} apierror.Response "Merchant or product not found"
+// @Router /admin/merchants/{id}/prices/{productId} [put]
+func (m *MerchantHandler) setPriceHandler(ctx *gin.Context) {
+	log := m.log.WithContext(ctx.Request.Context())
+	var payload entity.MerchantPriceRequest
+
+	log.Info("Starting to set a merchant product price in the handler layer", nil)
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for merchant price: ", err)
+		ctx.Error(apierror.Validation("Invalid Payload for Merchant Price", common.ValidationErrors(err)))
+		return
+	}
+
+	price := entity.MerchantProductPrice{IdMerchant: ctx.Param("id"), IdProduct: ctx.Param("productId"), Price: payload.Price}
+	if err := m.merchantUc.SetMerchantProductPrice(price); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidPriceOverride):
+			ctx.Error(apierror.Validation(err.Error(), nil))
+		case errors.Is(err, repository.ErrPriceOverrideTarget):
+			ctx.Error(apierror.NotFound(repository.ErrPriceOverrideTarget.Error()))
+		default:
+			ctx.Error(apierror.Internal(err))
+		}
+
+		log.Error("Failed to set the merchant product price: ", err)
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    entity.MerchantProductPrice
+	}{
+		Message: "Merchant Price Saved",
+		Data:    price,
 	}
 
-	m.log.Info("Merchant deleted successfully", response)
+	log.Info("Merchant product price saved successfully", response)
 	ctx.JSON(http.StatusOK, response)
 }
 
+// ClearMerchantPrice godoc
+// @Summary Clear a merchant product price
+// @Description Remove the price override so the merchant pays the catalog price again
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Merchant ID"
+// @Param productId path string true "Product ID"
+// @Success 204 "Price override cleared"
+// @Failure 404 {object} apierror.Response "No override for this product"
+// @Router /admin/merchants/{id}/prices/{productId} [delete]
+func (m *MerchantHandler) clearPriceHandler(ctx *gin.Context) {
+	log := m.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to clear a merchant product price in the handler layer", nil)
+
+	if err := m.merchantUc.ClearMerchantProductPrice(ctx.Param("id"), ctx.Param("productId")); err != nil {
+		if errors.Is(err, repository.ErrPriceOverrideNotFound) {
+			ctx.Error(apierror.NotFound(repository.ErrPriceOverrideNotFound.Error()))
+		} else {
+			ctx.Error(apierror.Internal(err))
+		}
+
+		log.Error("Failed to clear the merchant product price: ", err)
+		return
+	}
+
+	log.Info("Merchant product price cleared successfully", nil)
+	ctx.Status(http.StatusNoContent)
+}
+
+// MerchantCatalog godoc
+// @Summary Get the merchant catalog
+// @Description List the active products with the price this merchant pays, only for the owner or an admin
+// @Tags merchants
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Merchant ID"
+// @Success 200 {array} entity.MerchantCatalogItem "Merchant catalog"
+// @Failure 403 {object} apierror.Response "Forbidden"
+// @Failure 404 {object} apierror.Response "Merchant not found"
+// @Router /merchant/{id}/products [get]
+func (m *MerchantHandler) catalogHandler(ctx *gin.Context) {
+	log := m.log.WithContext(ctx.Request.Context())
+	id := ctx.Param("id")
+	merchantIds := ctx.GetStringSlice("merchantIds")
+	role := ctx.GetString("role")
+
+	log.Info("Starting to retrieve the merchant catalog in the handler layer", nil)
+	items, err := m.merchantUc.FindMerchantCatalog(id, merchantIds, role)
+	if err != nil {
+		log.Error("Failed to retrieve the merchant catalog: ", err)
+		ctx.Error(merchantError(err, id))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    []entity.MerchantCatalogItem
+	}{
+		Message: "Merchant Catalog",
+		Data:    items,
+	}
+
+	log.Info("Merchant catalog found successfully", nil)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// merchantError maps the errors of the merchant use case, the ones it does not know are 500s.
+func merchantError(err error, id string) *apierror.Error {
+	switch {
+	case errors.Is(err, usecase.ErrMerchantForbidden):
+		return apierror.Forbidden(err.Error())
+	case errors.Is(err, usecase.ErrMerchantNotFound):
+		return apierror.NotFound("Merchant of Id " + id + " Not Found")
+	}
+	return apierror.FromRepository(err, "Merchant of Id "+id+" Not Found")
+}
+
 func (m *MerchantHandler) Route() {
-	m.rg.POST(config.PostMerchant, m.authMiddleware.RequireToken("admin"), m.createHandler)
-	m.rg.GET(config.GetMerchantList, m.authMiddleware.RequireToken("admin"), m.listHandler)
-	m.rg.GET(config.GetMerchant, m.authMiddleware.RequireToken("admin"), m.getHandler)
-	m.rg.PUT(config.PutMerchant, m.authMiddleware.RequireToken("admin"), m.updateHandler)
-	m.rg.DELETE(config.DeleteMerchant, m.authMiddleware.RequireToken("admin"), m.deleteHandler)
+	m.rg.POST(config.PostMerchant, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.createHandler)
+	m.rg.GET(config.GetMerchantList, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.listHandler)
+	m.rg.GET(config.GetMerchant, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.getHandler)
+	m.rg.PUT(config.PutMerchant, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.updateHandler)
+	m.rg.DELETE(config.DeleteMerchant, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.deleteHandler)
+	m.rg.GET(config.GetMerchantBalance, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin", "employee"), m.balanceHandler)
+	m.rg.POST(config.PostBalanceAdjust, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.adjustBalanceHandler)
+	m.rg.PUT(config.PutMerchantPrice, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.setPriceHandler)
+	m.rg.DELETE(config.DeleteMerchantPrice, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.clearPriceHandler)
+	m.rg.GET(config.GetMerchantCatalog, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin", "employee"), m.catalogHandler)
 }
 
 func NewMerchantHandler(merchantUc usecase.MerchantUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *MerchantHandler {
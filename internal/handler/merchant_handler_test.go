@@ -9,9 +9,14 @@ import (
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/mock/middleware_mock"
 	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/usecase"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -28,7 +33,7 @@ func (m *MerchantHandlerTest) SetupTest() {
 	m.merchantUc = new(usecase_mock.MerchantUsecaseMock)
 	m.authMiddleware = new(middleware_mock.AuthMiddlewareMock)
 
-	m.router = gin.Default()
+	m.router = newTestRouter()
 	gin.SetMode(gin.TestMode)
 
 	rg := m.router.Group("/api/v1")
@@ -55,7 +60,13 @@ func (m *MerchantHandlerTest) TestCreate() {
 	if err != nil {
 		m.T().Fatalf("error '%s' occured when marshaling the payload", err)
 	}
-	m.merchantUc.On("RegisterNewMerchant", payload).Return(payload, nil)
+	// the id and balance are assigned by the server, only the request fields reach the usecase
+	m.merchantUc.On("RegisterNewMerchant", entity.Merchant{
+		IdUser:       payload.IdUser,
+		NameMerchant: payload.NameMerchant,
+		Address:      payload.Address,
+		IdProduct:    payload.IdProduct,
+	}).Return(payload, nil)
 	request, err := http.NewRequest("POST", "/api/v1/merchant", bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		m.T().Fatalf("error '%s' occured when creating the request", err)
@@ -67,21 +78,39 @@ func (m *MerchantHandlerTest) TestCreate() {
 	m.Equal(http.StatusCreated, w.Code)
 }
 
+func (m *MerchantHandlerTest) TestCreate_ValidationErrors() {
+	request, _ := http.NewRequest("POST", "/api/v1/merchant", bytes.NewBufferString(`{"nameMerchant": "Merchant Test"}`))
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusBadRequest, w.Code)
+	var response struct {
+		Code    string            `json:"code"`
+		Details map[string]string `json:"details"`
+	}
+	m.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	m.Equal(apierror.CodeValidation, response.Code)
+	m.Equal(map[string]string{
+		"idUser":    "idUser is required (required)",
+		"address":   "address is required (required)",
+		"idProduct": "idProduct is required (required)",
+	}, response.Details)
+	m.merchantUc.AssertNotCalled(m.T(), "RegisterNewMerchant")
+}
+
 func (m *MerchantHandlerTest) TestUpdate() {
-	payload := entity.Merchant{
-		IdMerchant:   "uuid-merchant-test",
+	payload := entity.MerchantUpdateRequest{
 		IdUser:       "uuid-user-test",
 		NameMerchant: "Merchant Test",
 		Address:      "address-test",
 		IdProduct:    "uuid-product-test",
-		Balance:      10000,
 	}
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		m.T().Fatalf("error '%s' occured when marshaling the payload", err)
 	}
-	m.merchantUc.On("UpdateMerchant", payload).Return(payload, nil)
-	request, err := http.NewRequest("PUT", "/api/v1/merchant/"+payload.IdMerchant, bytes.NewBuffer(jsonPayload))
+	m.merchantUc.On("UpdateMerchant", "uuid-merchant-test", payload).Return(entity.Merchant{IdMerchant: "uuid-merchant-test"}, nil)
+	request, err := http.NewRequest("PUT", "/api/v1/merchant/uuid-merchant-test", bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		m.T().Fatalf("error '%s' occured when creating the request", err)
 	}
@@ -92,6 +121,29 @@ func (m *MerchantHandlerTest) TestUpdate() {
 	m.Equal(http.StatusOK, w.Code)
 }
 
+func (m *MerchantHandlerTest) TestUpdate_ZeroLimitIsKeptApartFromAnOmittedOne() {
+	noLimit := 0
+	m.merchantUc.On("UpdateMerchant", "uuid-merchant-test", entity.MerchantUpdateRequest{DailyLimitCount: &noLimit}).
+		Return(entity.Merchant{IdMerchant: "uuid-merchant-test"}, nil)
+	request, _ := http.NewRequest("PUT", "/api/v1/merchant/uuid-merchant-test", bytes.NewBufferString(`{"dailyLimitCount": 0}`))
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusOK, w.Code)
+	m.merchantUc.AssertExpectations(m.T())
+}
+
+func (m *MerchantHandlerTest) TestUpdate_NegativeLimit() {
+	request, _ := http.NewRequest("PUT", "/api/v1/merchant/uuid-merchant-test", bytes.NewBufferString(`{"dailyLimitAmount": -1}`))
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusBadRequest, w.Code)
+	m.merchantUc.AssertNotCalled(m.T(), "UpdateMerchant", mock.Anything, mock.Anything)
+}
+
 func (m *MerchantHandlerTest) TestList() {
 	m.merchantUc.On("FindAllMerchant").Return([]entity.Merchant{}, nil)
 	request, err := http.NewRequest("GET", "/api/v1/merchants", nil)
@@ -133,6 +185,118 @@ func (m *MerchantHandlerTest) TestDelete() {
 	m.Equal(http.StatusOK, w.Code)
 }
 
+func (m *MerchantHandlerTest) TestBalance_owner() {
+	id := "uuid-merchant-test"
+	balance := entity.MerchantBalance{IdMerchant: id, Balance: 10000, CheckedAt: time.Now()}
+	m.merchantUc.On("FindMerchantBalance", id, []string{id}, "employee").Return(balance, nil)
+	m.router.GET("/api/v1/merchant/:id/balance", func(ctx *gin.Context) {
+		ctx.Set("merchantIds", []string{id})
+		ctx.Set("role", "employee")
+	}, m.merchantHandler.balanceHandler)
+	request, err := http.NewRequest("GET", "/api/v1/merchant/"+id+"/balance", nil)
+	if err != nil {
+		m.T().Fatalf("error '%s' occured when creating the request", err)
+	}
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusOK, w.Code)
+	m.Contains(w.Body.String(), `"balance":10000`)
+}
+
+func (m *MerchantHandlerTest) TestBalance_forbidden() {
+	id := "uuid-merchant-test"
+	m.merchantUc.On("FindMerchantBalance", id, []string{"uuid-other-merchant"}, "employee").Return(entity.MerchantBalance{}, usecase.ErrMerchantForbidden)
+	m.router.GET("/api/v1/merchant/:id/balance", func(ctx *gin.Context) {
+		ctx.Set("merchantIds", []string{"uuid-other-merchant"})
+		ctx.Set("role", "employee")
+	}, m.merchantHandler.balanceHandler)
+	request, err := http.NewRequest("GET", "/api/v1/merchant/"+id+"/balance", nil)
+	if err != nil {
+		m.T().Fatalf("error '%s' occured when creating the request", err)
+	}
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusForbidden, w.Code)
+}
+
+func (m *MerchantHandlerTest) TestSetPrice() {
+	price := entity.MerchantProductPrice{IdMerchant: "uuid-merchant-test", IdProduct: "uuid-product-test", Price: 10500}
+	m.merchantUc.On("SetMerchantProductPrice", price).Return(nil)
+	m.router.PUT("/api/v1/admin/merchants/:id/prices/:productId", m.merchantHandler.setPriceHandler)
+	request, err := http.NewRequest("PUT", "/api/v1/admin/merchants/uuid-merchant-test/prices/uuid-product-test", bytes.NewBufferString(`{"price": 10500}`))
+	if err != nil {
+		m.T().Fatalf("error '%s' occured when creating the request", err)
+	}
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusOK, w.Code)
+	m.Contains(w.Body.String(), `"price":10500`)
+}
+
+func (m *MerchantHandlerTest) TestSetPrice_unknownProduct() {
+	price := entity.MerchantProductPrice{IdMerchant: "uuid-merchant-test", IdProduct: "uuid-missing", Price: 10500}
+	m.merchantUc.On("SetMerchantProductPrice", price).Return(repository.ErrPriceOverrideTarget)
+	m.router.PUT("/api/v1/admin/merchants/:id/prices/:productId", m.merchantHandler.setPriceHandler)
+	request, _ := http.NewRequest("PUT", "/api/v1/admin/merchants/uuid-merchant-test/prices/uuid-missing", bytes.NewBufferString(`{"price": 10500}`))
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusNotFound, w.Code)
+}
+
+func (m *MerchantHandlerTest) TestClearPrice_notFound() {
+	m.merchantUc.On("ClearMerchantProductPrice", "uuid-merchant-test", "uuid-product-test").Return(repository.ErrPriceOverrideNotFound)
+	m.router.DELETE("/api/v1/admin/merchants/:id/prices/:productId", m.merchantHandler.clearPriceHandler)
+	request, _ := http.NewRequest("DELETE", "/api/v1/admin/merchants/uuid-merchant-test/prices/uuid-product-test", nil)
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusNotFound, w.Code)
+}
+
+func (m *MerchantHandlerTest) TestCatalog_owner() {
+	id := "uuid-merchant-test"
+	items := []entity.MerchantCatalogItem{{IdProduct: "uuid-product-test", NameProvider: "Telkomsel", Nominal: 10000, Price: 10500, PriceSource: entity.PriceSourceMerchant}}
+	m.merchantUc.On("FindMerchantCatalog", id, []string{id}, "employee").Return(items, nil)
+	m.router.GET("/api/v1/merchant/:id/products", func(ctx *gin.Context) {
+		ctx.Set("merchantIds", []string{id})
+		ctx.Set("role", "employee")
+	}, m.merchantHandler.catalogHandler)
+	request, _ := http.NewRequest("GET", "/api/v1/merchant/"+id+"/products", nil)
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusOK, w.Code)
+	m.Contains(w.Body.String(), `"priceSource":"merchant"`)
+}
+
+func (m *MerchantHandlerTest) TestAdjustBalance_merchantIdNotUUID() {
+	m.router.POST("/api/v1/admin/merchants/balance-adjust", m.merchantHandler.adjustBalanceHandler)
+	request, _ := http.NewRequest("POST", "/api/v1/admin/merchants/balance-adjust", bytes.NewBufferString(`{"adjustments": {"merchant-1": 150000}}`))
+
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, request)
+
+	m.Equal(http.StatusBadRequest, w.Code)
+	var response struct {
+		Code    string            `json:"code"`
+		Details map[string]string `json:"details"`
+	}
+	m.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	m.Equal(apierror.CodeValidation, response.Code)
+	m.Equal(map[string]string{"adjustments[merchant-1]": "adjustments[merchant-1] must be a UUID (uuid)"}, response.Details)
+	m.merchantUc.AssertNotCalled(m.T(), "AdjustMerchantBalances")
+}
+
 func TestMerchantHandlerSuite(t *testing.T) {
 	suite.Run(t, new(MerchantHandlerTest))
 }
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"errors"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondWeakPassword answers 400 with every password rule that failed in the details, it reports false for any other
+// error.
+func respondWeakPassword(ctx *gin.Context, err error) bool {
+	var weak *usecase.WeakPasswordError
+	if !errors.As(err, &weak) {
+		return false
+	}
+
+	ctx.Error(apierror.Validation(err.Error(), gin.H{"rules": weak.Rules}).WithCode("weak_password"))
+	return true
+}
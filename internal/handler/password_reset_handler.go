@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity/dto"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordResetHandler struct {
+	useCase usecase.PasswordResetUseCase
+	rg      *gin.RouterGroup
+	log     *logger.Logger
+}
+
+func (p *PasswordResetHandler) Route() {
+	p.rg.POST(config.ForgotPassword, p.forgotPasswordHandler)
+	p.rg.POST(config.ResetPassword, p.resetPasswordHandler)
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Send a single-use reset token to the user, the response is the same whether the username exists or not
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body dto.ForgotPasswordRequest true "Username"
+// @Success 202 {object} dto.ErrorResponse "Reset requested"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Router /auth/forgot-password [post]
+func (p *PasswordResetHandler) forgotPasswordHandler(ctx *gin.Context) {
+	log := p.log.WithContext(ctx.Request.Context())
+	var payload dto.ForgotPasswordRequest
+
+	log.Info("Starting to request a password reset in the handler layer", nil)
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	if err := p.useCase.RequestPasswordReset(payload.Username); err != nil {
+		log.Error("Failed to request a password reset: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset token has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Reset a password
+// @Description Set a new password with a reset token, every token works once and only before it expires
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body dto.ResetPasswordRequest true "Reset token and new password"
+// @Success 204 "Password reset"
+// @Failure 400 {object} apierror.Response "Invalid, expired or used token, or weak password"
+// @Router /auth/reset-password [post]
+func (p *PasswordResetHandler) resetPasswordHandler(ctx *gin.Context) {
+	log := p.log.WithContext(ctx.Request.Context())
+	var payload dto.ResetPasswordRequest
+
+	log.Info("Starting to reset a password in the handler layer", nil)
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	err := p.useCase.ResetPassword(payload.Token, payload.NewPassword, clientInfo(ctx))
+	switch {
+	case errors.Is(err, repository.ErrInvalidResetToken):
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	case respondWeakPassword(ctx, err):
+		return
+	case err != nil:
+		log.Error("Failed to reset the password: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func NewPasswordResetHandler(useCase usecase.PasswordResetUseCase, rg *gin.RouterGroup, log *logger.Logger) *PasswordResetHandler {
+	return &PasswordResetHandler{useCase: useCase, rg: rg, log: log}
+}
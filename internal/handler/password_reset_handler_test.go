@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type PasswordResetHandlerTest struct {
+	suite.Suite
+	useCase *usecase_mock.PasswordResetUseCaseMock
+	router  *gin.Engine
+	log     logger.Logger
+}
+
+func (p *PasswordResetHandlerTest) SetupTest() {
+	p.useCase = new(usecase_mock.PasswordResetUseCaseMock)
+	gin.SetMode(gin.TestMode)
+	p.router = newTestRouter()
+	p.log = logger.NewLogger()
+	NewPasswordResetHandler(p.useCase, p.router.Group("/api/v1"), &p.log).Route()
+}
+
+func (p *PasswordResetHandlerTest) serve(path, body string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	p.NoError(err)
+
+	w := httptest.NewRecorder()
+	p.router.ServeHTTP(w, request)
+	return w
+}
+
+func (p *PasswordResetHandlerTest) TestForgotPassword() {
+	p.useCase.On("RequestPasswordReset", "john").Return(nil)
+
+	w := p.serve("/api/v1/auth/forgot-password", `{"username": "john"}`)
+
+	p.Equal(http.StatusAccepted, w.Code)
+}
+
+func (p *PasswordResetHandlerTest) TestResetPassword() {
+	p.useCase.On("ResetPassword", "plain-token", "new-pass1", entity.ClientInfo{}).Return(nil)
+
+	w := p.serve("/api/v1/auth/reset-password", `{"token": "plain-token", "newPassword": "new-pass1"}`)
+
+	p.Equal(http.StatusNoContent, w.Code)
+}
+
+func (p *PasswordResetHandlerTest) TestResetPassword_InvalidToken() {
+	p.useCase.On("ResetPassword", "used-token", "new-pass1", entity.ClientInfo{}).Return(repository.ErrInvalidResetToken)
+
+	w := p.serve("/api/v1/auth/reset-password", `{"token": "used-token", "newPassword": "new-pass1"}`)
+
+	p.Equal(http.StatusBadRequest, w.Code)
+	p.Contains(w.Body.String(), "invalid or expired reset token")
+}
+
+func TestPasswordResetHandlerSuite(t *testing.T) {
+	suite.Run(t, new(PasswordResetHandlerTest))
+}
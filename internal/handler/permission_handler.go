@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionHandler struct {
+	permissionUc   usecase.PermissionUseCase
+	rg             *gin.RouterGroup
+	authMiddleware middleware.AuthMiddleware
+	log            *logger.Logger
+}
+
+// listRolePermissionsHandler godoc
+// @Summary List role permissions
+// @Description Get the permissions of every role, logins put them in the permissions claim of the access token
+// @Tags role-permissions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.RolePermissions "Permissions of each role"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Not an admin"
+// @Router /admin/roles/permissions [get]
+func (p *PermissionHandler) listRolePermissionsHandler(ctx *gin.Context) {
+	log := p.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to retrieve the role permissions in the handler layer", nil)
+
+	roles, err := p.permissionUc.ListRolePermissions()
+	if err != nil {
+		log.Error("Failed to retrieve the role permissions: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("Role permissions retrieved successfully", nil)
+	ctx.JSON(http.StatusOK, roles)
+}
+
+// updateRolePermissionsHandler godoc
+// @Summary Update role permissions
+// @Description Replace the permissions of a role. Tokens that were already issued keep their permissions until they are refreshed
+// @Tags role-permissions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param role path string true "Role" Enums(admin, employee)
+// @Param request body entity.RolePermissionsRequest true "New permissions of the role"
+// @Success 200 {object} entity.RolePermissions "Updated permissions"
+// @Failure 400 {object} apierror.Response "Invalid input, unknown role or unknown permission"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Not an admin"
+// @Router /admin/roles/{role}/permissions [put]
+func (p *PermissionHandler) updateRolePermissionsHandler(ctx *gin.Context) {
+	log := p.log.WithContext(ctx.Request.Context())
+	var payload entity.RolePermissionsRequest
+
+	log.Info("Starting to update the role permissions in the handler layer", nil)
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for updating the role permissions", err)
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	role, err := p.permissionUc.UpdateRolePermissions(ctx.Param("role"), payload.Permissions, ctx.GetString("employee"))
+	if errors.Is(err, usecase.ErrInvalidRole) || errors.Is(err, usecase.ErrUnknownPermission) {
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to update the role permissions: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("Role permissions updated successfully", nil)
+	ctx.JSON(http.StatusOK, role)
+}
+
+// Route keeps the mappings behind the admin role rather than a permission, so an edit can't lock every admin out.
+func (p *PermissionHandler) Route() {
+	p.rg.GET(config.GetRolePermissions, p.authMiddleware.RequireToken(), p.authMiddleware.RequireRoles("admin"), p.listRolePermissionsHandler)
+	p.rg.PUT(config.PutRolePermissions, p.authMiddleware.RequireToken(), p.authMiddleware.RequireRoles("admin"), p.updateRolePermissionsHandler)
+}
+
+func NewPermissionHandler(permissionUc usecase.PermissionUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *PermissionHandler {
+	return &PermissionHandler{permissionUc: permissionUc, authMiddleware: authMiddleware, rg: rg, log: log}
+}
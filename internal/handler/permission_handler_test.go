@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/middleware_mock"
+	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/usecase"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type PermissionHandlerTest struct {
+	suite.Suite
+	permissionUc      *usecase_mock.PermissionUseCaseMock
+	router            *gin.Engine
+	permissionHandler *PermissionHandler
+	log               logger.Logger
+}
+
+func (s *PermissionHandlerTest) SetupTest() {
+	s.permissionUc = new(usecase_mock.PermissionUseCaseMock)
+
+	gin.SetMode(gin.TestMode)
+	s.router = newTestRouter()
+
+	s.log = logger.NewLogger()
+	s.permissionHandler = NewPermissionHandler(s.permissionUc, new(middleware_mock.AuthMiddlewareMock), s.router.Group("/api/v1"), &s.log)
+	authenticated := func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-admin-test")
+	}
+	s.router.GET("/api/v1/admin/roles/permissions", authenticated, s.permissionHandler.listRolePermissionsHandler)
+	s.router.PUT("/api/v1/admin/roles/:role/permissions", authenticated, s.permissionHandler.updateRolePermissionsHandler)
+}
+
+func (s *PermissionHandlerTest) serve(method, url, body string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(method, url, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, request)
+	return w
+}
+
+func (s *PermissionHandlerTest) TestList() {
+	s.permissionUc.On("ListRolePermissions").Return([]entity.RolePermissions{
+		{Role: "admin", Permissions: []string{"product:write"}},
+		{Role: "employee", Permissions: []string{}},
+	}, nil).Once()
+
+	w := s.serve(http.MethodGet, "/api/v1/admin/roles/permissions", "")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `{"role":"employee","permissions":[]}`)
+}
+
+func (s *PermissionHandlerTest) TestUpdate() {
+	s.permissionUc.On("UpdateRolePermissions", "employee", []string{"product:write"}, "uuid-admin-test").
+		Return(entity.RolePermissions{Role: "employee", Permissions: []string{"product:write"}}, nil).Once()
+
+	w := s.serve(http.MethodPut, "/api/v1/admin/roles/employee/permissions", `{"permissions":["product:write"]}`)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"permissions":["product:write"]`)
+}
+
+func (s *PermissionHandlerTest) TestUpdate_UnknownPermission() {
+	s.permissionUc.On("UpdateRolePermissions", "employee", []string{"product:delete"}, "uuid-admin-test").
+		Return(entity.RolePermissions{}, fmt.Errorf("%w: product:delete", usecase.ErrUnknownPermission)).Once()
+
+	w := s.serve(http.MethodPut, "/api/v1/admin/roles/employee/permissions", `{"permissions":["product:delete"]}`)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), "product:delete")
+}
+
+func (s *PermissionHandlerTest) TestUpdate_InvalidRole() {
+	s.permissionUc.On("UpdateRolePermissions", "owner", []string{}, "uuid-admin-test").Return(entity.RolePermissions{}, usecase.ErrInvalidRole).Once()
+
+	w := s.serve(http.MethodPut, "/api/v1/admin/roles/owner/permissions", `{"permissions":[]}`)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *PermissionHandlerTest) TestUpdate_MissingPermissions() {
+	w := s.serve(http.MethodPut, "/api/v1/admin/roles/employee/permissions", `{}`)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.permissionUc.AssertNotCalled(s.T(), "UpdateRolePermissions")
+}
+
+func (s *PermissionHandlerTest) TestUpdate_Failure() {
+	s.permissionUc.On("UpdateRolePermissions", "employee", []string{}, "uuid-admin-test").Return(entity.RolePermissions{}, errors.New("db down")).Once()
+
+	w := s.serve(http.MethodPut, "/api/v1/admin/roles/employee/permissions", `{"permissions":[]}`)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func TestPermissionHandlerTest(t *testing.T) {
+	suite.Run(t, new(PermissionHandlerTest))
+}
@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
 	"server-pulsa-app/internal/usecase"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,11 +28,14 @@ type ProductController struct {
 }
 
 func (p *ProductController) Route() {
-	p.rg.POST(config.PostProduct, p.authMiddleware.RequireToken("admin"), p.CreateProduct)
-	p.rg.GET(config.GetProductList, p.authMiddleware.RequireToken("admin"), p.GetAllProduct)
-	p.rg.GET(config.GetProduct, p.authMiddleware.RequireToken("admin"), p.GetProductById)
-	p.rg.PUT(config.PutProduct, p.authMiddleware.RequireToken("admin"), p.UpdateProduct)
-	p.rg.DELETE(config.DeleteProduct, p.authMiddleware.RequireToken("admin"), p.DeleteProduct)
+	p.rg.POST(config.PostProduct, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProductWrite), p.CreateProduct)
+	// the whole catalog, clients polling it would crowd out everything else
+	p.rg.GET(config.GetProductList, p.authMiddleware.RequireToken(), p.authMiddleware.RequireRoles("admin", "employee"), p.authMiddleware.RateLimit(60), p.GetAllProduct)
+	p.rg.GET(config.GetProduct, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProductWrite), p.GetProductById)
+	p.rg.GET(config.GetProductCode, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProductWrite), p.GetProductByCode)
+	p.rg.POST(config.PostProductIds, p.authMiddleware.RequireToken(), p.authMiddleware.RequireRoles("admin", "employee"), p.GetProductsByIds)
+	p.rg.PUT(config.PutProduct, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProductWrite), p.UpdateProduct)
+	p.rg.DELETE(config.DeleteProduct, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProductWrite), p.DeleteProduct)
 }
 
 // CreateProduct godoc
@@ -39,24 +47,30 @@ func (p *ProductController) Route() {
 // @Security BearerAuth
 // @Param request body entity.ProductRequest true "Product details"
 // @Success 201 {object} entity.ProductResponse "Successfully created product"
-// @Failure 400 {object} entity.ProductErrorResponse "Invalid input"
-// @Failure 401 {object} entity.ProductErrorResponse "Unauthorized"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 409 {object} apierror.Response "Code already used by another product"
 // @Router /product [post]
 func (p *ProductController) CreateProduct(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
 	var payload entity.Product
 
-	p.log.Info("Starting to create a new product in the handler layer", nil)
+	log.Info("Starting to create a new product in the handler layer", nil)
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		p.log.Error("Invalid payload for product: ", err)
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		log.Error("Invalid payload for product: ", err)
+		c.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
 		return
 	}
 
 	Product, err := p.useCase.CreateNewProduct(payload)
 	if err != nil {
-		p.log.Error("Product creation failed", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		log.Error("Product creation failed", err)
+		if errors.Is(err, repository.ErrProductCodeTaken) {
+			c.Error(apierror.Conflict(err.Error()))
+			return
+		}
+		c.Error(apierror.Internal(err))
 		return
 	}
 
@@ -68,29 +82,55 @@ func (p *ProductController) CreateProduct(c *gin.Context) {
 		Data:    Product,
 	}
 
-	p.log.Info("Product created successfully", response)
+	log.Info("Product created successfully", response)
 	c.JSON(http.StatusCreated, response)
 }
 
 // ListProducts godoc
 // @Summary List all products
-// @Description Get a list of all products
+// @Description Get a list of all products. Admins see every product, other users see the global products plus the private products of their merchants
 // @Tags products
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param sort query string false "nominal, price, provider or updated_at, defaults to provider then nominal"
+// @Param order query string false "asc or desc" default(asc)
 // @Param page query int false "Page number"
 // @Param limit query int false "Items per page"
+// @Param If-None-Match header string false "ETag of a previous response"
 // @Success 200 {array} []entity.ProductResponse "List of products"
-// @Failure 401 {object} entity.ProductErrorResponse "Unauthorized"
+// @Success 304 "Product list not modified"
+// @Failure 401 {object} apierror.Response "Unauthorized"
 // @Router /products [get]
 func (p *ProductController) GetAllProduct(c *gin.Context) {
-	p.log.Info("Starting to retrieve all product in the handler layer", nil)
+	log := p.log.WithContext(c.Request.Context())
+	log.Info("Starting to retrieve all product in the handler layer", nil)
+
+	page, errPage := strconv.Atoi(c.DefaultQuery("page", "0"))
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if errPage != nil || errLimit != nil || page < 0 || limit < 0 {
+		c.Error(apierror.Validation("page and limit must be positive numbers", nil))
+		return
+	}
 
-	Products, err := p.useCase.FindAllProduct()
+	query := entity.ProductQuery{
+		Sort:  c.Query("sort"),
+		Order: c.Query("order"),
+		Page:  page,
+		Limit: limit,
+	}
+	if c.GetString("role") != "admin" {
+		query.UserId = c.GetString("employee")
+	}
+
+	Products, err := p.useCase.FindAllProduct(query)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidProductSort) {
+			c.Error(apierror.Validation(err.Error(), nil))
+			return
+		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"err": "Failed to retrieve data Products"})
+		c.Error(apierror.Internal(err))
 		return
 	}
 
@@ -103,13 +143,13 @@ func (p *ProductController) GetAllProduct(c *gin.Context) {
 			Data:    Products,
 		}
 
-		p.log.Info("Product found successfully", nil)
-		c.JSON(http.StatusOK, response)
+		log.Info("Product found successfully", nil)
+		common.SendJSONWithETag(c, http.StatusOK, response)
 		return
 	}
 
-	p.log.Info("Product not found", nil)
-	c.JSON(http.StatusOK, gin.H{"message": "List Product empty"})
+	log.Info("Product not found", nil)
+	common.SendJSONWithETag(c, http.StatusOK, gin.H{"message": "List Product empty"})
 }
 
 // GetProduct godoc
@@ -120,18 +160,21 @@ func (p *ProductController) GetAllProduct(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Product ID"
+// @Param If-None-Match header string false "ETag of a previous response"
 // @Success 200 {object} entity.ProductResponse "Product found"
-// @Failure 404 {object} entity.ProductErrorResponse "Product not found"
-// @Failure 401 {object} entity.ProductErrorResponse "Unauthorized"
+// @Success 304 "Product not modified"
+// @Failure 404 {object} apierror.Response "Product not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
 // @Router /product/{id} [get]
 func (p *ProductController) GetProductById(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
 	id := (c.Param("id"))
 
-	p.log.Info("Starting to retrieve product with id in the handler layer", nil)
+	log.Info("Starting to retrieve product with id in the handler layer", nil)
 	Product, err := p.useCase.FindProductById(id)
 	if err != nil {
-		p.log.Error("Product ID %s not found: ", id)
-		c.JSON(http.StatusNotFound, gin.H{"err": "Product not found"})
+		log.Error("Product ID %s not found: ", id)
+		c.Error(apierror.NotFound("Product not found"))
 		return
 	}
 
@@ -143,7 +186,91 @@ func (p *ProductController) GetProductById(c *gin.Context) {
 		Data:    Product,
 	}
 
-	p.log.Info("Product found successfully", nil)
+	log.Info("Product found successfully", nil)
+	common.SendJSONWithETag(c, http.StatusOK, response)
+}
+
+// GetProductByCode godoc
+// @Summary Get product by provider code
+// @Description Retrieve a product by the code its provider knows it by, used to map provider webhooks to products
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Provider product code"
+// @Success 200 {object} entity.ProductResponse "Product found"
+// @Failure 404 {object} apierror.Response "No product has the code"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /product/code/{code} [get]
+func (p *ProductController) GetProductByCode(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	code := c.Param("code")
+
+	log.Info("Starting to retrieve product with code in the handler layer", nil)
+	product, err := p.useCase.FindProductByCode(code)
+	if err != nil {
+		if errors.Is(err, repository.ErrProductNotFound) {
+			c.Error(apierror.NotFound("Product not found"))
+			return
+		}
+
+		log.Error("Failed to retrieve the product by code: ", err)
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    entity.Product
+	}{
+		Message: "Product found",
+		Data:    product,
+	}
+
+	log.Info("Product found successfully", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetProductsByIds godoc
+// @Summary Get products by ids
+// @Description Retrieve up to 100 products with one request, for example to validate a cart. Ids without a product the caller can see are listed as missing
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.ProductBatchRequest true "Product ids"
+// @Success 200 {object} entity.ProductBatch "Products found and the missing ids"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /products/batch [post]
+func (p *ProductController) GetProductsByIds(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	var payload entity.ProductBatchRequest
+
+	log.Info("Starting to retrieve products by ids in the handler layer", nil)
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for the product batch: ", err)
+		c.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	batch, err := p.useCase.FindProductsByIds(payload.Ids, c.GetStringSlice("merchantIds"), c.GetString("role"))
+	if err != nil {
+		log.Error("Failed to retrieve the products by ids: ", err)
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    entity.ProductBatch
+	}{
+		Message: "Products found",
+		Data:    batch,
+	}
+
+	log.Info("Products by ids found successfully", nil)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -157,29 +284,46 @@ func (p *ProductController) GetProductById(c *gin.Context) {
 // @Param id path string true "Product ID"
 // @Param request body entity.ProductRequest true "Updated product details"
 // @Success 200 {object} entity.ProductResponse "Successfully updated product"
-// @Failure 400 {object} entity.ProductErrorResponse "Invalid input"
-// @Failure 401 {object} entity.ProductErrorResponse "Unauthorized"
-// @Failure 404 {object} entity.ProductErrorResponse "Product not found"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "Product not found"
+// @Failure 409 {object} apierror.Response "Product was modified since it was read, or its code is used by another product"
 // @Router /product/{id} [put]
 func (p *ProductController) UpdateProduct(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
 	var payload entity.Product
 	id := (c.Param("id"))
 
-	p.log.Info("Starting to update product with id in the handler layer", nil)
+	log.Info("Starting to update product with id in the handler layer", nil)
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		p.log.Error("Invalid payload for product: ", err)
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		log.Error("Invalid payload for product: ", err)
+		c.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	if payload.Version <= 0 {
+		log.Error("Missing product version: ", id)
+		c.Error(apierror.Validation("version is required, send the version of the product you are editing", nil))
 		return
 	}
 
 	payload.IdProduct = id
 
-	p.log.Info("Updating product ID %s", id)
+	log.Info("Updating product ID %s", id)
 	product, err := p.useCase.UpdateProduct(payload)
 	if err != nil {
+		if errors.Is(err, repository.ErrProductVersionConflict) {
+			log.Error("Product ID %s version conflict: ", id)
+			c.Error(apierror.Conflict(err.Error()))
+			return
+		}
+		if errors.Is(err, repository.ErrProductCodeTaken) {
+			c.Error(apierror.Conflict(err.Error()))
+			return
+		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		c.Error(apierror.Internal(err))
 		return
 	}
 	response := struct {
@@ -190,7 +334,7 @@ func (p *ProductController) UpdateProduct(c *gin.Context) {
 		Data:    product,
 	}
 
-	p.log.Info("Product updated successfully", response)
+	log.Info("Product updated successfully", response)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -203,17 +347,32 @@ func (p *ProductController) UpdateProduct(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path string true "Product ID"
 // @Success 204 "Successfully deleted"
-// @Failure 401 {object} entity.ProductErrorResponse "Unauthorized"
-// @Failure 404 {object} entity.ProductErrorResponse "Product not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "Product not found"
+// @Failure 409 {object} apierror.Response "Product still used by transactions"
 // @Router /product/{id} [delete]
 func (p *ProductController) DeleteProduct(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
 	id := c.Param("id")
 
-	p.log.Info("Starting to delete product with id in the handler layer", nil)
-	err := p.useCase.DeleteProduct(id)
+	log.Info("Starting to delete product with id in the handler layer", nil)
+	err := p.useCase.DeleteProduct(id, c.GetString("employee"))
 	if err != nil {
-		p.log.Error("Product ID %s not found: ", id)
-		c.JSON(http.StatusNotFound, err.Error())
+		var inUse *repository.ErrProductInUse
+		if errors.As(err, &inUse) {
+			log.Error("Product ID %s still in use: ", inUse.Count)
+			c.Error(apierror.Conflict(inUse.Error()))
+			return
+		}
+
+		if errors.Is(err, usecase.ErrProductNotFound) {
+			log.Error("Product ID %s not found: ", id)
+			c.Error(apierror.NotFound("Product not found"))
+			return
+		}
+
+		log.Error("Failed to delete the product: ", err)
+		c.Error(apierror.Internal(err))
 		return
 	}
 
@@ -225,7 +384,7 @@ func (p *ProductController) DeleteProduct(c *gin.Context) {
 		Data:    entity.Product{},
 	}
 
-	p.log.Info("Product deleted successfully", response)
+	log.Info("Product deleted successfully", response)
 	c.JSON(http.StatusNoContent, response)
 }
 
@@ -3,12 +3,14 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	am "server-pulsa-app/internal/mock/auth_mock"
 	mock "server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -28,7 +30,7 @@ func (suite *ProductControllerTestSuite) SetupTest() {
 	suite.mockProductUC = new(mock.ProductUseCaseMock)
 	suite.mockAuthMiddleware = new(am.AuthMiddlewareMock)
 	gin.SetMode(gin.TestMode)
-	suite.router = gin.New()
+	suite.router = newTestRouter()
 
 	suite.log = logger.NewLogger()
 	suite.ProductController = NewProductController(suite.mockProductUC, suite.router.Group("/api/v1/products"), suite.mockAuthMiddleware, &suite.log)
@@ -37,6 +39,11 @@ func (suite *ProductControllerTestSuite) SetupTest() {
 	suite.router.DELETE("/api/v1/product/:id", suite.ProductController.DeleteProduct)
 	suite.router.GET("/api/v1/products", suite.ProductController.GetAllProduct)
 	suite.router.GET("/api/v1/product/:id", suite.ProductController.GetProductById)
+	suite.router.GET("/api/v1/product/code/:code", suite.ProductController.GetProductByCode)
+	suite.router.POST("/api/v1/products/batch", func(c *gin.Context) {
+		c.Set("role", "employee")
+		c.Set("merchantIds", []string{"uuid-merchant"})
+	}, suite.ProductController.GetProductsByIds)
 }
 
 func (suite *ProductControllerTestSuite) TestCreateProduct() {
@@ -70,6 +77,24 @@ func (suite *ProductControllerTestSuite) TestCreateProduct() {
 
 }
 
+func (suite *ProductControllerTestSuite) TestCreateProduct_ValidationErrors() {
+	req, _ := http.NewRequest("POST", "/api/v1/product", bytes.NewBufferString(`{"nameProvider": "Axis", "price": -500}`))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+	var response struct {
+		Code    string            `json:"code"`
+		Details map[string]string `json:"details"`
+	}
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Len(response.Details, 3)
+	suite.Contains(response.Details["nominal"], "(required)")
+	suite.Contains(response.Details["price"], "(gt)")
+	suite.Contains(response.Details["idSupliyer"], "(required)")
+	suite.mockProductUC.AssertNotCalled(suite.T(), "CreateNewProduct")
+}
+
 func (suite *ProductControllerTestSuite) TestGetProductById() {
 	id := "1"
 	intID := "1"
@@ -90,6 +115,82 @@ func (suite *ProductControllerTestSuite) TestGetProductById() {
 
 }
 
+func (suite *ProductControllerTestSuite) TestGetProductByCode() {
+	product := entity.Product{IdProduct: "1", Code: "TSEL10", Nominal: 10000, Price: 10900}
+	suite.mockProductUC.On("FindProductByCode", "TSEL10").Return(product, nil)
+
+	req, err := http.NewRequest("GET", "/api/v1/product/code/TSEL10", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+	var response struct{ Data entity.Product }
+	suite.Nil(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal(product, response.Data)
+}
+
+func (suite *ProductControllerTestSuite) TestGetProductByCode_NotFound() {
+	suite.mockProductUC.On("FindProductByCode", "NOPE").Return(entity.Product{}, fmt.Errorf("%w: NOPE", repository.ErrProductNotFound))
+
+	req, err := http.NewRequest("GET", "/api/v1/product/code/NOPE", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusNotFound, w.Code)
+}
+
+func (suite *ProductControllerTestSuite) TestGetProductsByIds() {
+	batch := entity.ProductBatch{Products: []entity.Product{{IdProduct: "uuid-product-1"}}, Missing: []string{"uuid-product-2"}}
+	suite.mockProductUC.On("FindProductsByIds", []string{"uuid-product-1", "uuid-product-2"}, []string{"uuid-merchant"}, "employee").Return(batch, nil)
+
+	req, _ := http.NewRequest("POST", "/api/v1/products/batch", bytes.NewBufferString(`{"ids": ["uuid-product-1", "uuid-product-2"]}`))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+	var response struct{ Data entity.ProductBatch }
+	suite.Nil(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal(batch, response.Data)
+}
+
+func (suite *ProductControllerTestSuite) TestGetProductsByIds_EmptyList() {
+	req, _ := http.NewRequest("POST", "/api/v1/products/batch", bytes.NewBufferString(`{"ids": []}`))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+	suite.mockProductUC.AssertNotCalled(suite.T(), "FindProductsByIds")
+}
+
+func (suite *ProductControllerTestSuite) TestCreateProduct_DuplicateCode() {
+	payload := entity.Product{NameProvider: "Axis", Nominal: 10000, Price: 11000, IdSupliyer: "1", Code: "AXIS10"}
+	suite.mockProductUC.On("CreateNewProduct", payload).Return(entity.Product{}, repository.ErrProductCodeTaken)
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+
+	req, err := http.NewRequest("POST", "/api/v1/product", bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		panic(err)
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusConflict, w.Code)
+	suite.Contains(w.Body.String(), repository.ErrProductCodeTaken.Error())
+}
+
 func (suite *ProductControllerTestSuite) TestUpdateProduct() {
 	payload := entity.Product{
 		IdProduct:    "1",
@@ -97,6 +198,7 @@ func (suite *ProductControllerTestSuite) TestUpdateProduct() {
 		Nominal:      10000,
 		Price:        11000,
 		IdSupliyer:   "1",
+		Version:      1,
 	}
 
 	suite.mockProductUC.On("UpdateProduct", payload).Return(payload, nil)
@@ -120,11 +222,35 @@ func (suite *ProductControllerTestSuite) TestUpdateProduct() {
 
 }
 
+func (suite *ProductControllerTestSuite) TestUpdateProduct_MissingVersion() {
+	jsonPayload, _ := json.Marshal(entity.Product{NameProvider: "Axis", Nominal: 10000, Price: 11000, IdSupliyer: "1"})
+
+	req, _ := http.NewRequest("PUT", "/api/v1/product/1", bytes.NewBuffer(jsonPayload))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+	suite.mockProductUC.AssertNotCalled(suite.T(), "UpdateProduct")
+}
+
+func (suite *ProductControllerTestSuite) TestUpdateProduct_VersionConflict() {
+	payload := entity.Product{IdProduct: "1", NameProvider: "Axis", Nominal: 10000, Price: 11000, IdSupliyer: "1", Version: 2}
+	suite.mockProductUC.On("UpdateProduct", payload).Return(entity.Product{}, repository.ErrProductVersionConflict)
+
+	jsonPayload, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("PUT", "/api/v1/product/1", bytes.NewBuffer(jsonPayload))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusConflict, w.Code)
+	suite.Contains(w.Body.String(), "refetch")
+}
+
 func (suite *ProductControllerTestSuite) TestDeleteProduct() {
 	id := "1"
 	intID := "1"
 
-	suite.mockProductUC.On("DeleteProduct", intID).Return(nil)
+	suite.mockProductUC.On("DeleteProduct", intID, "").Return(nil)
 
 	req, err := http.NewRequest("DELETE", "/api/v1/product/"+id, nil)
 
@@ -140,9 +266,27 @@ func (suite *ProductControllerTestSuite) TestDeleteProduct() {
 
 }
 
+func (suite *ProductControllerTestSuite) TestDeleteProduct_InUse() {
+	id := "1"
+
+	suite.mockProductUC.On("DeleteProduct", id, "").Return(&repository.ErrProductInUse{ProductId: id, Count: 2})
+
+	req, err := http.NewRequest("DELETE", "/api/v1/product/"+id, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	w := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusConflict, w.Code)
+	suite.Contains(w.Body.String(), "2 transaction details")
+}
+
 func (suite *ProductControllerTestSuite) TestGetAllProduct() {
 
-	suite.mockProductUC.On("FindAllProduct").Return([]entity.Product{}, nil)
+	suite.mockProductUC.On("FindAllProduct", entity.ProductQuery{}).Return([]entity.Product{}, nil)
 
 	req, err := http.NewRequest("GET", "/api/v1/products", nil)
 
@@ -158,6 +302,79 @@ func (suite *ProductControllerTestSuite) TestGetAllProduct() {
 
 }
 
+func (suite *ProductControllerTestSuite) TestGetAllProduct_ScopedToCallerMerchants() {
+	router := newTestRouter()
+	router.GET("/api/v1/products", func(c *gin.Context) {
+		c.Set("employee", "user-a")
+		c.Set("role", "employee")
+	}, suite.ProductController.GetAllProduct)
+
+	products := []entity.Product{
+		{IdProduct: "1", NameProvider: "Axis", Nominal: 10000, Price: 11000},
+		{IdProduct: "2", NameProvider: "Axis", Nominal: 20000, Price: 21000, IdMerchant: "merchant-a"},
+	}
+	suite.mockProductUC.On("FindAllProduct", entity.ProductQuery{UserId: "user-a"}).Return(products, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/products", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"idMerchant":"merchant-a"`)
+	suite.mockProductUC.AssertExpectations(suite.T())
+}
+
+func (suite *ProductControllerTestSuite) TestGetAllProduct_AdminSeesEveryProduct() {
+	router := newTestRouter()
+	router.GET("/api/v1/products", func(c *gin.Context) {
+		c.Set("employee", "admin-a")
+		c.Set("role", "admin")
+	}, suite.ProductController.GetAllProduct)
+
+	suite.mockProductUC.On("FindAllProduct", entity.ProductQuery{}).Return([]entity.Product{}, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/products", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+	suite.mockProductUC.AssertExpectations(suite.T())
+}
+
+func (suite *ProductControllerTestSuite) TestGetAllProduct_ConditionalGet() {
+	products := []entity.Product{{IdProduct: "1", NameProvider: "Axis", Nominal: 10000, Price: 11000, Version: 1}}
+	suite.mockProductUC.On("FindAllProduct", entity.ProductQuery{}).Return(products, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/products", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	suite.NotEmpty(etag)
+
+	req, _ = http.NewRequest("GET", "/api/v1/products", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusNotModified, w.Code)
+	suite.Empty(w.Body.String())
+}
+
+func (suite *ProductControllerTestSuite) TestGetProductById_StaleETag() {
+	suite.mockProductUC.On("FindProductById", "1").Return(entity.Product{IdProduct: "1", Version: 2}, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/product/1", nil)
+	req.Header.Set("If-None-Match", `"outdated"`)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+	suite.NotEqual(`"outdated"`, w.Header().Get("ETag"))
+	suite.Contains(w.Body.String(), `"version":2`)
+}
+
 func TestProductControllerTestSuite(t *testing.T) {
 	suite.Run(t, new(ProductControllerTestSuite))
 }
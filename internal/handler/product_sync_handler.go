@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductSyncHandler struct {
+	useCase        usecase.ProductSyncUseCase
+	rg             *gin.RouterGroup
+	authMiddleware middleware.AuthMiddleware
+	log            *logger.Logger
+}
+
+func (p *ProductSyncHandler) Route() {
+	// calls the supplier on every request
+	p.rg.POST(config.PostProductSync, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProductWrite), p.authMiddleware.RateLimit(5), p.SyncProducts)
+}
+
+// SyncProducts godoc
+// @Summary Sync product prices from the supplier
+// @Description Fetch the supplier price list and apply it in one transaction, unknown codes are created as draft products
+// @Tags products
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.ProductSyncReport "Sync report"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 502 {object} apierror.Response "Supplier price list could not be applied"
+// @Router /admin/products/sync [post]
+func (p *ProductSyncHandler) SyncProducts(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	log.Info("Starting to sync supplier prices in the handler layer", nil)
+
+	report, err := p.useCase.SyncSupplierPrices(c.Request.Context())
+	if err != nil {
+		log.Error("Supplier price sync failed", err)
+		c.Error(apierror.New(http.StatusBadGateway, "supplier_sync_failed", "the supplier price list could not be synced"))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    entity.ProductSyncReport
+	}{
+		Message: "Product prices synced",
+		Data:    report,
+	}
+
+	log.Info("Supplier prices synced successfully", response)
+	c.JSON(http.StatusOK, response)
+}
+
+func NewProductSyncHandler(useCase usecase.ProductSyncUseCase, rg *gin.RouterGroup, authMiddleware middleware.AuthMiddleware, log *logger.Logger) *ProductSyncHandler {
+	return &ProductSyncHandler{useCase: useCase, rg: rg, authMiddleware: authMiddleware, log: log}
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProviderController struct {
+	useCase        usecase.ProviderUseCase
+	rg             *gin.RouterGroup
+	authMiddleware middleware.AuthMiddleware
+	log            *logger.Logger
+}
+
+func (p *ProviderController) Route() {
+	p.rg.POST(config.PostProvider, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProviderWrite), p.CreateProvider)
+	p.rg.GET(config.GetProviderList, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProviderWrite), p.GetAllProvider)
+	p.rg.GET(config.GetProvider, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProviderWrite), p.GetProviderById)
+	p.rg.PUT(config.PutProvider, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProviderWrite), p.UpdateProvider)
+	p.rg.DELETE(config.DeleteProvider, p.authMiddleware.RequireToken(), p.authMiddleware.RequirePermission(entity.PermissionProviderWrite), p.DeleteProvider)
+}
+
+// CreateProvider godoc
+// @Summary Create new provider
+// @Description Create a new provider in the system
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.ProviderRequest true "Provider details"
+// @Success 201 {object} entity.ProviderResponse "Successfully created provider"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 409 {object} apierror.Response "Provider already exists"
+// @Router /provider [post]
+func (p *ProviderController) CreateProvider(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	var payload entity.ProviderRequest
+
+	log.Info("Starting to create a new provider in the handler layer", nil)
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for provider: ", err)
+		c.Error(apierror.Validation(err.Error(), nil))
+		return
+	}
+
+	provider, err := p.useCase.CreateNewProvider(entity.Provider{NameProvider: payload.NameProvider})
+	if err != nil {
+		log.Error("Provider creation failed", err)
+		c.Error(providerError(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    entity.Provider
+	}{
+		Message: "Provider Created",
+		Data:    provider,
+	}
+
+	log.Info("Provider created successfully", response)
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListProviders godoc
+// @Summary List all providers
+// @Description Get a list of all providers
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} []entity.ProviderResponse "List of providers"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /providers [get]
+func (p *ProviderController) GetAllProvider(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	log.Info("Starting to retrieve all provider in the handler layer", nil)
+
+	providers, err := p.useCase.FindAllProvider()
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	if len(providers) > 0 {
+		response := struct {
+			Message string
+			Data    []entity.Provider
+		}{
+			Message: "List All Provider",
+			Data:    providers,
+		}
+
+		log.Info("Provider found successfully", nil)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	log.Info("Provider not found", nil)
+	c.JSON(http.StatusOK, gin.H{"message": "List Provider empty"})
+}
+
+// GetProvider godoc
+// @Summary Get provider by ID
+// @Description Retrieve a provider by its ID
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Provider ID"
+// @Success 200 {object} entity.ProviderResponse "Provider found"
+// @Failure 404 {object} apierror.Response "Provider not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /provider/{id} [get]
+func (p *ProviderController) GetProviderById(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	id := c.Param("id")
+
+	log.Info("Starting to retrieve provider with id in the handler layer", nil)
+	provider, err := p.useCase.FindProviderById(id)
+	if err != nil {
+		log.Error("Provider ID %s not found: ", id)
+		c.Error(apierror.NotFound("Provider not found"))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    entity.Provider
+	}{
+		Message: "Provider found",
+		Data:    provider,
+	}
+
+	log.Info("Provider found successfully", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateProvider godoc
+// @Summary Update provider
+// @Description Rename an existing provider, products keep pointing to it
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Provider ID"
+// @Param request body entity.ProviderRequest true "Updated provider details"
+// @Success 200 {object} entity.ProviderResponse "Successfully updated provider"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 409 {object} apierror.Response "Provider already exists"
+// @Router /provider/{id} [put]
+func (p *ProviderController) UpdateProvider(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	var payload entity.ProviderRequest
+	id := c.Param("id")
+
+	log.Info("Starting to update provider with id in the handler layer", nil)
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for provider: ", err)
+		c.Error(apierror.Validation(err.Error(), nil))
+		return
+	}
+
+	provider, err := p.useCase.UpdateProvider(entity.Provider{IdProvider: id, NameProvider: payload.NameProvider})
+	if err != nil {
+		log.Error("Provider update failed", err)
+		c.Error(providerError(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    entity.Provider
+	}{
+		Message: "The provider has been updated",
+		Data:    provider,
+	}
+
+	log.Info("Provider updated successfully", response)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteProvider godoc
+// @Summary Delete provider
+// @Description Delete a provider that is not used by any product
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Provider ID"
+// @Success 204 "Successfully deleted"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "Provider not found"
+// @Failure 409 {object} apierror.Response "Provider still used by products"
+// @Router /provider/{id} [delete]
+func (p *ProviderController) DeleteProvider(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	id := c.Param("id")
+
+	log.Info("Starting to delete provider with id in the handler layer", nil)
+	if err := p.useCase.DeleteProvider(id); err != nil {
+		if errors.Is(err, repository.ErrProviderInUse) {
+			log.Error("Provider still in use: ", id)
+			c.Error(apierror.Conflict(err.Error()))
+			return
+		}
+
+		if errors.Is(err, usecase.ErrProviderNotFound) {
+			log.Error("Provider ID %s not found: ", id)
+			c.Error(apierror.NotFound("Provider not found"))
+			return
+		}
+
+		log.Error("Failed to delete the provider: ", err)
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("Provider deleted successfully", id)
+	c.Status(http.StatusNoContent)
+}
+
+// providerError maps provider usecase errors to the error answered by create and update.
+func providerError(err error) *apierror.Error {
+	if errors.Is(err, repository.ErrProviderExists) {
+		return apierror.Conflict(repository.ErrProviderExists.Error())
+	}
+	return apierror.Internal(err)
+}
+
+func NewProviderController(useCase usecase.ProviderUseCase, rg *gin.RouterGroup, authMiddleware middleware.AuthMiddleware, log *logger.Logger) *ProviderController {
+	return &ProviderController{useCase: useCase, rg: rg, authMiddleware: authMiddleware, log: log}
+}
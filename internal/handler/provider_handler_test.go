@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	am "server-pulsa-app/internal/mock/auth_mock"
+	mock "server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProviderControllerTestSuite struct {
+	suite.Suite
+	mockProviderUC     *mock.ProviderUseCaseMock
+	mockAuthMiddleware *am.AuthMiddlewareMock
+	ProviderController *ProviderController
+	router             *gin.Engine
+	log                logger.Logger
+}
+
+func (suite *ProviderControllerTestSuite) SetupTest() {
+	suite.mockProviderUC = new(mock.ProviderUseCaseMock)
+	suite.mockAuthMiddleware = new(am.AuthMiddlewareMock)
+	gin.SetMode(gin.TestMode)
+	suite.router = newTestRouter()
+
+	suite.log = logger.NewLogger()
+	suite.ProviderController = NewProviderController(suite.mockProviderUC, suite.router.Group("/api/v1"), suite.mockAuthMiddleware, &suite.log)
+	suite.router.POST("/api/v1/provider", suite.ProviderController.CreateProvider)
+	suite.router.GET("/api/v1/providers", suite.ProviderController.GetAllProvider)
+	suite.router.DELETE("/api/v1/provider/:id", suite.ProviderController.DeleteProvider)
+}
+
+func (suite *ProviderControllerTestSuite) TestCreateProvider() {
+	provider := entity.Provider{IdProvider: "1", NameProvider: "Axis"}
+	suite.mockProviderUC.On("CreateNewProvider", entity.Provider{NameProvider: "Axis"}).Return(provider, nil)
+
+	jsonPayload, _ := json.Marshal(entity.ProviderRequest{NameProvider: "Axis"})
+	req, _ := http.NewRequest("POST", "/api/v1/provider", bytes.NewBuffer(jsonPayload))
+	w := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusCreated, w.Code)
+	suite.Contains(w.Body.String(), `"idProvider":"1"`)
+}
+
+func (suite *ProviderControllerTestSuite) TestCreateProvider_Duplicate() {
+	suite.mockProviderUC.On("CreateNewProvider", entity.Provider{NameProvider: "Axis"}).Return(entity.Provider{}, repository.ErrProviderExists)
+
+	jsonPayload, _ := json.Marshal(entity.ProviderRequest{NameProvider: "Axis"})
+	req, _ := http.NewRequest("POST", "/api/v1/provider", bytes.NewBuffer(jsonPayload))
+	w := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusConflict, w.Code)
+}
+
+func (suite *ProviderControllerTestSuite) TestGetAllProvider() {
+	suite.mockProviderUC.On("FindAllProvider").Return([]entity.Provider{{IdProvider: "1", NameProvider: "Axis"}}, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/providers", nil)
+	w := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+}
+
+func (suite *ProviderControllerTestSuite) TestDeleteProvider_InUse() {
+	suite.mockProviderUC.On("DeleteProvider", "1").Return(repository.ErrProviderInUse)
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/provider/1", nil)
+	w := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusConflict, w.Code)
+}
+
+func TestProviderControllerTestSuite(t *testing.T) {
+	suite.Run(t, new(ProviderControllerTestSuite))
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PublicProductHandler struct {
+	useCase   usecase.ProductUseCase
+	rg        *gin.RouterGroup
+	rateLimit gin.HandlerFunc
+	log       *logger.Logger
+}
+
+func (p *PublicProductHandler) Route() {
+	p.rg.GET(config.GetPublicProducts, p.rateLimit, p.GetCatalog)
+}
+
+// GetCatalog godoc
+// @Summary Public product catalog
+// @Description Active products with their price, no authentication required
+// @Tags public
+// @Produce json
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {array} entity.PublicProduct "Active products"
+// @Success 304 "Catalog not modified"
+// @Failure 429 {object} apierror.Response "Too many requests"
+// @Router /public/products [get]
+func (p *PublicProductHandler) GetCatalog(c *gin.Context) {
+	log := p.log.WithContext(c.Request.Context())
+	log.Info("Starting to retrieve the public product catalog in the handler layer", nil)
+
+	products, err := p.useCase.FindPublicCatalog()
+	if err != nil {
+		log.Error("Failed to retrieve the public product catalog", err)
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	if products == nil {
+		products = []entity.PublicProduct{}
+	}
+
+	response := struct {
+		Message string
+		Data    []entity.PublicProduct
+	}{
+		Message: "Product Catalog",
+		Data:    products,
+	}
+
+	common.SendJSONWithETag(c, http.StatusOK, response)
+}
+
+func NewPublicProductHandler(useCase usecase.ProductUseCase, rg *gin.RouterGroup, rateLimit gin.HandlerFunc, log *logger.Logger) *PublicProductHandler {
+	return &PublicProductHandler{useCase: useCase, rg: rg, rateLimit: rateLimit, log: log}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	mock "server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type PublicProductHandlerTestSuite struct {
+	suite.Suite
+	mockProductUC *mock.ProductUseCaseMock
+	router        *gin.Engine
+	log           logger.Logger
+}
+
+func (suite *PublicProductHandlerTestSuite) SetupTest() {
+	suite.mockProductUC = new(mock.ProductUseCaseMock)
+	gin.SetMode(gin.TestMode)
+	suite.router = newTestRouter()
+	suite.log = logger.NewLogger()
+
+	NewPublicProductHandler(suite.mockProductUC, suite.router.Group("/api/v1"), middleware.NewIPRateLimitMiddleware(middleware.NewWindowLimiter(repository.NewMemoryRateLimitRepository(), 2, time.Minute), &suite.log), &suite.log).Route()
+}
+
+func (suite *PublicProductHandlerTestSuite) get(ifNoneMatch string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/api/v1/public/products", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *PublicProductHandlerTestSuite) TestGetCatalog_ConditionalGet() {
+	catalog := []entity.PublicProduct{{Code: "TSEL10", Provider: "Telkomsel", Category: "pulsa", Nominal: 10000, Price: 10900}}
+	suite.mockProductUC.On("FindPublicCatalog").Return(catalog, nil)
+
+	w := suite.get("")
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"code":"TSEL10"`)
+	suite.NotContains(w.Body.String(), "idProduct")
+
+	w = suite.get(w.Header().Get("ETag"))
+	suite.Equal(http.StatusNotModified, w.Code)
+}
+
+func (suite *PublicProductHandlerTestSuite) TestGetCatalog_RateLimited() {
+	suite.mockProductUC.On("FindPublicCatalog").Return([]entity.PublicProduct{}, nil)
+
+	suite.Equal(http.StatusOK, suite.get("").Code)
+	suite.Equal(http.StatusOK, suite.get("").Code)
+
+	w := suite.get("")
+	suite.Equal(http.StatusTooManyRequests, w.Code)
+	suite.NotEmpty(w.Header().Get("Retry-After"))
+	suite.mockProductUC.AssertNumberOfCalls(suite.T(), "FindPublicCatalog", 2)
+}
+
+func TestPublicProductHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(PublicProductHandlerTestSuite))
+}
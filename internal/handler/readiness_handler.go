@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/service"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessHandler tells load balancers whether to send traffic here. The dependencies are checked directly, there
+// is no usecase in between.
+type ReadinessHandler struct {
+	checkers []service.DependencyChecker
+	critical []string
+	timeout  time.Duration
+	cacheFor time.Duration
+	draining *atomic.Bool
+	rg       *gin.RouterGroup
+	log      *logger.Logger
+
+	// mu also makes the probes arriving during a check wait for its result instead of checking again
+	mu        sync.Mutex
+	cached    entity.Readiness
+	checkedAt time.Time
+}
+
+// readyHandler godoc
+// @Summary Readiness
+// @Description Check the database, its pending migrations and the configured provider and SMS integrations in parallel. Every dependency is reported, only a critical one that is down makes the server not ready
+// @Tags health
+// @Produce json
+// @Success 200 {object} entity.Readiness "Every critical dependency is up"
+// @Failure 503 {object} entity.Readiness "A critical dependency is down or the server is shutting down"
+// @Router /readyz [get]
+func (r *ReadinessHandler) readyHandler(ctx *gin.Context) {
+	if r.draining != nil && r.draining.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, entity.Readiness{Draining: true, Dependencies: []entity.DependencyStatus{}})
+		return
+	}
+
+	readiness := r.readiness(ctx.Request.Context())
+	if !readiness.Ready {
+		ctx.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+	ctx.JSON(http.StatusOK, readiness)
+}
+
+// readiness checks the dependencies at most once per cacheFor, the probes of several load balancers do not multiply
+// the load on them.
+func (r *ReadinessHandler) readiness(ctx context.Context) entity.Readiness {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.checkedAt.IsZero() && time.Since(r.checkedAt) < r.cacheFor {
+		return r.cached
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	readiness := entity.Readiness{Ready: true, Dependencies: make([]entity.DependencyStatus, len(r.checkers))}
+	var wg sync.WaitGroup
+	for i, checker := range r.checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			readiness.Dependencies[i] = r.check(checkCtx, checker)
+		}()
+	}
+	wg.Wait()
+
+	for _, dependency := range readiness.Dependencies {
+		if dependency.Status == entity.DependencyDown {
+			readiness.Ready = readiness.Ready && !dependency.Critical
+		}
+	}
+
+	r.cached, r.checkedAt = readiness, time.Now()
+	return readiness
+}
+
+func (r *ReadinessHandler) check(ctx context.Context, checker service.DependencyChecker) entity.DependencyStatus {
+	status := entity.DependencyStatus{Name: checker.Name(), Status: entity.DependencyUp, Critical: slices.Contains(r.critical, checker.Name())}
+
+	started := time.Now()
+	err := checker.Check(ctx)
+	status.LatencyMs = time.Since(started).Milliseconds()
+	if err != nil {
+		r.log.WithContext(ctx).Error("Readiness check failed for "+status.Name+": ", err.Error())
+		status.Status = entity.DependencyDown
+	}
+	return status
+}
+
+func (r *ReadinessHandler) Route() {
+	r.rg.GET(config.GetReady, r.readyHandler)
+	r.rg.GET(config.GetReadyz, r.readyHandler)
+}
+
+// NewReadinessHandler checks every checker within timeout, those named in critical fail the readiness when down.
+// A result is answered for cacheFor, zero checks on every probe. While draining is set the server is not ready at all.
+func NewReadinessHandler(checkers []service.DependencyChecker, critical []string, timeout, cacheFor time.Duration, draining *atomic.Bool, rg *gin.RouterGroup, log *logger.Logger) *ReadinessHandler {
+	return &ReadinessHandler{checkers: checkers, critical: critical, timeout: timeout, cacheFor: cacheFor, draining: draining, rg: rg, log: log}
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/service_mock"
+	"server-pulsa-app/internal/shared/service"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReadinessHandlerTest struct {
+	suite.Suite
+	database *service_mock.DependencyCheckerMock
+	provider *service_mock.DependencyCheckerMock
+	sms      *service_mock.DependencyCheckerMock
+	draining atomic.Bool
+	log      logger.Logger
+}
+
+func (r *ReadinessHandlerTest) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	r.database = service_mock.NewDependencyCheckerMock(service.DependencyDatabase)
+	r.provider = service_mock.NewDependencyCheckerMock(service.DependencyProvider)
+	r.sms = service_mock.NewDependencyCheckerMock(service.DependencySms)
+	r.draining.Store(false)
+	r.log = logger.NewLogger()
+}
+
+func (r *ReadinessHandlerTest) serve(critical []string, timeout time.Duration) (int, entity.Readiness) {
+	return r.servePath("/ready", critical, timeout)
+}
+
+func (r *ReadinessHandlerTest) servePath(path string, critical []string, timeout time.Duration) (int, entity.Readiness) {
+	w := r.request(r.router(critical, timeout, 0), path)
+
+	var readiness entity.Readiness
+	r.NoError(json.Unmarshal(w.Body.Bytes(), &readiness))
+	return w.Code, readiness
+}
+
+func (r *ReadinessHandlerTest) router(critical []string, timeout, cacheFor time.Duration) *gin.Engine {
+	router := gin.New()
+	checkers := []service.DependencyChecker{r.database, r.provider, r.sms}
+	NewReadinessHandler(checkers, critical, timeout, cacheFor, &r.draining, &router.RouterGroup, &r.log).Route()
+	return router
+}
+
+func (r *ReadinessHandlerTest) request(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+	return w
+}
+
+func (r *ReadinessHandlerTest) TestReady_AllUp() {
+	r.database.On("Check", mock.Anything).Return(nil)
+	r.provider.On("Check", mock.Anything).Return(nil)
+	r.sms.On("Check", mock.Anything).Return(nil)
+
+	code, readiness := r.serve([]string{"database"}, time.Second)
+
+	r.Equal(http.StatusOK, code)
+	r.True(readiness.Ready)
+	r.Len(readiness.Dependencies, 3)
+	for _, dependency := range readiness.Dependencies {
+		r.Equal(entity.DependencyUp, dependency.Status, dependency.Name)
+	}
+}
+
+func (r *ReadinessHandlerTest) TestReady_NonCriticalDownIsReported() {
+	r.database.On("Check", mock.Anything).Return(nil)
+	r.provider.On("Check", mock.Anything).Return(errors.New("provider returned status 502"))
+	r.sms.On("Check", mock.Anything).Return(nil)
+
+	code, readiness := r.serve([]string{"database"}, time.Second)
+
+	r.Equal(http.StatusOK, code)
+	r.True(readiness.Ready)
+	provider := readiness.Dependencies[1]
+	r.Equal("provider", provider.Name)
+	r.Equal(entity.DependencyDown, provider.Status)
+	r.False(provider.Critical)
+}
+
+func (r *ReadinessHandlerTest) TestReady_ErrorIsNotAnswered() {
+	r.database.On("Check", mock.Anything).Return(errors.New("dial tcp 10.1.2.3:5432: connection refused"))
+	r.provider.On("Check", mock.Anything).Return(nil)
+	r.sms.On("Check", mock.Anything).Return(nil)
+
+	w := r.request(r.router([]string{"database"}, time.Second, 0), "/ready")
+
+	r.Equal(http.StatusServiceUnavailable, w.Code)
+	r.Contains(w.Body.String(), `"status":"down"`)
+	r.NotContains(w.Body.String(), "10.1.2.3")
+}
+
+func (r *ReadinessHandlerTest) TestReady_ResultIsCached() {
+	r.database.On("Check", mock.Anything).Return(nil)
+	r.provider.On("Check", mock.Anything).Return(nil)
+	r.sms.On("Check", mock.Anything).Return(nil)
+	router := r.router([]string{"database"}, time.Second, time.Minute)
+
+	r.Equal(http.StatusOK, r.request(router, "/ready").Code)
+	r.Equal(http.StatusOK, r.request(router, "/readyz").Code)
+
+	r.database.AssertNumberOfCalls(r.T(), "Check", 1)
+}
+
+func (r *ReadinessHandlerTest) TestReady_WithoutCacheChecksEveryProbe() {
+	r.database.On("Check", mock.Anything).Return(nil)
+	r.provider.On("Check", mock.Anything).Return(nil)
+	r.sms.On("Check", mock.Anything).Return(nil)
+	router := r.router([]string{"database"}, time.Second, 0)
+
+	r.request(router, "/ready")
+	r.request(router, "/ready")
+
+	r.database.AssertNumberOfCalls(r.T(), "Check", 2)
+}
+
+func (r *ReadinessHandlerTest) TestReady_CriticalDown() {
+	r.database.On("Check", mock.Anything).Return(nil)
+	r.provider.On("Check", mock.Anything).Return(nil)
+	r.sms.On("Check", mock.Anything).Return(errors.New("sms is unreachable: connection refused"))
+
+	code, readiness := r.serve([]string{"database", "sms"}, time.Second)
+
+	r.Equal(http.StatusServiceUnavailable, code)
+	r.False(readiness.Ready)
+	r.Equal(entity.DependencyDown, readiness.Dependencies[2].Status)
+	r.True(readiness.Dependencies[2].Critical)
+}
+
+func (r *ReadinessHandlerTest) TestReady_SlowDependencyTimesOut() {
+	r.database.On("Check", mock.Anything).Return(nil)
+	r.provider.On("Check", mock.Anything).Return(context.DeadlineExceeded).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	})
+	r.sms.On("Check", mock.Anything).Return(nil)
+
+	started := time.Now()
+	code, readiness := r.serve([]string{"database", "provider"}, 50*time.Millisecond)
+
+	r.Less(time.Since(started), time.Second)
+	r.Equal(http.StatusServiceUnavailable, code)
+	r.Equal(entity.DependencyDown, readiness.Dependencies[1].Status)
+	r.Equal(entity.DependencyUp, readiness.Dependencies[0].Status)
+}
+
+func (r *ReadinessHandlerTest) TestReadyz_SameCheckAsReady() {
+	r.database.On("Check", mock.Anything).Return(errors.New("connection refused"))
+	r.provider.On("Check", mock.Anything).Return(nil)
+	r.sms.On("Check", mock.Anything).Return(nil)
+
+	code, readiness := r.servePath("/readyz", []string{"database"}, time.Second)
+
+	r.Equal(http.StatusServiceUnavailable, code)
+	r.Equal(entity.DependencyDown, readiness.Dependencies[0].Status)
+}
+
+func (r *ReadinessHandlerTest) TestReadyz_DrainingIsNotReady() {
+	r.draining.Store(true)
+
+	code, readiness := r.servePath("/readyz", []string{"database"}, time.Second)
+
+	r.Equal(http.StatusServiceUnavailable, code)
+	r.False(readiness.Ready)
+	r.True(readiness.Draining)
+	// a shutting down server does not bother its dependencies
+	r.database.AssertNotCalled(r.T(), "Check", mock.Anything)
+}
+
+func TestReadinessHandlerTest(t *testing.T) {
+	suite.Run(t, new(ReadinessHandlerTest))
+}
@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/shared/apierror"
 	"server-pulsa-app/internal/shared/custom"
 	"server-pulsa-app/internal/usecase"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,21 +22,15 @@ type ReportHandler struct {
 }
 
 func (r *ReportHandler) listHandler(ctx *gin.Context) {
-	r.log.Info("Starting to retrieve all merchant's transactions in the handler layer", nil)
+	log := r.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to retrieve all merchant's transactions in the handler layer", nil)
 
 	userId, _ := ctx.Get("employee")
 	startDate := ctx.Query("startDate")
 	endDate := ctx.Query("endDate")
 	err := r.reportUc.FindAllTransactions(userId.(string), startDate, endDate)
 	if err != nil {
-		response := struct {
-			Message string
-			Data    []custom.TransactionsReq
-		}{
-			Message: err.Error(),
-			Data:    nil,
-		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
 
@@ -42,12 +39,95 @@ func (r *ReportHandler) listHandler(ctx *gin.Context) {
 	}{
 		Message: "Report as Excel File Generated Successfully",
 	}
-	r.log.Info("Report as Excel File Generated Successfully", nil)
+	log.Info("Report as Excel File Generated Successfully", nil)
 	ctx.JSON(http.StatusOK, response)
 }
 
+// marginHandler godoc
+// @Summary Product margin report
+// @Description Units sold, revenue, cost (nominal x units) and margin per product across all merchants
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param sortBy query string false "margin or units" default(margin)
+// @Param limit query int false "Maximum number of products" default(50)
+// @Success 200 {array} custom.ProductMarginResp "Product margins"
+// @Failure 400 {object} apierror.Response "Invalid filter"
+// @Router /admin/products/report/margin [get]
+func (r *ReportHandler) marginHandler(ctx *gin.Context) {
+	log := r.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to retrieve product margin report in the handler layer", nil)
+
+	limit := 0
+	if rawLimit := ctx.Query("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			ctx.Error(apierror.Validation("limit must be a number", nil))
+			return
+		}
+		limit = parsed
+	}
+
+	filter := custom.MarginFilter{
+		StartDate: ctx.Query("startDate"),
+		EndDate:   ctx.Query("endDate"),
+		SortBy:    ctx.Query("sortBy"),
+		Limit:     limit,
+	}
+
+	margins, err := r.reportUc.FindProductMargin(filter)
+	if err != nil {
+		log.Error("Failed to retrieve product margin report", err)
+		if errors.Is(err, usecase.ErrInvalidMarginFilter) {
+			ctx.Error(apierror.Validation(err.Error(), nil))
+			return
+		}
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    []custom.ProductMarginResp
+	}{
+		Message: "Product Margin Report",
+		Data:    margins,
+	}
+
+	log.Info("Product margin report retrieved successfully", nil)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// overviewHandler godoc
+// @Summary Dashboard totals
+// @Description Number of active products, merchants, active users and transactions created today, counted without loading any rows
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} custom.StatsOverview "Totals"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /stats/overview [get]
+func (r *ReportHandler) overviewHandler(ctx *gin.Context) {
+	log := r.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to retrieve the stats overview in the handler layer", nil)
+
+	overview, err := r.reportUc.FindOverview()
+	if err != nil {
+		log.Error("Failed to retrieve the stats overview", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, overview)
+}
+
 func (m *ReportHandler) Route() {
-	m.rg.GET(config.GetReport, m.authMiddleware.RequireToken("employee"), m.listHandler)
+	// both aggregate over every transaction of the period
+	m.rg.GET(config.GetReport, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("employee"), m.authMiddleware.RateLimit(20), m.listHandler)
+	m.rg.GET(config.GetProductMarginReport, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.authMiddleware.RateLimit(20), m.marginHandler)
+	m.rg.GET(config.GetStatsOverview, m.authMiddleware.RequireToken(), m.authMiddleware.RequireRoles("admin"), m.overviewHandler)
 }
 
 func NewReportHandler(reportUc usecase.ReportUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *ReportHandler {
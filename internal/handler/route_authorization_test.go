@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/mock/repo_mock"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/mock/service_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type routeAuthorizationTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+	log    logger.Logger
+}
+
+// protectedRoutes lists every authenticated route with the roles allowed through it.
+var protectedRoutes = []struct {
+	method string
+	path   string
+	roles  []string
+}{
+	{http.MethodPost, "/api/v1" + config.Logout, []string{"admin", "employee"}},
+	{http.MethodPost, "/api/v1" + config.PostMerchant, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostMerchantOnboard, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetMerchantList, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetMerchant, []string{"admin"}},
+	{http.MethodPut, "/api/v1" + config.PutMerchant, []string{"admin"}},
+	{http.MethodDelete, "/api/v1" + config.DeleteMerchant, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetMerchantBalance, []string{"admin", "employee"}},
+	{http.MethodPost, "/api/v1" + config.PostBalanceAdjust, []string{"admin"}},
+	{http.MethodPut, "/api/v1" + config.PutMerchantPrice, []string{"admin"}},
+	{http.MethodDelete, "/api/v1" + config.DeleteMerchantPrice, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetMerchantCatalog, []string{"admin", "employee"}},
+	{http.MethodPost, "/api/v1" + config.PostProduct, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetProductList, []string{"admin", "employee"}},
+	{http.MethodGet, "/api/v1" + config.GetProduct, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetProductCode, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostProductIds, []string{"admin", "employee"}},
+	{http.MethodPut, "/api/v1" + config.PutProduct, []string{"admin"}},
+	{http.MethodDelete, "/api/v1" + config.DeleteProduct, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostProductSync, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostProvider, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetProviderList, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetProvider, []string{"admin"}},
+	{http.MethodPut, "/api/v1" + config.PutProvider, []string{"admin"}},
+	{http.MethodDelete, "/api/v1" + config.DeleteProvider, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostTransaction, []string{"employee"}},
+	{http.MethodPost, "/api/v1" + config.QuoteTransaction, []string{"employee"}},
+	{http.MethodGet, "/api/v1" + config.ListTransactions, []string{"employee"}},
+	{http.MethodGet, "/api/v1" + config.DetailTransaction, []string{"employee"}},
+	{http.MethodGet, "/api/v1" + config.ReceiptTransaction, []string{"employee"}},
+	{http.MethodGet, "/api/v1" + config.ReceiptPdf, []string{"employee"}},
+	{http.MethodPost, "/api/v1" + config.RefundTransaction, []string{"admin"}},
+	{http.MethodPost, "/api/v2" + config.PostTransaction, []string{"employee"}},
+	{http.MethodPost, "/api/v2" + config.QuoteTransaction, []string{"employee"}},
+	{http.MethodGet, "/api/v2" + config.ListTransactions, []string{"employee"}},
+	{http.MethodGet, "/api/v2" + config.DetailTransaction, []string{"employee"}},
+	{http.MethodGet, "/api/v2" + config.ReceiptTransaction, []string{"employee"}},
+	{http.MethodGet, "/api/v2" + config.ReceiptPdf, []string{"employee"}},
+	{http.MethodPost, "/api/v2" + config.RefundTransaction, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostUser, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetUserList, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetUser, []string{"admin"}},
+	{http.MethodPut, "/api/v1" + config.PutUser, []string{"admin"}},
+	{http.MethodDelete, "/api/v1" + config.DeleteUser, []string{"admin"}},
+	{http.MethodPatch, "/api/v1" + config.PatchUserActive, []string{"admin"}},
+	{http.MethodPut, "/api/v1" + config.PutUserPassword, []string{"admin", "employee"}},
+	{http.MethodGet, "/api/v1" + config.GetMe, []string{"admin", "employee"}},
+	{http.MethodPost, "/api/v1" + config.PostUserResetPassword, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetReport, []string{"employee"}},
+	{http.MethodGet, "/api/v1" + config.GetProductMarginReport, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostTopup, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetTopupByMerchantId, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetAuditLog, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetAuthEvents, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetMyAuthEvents, []string{"admin", "employee"}},
+	{http.MethodGet, "/api/v1" + config.GetMySessions, []string{"admin", "employee"}},
+	{http.MethodDelete, "/api/v1" + config.DeleteMySession, []string{"admin", "employee"}},
+	{http.MethodDelete, "/api/v1" + config.DeleteUserSessions, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostTwoFactorSetup, []string{"admin"}},
+	{http.MethodPost, "/api/v1" + config.PostTwoFactorEnable, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetRolePermissions, []string{"admin"}},
+	{http.MethodPut, "/api/v1" + config.PutRolePermissions, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetDbStats, []string{"admin"}},
+	{http.MethodGet, "/api/v1" + config.GetStatsOverview, []string{"admin"}},
+}
+
+func (s *routeAuthorizationTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.log = logger.NewLogger()
+
+	jwtService := new(service_mock.JwtServiceMock)
+	for _, role := range []string{"admin", "employee"} {
+		jwtService.On("ValidateToken", role+"-token").Return(&model.Claim{
+			RegisteredClaims: jwt.RegisteredClaims{ID: role + "-jti", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+			UserId:           "uuid-" + role,
+			Role:             role,
+		}, nil)
+	}
+	revokedRepo := new(repositorymock.MockRevokedTokenRepository)
+	revokedRepo.On("IsRevoked", mock.Anything).Return(false, nil)
+	userRepo := new(repo_mock.UserRepoMock)
+	userRepo.On("IsActive", mock.Anything).Return(true, nil)
+	authMiddleware := middleware.NewAuthMiddleware(jwtService, revokedRepo, new(repositorymock.MockSessionRepository), userRepo, repository.NewMemoryRateLimitRepository(), 0, middleware.UserRateLimit{})
+
+	// the usecases are left nil: a request that gets past the middleware panics in the handler and is answered with 500
+	s.router = newTestRouter()
+	s.router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, _ any) {
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	rg := s.router.Group("/api/v1")
+	NewMerchantHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewAuthController(nil, authMiddleware, rg, &s.log).Route()
+	NewProductController(nil, rg, authMiddleware, &s.log).Route()
+	NewProviderController(nil, rg, authMiddleware, &s.log).Route()
+	NewProductSyncHandler(nil, rg, authMiddleware, &s.log).Route()
+	NewTransactionHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewUserHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewReportHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewTopupHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewAuditHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewSessionHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewTwoFactorHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewPermissionHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewDbStatsHandler(nil, authMiddleware, rg, &s.log).Route()
+	NewTransactionHandlerV2(nil, authMiddleware, s.router.Group("/api/v2"), &s.log).Route()
+}
+
+func (s *routeAuthorizationTestSuite) request(method, path, token string) int {
+	req, _ := http.NewRequest(method, routeWithParams(path), strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func routeWithParams(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "param"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (s *routeAuthorizationTestSuite) TestProtectedRoutes_EnforceRoles() {
+	for _, route := range protectedRoutes {
+		for _, role := range []string{"admin", "employee"} {
+			code := s.request(route.method, route.path, role+"-token")
+
+			allowed := false
+			for _, r := range route.roles {
+				allowed = allowed || r == role
+			}
+			if allowed {
+				s.NotContains([]int{http.StatusUnauthorized, http.StatusForbidden}, code, "%s %s as %s", route.method, route.path, role)
+			} else {
+				s.Equal(http.StatusForbidden, code, "%s %s as %s", route.method, route.path, role)
+			}
+		}
+	}
+}
+
+func (s *routeAuthorizationTestSuite) TestProtectedRoutes_ListedCompletely() {
+	listed := map[string]bool{}
+	for _, route := range protectedRoutes {
+		listed[route.method+" "+route.path] = true
+	}
+
+	for _, route := range s.router.Routes() {
+		if s.request(route.Method, route.Path, "") == http.StatusUnauthorized {
+			s.True(listed[route.Method+" "+route.Path], "%s %s is protected but missing from protectedRoutes", route.Method, route.Path)
+		}
+	}
+}
+
+func TestRouteAuthorizationTestSuite(t *testing.T) {
+	suite.Run(t, new(routeAuthorizationTestSuite))
+}
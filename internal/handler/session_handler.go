@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	sessionUc      usecase.SessionUseCase
+	rg             *gin.RouterGroup
+	authMiddleware middleware.AuthMiddleware
+	log            *logger.Logger
+}
+
+// mySessionsHandler godoc
+// @Summary List my sessions
+// @Description Active logins of the logged in user, most recently used first. The last use is the last login or token refresh
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.Session "Active sessions"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Router /me/sessions [get]
+func (s *SessionHandler) mySessionsHandler(ctx *gin.Context) {
+	log := s.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to retrieve the own sessions in the handler layer", nil)
+
+	sessions, err := s.sessionUc.ListSessions(ctx.GetString("employee"), ctx.GetString("sessionId"))
+	if err != nil {
+		log.Error("Failed to retrieve the sessions: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    []entity.Session
+	}{
+		Message: "Sessions",
+		Data:    sessions,
+	}
+
+	log.Info("Sessions retrieved successfully", nil)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// revokeMySessionHandler godoc
+// @Summary Revoke one of my sessions
+// @Description Log one of the own sessions out, its access and refresh tokens stop working on the next request
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 204 "Session revoked"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "No active session with this id"
+// @Router /me/sessions/{id} [delete]
+func (s *SessionHandler) revokeMySessionHandler(ctx *gin.Context) {
+	log := s.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to revoke an own session in the handler layer", nil)
+
+	err := s.sessionUc.RevokeSession(ctx.GetString("employee"), ctx.Param("id"))
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to revoke the session: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("Session revoked successfully", nil)
+	ctx.Status(http.StatusNoContent)
+}
+
+// revokeUserSessionsHandler godoc
+// @Summary Force logout a user
+// @Description Revoke every session of a user, e.g. a compromised account. The tokens stop working on the next request
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]int64 "Number of sessions revoked"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Not an admin"
+// @Failure 404 {object} apierror.Response "Invalid user id"
+// @Router /admin/user/{id}/sessions [delete]
+func (s *SessionHandler) revokeUserSessionsHandler(ctx *gin.Context) {
+	log := s.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to revoke the sessions of a user in the handler layer", nil)
+
+	revoked, err := s.sessionUc.RevokeUserSessions(ctx.Param("id"), ctx.GetString("employee"))
+	if errors.Is(err, usecase.ErrUserNotFound) {
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to revoke the sessions of the user: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("Sessions of the user revoked successfully", nil)
+	ctx.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+func (s *SessionHandler) Route() {
+	s.rg.GET(config.GetMySessions, s.authMiddleware.RequireToken(), s.authMiddleware.RequireRoles("admin", "employee"), s.mySessionsHandler)
+	s.rg.DELETE(config.DeleteMySession, s.authMiddleware.RequireToken(), s.authMiddleware.RequireRoles("admin", "employee"), s.revokeMySessionHandler)
+	s.rg.DELETE(config.DeleteUserSessions, s.authMiddleware.RequireToken(), s.authMiddleware.RequireRoles("admin"), s.revokeUserSessionsHandler)
+}
+
+func NewSessionHandler(sessionUc usecase.SessionUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *SessionHandler {
+	return &SessionHandler{sessionUc: sessionUc, authMiddleware: authMiddleware, rg: rg, log: log}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/middleware_mock"
+	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/usecase"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionHandlerTest struct {
+	suite.Suite
+	sessionUc      *usecase_mock.SessionUseCaseMock
+	router         *gin.Engine
+	sessionHandler *SessionHandler
+	log            logger.Logger
+}
+
+func (s *SessionHandlerTest) SetupTest() {
+	s.sessionUc = new(usecase_mock.SessionUseCaseMock)
+
+	gin.SetMode(gin.TestMode)
+	s.router = newTestRouter()
+
+	s.log = logger.NewLogger()
+	s.sessionHandler = NewSessionHandler(s.sessionUc, new(middleware_mock.AuthMiddlewareMock), s.router.Group("/api/v1"), &s.log)
+	authenticated := func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-user-test")
+		ctx.Set("sessionId", "uuid-session-test")
+	}
+	s.router.GET("/api/v1/me/sessions", authenticated, s.sessionHandler.mySessionsHandler)
+	s.router.DELETE("/api/v1/me/sessions/:id", authenticated, s.sessionHandler.revokeMySessionHandler)
+	s.router.DELETE("/api/v1/admin/user/:id/sessions", authenticated, s.sessionHandler.revokeUserSessionsHandler)
+}
+
+func (s *SessionHandlerTest) serve(method, url string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(method, url, nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, request)
+	return w
+}
+
+func (s *SessionHandlerTest) TestMySessions() {
+	s.sessionUc.On("ListSessions", "uuid-user-test", "uuid-session-test").
+		Return([]entity.Session{{Id: "uuid-session-test", Ip: "10.0.0.1", Current: true}}, nil).Once()
+
+	w := s.serve(http.MethodGet, "/api/v1/me/sessions")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"current":true`)
+	s.sessionUc.AssertExpectations(s.T())
+}
+
+func (s *SessionHandlerTest) TestMySessions_UseCaseError() {
+	s.sessionUc.On("ListSessions", "uuid-user-test", "uuid-session-test").Return([]entity.Session(nil), errors.New("db down")).Once()
+
+	w := s.serve(http.MethodGet, "/api/v1/me/sessions")
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.NotContains(w.Body.String(), "db down")
+}
+
+func (s *SessionHandlerTest) TestRevokeMySession() {
+	s.sessionUc.On("RevokeSession", "uuid-user-test", "uuid-session-other").Return(nil).Once()
+
+	w := s.serve(http.MethodDelete, "/api/v1/me/sessions/uuid-session-other")
+
+	s.Equal(http.StatusNoContent, w.Code)
+	s.sessionUc.AssertExpectations(s.T())
+}
+
+func (s *SessionHandlerTest) TestRevokeMySession_NotFound() {
+	s.sessionUc.On("RevokeSession", "uuid-user-test", "uuid-session-other").Return(repository.ErrSessionNotFound).Once()
+
+	w := s.serve(http.MethodDelete, "/api/v1/me/sessions/uuid-session-other")
+
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func (s *SessionHandlerTest) TestRevokeUserSessions() {
+	s.sessionUc.On("RevokeUserSessions", "uuid-compromised", "uuid-user-test").Return(int64(2), nil).Once()
+
+	w := s.serve(http.MethodDelete, "/api/v1/admin/user/uuid-compromised/sessions")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{"revoked":2}`, w.Body.String())
+}
+
+func (s *SessionHandlerTest) TestRevokeUserSessions_InvalidUser() {
+	s.sessionUc.On("RevokeUserSessions", "not-a-uuid", "uuid-user-test").Return(int64(0), usecase.ErrUserNotFound).Once()
+
+	w := s.serve(http.MethodDelete, "/api/v1/admin/user/not-a-uuid/sessions")
+
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func TestSessionHandlerSuite(t *testing.T) {
+	suite.Run(t, new(SessionHandlerTest))
+}
@@ -2,11 +2,13 @@ package handler
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/shared/apierror"
 	"server-pulsa-app/internal/shared/common"
 	"server-pulsa-app/internal/usecase"
 	"strconv"
@@ -30,41 +32,42 @@ func initRestyClient() *resty.Client {
 }
 
 func (t *TopupHandler) CreateTopup(c *gin.Context) {
+	log := t.log.WithContext(c.Request.Context())
 	var payload entity.TopupRequest
 
-	t.log.Info("Starting to create a new topup in the handler layer", nil)
+	log.Info("Starting to create a new topup in the handler layer", nil)
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		t.log.Error("Invalid payload for topup: ", err)
-		common.SendErrorResponse(c, 400, err.Error())
+		log.Error("Invalid payload for topup: ", err)
+		c.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
 		return
 	}
 
 	payload.Status = "pending"
 
-	t.log.Info("Start validating if the top-up amount is less than 10,000", payload.Amount)
+	log.Info("Start validating if the top-up amount is less than 10,000", payload.Amount)
 	if payload.Amount < 10000 {
-		t.log.Error("Invalid topup amount", payload.Amount)
-		common.SendErrorResponse(c, 400, "minimum amount for topup is 10000")
+		log.Error("Invalid topup amount", payload.Amount)
+		c.Error(apierror.Validation("minimum amount for topup is 10000", nil))
 		return
 	}
 
-	t.log.Info("Starting validate if merchant and supliyer exist", nil)
+	log.Info("Starting validate if merchant and supliyer exist", nil)
 	if payload.IdMerchant == "" || payload.IdSupliyer == "" || payload.Item_name == "" {
-		t.log.Error("id_merchant, id_supliyer, and item_name are required", nil)
-		common.SendErrorResponse(c, 400, "id_merchant and id_supliyer are required")
+		log.Error("id_merchant, id_supliyer, and item_name are required", nil)
+		c.Error(apierror.Validation("id_merchant and id_supliyer are required", nil))
 		return
 	}
 
-	t.log.Info("Starting to send a payload to the usecase layer", nil)
+	log.Info("Starting to send a payload to the usecase layer", nil)
 	id, err := t.usecase.CreateTopup(payload)
 	if err != nil {
-		t.log.Error("Topup creation failed", err)
-		common.SendErrorResponse(c, 500, err.Error())
+		log.Error("Topup creation failed", err)
+		c.Error(apierror.Internal(err))
 		return
 	}
 
 	client := initRestyClient()
-	t.log.Info("Starting to send a payload to Midtrans", nil)
+	log.Info("Starting to send a payload to Midtrans", nil)
 	midtransReq := entity.MidtransRequest{
 		TransactionDetails: entity.TransactionDetails{
 			OrderId:     id,
@@ -80,37 +83,40 @@ func (t *TopupHandler) CreateTopup(c *gin.Context) {
 		Post("")
 
 	if err != nil {
-		t.log.Error("Error sending payload to Midtrans: ", err)
-		common.SendErrorResponse(c, 500, err.Error())
+		log.Error("Error sending payload to Midtrans: ", err)
+		c.Error(apierror.Internal(err))
 		return
 	}
 
-	t.log.Info("Starting to validate status code", nil)
+	log.Info("Starting to validate status code", nil)
 	if resp.StatusCode() != 201 {
-		common.SendErrorResponse(c, resp.StatusCode(), resp.String())
+		// the body of Midtrans is logged, not forwarded
+		log.Error("Midtrans rejected the topup: ", resp.String())
+		c.Error(apierror.New(http.StatusBadGateway, "payment_gateway_error", "the payment gateway rejected the topup"))
 		return
 	}
 
 	midtransResponse := resp.Result().(*entity.MidtransResponse)
-	t.log.Info("Request topup successfully", midtransResponse)
+	log.Info("Request topup successfully", midtransResponse)
 	common.SendSingleResponseCreated(c, midtransResponse, "Please make a balance payment at the link above using the virtual account payment method from BCA, BRI, or BNI")
 }
 
 func (t *TopupHandler) PaymentCallbackHandler(c *gin.Context) {
+	log := t.log.WithContext(c.Request.Context())
 	var notifPayment entity.CallbackPayment
 
-	t.log.Info("Starting to handle payment callback", nil)
+	log.Info("Starting to handle payment callback", nil)
 	if err := c.ShouldBindJSON(&notifPayment); err != nil {
-		common.SendErrorResponse(c, 400, err.Error())
+		c.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
 		return
 	}
 
-	t.log.Info("Get the data needed for the update", nil)
+	log.Info("Get the data needed for the update", nil)
 	idTopup := notifPayment.OrderID
 	status := notifPayment.TransactionStatus
 	amount, err := strconv.ParseFloat(notifPayment.GrossAmount, 64)
 	if err != nil {
-		common.SendErrorResponse(c, 400, err.Error())
+		c.Error(apierror.Validation("gross_amount must be a number", nil))
 		return
 	}
 	var paymentMethod string
@@ -118,7 +124,7 @@ func (t *TopupHandler) PaymentCallbackHandler(c *gin.Context) {
 		paymentMethod = notifPayment.VANumber[0].Bank
 	}
 
-	t.log.Info("Update the status and payment method if status condition = settlement", nil)
+	log.Info("Update the status and payment method if status condition = settlement", nil)
 	if status == "settlement" {
 		payload := entity.TopupRequest{
 			Id:            idTopup,
@@ -127,49 +133,50 @@ func (t *TopupHandler) PaymentCallbackHandler(c *gin.Context) {
 			PaymentMethod: paymentMethod,
 		}
 
-		t.log.Info("Starting to update the topup data", nil)
+		log.Info("Starting to update the topup data", nil)
 		idTopupSuccess, err := t.usecase.UpdateAfterPayment(payload)
 		if err != nil {
-			t.log.Error("Error updating topup data: ", err)
-			common.SendErrorResponse(c, 500, err.Error())
+			log.Error("Error updating topup data: ", err)
+			c.Error(apierror.Internal(err))
 			return
 		}
 
-		t.log.Info("Topup data updated successfully", nil)
+		log.Info("Topup data updated successfully", nil)
 		common.SendSingleResponseOk(c, idTopupSuccess, "Topup berhasil")
 		return
 	}
 
-	t.log.Info("Topup status is not settlement", nil)
-	common.SendErrorResponse(c, 400, "Topup gagal")
+	log.Info("Topup status is not settlement", nil)
+	c.Error(apierror.Validation("Topup gagal", nil))
 
 }
 
 func (t *TopupHandler) GetTopupByMerchantId(c *gin.Context) {
+	log := t.log.WithContext(c.Request.Context())
 	idMerchant := c.Param("id")
 
 	if idMerchant == "" {
-		t.log.Error("id_merchant is required", nil)
-		common.SendErrorResponse(c, 400, "id_merchant is required")
+		log.Error("id_merchant is required", nil)
+		c.Error(apierror.Validation("id_merchant is required", nil))
 		return
 	}
 
-	t.log.Info("Starting to get topup by merchant id", nil)
+	log.Info("Starting to get topup by merchant id", nil)
 	topups, err := t.usecase.GetTopupByMerchantId(idMerchant)
 	if err != nil {
-		t.log.Error("Error getting topup by merchant id: ", err)
-		common.SendErrorResponse(c, 500, err.Error())
+		log.Error("Error getting topup by merchant id: ", err)
+		c.Error(apierror.Internal(err))
 		return
 	}
 
-	t.log.Info("Topup data retrieved successfully", nil)
+	log.Info("Topup data retrieved successfully", nil)
 	common.SendSingleResponseOk(c, topups, "Data topup")
 }
 
 func (t *TopupHandler) Route() {
-	t.rg.POST(config.PostTopup, t.authMiddleware.RequireToken("admin"), t.CreateTopup)
+	t.rg.POST(config.PostTopup, t.authMiddleware.RequireToken(), t.authMiddleware.RequireRoles("admin"), t.CreateTopup)
 	t.rg.POST(config.PostCallback, t.PaymentCallbackHandler)
-	t.rg.GET(config.GetTopupByMerchantId, t.authMiddleware.RequireToken("admin"), t.GetTopupByMerchantId)
+	t.rg.GET(config.GetTopupByMerchantId, t.authMiddleware.RequireToken(), t.authMiddleware.RequireRoles("admin"), t.GetTopupByMerchantId)
 }
 
 func NewTopupHandler(usecase usecase.TopupUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *TopupHandler {
@@ -1,13 +1,21 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
 	"server-pulsa-app/internal/shared/custom"
+	"server-pulsa-app/internal/shared/service"
 	"server-pulsa-app/internal/usecase"
+	"slices"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,50 +29,196 @@ type TransactionHandler struct {
 	rg             *gin.RouterGroup
 	authMiddleware middleware.AuthMiddleware
 	log            *logger.Logger
+	presenter      transactionPresenter
+}
+
+// transactionPresenter writes the success responses, so every API version shares the same handler logic
+// and only the response shape differs.
+type transactionPresenter interface {
+	created(ctx *gin.Context, transaction entity.Transactions)
+	list(ctx *gin.Context, page custom.TransactionPage)
+	detail(ctx *gin.Context, transaction custom.TransactionsReq)
+	quoted(ctx *gin.Context, quote custom.TransactionQuote)
+}
+
+type transactionPresenterV1 struct{}
+
+func (transactionPresenterV1) created(ctx *gin.Context, transaction entity.Transactions) {
+	response := struct {
+		Message string              `json:"message"`
+		Data    entity.Transactions `json:"data"`
+	}{
+		Message: "Transaction Created",
+		Data:    transaction,
+	}
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (transactionPresenterV1) list(ctx *gin.Context, page custom.TransactionPage) {
+	if len(page.Transactions) == 0 {
+		ctx.Error(apierror.NotFound("Transactions is empty"))
+		return
+	}
+
+	response := struct {
+		Message    string                   `json:"message"`
+		Data       []custom.TransactionsReq `json:"data"`
+		NextCursor string                   `json:"nextCursor,omitempty"`
+	}{
+		Message:    "Transaction list",
+		Data:       page.Transactions,
+		NextCursor: page.NextCursor,
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (transactionPresenterV1) detail(ctx *gin.Context, transaction custom.TransactionsReq) {
+	response := struct {
+		Message string                 `json:"message"`
+		Data    custom.TransactionsReq `json:"data"`
+	}{
+		Message: "Transaction detail",
+		Data:    transaction,
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (transactionPresenterV1) quoted(ctx *gin.Context, quote custom.TransactionQuote) {
+	response := struct {
+		Message string                  `json:"message"`
+		Data    custom.TransactionQuote `json:"data"`
+	}{
+		Message: "Transaction quote",
+		Data:    quote,
+	}
+	ctx.JSON(http.StatusOK, response)
 }
 
 func NewTransactionHandler(usecase usecase.TransactionUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *TransactionHandler {
-	return &TransactionHandler{usecase: usecase, authMiddleware: authMiddleware, rg: rg, log: log}
+	return &TransactionHandler{usecase: usecase, authMiddleware: authMiddleware, rg: rg, log: log, presenter: transactionPresenterV1{}}
+}
+
+// checkMerchantOwner lets admins through and only allows other roles to the merchants of their access token, like
+// the balance of a merchant. It answers 403 and reports false otherwise.
+func (h *TransactionHandler) checkMerchantOwner(ctx *gin.Context, merchantId string) bool {
+	if ctx.GetString("role") == "admin" || slices.Contains(ctx.GetStringSlice("merchantIds"), merchantId) {
+		return true
+	}
+	h.log.WithContext(ctx.Request.Context()).Error("User is not the owner of the merchant: ", merchantId)
+	ctx.Error(apierror.Forbidden(usecase.ErrMerchantForbidden.Error()))
+	return false
 }
 
 // CreateTransaction godoc
 // @Summary Create new transaction
-// @Description Create a new transaction in the system
+// @Description Create a new transaction in the system. A detail line can be sold at overridePrice instead of the
+// @Description catalog price by a caller with the transaction:price_override permission, the override and its reason
+// @Description are audited. The merchant balance is debited by the nominal either way.
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param request body entity.TransactionReq true "Transaction details"
 // @Success 201 {object} entity.Transactions "Successfully created transaction"
-// @Failure 400 {object} entity.TransactionErrorResponse "Invalid input"
-// @Failure 401 {object} entity.TransactionErrorResponse "Unauthorized"
+// @Failure 400 {object} apierror.Response "Invalid input or unknown user"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Merchant of another user or price override without the permission"
+// @Failure 404 {object} apierror.Response "Merchant or product not found"
+// @Failure 422 {object} apierror.Response "Insufficient balance, merchant daily transaction limit exceeded or product not available"
 // @Router /transaction [post]
 func (h *TransactionHandler) createHandler(ctx *gin.Context) {
+	log := h.log.WithContext(ctx.Request.Context())
 	var payload entity.Transactions
 
-	h.log.Info("Starting to create a new transaction in the handler layer", nil)
+	log.Info("Starting to create a new transaction in the handler layer", nil)
 	err := ctx.ShouldBindJSON(&payload)
 	if err != nil {
-		h.log.Error("invalid payload for transaction", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		log.Error("invalid payload for transaction", err)
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+	if !h.checkMerchantOwner(ctx, payload.MerchantId) {
+		return
+	}
+	if slices.ContainsFunc(payload.TransactionDetail, func(detail entity.TransactionDetail) bool { return detail.OverridePrice != nil }) &&
+		!slices.Contains(ctx.GetStringSlice("permissions"), entity.PermissionPriceOverride) {
+		log.Error("price override without the permission", ctx.GetString("employee"))
+		ctx.Error(apierror.Forbidden("not allowed to override the price").WithCode("price_override_forbidden"))
+		return
+	}
+	transaction, err := h.usecase.Create(ctx.Request.Context(), payload, ctx.GetString("employee"))
+	switch {
+	case errors.Is(err, repository.ErrMerchantNotFound), errors.Is(err, repository.ErrProductNotFound):
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	case errors.Is(err, repository.ErrUserNotFound):
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	case errors.Is(err, repository.ErrDailyLimitExceeded):
+		ctx.Error(apierror.New(http.StatusUnprocessableEntity, "daily_limit_exceeded", err.Error()))
+		return
+	case errors.Is(err, repository.ErrProductUnavailable):
+		ctx.Error(apierror.New(http.StatusUnprocessableEntity, "product_unavailable", err.Error()))
+		return
+	case errors.Is(err, repository.ErrInsufficientBalance):
+		ctx.Error(apierror.New(http.StatusUnprocessableEntity, "insufficient_balance", err.Error()))
 		return
 	}
-	transaction, err := h.usecase.Create(payload)
 	if err != nil {
-		h.log.Error("failed to create a transaction", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create a transaction " + err.Error()})
+		log.Error("failed to create a transaction", err)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
-	response := struct {
-		Message string              `json:"message"`
-		Data    entity.Transactions `json:"data"`
-	}{
-		Message: "Transaction Created",
-		Data:    transaction,
+
+	log.Info("Transaction created successfuly", transaction)
+	h.presenter.created(ctx, transaction)
+}
+
+// QuoteTransaction godoc
+// @Summary Quote a transaction
+// @Description Sum the nominal of the products like a created transaction and tell whether the merchant balance
+// @Description covers it, nothing is inserted or debited. The balance can still change before the transaction is created.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.TransactionQuoteReq true "Merchant and products"
+// @Success 200 {object} custom.TransactionQuote "Total and whether the balance is sufficient"
+// @Failure 400 {object} apierror.Response "Invalid input"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Merchant of another user"
+// @Failure 404 {object} apierror.Response "Merchant or product not found"
+// @Failure 422 {object} apierror.Response "Product not available"
+// @Router /transaction/quote [post]
+func (h *TransactionHandler) quoteHandler(ctx *gin.Context) {
+	log := h.log.WithContext(ctx.Request.Context())
+	var payload entity.TransactionQuoteReq
+
+	log.Info("Starting to quote a transaction in the handler layer", nil)
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		log.Error("invalid payload for transaction quote", err)
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+	if !h.checkMerchantOwner(ctx, payload.MerchantId) {
+		return
+	}
+	quote, err := h.usecase.Quote(ctx.Request.Context(), payload)
+	switch {
+	case errors.Is(err, repository.ErrMerchantNotFound), errors.Is(err, repository.ErrProductNotFound):
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	case errors.Is(err, repository.ErrProductUnavailable):
+		ctx.Error(apierror.New(http.StatusUnprocessableEntity, "product_unavailable", err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("failed to quote a transaction", err)
+		ctx.Error(apierror.Internal(err))
+		return
 	}
 
-	h.log.Info("Transaction created successfuly", response)
-	ctx.JSON(http.StatusCreated, response)
+	h.presenter.quoted(ctx, quote)
 }
 
 // ListTransactions godoc
@@ -74,36 +228,47 @@ func (h *TransactionHandler) createHandler(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param page query int false "Page number"
-// @Param limit query int false "Items per page"
+// @Param page query int false "Page number, ignored with a cursor"
+// @Param limit query int false "Items per page, 20 with a cursor and no limit"
+// @Param cursor query string false "nextCursor of the previous page, date sorts only"
+// @Param sort query string false "Order of the history" Enums(date_desc, date_asc, customer) default(date_desc)
+// @Param status query string false "Only the transactions with this status" Enums(pending, success, failed)
 // @Success 200 {array} []entity.Transactions "List of transactions"
-// @Failure 401 {object} entity.TransactionErrorResponse "Unauthorized"
+// @Failure 400 {object} apierror.Response "Invalid sort, status, paging or cursor"
+// @Failure 401 {object} apierror.Response "Unauthorized"
 // @Router /transactions [get]
 func (h *TransactionHandler) listHandler(ctx *gin.Context) {
-	h.log.Info("Starting to get transactions list in the handler layer", nil)
+	log := h.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to get transactions list in the handler layer", nil)
 
-	userId, _ := ctx.Get("employee")
-	transactions, err := h.usecase.GetAll(userId.(string))
-	if err != nil {
-		h.log.Error("failed to retrieve a transactions", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve transactions " + err.Error()})
+	page, errPage := strconv.Atoi(ctx.DefaultQuery("page", "0"))
+	limit, errLimit := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if errPage != nil || errLimit != nil || page < 0 || limit < 0 {
+		ctx.Error(apierror.Validation("page and limit must be positive numbers", nil))
 		return
 	}
 
-	if len(transactions) > 0 {
-		response := struct {
-			Message string                   `json:"message"`
-			Data    []custom.TransactionsReq `json:"data"`
-		}{
-			Message: "Transaction list",
-			Data:    transactions,
-		}
-		h.log.Info("transactions list found", response)
-		ctx.JSON(http.StatusOK, response)
-	} else {
-		h.log.Error("transactions not found", err)
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Transactions is empty"})
+	transactions, err := h.usecase.GetAll(ctx.Request.Context(), custom.TransactionFilter{
+		MerchantIds: ctx.GetStringSlice("merchantIds"),
+		Sort:        ctx.Query("sort"),
+		Status:      ctx.Query("status"),
+		Page:        page,
+		Limit:       limit,
+		Cursor:      ctx.Query("cursor"),
+	})
+	if errors.Is(err, repository.ErrInvalidTransactionSort) || errors.Is(err, repository.ErrInvalidTransactionStatus) ||
+		errors.Is(err, repository.ErrInvalidTransactionCursor) {
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	}
+	if err != nil {
+		log.Error("failed to retrieve a transactions", err)
+		ctx.Error(apierror.Internal(err))
+		return
 	}
+
+	log.Info("transactions list found", len(transactions.Transactions))
+	h.presenter.list(ctx, transactions)
 }
 
 // GetTransaction godoc
@@ -115,32 +280,163 @@ func (h *TransactionHandler) listHandler(ctx *gin.Context) {
 // @Security BearerAuth
 // @Param id path string true "Transaction ID"
 // @Success 200 {object} entity.Transactions "Transaction found"
-// @Failure 404 {object} entity.TransactionErrorResponse "Transaction not found"
-// @Failure 401 {object} entity.TransactionErrorResponse "Unauthorized"
+// @Failure 404 {object} apierror.Response "Transaction not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Transaction of a merchant of another user"
 // @Router /transaction/{id} [get]
 func (h *TransactionHandler) getByIdHandler(ctx *gin.Context) {
+	log := h.log.WithContext(ctx.Request.Context())
 	id := ctx.Param("id")
 
-	h.log.Info("Starting to get transaction by id in the handler layer", nil)
-	transaction, err := h.usecase.GetById(id)
+	log.Info("Starting to get transaction by id in the handler layer", nil)
+	transaction, err := h.usecase.GetById(ctx.Request.Context(), id)
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		log.Error("transaction not found", id)
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	}
 	if err != nil {
-		h.log.Error("failed to retrieve a transaction", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve a transaction" + err.Error()})
+		log.Error("failed to retrieve a transaction", err)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
+	if !h.checkMerchantOwner(ctx, transaction.Merchant.IdMerchant) {
+		return
+	}
+	log.Info("transaction found", transaction)
+	h.presenter.detail(ctx, transaction)
+}
+
+// GetTransactionReceipt godoc
+// @Summary Get transaction receipt
+// @Description Retrieve a flat, print-ready receipt of a transaction for POS printers
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Success 200 {object} custom.TransactionReceipt "Transaction receipt"
+// @Failure 404 {object} apierror.Response "Transaction not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Transaction of a merchant of another user"
+// @Router /transaction/history/{id}/receipt [get]
+func (h *TransactionHandler) receiptHandler(ctx *gin.Context) {
+	log := h.log.WithContext(ctx.Request.Context())
+	id := ctx.Param("id")
+
+	log.Info("Starting to get transaction receipt in the handler layer", nil)
+	receipt, err := h.usecase.GetReceipt(ctx.Request.Context(), id)
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		log.Error("transaction not found", id)
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("failed to build a transaction receipt", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+	if !h.checkMerchantOwner(ctx, receipt.MerchantId) {
+		return
+	}
+
 	response := struct {
-		Message string                 `json:"message"`
-		Data    custom.TransactionsReq `json:"data"`
+		Message string                    `json:"message"`
+		Data    custom.TransactionReceipt `json:"data"`
 	}{
-		Message: "Transaction detail",
-		Data:    transaction,
+		Message: "Transaction receipt",
+		Data:    receipt,
 	}
-	h.log.Info("transaction found", response)
 	ctx.JSON(http.StatusOK, response)
 }
 
+// GetTransactionReceiptPdf godoc
+// @Summary Get transaction receipt as PDF
+// @Description Download the receipt of a transaction as a printable PDF for the customer, with the same content as the receipt endpoint
+// @Tags transactions
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Success 200 {file} file "Receipt PDF"
+// @Failure 404 {object} apierror.Response "Transaction not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Transaction of a merchant of another user"
+// @Router /transaction/history/{id}/receipt.pdf [get]
+func (h *TransactionHandler) receiptPdfHandler(ctx *gin.Context) {
+	log := h.log.WithContext(ctx.Request.Context())
+	id := ctx.Param("id")
+
+	log.Info("Starting to render transaction receipt PDF in the handler layer", nil)
+	receipt, err := h.usecase.GetReceipt(ctx.Request.Context(), id)
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		log.Error("transaction not found", id)
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("failed to build a transaction receipt", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+	if !h.checkMerchantOwner(ctx, receipt.MerchantId) {
+		return
+	}
+
+	pdf, err := service.RenderReceiptPDF(receipt)
+	if err != nil {
+		log.Error("failed to render the transaction receipt", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%s.pdf"`, receipt.TransactionId))
+	ctx.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// RefundTransactionDetail godoc
+// @Summary Refund a transaction detail
+// @Description Give the nominal of one detail line back to the merchant balance, for example when that product failed delivery. The other lines are not refunded
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Transaction ID"
+// @Param detailId path string true "Transaction detail ID"
+// @Success 200 {object} map[string]string "Transaction detail refunded"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "Transaction or detail not found"
+// @Failure 409 {object} apierror.Response "Detail already refunded"
+// @Router /transaction/history/{id}/detail/{detailId}/refund [post]
+func (h *TransactionHandler) refundDetailHandler(ctx *gin.Context) {
+	log := h.log.WithContext(ctx.Request.Context())
+	id := ctx.Param("id")
+	detailId := ctx.Param("detailId")
+
+	log.Info("Starting to refund a transaction detail in the handler layer", nil)
+	err := h.usecase.RefundDetail(ctx.Request.Context(), id, detailId)
+	switch {
+	case errors.Is(err, repository.ErrTransactionNotFound), errors.Is(err, repository.ErrTransactionDetailNotFound):
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	case errors.Is(err, repository.ErrDetailAlreadyRefunded):
+		ctx.Error(apierror.Conflict(err.Error()))
+		return
+	case err != nil:
+		log.Error("failed to refund a transaction detail", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Transaction detail refunded", "transactionDetailId": detailId})
+}
+
 func (h *TransactionHandler) Route() {
-	h.rg.POST(config.PostTransaction, h.authMiddleware.RequireToken("employee"), h.createHandler)
-	h.rg.GET(config.ListTransactions, h.authMiddleware.RequireToken("employee"), h.listHandler)
-	h.rg.GET(config.DetailTransaction, h.authMiddleware.RequireToken("employee"), h.getByIdHandler)
+	h.rg.POST(config.PostTransaction, h.authMiddleware.RequireToken(), h.authMiddleware.RequireRoles("employee"), h.createHandler)
+	h.rg.POST(config.QuoteTransaction, h.authMiddleware.RequireToken(), h.authMiddleware.RequireRoles("employee"), h.quoteHandler)
+	h.rg.GET(config.ListTransactions, h.authMiddleware.RequireToken(), h.authMiddleware.RequireRoles("employee"), h.listHandler)
+	h.rg.GET(config.DetailTransaction, h.authMiddleware.RequireToken(), h.authMiddleware.RequireRoles("employee"), h.getByIdHandler)
+	h.rg.GET(config.ReceiptTransaction, h.authMiddleware.RequireToken(), h.authMiddleware.RequireRoles("employee"), h.receiptHandler)
+	h.rg.GET(config.ReceiptPdf, h.authMiddleware.RequireToken(), h.authMiddleware.RequireRoles("employee"), h.receiptPdfHandler)
+	// a refund moves money back to the merchant balance, like the other balance changes it is left to admins
+	h.rg.POST(config.RefundTransaction, h.authMiddleware.RequireToken(), h.authMiddleware.RequireRoles("admin"), h.refundDetailHandler)
 }
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	testifymock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -31,7 +32,7 @@ func (suite *TransactionHandlerTestSuite) SetupTest() {
 	suite.mockTxUc = new(mock.MockTransactionUseCase)
 	suite.mockAuthMiddleware = new(am.AuthMiddlewareMock)
 	gin.SetMode(gin.TestMode)
-	suite.router = gin.New()
+	suite.router = newTestRouter()
 
 	rg := suite.router.Group("/api/v1")
 	suite.transactionHandler = NewTransactionHandler(suite.mockTxUc, suite.mockAuthMiddleware, rg, &suite.log)
@@ -70,7 +71,7 @@ func (suite *TransactionHandlerTestSuite) TestCreate_Success() {
 		},
 	}
 
-	suite.mockTxUc.On("Create", payload).Return(expectedResponse, nil)
+	suite.mockTxUc.On("Create", payload, testifymock.Anything).Return(expectedResponse, nil)
 
 	jsonPayload, err := json.Marshal(payload)
 	suite.NoError(err)
@@ -122,7 +123,7 @@ func (suite *TransactionHandlerTestSuite) TestCreate_UseCaseError() {
 		},
 	}
 
-	suite.mockTxUc.On("Create", payload).Return(entity.Transactions{}, errors.New("usecase error"))
+	suite.mockTxUc.On("Create", payload, testifymock.Anything).Return(entity.Transactions{}, errors.New("usecase error"))
 
 	jsonPayload, err := json.Marshal(payload)
 	suite.NoError(err)
@@ -169,7 +170,7 @@ func (suite *TransactionHandlerTestSuite) TestGetAll_Success() {
 		},
 	}
 
-	suite.mockTxUc.On("GetAll").Return(expectedTransactions, nil)
+	suite.mockTxUc.On("GetAll", testifymock.Anything).Return(custom.TransactionPage{Transactions: expectedTransactions}, nil)
 
 	req, err := http.NewRequest("GET", "/api/v1/transactions/history", nil)
 	suite.NoError(err)
@@ -190,7 +191,7 @@ func (suite *TransactionHandlerTestSuite) TestGetAll_Success() {
 }
 
 func (suite *TransactionHandlerTestSuite) TestGetAll_Empty() {
-	suite.mockTxUc.On("GetAll").Return([]custom.TransactionsReq{}, nil)
+	suite.mockTxUc.On("GetAll", testifymock.Anything).Return(custom.TransactionPage{}, nil)
 
 	req, err := http.NewRequest("GET", "/api/v1/transactions/history", nil)
 	suite.NoError(err)
@@ -202,7 +203,7 @@ func (suite *TransactionHandlerTestSuite) TestGetAll_Empty() {
 }
 
 func (suite *TransactionHandlerTestSuite) TestGetAll_Error() {
-	suite.mockTxUc.On("GetAll").Return([]custom.TransactionsReq{}, errors.New("usecase error"))
+	suite.mockTxUc.On("GetAll", testifymock.Anything).Return(custom.TransactionPage{}, errors.New("usecase error"))
 
 	req, err := http.NewRequest("GET", "/api/v1/transactions/history", nil)
 	suite.NoError(err)
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/shared/custom"
+	"server-pulsa-app/internal/shared/model"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// transactionPresenterV2 wraps transactions in the status envelope and adds the computed totals.
+type transactionPresenterV2 struct{}
+
+func (transactionPresenterV2) created(ctx *gin.Context, transaction entity.Transactions) {
+	summary := custom.TransactionCreatedSummary{
+		Transactions: transaction,
+		ItemCount:    len(transaction.TransactionDetail),
+	}
+	for _, detail := range transaction.TransactionDetail {
+		summary.TotalPrice += detail.Price
+	}
+
+	ctx.JSON(http.StatusCreated, &model.SingleResponse{
+		Status: model.Status{Code: http.StatusCreated, Message: "Transaction Created"},
+		Data:   summary,
+	})
+}
+
+func (transactionPresenterV2) list(ctx *gin.Context, page custom.TransactionPage) {
+	summaries := make([]custom.TransactionSummary, 0, len(page.Transactions))
+	for _, transaction := range page.Transactions {
+		summaries = append(summaries, summarizeTransaction(transaction))
+	}
+
+	ctx.JSON(http.StatusOK, struct {
+		model.ListResponse
+		NextCursor string `json:"nextCursor,omitempty"`
+	}{
+		ListResponse: model.ListResponse{
+			Status: model.Status{Code: http.StatusOK, Message: "Transaction list"},
+			Data:   summaries,
+			Total:  len(summaries),
+		},
+		NextCursor: page.NextCursor,
+	})
+}
+
+func (transactionPresenterV2) detail(ctx *gin.Context, transaction custom.TransactionsReq) {
+	ctx.JSON(http.StatusOK, &model.SingleResponse{
+		Status: model.Status{Code: http.StatusOK, Message: "Transaction detail"},
+		Data:   summarizeTransaction(transaction),
+	})
+}
+
+func (transactionPresenterV2) quoted(ctx *gin.Context, quote custom.TransactionQuote) {
+	ctx.JSON(http.StatusOK, &model.SingleResponse{
+		Status: model.Status{Code: http.StatusOK, Message: "Transaction quote"},
+		Data:   quote,
+	})
+}
+
+func summarizeTransaction(transaction custom.TransactionsReq) custom.TransactionSummary {
+	summary := custom.TransactionSummary{
+		TransactionsReq: transaction,
+		ItemCount:       len(transaction.TransactionDetail),
+	}
+	for _, detail := range transaction.TransactionDetail {
+		summary.TotalNominal += detail.Product.Nominal
+		summary.TotalPrice += detail.Product.Price
+	}
+	return summary
+}
+
+func NewTransactionHandlerV2(usecase usecase.TransactionUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *TransactionHandler {
+	return &TransactionHandler{usecase: usecase, authMiddleware: authMiddleware, rg: rg, log: log, presenter: transactionPresenterV2{}}
+}
@@ -0,0 +1,562 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/middleware_mock"
+	mock "server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/custom"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	testifymock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+var versionedTransaction = custom.TransactionsReq{
+	TransactionsId:    "tx-uuid",
+	CustomerName:      "test",
+	DestinationNumber: "087654321",
+	Merchant:          custom.MerchantRes{IdMerchant: "merchant-uuid"},
+	TransactionDate:   time.Date(2024, 10, 25, 0, 0, 0, 0, time.UTC),
+	TransactionDetail: []custom.TransactionDetailReq{
+		{TransactionDetailId: "detail-1", Product: custom.ProductRes{IdProduct: "product-1", Nominal: 10000, Price: 11000}},
+		{TransactionDetailId: "detail-2", Product: custom.ProductRes{IdProduct: "product-2", Nominal: 5000, Price: 6000}},
+	},
+}
+
+type TransactionHandlerVersionTestSuite struct {
+	suite.Suite
+	mockTxUc *mock.MockTransactionUseCase
+	router   *gin.Engine
+	log      logger.Logger
+}
+
+func (suite *TransactionHandlerVersionTestSuite) SetupTest() {
+	suite.mockTxUc = new(mock.MockTransactionUseCase)
+	gin.SetMode(gin.TestMode)
+	suite.router = newTestRouter()
+	suite.router.Use(func(ctx *gin.Context) {
+		ctx.Set("employee", "user-uuid")
+		ctx.Set("merchantIds", []string{"merchant-uuid"})
+	})
+
+	suite.log = logger.NewLogger()
+	authMiddleware := new(middleware_mock.AuthMiddlewareMock)
+	NewTransactionHandler(suite.mockTxUc, authMiddleware, suite.router.Group("/api/v1"), &suite.log).Route()
+	NewTransactionHandlerV2(suite.mockTxUc, authMiddleware, suite.router.Group("/api/v2"), &suite.log).Route()
+}
+
+func (suite *TransactionHandlerVersionTestSuite) serve(path string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", path, nil)
+	suite.NoError(err)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_V1Shape() {
+	suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}}).Return(custom.TransactionPage{Transactions: []custom.TransactionsReq{versionedTransaction}}, nil)
+
+	w := suite.serve("/api/v1/transactions")
+
+	suite.Equal(http.StatusOK, w.Code)
+	var response map[string]any
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal("Transaction list", response["message"])
+	suite.NotContains(response, "status")
+	suite.NotContains(response, "total")
+	suite.NotContains(w.Body.String(), "totalPrice")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_V2Shape() {
+	suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}}).Return(custom.TransactionPage{Transactions: []custom.TransactionsReq{versionedTransaction}}, nil)
+
+	w := suite.serve("/api/v2/transactions")
+
+	suite.Equal(http.StatusOK, w.Code)
+	var response struct {
+		Status struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"status"`
+		Data  []custom.TransactionSummary `json:"data"`
+		Total int                         `json:"total"`
+	}
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal(http.StatusOK, response.Status.Code)
+	suite.Equal(1, response.Total)
+	suite.Equal("tx-uuid", response.Data[0].TransactionsId)
+	suite.Equal(2, response.Data[0].ItemCount)
+	suite.Equal(float64(15000), response.Data[0].TotalNominal)
+	suite.Equal(float64(17000), response.Data[0].TotalPrice)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_EmptyDiffersByVersion() {
+	suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}}).Return(custom.TransactionPage{}, nil)
+
+	suite.Equal(http.StatusNotFound, suite.serve("/api/v1/transactions").Code)
+
+	w := suite.serve("/api/v2/transactions")
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"total":0`)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestDetail_V2Shape() {
+	suite.mockTxUc.On("GetById", "tx-uuid").Return(versionedTransaction, nil)
+
+	v1 := suite.serve("/api/v1/transaction/tx-uuid")
+	suite.Equal(http.StatusOK, v1.Code)
+	suite.Contains(v1.Body.String(), `"message":"Transaction detail"`)
+
+	v2 := suite.serve("/api/v2/transaction/tx-uuid")
+	suite.Equal(http.StatusOK, v2.Code)
+	suite.Contains(v2.Body.String(), `"status":{"code":200,"message":"Transaction detail"}`)
+	suite.Contains(v2.Body.String(), `"totalPrice":17000`)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestReceiptPdf() {
+	suite.mockTxUc.On("GetReceipt", "tx-uuid").Return(custom.TransactionReceipt{
+		TransactionId: "tx-uuid",
+		MerchantId:    "merchant-uuid",
+		MerchantName:  "Konter Pak Eko",
+		Cashier:       "john_doe",
+		Items:         []custom.ReceiptLine{{Product: "Telkomsel", Nominal: 10000, Price: 10900}},
+		GrandTotal:    10900,
+	}, nil)
+
+	w := suite.serve("/api/v1/transaction/history/tx-uuid/receipt.pdf")
+
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Equal("application/pdf", w.Header().Get("Content-Type"))
+	suite.True(bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestReceiptPdf_NotFound() {
+	suite.mockTxUc.On("GetReceipt", "tx-missing").Return(custom.TransactionReceipt{}, repository.ErrTransactionNotFound)
+
+	w := suite.serve("/api/v2/transaction/history/tx-missing/receipt.pdf")
+
+	suite.Equal(http.StatusNotFound, w.Code)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_ValidationErrors() {
+	body := `{"merchantId": "merchant-uuid", "destinationNumber": "0812", "transactionDetail": [{"productId": ""}]}`
+	req, err := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+	suite.NoError(err)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+	var response struct {
+		Code    string            `json:"code"`
+		Details map[string]string `json:"details"`
+	}
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal(map[string]string{
+		"userId":                         "userId is required (required)",
+		"customerName":                   "customerName is required (required)",
+		"destinationNumber":              "destinationNumber must be at least 8 (min)",
+		"transactionDetail[0].productId": "productId is required (required)",
+	}, response.Details)
+	suite.mockTxUc.AssertNotCalled(suite.T(), "Create")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_OmittedDate() {
+	suite.mockTxUc.On("Create", testifymock.MatchedBy(func(payload entity.Transactions) bool {
+		return payload.TransactionDate == ""
+	}), "user-uuid").Return(entity.Transactions{TransactionDate: time.Now().Format("02-01-2006")}, nil).Once()
+
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDetail": [{"productId": "product-1"}]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusCreated, w.Code)
+	suite.mockTxUc.AssertExpectations(suite.T())
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_InvalidDate() {
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "2024-10-25", "transactionDetail": [{"productId": "product-1"}]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+	suite.Contains(w.Body.String(), "transactionDate must be a date in dd-mm-yyyy format (ddmmyyyy)")
+	suite.mockTxUc.AssertNotCalled(suite.T(), "Create")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_ForeignMerchant() {
+	body := `{"merchantId": "merchant-other", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1"}]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusForbidden, w.Code)
+	suite.mockTxUc.AssertNotCalled(suite.T(), "Create")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_AdminForeignMerchant() {
+	router := newTestRouter()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set("employee", "admin-uuid")
+		ctx.Set("role", "admin")
+	})
+	NewTransactionHandler(suite.mockTxUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &suite.log).Route()
+	suite.mockTxUc.On("Create", testifymock.Anything, "admin-uuid").Return(entity.Transactions{MerchantId: "merchant-other"}, nil).Once()
+
+	body := `{"merchantId": "merchant-other", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1"}]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusCreated, w.Code)
+	suite.mockTxUc.AssertExpectations(suite.T())
+}
+
+func (suite *TransactionHandlerVersionTestSuite) quote(path string) *httptest.ResponseRecorder {
+	body := `{"merchantId": "merchant-uuid", "transactionDetail": [{"productId": "product-1"}]}`
+	req, err := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	suite.NoError(err)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestQuote_SufficientBalance() {
+	payload := entity.TransactionQuoteReq{MerchantId: "merchant-uuid", TransactionDetail: []entity.TransactionQuoteLine{{ProductId: "product-1"}}}
+	suite.mockTxUc.On("Quote", payload).Return(custom.TransactionQuote{MerchantId: "merchant-uuid", TotalNominal: 10000, Balance: 50000, Sufficient: true}, nil)
+
+	v1 := suite.quote("/api/v1/transaction/quote")
+	suite.Equal(http.StatusOK, v1.Code)
+	suite.Contains(v1.Body.String(), `"message":"Transaction quote"`)
+	suite.Contains(v1.Body.String(), `"sufficient":true`)
+
+	v2 := suite.quote("/api/v2/transaction/quote")
+	suite.Equal(http.StatusOK, v2.Code)
+	suite.Contains(v2.Body.String(), `"status":{"code":200,"message":"Transaction quote"}`)
+	suite.Contains(v2.Body.String(), `"totalNominal":10000`)
+	suite.mockTxUc.AssertNotCalled(suite.T(), "Create")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestQuote_InsufficientBalance() {
+	suite.mockTxUc.On("Quote", testifymock.Anything).Return(custom.TransactionQuote{MerchantId: "merchant-uuid", TotalNominal: 10000, Balance: 5000}, nil)
+
+	w := suite.quote("/api/v1/transaction/quote")
+
+	// a quote the balance does not cover is still answered, the cashier decides what to do with it
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"sufficient":false`)
+	suite.Contains(w.Body.String(), `"balance":5000`)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestQuote_ProductNotFound() {
+	suite.mockTxUc.On("Quote", testifymock.Anything).Return(custom.TransactionQuote{}, fmt.Errorf("%w: product-1", repository.ErrProductNotFound))
+
+	w := suite.quote("/api/v2/transaction/quote")
+
+	suite.Equal(http.StatusNotFound, w.Code)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestQuote_ForeignMerchant() {
+	body := `{"merchantId": "merchant-other", "transactionDetail": [{"productId": "product-1"}]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transaction/quote", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusForbidden, w.Code)
+	suite.Contains(w.Body.String(), `"code":"forbidden"`)
+	suite.mockTxUc.AssertNotCalled(suite.T(), "Quote")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_MissingReferences() {
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1"}]}`
+
+	for expected, err := range map[int]error{
+		http.StatusNotFound:   repository.ErrMerchantNotFound,
+		http.StatusBadRequest: repository.ErrUserNotFound,
+	} {
+		suite.mockTxUc.On("Create", testifymock.Anything, "user-uuid").Return(entity.Transactions{}, err).Once()
+		req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+
+		suite.Equal(expected, w.Code, err.Error())
+		suite.Contains(w.Body.String(), err.Error())
+	}
+
+	suite.mockTxUc.On("Create", testifymock.Anything, "user-uuid").Return(entity.Transactions{}, fmt.Errorf("%w: product-1", repository.ErrProductNotFound)).Once()
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusNotFound, w.Code)
+	suite.Contains(w.Body.String(), "product not found: product-1")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_DailyLimitExceeded() {
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1"}]}`
+	suite.mockTxUc.On("Create", testifymock.Anything, "user-uuid").
+		Return(entity.Transactions{}, fmt.Errorf("%w: 3 of 3 transactions made today", repository.ErrDailyLimitExceeded)).Once()
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusUnprocessableEntity, w.Code)
+	suite.Contains(w.Body.String(), "merchant daily transaction limit exceeded")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_ProductUnavailable() {
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1"}]}`
+	suite.mockTxUc.On("Create", testifymock.Anything, "user-uuid").
+		Return(entity.Transactions{}, fmt.Errorf("%w: line 1, product product-1 is inactive", repository.ErrProductUnavailable)).Once()
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusUnprocessableEntity, w.Code)
+	suite.Contains(w.Body.String(), "product product-1 is inactive")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_InsufficientBalance() {
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1"}]}`
+	suite.mockTxUc.On("Create", testifymock.Anything, "user-uuid").
+		Return(entity.Transactions{}, fmt.Errorf("%w: required 10000, current balance 5000", repository.ErrInsufficientBalance)).Once()
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusUnprocessableEntity, w.Code)
+	suite.Contains(w.Body.String(), `"code":"insufficient_balance"`)
+	suite.Contains(w.Body.String(), "required 10000, current balance 5000")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_PriceOverrideWithoutPermission() {
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1", "overridePrice": 9800, "overrideReason": "loyal customer"}]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusForbidden, w.Code)
+	suite.Contains(w.Body.String(), "price_override_forbidden")
+	suite.mockTxUc.AssertNotCalled(suite.T(), "Create")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_PriceOverride() {
+	router := newTestRouter()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set("employee", "user-uuid")
+		ctx.Set("merchantIds", []string{"merchant-uuid"})
+		ctx.Set("permissions", []string{entity.PermissionPriceOverride})
+	})
+	NewTransactionHandler(suite.mockTxUc, new(middleware_mock.AuthMiddlewareMock), router.Group("/api/v1"), &suite.log).Route()
+
+	overridePrice := 9800.0
+	suite.mockTxUc.On("Create", testifymock.MatchedBy(func(payload entity.Transactions) bool {
+		detail := payload.TransactionDetail[0]
+		return detail.OverridePrice != nil && *detail.OverridePrice == overridePrice && detail.OverrideReason == "loyal customer"
+	}), "user-uuid").Return(entity.Transactions{TransactionDetail: []entity.TransactionDetail{{Price: overridePrice, PriceSource: entity.PriceSourceOverride}}}, nil).Once()
+
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1", "overridePrice": 9800, "overrideReason": "loyal customer"}]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusCreated, w.Code)
+	suite.Contains(w.Body.String(), entity.PriceSourceOverride)
+	suite.mockTxUc.AssertExpectations(suite.T())
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestCreate_PriceOverrideNeedsAReason() {
+	body := `{"merchantId": "merchant-uuid", "userId": "user-1", "customerName": "test", "destinationNumber": "087654321",
+		"transactionDate": "25-10-2024", "transactionDetail": [{"productId": "product-1", "overridePrice": 9800}]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transaction", bytes.NewBufferString(body))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+	suite.Contains(w.Body.String(), "overrideReason is required with overridePrice (required_with)")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestReceipt() {
+	suite.mockTxUc.On("GetReceipt", "tx-uuid").Return(custom.TransactionReceipt{
+		TransactionId: "tx-uuid",
+		MerchantId:    "merchant-uuid",
+		Items:         []custom.ReceiptLine{{Product: "Telkomsel", Nominal: 10000, Price: 11000}},
+		GrandTotal:    11000,
+	}, nil)
+
+	w := suite.serve("/api/v1/transaction/history/tx-uuid/receipt")
+
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"grandTotal":11000`)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestReceipt_NotFound() {
+	suite.mockTxUc.On("GetReceipt", "missing").Return(custom.TransactionReceipt{}, repository.ErrTransactionNotFound)
+
+	w := suite.serve("/api/v1/transaction/history/missing/receipt")
+
+	suite.Equal(http.StatusNotFound, w.Code)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestDetailAndReceipt_ForeignMerchant() {
+	foreign := versionedTransaction
+	foreign.Merchant = custom.MerchantRes{IdMerchant: "merchant-other"}
+	suite.mockTxUc.On("GetById", "tx-other").Return(foreign, nil)
+	suite.mockTxUc.On("GetReceipt", "tx-other").Return(custom.TransactionReceipt{TransactionId: "tx-other", MerchantId: "merchant-other"}, nil)
+
+	for _, path := range []string{
+		"/api/v1/transaction/tx-other",
+		"/api/v2/transaction/tx-other",
+		"/api/v1/transaction/history/tx-other/receipt",
+		"/api/v1/transaction/history/tx-other/receipt.pdf",
+	} {
+		w := suite.serve(path)
+
+		suite.Equal(http.StatusForbidden, w.Code, path)
+		suite.Contains(w.Body.String(), `"code":"forbidden"`, path)
+	}
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestRefundDetail() {
+	suite.mockTxUc.On("RefundDetail", "tx-uuid", "detail-2").Return(nil).Once()
+	suite.mockTxUc.On("RefundDetail", "tx-uuid", "detail-2").Return(repository.ErrDetailAlreadyRefunded).Once()
+
+	req, _ := http.NewRequest("POST", "/api/v1/transaction/history/tx-uuid/detail/detail-2/refund", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"transactionDetailId":"detail-2"`)
+
+	// the second refund of the same line is refused
+	req, _ = http.NewRequest("POST", "/api/v1/transaction/history/tx-uuid/detail/detail-2/refund", nil)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusConflict, w.Code)
+	suite.mockTxUc.AssertExpectations(suite.T())
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestRefundDetail_NotFound() {
+	suite.mockTxUc.On("RefundDetail", "tx-uuid", "missing").Return(repository.ErrTransactionDetailNotFound).Once()
+
+	req, _ := http.NewRequest("POST", "/api/v1/transaction/history/tx-uuid/detail/missing/refund", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusNotFound, w.Code)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_PassesSort() {
+	for _, sort := range []string{"date_asc", "date_desc", "customer"} {
+		suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: sort}).Return(custom.TransactionPage{Transactions: []custom.TransactionsReq{versionedTransaction}}, nil).Once()
+
+		w := suite.serve("/api/v1/transactions?sort=" + sort)
+
+		suite.Equal(http.StatusOK, w.Code, sort)
+	}
+	suite.mockTxUc.AssertExpectations(suite.T())
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_InvalidSort() {
+	suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "amount"}).Return(custom.TransactionPage{}, repository.ErrInvalidTransactionSort).Once()
+
+	w := suite.serve("/api/v1/transactions?sort=amount")
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_PassesStatusWithSort() {
+	for _, status := range []string{entity.TransactionStatusPending, entity.TransactionStatusSuccess, entity.TransactionStatusFailed} {
+		suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_asc", Status: status}).Return(custom.TransactionPage{Transactions: []custom.TransactionsReq{versionedTransaction}}, nil).Once()
+
+		w := suite.serve("/api/v1/transactions?sort=date_asc&status=" + status)
+
+		suite.Equal(http.StatusOK, w.Code, status)
+	}
+	suite.mockTxUc.AssertExpectations(suite.T())
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_InvalidStatus() {
+	suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Status: "refunded"}).Return(custom.TransactionPage{}, repository.ErrInvalidTransactionStatus).Once()
+
+	w := suite.serve("/api/v1/transactions?status=refunded")
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+	suite.Contains(w.Body.String(), repository.ErrInvalidTransactionStatus.Error())
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_PassesPagingAndReturnsNextCursor() {
+	suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Page: 2, Limit: 10, Cursor: "next-a"}).
+		Return(custom.TransactionPage{Transactions: []custom.TransactionsReq{versionedTransaction}, NextCursor: "next-b"}, nil).Twice()
+
+	for _, path := range []string{"/api/v1/transactions?page=2&limit=10&cursor=next-a", "/api/v2/transactions?page=2&limit=10&cursor=next-a"} {
+		w := suite.serve(path)
+
+		suite.Equal(http.StatusOK, w.Code, path)
+		var response struct {
+			NextCursor string `json:"nextCursor"`
+		}
+		suite.NoError(json.Unmarshal(w.Body.Bytes(), &response), path)
+		suite.Equal("next-b", response.NextCursor, path)
+	}
+	suite.mockTxUc.AssertExpectations(suite.T())
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_LastPageHasNoNextCursor() {
+	suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}}).
+		Return(custom.TransactionPage{Transactions: []custom.TransactionsReq{versionedTransaction}}, nil)
+
+	suite.NotContains(suite.serve("/api/v1/transactions").Body.String(), "nextCursor")
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_InvalidPaging() {
+	for _, query := range []string{"page=-1", "limit=ten"} {
+		w := suite.serve("/api/v1/transactions?" + query)
+
+		suite.Equal(http.StatusBadRequest, w.Code, query)
+	}
+	suite.mockTxUc.AssertNotCalled(suite.T(), "GetAll", testifymock.Anything)
+}
+
+func (suite *TransactionHandlerVersionTestSuite) TestList_InvalidCursor() {
+	suite.mockTxUc.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Cursor: "forged"}).
+		Return(custom.TransactionPage{}, repository.ErrInvalidTransactionCursor).Once()
+
+	w := suite.serve("/api/v1/transactions?cursor=forged")
+
+	suite.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestTransactionHandlerVersionTestSuite(t *testing.T) {
+	suite.Run(t, new(TransactionHandlerVersionTestSuite))
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
+	"server-pulsa-app/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorHandler struct {
+	twoFactorUc    usecase.TwoFactorUseCase
+	rg             *gin.RouterGroup
+	authMiddleware middleware.AuthMiddleware
+	log            *logger.Logger
+}
+
+// setupTwoFactorHandler godoc
+// @Summary Set up two-factor authentication
+// @Description Generate a TOTP secret for the logged in admin. Logins only ask for a code once it is confirmed at /me/2fa/enable, setting up again replaces an unconfirmed secret
+// @Tags two-factor
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.TwoFactorSetup "Secret and otpauth URL for the authenticator app"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Not an admin"
+// @Failure 409 {object} apierror.Response "Two-factor authentication is already enabled"
+// @Router /me/2fa/setup [post]
+func (t *TwoFactorHandler) setupTwoFactorHandler(ctx *gin.Context) {
+	log := t.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to set up two-factor authentication in the handler layer", nil)
+
+	setup, err := t.twoFactorUc.Setup(ctx.GetString("employee"))
+	if errors.Is(err, repository.ErrTwoFactorEnabled) {
+		ctx.Error(apierror.Conflict(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to set up two-factor authentication: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("Two-factor authentication set up successfully", nil)
+	ctx.JSON(http.StatusOK, setup)
+}
+
+// enableTwoFactorHandler godoc
+// @Summary Enable two-factor authentication
+// @Description Confirm the secret of /me/2fa/setup with a code of the authenticator app. The backup codes are only shown in this response, each one can be used once instead of a code
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.TwoFactorCodeRequest true "Code of the authenticator app"
+// @Success 200 {object} entity.TwoFactorBackupCodes "Backup codes"
+// @Failure 400 {object} apierror.Response "Invalid input, invalid code or no secret set up"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Not an admin"
+// @Failure 409 {object} apierror.Response "Two-factor authentication is already enabled"
+// @Router /me/2fa/enable [post]
+func (t *TwoFactorHandler) enableTwoFactorHandler(ctx *gin.Context) {
+	log := t.log.WithContext(ctx.Request.Context())
+	var payload entity.TwoFactorCodeRequest
+
+	log.Info("Starting to enable two-factor authentication in the handler layer", nil)
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		log.Error("Invalid payload for enabling two-factor authentication", err)
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	backupCodes, err := t.twoFactorUc.Enable(ctx.GetString("employee"), payload.Code)
+	if errors.Is(err, usecase.ErrInvalidTwoFactorCode) || errors.Is(err, repository.ErrTwoFactorNotSetUp) {
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	}
+	if errors.Is(err, repository.ErrTwoFactorEnabled) {
+		ctx.Error(apierror.Conflict(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to enable two-factor authentication: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	log.Info("Two-factor authentication enabled successfully", nil)
+	ctx.JSON(http.StatusOK, entity.TwoFactorBackupCodes{BackupCodes: backupCodes})
+}
+
+func (t *TwoFactorHandler) Route() {
+	t.rg.POST(config.PostTwoFactorSetup, t.authMiddleware.RequireToken(), t.authMiddleware.RequireRoles("admin"), t.setupTwoFactorHandler)
+	t.rg.POST(config.PostTwoFactorEnable, t.authMiddleware.RequireToken(), t.authMiddleware.RequireRoles("admin"), t.enableTwoFactorHandler)
+}
+
+func NewTwoFactorHandler(twoFactorUc usecase.TwoFactorUseCase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactorUc: twoFactorUc, authMiddleware: authMiddleware, rg: rg, log: log}
+}
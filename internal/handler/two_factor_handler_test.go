@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/middleware_mock"
+	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/usecase"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type TwoFactorHandlerTest struct {
+	suite.Suite
+	twoFactorUc      *usecase_mock.TwoFactorUseCaseMock
+	router           *gin.Engine
+	twoFactorHandler *TwoFactorHandler
+	log              logger.Logger
+}
+
+func (s *TwoFactorHandlerTest) SetupTest() {
+	s.twoFactorUc = new(usecase_mock.TwoFactorUseCaseMock)
+
+	gin.SetMode(gin.TestMode)
+	s.router = newTestRouter()
+
+	s.log = logger.NewLogger()
+	s.twoFactorHandler = NewTwoFactorHandler(s.twoFactorUc, new(middleware_mock.AuthMiddlewareMock), s.router.Group("/api/v1"), &s.log)
+	authenticated := func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-admin-test")
+	}
+	s.router.POST("/api/v1/me/2fa/setup", authenticated, s.twoFactorHandler.setupTwoFactorHandler)
+	s.router.POST("/api/v1/me/2fa/enable", authenticated, s.twoFactorHandler.enableTwoFactorHandler)
+}
+
+func (s *TwoFactorHandlerTest) serve(url, body string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, request)
+	return w
+}
+
+func (s *TwoFactorHandlerTest) TestSetup() {
+	s.twoFactorUc.On("Setup", "uuid-admin-test").
+		Return(entity.TwoFactorSetup{Secret: "JBSWY3DPEHPK3PXP", OtpauthURL: "otpauth://totp/Server%20Pulsa:admin"}, nil).Once()
+
+	w := s.serve("/api/v1/me/2fa/setup", "")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"secret":"JBSWY3DPEHPK3PXP"`)
+	s.Contains(w.Body.String(), `"otpauthUrl"`)
+}
+
+func (s *TwoFactorHandlerTest) TestSetup_AlreadyEnabled() {
+	s.twoFactorUc.On("Setup", "uuid-admin-test").Return(entity.TwoFactorSetup{}, repository.ErrTwoFactorEnabled).Once()
+
+	w := s.serve("/api/v1/me/2fa/setup", "")
+
+	s.Equal(http.StatusConflict, w.Code)
+}
+
+func (s *TwoFactorHandlerTest) TestEnable() {
+	s.twoFactorUc.On("Enable", "uuid-admin-test", "287082").Return([]string{"4f2d9-c8e1b"}, nil).Once()
+
+	w := s.serve("/api/v1/me/2fa/enable", `{"code":"287082"}`)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"backupCodes":["4f2d9-c8e1b"]`)
+}
+
+func (s *TwoFactorHandlerTest) TestEnable_InvalidCode() {
+	s.twoFactorUc.On("Enable", "uuid-admin-test", "000000").Return([]string(nil), usecase.ErrInvalidTwoFactorCode).Once()
+
+	w := s.serve("/api/v1/me/2fa/enable", `{"code":"000000"}`)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *TwoFactorHandlerTest) TestEnable_MissingCode() {
+	w := s.serve("/api/v1/me/2fa/enable", `{}`)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.twoFactorUc.AssertNotCalled(s.T(), "Enable")
+}
+
+func (s *TwoFactorHandlerTest) TestEnable_UseCaseError() {
+	s.twoFactorUc.On("Enable", "uuid-admin-test", "287082").Return([]string(nil), errors.New("db down")).Once()
+
+	w := s.serve("/api/v1/me/2fa/enable", `{"code":"287082"}`)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.NotContains(w.Body.String(), "db down")
+}
+
+func TestTwoFactorHandlerTest(t *testing.T) {
+	suite.Run(t, new(TwoFactorHandlerTest))
+}
@@ -1,13 +1,21 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/common"
+	"server-pulsa-app/internal/shared/custom"
 	"server-pulsa-app/internal/usecase"
+	"strconv"
+	"strings"
 
 	// "server-pulsa-app/config"
 
@@ -25,40 +33,169 @@ type UserHandler struct {
 }
 
 // ListUsers godoc
-// @Summary List all Users
-// @Description Get a list of all users
+// @Summary List users
+// @Description Get one page of the users ordered by username, optionally filtered by a part of the username or email, a part of the username only and by role
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param page query int false "Page number"
-// @Param limit query int false "Items per page"
-// @Success 200 {array} []entity.User "List of users"
-// @Failure 401 {object} entity.UserErrorResponse "Unauthorized"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Users per page, at most 100" default(20)
+// @Param size query int false "Older name of pageSize, used when pageSize is not given" default(20)
+// @Param q query string false "Part of the username or email, case-insensitive"
+// @Param username query string false "Part of the username, case-insensitive"
+// @Param role query string false "Only users with this role" Enums(admin, employee)
+// @Param include_inactive query bool false "List deleted users too" default(false)
+// @Param inactive_since query string false "Only users without a login for this many days, such as 30d, users that never logged in included"
+// @Success 200 {object} custom.UserPage "Page of users"
+// @Failure 400 {object} apierror.Response "Invalid page, pageSize, role, include_inactive or inactive_since"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Not an admin"
 // @Router /users [get]
 func (u *UserHandler) ListHandler(ctx *gin.Context) {
-	u.log.Info("Starting to get all user in the handler layer", nil)
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to get all user in the handler layer", nil)
 
-	users, err := u.userUc.ListUser()
+	page, errPage := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	size, errSize := strconv.Atoi(ctx.DefaultQuery("pageSize", ctx.DefaultQuery("size", "0")))
+	if errPage != nil || errSize != nil || page < 1 || size < 0 {
+		ctx.Error(apierror.Validation("page and pageSize must be positive numbers", nil))
+		return
+	}
+	includeInactive, err := strconv.ParseBool(ctx.DefaultQuery("include_inactive", "false"))
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, err.Error())
+		ctx.Error(apierror.Validation("include_inactive must be true or false", nil))
 		return
 	}
+	inactiveDays := 0
+	if since := ctx.Query("inactive_since"); since != "" {
+		inactiveDays, err = strconv.Atoi(strings.TrimSuffix(since, "d"))
+		if err != nil || inactiveDays < 1 {
+			ctx.Error(apierror.Validation("inactive_since must be a number of days such as 30d", nil))
+			return
+		}
+	}
 
-	if len(users) > 0 {
-		ctx.JSON(http.StatusOK, users)
+	users, err := u.userUc.ListUser(custom.UserFilter{
+		Page:            page,
+		Size:            size,
+		Q:               strings.TrimSpace(ctx.Query("q")),
+		Username:        strings.TrimSpace(ctx.Query("username")),
+		Role:            ctx.Query("role"),
+		IncludeInactive: includeInactive,
+		InactiveDays:    inactiveDays,
+	})
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidRole) {
+			ctx.Error(apierror.Validation(err.Error(), nil))
+			return
+		}
+		log.Error("Failed to get the user list: ", err)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
 
-	reponse := struct {
-		Message string
-		Data    []entity.User
-	}{
-		Message: "List of user is empty",
-		Data:    users,
+	ctx.JSON(http.StatusOK, users)
+}
+
+// CreateUser godoc
+// @Summary Create user
+// @Description Create a user with a given role, unlike the public registration which always creates employees
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.UserCreateRequest true "New user details"
+// @Success 201 {object} entity.UserResponse "Successfully created user"
+// @Failure 400 {object} apierror.Response "Invalid input, role or email, or the password rules that failed"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "The admin role given by a caller who is not an admin"
+// @Failure 409 {object} apierror.Response "Username or email already taken"
+// @Router /user [post]
+func (u *UserHandler) createHandler(ctx *gin.Context) {
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to create a user in the handler layer", nil)
+
+	var payload entity.UserCreateRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	user, err := u.userUc.CreateUser(entity.User{Username: payload.Username, Password: payload.Password, Role: payload.Role, Email: payload.Email},
+		ctx.GetString("role"))
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRole), errors.Is(err, repository.ErrInvalidEmail):
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	case errors.Is(err, usecase.ErrAdminRoleForbidden):
+		ctx.Error(apierror.Forbidden(err.Error()))
+		return
+	case respondWeakPassword(ctx, err):
+		return
+	case respondTaken(ctx, err):
+		return
+	case err != nil:
+		log.Error("Failed to create the user: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, entity.UserResponse{Id_user: user.Id_user, Username: user.Username, Role: user.Role, Email: user.Email})
+}
+
+// OnboardMerchant godoc
+// @Summary Onboard a merchant
+// @Description Create a user and the merchant they own in one step, neither is created when the other fails. The merchant starts with a zero balance.
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.MerchantOnboardRequest true "Owner and merchant details"
+// @Success 201 {object} entity.MerchantOnboarding "Successfully onboarded"
+// @Failure 400 {object} apierror.Response "Invalid input, role or email, or the password rules that failed"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "Default product not found"
+// @Failure 409 {object} apierror.Response "Username or email already taken"
+// @Router /merchant/onboard [post]
+func (u *UserHandler) onboardMerchantHandler(ctx *gin.Context) {
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to onboard a merchant in the handler layer", nil)
+
+	var payload entity.MerchantOnboardRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	onboarding, err := u.userUc.OnboardMerchant(
+		entity.User{Username: payload.User.Username, Password: payload.User.Password, Role: payload.User.Role, Email: payload.User.Email},
+		entity.Merchant{
+			NameMerchant:        payload.Merchant.NameMerchant,
+			Address:             payload.Merchant.Address,
+			IdProduct:           payload.Merchant.IdProduct,
+			LowBalanceThreshold: payload.Merchant.LowBalanceThreshold,
+			DailyLimitCount:     payload.Merchant.DailyLimitCount,
+			DailyLimitAmount:    payload.Merchant.DailyLimitAmount,
+		})
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRole), errors.Is(err, repository.ErrInvalidEmail):
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	case errors.Is(err, repository.ErrProductNotFound):
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	case respondWeakPassword(ctx, err):
+		return
+	case respondTaken(ctx, err):
+		return
+	case err != nil:
+		log.Error("Failed to onboard the merchant: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, reponse)
+	ctx.JSON(http.StatusCreated, onboarding)
 }
 
 // GetUser godoc
@@ -70,17 +207,17 @@ func (u *UserHandler) ListHandler(ctx *gin.Context) {
 // @Security BearerAuth
 // @Param id path string true "User ID"
 // @Success 200 {object} entity.UserResponse "User found"
-// @Failure 404 {object} entity.UserErrorResponse "User not found"
-// @Failure 401 {object} entity.UserErrorResponse "Unauthorized"
+// @Failure 404 {object} apierror.Response "User not found"
+// @Failure 401 {object} apierror.Response "Unauthorized"
 // @Router /user/{id} [get]
 func (u *UserHandler) getIdHandler(ctx *gin.Context) {
-	u.log.Info("Starting to get user by id in the handler layer", nil)
+	u.log.WithContext(ctx.Request.Context()).Info("Starting to get user by id in the handler layer", nil)
 
 	id := ctx.Param("id")
 
 	user, err := u.userUc.GetUserByID(id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, fmt.Sprintf("User with id %s not found", id))
+		ctx.Error(apierror.FromRepository(err, fmt.Sprintf("User with id %s not found", id)))
 		return
 	}
 	response := struct {
@@ -104,25 +241,41 @@ func (u *UserHandler) getIdHandler(ctx *gin.Context) {
 // @Param id path string true "User ID"
 // @Param request body entity.UserReqUpdate true "Updated user details"
 // @Success 200 {object} entity.UserResponse "Successfully updated user"
-// @Failure 400 {object} entity.UserErrorResponse "Invalid input"
-// @Failure 401 {object} entity.UserErrorResponse "Unauthorized"
-// @Failure 404 {object} entity.UserErrorResponse "User not found"
+// @Failure 400 {object} apierror.Response "Invalid input or email"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "The admin role given, or an admin changed, by a caller who is not an admin"
+// @Failure 404 {object} apierror.Response "User not found"
+// @Failure 409 {object} apierror.Response "Username or email already taken"
 // @Router /user/{id} [put]
 func (u *UserHandler) updateHandler(ctx *gin.Context) {
-	u.log.Info("Starting to update user in the handler layer", nil)
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to update user in the handler layer", nil)
 	id := ctx.Param("id")
 	var payload entity.User
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.JSON(http.StatusBadRequest, fmt.Sprintf("User with id %s not found", id))
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
 		return
 	}
 
 	payload.Id_user = id
 
-	user, err := u.userUc.UpdateUser(payload)
+	user, err := u.userUc.UpdateUser(payload, ctx.GetString("employee"), ctx.GetString("role"))
 
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, err.Error())
+	switch {
+	case errors.Is(err, repository.ErrInvalidEmail):
+		ctx.Error(apierror.Validation(err.Error(), nil))
+		return
+	case errors.Is(err, usecase.ErrAdminRoleForbidden):
+		ctx.Error(apierror.Forbidden(err.Error()))
+		return
+	case respondTaken(ctx, err):
+		return
+	case errors.Is(err, usecase.ErrUserNotFound), errors.Is(err, repository.ErrUserNotFound):
+		ctx.Error(apierror.NotFound(fmt.Sprintf("User with id %s not found", id)))
+		return
+	case err != nil:
+		log.Error("Failed to update the user: ", err)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
 
@@ -140,23 +293,34 @@ func (u *UserHandler) updateHandler(ctx *gin.Context) {
 
 // DeleteUser godoc
 // @Summary Delete user
-// @Description Delete a user by its ID
+// @Description Deactivate a user by its ID, the user is kept for the transaction history and can no longer log in or use issued tokens
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "User ID"
-// @Success 204 "Successfully deleted"
-// @Failure 401 {object} entity.UserErrorResponse "Unauthorized"
-// @Failure 404 {object} entity.UserErrorResponse "User not found"
+// @Success 200 {object} entity.UserErrorResponse "Successfully deleted"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "User not found"
+// @Failure 409 {object} apierror.Response "Deleting yourself or the last admin"
 // @Router /user/{id} [delete]
 func (u *UserHandler) deleteHandler(ctx *gin.Context) {
-	u.log.Info("Starting to delete user in the handler layer", nil)
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to delete user in the handler layer", nil)
 
 	id := ctx.Param("id")
-	err := u.userUc.DeleteUser(id)
+	err := u.userUc.DeleteUser(id, ctx.GetString("employee"))
+	if errors.Is(err, usecase.ErrDeleteSelf) || errors.Is(err, repository.ErrLastAdmin) {
+		ctx.Error(apierror.Conflict(err.Error()))
+		return
+	}
+	if errors.Is(err, usecase.ErrUserNotFound) {
+		ctx.Error(apierror.NotFound(fmt.Sprintf("User with ID %s not found", id)))
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("User with ID %s not found", id)})
+		log.Error("Failed to delete the user: ", err)
+		ctx.Error(apierror.Internal(err))
 		return
 	}
 	response := struct {
@@ -167,11 +331,171 @@ func (u *UserHandler) deleteHandler(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+// ActivateUser godoc
+// @Summary Activate user
+// @Description Reinstate a deleted user, they can log in again right away
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} entity.UserErrorResponse "Successfully activated"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 404 {object} apierror.Response "User not found"
+// @Router /user/{id}/activate [patch]
+func (u *UserHandler) activateHandler(ctx *gin.Context) {
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to activate user in the handler layer", nil)
+
+	id := ctx.Param("id")
+	err := u.userUc.ActivateUser(id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		ctx.Error(apierror.NotFound(fmt.Sprintf("User with ID %s not found", id)))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to activate the user: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User activated successfully"})
+}
+
+// ChangePassword godoc
+// @Summary Change own password
+// @Description Change the password of the logged in user, the current password is required. Every session of the user ends with the change, the one of this request included, so the user logs in again with the new password.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.ChangePasswordRequest true "Current and new password"
+// @Success 204 "Password changed"
+// @Failure 400 {object} apierror.Response "Invalid input, or the password rules that failed"
+// @Failure 401 {object} apierror.Response "Invalid credentials"
+// @Router /user/password [put]
+func (u *UserHandler) changePasswordHandler(ctx *gin.Context) {
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to change user password in the handler layer", nil)
+
+	var payload entity.ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+		return
+	}
+
+	err := u.userUc.ChangePassword(ctx.GetString("employee"), payload.CurrentPassword, payload.NewPassword, clientInfo(ctx))
+	switch {
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		ctx.Error(apierror.Unauthorized(err.Error()))
+		return
+	case respondWeakPassword(ctx, err):
+		return
+	case err != nil:
+		log.Error("Failed to change the user password: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// AdminResetPassword godoc
+// @Summary Reset a user's password
+// @Description Set the password of a user who lost it, without the old one. Without a new password a temporary one is generated and returned, only in this response. The user must change it before doing anything else, every session of the user ends with the reset.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body entity.AdminResetPasswordRequest false "New password, empty to generate one"
+// @Success 200 {object} entity.AdminResetPasswordResponse "Password reset"
+// @Failure 400 {object} apierror.Response "Invalid input, or the password rules that failed"
+// @Failure 401 {object} apierror.Response "Unauthorized"
+// @Failure 403 {object} apierror.Response "Not an admin"
+// @Failure 404 {object} apierror.Response "User not found"
+// @Router /admin/user/{id}/reset-password [post]
+func (u *UserHandler) adminResetPasswordHandler(ctx *gin.Context) {
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to reset a user password in the handler layer", nil)
+
+	// the body is optional, a request without one generates the password
+	var payload entity.AdminResetPasswordRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+			ctx.Error(apierror.Validation("invalid payload", common.ValidationErrors(err)))
+			return
+		}
+	}
+
+	temporary, err := u.userUc.AdminResetPassword(ctx.Param("id"), payload.NewPassword, ctx.GetString("employee"))
+	switch {
+	case errors.Is(err, usecase.ErrUserNotFound):
+		ctx.Error(apierror.NotFound(err.Error()))
+		return
+	case respondWeakPassword(ctx, err):
+		return
+	case err != nil:
+		log.Error("Failed to reset the user password: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	// the temporary password must not be kept by a cache on the way
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, entity.AdminResetPasswordResponse{
+		Message:           "Password reset, it must be changed at the next login",
+		TemporaryPassword: temporary,
+	})
+}
+
+// GetMe godoc
+// @Summary Get own profile
+// @Description Profile of the logged in user with the merchants they own, for every role
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.UserProfile "Profile of the logged in user"
+// @Failure 401 {object} apierror.Response "Unauthorized or the user no longer exists"
+// @Router /me [get]
+func (u *UserHandler) meHandler(ctx *gin.Context) {
+	log := u.log.WithContext(ctx.Request.Context())
+	log.Info("Starting to get the user profile in the handler layer", nil)
+
+	profile, err := u.userUc.GetProfile(ctx.GetString("employee"))
+	if errors.Is(err, usecase.ErrUserNotFound) {
+		ctx.Error(apierror.Unauthorized("user no longer exists"))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to get the user profile: ", err)
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	response := struct {
+		Message string
+		Data    entity.UserProfile
+	}{
+		Message: "Success Get Profile",
+		Data:    profile,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
 func (u *UserHandler) Route() {
-	u.rg.GET(config.GetUserList, u.authMiddleware.RequireToken("admin"), u.ListHandler)
-	u.rg.GET(config.GetUser, u.authMiddleware.RequireToken("admin"), u.getIdHandler)
-	u.rg.PUT(config.PutUser, u.authMiddleware.RequireToken("admin"), u.updateHandler)
-	u.rg.DELETE(config.DeleteUser, u.authMiddleware.RequireToken("admin"), u.deleteHandler)
+	u.rg.POST(config.PostUser, u.authMiddleware.RequireToken(), u.authMiddleware.RequirePermission(entity.PermissionUserWrite), u.createHandler)
+	u.rg.POST(config.PostMerchantOnboard, u.authMiddleware.RequireToken(), u.authMiddleware.RequireRoles("admin"),
+		u.authMiddleware.RequirePermission(entity.PermissionUserWrite), u.onboardMerchantHandler)
+	u.rg.GET(config.GetUserList, u.authMiddleware.RequireToken(), u.authMiddleware.RequirePermission(entity.PermissionUserRead), u.ListHandler)
+	u.rg.GET(config.GetUser, u.authMiddleware.RequireToken(), u.authMiddleware.RequirePermission(entity.PermissionUserRead), u.getIdHandler)
+	u.rg.PUT(config.PutUser, u.authMiddleware.RequireToken(), u.authMiddleware.RequirePermission(entity.PermissionUserWrite), u.updateHandler)
+	u.rg.DELETE(config.DeleteUser, u.authMiddleware.RequireToken(), u.authMiddleware.RequirePermission(entity.PermissionUserWrite), u.deleteHandler)
+	u.rg.PATCH(config.PatchUserActive, u.authMiddleware.RequireToken(), u.authMiddleware.RequirePermission(entity.PermissionUserWrite), u.activateHandler)
+	u.rg.PUT(config.PutUserPassword, u.authMiddleware.RequireToken(), u.authMiddleware.RequireRoles("admin", "employee"), u.changePasswordHandler)
+	u.rg.GET(config.GetMe, u.authMiddleware.RequireToken(), u.authMiddleware.RequireRoles("admin", "employee"), u.meHandler)
+	u.rg.POST(config.PostUserResetPassword, u.authMiddleware.RequireToken(), u.authMiddleware.RequireRoles("admin"), u.adminResetPasswordHandler)
 }
 
 func NewUserHandler(userUc usecase.UserUsecase, authMiddleware middleware.AuthMiddleware, rg *gin.RouterGroup, log *logger.Logger) *UserHandler {
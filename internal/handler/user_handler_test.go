@@ -3,15 +3,20 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/mock/middleware_mock"
 	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/custom"
+	"server-pulsa-app/internal/usecase"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -28,17 +33,35 @@ func (u *UserHandlerTest) SetupTest() {
 	u.userUc = new(usecase_mock.UserUseCaseMock)
 	u.authMiddleware = new(middleware_mock.AuthMiddlewareMock)
 
-	u.router = gin.Default()
+	u.router = newTestRouter()
 	gin.SetMode(gin.TestMode)
 
 	rg := u.router.Group("/api/v1")
 
 	u.log = logger.NewLogger()
 	u.userHandler = NewUserHandler(u.userUc, u.authMiddleware, rg, &u.log)
+	u.router.POST("/api/v1/user", func(ctx *gin.Context) {
+		ctx.Set("role", ctx.GetHeader("X-Test-Role"))
+	}, u.userHandler.createHandler)
+	u.router.POST("/api/v1/merchant/onboard", u.userHandler.onboardMerchantHandler)
 	u.router.GET("/api/v1/users", u.userHandler.ListHandler)
 	u.router.GET("/api/v1/user/:id", u.userHandler.getIdHandler)
-	u.router.PUT("/api/v1/user/:id", u.userHandler.updateHandler)
-	u.router.DELETE("/api/v1/user/:id", u.userHandler.deleteHandler)
+	u.router.PUT("/api/v1/user/:id", func(ctx *gin.Context) {
+		ctx.Set("role", ctx.GetHeader("X-Test-Role"))
+	}, u.userHandler.updateHandler)
+	u.router.DELETE("/api/v1/user/:id", func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-admin-test")
+	}, u.userHandler.deleteHandler)
+	u.router.PATCH("/api/v1/user/:id/activate", u.userHandler.activateHandler)
+	u.router.PUT("/api/v1/user/password", func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-user-test")
+	}, u.userHandler.changePasswordHandler)
+	u.router.GET("/api/v1/me", func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-user-test")
+	}, u.userHandler.meHandler)
+	u.router.POST("/api/v1/admin/user/:id/reset-password", func(ctx *gin.Context) {
+		ctx.Set("employee", "uuid-admin-test")
+	}, u.userHandler.adminResetPasswordHandler)
 }
 
 func (u *UserHandlerTest) TestUpdate() {
@@ -52,7 +75,7 @@ func (u *UserHandlerTest) TestUpdate() {
 	if err != nil {
 		u.T().Fatalf("error '%s' occured when marshaling the payload", err)
 	}
-	u.userUc.On("UpdateUser", payload).Return(payload, nil)
+	u.userUc.On("UpdateUser", payload, "", "").Return(payload, nil)
 	request, err := http.NewRequest("PUT", "/api/v1/user/"+payload.Id_user, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		u.T().Fatalf("error '%s' occured when creating the request", err)
@@ -65,9 +88,15 @@ func (u *UserHandlerTest) TestUpdate() {
 }
 
 func (u *UserHandlerTest) TestList() {
-	u.userUc.On("ListUser").Return([]entity.User{}, nil)
+	page := custom.UserPage{
+		Users: []custom.UserListItem{{IdUser: "uuid-user-test", Username: "eko", Role: "employee", Email: "eko@example.com"}},
+		Page:  2,
+		Size:  10,
+		Total: 11,
+	}
+	u.userUc.On("ListUser", custom.UserFilter{Page: 2, Size: 10, Q: "eko", Role: "employee"}).Return(page, nil)
 
-	request, err := http.NewRequest("GET", "/api/v1/users", nil)
+	request, err := http.NewRequest("GET", "/api/v1/users?page=2&size=10&q=eko&role=employee", nil)
 	if err != nil {
 		u.T().Fatalf("error '%s' occurred when creating the request", err)
 	}
@@ -76,6 +105,67 @@ func (u *UserHandlerTest) TestList() {
 	u.router.ServeHTTP(w, request)
 
 	u.Equal(http.StatusOK, w.Code)
+	u.NotContains(w.Body.String(), "password")
+	var response custom.UserPage
+	u.Nil(json.Unmarshal(w.Body.Bytes(), &response))
+	u.Equal(page, response)
+}
+
+func (u *UserHandlerTest) TestList_SecondPageByUsername() {
+	page := custom.UserPage{Users: []custom.UserListItem{{IdUser: "uuid-user-test", Username: "eko3"}}, Page: 2, Size: 2, Total: 3}
+	u.userUc.On("ListUser", custom.UserFilter{Page: 2, Size: 2, Username: "eko"}).Return(page, nil)
+
+	request, _ := http.NewRequest("GET", "/api/v1/users?page=2&pageSize=2&username=eko", nil)
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusOK, w.Code)
+	var response custom.UserPage
+	u.Nil(json.Unmarshal(w.Body.Bytes(), &response))
+	u.Equal(page, response)
+}
+
+func (u *UserHandlerTest) TestList_InactiveSince() {
+	u.userUc.On("ListUser", custom.UserFilter{Page: 1, InactiveDays: 30}).Return(custom.UserPage{Users: []custom.UserListItem{}}, nil)
+
+	request, _ := http.NewRequest("GET", "/api/v1/users?inactive_since=30d", nil)
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusOK, w.Code)
+
+	request, _ = http.NewRequest("GET", "/api/v1/users?inactive_since=a+month", nil)
+	w = httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (u *UserHandlerTest) TestList_InvalidPage() {
+	request, err := http.NewRequest("GET", "/api/v1/users?page=0", nil)
+	if err != nil {
+		u.T().Fatalf("error '%s' occurred when creating the request", err)
+	}
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusBadRequest, w.Code)
+	u.userUc.AssertNotCalled(u.T(), "ListUser", mock.Anything)
+}
+
+func (u *UserHandlerTest) TestList_InvalidRole() {
+	u.userUc.On("ListUser", custom.UserFilter{Page: 1, Role: "root"}).Return(custom.UserPage{}, usecase.ErrInvalidRole)
+
+	request, err := http.NewRequest("GET", "/api/v1/users?role=root", nil)
+	if err != nil {
+		u.T().Fatalf("error '%s' occurred when creating the request", err)
+	}
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusBadRequest, w.Code)
 }
 
 func (u *UserHandlerTest) TestGet() {
@@ -94,7 +184,7 @@ func (u *UserHandlerTest) TestGet() {
 
 func (u *UserHandlerTest) TestDelete() {
 	id := "uuid-user-test"
-	u.userUc.On("DeleteUser", id).Return(nil)
+	u.userUc.On("DeleteUser", id, "uuid-admin-test").Return(nil)
 	request, err := http.NewRequest("DELETE", "/api/v1/user/"+id, nil)
 	if err != nil {
 		u.T().Fatalf("error '%s' occured when creating the request", err)
@@ -106,6 +196,225 @@ func (u *UserHandlerTest) TestDelete() {
 	u.Equal(http.StatusOK, w.Code)
 }
 
+func (u *UserHandlerTest) TestDelete_Conflict() {
+	u.userUc.On("DeleteUser", "uuid-admin-test", "uuid-admin-test").Return(usecase.ErrDeleteSelf)
+	u.userUc.On("DeleteUser", "uuid-other-admin", "uuid-admin-test").Return(fmt.Errorf("failed to delete user: %w", repository.ErrLastAdmin))
+
+	for id, message := range map[string]string{"uuid-admin-test": "cannot delete yourself", "uuid-other-admin": "cannot delete the last admin"} {
+		request, _ := http.NewRequest("DELETE", "/api/v1/user/"+id, nil)
+		w := httptest.NewRecorder()
+		u.router.ServeHTTP(w, request)
+
+		u.Equal(http.StatusConflict, w.Code, id)
+		u.Contains(w.Body.String(), message, id)
+	}
+}
+
+func (u *UserHandlerTest) TestActivate() {
+	id := "uuid-user-test"
+	u.userUc.On("ActivateUser", id).Return(nil)
+	request, _ := http.NewRequest("PATCH", "/api/v1/user/"+id+"/activate", nil)
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusOK, w.Code)
+}
+
+func (u *UserHandlerTest) TestActivate_NotFound() {
+	id := "uuid-user-test"
+	u.userUc.On("ActivateUser", id).Return(repository.ErrUserNotFound)
+	request, _ := http.NewRequest("PATCH", "/api/v1/user/"+id+"/activate", nil)
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusNotFound, w.Code)
+}
+
+func (u *UserHandlerTest) TestChangePassword() {
+	u.userUc.On("ChangePassword", "uuid-user-test", "old-pass1", "new-pass1", entity.ClientInfo{}).Return(nil)
+	request, _ := http.NewRequest("PUT", "/api/v1/user/password", bytes.NewBufferString(`{"currentPassword": "old-pass1", "newPassword": "new-pass1"}`))
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusNoContent, w.Code)
+}
+
+func (u *UserHandlerTest) TestChangePassword_wrongCurrentPassword() {
+	u.userUc.On("ChangePassword", "uuid-user-test", "guess", "new-pass1", entity.ClientInfo{}).Return(usecase.ErrInvalidCredentials)
+	request, _ := http.NewRequest("PUT", "/api/v1/user/password", bytes.NewBufferString(`{"currentPassword": "guess", "newPassword": "new-pass1"}`))
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusUnauthorized, w.Code)
+	u.NotContains(w.Body.String(), "not found")
+}
+
+func (u *UserHandlerTest) TestMe_Success() {
+	profile := entity.UserProfile{Id_user: "uuid-user-test", Username: "eko", Role: "employee", Merchants: []entity.Merchant{{IdMerchant: "uuid-merchant"}}}
+	u.userUc.On("GetProfile", "uuid-user-test").Return(profile, nil).Once()
+
+	request, _ := http.NewRequest("GET", "/api/v1/me", nil)
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusOK, record.Code)
+	u.Contains(record.Body.String(), "uuid-merchant")
+	u.NotContains(record.Body.String(), "password")
+}
+
+func (u *UserHandlerTest) TestMe_UserDeleted() {
+	u.userUc.On("GetProfile", "uuid-user-test").Return(entity.UserProfile{}, usecase.ErrUserNotFound).Once()
+
+	request, _ := http.NewRequest("GET", "/api/v1/me", nil)
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusUnauthorized, record.Code)
+}
+
+func (u *UserHandlerTest) TestCreate_Success() {
+	u.userUc.On("CreateUser", entity.User{Username: "eko", Password: "secret123", Role: "admin"}, "admin").
+		Return(entity.User{Id_user: "uuid-user", Username: "eko", Password: "hash", Role: "admin"}, nil).Once()
+
+	request, _ := http.NewRequest("POST", "/api/v1/user", bytes.NewBufferString(`{"name":"eko","password":"secret123","role":"admin"}`))
+	request.Header.Set("X-Test-Role", "admin")
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusCreated, record.Code)
+	u.Contains(record.Body.String(), `"role":"admin"`)
+	u.NotContains(record.Body.String(), "hash")
+}
+
+func (u *UserHandlerTest) TestCreate_AdminRoleByNonAdmin() {
+	u.userUc.On("CreateUser", entity.User{Username: "eko", Password: "secret123", Role: "admin"}, "supervisor").
+		Return(entity.User{}, usecase.ErrAdminRoleForbidden).Once()
+
+	request, _ := http.NewRequest("POST", "/api/v1/user", bytes.NewBufferString(`{"name":"eko","password":"secret123","role":"admin"}`))
+	request.Header.Set("X-Test-Role", "supervisor")
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusForbidden, record.Code)
+	u.Contains(record.Body.String(), `"code":"forbidden"`)
+}
+
+func (u *UserHandlerTest) TestUpdate_AdminRoleByNonAdmin() {
+	payload := entity.User{Id_user: "uuid-user-test", Username: "testuser", Password: "password", Role: "admin"}
+	u.userUc.On("UpdateUser", payload, "", "supervisor").Return(entity.User{}, usecase.ErrAdminRoleForbidden).Once()
+
+	body, _ := json.Marshal(payload)
+	request, _ := http.NewRequest("PUT", "/api/v1/user/uuid-user-test", bytes.NewBuffer(body))
+	request.Header.Set("X-Test-Role", "supervisor")
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusForbidden, record.Code)
+}
+
+func (u *UserHandlerTest) TestCreate_InvalidRole() {
+	request, _ := http.NewRequest("POST", "/api/v1/user", bytes.NewBufferString(`{"name":"eko","password":"secret123","role":"owner"}`))
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusBadRequest, record.Code)
+	var response struct {
+		Details map[string]string `json:"details"`
+	}
+	u.NoError(json.Unmarshal(record.Body.Bytes(), &response))
+	u.Equal(map[string]string{"role": "role must be one of [admin employee] (role)"}, response.Details)
+	u.userUc.AssertNotCalled(u.T(), "CreateUser")
+}
+
+func (u *UserHandlerTest) TestOnboardMerchant() {
+	u.userUc.On("OnboardMerchant", entity.User{Username: "eko", Password: "secret123", Role: "employee"},
+		entity.Merchant{NameMerchant: "Konter Pak Eko", Address: "Jombang", IdProduct: "uuid-product"}).
+		Return(entity.MerchantOnboarding{
+			User:     entity.UserResponse{Id_user: "uuid-eko", Username: "eko", Role: "employee"},
+			Merchant: entity.Merchant{IdMerchant: "uuid-merchant", IdUser: "uuid-eko", NameMerchant: "Konter Pak Eko"},
+		}, nil).Once()
+
+	body := `{"user": {"name": "eko", "password": "secret123", "role": "employee"},
+		"merchant": {"nameMerchant": "Konter Pak Eko", "address": "Jombang", "idProduct": "uuid-product"}}`
+	request, _ := http.NewRequest("POST", "/api/v1/merchant/onboard", bytes.NewBufferString(body))
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusCreated, record.Code)
+	var response entity.MerchantOnboarding
+	u.NoError(json.Unmarshal(record.Body.Bytes(), &response))
+	u.Equal("uuid-eko", response.User.Id_user)
+	u.Equal("uuid-eko", response.Merchant.IdUser)
+	u.userUc.AssertExpectations(u.T())
+}
+
+func (u *UserHandlerTest) TestOnboardMerchant_InvalidPayload() {
+	request, _ := http.NewRequest("POST", "/api/v1/merchant/onboard", bytes.NewBufferString(`{"user": {"name": "eko", "role": "employee"}, "merchant": {}}`))
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusBadRequest, record.Code)
+	var response struct {
+		Details map[string]string `json:"details"`
+	}
+	u.NoError(json.Unmarshal(record.Body.Bytes(), &response))
+	u.Contains(response.Details, "user.password")
+	u.Contains(response.Details, "merchant.nameMerchant")
+	u.userUc.AssertNotCalled(u.T(), "OnboardMerchant")
+}
+
+func (u *UserHandlerTest) TestOnboardMerchant_UnknownProduct() {
+	u.userUc.On("OnboardMerchant", mock.Anything, mock.Anything).
+		Return(entity.MerchantOnboarding{}, fmt.Errorf("%w: uuid-product", repository.ErrProductNotFound)).Once()
+
+	body := `{"user": {"name": "eko", "password": "secret123", "role": "employee"},
+		"merchant": {"nameMerchant": "Konter Pak Eko", "address": "Jombang", "idProduct": "uuid-product"}}`
+	request, _ := http.NewRequest("POST", "/api/v1/merchant/onboard", bytes.NewBufferString(body))
+	record := httptest.NewRecorder()
+	u.router.ServeHTTP(record, request)
+
+	u.Equal(http.StatusNotFound, record.Code)
+}
+
+func (u *UserHandlerTest) TestAdminResetPassword_ReturnsTemporaryPassword() {
+	u.userUc.On("AdminResetPassword", "uuid-user-test", "", "uuid-admin-test").Return("k7QmZp2xVt9sRw4e", nil).Once()
+	request, _ := http.NewRequest("POST", "/api/v1/admin/user/uuid-user-test/reset-password", nil)
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusOK, w.Code)
+	u.Equal("no-store", w.Header().Get("Cache-Control"))
+	var response entity.AdminResetPasswordResponse
+	u.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	u.Equal("k7QmZp2xVt9sRw4e", response.TemporaryPassword)
+}
+
+func (u *UserHandlerTest) TestAdminResetPassword_ChosenPassword() {
+	u.userUc.On("AdminResetPassword", "uuid-user-test", "n3wSecret123", "uuid-admin-test").Return("", nil).Once()
+	request, _ := http.NewRequest("POST", "/api/v1/admin/user/uuid-user-test/reset-password", bytes.NewBufferString(`{"newPassword": "n3wSecret123"}`))
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusOK, w.Code)
+	u.NotContains(w.Body.String(), "temporaryPassword")
+}
+
+func (u *UserHandlerTest) TestAdminResetPassword_UnknownUser() {
+	u.userUc.On("AdminResetPassword", "missing", "", "uuid-admin-test").Return("", usecase.ErrUserNotFound).Once()
+	request, _ := http.NewRequest("POST", "/api/v1/admin/user/missing/reset-password", nil)
+
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, request)
+
+	u.Equal(http.StatusNotFound, w.Code)
+}
+
 func TestUserHandlerSuite(t *testing.T) {
 	suite.Run(t, new(UserHandlerTest))
 }
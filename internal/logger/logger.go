@@ -1,7 +1,11 @@
 package logger
 
 import (
+	"context"
+	"io"
 	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
@@ -9,37 +13,116 @@ import (
 
 var Log *log.Logger
 
+const defaultOutput = "server-pulsa-app.log"
+
+// Logger is shared by every request goroutine and copied by value, so copies share the mutex that serializes writes.
+// requestId is only set on the copies WithContext returns.
 type Logger struct {
-	log *log.Logger
+	log       *log.Logger
+	mu        *sync.Mutex
+	requestId string
 }
 
-func NewLogger() Logger {
+type requestIdKey struct{}
 
-	log := logrus.New()
+// ContextWithRequestID stores the id of the request in ctx for WithContext.
+func ContextWithRequestID(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
 
-	file, err := os.OpenFile("server-pulsa-app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+// RequestIDFromContext is the id ContextWithRequestID stored, empty outside of a request.
+func RequestIDFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+// WithContext returns a copy of the logger whose lines carry the request id of ctx, the copy shares the output and
+// the mutex with l.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	withId := *l
+	withId.requestId = RequestIDFromContext(ctx)
+	return &withId
+}
+
+func (l *Logger) fields(data any) logrus.Fields {
+	fields := logrus.Fields{"data": data}
+	if l.requestId != "" {
+		fields["requestId"] = l.requestId
+	}
+	return fields
+}
+
+func NewLogger() Logger {
+
+	out, err := openOutput(defaultOutput)
 	if err != nil {
-		Log.Fatal("failed to open log file: ", err)
+		out = os.Stdout
 	}
 
-	log.Out = file
+	return newLogger(out)
+
+}
+
+func newLogger(out io.Writer) Logger {
+	log := logrus.New()
+
+	log.Out = out
 
 	log.SetFormatter(&logrus.JSONFormatter{
 		TimestampFormat: "2006-01-02 15:04:05",
 		PrettyPrint:     true,
 	})
 
-	return Logger{log: log}
+	return Logger{log: log, mu: &sync.Mutex{}}
+}
 
+// openOutput resolves "stdout", "stderr" or a file path, creating the parent directories of the file.
+func openOutput(destination string) (io.Writer, error) {
+	switch destination {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 }
+
+// SetOutput redirects every copy of the logger to destination, falling back to stdout with a warning when it cannot be opened.
+func (l *Logger) SetOutput(destination string) {
+	out, err := openOutput(destination)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if previous, ok := l.log.Out.(*os.File); ok && previous != os.Stdout && previous != os.Stderr {
+		previous.Close()
+	}
+
+	if err != nil {
+		l.log.Out = os.Stdout
+		l.log.WithFields(logrus.Fields{
+			"data": destination,
+		}).Warn("Failed to open the log output, logging to stdout instead: ", err)
+		return
+	}
+
+	l.log.Out = out
+}
+
 func (l *Logger) Info(message string, data any) {
-	l.log.WithFields(logrus.Fields{
-		"data": data,
-	}).Info(message)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.log.WithFields(l.fields(data)).Info(message)
 }
 
 func (l *Logger) Error(message string, data any) {
-	l.log.WithFields(logrus.Fields{
-		"data": data,
-	}).Error(message)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.log.WithFields(l.fields(data)).Error(message)
 }
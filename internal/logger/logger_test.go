@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_ConcurrentWrites(t *testing.T) {
+	// bytes.Buffer is not safe for concurrent use, the race detector flags any write that is not serialized
+	var out bytes.Buffer
+	logger := newLogger(&out)
+
+	const goroutines, perGoroutine = 50, 20
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				if j%2 == 0 {
+					logger.Info("concurrent info", i)
+				} else {
+					logger.Error("concurrent error", i)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	decoder := json.NewDecoder(&out)
+	entries := 0
+	for {
+		var entry map[string]any
+		err := decoder.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err, "log entries are interleaved") {
+			return
+		}
+		entries++
+	}
+	assert.Equal(t, goroutines*perGoroutine, entries)
+}
+
+func TestLogger_SetOutputFile(t *testing.T) {
+	destination := filepath.Join(t.TempDir(), "nested", "dir", "app.log")
+	logger := newLogger(io.Discard)
+
+	logger.SetOutput(destination)
+	logger.Info("written to the file", nil)
+
+	content, err := os.ReadFile(destination)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "written to the file")
+}
+
+func TestLogger_SetOutputInvalidPathFallsBackToStdout(t *testing.T) {
+	// a regular file cannot be the parent directory of the log file
+	parent := filepath.Join(t.TempDir(), "not-a-dir")
+	assert.NoError(t, os.WriteFile(parent, nil, 0644))
+	logger := newLogger(io.Discard)
+
+	logger.SetOutput(filepath.Join(parent, "app.log"))
+
+	assert.Equal(t, os.Stdout, logger.log.Out)
+}
+
+func TestLogger_SetOutputStderr(t *testing.T) {
+	logger := newLogger(io.Discard)
+
+	logger.SetOutput("stderr")
+
+	assert.Equal(t, os.Stderr, logger.log.Out)
+}
+
+func TestLogger_WithContextAddsRequestID(t *testing.T) {
+	var out bytes.Buffer
+	logger := newLogger(&out)
+	ctx := ContextWithRequestID(context.Background(), "req-123")
+
+	logger.WithContext(ctx).Error("failed during the request", nil)
+	logger.Info("outside of a request", nil)
+
+	decoder := json.NewDecoder(&out)
+	var withId, withoutId map[string]any
+	assert.NoError(t, decoder.Decode(&withId))
+	assert.NoError(t, decoder.Decode(&withoutId))
+	assert.Equal(t, "req-123", withId["requestId"])
+	assert.NotContains(t, withoutId, "requestId")
+}
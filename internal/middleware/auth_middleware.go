@@ -1,61 +1,239 @@
 package middleware
 
 import (
+	"errors"
 	"log"
-	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
 	"server-pulsa-app/internal/shared/service"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+// Error codes of rejected tokens, so clients can tell a token to refresh from one that was logged out.
+const (
+	TokenExpiredCode       = "token_expired"
+	TokenInvalidCode       = "token_invalid"
+	TokenMissingCode       = "token_missing"
+	TokenRevokedCode       = "token_revoked"
+	AccountDeactivatedCode = "account_deactivated"
+	// PasswordChangeRequiredCode rejects the token of a user whose password an admin reset, until they change it.
+	PasswordChangeRequiredCode = "password_change_required"
+)
+
+// passwordChangeRoutes are the routes a token with MustChangePassword still reaches, matched on the end of the route
+// so every API version is covered.
+var passwordChangeRoutes = []string{config.PutUserPassword, config.Logout, config.GetMe}
+
+// AuthMiddleware authenticates with RequireToken and authorizes with RequireRoles or RequirePermission, which must
+// run after it, like RateLimit for the routes with a limit of their own:
+//
+//	rg.DELETE(path, authMiddleware.RequireToken(), authMiddleware.RequireRoles("admin"), handler)
+//	rg.PUT(path, authMiddleware.RequireToken(), authMiddleware.RequirePermission(entity.PermissionProductWrite), handler)
+//	rg.GET(path, authMiddleware.RequireToken(), authMiddleware.RateLimit(20), handler)
 type AuthMiddleware interface {
-	RequireToken(roles ...string) gin.HandlerFunc
+	RequireToken() gin.HandlerFunc
+	RequireRoles(roles ...string) gin.HandlerFunc
+	RequirePermission(permission string) gin.HandlerFunc
+	RateLimit(perMinute int) gin.HandlerFunc
 }
 
 type authMiddleware struct {
-	jwtService service.JwtService
+	jwtService  service.JwtService
+	revokedRepo repository.RevokedTokenRepository
+	sessionRepo repository.SessionRepository
+	userRepo    repository.UserRepository
+	statusCache *userStatusCache
+	// userLimiter is nil when the per-user rate limit is disabled
+	userLimiter Limiter
+	routeLimits repository.RateLimitRepository
+}
+
+// userStatusCache remembers for ttl whether a user is active, so deactivating a user locks out their tokens within
+// ttl without a query on every request.
+type userStatusCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]userStatus
+}
+
+type userStatus struct {
+	active    bool
+	checkedAt time.Time
+}
+
+func (c *userStatusCache) isActive(userId string, lookup func(string) (bool, error)) (bool, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	status, ok := c.entries[userId]
+	c.mu.Unlock()
+	if ok && now.Sub(status.checkedAt) < c.ttl {
+		return status.active, nil
+	}
+
+	active, err := lookup(userId)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.entries[userId] = userStatus{active: active, checkedAt: now}
+	c.mu.Unlock()
+	return active, nil
 }
 
 type AuthHeader struct {
 	AuthorizationHeader string `header:"Authorization"`
 }
 
-func (a *authMiddleware) RequireToken(roles ...string) gin.HandlerFunc {
+func (a *authMiddleware) RequireToken() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var authHeader AuthHeader
 		if err := ctx.ShouldBindHeader(&authHeader); err != nil {
 			log.Printf("RequireToken: Error binding header: %v \n", err)
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+			abortTokenMissing(ctx)
 			return
 		}
 
-		tokenHeader := strings.TrimPrefix(authHeader.AuthorizationHeader, "Bearer ")
-		if tokenHeader == "" {
+		tokenHeader, found := strings.CutPrefix(authHeader.AuthorizationHeader, "Bearer ")
+		if !found || strings.TrimSpace(tokenHeader) == "" {
 			log.Println("RequireToken: Missing token")
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+			abortTokenMissing(ctx)
 			return
 		}
 
 		claims, err := a.jwtService.ValidateToken(tokenHeader)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			log.Println("RequireToken: Token expired")
+			// the WWW-Authenticate hint of RFC 6750 tells generic clients to refresh rather than login again
+			ctx.Header("WWW-Authenticate", `Bearer error="invalid_token", error_description="token has expired"`)
+			abortWithError(ctx, apierror.Unauthorized("token has expired").WithCode(TokenExpiredCode))
+			return
+		}
 		if err != nil {
 			log.Printf("RequireToken: Error parsing token: %v \n", err)
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+			abortTokenInvalid(ctx)
+			return
+		}
+
+		if claims.ID == "" {
+			log.Println("RequireToken: Missing jti in token")
+			abortTokenInvalid(ctx)
+			return
+		}
+
+		revoked, err := a.revokedRepo.IsRevoked(claims.ID)
+		if err != nil {
+			log.Printf("RequireToken: Error checking token revocation: %v \n", err)
+			abortWithError(ctx, apierror.Internal(err))
+			return
+		}
+		if revoked {
+			log.Println("RequireToken: Token revoked")
+			abortWithError(ctx, apierror.Unauthorized("token has been revoked").WithCode(TokenRevokedCode))
+			return
+		}
+
+		// not cached, a revoked session must be rejected from the very next request on
+		if claims.SessionId != "" {
+			revoked, err := a.sessionRepo.IsRevoked(claims.SessionId)
+			if err != nil {
+				log.Printf("RequireToken: Error checking session revocation: %v \n", err)
+				abortWithError(ctx, apierror.Internal(err))
+				return
+			}
+			if revoked {
+				log.Println("RequireToken: Session revoked")
+				abortWithError(ctx, apierror.Unauthorized("session has been revoked").WithCode(TokenRevokedCode))
+				return
+			}
+		}
+
+		active, err := a.statusCache.isActive(claims.UserId, a.userRepo.IsActive)
+		if err != nil {
+			log.Printf("RequireToken: Error checking the user status: %v \n", err)
+			abortWithError(ctx, apierror.Internal(err))
+			return
+		}
+		if !active {
+			log.Println("RequireToken: User deactivated")
+			abortWithError(ctx, apierror.Unauthorized("account has been deactivated").WithCode(AccountDeactivatedCode))
 			return
 		}
 
 		ctx.Set("employee", claims.UserId)
+		ctx.Set("jti", claims.ID)
+		ctx.Set("sessionId", claims.SessionId)
+		if claims.ExpiresAt != nil {
+			ctx.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+		}
 
 		role := claims.Role
 		if role == "" {
 			log.Println("RequireToken: Missing role in token")
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+			abortTokenInvalid(ctx)
 			return
 		}
 
-		if !isValidRole(role, roles) {
-			log.Println("RequireToken: Invalid role")
-			ctx.AbortWithStatus(http.StatusForbidden)
+		if claims.MustChangePassword && !slices.ContainsFunc(passwordChangeRoutes, func(route string) bool {
+			return strings.HasSuffix(ctx.FullPath(), route)
+		}) {
+			log.Println("RequireToken: Password must be changed first")
+			abortWithError(ctx, apierror.Forbidden("password must be changed first").WithCode(PasswordChangeRequiredCode))
+			return
+		}
+
+		if a.userLimiter != nil && isMutating(ctx.Request.Method) {
+			allowed, err := allowRequest(ctx, a.userLimiter, claims.UserId)
+			if err != nil {
+				log.Printf("RequireToken: Error checking the rate limit of the user: %v \n", err)
+			}
+			if !allowed {
+				log.Println("RequireToken: Too many requests of the user")
+				return
+			}
+		}
+
+		permissions := claims.Permissions
+		if permissions == nil {
+			// issued before permissions existed, the role keeps what it could do back then
+			permissions = entity.DefaultRolePermissions[role]
+		}
+
+		ctx.Set("role", role)
+		ctx.Set("permissions", permissions)
+		ctx.Set("merchantIds", claims.MerchantIds)
+		ctx.Next()
+	}
+}
+
+// abortTokenMissing answers requests without an "Authorization: Bearer <token>" header.
+func abortTokenMissing(ctx *gin.Context) {
+	ctx.Header("WWW-Authenticate", "Bearer")
+	abortWithError(ctx, apierror.Unauthorized("missing bearer token").WithCode(TokenMissingCode))
+}
+
+// abortTokenInvalid answers tokens with a bad signature or claims, refreshing can't fix them so the client has to
+// login again.
+func abortTokenInvalid(ctx *gin.Context) {
+	ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	abortWithError(ctx, apierror.Unauthorized("invalid token").WithCode(TokenInvalidCode))
+}
+
+// RequireRoles rejects with 403 a token whose role, set by RequireToken, is not one of roles.
+func (a *authMiddleware) RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !isValidRole(ctx.GetString("role"), roles) {
+			log.Println("RequireRoles: Insufficient role")
+			abortWithError(ctx, apierror.Forbidden("insufficient role"))
 			return
 		}
 
@@ -63,6 +241,42 @@ func (a *authMiddleware) RequireToken(roles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission rejects with 403 a token without permission, the permissions are set by RequireToken. A changed
+// role mapping applies from the next login or token refresh.
+func (a *authMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !slices.Contains(ctx.GetStringSlice("permissions"), permission) {
+			log.Println("RequirePermission: Missing permission " + permission)
+			abortWithError(ctx, apierror.Forbidden("missing permission "+permission))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RateLimit allows the user set by RequireToken perMinute requests a minute of the route, counted on their own but
+// on top of REQUEST_RATE_LIMIT. It is for the routes too expensive to be called as often as the others.
+func (a *authMiddleware) RateLimit(perMinute int) gin.HandlerFunc {
+	limiter := NewWindowLimiter(a.routeLimits, perMinute, time.Minute)
+	return func(ctx *gin.Context) {
+		if limiter == nil {
+			ctx.Next()
+			return
+		}
+
+		allowed, err := allowRequest(ctx, limiter, "user:"+ctx.GetString("employee")+" "+ctx.FullPath())
+		if err != nil {
+			log.Printf("RateLimit: Error checking the rate limit of the route: %v \n", err)
+		}
+		if !allowed {
+			log.Println("RateLimit: Too many requests of the route")
+			return
+		}
+		ctx.Next()
+	}
+}
+
 func isValidRole(userRole string, validRoles []string) bool {
 	for _, role := range validRoles {
 		if userRole == role {
@@ -72,6 +286,18 @@ func isValidRole(userRole string, validRoles []string) bool {
 	return false
 }
 
-func NewAuthMiddleware(jwtService service.JwtService) AuthMiddleware {
-	return &authMiddleware{jwtService: jwtService}
+// NewAuthMiddleware checks the user of every token is still active, the answer is cached per user for statusTTL.
+// Mutating requests are also limited per user with userLimit, unauthenticated routes keep their IP based limiter.
+// The limits of RateLimit are counted in routeLimits.
+func NewAuthMiddleware(jwtService service.JwtService, revokedRepo repository.RevokedTokenRepository, sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository, routeLimits repository.RateLimitRepository, statusTTL time.Duration, userLimit UserRateLimit) AuthMiddleware {
+	return &authMiddleware{
+		jwtService:  jwtService,
+		revokedRepo: revokedRepo,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		statusCache: &userStatusCache{ttl: statusTTL, entries: map[string]userStatus{}},
+		userLimiter: newTokenBucketLimiter(userLimit),
+		routeLimits: routeLimits,
+	}
 }
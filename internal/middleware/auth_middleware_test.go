@@ -0,0 +1,409 @@
+package middleware
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/mock/repo_mock"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/mock/service_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/model"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type authMiddlewareTestSuite struct {
+	suite.Suite
+	jwtService  *service_mock.JwtServiceMock
+	revokedRepo *repositorymock.MockRevokedTokenRepository
+	sessionRepo *repositorymock.MockSessionRepository
+	userRepo    *repo_mock.UserRepoMock
+	router      *gin.Engine
+}
+
+func (s *authMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.jwtService = new(service_mock.JwtServiceMock)
+	s.revokedRepo = new(repositorymock.MockRevokedTokenRepository)
+	s.sessionRepo = new(repositorymock.MockSessionRepository)
+	s.userRepo = new(repo_mock.UserRepoMock)
+	s.userRepo.On("IsActive", "uuid-user").Return(true, nil).Maybe()
+	s.router = gin.New()
+	authMiddleware := NewAuthMiddleware(s.jwtService, s.revokedRepo, s.sessionRepo, s.userRepo, repository.NewMemoryRateLimitRepository(), time.Minute, UserRateLimit{})
+	s.router.GET("/protected", authMiddleware.RequireToken(), authMiddleware.RequireRoles("admin"), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"jti": ctx.GetString("jti"), "merchantIds": ctx.GetStringSlice("merchantIds"), "sessionId": ctx.GetString("sessionId")})
+	})
+	s.router.GET("/products", authMiddleware.RequireToken(), authMiddleware.RequirePermission(entity.PermissionProductWrite), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"permissions": ctx.GetStringSlice("permissions")})
+	})
+	s.router.GET("/api/v1/me", authMiddleware.RequireToken(), authMiddleware.RequireRoles("admin"), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+}
+
+func (s *authMiddlewareTestSuite) request(token string) *httptest.ResponseRecorder {
+	return s.requestPath("/protected", token)
+}
+
+func (s *authMiddlewareTestSuite) requestPath(path, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func claimWithJti(jti string) *model.Claim {
+	return claimWithRole(jti, "admin")
+}
+
+func claimWithRole(jti, role string) *model.Claim {
+	return &model.Claim{
+		RegisteredClaims: jwt.RegisteredClaims{ID: jti, ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+		UserId:           "uuid-user",
+		Role:             role,
+	}
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_Valid() {
+	s.jwtService.On("ValidateToken", "valid-token").Return(claimWithJti("token-jti"), nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+
+	w := s.request("valid-token")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "token-jti")
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_SetsMerchantIds() {
+	claim := claimWithJti("token-jti")
+	claim.MerchantIds = []string{"uuid-merchant-a", "uuid-merchant-b"}
+	s.jwtService.On("ValidateToken", "valid-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+
+	w := s.request("valid-token")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"merchantIds":["uuid-merchant-a","uuid-merchant-b"]`)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_Revoked() {
+	s.jwtService.On("ValidateToken", "revoked-token").Return(claimWithJti("token-jti"), nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(true, nil)
+
+	w := s.request("revoked-token")
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.Contains(w.Body.String(), TokenRevokedCode)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_ActiveSession() {
+	claim := claimWithJti("token-jti")
+	claim.SessionId = "uuid-session"
+	s.jwtService.On("ValidateToken", "valid-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+	s.sessionRepo.On("IsRevoked", "uuid-session").Return(false, nil).Once()
+
+	w := s.request("valid-token")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"sessionId":"uuid-session"`)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_RevokedSession() {
+	claim := claimWithJti("token-jti")
+	claim.SessionId = "uuid-session"
+	s.jwtService.On("ValidateToken", "valid-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+	s.sessionRepo.On("IsRevoked", "uuid-session").Return(false, nil).Once()
+	s.sessionRepo.On("IsRevoked", "uuid-session").Return(true, nil).Once()
+
+	s.Equal(http.StatusOK, s.request("valid-token").Code)
+	// the revocation is not cached, the request right after it is rejected
+	w := s.request("valid-token")
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.Contains(w.Body.String(), TokenRevokedCode)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_SessionLookupFails() {
+	claim := claimWithJti("token-jti")
+	claim.SessionId = "uuid-session"
+	s.jwtService.On("ValidateToken", "valid-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+	s.sessionRepo.On("IsRevoked", "uuid-session").Return(false, sql.ErrConnDone).Once()
+
+	w := s.request("valid-token")
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.Contains(w.Body.String(), `"code":"`+apierror.CodeInternal+`"`)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_Expired() {
+	s.jwtService.On("ValidateToken", "expired-token").Return((*model.Claim)(nil), fmt.Errorf("unauthorized : %w", jwt.ErrTokenExpired))
+
+	w := s.request("expired-token")
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.Contains(w.Body.String(), TokenExpiredCode)
+	s.Contains(w.Header().Get("WWW-Authenticate"), `error_description="token has expired"`)
+	s.revokedRepo.AssertNotCalled(s.T(), "IsRevoked")
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_NearExpiry() {
+	claim := claimWithJti("token-jti")
+	claim.ExpiresAt = jwt.NewNumericDate(time.Now().Add(2 * time.Second))
+	s.jwtService.On("ValidateToken", "near-expiry-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+
+	w := s.request("near-expiry-token")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Empty(w.Header().Get("WWW-Authenticate"))
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_InvalidSignature() {
+	s.jwtService.On("ValidateToken", "forged-token").Return((*model.Claim)(nil), fmt.Errorf("unauthorized : %w", jwt.ErrTokenSignatureInvalid))
+
+	w := s.request("forged-token")
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.Contains(w.Body.String(), TokenInvalidCode)
+	s.Equal(`Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+	s.revokedRepo.AssertNotCalled(s.T(), "IsRevoked")
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_Missing() {
+	for _, header := range []string{"", "Bearer ", "Basic YWRtaW46c2VjcmV0", "valid-token"} {
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+
+		s.Equal(http.StatusUnauthorized, w.Code, header)
+		s.Contains(w.Body.String(), TokenMissingCode, header)
+	}
+	s.jwtService.AssertNotCalled(s.T(), "ValidateToken", mock.Anything)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireRoles_InsufficientRole() {
+	s.jwtService.On("ValidateToken", "employee-token").Return(claimWithRole("token-jti", "employee"), nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+
+	w := s.request("employee-token")
+
+	s.Equal(http.StatusForbidden, w.Code)
+	s.Contains(w.Body.String(), "insufficient role")
+}
+
+func (s *authMiddlewareTestSuite) TestRequirePermission_LegacyTokenUsesRoleDefaults() {
+	s.jwtService.On("ValidateToken", "admin-token").Return(claimWithRole("admin-jti", "admin"), nil)
+	s.jwtService.On("ValidateToken", "employee-token").Return(claimWithRole("employee-jti", "employee"), nil)
+	s.revokedRepo.On("IsRevoked", mock.Anything).Return(false, nil)
+
+	s.Equal(http.StatusOK, s.requestPath("/products", "admin-token").Code)
+	s.Equal(http.StatusForbidden, s.requestPath("/products", "employee-token").Code)
+}
+
+func (s *authMiddlewareTestSuite) TestRequirePermission_GrantedToEmployee() {
+	claim := claimWithRole("token-jti", "employee")
+	claim.Permissions = []string{entity.PermissionProductWrite}
+	s.jwtService.On("ValidateToken", "employee-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+
+	w := s.requestPath("/products", "employee-token")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"permissions":["product:write"]`)
+}
+
+func (s *authMiddlewareTestSuite) TestRequirePermission_Missing() {
+	claim := claimWithRole("token-jti", "admin")
+	claim.Permissions = []string{}
+	s.jwtService.On("ValidateToken", "admin-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+
+	w := s.requestPath("/products", "admin-token")
+
+	s.Equal(http.StatusForbidden, w.Code)
+	s.Contains(w.Body.String(), "missing permission product:write")
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_MissingRole() {
+	s.jwtService.On("ValidateToken", "roleless-token").Return(claimWithRole("token-jti", ""), nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+
+	w := s.request("roleless-token")
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.Contains(w.Body.String(), TokenInvalidCode)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_DeactivatedUser() {
+	claim := claimWithJti("token-jti")
+	claim.UserId = "uuid-deactivated"
+	s.jwtService.On("ValidateToken", "valid-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+	s.userRepo.On("IsActive", "uuid-deactivated").Return(false, nil).Once()
+
+	w := s.request("valid-token")
+
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.Contains(w.Body.String(), AccountDeactivatedCode)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_CachesUserStatus() {
+	claim := claimWithJti("token-jti")
+	claim.UserId = "uuid-cached"
+	s.jwtService.On("ValidateToken", "valid-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "token-jti").Return(false, nil)
+	s.userRepo.On("IsActive", "uuid-cached").Return(true, nil).Once()
+
+	s.Equal(http.StatusOK, s.request("valid-token").Code)
+	s.Equal(http.StatusOK, s.request("valid-token").Code)
+
+	s.userRepo.AssertNumberOfCalls(s.T(), "IsActive", 1)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_UserRateLimit() {
+	s.userRepo.On("IsActive", "uuid-other").Return(true, nil).Maybe()
+	authMiddleware := NewAuthMiddleware(s.jwtService, s.revokedRepo, s.sessionRepo, s.userRepo, repository.NewMemoryRateLimitRepository(), time.Minute, UserRateLimit{PerMinute: 1, Burst: 2})
+	router := gin.New()
+	router.POST("/limited", authMiddleware.RequireToken(), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.GET("/limited", authMiddleware.RequireToken(), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	other := claimWithJti("jti-other")
+	other.UserId = "uuid-other"
+	s.jwtService.On("ValidateToken", "user-token").Return(claimWithJti("jti-user"), nil)
+	s.jwtService.On("ValidateToken", "other-token").Return(other, nil)
+	s.revokedRepo.On("IsRevoked", "jti-user").Return(false, nil)
+	s.revokedRepo.On("IsRevoked", "jti-other").Return(false, nil)
+
+	send := func(method, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/limited", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	s.Equal(http.StatusOK, send("POST", "user-token").Code)
+	s.Equal(http.StatusOK, send("POST", "user-token").Code)
+	w := send("POST", "user-token")
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	s.NotEmpty(w.Header().Get("Retry-After"))
+
+	// the bucket is per user and only spent by mutating requests
+	s.Equal(http.StatusOK, send("POST", "other-token").Code)
+	s.Equal(http.StatusOK, send("GET", "user-token").Code)
+}
+
+func (s *authMiddlewareTestSuite) TestRateLimit_CountsEveryRouteOfTheUserOnItsOwn() {
+	s.userRepo.On("IsActive", "uuid-other").Return(true, nil).Maybe()
+	authMiddleware := NewAuthMiddleware(s.jwtService, s.revokedRepo, s.sessionRepo, s.userRepo, repository.NewMemoryRateLimitRepository(), time.Minute, UserRateLimit{})
+	router := gin.New()
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	router.GET("/api/v1/products", authMiddleware.RequireToken(), authMiddleware.RateLimit(1), ok)
+	router.GET("/api/v1/report", authMiddleware.RequireToken(), authMiddleware.RateLimit(1), ok)
+	router.GET("/api/v1/merchants", authMiddleware.RequireToken(), authMiddleware.RateLimit(0), ok)
+
+	other := claimWithJti("jti-other")
+	other.UserId = "uuid-other"
+	s.jwtService.On("ValidateToken", "user-token").Return(claimWithJti("jti-user"), nil)
+	s.jwtService.On("ValidateToken", "other-token").Return(other, nil)
+	s.revokedRepo.On("IsRevoked", "jti-user").Return(false, nil)
+	s.revokedRepo.On("IsRevoked", "jti-other").Return(false, nil)
+	send := func(path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	s.Equal(http.StatusOK, send("/api/v1/products", "user-token").Code)
+	w := send("/api/v1/products", "user-token")
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	s.NotEmpty(w.Header().Get("Retry-After"))
+
+	// another route and another user have their own count
+	s.Equal(http.StatusOK, send("/api/v1/report", "user-token").Code)
+	s.Equal(http.StatusOK, send("/api/v1/products", "other-token").Code)
+	for i := 0; i < 3; i++ {
+		s.Equal(http.StatusOK, send("/api/v1/merchants", "user-token").Code, "a limit of zero is unlimited")
+	}
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_MustChangePassword() {
+	claim := claimWithJti("reset-jti")
+	claim.MustChangePassword = true
+	s.jwtService.On("ValidateToken", "reset-token").Return(claim, nil)
+	s.revokedRepo.On("IsRevoked", "reset-jti").Return(false, nil)
+
+	w := s.request("reset-token")
+
+	s.Equal(http.StatusForbidden, w.Code)
+	s.Contains(w.Body.String(), PasswordChangeRequiredCode)
+	s.Equal(http.StatusOK, s.requestPath("/api/v1/me", "reset-token").Code)
+}
+
+func (s *authMiddlewareTestSuite) TestRequireToken_MustChangePasswordUntilChanged() {
+	authMiddleware := NewAuthMiddleware(s.jwtService, s.revokedRepo, s.sessionRepo, s.userRepo, repository.NewMemoryRateLimitRepository(), time.Minute, UserRateLimit{})
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	s.router.POST("/api/v1/transaction", authMiddleware.RequireToken(), ok)
+	s.router.PUT("/api/v1/user/password", authMiddleware.RequireToken(), ok)
+	send := func(method, path, token string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	flagged := claimWithRole("first-login-jti", "employee")
+	flagged.MustChangePassword = true
+	s.jwtService.On("ValidateToken", "first-login-token").Return(flagged, nil)
+	s.revokedRepo.On("IsRevoked", "first-login-jti").Return(false, nil)
+	// the token issued after the password change no longer carries the flag
+	s.jwtService.On("ValidateToken", "changed-token").Return(claimWithRole("changed-jti", "employee"), nil)
+	s.revokedRepo.On("IsRevoked", "changed-jti").Return(false, nil)
+
+	s.Equal(http.StatusForbidden, send("POST", "/api/v1/transaction", "first-login-token"))
+	s.Equal(http.StatusOK, send("PUT", "/api/v1/user/password", "first-login-token"))
+	s.Equal(http.StatusOK, send("POST", "/api/v1/transaction", "changed-token"))
+}
+
+func TestTokenBucketLimiter_Refills(t *testing.T) {
+	limiter := newTokenBucketLimiter(UserRateLimit{PerMinute: 60, Burst: 1})
+	now := time.Now()
+
+	allowed, _, _ := limiter.Allow("uuid-user", now)
+	assert.True(t, allowed)
+	allowed, retryAfter, err := limiter.Allow("uuid-user", now)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+
+	allowed, _, _ = limiter.Allow("uuid-user", now.Add(time.Second))
+	assert.True(t, allowed)
+}
+
+func TestNewTokenBucketLimiter_Disabled(t *testing.T) {
+	assert.Nil(t, newTokenBucketLimiter(UserRateLimit{}))
+}
+
+func TestAuthMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(authMiddlewareTestSuite))
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"server-pulsa-app/internal/shared/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyLimitKey holds the *limitedBody of the request, the error middleware answers body_too_large for it.
+const bodyLimitKey = "bodyLimit"
+
+// errBodyTooLarge is the answer to a body over the limit.
+var errBodyTooLarge = apierror.New(http.StatusRequestEntityTooLarge, "body_too_large", "request body too large")
+
+// limitedBody remembers whether the handler hit the body limit while reading.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimitWriter turns the 400 a handler answers to a truncated body into a 413.
+type bodyLimitWriter struct {
+	gin.ResponseWriter
+	body *limitedBody
+}
+
+func (w *bodyLimitWriter) WriteHeader(code int) {
+	if code == http.StatusBadRequest && w.body.exceeded {
+		code = http.StatusRequestEntityTooLarge
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// NewBodyLimitMiddleware caps request bodies at limit bytes and answers 413 when a body is larger. Routes in
+// overrides, keyed by their full route path, get their own limit so uploads can opt into more, the server fills it
+// from config.UploadRoutes. A limit of zero or less disables the check.
+func NewBodyLimitMiddleware(limit int64, overrides map[string]int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		routeLimit := limit
+		if override, ok := overrides[ctx.FullPath()]; ok {
+			routeLimit = override
+		}
+
+		if routeLimit <= 0 || ctx.Request.Body == nil {
+			ctx.Next()
+			return
+		}
+
+		if ctx.Request.ContentLength > routeLimit {
+			abortWithError(ctx, errBodyTooLarge)
+			return
+		}
+
+		// bodies without a Content-Length are only noticed while the handler reads them
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(ctx.Writer, ctx.Request.Body, routeLimit)}
+		ctx.Request.Body = body
+		ctx.Set(bodyLimitKey, body)
+		ctx.Writer = &bodyLimitWriter{ResponseWriter: ctx.Writer, body: body}
+		ctx.Next()
+	}
+}
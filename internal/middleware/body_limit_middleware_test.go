@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type bodyLimitMiddlewareTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func (s *bodyLimitMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.router.Use(NewBodyLimitMiddleware(64, map[string]int64{"/import": 1024}))
+
+	echo := func(ctx *gin.Context) {
+		var payload map[string]string
+		if err := ctx.ShouldBindJSON(&payload); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		ctx.JSON(http.StatusOK, payload)
+	}
+	s.router.POST("/transaction", echo)
+	s.router.POST("/import", echo)
+}
+
+func (s *bodyLimitMiddlewareTestSuite) post(path string, body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, body)
+	req.ContentLength = contentLength
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *bodyLimitMiddlewareTestSuite) TestNormalBodyPassesThrough() {
+	body := `{"name": "ok"}`
+
+	w := s.post("/transaction", strings.NewReader(body), int64(len(body)))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"name":"ok"`)
+}
+
+func (s *bodyLimitMiddlewareTestSuite) TestOversizedBodyIsRejected() {
+	body := `{"name": "` + strings.Repeat("a", 100) + `"}`
+
+	w := s.post("/transaction", strings.NewReader(body), int64(len(body)))
+
+	s.Equal(http.StatusRequestEntityTooLarge, w.Code)
+}
+
+// without a Content-Length the limit is only hit while the handler reads the body
+func (s *bodyLimitMiddlewareTestSuite) TestOversizedBodyWithoutContentLength() {
+	body := `{"name": "` + strings.Repeat("a", 100) + `"}`
+
+	w := s.post("/transaction", io.NopCloser(strings.NewReader(body)), -1)
+
+	s.Equal(http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func (s *bodyLimitMiddlewareTestSuite) TestOverrideAllowsLargerBody() {
+	body := `{"name": "` + strings.Repeat("a", 100) + `"}`
+
+	w := s.post("/import", strings.NewReader(body), int64(len(body)))
+
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func TestBodyLimitMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(bodyLimitMiddlewareTestSuite))
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"server-pulsa-app/internal/shared/apierror"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// concurrencyRetryAfter is the seconds a shed request is told to wait, the requests holding the slots are normally
+// done by then.
+const concurrencyRetryAfter = 1
+
+// NewConcurrencyLimitMiddleware answers 503 with Retry-After while limit requests are already being served, so a
+// burst waits in the clients instead of piling up on the database connection pool. Routes in exempt, keyed by their
+// full route path such as "/readyz", are never shed so the probes keep answering under load. A limit of zero or less
+// disables the check.
+func NewConcurrencyLimitMiddleware(limit int, exempt map[string]bool) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+
+	slots := make(chan struct{}, limit)
+	return func(ctx *gin.Context) {
+		if exempt[ctx.FullPath()] {
+			ctx.Next()
+			return
+		}
+
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			ctx.Next()
+		default:
+			ctx.Header("Retry-After", strconv.Itoa(concurrencyRetryAfter))
+			abortWithError(ctx, apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable, "server is busy, try again later"))
+		}
+	}
+}
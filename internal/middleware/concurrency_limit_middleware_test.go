@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type concurrencyLimitMiddlewareTestSuite struct {
+	suite.Suite
+	router  *gin.Engine
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *concurrencyLimitMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.started = make(chan struct{})
+	s.release = make(chan struct{})
+	s.router = gin.New()
+	s.router.Use(NewConcurrencyLimitMiddleware(2, map[string]bool{"/readyz": true}))
+
+	// /slow holds its slot until the test releases it
+	s.router.GET("/slow", func(ctx *gin.Context) {
+		s.started <- struct{}{}
+		<-s.release
+		ctx.Status(http.StatusOK)
+	})
+	s.router.GET("/fast", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	s.router.GET("/readyz", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+}
+
+func (s *concurrencyLimitMiddlewareTestSuite) get(path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+// saturate fills both slots with requests that stay in flight until the returned func releases them
+func (s *concurrencyLimitMiddlewareTestSuite) saturate() func() {
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.get("/slow")
+		}()
+		<-s.started
+	}
+	return func() {
+		close(s.release)
+		wg.Wait()
+	}
+}
+
+func (s *concurrencyLimitMiddlewareTestSuite) TestShedsOverTheLimitThenRecovers() {
+	release := s.saturate()
+
+	w := s.get("/fast")
+	s.Equal(http.StatusServiceUnavailable, w.Code)
+	s.Equal("1", w.Header().Get("Retry-After"))
+
+	release()
+
+	s.Equal(http.StatusOK, s.get("/fast").Code)
+}
+
+func (s *concurrencyLimitMiddlewareTestSuite) TestExemptRouteIsNeverShed() {
+	release := s.saturate()
+	defer release()
+
+	s.Equal(http.StatusOK, s.get("/readyz").Code)
+}
+
+func (s *concurrencyLimitMiddlewareTestSuite) TestZeroDisablesTheLimit() {
+	router := gin.New()
+	router.Use(NewConcurrencyLimitMiddleware(0, nil))
+	router.GET("/fast", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func TestConcurrencyLimitMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(concurrencyLimitMiddlewareTestSuite))
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"server-pulsa-app/internal/shared/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewJSONContentTypeMiddleware answers 415 to POST, PUT and PATCH requests whose body is not sent as
+// application/json, instead of letting the handler fail to bind it. Requests without a body, such as a refund, pass
+// through. Routes in skip, keyed by their full route path, opt out so CSV and multipart uploads can send their own
+// content type, the server fills it from config.UploadRoutes.
+func NewJSONContentTypeMiddleware(skip map[string]bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		switch ctx.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			ctx.Next()
+			return
+		}
+
+		if skip[ctx.FullPath()] || ctx.Request.Body == nil || ctx.Request.Body == http.NoBody || ctx.Request.ContentLength == 0 {
+			ctx.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+		if err != nil || mediaType != gin.MIMEJSON {
+			abortWithError(ctx, apierror.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json"))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type contentTypeMiddlewareTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func (s *contentTypeMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.router.Use(NewJSONContentTypeMiddleware(map[string]bool{"/import": true}))
+
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	s.router.POST("/transaction", ok)
+	s.router.PUT("/transaction", ok)
+	s.router.GET("/transaction", ok)
+	s.router.POST("/refund", ok)
+	s.router.POST("/import", ok)
+}
+
+func (s *contentTypeMiddlewareTestSuite) send(method, path string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *contentTypeMiddlewareTestSuite) TestJSONPassesThrough() {
+	w := s.send("POST", "/transaction", strings.NewReader(`{"customerName": "ani"}`), "application/json; charset=utf-8")
+
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func (s *contentTypeMiddlewareTestSuite) TestFormEncodedIsRejected() {
+	for _, method := range []string{"POST", "PUT"} {
+		w := s.send(method, "/transaction", strings.NewReader("customerName=ani"), "application/x-www-form-urlencoded")
+
+		s.Equal(http.StatusUnsupportedMediaType, w.Code, method)
+	}
+}
+
+func (s *contentTypeMiddlewareTestSuite) TestMissingContentTypeIsRejected() {
+	w := s.send("POST", "/transaction", strings.NewReader(`{"customerName": "ani"}`), "")
+
+	s.Equal(http.StatusUnsupportedMediaType, w.Code)
+}
+
+func (s *contentTypeMiddlewareTestSuite) TestRequestsWithoutBodyPassThrough() {
+	s.Equal(http.StatusOK, s.send("POST", "/refund", nil, "").Code)
+	s.Equal(http.StatusOK, s.send("GET", "/transaction", nil, "").Code)
+}
+
+func (s *contentTypeMiddlewareTestSuite) TestSkippedRouteAcceptsOtherTypes() {
+	w := s.send("POST", "/import", strings.NewReader("code,price\nAXIS10,10500\n"), "text/csv")
+
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func TestContentTypeMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(contentTypeMiddlewareTestSuite))
+}
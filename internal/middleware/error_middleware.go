@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewErrorMiddleware answers the last error a handler passed to ctx.Error as an apierror.Response, handlers return
+// right after it without writing anything:
+//
+//	if err != nil {
+//		ctx.Error(apierror.NotFound("merchant not found"))
+//		return
+//	}
+//
+// An error that is not an *apierror.Error is a 500. The causes of the 500s are logged with the request id. A 400
+// for a body cut at the limit of the body limit middleware is answered as body_too_large.
+func NewErrorMiddleware(log *logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+		apiErr := apierror.As(ctx.Errors.Last().Err)
+		if body, ok := ctx.Value(bodyLimitKey).(*limitedBody); ok && body.exceeded && apiErr.Status == http.StatusBadRequest {
+			apiErr = errBodyTooLarge
+		}
+		if apiErr.Status >= http.StatusInternalServerError {
+			log.WithContext(ctx.Request.Context()).Error("Request failed: ", apiErr.Error())
+		}
+		abortWithError(ctx, apiErr)
+	}
+}
+
+// abortWithError writes err as an apierror.Response with the request id, the middlewares answering before the
+// handler runs use it directly.
+func abortWithError(ctx *gin.Context, err *apierror.Error) {
+	response := err.Response()
+	response.RequestId = ctx.GetString(RequestIDKey)
+	ctx.AbortWithStatusJSON(err.Status, response)
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/apierror"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type errorMiddlewareTestSuite struct {
+	suite.Suite
+	router  *gin.Engine
+	logFile string
+}
+
+func (s *errorMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger()
+	s.logFile = filepath.Join(s.T().TempDir(), "error.log")
+	log.SetOutput(s.logFile)
+
+	s.router = gin.New()
+	s.router.Use(NewRequestIDMiddleware(), NewBodyLimitMiddleware(16, nil), NewErrorMiddleware(&log))
+	s.router.GET("/not-found", func(ctx *gin.Context) {
+		ctx.Error(apierror.NotFound("merchant not found"))
+	})
+	s.router.GET("/database", func(ctx *gin.Context) {
+		ctx.Error(errors.New(`pq: relation "mst_merchant" does not exist`))
+	})
+	s.router.GET("/written", func(ctx *gin.Context) {
+		ctx.Error(apierror.NotFound("merchant not found"))
+		ctx.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	s.router.POST("/bind", func(ctx *gin.Context) {
+		var payload struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if err := ctx.ShouldBindJSON(&payload); err != nil {
+			ctx.Error(apierror.Validation("invalid payload", nil))
+		}
+	})
+}
+
+func (s *errorMiddlewareTestSuite) serve(req *http.Request) (*httptest.ResponseRecorder, map[string]any) {
+	req.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	var body map[string]any
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+	return w, body
+}
+
+func (s *errorMiddlewareTestSuite) TestAnswersTheError() {
+	w, body := s.serve(httptest.NewRequest(http.MethodGet, "/not-found", nil))
+
+	s.Equal(http.StatusNotFound, w.Code)
+	s.Equal(map[string]any{"code": apierror.CodeNotFound, "message": "merchant not found", "request_id": "req-123"}, body)
+}
+
+func (s *errorMiddlewareTestSuite) TestOtherErrorsAreInternalAndLogged() {
+	w, body := s.serve(httptest.NewRequest(http.MethodGet, "/database", nil))
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.Equal(map[string]any{"code": apierror.CodeInternal, "message": "internal server error", "request_id": "req-123"}, body)
+	s.NotContains(w.Body.String(), "mst_merchant")
+
+	logged, err := os.ReadFile(s.logFile)
+	s.Require().NoError(err)
+	s.Contains(string(logged), "mst_merchant")
+	s.Contains(string(logged), "req-123")
+}
+
+func (s *errorMiddlewareTestSuite) TestKeepsWhatTheHandlerWrote() {
+	w, body := s.serve(httptest.NewRequest(http.MethodGet, "/written", nil))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(map[string]any{"ok": true}, body)
+}
+
+func (s *errorMiddlewareTestSuite) TestBodyOverTheLimitIsTooLarge() {
+	// no Content-Length, so the limit is only hit while the handler reads
+	req := httptest.NewRequest(http.MethodPost, "/bind", io.NopCloser(bytes.NewBufferString(`{"name": "a name longer than the limit"}`)))
+	req.ContentLength = -1
+	w, body := s.serve(req)
+
+	s.Equal(http.StatusRequestEntityTooLarge, w.Code)
+	s.Equal("body_too_large", body["code"])
+
+	w, body = s.serve(httptest.NewRequest(http.MethodPost, "/bind", bytes.NewBufferString(`{}`)))
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Equal(apierror.CodeValidation, body["code"])
+}
+
+func TestErrorMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(errorMiddlewareTestSuite))
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressedContentTypes are payloads that are already compressed, gzipping them again only costs CPU.
+var compressedContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/vnd.openxmlformats",
+}
+
+type gzipWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *gzipWriter) WriteHeaderNow() {}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *gzipWriter) Status() int {
+	return w.status
+}
+
+func (w *gzipWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *gzipWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// shouldCompress reports whether the buffered response is worth compressing.
+func (w *gzipWriter) shouldCompress(minSize int) bool {
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" || w.body.Len() == 0 || w.body.Len() < minSize {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	for _, prefix := range compressedContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *gzipWriter) flush(minSize int) {
+	w.ResponseWriter.WriteHeader(w.status)
+	header := w.ResponseWriter.Header()
+	header.Add("Vary", "Accept-Encoding")
+
+	if !w.shouldCompress(minSize) {
+		w.ResponseWriter.Write(w.body.Bytes())
+		return
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(w.body.Bytes()); err != nil || gz.Close() != nil {
+		w.ResponseWriter.Write(w.body.Bytes())
+		return
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	header.Set("Content-Length", strconv.Itoa(compressed.Len()))
+	w.ResponseWriter.Write(compressed.Bytes())
+}
+
+func acceptsGzip(ctx *gin.Context) bool {
+	for _, encoding := range strings.Split(ctx.GetHeader("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.TrimSpace(name) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// NewGzipMiddleware compresses responses of at least minSize bytes for clients that send
+// "Accept-Encoding: gzip". Smaller payloads and already compressed content are sent as is.
+func NewGzipMiddleware(minSize int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !acceptsGzip(ctx) {
+			ctx.Next()
+			return
+		}
+
+		writer := &gzipWriter{ResponseWriter: ctx.Writer, status: http.StatusOK}
+		ctx.Writer = writer
+		defer func() {
+			ctx.Writer = writer.ResponseWriter
+			// a panicking handler wrote nothing worth sending, the recovery middleware answers it on the real writer
+			if recovered := recover(); recovered != nil {
+				panic(recovered)
+			}
+			writer.flush(minSize)
+		}()
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/service"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type gzipMiddlewareTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func (s *gzipMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.router.Use(NewGzipMiddleware(1024))
+	s.router.GET("/large", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"data": strings.Repeat("transaction ", 500)})
+	})
+	s.router.GET("/small", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"data": "ok"})
+	})
+	s.router.GET("/report", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", make([]byte, 4096))
+	})
+}
+
+func (s *gzipMiddlewareTestSuite) serve(path, acceptEncoding string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *gzipMiddlewareTestSuite) TestLargeJSON_Compressed() {
+	w := s.serve("/large", "gzip, deflate")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("gzip", w.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(w.Body)
+	s.Require().NoError(err)
+	body, err := io.ReadAll(reader)
+	s.Require().NoError(err)
+	s.Contains(string(body), `"data":"transaction transaction`)
+}
+
+func (s *gzipMiddlewareTestSuite) TestLargeJSON_PlainWithoutHeader() {
+	w := s.serve("/large", "")
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Empty(w.Header().Get("Content-Encoding"))
+	s.Contains(w.Body.String(), `"data":"transaction transaction`)
+}
+
+func (s *gzipMiddlewareTestSuite) TestSmallPayload_NotCompressed() {
+	w := s.serve("/small", "gzip")
+
+	s.Empty(w.Header().Get("Content-Encoding"))
+	s.JSONEq(`{"data":"ok"}`, w.Body.String())
+}
+
+func (s *gzipMiddlewareTestSuite) TestCompressedContent_NotCompressed() {
+	w := s.serve("/report", "gzip")
+
+	s.Empty(w.Header().Get("Content-Encoding"))
+	s.Len(w.Body.Bytes(), 4096)
+}
+
+func (s *gzipMiddlewareTestSuite) TestPanic_AnsweredByRecovery() {
+	log := logger.NewLogger()
+	log.SetOutput(filepath.Join(s.T().TempDir(), "gzip.log"))
+	s.router = gin.New()
+	s.router.Use(NewRecoveryMiddleware(&log, service.NewMetrics()), NewGzipMiddleware(1024))
+	s.router.GET("/panic", func(ctx *gin.Context) { panic("nil map") })
+
+	w := s.serve("/panic", "gzip")
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.Contains(w.Header().Get("Content-Type"), "application/json")
+	var response map[string]string
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Equal(InternalErrorCode, response["code"])
+}
+
+func TestGzipMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(gzipMiddlewareTestSuite))
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"server-pulsa-app/internal/shared/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels the requests that hit no route, so scanners probing random paths share one series.
+const unmatchedRoute = "unmatched"
+
+// NewMetricsMiddleware records the count, the duration and the in-flight requests of every route.
+func NewMetricsMiddleware(metrics *service.Metrics) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		inFlight := metrics.InFlight(route)
+		inFlight.Inc()
+		start := time.Now()
+		ctx.Next()
+		inFlight.Dec()
+
+		metrics.ObserveRequest(route, ctx.Request.Method, strconv.Itoa(ctx.Writer.Status()), time.Since(start))
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/shared/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type metricsMiddlewareTestSuite struct {
+	suite.Suite
+	router  *gin.Engine
+	metrics *service.Metrics
+}
+
+func (s *metricsMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.metrics = service.NewMetrics()
+	s.router = gin.New()
+	s.router.Use(NewMetricsMiddleware(s.metrics))
+	s.router.GET("/transaction/:id", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+}
+
+func (s *metricsMiddlewareTestSuite) get(path string) {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+func (s *metricsMiddlewareTestSuite) scrape() string {
+	w := httptest.NewRecorder()
+	s.metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+// the route pattern is the label, not the path with the id in it
+func (s *metricsMiddlewareTestSuite) TestRecordsRequestsByRoute() {
+	s.get("/transaction/tx-a")
+	s.get("/transaction/tx-b")
+
+	metrics := s.scrape()
+
+	s.Contains(metrics, `http_requests_total{method="GET",route="/transaction/:id",status="200"} 2`)
+	s.Contains(metrics, `http_request_duration_seconds_count{method="GET",route="/transaction/:id",status="200"} 2`)
+	s.Contains(metrics, `http_requests_in_flight{route="/transaction/:id"} 0`)
+	s.NotContains(metrics, "tx-a")
+}
+
+func (s *metricsMiddlewareTestSuite) TestUnmatchedPathsShareOneRoute() {
+	s.get("/wp-login.php")
+	s.get("/.env")
+
+	s.Contains(s.scrape(), `http_requests_total{method="GET",route="unmatched",status="404"} 2`)
+}
+
+func TestMetricsMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(metricsMiddlewareTestSuite))
+}
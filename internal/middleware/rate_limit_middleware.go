@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"net/http"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/service"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limiter decides whether one more request of key is allowed at now, otherwise how long until it is. Every rate
+// limit of the API is a Limiter: the windows per client, per route and per public client IP as well as the token
+// bucket of the mutating requests of a user.
+type Limiter interface {
+	Allow(key string, now time.Time) (bool, time.Duration, error)
+}
+
+// windowLimiter allows limit requests of every key in each window, counted in store.
+type windowLimiter struct {
+	store  repository.RateLimitRepository
+	limit  int
+	window time.Duration
+}
+
+func (w *windowLimiter) Allow(key string, now time.Time) (bool, time.Duration, error) {
+	count, resetAt, err := w.store.Hit(key, w.window, now)
+	if err != nil {
+		return false, 0, err
+	}
+	return count <= w.limit, resetAt.Sub(now), nil
+}
+
+// NewWindowLimiter allows limit requests of every key in each window. It is nil for a limit of zero or less, which
+// disables the check.
+func NewWindowLimiter(store repository.RateLimitRepository, limit int, window time.Duration) Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &windowLimiter{store: store, limit: limit, window: window}
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// tokenBucketLimiter lets every key burst up to burst requests, then refills rate tokens per second.
+type tokenBucketLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+// Allow takes a token of key and reports whether there was one, otherwise the time until the next token.
+func (t *tokenBucketLimiter) Allow(key string, now time.Time) (bool, time.Duration, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.buckets[key]
+	if !ok {
+		if len(t.buckets) > 10000 {
+			t.evict(now)
+		}
+		b = &tokenBucket{tokens: t.burst, updatedAt: now}
+		t.buckets[key] = b
+	} else {
+		b.tokens = min(t.burst, b.tokens+now.Sub(b.updatedAt).Seconds()*t.rate)
+		b.updatedAt = now
+	}
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / t.rate * float64(time.Second)), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+// evict drops the buckets that have refilled, a new bucket starts full anyway.
+func (t *tokenBucketLimiter) evict(now time.Time) {
+	for key, b := range t.buckets {
+		if b.tokens+now.Sub(b.updatedAt).Seconds()*t.rate >= t.burst {
+			delete(t.buckets, key)
+		}
+	}
+}
+
+// UserRateLimit is the token bucket of every user on mutating routes, PerMinute tokens are refilled every minute up
+// to Burst. A PerMinute of zero or less disables the check.
+type UserRateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+func newTokenBucketLimiter(limit UserRateLimit) Limiter {
+	if limit.PerMinute <= 0 {
+		return nil
+	}
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucketLimiter{rate: float64(limit.PerMinute) / 60, burst: float64(burst), buckets: make(map[string]*tokenBucket)}
+}
+
+// isMutating reports whether the request can change data, reads are not limited per user.
+func isMutating(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions
+}
+
+// abortTooManyRequests answers 429 with the seconds to wait in Retry-After.
+func abortTooManyRequests(ctx *gin.Context, retryAfter time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	abortWithError(ctx, apierror.New(http.StatusTooManyRequests, apierror.CodeTooManyRequests, "too many requests, try again later"))
+}
+
+// allowRequest answers 429 and returns false when limiter refuses the request of key. A failing limiter lets the
+// request through, a broken counter must not take the API down with it, the error is only returned for the log.
+func allowRequest(ctx *gin.Context, limiter Limiter, key string) (bool, error) {
+	allowed, retryAfter, err := limiter.Allow(key, time.Now())
+	if err != nil {
+		return true, err
+	}
+	if !allowed {
+		abortTooManyRequests(ctx, retryAfter)
+	}
+	return allowed, nil
+}
+
+// NewIPRateLimitMiddleware counts the requests of every client IP in limiter, for the routes without a token.
+// A nil limiter disables the check.
+func NewIPRateLimitMiddleware(limiter Limiter, log *logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if limiter == nil {
+			ctx.Next()
+			return
+		}
+
+		allowed, err := allowRequest(ctx, limiter, "ip:"+ctx.ClientIP())
+		if err != nil {
+			log.WithContext(ctx.Request.Context()).Error("Rate limit check failed: ", err)
+		}
+		if allowed {
+			ctx.Next()
+		}
+	}
+}
+
+// NewRateLimitMiddleware counts the requests of every client in limiter. A client is the user of a valid bearer
+// token, otherwise the client IP, so the users of one office network do not share a limit. Routes in exempt,
+// keyed by their full route path such as "/readyz", are never limited, a nil limiter disables the check. The routes
+// with a limit of their own, see AuthMiddleware.RateLimit, count against both.
+func NewRateLimitMiddleware(limiter Limiter, jwtService service.JwtService, exempt map[string]bool, log *logger.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if limiter == nil || exempt[ctx.FullPath()] {
+			ctx.Next()
+			return
+		}
+
+		allowed, err := allowRequest(ctx, limiter, rateLimitClient(ctx, jwtService))
+		if err != nil {
+			log.WithContext(ctx.Request.Context()).Error("Rate limit check failed: ", err)
+		}
+		if allowed {
+			ctx.Next()
+		}
+	}
+}
+
+// rateLimitClient is the key a request is counted under, the token is only read for its user here, RequireToken
+// still decides whether it is accepted.
+func rateLimitClient(ctx *gin.Context, jwtService service.JwtService) string {
+	if token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+		if claims, err := jwtService.ValidateToken(token); err == nil && claims.UserId != "" {
+			return "user:" + claims.UserId
+		}
+	}
+	return "ip:" + ctx.ClientIP()
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/service_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/model"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/suite"
+)
+
+// failingRateLimitRepository stands in for a shared store that cannot be reached.
+type failingRateLimitRepository struct{}
+
+func (failingRateLimitRepository) Hit(string, time.Duration, time.Time) (int, time.Time, error) {
+	return 0, time.Time{}, errors.New("dial tcp 10.0.0.5:6379: connection refused")
+}
+
+type rateLimitMiddlewareTestSuite struct {
+	suite.Suite
+	jwtService *service_mock.JwtServiceMock
+}
+
+func (s *rateLimitMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.jwtService = new(service_mock.JwtServiceMock)
+	s.jwtService.On("ValidateToken", "token-eko").Return(&model.Claim{UserId: "uuid-eko"}, nil)
+	s.jwtService.On("ValidateToken", "token-expired").Return(&model.Claim{}, jwt.ErrTokenExpired)
+}
+
+func (s *rateLimitMiddlewareTestSuite) router(store repository.RateLimitRepository) *gin.Engine {
+	log := logger.NewLogger()
+	router := gin.New()
+	router.Use(NewRateLimitMiddleware(NewWindowLimiter(store, 2, time.Minute), s.jwtService, map[string]bool{"/healthz": true}, &log))
+	for _, path := range []string{"/api/v1/merchants", "/healthz"} {
+		router.GET(path, func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	}
+	return router
+}
+
+func (s *rateLimitMiddlewareTestSuite) get(router *gin.Engine, path, token, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ip + ":40000"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *rateLimitMiddlewareTestSuite) TestLimitsEveryClient() {
+	router := s.router(repository.NewMemoryRateLimitRepository())
+
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "", "10.0.0.1").Code)
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "", "10.0.0.1").Code)
+	w := s.get(router, "/api/v1/merchants", "", "10.0.0.1")
+
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	s.NoError(err)
+	s.True(retryAfter > 0 && retryAfter <= 61, "Retry-After %d", retryAfter)
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "", "10.0.0.2").Code, "another IP has its own limit")
+}
+
+func (s *rateLimitMiddlewareTestSuite) TestUsersAreCountedApartFromTheirIP() {
+	router := s.router(repository.NewMemoryRateLimitRepository())
+
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "", "10.0.0.1").Code)
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "", "10.0.0.1").Code)
+
+	// the same user from two addresses shares one limit
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "token-eko", "10.0.0.1").Code)
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "token-eko", "10.0.0.2").Code)
+	s.Equal(http.StatusTooManyRequests, s.get(router, "/api/v1/merchants", "token-eko", "10.0.0.3").Code)
+
+	// a token that is not valid counts against the IP
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "token-expired", "10.0.0.4").Code)
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "token-expired", "10.0.0.4").Code)
+	s.Equal(http.StatusTooManyRequests, s.get(router, "/api/v1/merchants", "", "10.0.0.4").Code)
+}
+
+func (s *rateLimitMiddlewareTestSuite) TestExemptRoutes() {
+	router := s.router(repository.NewMemoryRateLimitRepository())
+
+	for i := 0; i < 5; i++ {
+		s.Equal(http.StatusOK, s.get(router, "/healthz", "", "10.0.0.1").Code)
+	}
+	s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "", "10.0.0.1").Code, "exempt requests are not counted")
+}
+
+func (s *rateLimitMiddlewareTestSuite) TestLimiterFailureLetsRequestsThrough() {
+	router := s.router(failingRateLimitRepository{})
+
+	for i := 0; i < 5; i++ {
+		s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "", "10.0.0.1").Code)
+	}
+}
+
+func (s *rateLimitMiddlewareTestSuite) TestDisabled() {
+	log := logger.NewLogger()
+	router := gin.New()
+	router.Use(NewRateLimitMiddleware(NewWindowLimiter(repository.NewMemoryRateLimitRepository(), 0, time.Minute), s.jwtService, nil, &log))
+	router.GET("/api/v1/merchants", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	for i := 0; i < 5; i++ {
+		s.Equal(http.StatusOK, s.get(router, "/api/v1/merchants", "", "10.0.0.1").Code)
+	}
+}
+
+func (s *rateLimitMiddlewareTestSuite) TestIPRateLimit() {
+	log := logger.NewLogger()
+	router := gin.New()
+	router.GET("/public/products", NewIPRateLimitMiddleware(NewWindowLimiter(repository.NewMemoryRateLimitRepository(), 1, time.Minute), &log),
+		func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	s.Equal(http.StatusOK, s.get(router, "/public/products", "token-eko", "10.0.0.1").Code)
+	s.Equal(http.StatusTooManyRequests, s.get(router, "/public/products", "token-eko", "10.0.0.1").Code, "a token does not matter")
+	s.Equal(http.StatusOK, s.get(router, "/public/products", "", "10.0.0.2").Code)
+}
+
+func TestRateLimitMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(rateLimitMiddlewareTestSuite))
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/apierror"
+	"server-pulsa-app/internal/shared/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InternalErrorCode is the code of the 500 answered to a panicking request.
+const InternalErrorCode = apierror.CodeInternal
+
+// NewRecoveryMiddleware answers 500 to a request whose handler panicked, logs the panic with its stack through log
+// and counts it in metrics. gin.Recovery would write them as plain text to stderr. The request id is part of the
+// answer, so a client can report it and the logged stack can be found.
+func NewRecoveryMiddleware(log *logger.Logger, metrics *service.Metrics) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.WithContext(ctx.Request.Context()).Error("Recovered from a panic", map[string]any{
+					"panic": recovered,
+					"stack": string(debug.Stack()),
+				})
+				metrics.PanicRecovered()
+				abortWithError(ctx, apierror.Internal(fmt.Errorf("panic: %v", recovered)))
+			}
+		}()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/service"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type recoveryMiddlewareTestSuite struct {
+	suite.Suite
+	router  *gin.Engine
+	metrics *service.Metrics
+}
+
+func (s *recoveryMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger()
+	log.SetOutput(filepath.Join(s.T().TempDir(), "recovery.log"))
+	s.metrics = service.NewMetrics()
+
+	s.router = gin.New()
+	s.router.Use(NewRequestIDMiddleware(), NewRecoveryMiddleware(&log, s.metrics))
+	s.router.GET("/panic", func(ctx *gin.Context) { panic("nil map") })
+	s.router.GET("/ok", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+}
+
+// panics scrapes the panic counter
+func (s *recoveryMiddlewareTestSuite) panics() string {
+	w := httptest.NewRecorder()
+	s.metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, "http_panics_total ") {
+			return strings.TrimPrefix(line, "http_panics_total ")
+		}
+	}
+	return ""
+}
+
+func (s *recoveryMiddlewareTestSuite) TestPanicAnswersJSONAndIsCounted() {
+	req, _ := http.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.Contains(w.Header().Get("Content-Type"), "application/json")
+	var response map[string]string
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Equal(map[string]string{"code": InternalErrorCode, "message": "internal server error", "request_id": "req-123"}, response)
+	s.NotContains(w.Body.String(), "nil map", "the panic value is only logged")
+	s.Equal("1", s.panics())
+}
+
+func (s *recoveryMiddlewareTestSuite) TestRequestsWithoutPanicPassThrough() {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("0", s.panics())
+}
+
+func TestRecoveryMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(recoveryMiddlewareTestSuite))
+}
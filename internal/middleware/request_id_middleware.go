@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"server-pulsa-app/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the id of a request, a client or proxy may send one and the response always has it.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is where the id is kept in the gin context.
+const RequestIDKey = "requestId"
+
+// validRequestID keeps ids that would break or forge log lines out, anything else gets a fresh id.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// NewRequestIDMiddleware takes the X-Request-ID of the request or generates a UUID, stores it in the gin context and
+// the request context and echoes it in the response, the access log records it so the id a merchant reports leads to
+// the logs of their request.
+func NewRequestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestId := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestId) {
+			requestId = newRequestID()
+		}
+
+		ctx.Set(RequestIDKey, requestId)
+		ctx.Request = ctx.Request.WithContext(logger.ContextWithRequestID(ctx.Request.Context(), requestId))
+		ctx.Header(RequestIDHeader, requestId)
+		ctx.Next()
+	}
+}
+
+// newRequestID is a random version 4 UUID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
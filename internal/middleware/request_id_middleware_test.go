@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"server-pulsa-app/internal/logger"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+type requestIdMiddlewareTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func (s *requestIdMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.router.Use(NewRequestIDMiddleware())
+
+	// the handler answers with the ids it sees in the gin context and in the request context
+	s.router.GET("/transaction", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"gin":     ctx.GetString(RequestIDKey),
+			"context": logger.RequestIDFromContext(ctx.Request.Context()),
+		})
+	})
+}
+
+func (s *requestIdMiddlewareTestSuite) get(requestId string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/transaction", nil)
+	if requestId != "" {
+		req.Header.Set(RequestIDHeader, requestId)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *requestIdMiddlewareTestSuite) TestGeneratesIdWhenMissing() {
+	w := s.get("")
+
+	requestId := w.Header().Get(RequestIDHeader)
+	s.Regexp(uuidPattern, requestId)
+	s.JSONEq(`{"gin":"`+requestId+`","context":"`+requestId+`"}`, w.Body.String())
+}
+
+func (s *requestIdMiddlewareTestSuite) TestKeepsIncomingId() {
+	w := s.get("lb-7f3a.42")
+
+	s.Equal("lb-7f3a.42", w.Header().Get(RequestIDHeader))
+	s.JSONEq(`{"gin":"lb-7f3a.42","context":"lb-7f3a.42"}`, w.Body.String())
+}
+
+func (s *requestIdMiddlewareTestSuite) TestReplacesInvalidId() {
+	w := s.get("forged\" id")
+
+	s.Regexp(uuidPattern, w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIdMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(requestIdMiddlewareTestSuite))
+}
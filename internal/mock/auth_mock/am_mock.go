@@ -9,6 +9,18 @@ type AuthMiddlewareMock struct {
 	mock.Mock
 }
 
-func (m *AuthMiddlewareMock) RequireToken(roles ...string) gin.HandlerFunc {
+func (m *AuthMiddlewareMock) RequireToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {}
+}
+
+func (m *AuthMiddlewareMock) RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {}
+}
+
+func (m *AuthMiddlewareMock) RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {}
+}
+
+func (m *AuthMiddlewareMock) RateLimit(perMinute int) gin.HandlerFunc {
 	return func(ctx *gin.Context) {}
 }
@@ -9,6 +9,18 @@ type AuthMiddlewareMock struct {
 	mock.Mock
 }
 
-func (a *AuthMiddlewareMock) RequireToken(roles ...string) gin.HandlerFunc {
+func (a *AuthMiddlewareMock) RequireToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {}
+}
+
+func (a *AuthMiddlewareMock) RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {}
+}
+
+func (a *AuthMiddlewareMock) RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {}
+}
+
+func (a *AuthMiddlewareMock) RateLimit(perMinute int) gin.HandlerFunc {
 	return func(ctx *gin.Context) {}
 }
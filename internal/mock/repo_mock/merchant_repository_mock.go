@@ -20,18 +20,28 @@ func (m *MerchantRepoMock) Create(payload entity.Merchant) (entity.Merchant, err
 	return args.Get(0).(entity.Merchant), args.Error(1)
 }
 
+func (m *MerchantRepoMock) Onboard(owner entity.User, merchant entity.Merchant) (entity.User, entity.Merchant, error) {
+	args := m.Called(owner, merchant)
+	return args.Get(0).(entity.User), args.Get(1).(entity.Merchant), args.Error(2)
+}
+
 func (m *MerchantRepoMock) List() ([]entity.Merchant, error) {
 	args := m.Called()
 	return args.Get(0).([]entity.Merchant), args.Error(1)
 }
 
+func (m *MerchantRepoMock) ListByUser(userId string) ([]entity.Merchant, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]entity.Merchant), args.Error(1)
+}
+
 func (m *MerchantRepoMock) Get(id string) (entity.Merchant, error) {
 	args := m.Called(id)
 	return args.Get(0).(entity.Merchant), args.Error(1)
 }
 
-func (m *MerchantRepoMock) Update(merchant, newMerchant entity.Merchant) (entity.Merchant, error) {
-	args := m.Called(merchant, newMerchant)
+func (m *MerchantRepoMock) Update(merchant entity.Merchant, payload entity.MerchantUpdateRequest) (entity.Merchant, error) {
+	args := m.Called(merchant, payload)
 	return args.Get(0).(entity.Merchant), args.Error(1)
 }
 
@@ -39,3 +49,28 @@ func (m *MerchantRepoMock) Delete(id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+
+func (m *MerchantRepoMock) GetBalance(merchantId string) (float64, error) {
+	args := m.Called(merchantId)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MerchantRepoMock) AdjustBalances(adjustments map[string]float64, actorId string) error {
+	args := m.Called(adjustments, actorId)
+	return args.Error(0)
+}
+
+func (m *MerchantRepoMock) SetProductPrice(price entity.MerchantProductPrice) error {
+	args := m.Called(price)
+	return args.Error(0)
+}
+
+func (m *MerchantRepoMock) ClearProductPrice(merchantId, productId string) error {
+	args := m.Called(merchantId, productId)
+	return args.Error(0)
+}
+
+func (m *MerchantRepoMock) ListCatalog(merchantId string) ([]entity.MerchantCatalogItem, error) {
+	args := m.Called(merchantId)
+	return args.Get(0).([]entity.MerchantCatalogItem), args.Error(1)
+}
@@ -2,6 +2,8 @@ package repo_mock
 
 import (
 	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/shared/custom"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -20,14 +22,19 @@ func (u *UserRepoMock) GetUserByUsername(username string) (entity.User, error) {
 	return args.Get(0).(entity.User), args.Error(1)
 }
 
+func (u *UserRepoMock) GetUserByEmail(email string) (entity.User, error) {
+	args := u.Called(email)
+	return args.Get(0).(entity.User), args.Error(1)
+}
+
 func (u *UserRepoMock) GetUserByID(id string) (entity.User, error) {
 	args := u.Called(id)
 	return args.Get(0).(entity.User), args.Error(1)
 }
 
-func (u *UserRepoMock) ListUser() ([]entity.User, error) {
-	args := u.Called()
-	return args.Get(0).([]entity.User), args.Error(1)
+func (u *UserRepoMock) ListUser(filter custom.UserFilter) ([]custom.UserListItem, int, error) {
+	args := u.Called(filter)
+	return args.Get(0).([]custom.UserListItem), args.Int(1), args.Error(2)
 }
 
 func (u *UserRepoMock) UpdateUser(payload entity.User) (entity.User, error) {
@@ -35,7 +42,37 @@ func (u *UserRepoMock) UpdateUser(payload entity.User) (entity.User, error) {
 	return args.Get(0).(entity.User), args.Error(1)
 }
 
-func (u *UserRepoMock) DeleteUser(id string) error {
+func (u *UserRepoMock) UpdatePassword(id, hash string) error {
+	args := u.Called(id, hash)
+	return args.Error(0)
+}
+
+func (u *UserRepoMock) SetPassword(id, hash string, mustChange bool) error {
+	args := u.Called(id, hash, mustChange)
+	return args.Error(0)
+}
+
+func (u *UserRepoMock) SoftDeleteUser(id string) error {
 	args := u.Called(id)
 	return args.Error(0)
 }
+
+func (u *UserRepoMock) ActivateUser(id string) error {
+	args := u.Called(id)
+	return args.Error(0)
+}
+
+func (u *UserRepoMock) UpdateLastLogin(id string, at time.Time) error {
+	args := u.Called(id, at)
+	return args.Error(0)
+}
+
+func (u *UserRepoMock) FindDuplicateUsernames() ([]custom.DuplicateUsername, error) {
+	args := u.Called()
+	return args.Get(0).([]custom.DuplicateUsername), args.Error(1)
+}
+
+func (u *UserRepoMock) IsActive(id string) (bool, error) {
+	args := u.Called(id)
+	return args.Bool(0), args.Error(1)
+}
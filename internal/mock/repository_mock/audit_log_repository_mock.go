@@ -0,0 +1,21 @@
+package repositorymock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditLogRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditLogRepository) Record(entry entity.AuditLog) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockAuditLogRepository) List(query entity.AuditQuery) ([]entity.AuditLog, error) {
+	args := m.Called(query)
+	return args.Get(0).([]entity.AuditLog), args.Error(1)
+}
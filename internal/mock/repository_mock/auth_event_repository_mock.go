@@ -0,0 +1,21 @@
+package repositorymock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthEventRepository) Record(event entity.AuthEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockAuthEventRepository) List(query entity.AuthEventQuery) ([]entity.AuthEvent, error) {
+	args := m.Called(query)
+	return args.Get(0).([]entity.AuthEvent), args.Error(1)
+}
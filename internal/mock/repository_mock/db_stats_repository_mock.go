@@ -0,0 +1,16 @@
+package repositorymock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDbStatsRepository struct {
+	mock.Mock
+}
+
+func (m *MockDbStatsRepository) Stats() entity.DbStats {
+	args := m.Called()
+	return args.Get(0).(entity.DbStats)
+}
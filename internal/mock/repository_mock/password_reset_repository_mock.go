@@ -0,0 +1,26 @@
+package repositorymock
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) CreateToken(userId, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(userId, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) FindUsername(tokenHash string) (string, error) {
+	args := m.Called(tokenHash)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) ResetPassword(tokenHash, passwordHash string) (string, error) {
+	args := m.Called(tokenHash, passwordHash)
+	return args.String(0), args.Error(1)
+}
@@ -15,16 +15,31 @@ func (m *MockProductRepository) Create(product entity.Product) (entity.Product,
 	return args.Get(0).(entity.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) List() ([]entity.Product, error) {
-	args := m.Called()
+func (m *MockProductRepository) List(query entity.ProductQuery) ([]entity.Product, error) {
+	args := m.Called(query)
 	return args.Get(0).([]entity.Product), args.Error(1)
 }
 
+func (m *MockProductRepository) ListPublicCatalog() ([]entity.PublicProduct, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.PublicProduct), args.Error(1)
+}
+
 func (m *MockProductRepository) Get(id string) (entity.Product, error) {
 	args := m.Called(id)
 	return args.Get(0).(entity.Product), args.Error(1)
 }
 
+func (m *MockProductRepository) GetByCode(code string) (entity.Product, error) {
+	args := m.Called(code)
+	return args.Get(0).(entity.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetByIds(ids []string) ([]entity.Product, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]entity.Product), args.Error(1)
+}
+
 func (m *MockProductRepository) Update(product entity.Product) (entity.Product, error) {
 	args := m.Called(product)
 	return args.Get(0).(entity.Product), args.Error(1)
@@ -34,3 +49,8 @@ func (m *MockProductRepository) Delete(id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+
+func (m *MockProductRepository) SyncPrices(items []entity.SupplierPriceItem, supplierId string) (entity.ProductSyncReport, error) {
+	args := m.Called(items, supplierId)
+	return args.Get(0).(entity.ProductSyncReport), args.Error(1)
+}
@@ -0,0 +1,41 @@
+package repositorymock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockProviderRepository struct {
+	mock.Mock
+}
+
+func (m *MockProviderRepository) Create(provider entity.Provider) (entity.Provider, error) {
+	args := m.Called(provider)
+	return args.Get(0).(entity.Provider), args.Error(1)
+}
+
+func (m *MockProviderRepository) List() ([]entity.Provider, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.Provider), args.Error(1)
+}
+
+func (m *MockProviderRepository) Get(id string) (entity.Provider, error) {
+	args := m.Called(id)
+	return args.Get(0).(entity.Provider), args.Error(1)
+}
+
+func (m *MockProviderRepository) Update(provider entity.Provider) (entity.Provider, error) {
+	args := m.Called(provider)
+	return args.Get(0).(entity.Provider), args.Error(1)
+}
+
+func (m *MockProviderRepository) Delete(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockProviderRepository) FindOrCreateByName(name string) (entity.Provider, error) {
+	args := m.Called(name)
+	return args.Get(0).(entity.Provider), args.Error(1)
+}
@@ -0,0 +1,31 @@
+package repositorymock
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) Create(userId, sessionId, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(userId, sessionId, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) Rotate(tokenHash, newTokenHash string, expiresAt time.Time) (string, string, error) {
+	args := m.Called(tokenHash, newTokenHash, expiresAt)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(tokenHash string) error {
+	args := m.Called(tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) IsRememberMe(tokenHash string) (bool, error) {
+	args := m.Called(tokenHash)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,26 @@
+package repositorymock
+
+import (
+	"server-pulsa-app/internal/shared/custom"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReportRepository struct {
+	mock.Mock
+}
+
+func (m *MockReportRepository) List(userId, startDate, endDate string) ([]custom.ReportResp, error) {
+	args := m.Called(userId, startDate, endDate)
+	return args.Get(0).([]custom.ReportResp), args.Error(1)
+}
+
+func (m *MockReportRepository) Overview() (custom.StatsOverview, error) {
+	args := m.Called()
+	return args.Get(0).(custom.StatsOverview), args.Error(1)
+}
+
+func (m *MockReportRepository) ProductMargin(filter custom.MarginFilter) ([]custom.ProductMarginResp, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]custom.ProductMarginResp), args.Error(1)
+}
@@ -0,0 +1,26 @@
+package repositorymock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRolePermissionRepository struct {
+	mock.Mock
+}
+
+func (m *MockRolePermissionRepository) ListByRole(role string) ([]string, error) {
+	args := m.Called(role)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRolePermissionRepository) List() ([]entity.RolePermissions, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.RolePermissions), args.Error(1)
+}
+
+func (m *MockRolePermissionRepository) Replace(role string, permissions []string) error {
+	args := m.Called(role, permissions)
+	return args.Error(0)
+}
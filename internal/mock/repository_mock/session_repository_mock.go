@@ -0,0 +1,36 @@
+package repositorymock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Create(userId string, client entity.ClientInfo, rememberMe bool) (string, error) {
+	args := m.Called(userId, client, rememberMe)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) ListActive(userId string) ([]entity.Session, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]entity.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Revoke(userId, id string) error {
+	args := m.Called(userId, id)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeAll(userId string) (int64, error) {
+	args := m.Called(userId)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionRepository) IsRevoked(id string) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
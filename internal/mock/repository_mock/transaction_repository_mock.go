@@ -1,27 +1,39 @@
 package repositorymock
 
 import (
+	"context"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/shared/custom"
 
 	"github.com/stretchr/testify/mock"
 )
 
+// MockTransactionRepository leaves the context out of the expectations, the calls are matched on their other arguments.
 type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) Create(payload entity.Transactions) (entity.Transactions, error) {
-	args := m.Called(payload)
+func (m *MockTransactionRepository) Create(ctx context.Context, payload entity.Transactions, actorId string) (entity.Transactions, error) {
+	args := m.Called(payload, actorId)
 	return args.Get(0).(entity.Transactions), args.Error(1)
 }
 
-func (m *MockTransactionRepository) GetAll(userId string) ([]custom.TransactionsReq, error) {
-	args := m.Called()
-	return args.Get(0).([]custom.TransactionsReq), args.Error(1)
+func (m *MockTransactionRepository) GetAll(ctx context.Context, filter custom.TransactionFilter) (custom.TransactionPage, error) {
+	args := m.Called(filter)
+	return args.Get(0).(custom.TransactionPage), args.Error(1)
 }
 
-func (m *MockTransactionRepository) GetById(id string) (custom.TransactionsReq, error) {
+func (m *MockTransactionRepository) GetById(ctx context.Context, id string) (custom.TransactionsReq, error) {
 	args := m.Called(id)
 	return args.Get(0).(custom.TransactionsReq), args.Error(1)
 }
+
+func (m *MockTransactionRepository) RefundDetail(ctx context.Context, detailId string) error {
+	args := m.Called(detailId)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) Quote(ctx context.Context, payload entity.TransactionQuoteReq) (custom.TransactionQuote, error) {
+	args := m.Called(payload)
+	return args.Get(0).(custom.TransactionQuote), args.Error(1)
+}
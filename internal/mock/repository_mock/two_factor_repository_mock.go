@@ -0,0 +1,57 @@
+package repositorymock
+
+import (
+	"server-pulsa-app/internal/entity"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepository) SaveSecret(userId, secret string) error {
+	args := m.Called(userId, secret)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) Get(userId string) (entity.TwoFactor, error) {
+	args := m.Called(userId)
+	return args.Get(0).(entity.TwoFactor), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) IsEnabled(userId string) (bool, error) {
+	args := m.Called(userId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) Enable(userId string, step int64, backupCodeHashes []string) error {
+	args := m.Called(userId, step, backupCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) UseStep(userId string, step int64) (bool, error) {
+	args := m.Called(userId, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) UseBackupCode(userId, codeHash string) (bool, error) {
+	args := m.Called(userId, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) CreateChallenge(userId, tokenHash string, expiresAt time.Time, rememberMe bool) error {
+	args := m.Called(userId, tokenHash, expiresAt, rememberMe)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) UseChallenge(tokenHash string, maxAttempts int) (string, bool, error) {
+	args := m.Called(tokenHash, maxAttempts)
+	return args.String(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockTwoFactorRepository) DeleteChallenge(tokenHash string) error {
+	args := m.Called(tokenHash)
+	return args.Error(0)
+}
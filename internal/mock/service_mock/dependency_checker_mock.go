@@ -0,0 +1,25 @@
+package service_mock
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type DependencyCheckerMock struct {
+	mock.Mock
+	name string
+}
+
+func (d *DependencyCheckerMock) Name() string {
+	return d.name
+}
+
+func (d *DependencyCheckerMock) Check(ctx context.Context) error {
+	args := d.Called(ctx)
+	return args.Error(0)
+}
+
+func NewDependencyCheckerMock(name string) *DependencyCheckerMock {
+	return &DependencyCheckerMock{name: name}
+}
@@ -1,6 +1,8 @@
 package service_mock
 
 import (
+	"time"
+
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/entity/dto"
 	"server-pulsa-app/internal/shared/model"
@@ -12,8 +14,8 @@ type JwtServiceMock struct {
 	mock.Mock
 }
 
-func (j *JwtServiceMock) CreateToken(user entity.User) (dto.AuthResponseDto, error) {
-	args := j.Called(user)
+func (j *JwtServiceMock) CreateToken(user entity.User, merchantIds []string, sessionId string, permissions []string, rememberMe bool) (dto.AuthResponseDto, error) {
+	args := j.Called(user, merchantIds, sessionId, permissions, rememberMe)
 	return args.Get(0).(dto.AuthResponseDto), args.Error(1)
 }
 
@@ -21,3 +23,8 @@ func (j *JwtServiceMock) ValidateToken(tokenString string) (*model.Claim, error)
 	args := j.Called(tokenString)
 	return args.Get(0).(*model.Claim), args.Error(1)
 }
+
+func (j *JwtServiceMock) CreateRefreshToken(rememberMe bool) (string, time.Time, error) {
+	args := j.Called(rememberMe)
+	return args.String(0), args.Get(1).(time.Time), args.Error(2)
+}
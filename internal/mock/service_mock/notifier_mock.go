@@ -0,0 +1,12 @@
+package service_mock
+
+import "github.com/stretchr/testify/mock"
+
+type NotifierMock struct {
+	mock.Mock
+}
+
+func (n *NotifierMock) Notify(recipient, subject string, data any) error {
+	args := n.Called(recipient, subject, data)
+	return args.Error(0)
+}
@@ -0,0 +1,17 @@
+package service_mock
+
+import (
+	"context"
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SupplierCatalogClientMock struct {
+	mock.Mock
+}
+
+func (s *SupplierCatalogClientMock) FetchPriceList(ctx context.Context) ([]entity.SupplierPriceItem, error) {
+	args := s.Called(ctx)
+	return args.Get(0).([]entity.SupplierPriceItem), args.Error(1)
+}
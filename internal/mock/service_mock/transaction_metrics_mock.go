@@ -0,0 +1,19 @@
+package service_mock
+
+import "github.com/stretchr/testify/mock"
+
+type TransactionMetricsMock struct {
+	mock.Mock
+}
+
+func (t *TransactionMetricsMock) TransactionCreated() {
+	t.Called()
+}
+
+func (t *TransactionMetricsMock) TransactionFailed() {
+	t.Called()
+}
+
+func (t *TransactionMetricsMock) InsufficientBalance() {
+	t.Called()
+}
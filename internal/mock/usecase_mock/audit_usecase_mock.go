@@ -0,0 +1,21 @@
+package usecase_mock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type AuditUseCaseMock struct {
+	mock.Mock
+}
+
+func (a *AuditUseCaseMock) FindAuditLog(query entity.AuditQuery) ([]entity.AuditLog, error) {
+	args := a.Called(query)
+	return args.Get(0).([]entity.AuditLog), args.Error(1)
+}
+
+func (a *AuditUseCaseMock) FindAuthEvents(query entity.AuthEventQuery) ([]entity.AuthEvent, error) {
+	args := a.Called(query)
+	return args.Get(0).([]entity.AuthEvent), args.Error(1)
+}
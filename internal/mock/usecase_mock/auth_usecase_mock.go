@@ -1,6 +1,8 @@
 package usecase_mock
 
 import (
+	"time"
+
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/entity/dto"
 
@@ -11,8 +13,13 @@ type AuthUseCaseMock struct {
 	mock.Mock
 }
 
-func (a *AuthUseCaseMock) Login(payload dto.AuthRequestDto) (dto.AuthResponseDto, error) {
-	args := a.Called(payload)
+func (a *AuthUseCaseMock) Login(payload dto.LoginRequestDto, client entity.ClientInfo) (dto.AuthResponseDto, error) {
+	args := a.Called(payload, client)
+	return args.Get(0).(dto.AuthResponseDto), args.Error(1)
+}
+
+func (a *AuthUseCaseMock) VerifyTwoFactor(payload dto.TwoFactorVerifyRequestDto, client entity.ClientInfo) (dto.AuthResponseDto, error) {
+	args := a.Called(payload, client)
 	return args.Get(0).(dto.AuthResponseDto), args.Error(1)
 }
 
@@ -20,3 +27,13 @@ func (a *AuthUseCaseMock) Register(payload dto.AuthRequestDto) (entity.User, err
 	args := a.Called(payload)
 	return args.Get(0).(entity.User), args.Error(1)
 }
+
+func (a *AuthUseCaseMock) Refresh(refreshToken string) (dto.AuthResponseDto, error) {
+	args := a.Called(refreshToken)
+	return args.Get(0).(dto.AuthResponseDto), args.Error(1)
+}
+
+func (a *AuthUseCaseMock) Logout(userId, jti string, expiresAt time.Time, refreshToken string, client entity.ClientInfo) error {
+	args := a.Called(userId, jti, expiresAt, refreshToken, client)
+	return args.Error(0)
+}
@@ -25,8 +25,8 @@ func (m *MerchantUsecaseMock) FindMerchantByID(id string) (entity.Merchant, erro
 	return args.Get(0).(entity.Merchant), args.Error(1)
 }
 
-func (m *MerchantUsecaseMock) UpdateMerchant(payload entity.Merchant) (entity.Merchant, error) {
-	args := m.Called(payload)
+func (m *MerchantUsecaseMock) UpdateMerchant(id string, payload entity.MerchantUpdateRequest) (entity.Merchant, error) {
+	args := m.Called(id, payload)
 	return args.Get(0).(entity.Merchant), args.Error(1)
 }
 
@@ -34,3 +34,28 @@ func (m *MerchantUsecaseMock) DeleteMerchant(id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+
+func (m *MerchantUsecaseMock) FindMerchantBalance(id string, merchantIds []string, role string) (entity.MerchantBalance, error) {
+	args := m.Called(id, merchantIds, role)
+	return args.Get(0).(entity.MerchantBalance), args.Error(1)
+}
+
+func (m *MerchantUsecaseMock) AdjustMerchantBalances(adjustments map[string]float64, actorId string) error {
+	args := m.Called(adjustments, actorId)
+	return args.Error(0)
+}
+
+func (m *MerchantUsecaseMock) SetMerchantProductPrice(price entity.MerchantProductPrice) error {
+	args := m.Called(price)
+	return args.Error(0)
+}
+
+func (m *MerchantUsecaseMock) ClearMerchantProductPrice(merchantId, productId string) error {
+	args := m.Called(merchantId, productId)
+	return args.Error(0)
+}
+
+func (m *MerchantUsecaseMock) FindMerchantCatalog(id string, merchantIds []string, role string) ([]entity.MerchantCatalogItem, error) {
+	args := m.Called(id, merchantIds, role)
+	return args.Get(0).([]entity.MerchantCatalogItem), args.Error(1)
+}
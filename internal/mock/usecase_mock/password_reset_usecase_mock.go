@@ -0,0 +1,21 @@
+package usecase_mock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PasswordResetUseCaseMock struct {
+	mock.Mock
+}
+
+func (p *PasswordResetUseCaseMock) RequestPasswordReset(username string) error {
+	args := p.Called(username)
+	return args.Error(0)
+}
+
+func (p *PasswordResetUseCaseMock) ResetPassword(token, newPassword string, client entity.ClientInfo) error {
+	args := p.Called(token, newPassword, client)
+	return args.Error(0)
+}
@@ -0,0 +1,21 @@
+package usecase_mock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PermissionUseCaseMock struct {
+	mock.Mock
+}
+
+func (p *PermissionUseCaseMock) ListRolePermissions() ([]entity.RolePermissions, error) {
+	args := p.Called()
+	return args.Get(0).([]entity.RolePermissions), args.Error(1)
+}
+
+func (p *PermissionUseCaseMock) UpdateRolePermissions(role string, permissions []string, actorId string) (entity.RolePermissions, error) {
+	args := p.Called(role, permissions, actorId)
+	return args.Get(0).(entity.RolePermissions), args.Error(1)
+}
@@ -16,9 +16,14 @@ func (m *ProductUseCaseMock) CreateNewProduct(product entity.Product) (entity.Pr
 	return args.Get(0).(entity.Product), args.Error(1)
 }
 
-// List adalah mock dari metode List
-func (m *ProductUseCaseMock) FindAllProduct() ([]entity.Product, error) {
+func (m *ProductUseCaseMock) FindPublicCatalog() ([]entity.PublicProduct, error) {
 	args := m.Called()
+	return args.Get(0).([]entity.PublicProduct), args.Error(1)
+}
+
+// List adalah mock dari metode List
+func (m *ProductUseCaseMock) FindAllProduct(query entity.ProductQuery) ([]entity.Product, error) {
+	args := m.Called(query)
 	return args.Get(0).([]entity.Product), args.Error(1)
 }
 
@@ -28,6 +33,16 @@ func (m *ProductUseCaseMock) FindProductById(id string) (entity.Product, error)
 	return args.Get(0).(entity.Product), args.Error(1)
 }
 
+func (m *ProductUseCaseMock) FindProductByCode(code string) (entity.Product, error) {
+	args := m.Called(code)
+	return args.Get(0).(entity.Product), args.Error(1)
+}
+
+func (m *ProductUseCaseMock) FindProductsByIds(ids, merchantIds []string, role string) (entity.ProductBatch, error) {
+	args := m.Called(ids, merchantIds, role)
+	return args.Get(0).(entity.ProductBatch), args.Error(1)
+}
+
 // Update adalah mock dari metode Update
 func (m *ProductUseCaseMock) UpdateProduct(product entity.Product) (entity.Product, error) {
 	args := m.Called(product)
@@ -35,7 +50,7 @@ func (m *ProductUseCaseMock) UpdateProduct(product entity.Product) (entity.Produ
 }
 
 // Delete adalah mock dari metode Delete
-func (m *ProductUseCaseMock) DeleteProduct(id string) error {
-	args := m.Called(id)
+func (m *ProductUseCaseMock) DeleteProduct(id, actorId string) error {
+	args := m.Called(id, actorId)
 	return args.Error(0)
 }
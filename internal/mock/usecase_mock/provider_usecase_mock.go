@@ -0,0 +1,41 @@
+package usecase_mock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type ProviderUseCaseMock struct {
+	mock.Mock
+}
+
+func (m *ProviderUseCaseMock) CreateNewProvider(provider entity.Provider) (entity.Provider, error) {
+	args := m.Called(provider)
+	return args.Get(0).(entity.Provider), args.Error(1)
+}
+
+func (m *ProviderUseCaseMock) FindAllProvider() ([]entity.Provider, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.Provider), args.Error(1)
+}
+
+func (m *ProviderUseCaseMock) FindProviderById(id string) (entity.Provider, error) {
+	args := m.Called(id)
+	return args.Get(0).(entity.Provider), args.Error(1)
+}
+
+func (m *ProviderUseCaseMock) UpdateProvider(provider entity.Provider) (entity.Provider, error) {
+	args := m.Called(provider)
+	return args.Get(0).(entity.Provider), args.Error(1)
+}
+
+func (m *ProviderUseCaseMock) DeleteProvider(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *ProviderUseCaseMock) ResolveProviderByName(name string) (entity.Provider, error) {
+	args := m.Called(name)
+	return args.Get(0).(entity.Provider), args.Error(1)
+}
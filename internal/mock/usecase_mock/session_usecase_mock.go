@@ -0,0 +1,26 @@
+package usecase_mock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionUseCaseMock struct {
+	mock.Mock
+}
+
+func (s *SessionUseCaseMock) ListSessions(userId, currentSessionId string) ([]entity.Session, error) {
+	args := s.Called(userId, currentSessionId)
+	return args.Get(0).([]entity.Session), args.Error(1)
+}
+
+func (s *SessionUseCaseMock) RevokeSession(userId, sessionId string) error {
+	args := s.Called(userId, sessionId)
+	return args.Error(0)
+}
+
+func (s *SessionUseCaseMock) RevokeUserSessions(userId, actorId string) (int64, error) {
+	args := s.Called(userId, actorId)
+	return args.Get(0).(int64), args.Error(1)
+}
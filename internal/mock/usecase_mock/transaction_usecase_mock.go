@@ -1,27 +1,44 @@
 package usecase_mock
 
 import (
+	"context"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/shared/custom"
 
 	"github.com/stretchr/testify/mock"
 )
 
+// MockTransactionUseCase leaves the context out of the expectations, the calls are matched on their other arguments.
 type MockTransactionUseCase struct {
 	mock.Mock
 }
 
-func (m *MockTransactionUseCase) Create(payload entity.Transactions) (entity.Transactions, error) {
-	args := m.Called(payload)
+func (m *MockTransactionUseCase) Create(ctx context.Context, payload entity.Transactions, actorId string) (entity.Transactions, error) {
+	args := m.Called(payload, actorId)
 	return args.Get(0).(entity.Transactions), args.Error(1)
 }
 
-func (m *MockTransactionUseCase) GetAll(userId string) ([]custom.TransactionsReq, error) {
-	args := m.Called()
-	return args.Get(0).([]custom.TransactionsReq), args.Error(1)
+func (m *MockTransactionUseCase) GetAll(ctx context.Context, filter custom.TransactionFilter) (custom.TransactionPage, error) {
+	args := m.Called(filter)
+	return args.Get(0).(custom.TransactionPage), args.Error(1)
 }
 
-func (m *MockTransactionUseCase) GetById(id string) (custom.TransactionsReq, error) {
+func (m *MockTransactionUseCase) GetById(ctx context.Context, id string) (custom.TransactionsReq, error) {
 	args := m.Called(id)
 	return args.Get(0).(custom.TransactionsReq), args.Error(1)
 }
+
+func (m *MockTransactionUseCase) GetReceipt(ctx context.Context, id string) (custom.TransactionReceipt, error) {
+	args := m.Called(id)
+	return args.Get(0).(custom.TransactionReceipt), args.Error(1)
+}
+
+func (m *MockTransactionUseCase) RefundDetail(ctx context.Context, transactionId, detailId string) error {
+	args := m.Called(transactionId, detailId)
+	return args.Error(0)
+}
+
+func (m *MockTransactionUseCase) Quote(ctx context.Context, payload entity.TransactionQuoteReq) (custom.TransactionQuote, error) {
+	args := m.Called(payload)
+	return args.Get(0).(custom.TransactionQuote), args.Error(1)
+}
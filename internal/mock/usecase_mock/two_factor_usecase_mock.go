@@ -0,0 +1,21 @@
+package usecase_mock
+
+import (
+	"server-pulsa-app/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type TwoFactorUseCaseMock struct {
+	mock.Mock
+}
+
+func (t *TwoFactorUseCaseMock) Setup(userId string) (entity.TwoFactorSetup, error) {
+	args := t.Called(userId)
+	return args.Get(0).(entity.TwoFactorSetup), args.Error(1)
+}
+
+func (t *TwoFactorUseCaseMock) Enable(userId, code string) ([]string, error) {
+	args := t.Called(userId, code)
+	return args.Get(0).([]string), args.Error(1)
+}
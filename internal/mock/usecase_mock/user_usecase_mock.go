@@ -2,6 +2,8 @@ package usecase_mock
 
 import (
 	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/shared/custom"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -20,6 +22,11 @@ func (u *UserUseCaseMock) GetUserByUsername(username string) (entity.User, error
 	return args.Get(0).(entity.User), args.Error(1)
 }
 
+func (u *UserUseCaseMock) GetUserByEmail(email string) (entity.User, error) {
+	args := u.Called(email)
+	return args.Get(0).(entity.User), args.Error(1)
+}
+
 func (u *UserUseCaseMock) GetUserByID(id string) (entity.User, error) {
 	args := u.Called(id)
 	return args.Get(0).(entity.User), args.Error(1)
@@ -30,17 +37,66 @@ func (u *UserUseCaseMock) FindUserByUsernamePassword(username, password string)
 	return args.Get(0).(entity.User), args.Error(1)
 }
 
-func (u *UserUseCaseMock) ListUser() ([]entity.User, error) {
-	args := u.Called()
-	return args.Get(0).([]entity.User), args.Error(1)
+func (u *UserUseCaseMock) FindUserByEmailPassword(email, password string) (entity.User, error) {
+	args := u.Called(email, password)
+	return args.Get(0).(entity.User), args.Error(1)
 }
 
-func (u *UserUseCaseMock) UpdateUser(payload entity.User) (entity.User, error) {
-	args := u.Called(payload)
+func (u *UserUseCaseMock) CreateUser(user entity.User, actorRole string) (entity.User, error) {
+	args := u.Called(user, actorRole)
 	return args.Get(0).(entity.User), args.Error(1)
 }
 
-func (u *UserUseCaseMock) DeleteUser(id string) error {
+func (u *UserUseCaseMock) OnboardMerchant(owner entity.User, merchant entity.Merchant) (entity.MerchantOnboarding, error) {
+	args := u.Called(owner, merchant)
+	return args.Get(0).(entity.MerchantOnboarding), args.Error(1)
+}
+
+func (u *UserUseCaseMock) ListUser(filter custom.UserFilter) (custom.UserPage, error) {
+	args := u.Called(filter)
+	return args.Get(0).(custom.UserPage), args.Error(1)
+}
+
+func (u *UserUseCaseMock) UpdateUser(payload entity.User, actorId, actorRole string) (entity.User, error) {
+	args := u.Called(payload, actorId, actorRole)
+	return args.Get(0).(entity.User), args.Error(1)
+}
+
+func (u *UserUseCaseMock) DeleteUser(id, actorId string) error {
+	args := u.Called(id, actorId)
+	return args.Error(0)
+}
+
+func (u *UserUseCaseMock) RecordLogin(id string, at time.Time) {
+	u.Called(id, at)
+}
+
+func (u *UserUseCaseMock) ReportDuplicateUsernames() error {
+	args := u.Called()
+	return args.Error(0)
+}
+
+func (u *UserUseCaseMock) ActivateUser(id string) error {
+	args := u.Called(id)
+	return args.Error(0)
+}
+
+func (u *UserUseCaseMock) GetProfile(id string) (entity.UserProfile, error) {
 	args := u.Called(id)
+	return args.Get(0).(entity.UserProfile), args.Error(1)
+}
+
+func (u *UserUseCaseMock) ChangePassword(id, currentPassword, newPassword string, client entity.ClientInfo) error {
+	args := u.Called(id, currentPassword, newPassword, client)
 	return args.Error(0)
 }
+
+func (u *UserUseCaseMock) AdminResetPassword(id, newPassword, actorId string) (string, error) {
+	args := u.Called(id, newPassword, actorId)
+	return args.String(0), args.Error(1)
+}
+
+func (u *UserUseCaseMock) FindMerchantIds(id string) ([]string, error) {
+	args := u.Called(id)
+	return args.Get(0).([]string), args.Error(1)
+}
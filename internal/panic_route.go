@@ -0,0 +1,17 @@
+//go:build panicroute
+
+package internal
+
+import (
+	"server-pulsa-app/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerPanicRoute adds a route that always panics, so integration tests can check that a panic is answered with
+// the JSON 500 and counted. It only exists in builds with -tags panicroute, never in a release binary.
+func (s *Server) registerPanicRoute() {
+	s.engine.GET(config.GetDebugPanic, func(ctx *gin.Context) {
+		panic("panic route hit")
+	})
+}
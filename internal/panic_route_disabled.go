@@ -0,0 +1,6 @@
+//go:build !panicroute
+
+package internal
+
+// registerPanicRoute registers nothing, the panic route is only built with -tags panicroute.
+func (s *Server) registerPanicRoute() {}
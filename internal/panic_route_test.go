@@ -0,0 +1,55 @@
+//go:build panicroute
+
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/shared/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// run with: go test -tags panicroute ./internal -run TestServerTestSuite/TestPanicRoute
+func (s *serverTestSuite) TestPanicRoute_AnsweredWithJSON500() {
+	gin.SetMode(gin.TestMode)
+	metrics := service.NewMetrics()
+	engine := gin.New()
+	engine.Use(middleware.NewRequestIDMiddleware(), middleware.NewRecoveryMiddleware(&log, metrics))
+	server := &Server{engine: engine, basePath: "/api/v1", basePathV2: "/api/v2", metrics: metrics}
+	server.initRoute()
+
+	w := httptest.NewRecorder()
+	server.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, config.GetDebugPanic, nil))
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.Contains(w.Body.String(), `"code":"`+middleware.InternalErrorCode+`"`)
+
+	scrape := httptest.NewRecorder()
+	server.engine.ServeHTTP(scrape, httptest.NewRequest(http.MethodGet, config.GetMetrics, nil))
+	s.Contains(scrape.Body.String(), "http_panics_total 1")
+}
+
+// the gzip middleware sits between the recovery middleware and the handler in the server
+func (s *serverTestSuite) TestPanicRoute_GzipAcceptedAnsweredWithJSON500() {
+	gin.SetMode(gin.TestMode)
+	metrics := service.NewMetrics()
+	engine := gin.New()
+	engine.Use(middleware.NewRequestIDMiddleware(), middleware.NewRecoveryMiddleware(&log, metrics), middleware.NewGzipMiddleware(0))
+	server := &Server{engine: engine, basePath: "/api/v1", basePathV2: "/api/v2", metrics: metrics}
+	server.initRoute()
+
+	req := httptest.NewRequest(http.MethodGet, config.GetDebugPanic, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set(middleware.RequestIDHeader, "req-panic")
+	w := httptest.NewRecorder()
+	server.engine.ServeHTTP(w, req)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	var response map[string]string
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Equal(map[string]string{"code": middleware.InternalErrorCode, "message": "internal server error", "request_id": "req-panic"}, response)
+}
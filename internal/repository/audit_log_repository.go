@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"database/sql"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+)
+
+// auditExecutor is satisfied by *sql.DB and *sql.Tx, so an audit row can be written in the transaction of the change.
+type auditExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertAuditLog(exec auditExecutor, entry entity.AuditLog) error {
+	_, err := exec.Exec("INSERT INTO audit_log (actor_id, action, target_id, detail) VALUES ($1, $2, $3, $4)", entry.ActorId, entry.Action, entry.TargetId, entry.Detail)
+	return err
+}
+
+type AuditLogRepository interface {
+	Record(entry entity.AuditLog) error
+	List(query entity.AuditQuery) ([]entity.AuditLog, error)
+}
+
+type auditLogRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// Record writes an audit row on its own, for changes that do not run in a transaction of their own.
+func (a *auditLogRepository) Record(entry entity.AuditLog) error {
+	a.log.Info("Starting to record an audit log in the repository layer", nil)
+
+	if err := insertAuditLog(a.db, entry); err != nil {
+		a.log.Error("Failed to record the audit log: ", err)
+		return err
+	}
+
+	a.log.Info("Audit log has been recorded successfully", entry.Action)
+	return nil
+}
+
+func (a *auditLogRepository) List(query entity.AuditQuery) ([]entity.AuditLog, error) {
+	a.log.Info("Starting to retrive the audit log in the repository layer", nil)
+
+	rows, err := a.db.Query("SELECT id, actor_id, action, target_id, detail, created_at FROM audit_log ORDER BY created_at DESC, id LIMIT $1 OFFSET $2",
+		query.Limit, (query.Page-1)*query.Limit)
+	if err != nil {
+		a.log.Error("Failed to retrive the audit log: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []entity.AuditLog{}
+	for rows.Next() {
+		var entry entity.AuditLog
+		if err := rows.Scan(&entry.Id, &entry.ActorId, &entry.Action, &entry.TargetId, &entry.Detail, &entry.CreatedAt); err != nil {
+			a.log.Error("Failed to scan the audit log: ", err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		a.log.Error("Failed to retrive the audit log: ", err)
+		return nil, err
+	}
+
+	a.log.Info("Getting the audit log was successfully: ", len(entries))
+	return entries, nil
+}
+
+func NewAuditLogRepository(db *sql.DB, log *logger.Logger) AuditLogRepository {
+	return &auditLogRepository{db: db, log: log}
+}
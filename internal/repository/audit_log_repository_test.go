@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type auditLogRepositoryTestSuite struct {
+	suite.Suite
+	mockDb  *sql.DB
+	mockSql sqlmock.Sqlmock
+	repo    AuditLogRepository
+	log     logger.Logger
+}
+
+func TestAuditLogRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(auditLogRepositoryTestSuite))
+}
+
+func (a *auditLogRepositoryTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	a.NoError(err)
+
+	a.mockDb = mockDb
+	a.mockSql = mockSql
+	a.log = logger.NewLogger()
+	a.repo = NewAuditLogRepository(mockDb, &a.log)
+}
+
+func (a *auditLogRepositoryTestSuite) TestRecord() {
+	a.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log (actor_id, action, target_id, detail) VALUES ($1, $2, $3, $4)")).
+		WithArgs("uuid-admin", entity.AuditActionUserRole, "uuid-user", "").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := a.repo.Record(entity.AuditLog{ActorId: "uuid-admin", Action: entity.AuditActionUserRole, TargetId: "uuid-user"})
+
+	a.Nil(err)
+	a.Nil(a.mockSql.ExpectationsWereMet())
+}
+
+func (a *auditLogRepositoryTestSuite) TestList() {
+	createdAt := time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)
+	a.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id, actor_id, action, target_id, detail, created_at FROM audit_log ORDER BY created_at DESC, id LIMIT $1 OFFSET $2")).
+		WithArgs(20, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "action", "target_id", "detail", "created_at"}).
+			AddRow("uuid-audit", "uuid-admin", entity.AuditActionBalanceAdjust, "uuid-merchant", "", createdAt))
+
+	entries, err := a.repo.List(entity.AuditQuery{Page: 2, Limit: 20})
+
+	a.Nil(err)
+	a.Equal([]entity.AuditLog{{Id: "uuid-audit", ActorId: "uuid-admin", Action: entity.AuditActionBalanceAdjust, TargetId: "uuid-merchant", CreatedAt: createdAt}}, entries)
+	a.Nil(a.mockSql.ExpectationsWereMet())
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"database/sql"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+)
+
+type AuthEventRepository interface {
+	Record(event entity.AuthEvent) error
+	List(query entity.AuthEventQuery) ([]entity.AuthEvent, error)
+}
+
+type authEventRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func (a *authEventRepository) Record(event entity.AuthEvent) error {
+	a.log.Info("Starting to record an auth event in the repository layer", nil)
+
+	_, err := a.db.Exec("INSERT INTO auth_events (user_id, event, identifier, ip, user_agent) VALUES ($1, $2, $3, $4, $5)",
+		sql.NullString{String: event.UserId, Valid: event.UserId != ""}, event.Event, event.Identifier, event.Ip, event.UserAgent)
+	if err != nil {
+		a.log.Error("Failed to record the auth event: ", err)
+		return err
+	}
+
+	a.log.Info("Auth event has been recorded successfully", event.Event)
+	return nil
+}
+
+func (a *authEventRepository) List(query entity.AuthEventQuery) ([]entity.AuthEvent, error) {
+	a.log.Info("Starting to retrive the auth events in the repository layer", nil)
+
+	userId := sql.NullString{String: query.UserId, Valid: query.UserId != ""}
+	startDate := sql.NullString{String: query.StartDate, Valid: query.StartDate != ""}
+	endDate := sql.NullString{String: query.EndDate, Valid: query.EndDate != ""}
+
+	rows, err := a.db.Query(`SELECT id, COALESCE(user_id::text, ''), event, identifier, ip, user_agent, created_at
+		FROM auth_events
+		WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+		AND ($2::date IS NULL OR created_at >= $2::date)
+		AND ($3::date IS NULL OR created_at < $3::date + 1)
+		ORDER BY created_at DESC, id
+		LIMIT $4 OFFSET $5`,
+		userId, startDate, endDate, query.Limit, (query.Page-1)*query.Limit)
+	if err != nil {
+		a.log.Error("Failed to retrive the auth events: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []entity.AuthEvent{}
+	for rows.Next() {
+		var event entity.AuthEvent
+		if err := rows.Scan(&event.Id, &event.UserId, &event.Event, &event.Identifier, &event.Ip, &event.UserAgent, &event.CreatedAt); err != nil {
+			a.log.Error("Failed to scan the auth events: ", err)
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		a.log.Error("Failed to retrive the auth events: ", err)
+		return nil, err
+	}
+
+	a.log.Info("Getting the auth events was successfully: ", len(events))
+	return events, nil
+}
+
+func NewAuthEventRepository(db *sql.DB, log *logger.Logger) AuthEventRepository {
+	return &authEventRepository{db: db, log: log}
+}
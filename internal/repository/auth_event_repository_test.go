@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type authEventRepositoryTestSuite struct {
+	suite.Suite
+	mockDb  *sql.DB
+	mockSql sqlmock.Sqlmock
+	repo    AuthEventRepository
+	log     logger.Logger
+}
+
+func TestAuthEventRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(authEventRepositoryTestSuite))
+}
+
+func (a *authEventRepositoryTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	a.NoError(err)
+
+	a.mockDb = mockDb
+	a.mockSql = mockSql
+	a.log = logger.NewLogger()
+	a.repo = NewAuthEventRepository(mockDb, &a.log)
+}
+
+func (a *authEventRepositoryTestSuite) TestRecord_UnknownUserIsNull() {
+	a.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO auth_events (user_id, event, identifier, ip, user_agent) VALUES ($1, $2, $3, $4, $5)")).
+		WithArgs(nil, entity.AuthEventLoginFailure, "ghost", "10.0.0.1", "curl/8.0").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := a.repo.Record(entity.AuthEvent{Event: entity.AuthEventLoginFailure, Identifier: "ghost", Ip: "10.0.0.1", UserAgent: "curl/8.0"})
+
+	a.Nil(err)
+	a.Nil(a.mockSql.ExpectationsWereMet())
+}
+
+func (a *authEventRepositoryTestSuite) TestList_Filters() {
+	createdAt := time.Date(2024, 8, 1, 10, 0, 0, 0, time.UTC)
+	a.mockSql.ExpectQuery(regexp.QuoteMeta("FROM auth_events")).
+		WithArgs("uuid-user", "2024-08-01", nil, 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "event", "identifier", "ip", "user_agent", "created_at"}).
+			AddRow("uuid-event", "uuid-user", entity.AuthEventLogout, "", "10.0.0.1", "curl/8.0", createdAt))
+
+	events, err := a.repo.List(entity.AuthEventQuery{UserId: "uuid-user", StartDate: "2024-08-01", Page: 2, Limit: 10})
+
+	a.Nil(err)
+	a.Equal([]entity.AuthEvent{{Id: "uuid-event", UserId: "uuid-user", Event: entity.AuthEventLogout, Ip: "10.0.0.1", UserAgent: "curl/8.0", CreatedAt: createdAt}}, events)
+	a.Nil(a.mockSql.ExpectationsWereMet())
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"database/sql"
+	"server-pulsa-app/internal/entity"
+)
+
+// DbStatsRepository reports the state of the connection pool, it never queries the database.
+type DbStatsRepository interface {
+	Stats() entity.DbStats
+}
+
+type dbStatsRepository struct {
+	db *sql.DB
+}
+
+func (d *dbStatsRepository) Stats() entity.DbStats {
+	stats := d.db.Stats()
+	return entity.DbStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
+func NewDbStatsRepository(db *sql.DB) DbStatsRepository {
+	return &dbStatsRepository{db: db}
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDbStatsRepository_Stats(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(25)
+
+	stats := NewDbStatsRepository(db).Stats()
+
+	assert.Equal(t, 25, stats.MaxOpenConnections)
+	assert.Equal(t, stats.OpenConnections, stats.InUse+stats.Idle)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginAttemptRepository keeps failed login timestamps per key (a username or a client IP). The in-memory
+// implementation only works for a single instance, a shared store such as Redis can implement the same interface.
+type LoginAttemptRepository interface {
+	RecordFailure(key string, at time.Time) error
+	// Failures returns the failures of key at or after since, oldest first.
+	Failures(key string, since time.Time) ([]time.Time, error)
+	Reset(key string) error
+}
+
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	window   time.Duration
+	failures map[string][]time.Time
+}
+
+func (m *memoryLoginAttemptRepository) RecordFailure(key string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.failures) > 10000 {
+		m.evict(at)
+	}
+	m.failures[key] = append(m.failures[key], at)
+	return nil
+}
+
+// evict drops keys whose latest failure has left the window, so the map does not grow with every username tried.
+func (m *memoryLoginAttemptRepository) evict(now time.Time) {
+	for key, failures := range m.failures {
+		if now.Sub(failures[len(failures)-1]) >= m.window {
+			delete(m.failures, key)
+		}
+	}
+}
+
+func (m *memoryLoginAttemptRepository) Failures(key string, since time.Time) ([]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// failures are appended in order, so everything before the first recent one has left the window
+	failures := m.failures[key]
+	first := len(failures)
+	for i, at := range failures {
+		if !at.Before(since) {
+			first = i
+			break
+		}
+	}
+
+	recent := append([]time.Time(nil), failures[first:]...)
+	if len(recent) == 0 {
+		delete(m.failures, key)
+	} else {
+		m.failures[key] = recent
+	}
+	return append([]time.Time(nil), recent...), nil
+}
+
+func (m *memoryLoginAttemptRepository) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+	return nil
+}
+
+// NewMemoryLoginAttemptRepository keeps failures in process memory, window is how long a failure is worth keeping.
+func NewMemoryLoginAttemptRepository(window time.Duration) LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{window: window, failures: make(map[string][]time.Time)}
+}
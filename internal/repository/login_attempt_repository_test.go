@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLoginAttemptRepository_SlidingWindow(t *testing.T) {
+	repo := NewMemoryLoginAttemptRepository(10 * time.Minute)
+	now := time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, repo.RecordFailure("user:eko", now.Add(-12*time.Minute)))
+	assert.NoError(t, repo.RecordFailure("user:eko", now.Add(-5*time.Minute)))
+	assert.NoError(t, repo.RecordFailure("user:eko", now))
+	assert.NoError(t, repo.RecordFailure("ip:10.0.0.1", now))
+
+	failures, err := repo.Failures("user:eko", now.Add(-10*time.Minute))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{now.Add(-5 * time.Minute), now}, failures)
+}
+
+func TestMemoryLoginAttemptRepository_Reset(t *testing.T) {
+	repo := NewMemoryLoginAttemptRepository(10 * time.Minute)
+	now := time.Now()
+	assert.NoError(t, repo.RecordFailure("user:eko", now))
+
+	assert.NoError(t, repo.Reset("user:eko"))
+
+	failures, err := repo.Failures("user:eko", now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+}
@@ -2,18 +2,50 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/dbscan"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrPriceOverrideTarget is returned by SetProductPrice when the merchant or the product does not exist.
+	ErrPriceOverrideTarget = errors.New("merchant or product not found")
+	// ErrPriceOverrideNotFound is returned by ClearProductPrice when the merchant has no override for the product.
+	ErrPriceOverrideNotFound = errors.New("merchant has no custom price for this product")
 )
 
 type MerchantRepository interface {
 	Create(payload entity.Merchant) (entity.Merchant, error)
+	// Onboard creates owner and merchant in one db transaction, the merchant belongs to owner and starts with a zero
+	// balance. Neither is kept when the other fails.
+	Onboard(owner entity.User, merchant entity.Merchant) (entity.User, entity.Merchant, error)
 	List() ([]entity.Merchant, error)
+	ListByUser(userId string) ([]entity.Merchant, error)
 	Get(id string) (entity.Merchant, error)
-	Update(merchant, newMerchant entity.Merchant) (entity.Merchant, error)
+	// Update applies what payload sets to merchant and stores the result.
+	Update(merchant entity.Merchant, payload entity.MerchantUpdateRequest) (entity.Merchant, error)
 	Delete(id string) error
+	GetBalance(merchantId string) (float64, error)
+	AdjustBalances(adjustments map[string]float64, actorId string) error
+	SetProductPrice(price entity.MerchantProductPrice) error
+	ClearProductPrice(merchantId, productId string) error
+	ListCatalog(merchantId string) ([]entity.MerchantCatalogItem, error)
+}
+
+// ErrUnknownMerchant is returned by AdjustBalances when one of the merchant ids does not exist.
+type ErrUnknownMerchant struct {
+	MerchantId string
+}
+
+func (e *ErrUnknownMerchant) Error() string {
+	return fmt.Sprintf("merchant %s not found, no balance has been adjusted", e.MerchantId)
 }
 
 type merchantRepository struct {
@@ -21,47 +53,119 @@ type merchantRepository struct {
 	log *logger.Logger
 }
 
+// merchantColumns are read into entity.Merchant in this order. The owner, the default product, the address and the
+// balance may be NULL in rows created before they were required, they read as empty and 0.
+const merchantColumns = `id_merchant, COALESCE(id_user::text, '') AS id_user, name_merchant, COALESCE(address, '') AS address,
+	COALESCE(id_product::text, '') AS id_product, COALESCE(balance, 0) AS balance, low_balance_threshold, daily_limit_count,
+	daily_limit_amount`
+
+const insertMerchant = "INSERT INTO mst_merchant (id_user, name_merchant, address, id_product, balance, low_balance_threshold, daily_limit_count, daily_limit_amount) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id_merchant"
+
 func (m *merchantRepository) Create(payload entity.Merchant) (entity.Merchant, error) {
 	m.log.Info("Starting to create a new merchant in the repository layer", nil)
 
-	err := m.db.QueryRow("INSERT INTO mst_merchant (id_user, name_merchant, address, id_product, balance) VALUES ($1, $2, $3, $4, $5) RETURNING id_merchant", payload.IdUser, payload.NameMerchant, payload.Address, payload.IdProduct, 0.0).Scan(&payload.IdMerchant)
+	err := m.db.QueryRow(insertMerchant, payload.IdUser, payload.NameMerchant, payload.Address, payload.IdProduct, 0.0, payload.LowBalanceThreshold, payload.DailyLimitCount, payload.DailyLimitAmount).Scan(&payload.IdMerchant)
 	if err != nil {
 		m.log.Error("Failed to create the merchant: ", err)
-		return entity.Merchant{}, err
+		return entity.Merchant{}, fmt.Errorf("create the merchant: %w", err)
 	}
 
 	m.log.Info("Merchant has been created successfully: ", payload)
 	return payload, nil
 }
 
-func (m *merchantRepository) List() ([]entity.Merchant, error) {
-	var merchants []entity.Merchant
-	var rows *sql.Rows
-	var err error
+func (m *merchantRepository) Onboard(owner entity.User, merchant entity.Merchant) (entity.User, entity.Merchant, error) {
+	m.log.Info("Starting to onboard a new merchant in the repository layer", nil)
+
+	if !validEmail(owner.Email) {
+		m.log.Error("Invalid email for the merchant owner: ", owner.Email)
+		return entity.User{}, entity.Merchant{}, ErrInvalidEmail
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		m.log.Error("Failed to begin the onboarding transaction: ", err)
+		return entity.User{}, entity.Merchant{}, fmt.Errorf("begin the onboarding transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err = tx.QueryRow(insertUser, owner.Username, owner.Password, owner.Role, owner.Email, owner.MustChangePassword).Scan(&owner.Id_user)
+	if err != nil {
+		m.log.Error("Failed to create the merchant owner: ", err)
+		return entity.User{}, entity.Merchant{}, mapUserError(err)
+	}
+	owner.Active = true
+
+	merchant.IdUser, merchant.Balance = owner.Id_user, 0
+	err = tx.QueryRow(insertMerchant, merchant.IdUser, merchant.NameMerchant, merchant.Address, merchant.IdProduct, merchant.Balance,
+		merchant.LowBalanceThreshold, merchant.DailyLimitCount, merchant.DailyLimitAmount).Scan(&merchant.IdMerchant)
+	if err != nil {
+		m.log.Error("Failed to create the merchant of the new owner: ", err)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return entity.User{}, entity.Merchant{}, fmt.Errorf("%w: %s", ErrProductNotFound, merchant.IdProduct)
+		}
+		return entity.User{}, entity.Merchant{}, fmt.Errorf("create the merchant: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		m.log.Error("Failed to commit the onboarding transaction: ", err)
+		return entity.User{}, entity.Merchant{}, fmt.Errorf("commit the onboarding transaction: %w", err)
+	}
+
+	m.log.Info("Merchant has been onboarded successfully: ", merchant)
+	return owner, merchant, nil
+}
 
+func (m *merchantRepository) List() ([]entity.Merchant, error) {
 	m.log.Info("Starting to retrive all merchant in the repository layer", nil)
 
-	rows, err = m.db.Query("SELECT id_merchant, id_user, name_merchant, address, id_product, balance FROM mst_merchant")
+	rows, err := m.db.Query("SELECT " + merchantColumns + " FROM mst_merchant")
 
 	if err != nil {
 		m.log.Error("Failed to retrive the merchant: ", err)
-		return nil, err
+		return nil, fmt.Errorf("retrieve the merchant: %w", err)
+	}
+
+	merchants, err := dbscan.All[entity.Merchant](rows)
+	if err != nil {
+		m.log.Error("Failed to scan the merchant: ", err)
+		return nil, fmt.Errorf("scan the merchant: %w", err)
 	}
 
+	m.log.Info("Getting all merchant was successfully: ", merchants)
+	return merchants, nil
+}
+
+func (m *merchantRepository) ListByUser(userId string) ([]entity.Merchant, error) {
+	m.log.Info("Starting to retrive the merchants of a user in the repository layer", nil)
+
+	rows, err := m.db.Query("SELECT "+merchantColumns+" FROM mst_merchant WHERE id_user = $1", userId)
+	if err != nil {
+		m.log.Error("Failed to retrive the merchants of the user: ", err)
+		return nil, fmt.Errorf("retrieve the merchants of the user: %w", err)
+	}
+	defer rows.Close()
+
+	merchants := []entity.Merchant{}
 	for rows.Next() {
 		var merchant entity.Merchant
-
-		m.log.Info("Starting to scan all merchant in the repository layer", nil)
-		if err := rows.Scan(&merchant.IdMerchant, &merchant.IdUser, &merchant.NameMerchant, &merchant.Address, &merchant.IdProduct, &merchant.Balance); err != nil {
+		if err := rows.Scan(&merchant.IdMerchant, &merchant.IdUser, &merchant.NameMerchant, &merchant.Address, &merchant.IdProduct, &merchant.Balance, &merchant.LowBalanceThreshold, &merchant.DailyLimitCount, &merchant.DailyLimitAmount); err != nil {
 			m.log.Error("Failed to scan the merchant: ", err)
-			return nil, err
+			return nil, fmt.Errorf("scan the merchant: %w", err)
 		}
-
-		m.log.Info("Starting to add merchant in the repository layer", nil)
 		merchants = append(merchants, merchant)
 	}
+	if err := rows.Err(); err != nil {
+		m.log.Error("Failed to retrive the merchants of the user: ", err)
+		return nil, fmt.Errorf("retrieve the merchants of the user: %w", err)
+	}
 
-	m.log.Info("Getting all merchant was successfully: ", merchants)
+	m.log.Info("Getting the merchants of the user was successfully: ", len(merchants))
 	return merchants, nil
 }
 
@@ -70,16 +174,16 @@ func (m *merchantRepository) Get(id string) (entity.Merchant, error) {
 
 	m.log.Info("Starting to retrive a merchant by id in the repository layer", nil)
 
-	if err := m.db.QueryRow("SELECT id_merchant, id_user, name_merchant, address, id_product, balance FROM mst_merchant WHERE id_merchant = $1", id).Scan(&merchant.IdMerchant, &merchant.IdUser, &merchant.NameMerchant, &merchant.Address, &merchant.IdProduct, &merchant.Balance); err != nil {
+	if err := m.db.QueryRow("SELECT "+merchantColumns+" FROM mst_merchant WHERE id_merchant = $1", id).Scan(&merchant.IdMerchant, &merchant.IdUser, &merchant.NameMerchant, &merchant.Address, &merchant.IdProduct, &merchant.Balance, &merchant.LowBalanceThreshold, &merchant.DailyLimitCount, &merchant.DailyLimitAmount); err != nil {
 		m.log.Error("Failed to retrive the merchant: ", err)
-		return entity.Merchant{}, err
+		return entity.Merchant{}, fmt.Errorf("retrieve the merchant: %w", err)
 	}
 
 	m.log.Info("Getting merchant by id was successfully: ", merchant)
 	return merchant, nil
 }
 
-func (m *merchantRepository) Update(merchant, payload entity.Merchant) (entity.Merchant, error) {
+func (m *merchantRepository) Update(merchant entity.Merchant, payload entity.MerchantUpdateRequest) (entity.Merchant, error) {
 	m.log.Info("Starting to map merchant and payload in the repository layer", nil)
 
 	if strings.TrimSpace(payload.IdUser) != "" {
@@ -94,13 +198,22 @@ func (m *merchantRepository) Update(merchant, payload entity.Merchant) (entity.M
 	if strings.TrimSpace(payload.IdProduct) != "" {
 		merchant.IdProduct = payload.IdProduct
 	}
+	if payload.LowBalanceThreshold != nil {
+		merchant.LowBalanceThreshold = *payload.LowBalanceThreshold
+	}
+	if payload.DailyLimitCount != nil {
+		merchant.DailyLimitCount = *payload.DailyLimitCount
+	}
+	if payload.DailyLimitAmount != nil {
+		merchant.DailyLimitAmount = *payload.DailyLimitAmount
+	}
 
 	m.log.Info("Starting to update merchant in the repository layer", nil)
 
-	_, err := m.db.Exec("UPDATE mst_merchant SET id_user = $2, name_merchant = $3, address = $4, id_product = $5 WHERE id_merchant = $1", merchant.IdMerchant, merchant.IdUser, merchant.NameMerchant, merchant.Address, merchant.IdProduct)
+	_, err := m.db.Exec("UPDATE mst_merchant SET id_user = $2, name_merchant = $3, address = $4, id_product = $5, low_balance_threshold = $6, daily_limit_count = $7, daily_limit_amount = $8 WHERE id_merchant = $1", merchant.IdMerchant, merchant.IdUser, merchant.NameMerchant, merchant.Address, merchant.IdProduct, merchant.LowBalanceThreshold, merchant.DailyLimitCount, merchant.DailyLimitAmount)
 	if err != nil {
 		m.log.Error("Failed to update the merchant: ", err)
-		return entity.Merchant{}, err
+		return entity.Merchant{}, fmt.Errorf("update the merchant: %w", err)
 	}
 
 	m.log.Info("Merchant has been updated successfully: ", merchant)
@@ -113,13 +226,168 @@ func (m *merchantRepository) Delete(id string) error {
 	_, err := m.db.Exec("DELETE FROM mst_merchant WHERE id_merchant = $1", id)
 	if err != nil {
 		m.log.Error("Failed to delete the merchant: ", err)
-		return err
+		return fmt.Errorf("delete the merchant: %w", err)
 	}
 
 	m.log.Info("Merchant has been deleted successfully: ", id)
 	return nil
 }
 
+func (m *merchantRepository) GetBalance(merchantId string) (float64, error) {
+	var balance float64
+
+	m.log.Info("Starting to retrive a merchant balance in the repository layer", nil)
+
+	if err := m.db.QueryRow("SELECT COALESCE(balance, 0) FROM mst_merchant WHERE id_merchant = $1", merchantId).Scan(&balance); err != nil {
+		m.log.Error("Failed to retrive the merchant balance: ", err)
+		return 0, fmt.Errorf("retrieve the merchant balance: %w", err)
+	}
+
+	m.log.Info("Getting merchant balance was successfully: ", merchantId)
+	return balance, nil
+}
+
+// AdjustBalances applies every delta in one transaction and records it in merchant_balance_ledger and the audit log. Merchants are
+// locked in id order so two concurrent adjustments over the same merchants cannot deadlock.
+func (m *merchantRepository) AdjustBalances(adjustments map[string]float64, actorId string) error {
+	m.log.Info("Starting to adjust merchant balances in the repository layer", nil)
+
+	ids := make([]string, 0, len(adjustments))
+	for id := range adjustments {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		m.log.Error("Failed to begin the balance adjustment transaction: ", err)
+		return fmt.Errorf("begin the balance adjustment transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, id := range ids {
+		var balance float64
+
+		err = tx.QueryRow("SELECT COALESCE(balance, 0) FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE", id).Scan(&balance)
+		if err == sql.ErrNoRows {
+			err = &ErrUnknownMerchant{MerchantId: id}
+		}
+		if err != nil {
+			m.log.Error("Failed to lock the merchant balance: ", err)
+			return fmt.Errorf("lock the merchant balance: %w", err)
+		}
+
+		newBalance := balance + adjustments[id]
+
+		_, err = tx.Exec("UPDATE mst_merchant SET balance = $1 WHERE id_merchant = $2", newBalance, id)
+		if err != nil {
+			m.log.Error("Failed to adjust the merchant balance: ", err)
+			return fmt.Errorf("adjust the merchant balance: %w", err)
+		}
+
+		_, err = tx.Exec("INSERT INTO merchant_balance_ledger (id_merchant, amount, balance_after, reason) VALUES ($1, $2, $3, $4)", id, adjustments[id], newBalance, "admin_adjustment")
+		if err != nil {
+			m.log.Error("Failed to record the balance ledger: ", err)
+			return fmt.Errorf("record the balance ledger: %w", err)
+		}
+
+		err = insertAuditLog(tx, entity.AuditLog{ActorId: actorId, Action: entity.AuditActionBalanceAdjust, TargetId: id})
+		if err != nil {
+			m.log.Error("Failed to record the balance adjustment audit log: ", err)
+			return fmt.Errorf("record the balance adjustment audit log: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		m.log.Error("Failed to commit the balance adjustment transaction: ", err)
+		return fmt.Errorf("commit the balance adjustment transaction: %w", err)
+	}
+
+	m.log.Info("Merchant balances have been adjusted successfully: ", adjustments)
+	return nil
+}
+
+func (m *merchantRepository) SetProductPrice(price entity.MerchantProductPrice) error {
+	m.log.Info("Starting to set a merchant product price in the repository layer", nil)
+
+	_, err := m.db.Exec(`INSERT INTO merchant_product_price (id_merchant, id_product, price) VALUES ($1, $2, $3)
+		ON CONFLICT (id_merchant, id_product) DO UPDATE SET price = EXCLUDED.price, updated_at = NOW()`,
+		price.IdMerchant, price.IdProduct, price.Price)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			err = ErrPriceOverrideTarget
+		}
+		m.log.Error("Failed to set the merchant product price: ", err)
+		return fmt.Errorf("set the merchant product price: %w", err)
+	}
+
+	m.log.Info("Merchant product price has been set successfully: ", price)
+	return nil
+}
+
+func (m *merchantRepository) ClearProductPrice(merchantId, productId string) error {
+	m.log.Info("Starting to clear a merchant product price in the repository layer", nil)
+
+	result, err := m.db.Exec("DELETE FROM merchant_product_price WHERE id_merchant = $1 AND id_product = $2", merchantId, productId)
+	if err != nil {
+		m.log.Error("Failed to clear the merchant product price: ", err)
+		return fmt.Errorf("clear the merchant product price: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		m.log.Error("Failed to clear the merchant product price: ", err)
+		return fmt.Errorf("clear the merchant product price: %w", err)
+	}
+	if affected == 0 {
+		return ErrPriceOverrideNotFound
+	}
+
+	m.log.Info("Merchant product price has been cleared successfully: ", productId)
+	return nil
+}
+
+// ListCatalog returns the active products with the price the merchant pays, the override wins over the catalog price.
+func (m *merchantRepository) ListCatalog(merchantId string) ([]entity.MerchantCatalogItem, error) {
+	m.log.Info("Starting to retrive the merchant catalog in the repository layer", nil)
+
+	rows, err := m.db.Query(`SELECT p.id_product, pv.name_provider, p.nominal, COALESCE(mpp.price, p.price),
+		CASE WHEN mpp.price IS NULL THEN $2 ELSE $3 END
+		FROM mst_product p
+		JOIN mst_provider pv ON pv.id_provider = p.id_provider
+		LEFT JOIN merchant_product_price mpp ON mpp.id_product = p.id_product AND mpp.id_merchant = $1
+		WHERE p.status = $4 AND (p.id_merchant IS NULL OR p.id_merchant = $1)
+		ORDER BY pv.name_provider, p.nominal, p.id_product`,
+		merchantId, entity.PriceSourceCatalog, entity.PriceSourceMerchant, entity.ProductStatusActive)
+	if err != nil {
+		m.log.Error("Failed to retrive the merchant catalog: ", err)
+		return nil, fmt.Errorf("retrieve the merchant catalog: %w", err)
+	}
+	defer rows.Close()
+
+	var items []entity.MerchantCatalogItem
+	for rows.Next() {
+		var item entity.MerchantCatalogItem
+		if err := rows.Scan(&item.IdProduct, &item.NameProvider, &item.Nominal, &item.Price, &item.PriceSource); err != nil {
+			m.log.Error("Failed to scan the merchant catalog: ", err)
+			return nil, fmt.Errorf("scan the merchant catalog: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		m.log.Error("Failed to retrive the merchant catalog: ", err)
+		return nil, fmt.Errorf("retrieve the merchant catalog: %w", err)
+	}
+
+	m.log.Info("Merchant catalog has been retrived successfully: ", len(items))
+	return items, nil
+}
+
 func NewMerchantRepository(db *sql.DB, log *logger.Logger) MerchantRepository {
 	return &merchantRepository{db: db, log: log}
 }
@@ -8,16 +8,20 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/suite"
 )
 
 var expectedMerchant = entity.Merchant{
-	IdMerchant:   "uuid-merchant-test",
-	IdUser:       "uuid-user-test",
-	NameMerchant: "name-merchant-test",
-	Address:      "address-test",
-	IdProduct:    "uuid-product-test",
-	Balance:      10000,
+	IdMerchant:          "uuid-merchant-test",
+	IdUser:              "uuid-user-test",
+	NameMerchant:        "name-merchant-test",
+	Address:             "address-test",
+	IdProduct:           "uuid-product-test",
+	Balance:             10000,
+	LowBalanceThreshold: 5000,
+	DailyLimitCount:     100,
+	DailyLimitAmount:    1000000,
 }
 
 type merchantRepositoryTestSuite struct {
@@ -44,16 +48,19 @@ func (m *merchantRepositoryTestSuite) SetupTest() {
 
 func (m *merchantRepositoryTestSuite) TestGet_success() {
 
-	merchantRows := sqlmock.NewRows([]string{"id_merchant", "id_user", "name_merchant", "address", "id_product", "balance"}).AddRow(
+	merchantRows := sqlmock.NewRows([]string{"id_merchant", "id_user", "name_merchant", "address", "id_product", "balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(
 		expectedMerchant.IdMerchant,
 		expectedMerchant.IdUser,
 		expectedMerchant.NameMerchant,
 		expectedMerchant.Address,
 		expectedMerchant.IdProduct,
 		expectedMerchant.Balance,
+		expectedMerchant.LowBalanceThreshold,
+		expectedMerchant.DailyLimitCount,
+		expectedMerchant.DailyLimitAmount,
 	)
 
-	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_merchant, id_user, name_merchant, address, id_product, balance FROM mst_merchant WHERE id_merchant = $1")).
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT " + merchantColumns + " FROM mst_merchant WHERE id_merchant = $1")).
 		WithArgs(expectedMerchant.IdMerchant).WillReturnRows(
 		merchantRows,
 	)
@@ -65,25 +72,29 @@ func (m *merchantRepositoryTestSuite) TestGet_success() {
 }
 
 func (m *merchantRepositoryTestSuite) TestGet_fail() {
-	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_merchant, id_user, name_merchant, address, id_product, balance FROM mst_merchant WHERE id_merchant = $1")).
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT " + merchantColumns + " FROM mst_merchant WHERE id_merchant = $1")).
 		WithArgs(expectedMerchant.IdMerchant).WillReturnError(sql.ErrNoRows)
 
 	_, err := m.mr.Get("uuid-merchant-test")
 
-	m.NotNil(err)
+	m.ErrorIs(err, sql.ErrNoRows)
+	m.ErrorContains(err, "retrieve the merchant")
 }
 
 func (m *merchantRepositoryTestSuite) TestList_success() {
-	merchantRows := sqlmock.NewRows([]string{"id_merchant", "id_user", "name_merchant", "address", "id_product", "balance"}).AddRow(
+	merchantRows := sqlmock.NewRows([]string{"id_merchant", "id_user", "name_merchant", "address", "id_product", "balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(
 		expectedMerchant.IdMerchant,
 		expectedMerchant.IdUser,
 		expectedMerchant.NameMerchant,
 		expectedMerchant.Address,
 		expectedMerchant.IdProduct,
 		expectedMerchant.Balance,
+		expectedMerchant.LowBalanceThreshold,
+		expectedMerchant.DailyLimitCount,
+		expectedMerchant.DailyLimitAmount,
 	)
 
-	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_merchant, id_user, name_merchant, address, id_product, balance FROM mst_merchant")).WillReturnRows(
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT " + merchantColumns + " FROM mst_merchant")).WillReturnRows(
 		merchantRows,
 	)
 
@@ -94,15 +105,29 @@ func (m *merchantRepositoryTestSuite) TestList_success() {
 }
 
 func (m *merchantRepositoryTestSuite) TestList_fail() {
-	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_merchant, id_user, name_merchant, address, id_product, balance FROM mst_merchant")).WillReturnError(sql.ErrNoRows)
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT " + merchantColumns + " FROM mst_merchant")).WillReturnError(sql.ErrNoRows)
 
 	_, err := m.mr.List()
 
 	m.NotNil(err)
 }
 
+func (m *merchantRepositoryTestSuite) TestListByUser_success() {
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT " + merchantColumns + " FROM mst_merchant WHERE id_user = $1")).
+		WithArgs(expectedMerchant.IdUser).
+		WillReturnRows(sqlmock.NewRows([]string{"id_merchant", "id_user", "name_merchant", "address", "id_product", "balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(
+			expectedMerchant.IdMerchant, expectedMerchant.IdUser, expectedMerchant.NameMerchant, expectedMerchant.Address,
+			expectedMerchant.IdProduct, expectedMerchant.Balance, expectedMerchant.LowBalanceThreshold, expectedMerchant.DailyLimitCount, expectedMerchant.DailyLimitAmount,
+		))
+
+	merchants, err := m.mr.ListByUser(expectedMerchant.IdUser)
+
+	m.Nil(err)
+	m.Equal([]entity.Merchant{expectedMerchant}, merchants)
+}
+
 func (m *merchantRepositoryTestSuite) TestCreate_success() {
-	m.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_merchant (id_user, name_merchant, address, id_product, balance) VALUES ($1, $2, $3, $4, $5) RETURNING id_merchant")).WillReturnRows(
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_merchant (id_user, name_merchant, address, id_product, balance, low_balance_threshold, daily_limit_count, daily_limit_amount) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id_merchant")).WillReturnRows(
 		sqlmock.NewRows([]string{"id_merchant"}).AddRow(expectedMerchant.IdMerchant),
 	)
 
@@ -139,7 +164,186 @@ func (m *merchantRepositoryTestSuite) TestUpdate_fail() {
 
 	m.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE mst_merchant SET id_user = $1, name_merchant = $2, address = $3, id_product = $4, balance = $5 WHERE id_merchant = $6")).WillReturnError(sql.ErrNoRows)
 
-	_, err := m.mr.Update(merchant, expectedMerchant)
+	_, err := m.mr.Update(merchant, entity.MerchantUpdateRequest{NameMerchant: expectedMerchant.NameMerchant})
+
+	m.NotNil(err)
+}
+
+func (m *merchantRepositoryTestSuite) TestUpdate_ClearsTheLimitsItSets() {
+	merchant := entity.Merchant{IdMerchant: "uuid-merchant-test", IdUser: "uuid-user-test", NameMerchant: "name-merchant-test",
+		Address: "address-test", IdProduct: "uuid-product-test", LowBalanceThreshold: 50000, DailyLimitCount: 200, DailyLimitAmount: 5000000}
+	noLimit := 0
+	m.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_merchant SET id_user = $2, name_merchant = $3, address = $4, id_product = $5, low_balance_threshold = $6, daily_limit_count = $7, daily_limit_amount = $8 WHERE id_merchant = $1")).
+		WithArgs("uuid-merchant-test", "uuid-user-test", "name-merchant-test", "address-test", "uuid-product-test", float64(50000), 0, float64(5000000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// the count is removed, the threshold and the amount are left out so they are kept
+	updated, err := m.mr.Update(merchant, entity.MerchantUpdateRequest{DailyLimitCount: &noLimit})
+
+	m.NoError(err)
+	m.Equal(0, updated.DailyLimitCount)
+	m.Equal(float64(5000000), updated.DailyLimitAmount)
+	m.NoError(m.mockSql.ExpectationsWereMet())
+}
+
+func (m *merchantRepositoryTestSuite) TestGetBalance_success() {
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(balance, 0) FROM mst_merchant WHERE id_merchant = $1")).
+		WithArgs(expectedMerchant.IdMerchant).WillReturnRows(
+		sqlmock.NewRows([]string{"balance"}).AddRow(expectedMerchant.Balance),
+	)
+
+	balance, err := m.mr.GetBalance(expectedMerchant.IdMerchant)
+
+	m.Nil(err)
+	m.Equal(expectedMerchant.Balance, balance)
+}
+
+func (m *merchantRepositoryTestSuite) TestGetBalance_fail() {
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(balance, 0) FROM mst_merchant WHERE id_merchant = $1")).
+		WithArgs(expectedMerchant.IdMerchant).WillReturnError(sql.ErrNoRows)
+
+	_, err := m.mr.GetBalance(expectedMerchant.IdMerchant)
 
 	m.NotNil(err)
 }
+
+func (m *merchantRepositoryTestSuite) TestSetProductPrice_upserts() {
+	price := entity.MerchantProductPrice{IdMerchant: "merchant-a", IdProduct: "product-a", Price: 10500}
+
+	m.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO merchant_product_price (id_merchant, id_product, price) VALUES ($1, $2, $3)")).
+		WithArgs("merchant-a", "product-a", 10500.0).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	m.Nil(m.mr.SetProductPrice(price))
+	m.Nil(m.mockSql.ExpectationsWereMet())
+}
+
+func (m *merchantRepositoryTestSuite) TestSetProductPrice_unknownTarget() {
+	m.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO merchant_product_price")).
+		WillReturnError(&pq.Error{Code: "23503"})
+
+	err := m.mr.SetProductPrice(entity.MerchantProductPrice{IdMerchant: "merchant-a", IdProduct: "product-x", Price: 10500})
+	m.ErrorIs(err, ErrPriceOverrideTarget)
+}
+
+func (m *merchantRepositoryTestSuite) TestClearProductPrice_notFound() {
+	m.mockSql.ExpectExec(regexp.QuoteMeta("DELETE FROM merchant_product_price WHERE id_merchant = $1 AND id_product = $2")).
+		WithArgs("merchant-a", "product-a").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := m.mr.ClearProductPrice("merchant-a", "product-a")
+	m.ErrorIs(err, ErrPriceOverrideNotFound)
+}
+
+func (m *merchantRepositoryTestSuite) TestListCatalog_prefersOverride() {
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("LEFT JOIN merchant_product_price mpp")).
+		WithArgs("merchant-a", entity.PriceSourceCatalog, entity.PriceSourceMerchant, entity.ProductStatusActive).
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "name_provider", "nominal", "price", "price_source"}).
+			AddRow("product-a", "Telkomsel", 10000, 10500, entity.PriceSourceMerchant).
+			AddRow("product-b", "Indosat", 5000, 6000, entity.PriceSourceCatalog))
+
+	items, err := m.mr.ListCatalog("merchant-a")
+
+	m.Nil(err)
+	m.Len(items, 2)
+	m.Equal(entity.PriceSourceMerchant, items[0].PriceSource)
+	m.Equal(10500.0, items[0].Price)
+	m.Equal(entity.PriceSourceCatalog, items[1].PriceSource)
+}
+
+func (m *merchantRepositoryTestSuite) TestAdjustBalances_success() {
+	adjustments := map[string]float64{"merchant-b": -2500, "merchant-a": 15000}
+
+	m.mockSql.ExpectBegin()
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(balance, 0) FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE")).
+		WithArgs("merchant-a").WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(10000))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_merchant SET balance = $1 WHERE id_merchant = $2")).
+		WithArgs(25000.0, "merchant-a").WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO merchant_balance_ledger (id_merchant, amount, balance_after, reason) VALUES ($1, $2, $3, $4)")).
+		WithArgs("merchant-a", 15000.0, 25000.0, "admin_adjustment").WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log (actor_id, action, target_id, detail) VALUES ($1, $2, $3, $4)")).
+		WithArgs("uuid-admin", entity.AuditActionBalanceAdjust, "merchant-a", "").WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(balance, 0) FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE")).
+		WithArgs("merchant-b").WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(5000))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_merchant SET balance = $1 WHERE id_merchant = $2")).
+		WithArgs(2500.0, "merchant-b").WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO merchant_balance_ledger (id_merchant, amount, balance_after, reason) VALUES ($1, $2, $3, $4)")).
+		WithArgs("merchant-b", -2500.0, 2500.0, "admin_adjustment").WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log (actor_id, action, target_id, detail) VALUES ($1, $2, $3, $4)")).
+		WithArgs("uuid-admin", entity.AuditActionBalanceAdjust, "merchant-b", "").WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectCommit()
+
+	err := m.mr.AdjustBalances(adjustments, "uuid-admin")
+
+	m.Nil(err)
+	m.Nil(m.mockSql.ExpectationsWereMet())
+}
+
+func (m *merchantRepositoryTestSuite) TestAdjustBalances_unknownMerchantRollsBack() {
+	adjustments := map[string]float64{"merchant-a": 15000, "merchant-x": 1000}
+
+	m.mockSql.ExpectBegin()
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(balance, 0) FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE")).
+		WithArgs("merchant-a").WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(10000))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_merchant SET balance = $1 WHERE id_merchant = $2")).
+		WithArgs(25000.0, "merchant-a").WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO merchant_balance_ledger")).WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log")).WillReturnResult(sqlmock.NewResult(0, 1))
+	m.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(balance, 0) FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE")).
+		WithArgs("merchant-x").WillReturnError(sql.ErrNoRows)
+	m.mockSql.ExpectRollback()
+
+	err := m.mr.AdjustBalances(adjustments, "uuid-admin")
+
+	var unknown *ErrUnknownMerchant
+	m.ErrorAs(err, &unknown)
+	m.Equal("merchant-x", unknown.MerchantId)
+	m.Nil(m.mockSql.ExpectationsWereMet())
+}
+
+func (m *merchantRepositoryTestSuite) TestOnboard_success() {
+	owner := entity.User{Username: "eko", Password: "hash", Role: "employee", Email: "eko@example.com", MustChangePassword: true}
+
+	m.mockSql.ExpectBegin()
+	m.mockSql.ExpectQuery(regexp.QuoteMeta(insertUser)).
+		WithArgs("eko", "hash", "employee", "eko@example.com", true).
+		WillReturnRows(sqlmock.NewRows([]string{"id_user"}).AddRow("uuid-eko"))
+	m.mockSql.ExpectQuery(regexp.QuoteMeta(insertMerchant)).
+		WithArgs("uuid-eko", expectedMerchant.NameMerchant, expectedMerchant.Address, expectedMerchant.IdProduct, 0.0,
+			expectedMerchant.LowBalanceThreshold, expectedMerchant.DailyLimitCount, expectedMerchant.DailyLimitAmount).
+		WillReturnRows(sqlmock.NewRows([]string{"id_merchant"}).AddRow("uuid-merchant-eko"))
+	m.mockSql.ExpectCommit()
+
+	user, merchant, err := m.mr.Onboard(owner, expectedMerchant)
+
+	m.NoError(err)
+	m.Equal("uuid-eko", user.Id_user)
+	m.Equal("uuid-merchant-eko", merchant.IdMerchant)
+	m.Equal("uuid-eko", merchant.IdUser)
+	m.Zero(merchant.Balance)
+	m.Nil(m.mockSql.ExpectationsWereMet())
+}
+
+func (m *merchantRepositoryTestSuite) TestOnboard_merchantFailureRollsBackTheUser() {
+	m.mockSql.ExpectBegin()
+	m.mockSql.ExpectQuery(regexp.QuoteMeta(insertUser)).
+		WillReturnRows(sqlmock.NewRows([]string{"id_user"}).AddRow("uuid-eko"))
+	m.mockSql.ExpectQuery(regexp.QuoteMeta(insertMerchant)).
+		WillReturnError(&pq.Error{Code: "23503", Constraint: "mst_merchant_id_product_fkey"})
+	m.mockSql.ExpectRollback()
+
+	_, _, err := m.mr.Onboard(entity.User{Username: "eko", Password: "hash", Role: "employee"}, expectedMerchant)
+
+	m.ErrorIs(err, ErrProductNotFound)
+	m.Nil(m.mockSql.ExpectationsWereMet())
+}
+
+func (m *merchantRepositoryTestSuite) TestOnboard_takenUsernameRollsBack() {
+	m.mockSql.ExpectBegin()
+	m.mockSql.ExpectQuery(regexp.QuoteMeta(insertUser)).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "mst_user_username_lower_idx"})
+	m.mockSql.ExpectRollback()
+
+	_, _, err := m.mr.Onboard(entity.User{Username: "eko", Password: "hash", Role: "employee"}, expectedMerchant)
+
+	m.ErrorIs(err, ErrUsernameTaken)
+	m.Nil(m.mockSql.ExpectationsWereMet())
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"server-pulsa-app/internal/logger"
+	"time"
+)
+
+// ErrInvalidResetToken is returned for unknown, expired and already used reset tokens alike,
+// so callers cannot tell which tokens ever existed.
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+type PasswordResetRepository interface {
+	CreateToken(userId, tokenHash string, expiresAt time.Time) error
+	FindUsername(tokenHash string) (string, error)
+	ResetPassword(tokenHash, passwordHash string) (string, error)
+}
+
+type passwordResetRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func (r *passwordResetRepository) CreateToken(userId, tokenHash string, expiresAt time.Time) error {
+	r.log.Info("Starting to create a password reset token in the repository layer", nil)
+
+	_, err := r.db.Exec("INSERT INTO password_reset_token (token_hash, id_user, expires_at) VALUES ($1, $2, $3)", tokenHash, userId, expiresAt)
+	if err != nil {
+		r.log.Error("Failed to create the password reset token: ", err)
+		return err
+	}
+
+	r.log.Info("Password reset token has been created successfully", userId)
+	return nil
+}
+
+// FindUsername returns the username of a token that can still be used, without consuming it.
+func (r *passwordResetRepository) FindUsername(tokenHash string) (string, error) {
+	r.log.Info("Starting to find the user of a password reset token in the repository layer", nil)
+
+	var username string
+	err := r.db.QueryRow(`SELECT u.username FROM password_reset_token t
+		JOIN mst_user u ON u.id_user = t.id_user
+		WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > NOW()`, tokenHash).Scan(&username)
+	if err == sql.ErrNoRows {
+		err = ErrInvalidResetToken
+	}
+	if err != nil {
+		r.log.Error("Failed to find the user of the password reset token: ", err)
+		return "", err
+	}
+
+	return username, nil
+}
+
+// ResetPassword marks the token as used and stores the new password hash in one transaction,
+// the token is only accepted once and only before it expires. It returns the id of the user.
+func (r *passwordResetRepository) ResetPassword(tokenHash, passwordHash string) (string, error) {
+	r.log.Info("Starting to reset a password in the repository layer", nil)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.log.Error("Failed to begin the password reset transaction: ", err)
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var userId string
+	err = tx.QueryRow(`UPDATE password_reset_token SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id_user`, tokenHash).Scan(&userId)
+	if err == sql.ErrNoRows {
+		err = ErrInvalidResetToken
+	}
+	if err != nil {
+		r.log.Error("Failed to consume the password reset token: ", err)
+		return "", err
+	}
+
+	_, err = tx.Exec("UPDATE mst_user SET password = $1, must_change_password = FALSE WHERE id_user = $2", passwordHash, userId)
+	if err != nil {
+		r.log.Error("Failed to update the password: ", err)
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("Failed to commit the password reset transaction: ", err)
+		return "", err
+	}
+
+	r.log.Info("Password has been reset successfully", userId)
+	return userId, nil
+}
+
+func NewPasswordResetRepository(db *sql.DB, log *logger.Logger) PasswordResetRepository {
+	return &passwordResetRepository{db: db, log: log}
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type passwordResetRepositoryTestSuite struct {
+	suite.Suite
+	mockDb  *sql.DB
+	mockSql sqlmock.Sqlmock
+	repo    PasswordResetRepository
+	log     logger.Logger
+}
+
+func TestPasswordResetRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(passwordResetRepositoryTestSuite))
+}
+
+func (p *passwordResetRepositoryTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	p.NoError(err)
+
+	p.mockDb = mockDb
+	p.mockSql = mockSql
+	p.log = logger.NewLogger()
+	p.repo = NewPasswordResetRepository(mockDb, &p.log)
+}
+
+func (p *passwordResetRepositoryTestSuite) TestCreateToken() {
+	expiresAt := time.Now().Add(30 * time.Minute)
+	p.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO password_reset_token (token_hash, id_user, expires_at) VALUES ($1, $2, $3)")).
+		WithArgs("token-hash", "uuid-user", expiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	p.Nil(p.repo.CreateToken("uuid-user", "token-hash", expiresAt))
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+func (p *passwordResetRepositoryTestSuite) TestResetPassword_Success() {
+	p.mockSql.ExpectBegin()
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE password_reset_token SET used_at = NOW()")).
+		WithArgs("token-hash").WillReturnRows(sqlmock.NewRows([]string{"id_user"}).AddRow("uuid-user"))
+	p.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_user SET password = $1, must_change_password = FALSE WHERE id_user = $2")).
+		WithArgs("password-hash", "uuid-user").WillReturnResult(sqlmock.NewResult(0, 1))
+	p.mockSql.ExpectCommit()
+
+	userId, err := p.repo.ResetPassword("token-hash", "password-hash")
+
+	p.Nil(err)
+	p.Equal("uuid-user", userId)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+// a token that was already used, has expired or never existed matches no row and gets the same error
+func (p *passwordResetRepositoryTestSuite) TestResetPassword_UsedOrExpiredToken() {
+	p.mockSql.ExpectBegin()
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE password_reset_token SET used_at = NOW()")).
+		WithArgs("token-hash").WillReturnError(sql.ErrNoRows)
+	p.mockSql.ExpectRollback()
+
+	_, err := p.repo.ResetPassword("token-hash", "password-hash")
+
+	p.ErrorIs(err, ErrInvalidResetToken)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+func (p *passwordResetRepositoryTestSuite) TestFindUsername() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > NOW()")).
+		WithArgs("token-hash").WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("john"))
+
+	username, err := p.repo.FindUsername("token-hash")
+
+	p.NoError(err)
+	p.Equal("john", username)
+}
+
+func (p *passwordResetRepositoryTestSuite) TestFindUsername_UsedOrExpiredToken() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > NOW()")).
+		WithArgs("used-hash").WillReturnError(sql.ErrNoRows)
+
+	_, err := p.repo.FindUsername("used-hash")
+
+	p.ErrorIs(err, ErrInvalidResetToken)
+}
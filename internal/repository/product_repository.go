@@ -3,16 +3,84 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/dbscan"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
+// ErrProductVersionConflict is returned by Update when the product was changed since the client read it.
+var ErrProductVersionConflict = errors.New("product has been modified by someone else, refetch it and try again")
+
+// ErrInvalidProductSort is returned by List when the sort column or order is not whitelisted.
+var ErrInvalidProductSort = errors.New("sort must be one of nominal, price, provider, updated_at and order asc or desc")
+
+// ErrProductCodeTaken is returned by Create and Update when another product already has the provider code.
+var ErrProductCodeTaken = errors.New("product code already used by another product")
+
+// mapProductError translates the unique violation on code, the only unique column besides the id, into
+// ErrProductCodeTaken.
+func mapProductError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrProductCodeTaken
+	}
+	return err
+}
+
+// productSortColumns whitelists the columns the product listing can be ordered by.
+var productSortColumns = map[string]string{
+	"nominal":    "p.nominal",
+	"price":      "p.price",
+	"provider":   "pv.name_provider",
+	"updated_at": "p.updated_at",
+}
+
+// productOrderBy builds the ORDER BY clause from whitelisted values only. p.id_product is always the last key so
+// rows with equal sort values keep the same order and page boundaries stay stable.
+func productOrderBy(query entity.ProductQuery) (string, error) {
+	order := strings.ToUpper(query.Order)
+	if order == "" {
+		order = "ASC"
+	}
+	if order != "ASC" && order != "DESC" {
+		return "", ErrInvalidProductSort
+	}
+
+	if query.Sort == "" {
+		return fmt.Sprintf("pv.name_provider %s, p.nominal %s, p.id_product", order, order), nil
+	}
+
+	column, ok := productSortColumns[query.Sort]
+	if !ok {
+		return "", ErrInvalidProductSort
+	}
+	return fmt.Sprintf("%s %s, p.id_product", column, order), nil
+}
+
+// ErrProductInUse is returned by Delete when the product is still referenced by transaction details.
+type ErrProductInUse struct {
+	ProductId string
+	Count     int
+}
+
+func (e *ErrProductInUse) Error() string {
+	return fmt.Sprintf("product %s is used by %d transaction details and cannot be deleted, deactivate it instead", e.ProductId, e.Count)
+}
+
 type ProductRepository interface {
 	Create(product entity.Product) (entity.Product, error)
-	List() ([]entity.Product, error)
+	List(query entity.ProductQuery) ([]entity.Product, error)
+	ListPublicCatalog() ([]entity.PublicProduct, error)
 	Get(id string) (entity.Product, error)
+	GetByCode(code string) (entity.Product, error)
+	GetByIds(ids []string) ([]entity.Product, error)
 	Update(product entity.Product) (entity.Product, error)
 	Delete(id string) error
+	SyncPrices(items []entity.SupplierPriceItem, supplierId string) (entity.ProductSyncReport, error)
 }
 
 type productRepository struct {
@@ -27,13 +95,13 @@ func (p *productRepository) Create(product entity.Product) (entity.Product, erro
 	if product.Price < product.Nominal {
 		err := errors.New("price must be greater than nominal")
 		p.log.Error("Failed to create the product: ", err)
-		return entity.Product{}, err
+		return entity.Product{}, fmt.Errorf("create the product: %w", err)
 	}
 
-	err := p.db.QueryRow("INSERT INTO mst_product (name_provider, nominal, price, id_supliyer) VALUES ($1, $2, $3, $4) RETURNING id_product", product.NameProvider, product.Nominal, product.Price, product.IdSupliyer).Scan(&product.IdProduct)
+	err := p.db.QueryRow("INSERT INTO mst_product (id_provider, nominal, price, id_supliyer, code, id_merchant) VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, '')::uuid) RETURNING id_product, version, status, (SELECT name_provider FROM mst_provider WHERE id_provider = $1)", product.IdProvider, product.Nominal, product.Price, product.IdSupliyer, product.Code, product.IdMerchant).Scan(&product.IdProduct, &product.Version, &product.Status, &product.NameProvider)
 	if err != nil {
 		p.log.Error("Failed to create the product: ", err)
-		return entity.Product{}, err
+		return entity.Product{}, mapProductError(err)
 	}
 
 	p.log.Info("Product has been created successfully: ", product)
@@ -46,45 +114,132 @@ func (p *productRepository) Get(id string) (entity.Product, error) {
 
 	p.log.Info("Starting to retrive a product by id in the repository layer", nil)
 
-	err := p.db.QueryRow("SELECT id_product, name_provider, nominal, price, id_supliyer FROM mst_product WHERE id_product = $1", id).Scan(&product.IdProduct, &product.NameProvider, &product.Nominal, &product.Price, &product.IdSupliyer)
+	err := p.db.QueryRow("SELECT p.id_product, p.id_provider, pv.name_provider, p.nominal, p.price, p.id_supliyer, p.version, COALESCE(p.code, ''), p.status, COALESCE(p.id_merchant::text, '') FROM mst_product p JOIN mst_provider pv ON p.id_provider = pv.id_provider WHERE p.id_product = $1", id).Scan(&product.IdProduct, &product.IdProvider, &product.NameProvider, &product.Nominal, &product.Price, &product.IdSupliyer, &product.Version, &product.Code, &product.Status, &product.IdMerchant)
 	if err != nil {
 		p.log.Error("Failed to retrive the product: ", err)
-		return entity.Product{}, err
+		return entity.Product{}, fmt.Errorf("retrieve the product: %w", err)
 	}
 
 	p.log.Info("Getting user by id was successfully: ", product)
 	return product, nil
 }
 
-func (p *productRepository) List() ([]entity.Product, error) {
-	var products []entity.Product
+// GetByCode looks a product up by the code its provider knows it by, so provider webhooks can be mapped to products.
+func (p *productRepository) GetByCode(code string) (entity.Product, error) {
+	var product entity.Product
 
-	p.log.Info("Starting to retrive all product in the repository layer", nil)
+	p.log.Info("Starting to retrive a product by code in the repository layer", nil)
 
-	rows, err := p.db.Query("SELECT id_product, name_provider, nominal, price, id_supliyer FROM mst_product")
+	err := p.db.QueryRow("SELECT p.id_product, p.id_provider, pv.name_provider, p.nominal, p.price, p.id_supliyer, p.version, COALESCE(p.code, ''), p.status, COALESCE(p.id_merchant::text, '') FROM mst_product p JOIN mst_provider pv ON p.id_provider = pv.id_provider WHERE p.code = $1", code).Scan(&product.IdProduct, &product.IdProvider, &product.NameProvider, &product.Nominal, &product.Price, &product.IdSupliyer, &product.Version, &product.Code, &product.Status, &product.IdMerchant)
+	if err == sql.ErrNoRows {
+		p.log.Error("Failed to retrive the product, unknown code: ", code)
+		return entity.Product{}, fmt.Errorf("%w: %s", ErrProductNotFound, code)
+	}
 	if err != nil {
 		p.log.Error("Failed to retrive the product: ", err)
-		return nil, err
+		return entity.Product{}, fmt.Errorf("retrieve the product: %w", err)
+	}
+
+	p.log.Info("Getting product by code was successfully: ", product)
+	return product, nil
+}
+
+// GetByIds fetches the products with one query, unknown ids are left out of the result.
+func (p *productRepository) GetByIds(ids []string) ([]entity.Product, error) {
+	p.log.Info("Starting to retrive products by ids in the repository layer", nil)
+
+	rows, err := p.db.Query("SELECT p.id_product, p.id_provider, pv.name_provider, p.nominal, p.price, p.id_supliyer, p.version, COALESCE(p.code, ''), p.status, COALESCE(p.id_merchant::text, '') FROM mst_product p JOIN mst_provider pv ON p.id_provider = pv.id_provider WHERE p.id_product = ANY($1)", pq.Array(ids))
+	if err != nil {
+		p.log.Error("Failed to retrive the products: ", err)
+		return nil, fmt.Errorf("retrieve the products: %w", err)
 	}
+	defer rows.Close()
 
+	products := []entity.Product{}
 	for rows.Next() {
 		var product entity.Product
-
-		p.log.Info("Starting to scan all product in the repository layer", nil)
-		err := rows.Scan(&product.IdProduct, &product.NameProvider, &product.Nominal, &product.Price, &product.IdSupliyer)
-		if err != nil {
+		if err := rows.Scan(&product.IdProduct, &product.IdProvider, &product.NameProvider, &product.Nominal, &product.Price, &product.IdSupliyer, &product.Version, &product.Code, &product.Status, &product.IdMerchant); err != nil {
 			p.log.Error("Failed to scan the product: ", err)
-			return nil, err
+			return nil, fmt.Errorf("scan the product: %w", err)
 		}
-
-		p.log.Info("Starting to add product in the repository layer", nil)
 		products = append(products, product)
 	}
+	if err := rows.Err(); err != nil {
+		p.log.Error("Failed to retrive the products: ", err)
+		return nil, fmt.Errorf("retrieve the products: %w", err)
+	}
+
+	p.log.Info("Getting products by ids was successfully: ", len(products))
+	return products, nil
+}
+
+func (p *productRepository) List(query entity.ProductQuery) ([]entity.Product, error) {
+	p.log.Info("Starting to retrive all product in the repository layer", nil)
+
+	orderBy, err := productOrderBy(query)
+	if err != nil {
+		p.log.Error("Failed to retrive the product: ", err)
+		return nil, fmt.Errorf("retrieve the product: %w", err)
+	}
+
+	// the computed columns are named after the db tags of entity.Product for dbscan
+	selectQuery := "SELECT p.id_product, p.id_provider, pv.name_provider, p.nominal, p.price, p.id_supliyer, p.version, COALESCE(p.code, '') AS code, p.status, COALESCE(p.id_merchant::text, '') AS id_merchant FROM mst_product p JOIN mst_provider pv ON p.id_provider = pv.id_provider"
+	args := []any{}
+	if query.UserId != "" {
+		args = append(args, query.UserId)
+		selectQuery += " WHERE (p.id_merchant IS NULL OR p.id_merchant IN (SELECT id_merchant FROM mst_merchant WHERE id_user = $1))"
+	}
+	selectQuery += " ORDER BY " + orderBy
+	if query.Limit > 0 {
+		page := query.Page
+		if page < 1 {
+			page = 1
+		}
+		selectQuery += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+		args = append(args, query.Limit, (page-1)*query.Limit)
+	}
+
+	rows, err := p.db.Query(selectQuery, args...)
+	if err != nil {
+		p.log.Error("Failed to retrive the product: ", err)
+		return nil, fmt.Errorf("retrieve the product: %w", err)
+	}
+
+	products, err := dbscan.All[entity.Product](rows)
+	if err != nil {
+		p.log.Error("Failed to scan the product: ", err)
+		return nil, fmt.Errorf("scan the product: %w", err)
+	}
 
 	p.log.Info("Getting all product was successfully: ", products)
 	return products, nil
 }
 
+func (p *productRepository) ListPublicCatalog() ([]entity.PublicProduct, error) {
+	var products []entity.PublicProduct
+
+	p.log.Info("Starting to retrive the public product catalog in the repository layer", nil)
+
+	rows, err := p.db.Query("SELECT COALESCE(p.code, ''), pv.name_provider, p.category, p.nominal, p.price FROM mst_product p JOIN mst_provider pv ON p.id_provider = pv.id_provider WHERE p.status = $1 AND p.id_merchant IS NULL ORDER BY pv.name_provider, p.nominal, p.id_product", entity.ProductStatusActive)
+	if err != nil {
+		p.log.Error("Failed to retrive the public product catalog: ", err)
+		return nil, fmt.Errorf("retrieve the public product catalog: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product entity.PublicProduct
+		if err := rows.Scan(&product.Code, &product.Provider, &product.Category, &product.Nominal, &product.Price); err != nil {
+			p.log.Error("Failed to scan the public product catalog: ", err)
+			return nil, fmt.Errorf("scan the public product catalog: %w", err)
+		}
+		products = append(products, product)
+	}
+
+	p.log.Info("Getting the public product catalog was successfully: ", len(products))
+	return products, nil
+}
+
 func (p *productRepository) Update(product entity.Product) (entity.Product, error) {
 	p.log.Info("Starting to update product in the repository layer", nil)
 
@@ -92,16 +247,29 @@ func (p *productRepository) Update(product entity.Product) (entity.Product, erro
 	if product.Price < product.Nominal {
 		err := errors.New("price must be greater than nominal")
 		p.log.Error("Failed to update the product: ", err)
-		return entity.Product{}, err
+		return entity.Product{}, fmt.Errorf("update the product: %w", err)
 	}
 
-	// Menggunakan id yang diberikan untuk mengupdate product
-	_, err := p.db.Exec("UPDATE mst_product SET name_provider = $1, nominal = $2, price = $3, id_supliyer = $4 WHERE id_product = $5", product.NameProvider, product.Nominal, product.Price, product.IdSupliyer, product.IdProduct)
+	// Menggunakan id dan version yang diberikan untuk mengupdate product, an empty code keeps the current one
+	result, err := p.db.Exec("UPDATE mst_product SET id_provider = $1, nominal = $2, price = $3, id_supliyer = $4, code = COALESCE(NULLIF($7, ''), code), version = version + 1, updated_at = NOW() WHERE id_product = $5 AND version = $6", product.IdProvider, product.Nominal, product.Price, product.IdSupliyer, product.IdProduct, product.Version, product.Code)
 	if err != nil {
 		p.log.Error("Failed to update the product: ", err)
-		return entity.Product{}, err
+		return entity.Product{}, mapProductError(err)
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		p.log.Error("Failed to update the product: ", err)
+		return entity.Product{}, fmt.Errorf("update the product: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		p.log.Error("Failed to update the product, stale version: ", product.Version)
+		return entity.Product{}, ErrProductVersionConflict
+	}
+
+	product.Version++
+
 	p.log.Info("Product has been updated successfully: ", product)
 	return product, nil
 }
@@ -111,14 +279,106 @@ func (p *productRepository) Delete(id string) error {
 
 	_, err := p.db.Exec("DELETE FROM mst_product WHERE id_product = $1", id)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			var count int
+			if err := p.db.QueryRow("SELECT COUNT(*) FROM transaction_detail WHERE id_product = $1", id).Scan(&count); err != nil {
+				p.log.Error("Failed to count the product transaction details: ", err)
+				return fmt.Errorf("count the product transaction details: %w", err)
+			}
+
+			p.log.Error("Failed to delete the product, product still in use: ", count)
+			return &ErrProductInUse{ProductId: id, Count: count}
+		}
+
 		p.log.Error("Failed to delete the product: ", err)
-		return err
+		return fmt.Errorf("delete the product: %w", err)
 	}
 
 	p.log.Info("Product has been deleted successfully: ", id)
 	return nil
 }
 
+// SyncPrices applies a supplier price list in a single transaction. Products are matched by code, price changes are
+// recorded in product_price_history and unknown codes are created as draft products so an admin can review them.
+// The created products have no supplier when supplierId is empty.
+func (p *productRepository) SyncPrices(items []entity.SupplierPriceItem, supplierId string) (entity.ProductSyncReport, error) {
+	var report entity.ProductSyncReport
+
+	p.log.Info("Starting to sync product prices in the repository layer", nil)
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		p.log.Error("Failed to begin the price sync transaction: ", err)
+		return entity.ProductSyncReport{}, fmt.Errorf("begin the price sync transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, item := range items {
+		var current entity.Product
+
+		err = tx.QueryRow("SELECT id_product, nominal, price FROM mst_product WHERE code = $1 FOR UPDATE", item.Code).Scan(&current.IdProduct, &current.Nominal, &current.Price)
+		if err == sql.ErrNoRows {
+			var idProvider, idProduct string
+
+			err = tx.QueryRow("SELECT id_provider FROM mst_provider WHERE LOWER(name_provider) = LOWER($1)", item.Provider).Scan(&idProvider)
+			if err == sql.ErrNoRows {
+				err = tx.QueryRow("INSERT INTO mst_provider (name_provider) VALUES ($1) RETURNING id_provider", item.Provider).Scan(&idProvider)
+			}
+			if err != nil {
+				p.log.Error("Failed to resolve the provider of a synced product: ", err)
+				return entity.ProductSyncReport{}, fmt.Errorf("resolve the provider of a synced product: %w", err)
+			}
+
+			err = tx.QueryRow("INSERT INTO mst_product (id_provider, nominal, price, id_supliyer, code, status) VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5, $6) RETURNING id_product",
+				idProvider, item.Nominal, item.Price, supplierId, item.Code, entity.ProductStatusDraft).Scan(&idProduct)
+			if err != nil {
+				p.log.Error("Failed to create the synced product: ", err)
+				return entity.ProductSyncReport{}, fmt.Errorf("create the synced product: %w", err)
+			}
+
+			report.Created = append(report.Created, entity.ProductSyncItem{Code: item.Code, IdProduct: idProduct})
+			continue
+		}
+		if err != nil {
+			p.log.Error("Failed to retrive the synced product: ", err)
+			return entity.ProductSyncReport{}, fmt.Errorf("retrieve the synced product: %w", err)
+		}
+
+		if current.Nominal == item.Nominal && current.Price == item.Price {
+			report.Unchanged = append(report.Unchanged, entity.ProductSyncItem{Code: item.Code, IdProduct: current.IdProduct})
+			continue
+		}
+
+		_, err = tx.Exec("UPDATE mst_product SET nominal = $1, price = $2, version = version + 1, updated_at = NOW() WHERE id_product = $3", item.Nominal, item.Price, current.IdProduct)
+		if err != nil {
+			p.log.Error("Failed to update the synced product: ", err)
+			return entity.ProductSyncReport{}, fmt.Errorf("update the synced product: %w", err)
+		}
+
+		_, err = tx.Exec("INSERT INTO product_price_history (id_product, old_nominal, old_price, new_nominal, new_price, source) VALUES ($1, $2, $3, $4, $5, $6)",
+			current.IdProduct, current.Nominal, current.Price, item.Nominal, item.Price, "supplier_sync")
+		if err != nil {
+			p.log.Error("Failed to record the product price history: ", err)
+			return entity.ProductSyncReport{}, fmt.Errorf("record the product price history: %w", err)
+		}
+
+		report.Updated = append(report.Updated, entity.ProductSyncItem{Code: item.Code, IdProduct: current.IdProduct})
+	}
+
+	if err = tx.Commit(); err != nil {
+		p.log.Error("Failed to commit the price sync transaction: ", err)
+		return entity.ProductSyncReport{}, fmt.Errorf("commit the price sync transaction: %w", err)
+	}
+
+	p.log.Info("Product prices have been synced successfully: ", report)
+	return report, nil
+}
+
 func NewProductRepository(db *sql.DB, log *logger.Logger) ProductRepository {
 	return &productRepository{db: db, log: log}
 }
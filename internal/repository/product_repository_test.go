@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -37,51 +38,127 @@ func (p *productRepoTestSuite) TearDownTest() {
 
 func (p *productRepoTestSuite) TestCreateProduct_Repository() {
 	product := entity.Product{
-		NameProvider: "Provider A",
-		Nominal:      10000,
-		Price:        12000,
-		IdSupliyer:   "Supplier A",
+		IdProvider: "provider-a",
+		Nominal:    10000,
+		Price:      12000,
+		IdSupliyer: "Supplier A",
 	}
 
-	query := "INSERT INTO mst_product (name_provider, nominal, price, id_supliyer) VALUES ($1, $2, $3, $4) RETURNING id_product"
+	query := "INSERT INTO mst_product (id_provider, nominal, price, id_supliyer, code, id_merchant) VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, '')::uuid) RETURNING id_product, version, status, (SELECT name_provider FROM mst_provider WHERE id_provider = $1)"
 
-	p.mockSql.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(product.NameProvider, product.Nominal, product.Price, product.IdSupliyer).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	p.mockSql.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(product.IdProvider, product.Nominal, product.Price, product.IdSupliyer, product.Code, product.IdMerchant).WillReturnRows(sqlmock.NewRows([]string{"id", "version", "status", "name_provider"}).AddRow(1, 1, "active", "Provider A"))
 
 	createdProduct, err := p.productRepo.Create(product)
 
 	p.Nil(err)
 	p.Equal("1", createdProduct.IdProduct)
-	p.Equal(product.NameProvider, createdProduct.NameProvider)
+	p.Equal("provider-a", createdProduct.IdProvider)
+	p.Equal(1, createdProduct.Version)
+	p.Equal("Provider A", createdProduct.NameProvider)
 	p.Equal(product.Nominal, createdProduct.Nominal)
 	p.Equal(product.Price, createdProduct.Price)
 	p.Equal(product.IdSupliyer, createdProduct.IdSupliyer)
 }
 
+func (p *productRepoTestSuite) TestCreateProduct_DuplicateCode() {
+	product := entity.Product{IdProvider: "provider-a", Nominal: 10000, Price: 12000, IdSupliyer: "Supplier A", Code: "TSEL10"}
+
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_product")).
+		WithArgs(product.IdProvider, product.Nominal, product.Price, product.IdSupliyer, product.Code, product.IdMerchant).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "mst_product_code_key"})
+
+	_, err := p.productRepo.Create(product)
+
+	p.ErrorIs(err, ErrProductCodeTaken)
+}
+
+func (p *productRepoTestSuite) TestGetProductByCode_Repository() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("FROM mst_product p JOIN mst_provider pv ON p.id_provider = pv.id_provider WHERE p.code = $1")).
+		WithArgs("TSEL10").
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "id_provider", "name_provider", "nominal", "price", "id_supliyer", "version", "code", "status", "id_merchant"}).
+			AddRow("1", "provider-a", "Telkomsel", 10000, 10900, "supplier-a", 2, "TSEL10", "active", ""))
+
+	product, err := p.productRepo.GetByCode("TSEL10")
+
+	p.Nil(err)
+	p.Equal("1", product.IdProduct)
+	p.Equal("TSEL10", product.Code)
+	p.Equal("Telkomsel", product.NameProvider)
+}
+
+func (p *productRepoTestSuite) TestGetProductByCode_NotFound() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("WHERE p.code = $1")).WithArgs("NOPE").WillReturnError(sql.ErrNoRows)
+
+	_, err := p.productRepo.GetByCode("NOPE")
+
+	p.ErrorIs(err, ErrProductNotFound)
+}
+
+func (p *productRepoTestSuite) TestUpdateProduct_DuplicateCode() {
+	product := entity.Product{IdProduct: "1", IdProvider: "provider-a", Nominal: 10000, Price: 12000, IdSupliyer: "Supplier A", Version: 1, Code: "TSEL10"}
+
+	p.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_product SET")).WillReturnError(&pq.Error{Code: "23505", Constraint: "mst_product_code_key"})
+
+	_, err := p.productRepo.Update(product)
+
+	p.ErrorIs(err, ErrProductCodeTaken)
+}
+
+func (p *productRepoTestSuite) TestGetProductsByIds_AllFound() {
+	ids := []string{"uuid-product-1", "uuid-product-2"}
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("WHERE p.id_product = ANY($1)")).
+		WithArgs(pq.Array(ids)).
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "id_provider", "name_provider", "nominal", "price", "id_supliyer", "version", "code", "status", "id_merchant"}).
+			AddRow("uuid-product-1", "provider-a", "Telkomsel", 10000, 10900, "supplier-a", 1, "TSEL10", "active", "").
+			AddRow("uuid-product-2", "provider-a", "Telkomsel", 20000, 20900, "supplier-a", 1, "TSEL20", "active", ""))
+
+	products, err := p.productRepo.GetByIds(ids)
+
+	p.Nil(err)
+	p.Len(products, 2)
+	p.Equal("uuid-product-1", products[0].IdProduct)
+	p.Equal("TSEL20", products[1].Code)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
 func (p *productRepoTestSuite) TestGetProductById_Repository() {
 	id := "1"
 
-	query := "SELECT id_product, name_provider, nominal, price, id_supliyer FROM mst_product WHERE id_product = $1"
+	query := "SELECT p.id_product, p.id_provider, pv.name_provider, p.nominal, p.price, p.id_supliyer, p.version, COALESCE(p.code, ''), p.status, COALESCE(p.id_merchant::text, '') FROM mst_product p JOIN mst_provider pv ON p.id_provider = pv.id_provider WHERE p.id_product = $1"
 
-	p.mockSql.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"id_product", "name_provider", "nominal", "price", "id_supliyer"}).AddRow(id, "Provider A", 10000, 12000, "Supplier A"))
+	p.mockSql.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"id_product", "id_provider", "name_provider", "nominal", "price", "id_supliyer", "version", "code", "status", "id_merchant"}).AddRow(id, "provider-a", "Provider A", 10000, 12000, "Supplier A", 3, "AXIS10", "active", ""))
 
 	product, err := p.productRepo.Get(id)
 
 	p.Nil(err)
 	p.Equal("1", product.IdProduct)
+	p.Equal("provider-a", product.IdProvider)
 	p.Equal("Provider A", product.NameProvider)
 	p.Equal(float64(10000), product.Nominal)
 	p.Equal(float64(12000), product.Price)
 	p.Equal("Supplier A", product.IdSupliyer)
+	p.Equal(3, product.Version)
+	p.Equal("AXIS10", product.Code)
+	p.Equal("active", product.Status)
+}
+
+func (p *productRepoTestSuite) TestGetProductById_NotFoundKeepsErrNoRows() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("WHERE p.id_product = $1")).WithArgs("missing").WillReturnError(sql.ErrNoRows)
+
+	_, err := p.productRepo.Get("missing")
+
+	p.ErrorIs(err, sql.ErrNoRows)
+	p.ErrorContains(err, "retrieve the product")
 }
 
 func (p *productRepoTestSuite) TestFindAllProduct_Repository() {
-	query := "SELECT id_product, name_provider, nominal, price, id_supliyer FROM mst_product"
+	query := "SELECT p.id_product, p.id_provider, pv.name_provider, p.nominal, p.price, p.id_supliyer, p.version, COALESCE(p.code, '') AS code, p.status, COALESCE(p.id_merchant::text, '') AS id_merchant FROM mst_product p JOIN mst_provider pv ON p.id_provider = pv.id_provider ORDER BY pv.name_provider ASC, p.nominal ASC, p.id_product"
 
-	p.mockSql.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id_product", "name_provider", "nominal", "price", "id_supliyer"}).
-		AddRow("1", "Provider A", 10000, 12000, "Supplier A").
-		AddRow("2", "Provider B", 20000, 24000, "Supplier B"))
+	p.mockSql.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id_product", "id_provider", "name_provider", "nominal", "price", "id_supliyer", "version", "code", "status", "id_merchant"}).
+		AddRow("1", "provider-a", "Provider A", 10000, 12000, "Supplier A", 1, "", "active", "").
+		AddRow("2", "provider-b", "Provider B", 20000, 24000, "Supplier B", 1, "", "draft", ""))
 
-	products, err := p.productRepo.List()
+	products, err := p.productRepo.List(entity.ProductQuery{})
 
 	p.Nil(err)
 	p.Len(products, 2)
@@ -97,18 +174,65 @@ func (p *productRepoTestSuite) TestFindAllProduct_Repository() {
 	p.Equal("Supplier B", products[1].IdSupliyer)
 }
 
+func (p *productRepoTestSuite) TestFindAllProduct_SortedPage() {
+	query := "ORDER BY p.updated_at DESC, p.id_product LIMIT $1 OFFSET $2"
+
+	p.mockSql.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(20, 40).
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "id_provider", "name_provider", "nominal", "price", "id_supliyer", "version", "code", "status", "id_merchant"}))
+
+	_, err := p.productRepo.List(entity.ProductQuery{Sort: "updated_at", Order: "desc", Page: 3, Limit: 20})
+
+	p.Nil(err)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+func (p *productRepoTestSuite) TestFindAllProduct_MerchantScoped() {
+	query := "WHERE (p.id_merchant IS NULL OR p.id_merchant IN (SELECT id_merchant FROM mst_merchant WHERE id_user = $1)) ORDER BY pv.name_provider ASC, p.nominal ASC, p.id_product LIMIT $2 OFFSET $3"
+
+	p.mockSql.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("user-a", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "id_provider", "name_provider", "nominal", "price", "id_supliyer", "version", "code", "status", "id_merchant"}).
+			AddRow("1", "provider-a", "Provider A", 10000, 12000, "Supplier A", 1, "", "active", "").
+			AddRow("2", "provider-a", "Provider A", 20000, 21000, "Supplier A", 1, "", "active", "merchant-a"))
+
+	products, err := p.productRepo.List(entity.ProductQuery{Limit: 10, UserId: "user-a"})
+
+	p.Nil(err)
+	p.Len(products, 2)
+	p.Equal("", products[0].IdMerchant)
+	p.Equal("merchant-a", products[1].IdMerchant)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+func (p *productRepoTestSuite) TestFindAllProduct_InvalidSort() {
+	_, err := p.productRepo.List(entity.ProductQuery{Sort: "price; DROP TABLE mst_product"})
+
+	p.ErrorIs(err, ErrInvalidProductSort)
+}
+
+func (p *productRepoTestSuite) TestListPublicCatalog_ActiveOnly() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("WHERE p.status = $1 AND p.id_merchant IS NULL")).WithArgs(entity.ProductStatusActive).
+		WillReturnRows(sqlmock.NewRows([]string{"code", "name_provider", "category", "nominal", "price"}).AddRow("TSEL10", "Telkomsel", "pulsa", 10000, 10900))
+
+	products, err := p.productRepo.ListPublicCatalog()
+
+	p.Nil(err)
+	p.Equal([]entity.PublicProduct{{Code: "TSEL10", Provider: "Telkomsel", Category: "pulsa", Nominal: 10000, Price: 10900}}, products)
+}
+
 func (p *productRepoTestSuite) TestUpdateProduct_Repository() {
 	product := entity.Product{
 		IdProduct:    "1",
+		IdProvider:   "provider-a",
 		NameProvider: "Provider A",
 		Nominal:      10000,
 		Price:        12000,
 		IdSupliyer:   "Supplier A",
+		Version:      1,
 	}
 
-	query := "UPDATE mst_product SET name_provider = $1, nominal = $2, price = $3, id_supliyer = $4 WHERE id_product = $5"
+	query := "UPDATE mst_product SET id_provider = $1, nominal = $2, price = $3, id_supliyer = $4, code = COALESCE(NULLIF($7, ''), code), version = version + 1, updated_at = NOW() WHERE id_product = $5 AND version = $6"
 
-	p.mockSql.ExpectExec(regexp.QuoteMeta(query)).WithArgs(product.NameProvider, product.Nominal, product.Price, product.IdSupliyer, product.IdProduct).WillReturnResult(sqlmock.NewResult(1, 1))
+	p.mockSql.ExpectExec(regexp.QuoteMeta(query)).WithArgs(product.IdProvider, product.Nominal, product.Price, product.IdSupliyer, product.IdProduct, product.Version, product.Code).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	updatedProduct, err := p.productRepo.Update(product)
 
@@ -118,6 +242,19 @@ func (p *productRepoTestSuite) TestUpdateProduct_Repository() {
 	p.Equal(float64(10000), updatedProduct.Nominal)
 	p.Equal(float64(12000), updatedProduct.Price)
 	p.Equal("Supplier A", updatedProduct.IdSupliyer)
+	p.Equal(2, updatedProduct.Version)
+}
+
+func (p *productRepoTestSuite) TestUpdateProduct_StaleVersion() {
+	product := entity.Product{IdProduct: "1", IdProvider: "provider-a", Nominal: 10000, Price: 12000, IdSupliyer: "Supplier A", Version: 1}
+
+	query := "UPDATE mst_product SET id_provider = $1, nominal = $2, price = $3, id_supliyer = $4, code = COALESCE(NULLIF($7, ''), code), version = version + 1, updated_at = NOW() WHERE id_product = $5 AND version = $6"
+
+	p.mockSql.ExpectExec(regexp.QuoteMeta(query)).WithArgs(product.IdProvider, product.Nominal, product.Price, product.IdSupliyer, product.IdProduct, product.Version, product.Code).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err := p.productRepo.Update(product)
+
+	p.ErrorIs(err, ErrProductVersionConflict)
 }
 
 func (p *productRepoTestSuite) TestDeleteProduct_Repository() {
@@ -132,6 +269,91 @@ func (p *productRepoTestSuite) TestDeleteProduct_Repository() {
 	p.Nil(err)
 }
 
+func (p *productRepoTestSuite) TestDeleteProduct_InUse() {
+	id := "1"
+
+	p.mockSql.ExpectExec(regexp.QuoteMeta("DELETE FROM mst_product WHERE id_product = $1")).WithArgs(id).WillReturnError(&pq.Error{Code: "23503"})
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM transaction_detail WHERE id_product = $1")).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	err := p.productRepo.Delete(id)
+
+	var inUse *ErrProductInUse
+	p.ErrorAs(err, &inUse)
+	p.Equal(3, inUse.Count)
+	p.Contains(err.Error(), "deactivate")
+}
+
+func (p *productRepoTestSuite) TestSyncPrices_CreatesAndUpdates() {
+	items := []entity.SupplierPriceItem{
+		{Code: "TSEL10", Provider: "Telkomsel", Nominal: 10000, Price: 10800},
+		{Code: "AXIS5", Provider: "Axis", Nominal: 5000, Price: 5600},
+		{Code: "XL10", Provider: "Xl", Nominal: 10000, Price: 10700},
+	}
+
+	p.mockSql.ExpectBegin()
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_product, nominal, price FROM mst_product WHERE code = $1 FOR UPDATE")).WithArgs("TSEL10").
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "nominal", "price"}).AddRow("1", 10000, 10900))
+	p.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_product SET nominal = $1, price = $2, version = version + 1, updated_at = NOW() WHERE id_product = $3")).
+		WithArgs(10000.0, 10800.0, "1").WillReturnResult(sqlmock.NewResult(0, 1))
+	p.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO product_price_history")).
+		WithArgs("1", 10000.0, 10900.0, 10000.0, 10800.0, "supplier_sync").WillReturnResult(sqlmock.NewResult(0, 1))
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_product, nominal, price FROM mst_product WHERE code = $1 FOR UPDATE")).WithArgs("AXIS5").
+		WillReturnError(sql.ErrNoRows)
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_provider FROM mst_provider WHERE LOWER(name_provider) = LOWER($1)")).WithArgs("Axis").
+		WillReturnRows(sqlmock.NewRows([]string{"id_provider"}).AddRow("provider-axis"))
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_product (id_provider, nominal, price, id_supliyer, code, status)")).
+		WithArgs("provider-axis", 5000.0, 5600.0, "supplier-1", "AXIS5", entity.ProductStatusDraft).
+		WillReturnRows(sqlmock.NewRows([]string{"id_product"}).AddRow("2"))
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_product, nominal, price FROM mst_product WHERE code = $1 FOR UPDATE")).WithArgs("XL10").
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "nominal", "price"}).AddRow("3", 10000, 10700))
+	p.mockSql.ExpectCommit()
+
+	report, err := p.productRepo.SyncPrices(items, "supplier-1")
+
+	p.Nil(err)
+	p.Equal([]entity.ProductSyncItem{{Code: "TSEL10", IdProduct: "1"}}, report.Updated)
+	p.Equal([]entity.ProductSyncItem{{Code: "AXIS5", IdProduct: "2"}}, report.Created)
+	p.Equal([]entity.ProductSyncItem{{Code: "XL10", IdProduct: "3"}}, report.Unchanged)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+func (p *productRepoTestSuite) TestSyncPrices_RollbackOnError() {
+	items := []entity.SupplierPriceItem{{Code: "TSEL10", Provider: "Telkomsel", Nominal: 10000, Price: 10800}}
+
+	p.mockSql.ExpectBegin()
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_product, nominal, price FROM mst_product WHERE code = $1 FOR UPDATE")).WithArgs("TSEL10").
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "nominal", "price"}).AddRow("1", 10000, 10900))
+	p.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_product SET nominal = $1")).WillReturnError(sql.ErrConnDone)
+	p.mockSql.ExpectRollback()
+
+	_, err := p.productRepo.SyncPrices(items, "supplier-1")
+
+	p.ErrorIs(err, sql.ErrConnDone)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+func (p *productRepoTestSuite) TestSyncPrices_NewCodeWithoutSupplier() {
+	items := []entity.SupplierPriceItem{{Code: "AXIS5", Provider: "Axis", Nominal: 5000, Price: 5600}}
+
+	p.mockSql.ExpectBegin()
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_product, nominal, price FROM mst_product WHERE code = $1 FOR UPDATE")).WithArgs("AXIS5").
+		WillReturnError(sql.ErrNoRows)
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_provider FROM mst_provider WHERE LOWER(name_provider) = LOWER($1)")).WithArgs("Axis").
+		WillReturnRows(sqlmock.NewRows([]string{"id_provider"}).AddRow("provider-axis"))
+	// an empty supplier is stored as NULL, Postgres refuses '' for the uuid column
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_product (id_provider, nominal, price, id_supliyer, code, status) VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5, $6)")).
+		WithArgs("provider-axis", 5000.0, 5600.0, "", "AXIS5", entity.ProductStatusDraft).
+		WillReturnRows(sqlmock.NewRows([]string{"id_product"}).AddRow("2"))
+	p.mockSql.ExpectCommit()
+
+	report, err := p.productRepo.SyncPrices(items, "")
+
+	p.Nil(err)
+	p.Equal([]entity.ProductSyncItem{{Code: "AXIS5", IdProduct: "2"}}, report.Created)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
 func TestProductRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(productRepoTestSuite))
 }
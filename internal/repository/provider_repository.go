@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrProviderExists is returned when another provider already uses the same name.
+	ErrProviderExists = errors.New("provider with the same name already exists")
+	// ErrProviderInUse is returned by Delete when products still reference the provider.
+	ErrProviderInUse = errors.New("provider is used by products and cannot be deleted")
+)
+
+// mapProviderError translates constraint violations into the provider sentinel errors.
+func mapProviderError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return ErrProviderExists
+		case "23503":
+			return ErrProviderInUse
+		}
+	}
+	return err
+}
+
+type ProviderRepository interface {
+	Create(provider entity.Provider) (entity.Provider, error)
+	List() ([]entity.Provider, error)
+	Get(id string) (entity.Provider, error)
+	Update(provider entity.Provider) (entity.Provider, error)
+	Delete(id string) error
+	FindOrCreateByName(name string) (entity.Provider, error)
+}
+
+type providerRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func (p *providerRepository) Create(provider entity.Provider) (entity.Provider, error) {
+	p.log.Info("Starting to create a new provider in the repository layer", nil)
+
+	err := p.db.QueryRow("INSERT INTO mst_provider (name_provider) VALUES ($1) RETURNING id_provider", provider.NameProvider).Scan(&provider.IdProvider)
+	if err != nil {
+		p.log.Error("Failed to create the provider: ", err)
+		return entity.Provider{}, mapProviderError(err)
+	}
+
+	p.log.Info("Provider has been created successfully: ", provider)
+	return provider, nil
+}
+
+func (p *providerRepository) List() ([]entity.Provider, error) {
+	var providers []entity.Provider
+
+	p.log.Info("Starting to retrive all provider in the repository layer", nil)
+
+	rows, err := p.db.Query("SELECT id_provider, name_provider FROM mst_provider ORDER BY name_provider")
+	if err != nil {
+		p.log.Error("Failed to retrive the provider: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var provider entity.Provider
+		if err := rows.Scan(&provider.IdProvider, &provider.NameProvider); err != nil {
+			p.log.Error("Failed to scan the provider: ", err)
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	p.log.Info("Getting all provider was successfully: ", providers)
+	return providers, nil
+}
+
+func (p *providerRepository) Get(id string) (entity.Provider, error) {
+	var provider entity.Provider
+
+	p.log.Info("Starting to retrive a provider by id in the repository layer", nil)
+
+	err := p.db.QueryRow("SELECT id_provider, name_provider FROM mst_provider WHERE id_provider = $1", id).Scan(&provider.IdProvider, &provider.NameProvider)
+	if err != nil {
+		p.log.Error("Failed to retrive the provider: ", err)
+		return entity.Provider{}, err
+	}
+
+	p.log.Info("Getting provider by id was successfully: ", provider)
+	return provider, nil
+}
+
+func (p *providerRepository) Update(provider entity.Provider) (entity.Provider, error) {
+	p.log.Info("Starting to update provider in the repository layer", nil)
+
+	_, err := p.db.Exec("UPDATE mst_provider SET name_provider = $1 WHERE id_provider = $2", provider.NameProvider, provider.IdProvider)
+	if err != nil {
+		p.log.Error("Failed to update the provider: ", err)
+		return entity.Provider{}, mapProviderError(err)
+	}
+
+	p.log.Info("Provider has been updated successfully: ", provider)
+	return provider, nil
+}
+
+func (p *providerRepository) Delete(id string) error {
+	p.log.Info("Starting to delete provider in the repository layer", nil)
+
+	_, err := p.db.Exec("DELETE FROM mst_provider WHERE id_provider = $1", id)
+	if err != nil {
+		p.log.Error("Failed to delete the provider: ", err)
+		return mapProviderError(err)
+	}
+
+	p.log.Info("Provider has been deleted successfully: ", id)
+	return nil
+}
+
+// FindOrCreateByName is used by imports that only know the provider by name, matching case-insensitively
+// so "telkomsel" and "Telkomsel" resolve to the same provider.
+func (p *providerRepository) FindOrCreateByName(name string) (entity.Provider, error) {
+	var provider entity.Provider
+
+	p.log.Info("Starting to resolve a provider by name in the repository layer", nil)
+
+	err := p.db.QueryRow("SELECT id_provider, name_provider FROM mst_provider WHERE LOWER(name_provider) = LOWER($1)", name).Scan(&provider.IdProvider, &provider.NameProvider)
+	if err == sql.ErrNoRows {
+		return p.Create(entity.Provider{NameProvider: name})
+	}
+	if err != nil {
+		p.log.Error("Failed to resolve the provider: ", err)
+		return entity.Provider{}, err
+	}
+
+	p.log.Info("Provider has been resolved successfully: ", provider)
+	return provider, nil
+}
+
+func NewProviderRepository(db *sql.DB, log *logger.Logger) ProviderRepository {
+	return &providerRepository{db: db, log: log}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/suite"
+)
+
+type providerRepoTestSuite struct {
+	suite.Suite
+	mockDB       *sql.DB
+	mockSql      sqlmock.Sqlmock
+	providerRepo ProviderRepository
+	log          logger.Logger
+}
+
+func (p *providerRepoTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	if err != nil {
+		p.T().Fatalf("an error '%s' occurred when opening a mock database connection", err)
+	}
+
+	p.mockDB = mockDb
+	p.mockSql = mockSql
+	p.log = logger.NewLogger()
+	p.providerRepo = NewProviderRepository(p.mockDB, &p.log)
+}
+
+func (p *providerRepoTestSuite) TearDownTest() {
+	p.mockDB.Close()
+}
+
+func (p *providerRepoTestSuite) TestCreateProvider_Repository() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_provider (name_provider) VALUES ($1) RETURNING id_provider")).WithArgs("Telkomsel").
+		WillReturnRows(sqlmock.NewRows([]string{"id_provider"}).AddRow("1"))
+
+	provider, err := p.providerRepo.Create(entity.Provider{NameProvider: "Telkomsel"})
+
+	p.Nil(err)
+	p.Equal(entity.Provider{IdProvider: "1", NameProvider: "Telkomsel"}, provider)
+}
+
+func (p *providerRepoTestSuite) TestCreateProvider_Duplicate() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_provider (name_provider) VALUES ($1) RETURNING id_provider")).WithArgs("Telkomsel").
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	_, err := p.providerRepo.Create(entity.Provider{NameProvider: "Telkomsel"})
+
+	p.ErrorIs(err, ErrProviderExists)
+}
+
+func (p *providerRepoTestSuite) TestDeleteProvider_InUse() {
+	p.mockSql.ExpectExec(regexp.QuoteMeta("DELETE FROM mst_provider WHERE id_provider = $1")).WithArgs("1").
+		WillReturnError(&pq.Error{Code: "23503"})
+
+	err := p.providerRepo.Delete("1")
+
+	p.ErrorIs(err, ErrProviderInUse)
+}
+
+func (p *providerRepoTestSuite) TestFindOrCreateByName_Existing() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_provider, name_provider FROM mst_provider WHERE LOWER(name_provider) = LOWER($1)")).WithArgs("telkomsel").
+		WillReturnRows(sqlmock.NewRows([]string{"id_provider", "name_provider"}).AddRow("1", "Telkomsel"))
+
+	provider, err := p.providerRepo.FindOrCreateByName("telkomsel")
+
+	p.Nil(err)
+	p.Equal(entity.Provider{IdProvider: "1", NameProvider: "Telkomsel"}, provider)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+func (p *providerRepoTestSuite) TestFindOrCreateByName_CreatesMissing() {
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_provider, name_provider FROM mst_provider WHERE LOWER(name_provider) = LOWER($1)")).WithArgs("Smartfren").
+		WillReturnError(sql.ErrNoRows)
+	p.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_provider (name_provider) VALUES ($1) RETURNING id_provider")).WithArgs("Smartfren").
+		WillReturnRows(sqlmock.NewRows([]string{"id_provider"}).AddRow("2"))
+
+	provider, err := p.providerRepo.FindOrCreateByName("Smartfren")
+
+	p.Nil(err)
+	p.Equal(entity.Provider{IdProvider: "2", NameProvider: "Smartfren"}, provider)
+	p.Nil(p.mockSql.ExpectationsWereMet())
+}
+
+func TestProviderRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(providerRepoTestSuite))
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimitRepository counts the requests of a key (a user or a client IP) in fixed windows. The in-memory
+// implementation only works for a single instance, a shared store such as Redis can implement the same interface
+// with INCR and an expiry of window.
+type RateLimitRepository interface {
+	// Hit counts a request of key at at and returns the requests of key in the current window and when it ends.
+	Hit(key string, window time.Duration, at time.Time) (int, time.Time, error)
+}
+
+type rateLimitWindow struct {
+	count int
+	endAt time.Time
+}
+
+type memoryRateLimitRepository struct {
+	mu      sync.Mutex
+	windows map[string]*rateLimitWindow
+}
+
+func (m *memoryRateLimitRepository) Hit(key string, window time.Duration, at time.Time) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.windows[key]
+	if !ok || !at.Before(w.endAt) {
+		if len(m.windows) > 10000 {
+			m.evict(at)
+		}
+		w = &rateLimitWindow{endAt: at.Add(window)}
+		m.windows[key] = w
+	}
+
+	w.count++
+	return w.count, w.endAt, nil
+}
+
+// evict drops the windows that have ended, so the map does not grow with every client ever seen.
+func (m *memoryRateLimitRepository) evict(now time.Time) {
+	for key, w := range m.windows {
+		if !now.Before(w.endAt) {
+			delete(m.windows, key)
+		}
+	}
+}
+
+// NewMemoryRateLimitRepository counts requests in process memory.
+func NewMemoryRateLimitRepository() RateLimitRepository {
+	return &memoryRateLimitRepository{windows: make(map[string]*rateLimitWindow)}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRateLimitRepository_FixedWindow(t *testing.T) {
+	repo := NewMemoryRateLimitRepository()
+	now := time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	count, endAt, err := repo.Hit("user:eko", time.Minute, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, now.Add(time.Minute), endAt)
+
+	count, endAt, _ = repo.Hit("user:eko", time.Minute, now.Add(59*time.Second))
+	assert.Equal(t, 2, count)
+	assert.Equal(t, now.Add(time.Minute), endAt)
+
+	count, _, _ = repo.Hit("ip:10.0.0.1", time.Minute, now.Add(59*time.Second))
+	assert.Equal(t, 1, count, "every key has its own window")
+
+	count, endAt, _ = repo.Hit("user:eko", time.Minute, now.Add(time.Minute))
+	assert.Equal(t, 1, count, "a new window starts once the last one ended")
+	assert.Equal(t, now.Add(2*time.Minute), endAt)
+}
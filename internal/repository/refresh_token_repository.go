@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"server-pulsa-app/internal/logger"
+	"time"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired and revoked refresh tokens.
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again. The whole token
+	// family is revoked at that point, so the user has to login again.
+	ErrRefreshTokenReused = errors.New("refresh token has already been used, please login again")
+)
+
+type RefreshTokenRepository interface {
+	Create(userId, sessionId, tokenHash string, expiresAt time.Time) error
+	Rotate(tokenHash, newTokenHash string, expiresAt time.Time) (string, string, error)
+	RevokeFamily(tokenHash string) error
+	IsRememberMe(tokenHash string) (bool, error)
+}
+
+type refreshTokenRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// Create stores the first refresh token of a login, it starts a new token family named after the session.
+func (r *refreshTokenRepository) Create(userId, sessionId, tokenHash string, expiresAt time.Time) error {
+	r.log.Info("Starting to create a refresh token in the repository layer", nil)
+
+	_, err := r.db.Exec("INSERT INTO refresh_token (token_hash, id_user, family_id, expires_at) VALUES ($1, $2, $3, $4)", tokenHash, userId, sessionId, expiresAt)
+	if err != nil {
+		r.log.Error("Failed to create the refresh token: ", err)
+		return err
+	}
+
+	r.log.Info("Refresh token has been created successfully", userId)
+	return nil
+}
+
+// Rotate marks the refresh token as used and stores its successor in the same family, in one transaction.
+// Presenting a token that was already rotated revokes every token of its family. It returns the id of the user and
+// of the session, whose last use is updated in the same transaction.
+func (r *refreshTokenRepository) Rotate(tokenHash, newTokenHash string, expiresAt time.Time) (string, string, error) {
+	r.log.Info("Starting to rotate a refresh token in the repository layer", nil)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.log.Error("Failed to begin the refresh token transaction: ", err)
+		return "", "", err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var userId, familyId string
+	err = tx.QueryRow(`UPDATE refresh_token SET rotated_at = NOW()
+		WHERE token_hash = $1 AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING id_user, family_id`, tokenHash).Scan(&userId, &familyId)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRow("SELECT family_id FROM refresh_token WHERE token_hash = $1 AND rotated_at IS NOT NULL", tokenHash).Scan(&familyId)
+		if err == sql.ErrNoRows {
+			err = ErrInvalidRefreshToken
+		}
+		if err != nil {
+			r.log.Error("Failed to rotate the refresh token: ", err)
+			return "", "", err
+		}
+
+		_, err = tx.Exec("UPDATE refresh_token SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", familyId)
+		if err != nil {
+			r.log.Error("Failed to revoke the refresh token family: ", err)
+			return "", "", err
+		}
+
+		if err = tx.Commit(); err != nil {
+			r.log.Error("Failed to commit the refresh token revocation: ", err)
+			return "", "", err
+		}
+
+		r.log.Error("Refresh token reuse detected, token family has been revoked: ", familyId)
+		return "", "", ErrRefreshTokenReused
+	}
+	if err != nil {
+		r.log.Error("Failed to rotate the refresh token: ", err)
+		return "", "", err
+	}
+
+	_, err = tx.Exec("INSERT INTO refresh_token (token_hash, id_user, family_id, expires_at) VALUES ($1, $2, $3, $4)", newTokenHash, userId, familyId, expiresAt)
+	if err != nil {
+		r.log.Error("Failed to store the rotated refresh token: ", err)
+		return "", "", err
+	}
+
+	_, err = tx.Exec("UPDATE user_session SET last_used_at = NOW() WHERE id_session = $1", familyId)
+	if err != nil {
+		r.log.Error("Failed to update the last use of the session: ", err)
+		return "", "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("Failed to commit the refresh token transaction: ", err)
+		return "", "", err
+	}
+
+	r.log.Info("Refresh token has been rotated successfully", userId)
+	return userId, familyId, nil
+}
+
+// RevokeFamily revokes the refresh token and every other token issued from the same login.
+func (r *refreshTokenRepository) RevokeFamily(tokenHash string) error {
+	r.log.Info("Starting to revoke a refresh token family in the repository layer", nil)
+
+	_, err := r.db.Exec(`UPDATE refresh_token SET revoked_at = NOW()
+		WHERE family_id = (SELECT family_id FROM refresh_token WHERE token_hash = $1) AND revoked_at IS NULL`, tokenHash)
+	if err != nil {
+		r.log.Error("Failed to revoke the refresh token family: ", err)
+		return err
+	}
+
+	r.log.Info("Refresh token family has been revoked successfully", nil)
+	return nil
+}
+
+// IsRememberMe reports whether the login the refresh token belongs to asked for remember me, so its successor gets
+// the same lifetime. Unknown tokens are not, Rotate refuses them anyway.
+func (r *refreshTokenRepository) IsRememberMe(tokenHash string) (bool, error) {
+	var rememberMe bool
+
+	err := r.db.QueryRow(`SELECT s.remember_me FROM refresh_token t JOIN user_session s ON s.id_session = t.family_id
+		WHERE t.token_hash = $1`, tokenHash).Scan(&rememberMe)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		r.log.Error("Failed to check the remember me of the refresh token: ", err)
+		return false, err
+	}
+
+	return rememberMe, nil
+}
+
+func NewRefreshTokenRepository(db *sql.DB, log *logger.Logger) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db, log: log}
+}
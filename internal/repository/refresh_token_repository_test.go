@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type refreshTokenRepositoryTestSuite struct {
+	suite.Suite
+	mockDb  *sql.DB
+	mockSql sqlmock.Sqlmock
+	repo    RefreshTokenRepository
+	log     logger.Logger
+}
+
+func TestRefreshTokenRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(refreshTokenRepositoryTestSuite))
+}
+
+func (r *refreshTokenRepositoryTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	r.NoError(err)
+
+	r.mockDb = mockDb
+	r.mockSql = mockSql
+	r.log = logger.NewLogger()
+	r.repo = NewRefreshTokenRepository(mockDb, &r.log)
+}
+
+func (r *refreshTokenRepositoryTestSuite) TestCreate() {
+	expiresAt := time.Now().Add(24 * time.Hour)
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO refresh_token (token_hash, id_user, family_id, expires_at) VALUES ($1, $2, $3, $4)")).
+		WithArgs("token-hash", "uuid-user", "uuid-session", expiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r.Nil(r.repo.Create("uuid-user", "uuid-session", "token-hash", expiresAt))
+	r.Nil(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *refreshTokenRepositoryTestSuite) TestRotate_Success() {
+	expiresAt := time.Now().Add(24 * time.Hour)
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE refresh_token SET rotated_at = NOW()")).
+		WithArgs("token-hash").WillReturnRows(sqlmock.NewRows([]string{"id_user", "family_id"}).AddRow("uuid-user", "family-a"))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO refresh_token (token_hash, id_user, family_id, expires_at) VALUES ($1, $2, $3, $4)")).
+		WithArgs("new-token-hash", "uuid-user", "family-a", expiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE user_session SET last_used_at = NOW() WHERE id_session = $1")).
+		WithArgs("family-a").WillReturnResult(sqlmock.NewResult(0, 1))
+	r.mockSql.ExpectCommit()
+
+	userId, sessionId, err := r.repo.Rotate("token-hash", "new-token-hash", expiresAt)
+
+	r.Nil(err)
+	r.Equal("uuid-user", userId)
+	r.Equal("family-a", sessionId)
+	r.Nil(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *refreshTokenRepositoryTestSuite) TestRotate_ReusedTokenRevokesFamily() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE refresh_token SET rotated_at = NOW()")).
+		WithArgs("token-hash").WillReturnError(sql.ErrNoRows)
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT family_id FROM refresh_token WHERE token_hash = $1 AND rotated_at IS NOT NULL")).
+		WithArgs("token-hash").WillReturnRows(sqlmock.NewRows([]string{"family_id"}).AddRow("family-a"))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE refresh_token SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL")).
+		WithArgs("family-a").WillReturnResult(sqlmock.NewResult(0, 2))
+	r.mockSql.ExpectCommit()
+
+	_, _, err := r.repo.Rotate("token-hash", "new-token-hash", time.Now())
+
+	r.ErrorIs(err, ErrRefreshTokenReused)
+	r.Nil(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *refreshTokenRepositoryTestSuite) TestRotate_UnknownToken() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE refresh_token SET rotated_at = NOW()")).
+		WithArgs("token-hash").WillReturnError(sql.ErrNoRows)
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT family_id FROM refresh_token")).
+		WithArgs("token-hash").WillReturnError(sql.ErrNoRows)
+	r.mockSql.ExpectRollback()
+
+	_, _, err := r.repo.Rotate("token-hash", "new-token-hash", time.Now())
+
+	r.ErrorIs(err, ErrInvalidRefreshToken)
+	r.Nil(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *refreshTokenRepositoryTestSuite) TestIsRememberMe() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT s.remember_me FROM refresh_token t JOIN user_session s")).
+		WithArgs("token-hash").WillReturnRows(sqlmock.NewRows([]string{"remember_me"}).AddRow(true))
+
+	rememberMe, err := r.repo.IsRememberMe("token-hash")
+
+	r.Nil(err)
+	r.True(rememberMe)
+	r.Nil(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *refreshTokenRepositoryTestSuite) TestIsRememberMe_UnknownToken() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT s.remember_me FROM refresh_token t JOIN user_session s")).
+		WithArgs("token-hash").WillReturnError(sql.ErrNoRows)
+
+	rememberMe, err := r.repo.IsRememberMe("token-hash")
+
+	r.Nil(err)
+	r.False(rememberMe)
+}
+
+func (r *refreshTokenRepositoryTestSuite) TestRevokeFamily() {
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE refresh_token SET revoked_at = NOW()")).
+		WithArgs("token-hash").WillReturnResult(sqlmock.NewResult(0, 3))
+
+	r.Nil(r.repo.RevokeFamily("token-hash"))
+	r.Nil(r.mockSql.ExpectationsWereMet())
+}
@@ -2,13 +2,31 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 
+	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/shared/custom"
 )
 
 type ReportRepository interface {
 	List(userId, startDate, endDate string) ([]custom.ReportResp, error)
+	ProductMargin(filter custom.MarginFilter) ([]custom.ProductMarginResp, error)
+	Overview() (custom.StatsOverview, error)
+}
+
+// selectStatsOverview counts every total in one round trip, no row is read into the server. $1 is the status of the
+// products that are counted, the inactive ones can't be sold.
+const selectStatsOverview = `SELECT
+	(SELECT COUNT(*) FROM mst_product WHERE status = $1),
+	(SELECT COUNT(*) FROM mst_merchant),
+	(SELECT COUNT(*) FROM mst_user WHERE deleted_at IS NULL),
+	(SELECT COUNT(*) FROM transactions WHERE created_at >= date_trunc('day', NOW()))`
+
+// marginSortColumns whitelists the ORDER BY clause of the margin report, the usecase validates SortBy against it.
+var marginSortColumns = map[string]string{
+	"margin": "margin DESC",
+	"units":  "units_sold DESC",
 }
 
 type reportRepository struct {
@@ -19,13 +37,14 @@ type reportRepository struct {
 func (r *reportRepository) List(userId, startDate, endDate string) ([]custom.ReportResp, error) {
 	selectQuery := `
 		SELECT
-			p.name_provider,
+			pv.name_provider,
 			COUNT(t.transaction_id)
 		FROM transactions t
 		JOIN mst_user u ON t.id_user = u.id_user
 		JOIN mst_merchant m ON t.id_merchant = m.id_merchant
 		JOIN transaction_detail td ON t.transaction_id = td.transaction_id
 		JOIN mst_product p ON td.id_product = p.id_product
+		JOIN mst_provider pv ON p.id_provider = pv.id_provider
 		WHERE m.id_merchant = (
 			SELECT
 				m.id_merchant
@@ -34,7 +53,7 @@ func (r *reportRepository) List(userId, startDate, endDate string) ([]custom.Rep
 		)
 		AND t.transaction_date >= $2
 		AND t.transaction_date <= $3
-		GROUP BY p.name_provider
+		GROUP BY pv.id_provider, pv.name_provider
 		ORDER BY 2 DESC;`
 
 	r.log.Info("Starting to retrive report of all transactions in the repository layer", nil)
@@ -68,6 +87,75 @@ func (r *reportRepository) List(userId, startDate, endDate string) ([]custom.Rep
 	return reportSlice, nil
 }
 
+func (r *reportRepository) ProductMargin(filter custom.MarginFilter) ([]custom.ProductMarginResp, error) {
+	orderBy, ok := marginSortColumns[filter.SortBy]
+	if !ok {
+		orderBy = marginSortColumns["margin"]
+	}
+
+	selectQuery := fmt.Sprintf(`
+		SELECT
+			p.id_product, pv.name_provider, p.nominal,
+			COUNT(td.transaction_detail_id) AS units_sold,
+			SUM(td.price) AS total_revenue,
+			SUM(td.nominal) AS total_cost,
+			SUM(td.price) - SUM(td.nominal) AS margin
+		FROM transaction_detail td
+		JOIN transactions t ON td.transaction_id = t.transaction_id
+		JOIN mst_product p ON td.id_product = p.id_product
+		JOIN mst_provider pv ON p.id_provider = pv.id_provider
+		WHERE ($1::date IS NULL OR t.transaction_date >= $1::date)
+		AND ($2::date IS NULL OR t.transaction_date <= $2::date)
+		GROUP BY p.id_product, pv.name_provider, p.nominal
+		ORDER BY %s, p.id_product
+		LIMIT $3;`, orderBy)
+
+	r.log.Info("Starting to retrive product margin report in the repository layer", nil)
+
+	startDate := sql.NullString{String: filter.StartDate, Valid: filter.StartDate != ""}
+	endDate := sql.NullString{String: filter.EndDate, Valid: filter.EndDate != ""}
+
+	rows, err := r.db.Query(selectQuery, startDate, endDate, filter.Limit)
+	if err != nil {
+		r.log.Error("Failed to retrieve the product margin report", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var margins []custom.ProductMarginResp
+
+	for rows.Next() {
+		var margin custom.ProductMarginResp
+		if err := rows.Scan(
+			&margin.IdProduct, &margin.NameProvider, &margin.Nominal,
+			&margin.UnitsSold, &margin.TotalRevenue, &margin.TotalCost, &margin.Margin,
+		); err != nil {
+			r.log.Error("Failed to scan product margin report", err)
+			return nil, err
+		}
+		margins = append(margins, margin)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to scan product margin report", err)
+		return nil, err
+	}
+
+	return margins, nil
+}
+
+func (r *reportRepository) Overview() (custom.StatsOverview, error) {
+	r.log.Info("Starting to count the stats overview in the repository layer", nil)
+
+	var overview custom.StatsOverview
+	if err := r.db.QueryRow(selectStatsOverview, entity.ProductStatusActive).Scan(&overview.Products, &overview.Merchants, &overview.Users, &overview.TransactionsToday); err != nil {
+		r.log.Error("Failed to count the stats overview", err)
+		return custom.StatsOverview{}, err
+	}
+
+	return overview, nil
+}
+
 func NewReportRepository(db *sql.DB, log *logger.Logger) ReportRepository {
 	return &reportRepository{db: db, log: log}
 }
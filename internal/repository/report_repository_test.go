@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/custom"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type reportRepoTestSuite struct {
+	suite.Suite
+	mockDB     *sql.DB
+	mockSql    sqlmock.Sqlmock
+	reportRepo ReportRepository
+	log        logger.Logger
+}
+
+func (r *reportRepoTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	if err != nil {
+		r.T().Fatalf("an error '%s' occurred when opening a mock database connection", err)
+	}
+
+	r.mockDB = mockDb
+	r.mockSql = mockSql
+	r.log = logger.NewLogger()
+	r.reportRepo = NewReportRepository(r.mockDB, &r.log)
+}
+
+func (r *reportRepoTestSuite) TearDownTest() {
+	r.mockDB.Close()
+}
+
+func (r *reportRepoTestSuite) TestProductMargin_SortByUnits() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("ORDER BY units_sold DESC, p.id_product")).
+		WithArgs(sql.NullString{String: "2024-10-01", Valid: true}, sql.NullString{}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id_product", "name_provider", "nominal", "units_sold", "total_revenue", "total_cost", "margin"}).
+			AddRow("1", "Telkomsel", 10000, 3, 32700, 30000, 2700))
+
+	margins, err := r.reportRepo.ProductMargin(custom.MarginFilter{StartDate: "2024-10-01", SortBy: "units", Limit: 10})
+
+	r.Nil(err)
+	r.Equal([]custom.ProductMarginResp{{
+		IdProduct: "1", NameProvider: "Telkomsel", Nominal: 10000,
+		UnitsSold: 3, TotalRevenue: 32700, TotalCost: 30000, Margin: 2700,
+	}}, margins)
+	r.Nil(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *reportRepoTestSuite) TestOverview() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("(SELECT COUNT(*) FROM mst_product WHERE status = $1),\n\t" +
+		"(SELECT COUNT(*) FROM mst_merchant),\n\t" +
+		"(SELECT COUNT(*) FROM mst_user WHERE deleted_at IS NULL),\n\t" +
+		"(SELECT COUNT(*) FROM transactions WHERE created_at >= date_trunc('day', NOW()))")).
+		WithArgs("active").
+		WillReturnRows(sqlmock.NewRows([]string{"products", "merchants", "users", "transactions_today"}).AddRow(120, 35, 48, 210))
+
+	overview, err := r.reportRepo.Overview()
+
+	r.NoError(err)
+	r.Equal(custom.StatsOverview{Products: 120, Merchants: 35, Users: 48, TransactionsToday: 210}, overview)
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *reportRepoTestSuite) TestOverview_Fail() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta(selectStatsOverview)).WillReturnError(sql.ErrConnDone)
+
+	_, err := r.reportRepo.Overview()
+
+	r.ErrorIs(err, sql.ErrConnDone)
+}
+
+func TestReportRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(reportRepoTestSuite))
+}
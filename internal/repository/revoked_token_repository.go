@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"database/sql"
+	"server-pulsa-app/internal/logger"
+	"time"
+)
+
+// RevokedTokenRepository keeps the ids (jti) of access tokens that were logged out before they expired.
+type RevokedTokenRepository interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+	DeleteExpired() (int64, error)
+}
+
+type revokedTokenRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func (r *revokedTokenRepository) Revoke(jti string, expiresAt time.Time) error {
+	r.log.Info("Starting to revoke a token in the repository layer", nil)
+
+	_, err := r.db.Exec("INSERT INTO revoked_token (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", jti, expiresAt)
+	if err != nil {
+		r.log.Error("Failed to revoke the token: ", err)
+		return err
+	}
+
+	r.log.Info("Token has been revoked successfully", jti)
+	return nil
+}
+
+func (r *revokedTokenRepository) IsRevoked(jti string) (bool, error) {
+	var revoked bool
+
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM revoked_token WHERE jti = $1)", jti).Scan(&revoked)
+	if err != nil {
+		r.log.Error("Failed to check the token revocation: ", err)
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+// DeleteExpired drops revocations of tokens that have expired by now, those are rejected as expired anyway.
+func (r *revokedTokenRepository) DeleteExpired() (int64, error) {
+	r.log.Info("Starting to delete expired token revocations in the repository layer", nil)
+
+	result, err := r.db.Exec("DELETE FROM revoked_token WHERE expires_at < NOW()")
+	if err != nil {
+		r.log.Error("Failed to delete expired token revocations: ", err)
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		r.log.Error("Failed to delete expired token revocations: ", err)
+		return 0, err
+	}
+
+	r.log.Info("Expired token revocations have been deleted: ", deleted)
+	return deleted, nil
+}
+
+func NewRevokedTokenRepository(db *sql.DB, log *logger.Logger) RevokedTokenRepository {
+	return &revokedTokenRepository{db: db, log: log}
+}
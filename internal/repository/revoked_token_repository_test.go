@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type revokedTokenRepositoryTestSuite struct {
+	suite.Suite
+	mockDb  *sql.DB
+	mockSql sqlmock.Sqlmock
+	repo    RevokedTokenRepository
+	log     logger.Logger
+}
+
+func TestRevokedTokenRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(revokedTokenRepositoryTestSuite))
+}
+
+func (r *revokedTokenRepositoryTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	r.NoError(err)
+
+	r.mockDb = mockDb
+	r.mockSql = mockSql
+	r.log = logger.NewLogger()
+	r.repo = NewRevokedTokenRepository(mockDb, &r.log)
+}
+
+func (r *revokedTokenRepositoryTestSuite) TestRevoke() {
+	expiresAt := time.Now().Add(time.Hour)
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO revoked_token (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING")).
+		WithArgs("token-jti", expiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r.Nil(r.repo.Revoke("token-jti", expiresAt))
+	r.Nil(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *revokedTokenRepositoryTestSuite) TestIsRevoked() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT 1 FROM revoked_token WHERE jti = $1)")).
+		WithArgs("token-jti").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	revoked, err := r.repo.IsRevoked("token-jti")
+
+	r.Nil(err)
+	r.True(revoked)
+}
+
+func (r *revokedTokenRepositoryTestSuite) TestDeleteExpired() {
+	r.mockSql.ExpectExec(regexp.QuoteMeta("DELETE FROM revoked_token WHERE expires_at < NOW()")).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	deleted, err := r.repo.DeleteExpired()
+
+	r.Nil(err)
+	r.Equal(int64(4), deleted)
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"database/sql"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+)
+
+// RolePermissionRepository keeps the role to permission mappings, the permissions of a role are written into the
+// access tokens of its users.
+type RolePermissionRepository interface {
+	ListByRole(role string) ([]string, error)
+	List() ([]entity.RolePermissions, error)
+	Replace(role string, permissions []string) error
+}
+
+type rolePermissionRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func (r *rolePermissionRepository) ListByRole(role string) ([]string, error) {
+	rows, err := r.db.Query("SELECT permission FROM role_permission WHERE role = $1 ORDER BY permission", role)
+	if err != nil {
+		r.log.Error("Failed to retrieve the permissions of the role: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	// never nil, an empty list in a token means no permissions rather than the defaults of the role
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			r.log.Error("Failed to scan the permission: ", err)
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to read the permissions: ", err)
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// List returns the permissions of every role that has at least one.
+func (r *rolePermissionRepository) List() ([]entity.RolePermissions, error) {
+	r.log.Info("Starting to retrieve the role permissions in the repository layer", nil)
+
+	rows, err := r.db.Query("SELECT role, permission FROM role_permission ORDER BY role, permission")
+	if err != nil {
+		r.log.Error("Failed to retrieve the role permissions: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	mappings := []entity.RolePermissions{}
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			r.log.Error("Failed to scan the role permission: ", err)
+			return nil, err
+		}
+		if len(mappings) == 0 || mappings[len(mappings)-1].Role != role {
+			mappings = append(mappings, entity.RolePermissions{Role: role, Permissions: []string{}})
+		}
+		last := &mappings[len(mappings)-1]
+		last.Permissions = append(last.Permissions, permission)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Failed to read the role permissions: ", err)
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+// Replace sets the permissions of the role to exactly permissions, in one transaction.
+func (r *rolePermissionRepository) Replace(role string, permissions []string) error {
+	r.log.Info("Starting to replace the permissions of a role in the repository layer", nil)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.log.Error("Failed to begin the role permission transaction: ", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec("DELETE FROM role_permission WHERE role = $1", role); err != nil {
+		r.log.Error("Failed to delete the permissions of the role: ", err)
+		return err
+	}
+	for _, permission := range permissions {
+		if _, err = tx.Exec("INSERT INTO role_permission (role, permission) VALUES ($1, $2)", role, permission); err != nil {
+			r.log.Error("Failed to insert the permission of the role: ", err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("Failed to commit the role permission transaction: ", err)
+		return err
+	}
+
+	r.log.Info("Permissions of the role have been replaced successfully", role)
+	return nil
+}
+
+func NewRolePermissionRepository(db *sql.DB, log *logger.Logger) RolePermissionRepository {
+	return &rolePermissionRepository{db: db, log: log}
+}
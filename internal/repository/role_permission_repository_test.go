@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type rolePermissionRepositoryTestSuite struct {
+	suite.Suite
+	mockDb  *sql.DB
+	mockSql sqlmock.Sqlmock
+	repo    RolePermissionRepository
+	log     logger.Logger
+}
+
+func TestRolePermissionRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(rolePermissionRepositoryTestSuite))
+}
+
+func (r *rolePermissionRepositoryTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	r.NoError(err)
+
+	r.mockDb = mockDb
+	r.mockSql = mockSql
+	r.log = logger.NewLogger()
+	r.repo = NewRolePermissionRepository(mockDb, &r.log)
+}
+
+func (r *rolePermissionRepositoryTestSuite) TestListByRole_NoPermissions() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT permission FROM role_permission WHERE role = $1")).WithArgs("employee").
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}))
+
+	permissions, err := r.repo.ListByRole("employee")
+
+	r.NoError(err)
+	r.NotNil(permissions)
+	r.Empty(permissions)
+}
+
+func (r *rolePermissionRepositoryTestSuite) TestList_GroupsByRole() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT role, permission FROM role_permission")).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "permission"}).
+			AddRow("admin", "product:write").AddRow("admin", "user:read").AddRow("employee", "product:write"))
+
+	mappings, err := r.repo.List()
+
+	r.NoError(err)
+	r.Equal([]entity.RolePermissions{
+		{Role: "admin", Permissions: []string{"product:write", "user:read"}},
+		{Role: "employee", Permissions: []string{"product:write"}},
+	}, mappings)
+}
+
+func (r *rolePermissionRepositoryTestSuite) TestReplace() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectExec(regexp.QuoteMeta("DELETE FROM role_permission WHERE role = $1")).WithArgs("employee").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO role_permission (role, permission)")).WithArgs("employee", "product:write").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	r.mockSql.ExpectCommit()
+
+	r.NoError(r.repo.Replace("employee", []string{"product:write"}))
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *rolePermissionRepositoryTestSuite) TestReplace_RollsBackOnFailure() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectExec(regexp.QuoteMeta("DELETE FROM role_permission WHERE role = $1")).WithArgs("employee").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO role_permission (role, permission)")).WithArgs("employee", "product:write").
+		WillReturnError(errors.New("insert failed"))
+	r.mockSql.ExpectRollback()
+
+	r.Error(r.repo.Replace("employee", []string{"product:write"}))
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+)
+
+// ErrSessionNotFound is returned when revoking a session that is not an active session of the user.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository keeps the logins of the users. Revoking a session revokes its refresh tokens too, and the auth
+// middleware rejects its access tokens from the next request on.
+type SessionRepository interface {
+	Create(userId string, client entity.ClientInfo, rememberMe bool) (string, error)
+	ListActive(userId string) ([]entity.Session, error)
+	Revoke(userId, id string) error
+	RevokeAll(userId string) (int64, error)
+	IsRevoked(id string) (bool, error)
+}
+
+type sessionRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func (s *sessionRepository) Create(userId string, client entity.ClientInfo, rememberMe bool) (string, error) {
+	s.log.Info("Starting to create a session in the repository layer", nil)
+
+	var id string
+	err := s.db.QueryRow(`INSERT INTO user_session (id_user, ip, user_agent, remember_me) VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+		RETURNING id_session`, userId, client.Ip, client.UserAgent, rememberMe).Scan(&id)
+	if err != nil {
+		s.log.Error("Failed to create the session: ", err)
+		return "", err
+	}
+
+	s.log.Info("Session has been created successfully", id)
+	return id, nil
+}
+
+// ListActive returns the sessions that are not revoked and still have a usable refresh token, most recently used
+// first.
+func (s *sessionRepository) ListActive(userId string) ([]entity.Session, error) {
+	s.log.Info("Starting to retrieve the active sessions in the repository layer", nil)
+
+	rows, err := s.db.Query(`SELECT s.id_session, s.issued_at, s.last_used_at, COALESCE(s.ip, ''), COALESCE(s.user_agent, ''), s.remember_me
+		FROM user_session s
+		WHERE s.id_user = $1 AND s.revoked_at IS NULL AND EXISTS (
+			SELECT 1 FROM refresh_token t
+			WHERE t.family_id = s.id_session AND t.rotated_at IS NULL AND t.revoked_at IS NULL AND t.expires_at > NOW())
+		ORDER BY s.last_used_at DESC`, userId)
+	if err != nil {
+		s.log.Error("Failed to retrieve the active sessions: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []entity.Session{}
+	for rows.Next() {
+		var session entity.Session
+		if err := rows.Scan(&session.Id, &session.IssuedAt, &session.LastUsedAt, &session.Ip, &session.UserAgent, &session.RememberMe); err != nil {
+			s.log.Error("Failed to scan the session: ", err)
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		s.log.Error("Failed to read the sessions: ", err)
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Revoke ends one session of the user together with its refresh tokens, in one transaction.
+func (s *sessionRepository) Revoke(userId, id string) error {
+	s.log.Info("Starting to revoke a session in the repository layer", nil)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.log.Error("Failed to begin the session transaction: ", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec("UPDATE user_session SET revoked_at = NOW() WHERE id_session = $1 AND id_user = $2 AND revoked_at IS NULL", id, userId)
+	if err != nil {
+		s.log.Error("Failed to revoke the session: ", err)
+		return err
+	}
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		s.log.Error("Failed to revoke the session: ", err)
+		return err
+	}
+	if revoked == 0 {
+		err = ErrSessionNotFound
+		s.log.Error("Session to revoke not found: ", id)
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE refresh_token SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		s.log.Error("Failed to revoke the refresh tokens of the session: ", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		s.log.Error("Failed to commit the session revocation: ", err)
+		return err
+	}
+
+	s.log.Info("Session has been revoked successfully", id)
+	return nil
+}
+
+// RevokeAll ends every session of the user and revokes all of their refresh tokens, it returns how many sessions
+// were still open.
+func (s *sessionRepository) RevokeAll(userId string) (int64, error) {
+	s.log.Info("Starting to revoke every session of a user in the repository layer", nil)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.log.Error("Failed to begin the session transaction: ", err)
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec("UPDATE user_session SET revoked_at = NOW() WHERE id_user = $1 AND revoked_at IS NULL", userId)
+	if err != nil {
+		s.log.Error("Failed to revoke the sessions: ", err)
+		return 0, err
+	}
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		s.log.Error("Failed to revoke the sessions: ", err)
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE refresh_token SET revoked_at = NOW() WHERE id_user = $1 AND revoked_at IS NULL", userId)
+	if err != nil {
+		s.log.Error("Failed to revoke the refresh tokens of the user: ", err)
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		s.log.Error("Failed to commit the session revocation: ", err)
+		return 0, err
+	}
+
+	s.log.Info("Sessions have been revoked successfully", revoked)
+	return revoked, nil
+}
+
+// IsRevoked is checked on every authenticated request, a session that no longer exists counts as revoked.
+func (s *sessionRepository) IsRevoked(id string) (bool, error) {
+	var revoked bool
+
+	err := s.db.QueryRow("SELECT revoked_at IS NOT NULL FROM user_session WHERE id_session = $1", id).Scan(&revoked)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		s.log.Error("Failed to check the session revocation: ", err)
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+func NewSessionRepository(db *sql.DB, log *logger.Logger) SessionRepository {
+	return &sessionRepository{db: db, log: log}
+}
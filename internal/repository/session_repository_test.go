@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type sessionRepositoryTestSuite struct {
+	suite.Suite
+	mockDb  *sql.DB
+	mockSql sqlmock.Sqlmock
+	repo    SessionRepository
+	log     logger.Logger
+}
+
+func TestSessionRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(sessionRepositoryTestSuite))
+}
+
+func (r *sessionRepositoryTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	r.NoError(err)
+
+	r.mockDb = mockDb
+	r.mockSql = mockSql
+	r.log = logger.NewLogger()
+	r.repo = NewSessionRepository(mockDb, &r.log)
+}
+
+func (r *sessionRepositoryTestSuite) TestCreate() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO user_session (id_user, ip, user_agent, remember_me)")).
+		WithArgs("uuid-user", "10.0.0.1", "Mozilla/5.0", true).WillReturnRows(sqlmock.NewRows([]string{"id_session"}).AddRow("uuid-session"))
+
+	id, err := r.repo.Create("uuid-user", entity.ClientInfo{Ip: "10.0.0.1", UserAgent: "Mozilla/5.0"}, true)
+
+	r.NoError(err)
+	r.Equal("uuid-session", id)
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *sessionRepositoryTestSuite) TestListActive() {
+	issuedAt := time.Date(2024, 8, 1, 10, 0, 0, 0, time.UTC)
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("FROM user_session s")).WithArgs("uuid-user").
+		WillReturnRows(sqlmock.NewRows([]string{"id_session", "issued_at", "last_used_at", "ip", "user_agent", "remember_me"}).
+			AddRow("uuid-session", issuedAt, issuedAt.Add(time.Hour), "10.0.0.1", "", true))
+
+	sessions, err := r.repo.ListActive("uuid-user")
+
+	r.NoError(err)
+	r.Equal([]entity.Session{{Id: "uuid-session", IssuedAt: issuedAt, LastUsedAt: issuedAt.Add(time.Hour), Ip: "10.0.0.1", RememberMe: true}}, sessions)
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *sessionRepositoryTestSuite) TestRevoke() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE user_session SET revoked_at = NOW() WHERE id_session = $1 AND id_user = $2 AND revoked_at IS NULL")).
+		WithArgs("uuid-session", "uuid-user").WillReturnResult(sqlmock.NewResult(0, 1))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE refresh_token SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL")).
+		WithArgs("uuid-session").WillReturnResult(sqlmock.NewResult(0, 1))
+	r.mockSql.ExpectCommit()
+
+	r.NoError(r.repo.Revoke("uuid-user", "uuid-session"))
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *sessionRepositoryTestSuite) TestRevoke_NotOwnSession() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE user_session SET revoked_at = NOW()")).
+		WithArgs("uuid-session", "uuid-user").WillReturnResult(sqlmock.NewResult(0, 0))
+	r.mockSql.ExpectRollback()
+
+	r.ErrorIs(r.repo.Revoke("uuid-user", "uuid-session"), ErrSessionNotFound)
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *sessionRepositoryTestSuite) TestRevokeAll() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE user_session SET revoked_at = NOW() WHERE id_user = $1 AND revoked_at IS NULL")).
+		WithArgs("uuid-user").WillReturnResult(sqlmock.NewResult(0, 2))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE refresh_token SET revoked_at = NOW() WHERE id_user = $1 AND revoked_at IS NULL")).
+		WithArgs("uuid-user").WillReturnResult(sqlmock.NewResult(0, 3))
+	r.mockSql.ExpectCommit()
+
+	revoked, err := r.repo.RevokeAll("uuid-user")
+
+	r.NoError(err)
+	r.Equal(int64(2), revoked)
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *sessionRepositoryTestSuite) TestIsRevoked() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT revoked_at IS NOT NULL FROM user_session WHERE id_session = $1")).
+		WithArgs("uuid-session").WillReturnRows(sqlmock.NewRows([]string{"revoked"}).AddRow(false))
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT revoked_at IS NOT NULL FROM user_session WHERE id_session = $1")).
+		WithArgs("uuid-deleted").WillReturnError(sql.ErrNoRows)
+
+	revoked, err := r.repo.IsRevoked("uuid-session")
+	r.NoError(err)
+	r.False(revoked)
+
+	// the session is gone with its user, its tokens must not keep working
+	revoked, err = r.repo.IsRevoked("uuid-deleted")
+	r.NoError(err)
+	r.True(revoked)
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
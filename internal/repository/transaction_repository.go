@@ -1,117 +1,259 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/shared/custom"
+	"server-pulsa-app/internal/shared/service"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrTransactionNotFound is returned when no transaction matches the requested id.
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrInvalidTransactionSort is returned by GetAll for a sort that is not in transactionSortOrders.
+	ErrInvalidTransactionSort = errors.New("sort must be one of date_asc, date_desc or customer")
+	// ErrInvalidTransactionStatus is returned by GetAll for a status filter that is not in transactionStatuses.
+	ErrInvalidTransactionStatus = errors.New("status must be one of pending, success or failed")
+	// ErrInvalidTransactionCursor is returned by GetAll for a cursor it did not issue, or issued for another sort.
+	ErrInvalidTransactionCursor = errors.New("cursor must be the nextCursor of a date_desc or date_asc history page")
+	// ErrTransactionDetailNotFound is returned when no detail line matches the requested id.
+	ErrTransactionDetailNotFound = errors.New("transaction detail not found")
+	// ErrDetailAlreadyRefunded is returned by RefundDetail for a line that was refunded before.
+	ErrDetailAlreadyRefunded = errors.New("transaction detail is already refunded")
+	// ErrMerchantNotFound is returned by Create when the merchant of the transaction does not exist.
+	ErrMerchantNotFound = errors.New("merchant not found")
+	// ErrUserNotFound is returned by Create when the user of the transaction does not exist, and by ActivateUser for
+	// an unknown user.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrProductNotFound is returned by Create when a detail line references a product that does not exist, and by
+	// the product lookup by code.
+	ErrProductNotFound = errors.New("product not found")
+	// ErrDailyLimitExceeded is returned by Create when the transaction would take the merchant over its daily count
+	// or amount limit.
+	ErrDailyLimitExceeded = errors.New("merchant daily transaction limit exceeded")
+	// ErrInsufficientBalance is returned by Create when the merchant balance does not cover the nominal.
+	ErrInsufficientBalance = errors.New("insufficient merchant balance")
+	// ErrProductUnavailable is returned by Create when a detail line references a product that is not active.
+	ErrProductUnavailable = errors.New("product is not available")
+)
+
+// transactionSortOrders is the allowlist of history sorts, only these fixed clauses ever reach the query.
+var transactionSortOrders = map[string]string{
+	"date_desc": "t.transaction_date DESC, t.transaction_id",
+	"date_asc":  "t.transaction_date ASC, t.transaction_id",
+	"customer":  "t.customer_name ASC, t.transaction_date DESC, t.transaction_id",
+}
+
+// transactionCursorConditions are the keyset conditions of the sorts that can be paged by cursor, $3 and $4 are the
+// date and the id of the last transaction of the previous page. The id breaks ties in ascending order in both sorts,
+// the same as in transactionSortOrders, so a page starts right after the previous one whatever arrived meanwhile.
+var transactionCursorConditions = map[string]string{
+	"date_desc": "(t.transaction_date < $3::date OR (t.transaction_date = $3::date AND t.transaction_id > $4::uuid))",
+	"date_asc":  "(t.transaction_date > $3::date OR (t.transaction_date = $3::date AND t.transaction_id > $4::uuid))",
+}
+
+// transactionStatuses is the allowlist of the history status filter.
+var transactionStatuses = map[string]bool{
+	entity.TransactionStatusPending: true,
+	entity.TransactionStatusSuccess: true,
+	entity.TransactionStatusFailed:  true,
+}
+
+// selectProductSnapshot reads the nominal and the price the merchant pays, a merchant override wins over the catalog
+// price. Both come from one read of the product row and the share lock keeps a price update waiting until the
+// transaction is committed, so the stored detail is exactly what the merchant was charged. The status is read with
+// them so a product deactivated meanwhile is not sold.
+const selectProductSnapshot = `SELECT p.nominal, COALESCE(mpp.price, p.price), CASE WHEN mpp.price IS NULL THEN $3 ELSE $4 END, p.status
+	FROM mst_product p
+	LEFT JOIN merchant_product_price mpp ON mpp.id_product = p.id_product AND mpp.id_merchant = $2
+	WHERE p.id_product = $1 AND (p.id_merchant IS NULL OR p.id_merchant = $2)
+	FOR SHARE OF p`
+
+// selectDailyUsage counts the transactions of the merchant since midnight and sums their nominal, refunded lines
+// went back to the balance and do not count.
+const selectDailyUsage = `SELECT COUNT(DISTINCT t.transaction_id), COALESCE(SUM(d.nominal) FILTER (WHERE d.refunded_at IS NULL), 0)
+	FROM transactions t
+	LEFT JOIN transaction_detail d ON d.transaction_id = t.transaction_id
+	WHERE t.id_merchant = $1 AND t.created_at >= date_trunc('day', NOW())`
+
+// maxSerializationRetries is how many times Create runs a transaction again after a serialization failure before
+// it returns the error, serializationRetryBackoff is the wait before the first retry and doubles for each next one.
+const (
+	maxSerializationRetries   = 3
+	serializationRetryBackoff = 10 * time.Millisecond
 )
 
 type transactionRepository struct {
-	db  *sql.DB
-	log *logger.Logger
+	db       *sql.DB
+	log      *logger.Logger
+	notifier service.Notifier
 }
 
 type TransactionRepository interface {
-	Create(payload entity.Transactions) (entity.Transactions, error)
-	GetAll(userId string) ([]custom.TransactionsReq, error)
-	GetById(id string) (custom.TransactionsReq, error)
+	// Create charges the transaction to the merchant, actorId is the caller recorded in the audit log for the
+	// detail lines sold at an override price.
+	Create(ctx context.Context, payload entity.Transactions, actorId string) (entity.Transactions, error)
+	GetAll(ctx context.Context, filter custom.TransactionFilter) (custom.TransactionPage, error)
+	GetById(ctx context.Context, id string) (custom.TransactionsReq, error)
+	RefundDetail(ctx context.Context, detailId string) error
+	// Quote sums the nominal of the products like Create and checks it against the merchant balance, nothing is
+	// locked, inserted or debited.
+	Quote(ctx context.Context, payload entity.TransactionQuoteReq) (custom.TransactionQuote, error)
 	// Update(payload entity.Transactions) (entity.Transactions, error)
 	// Delete(id string) error
 }
 
-func NewTransactionRepository(db *sql.DB, log *logger.Logger) TransactionRepository {
-	return &transactionRepository{db: db, log: log}
+func NewTransactionRepository(db *sql.DB, log *logger.Logger, notifier service.Notifier) TransactionRepository {
+	return &transactionRepository{db: db, log: log, notifier: notifier}
 }
 
-func (r *transactionRepository) Create(payload entity.Transactions) (entity.Transactions, error) {
-	r.log.Info("Starting to create a new transaction in the repository layer", nil)
+func (r *transactionRepository) Create(ctx context.Context, payload entity.Transactions, actorId string) (entity.Transactions, error) {
+	log := r.log.WithContext(ctx)
+	log.Info("Starting to create a new transaction in the repository layer", nil)
+	// a transaction sent without a date is made today, in the time zone of the server
+	if payload.TransactionDate == "" {
+		payload.TransactionDate = time.Now().Format("02-01-2006")
+	}
 	parsedDate, err := time.Parse("02-01-2006", payload.TransactionDate)
 	if err != nil {
-		r.log.Error("invalid date format", err)
+		log.Error("invalid date format", err)
 		return entity.Transactions{}, fmt.Errorf("invalid date format. Please use dd-mm-yyyy format: %v", err)
 	}
 
-	r.log.Info("Starting the db transaction create method in the repository layer", nil)
-	tx, err := r.db.Begin()
-	if err != nil {
-		r.log.Error("Failed start db transaction", err)
+	// the references are checked before the db transaction locks the merchant, so a typo gets a clear error
+	if err := r.checkExists(ctx, "SELECT EXISTS(SELECT 1 FROM mst_merchant WHERE id_merchant = $1)", payload.MerchantId, ErrMerchantNotFound); err != nil {
 		return entity.Transactions{}, err
 	}
+	if err := r.checkExists(ctx, "SELECT EXISTS(SELECT 1 FROM mst_user WHERE id_user = $1)", payload.UserId, ErrUserNotFound); err != nil {
+		return entity.Transactions{}, err
+	}
+
+	backoff := serializationRetryBackoff
+	for retry := 0; ; retry++ {
+		// every attempt starts from the lines as requested, a failed one may have filled them in
+		attempt := payload
+		attempt.TransactionDetail = append([]entity.TransactionDetail(nil), payload.TransactionDetail...)
+
+		created, err := r.create(ctx, attempt, actorId, parsedDate)
+		if !isSerializationFailure(err) || retry == maxSerializationRetries {
+			return created, err
+		}
+		log.Info("Retrying the transaction after a serialization failure", map[string]interface{}{
+			"merchantId": payload.MerchantId,
+			"retry":      retry + 1,
+			"backoff":    backoff.String(),
+		})
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// create charges the transaction in one db transaction, Create runs it again when the database aborts it to keep
+// concurrent transactions of the merchant serializable.
+func (r *transactionRepository) create(ctx context.Context, payload entity.Transactions, actorId string, parsedDate time.Time) (entity.Transactions, error) {
+	log := r.log.WithContext(ctx)
+	log.Info("Starting the db transaction create method in the repository layer", nil)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed start db transaction", err)
+		return entity.Transactions{}, fmt.Errorf("start db transaction: %w", err)
+	}
 
-	// Check merchant's current balance before processing
-	var currentBalance float64
-	if err := tx.QueryRow(
-		"SELECT balance FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE",
+	// Check merchant's current balance before processing, the row lock also serializes the daily limit check
+	var currentBalance, lowBalanceThreshold, dailyLimitAmount float64
+	var dailyLimitCount int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(balance, 0), low_balance_threshold, daily_limit_count, daily_limit_amount FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE",
 		payload.MerchantId,
-	).Scan(&currentBalance); err != nil {
+	).Scan(&currentBalance, &lowBalanceThreshold, &dailyLimitCount, &dailyLimitAmount); err != nil {
 		tx.Rollback()
-		r.log.Error("Failed to fetch merchant balance", err)
-		return entity.Transactions{}, err
+		log.Error("Failed to fetch merchant balance", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			// deleted since the existence check
+			return entity.Transactions{}, ErrMerchantNotFound
+		}
+		return entity.Transactions{}, fmt.Errorf("fetch merchant balance: %w", err)
 	}
 
-	// Calculate total nominal needed for the transaction
-	var totalNominal float64
-	for _, detail := range payload.TransactionDetail {
-		var nominal float64
-		if err := tx.QueryRow(
-			"SELECT nominal FROM mst_product WHERE id_product = $1",
-			detail.ProductId,
-		).Scan(&nominal); err != nil {
-			tx.Rollback()
-			r.log.Error("Failed to fetch product nominal", err)
-			return entity.Transactions{}, err
-		}
-		totalNominal += nominal
+	// Snapshot the nominal and price of every line, the same values are charged and stored
+	totalNominal, overriddenPrices, err := r.priceLines(ctx, tx, payload.MerchantId, payload.TransactionDetail)
+	if err != nil {
+		tx.Rollback()
+		return entity.Transactions{}, err
 	}
 
 	// Check if merchant has sufficient balance
 	if currentBalance < totalNominal {
 		tx.Rollback()
-		r.log.Error("Insufficient merchant balance", fmt.Errorf("required balance: %v, current balance: %v", totalNominal, currentBalance))
-		return entity.Transactions{}, fmt.Errorf("insufficient merchant balance: required %v, current balance %v", totalNominal, currentBalance)
+		log.Error("Insufficient merchant balance", fmt.Errorf("required balance: %v, current balance: %v", totalNominal, currentBalance))
+		return entity.Transactions{}, fmt.Errorf("%w: required %v, current balance %v", ErrInsufficientBalance, totalNominal, currentBalance)
+	}
+
+	if dailyLimitCount > 0 || dailyLimitAmount > 0 {
+		var todayCount int
+		var todayAmount float64
+		if err := tx.QueryRowContext(ctx, selectDailyUsage, payload.MerchantId).Scan(&todayCount, &todayAmount); err != nil {
+			tx.Rollback()
+			log.Error("Failed to fetch the daily usage of the merchant", err)
+			return entity.Transactions{}, fmt.Errorf("fetch the daily usage of the merchant: %w", err)
+		}
+		if dailyLimitCount > 0 && todayCount+1 > dailyLimitCount {
+			tx.Rollback()
+			log.Error("Merchant daily transaction count exceeded", payload.MerchantId)
+			return entity.Transactions{}, fmt.Errorf("%w: %d of %d transactions made today", ErrDailyLimitExceeded, todayCount, dailyLimitCount)
+		}
+		if dailyLimitAmount > 0 && todayAmount+totalNominal > dailyLimitAmount {
+			tx.Rollback()
+			log.Error("Merchant daily transaction amount exceeded", payload.MerchantId)
+			return entity.Transactions{}, fmt.Errorf("%w: %v of %v spent today, this transaction needs %v", ErrDailyLimitExceeded, todayAmount, dailyLimitAmount, totalNominal)
+		}
 	}
 
 	//insert into transactions table
 	var transactionId string
-	insertTransaction := "INSERT INTO transactions (id_merchant, id_user, customer_name, destination_number, transaction_date) VALUES ($1, $2, $3, $4, $5) RETURNING transaction_id"
+	insertTransaction := "INSERT INTO transactions (id_merchant, id_user, customer_name, destination_number, transaction_date) VALUES ($1, $2, $3, $4, $5) RETURNING transaction_id, created_at, updated_at"
 
-	if err := tx.QueryRow(insertTransaction, payload.MerchantId, payload.UserId, payload.CustomerName, payload.DestinationNumber, parsedDate).Scan(&transactionId); err != nil {
+	if err := tx.QueryRowContext(ctx, insertTransaction, payload.MerchantId, payload.UserId, payload.CustomerName, payload.DestinationNumber, parsedDate).Scan(&transactionId, &payload.CreatedAt, &payload.UpdatedAt); err != nil {
 		tx.Rollback()
-		r.log.Error("Failed to insert into transactions table", err)
-		return entity.Transactions{}, err
+		log.Error("Failed to insert into transactions table", err)
+		return entity.Transactions{}, fmt.Errorf("insert into transactions table: %w", err)
 	}
 
 	payload.TransactionsId = transactionId
 
 	//insert into transaction detail table
-	insertTransactionDetail := "INSERT INTO transaction_detail (transaction_id, id_product, price) VALUES ($1, $2, $3) RETURNING transaction_detail_id"
+	insertTransactionDetail := "INSERT INTO transaction_detail (transaction_id, id_product, nominal, price, price_source) VALUES ($1, $2, $3, $4, $5) RETURNING transaction_detail_id"
 
 	for i := range payload.TransactionDetail {
-		var transactionDetailId string
-
-		if err := tx.QueryRow(insertTransactionDetail, transactionId, payload.TransactionDetail[i].ProductId, payload.TransactionDetail[i].Price).Scan(&transactionDetailId); err != nil {
+		detail := &payload.TransactionDetail[i]
+		if err := tx.QueryRowContext(ctx, insertTransactionDetail, transactionId, detail.ProductId, detail.Nominal, detail.Price, detail.PriceSource).Scan(&detail.TransactionDetailId); err != nil {
 			tx.Rollback()
-			r.log.Error("Failed to insert into transaction detail table", err)
-			return entity.Transactions{}, err
+			log.Error("Failed to insert into transaction detail table", err)
+			return entity.Transactions{}, fmt.Errorf("insert into transaction detail table: %w", err)
 		}
-		payload.TransactionDetail[i].TransactionDetailId = transactionDetailId
-		payload.TransactionDetail[i].TransactionsId = transactionId
-
-		// Fetch product price from product table
-		var productPrice float64
-		if err := tx.QueryRow(
-			"SELECT price FROM mst_product WHERE id_product = $1",
-			payload.TransactionDetail[i].ProductId,
-		).Scan(&productPrice); err != nil {
-			tx.Rollback()
-			r.log.Error("Failed to fetch product price", err)
-			return entity.Transactions{}, err
+		detail.TransactionsId = transactionId
+
+		if price, ok := overriddenPrices[i]; ok {
+			if err := insertAuditLog(tx, entity.AuditLog{
+				ActorId:  actorId,
+				Action:   entity.AuditActionPriceOverride,
+				TargetId: detail.TransactionDetailId,
+				Detail:   fmt.Sprintf("price %v instead of %v: %s", detail.Price, price, detail.OverrideReason),
+			}); err != nil {
+				tx.Rollback()
+				log.Error("Failed to record the price override", err)
+				return entity.Transactions{}, fmt.Errorf("record the price override: %w", err)
+			}
 		}
-
-		payload.TransactionDetail[i].Price = productPrice
 	}
 
 	// Update merchant balance - only subtract the nominal amount
@@ -122,168 +264,406 @@ func (r *transactionRepository) Create(payload entity.Transactions) (entity.Tran
 		RETURNING balance`
 
 	var newBalance float64
-	if err := tx.QueryRow(
+	if err := tx.QueryRowContext(ctx,
 		updateMerchantBalance,
 		totalNominal, // amount to subtract (nominal/cost)
 		payload.MerchantId,
 	).Scan(&newBalance); err != nil {
 		tx.Rollback()
-		r.log.Error("Failed to update merchant balance", err)
-		return entity.Transactions{}, err
+		log.Error("Failed to update merchant balance", err)
+		return entity.Transactions{}, fmt.Errorf("update merchant balance: %w", err)
 	}
 
 	// commit transaction
 	if err := tx.Commit(); err != nil {
-		r.log.Error("Failed to commit transaction", err)
-		return entity.Transactions{}, err
+		log.Error("Failed to commit transaction", err)
+		return entity.Transactions{}, fmt.Errorf("commit transaction: %w", err)
 	}
 
 	payload.TransactionDate = parsedDate.Format("02-01-2006")
-	r.log.Info("Transaction created successfully with updated merchant balance", map[string]interface{}{
+	payload.Status = entity.TransactionStatusSuccess
+	payload.Balance = newBalance
+	log.Info("Transaction created successfully with updated merchant balance", map[string]interface{}{
 		"payload":    payload,
 		"newBalance": newBalance,
 	})
+
+	// Only alert on the transaction that crosses the threshold, not on every one after it
+	if currentBalance >= lowBalanceThreshold && newBalance < lowBalanceThreshold {
+		r.notifyLowBalance(ctx, payload.MerchantId, newBalance, lowBalanceThreshold)
+	}
+
 	return payload, nil
 }
 
-func (r *transactionRepository) GetAll(userId string) ([]custom.TransactionsReq, error) {
-	selectQuery := `
-		SELECT
-			t.transaction_id, t.customer_name, t.destination_number, t.transaction_date,
-			u.id_user, u.username, u.role,
-			m.id_merchant, m.name_merchant, m.address,
-			td.transaction_detail_id, td.transaction_id, p.id_product, p.name_provider, p.nominal, p.price
-			
-		FROM transactions t
-		JOIN mst_user u ON t.id_user = u.id_user
-		JOIN mst_merchant m ON t.id_merchant = m.id_merchant
-		JOIN transaction_detail td ON t.transaction_id = td.transaction_id
-		JOIN mst_product p ON td.id_product = p.id_product
-		WHERE m.id_merchant = (
-			SELECT
-				m.id_merchant
-			FROM mst_merchant m
-			WHERE m.id_user = $1
-		)
-		ORDER BY t.transaction_date DESC`
+// rowQuerier is satisfied by *sql.DB and *sql.Tx, so a quote prices its lines with the queries of Create.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
-	r.log.Info("Starting to retrive all transactions in the repository layer", nil)
+// priceLines fills in the nominal and the price of every line of details and sums the nominal the merchant is
+// debited. The lines with an override get the override price, the price they replaced is returned by line index for
+// the audit log.
+func (r *transactionRepository) priceLines(ctx context.Context, q rowQuerier, merchantId string, details []entity.TransactionDetail) (float64, map[int]float64, error) {
+	log := r.log.WithContext(ctx)
+	var totalNominal float64
+	overriddenPrices := make(map[int]float64)
+	for i := range details {
+		detail := &details[i]
+		var productStatus string
+		if err := q.QueryRowContext(ctx,
+			selectProductSnapshot,
+			detail.ProductId,
+			merchantId,
+			entity.PriceSourceCatalog,
+			entity.PriceSourceMerchant,
+		).Scan(&detail.Nominal, &detail.Price, &detail.PriceSource, &productStatus); err != nil {
+			log.Error("Failed to fetch the product nominal and price", err)
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, nil, fmt.Errorf("%w: %s", ErrProductNotFound, detail.ProductId)
+			}
+			return 0, nil, fmt.Errorf("fetch the product nominal and price: %w", err)
+		}
+		if productStatus != entity.ProductStatusActive {
+			log.Error("Product of the transaction is not active: ", detail.ProductId)
+			return 0, nil, fmt.Errorf("%w: line %d, product %s is %s", ErrProductUnavailable, i+1, detail.ProductId, productStatus)
+		}
+		if detail.OverridePrice != nil {
+			overriddenPrices[i] = detail.Price
+			detail.Price, detail.PriceSource = *detail.OverridePrice, entity.PriceSourceOverride
+		}
+		totalNominal += detail.Nominal
+	}
+	return totalNominal, overriddenPrices, nil
+}
 
-	rows, err := r.db.Query(selectQuery, userId)
+// Quote reads the balance without the lock of Create, the balance can change before the transaction is made and
+// Create checks it again.
+func (r *transactionRepository) Quote(ctx context.Context, payload entity.TransactionQuoteReq) (custom.TransactionQuote, error) {
+	log := r.log.WithContext(ctx)
+	log.Info("Starting to quote a transaction in the repository layer", nil)
+
+	var balance float64
+	err := r.db.QueryRowContext(ctx, "SELECT balance FROM mst_merchant WHERE id_merchant = $1", payload.MerchantId).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Error("Merchant of the quote not found: ", payload.MerchantId)
+		return custom.TransactionQuote{}, ErrMerchantNotFound
+	}
 	if err != nil {
-		r.log.Error("Failed to retrieve the transactions", err)
-		return nil, err
+		log.Error("Failed to fetch merchant balance", err)
+		return custom.TransactionQuote{}, fmt.Errorf("fetch merchant balance: %w", err)
 	}
-	defer rows.Close()
 
-	transactionMap := make(map[string]*custom.TransactionsReq)
+	details := make([]entity.TransactionDetail, len(payload.TransactionDetail))
+	for i, line := range payload.TransactionDetail {
+		details[i].ProductId = line.ProductId
+	}
+	totalNominal, _, err := r.priceLines(ctx, r.db, payload.MerchantId, details)
+	if err != nil {
+		return custom.TransactionQuote{}, err
+	}
 
-	for rows.Next() {
-		var (
-			transaction       custom.TransactionsReq
-			user              custom.UserRes
-			merchant          custom.MerchantRes
-			transactionDetail custom.TransactionDetailReq
-			product           custom.ProductRes
-		)
+	return custom.TransactionQuote{
+		MerchantId:   payload.MerchantId,
+		TotalNominal: totalNominal,
+		Balance:      balance,
+		Sufficient:   balance >= totalNominal,
+	}, nil
+}
 
-		if err := rows.Scan(
-			&transaction.TransactionsId, &transaction.CustomerName, &transaction.DestinationNumber, &transaction.TransactionDate,
-			&user.Id_user, &user.Username, &user.Role,
-			&merchant.IdMerchant, &merchant.NameMerchant, &merchant.Address,
-			&transactionDetail.TransactionDetailId, &transactionDetail.TransactionsId,
-			&product.IdProduct, &product.NameProvider, &product.Nominal, &product.Price,
-		); err != nil {
-			r.log.Error("Failed to scan transactions", err)
-			return nil, err
-		}
+// isSerializationFailure reports the error Postgres aborts a transaction with when it conflicts with a concurrent
+// one (40001), the transaction did nothing and can run again.
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "40001"
+}
 
-		transactionDetail.Product = product
+// checkExists runs an EXISTS query for id and returns notFound when it is false.
+func (r *transactionRepository) checkExists(ctx context.Context, query, id string, notFound error) error {
+	log := r.log.WithContext(ctx)
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		log.Error("Failed to check the transaction references", err)
+		return fmt.Errorf("check the transaction references: %w", err)
+	}
+	if !exists {
+		log.Error("Transaction reference not found: ", id)
+		return notFound
+	}
+	return nil
+}
 
-		if existingTransaction, ok := transactionMap[transaction.TransactionsId]; ok {
-			existingTransaction.TransactionDetail = append(existingTransaction.TransactionDetail, transactionDetail)
-		} else {
-			transaction.User = user
-			transaction.Merchant = merchant
-			transaction.TransactionDetail = []custom.TransactionDetailReq{transactionDetail}
-			transactionMap[transaction.TransactionsId] = &transaction
+func (r *transactionRepository) notifyLowBalance(ctx context.Context, merchantId string, balance, threshold float64) {
+	log := r.log.WithContext(ctx)
+	data := map[string]interface{}{
+		"merchantId": merchantId,
+		"balance":    balance,
+		"threshold":  threshold,
+	}
+
+	log.Info("Merchant balance dropped below the low balance threshold", data)
+	if err := r.notifier.Notify(merchantId, "Low merchant balance", data); err != nil {
+		log.Error("Failed to send low balance notification", err)
+	}
+}
+
+// GetAll lists the transactions of the merchants, an empty status lists them whatever their status. A limit pages
+// the transactions rather than their detail rows, so a transaction is never split across two pages.
+func (r *transactionRepository) GetAll(ctx context.Context, filter custom.TransactionFilter) (custom.TransactionPage, error) {
+	log := r.log.WithContext(ctx)
+	orderBy, ok := transactionSortOrders[filter.Sort]
+	if !ok {
+		log.Error("Invalid transactions sort: ", filter.Sort)
+		return custom.TransactionPage{}, ErrInvalidTransactionSort
+	}
+	if filter.Status != "" && !transactionStatuses[filter.Status] {
+		log.Error("Invalid transactions status: ", filter.Status)
+		return custom.TransactionPage{}, ErrInvalidTransactionStatus
+	}
+
+	where := "t.id_merchant = ANY($1) AND ($2 = '' OR t.status = $2)"
+	args := []any{pq.Array(filter.MerchantIds), filter.Status}
+	if filter.Limit > 0 {
+		pageQuery := "SELECT t.transaction_id FROM transactions t WHERE " + where
+		if filter.Cursor != "" {
+			date, id, err := decodeTransactionCursor(filter.Cursor, filter.Sort)
+			if err != nil {
+				log.Error("Invalid transactions cursor: ", filter.Cursor)
+				return custom.TransactionPage{}, err
+			}
+			pageQuery += " AND " + transactionCursorConditions[filter.Sort]
+			args = append(args, date, id)
+		}
+		// one transaction more than the page tells whether there is a next page
+		pageQuery += fmt.Sprintf(" ORDER BY %s LIMIT $%d", orderBy, len(args)+1)
+		args = append(args, filter.Limit+1)
+		if filter.Cursor == "" && filter.Page > 1 {
+			pageQuery += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+			args = append(args, (filter.Page-1)*filter.Limit)
 		}
+		where += " AND t.transaction_id IN (" + pageQuery + ")"
 	}
 
-	if err := rows.Err(); err != nil {
-		r.log.Error("Rows not found", err)
-		return nil, err
+	selectQuery := `
+		SELECT ` + transactionColumns + `
+		FROM ` + transactionJoins + `
+		WHERE ` + where + `
+		ORDER BY ` + orderBy
+
+	log.Info("Starting to retrive all transactions in the repository layer", nil)
+
+	rows, err := r.db.QueryContext(ctx, selectQuery, args...)
+	if err != nil {
+		log.Error("Failed to retrieve the transactions", err)
+		return custom.TransactionPage{}, fmt.Errorf("retrieve the transactions: %w", err)
 	}
+	defer rows.Close()
 
-	transactions := make([]custom.TransactionsReq, 0, len(transactionMap))
-	for _, transaction := range transactionMap {
-		transactions = append(transactions, *transaction)
+	transactions, err := scanTransactionRows(rows)
+	if err != nil {
+		log.Error("Failed to scan transactions", err)
+		return custom.TransactionPage{}, fmt.Errorf("scan transactions: %w", err)
 	}
 
-	r.log.Info("Successfully Get the transactions list", transactions)
-	return transactions, nil
+	page := custom.TransactionPage{Transactions: transactions}
+	if filter.Limit > 0 && len(transactions) > filter.Limit {
+		page.Transactions = transactions[:filter.Limit]
+		if _, ok := transactionCursorConditions[filter.Sort]; ok {
+			last := page.Transactions[filter.Limit-1]
+			page.NextCursor = encodeTransactionCursor(filter.Sort, last.TransactionDate, last.TransactionsId)
+		}
+	}
+
+	log.Info("Successfully Get the transactions list", page.Transactions)
+	return page, nil
+}
+
+// encodeTransactionCursor makes the opaque cursor of the page after the transaction, the sort is kept in it so a
+// cursor of one sort is not used to page another.
+func encodeTransactionCursor(sort string, date time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sort + "|" + date.Format(time.DateOnly) + "|" + id))
 }
 
-func (r *transactionRepository) GetById(id string) (custom.TransactionsReq, error) {
+// decodeTransactionCursor reads the date and the id back from a cursor of encodeTransactionCursor.
+func decodeTransactionCursor(cursor, sort string) (string, string, error) {
+	if _, ok := transactionCursorConditions[sort]; !ok {
+		return "", "", ErrInvalidTransactionCursor
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", ErrInvalidTransactionCursor
+	}
+	parts := strings.Split(string(decoded), "|")
+	if len(parts) != 3 || parts[0] != sort || parts[2] == "" {
+		return "", "", ErrInvalidTransactionCursor
+	}
+	if _, err := time.Parse(time.DateOnly, parts[1]); err != nil {
+		return "", "", ErrInvalidTransactionCursor
+	}
+	return parts[1], parts[2], nil
+}
+
+func (r *transactionRepository) GetById(ctx context.Context, id string) (custom.TransactionsReq, error) {
+	log := r.log.WithContext(ctx)
 	selectQuery := `
-	SELECT
-		t.transaction_id, t.customer_name, t.destination_number, t.transaction_date,
-		u.id_user, u.username, u.role,
-		m.id_merchant, m.name_merchant, m.address,
-		td.transaction_detail_id, p.id_product, p.name_provider, p.nominal, p.price
-		
-	FROM transactions t
-	JOIN mst_user u ON t.id_user = u.id_user
-	JOIN mst_merchant m ON t.id_merchant = m.id_merchant
-	JOIN transaction_detail td ON t.transaction_id = td.transaction_id
-	JOIN mst_product p ON td.id_product = p.id_product
+	SELECT ` + transactionColumns + `
+	FROM ` + transactionJoins + `
 	WHERE t.transaction_id = $1
 	`
-	r.log.Info("Starting to retrive transaction by id in the repository layer", nil)
-	rows, err := r.db.Query(selectQuery, id)
+	log.Info("Starting to retrive transaction by id in the repository layer", nil)
+	rows, err := r.db.QueryContext(ctx, selectQuery, id)
 	if err != nil {
-		r.log.Error("Failed to retrieve the transaction", err)
-		return custom.TransactionsReq{}, err
+		log.Error("Failed to retrieve the transaction", err)
+		return custom.TransactionsReq{}, fmt.Errorf("retrieve the transaction: %w", err)
 	}
 	defer rows.Close()
 
-	var transaction custom.TransactionsReq
-	transactionDetailMap := make(map[string]custom.TransactionDetailReq)
+	transactions, err := scanTransactionRows(rows)
+	if err != nil {
+		log.Error("Failed to scan transaction", err)
+		return custom.TransactionsReq{}, fmt.Errorf("scan transaction: %w", err)
+	}
+	if len(transactions) == 0 {
+		log.Error("Transaction not found: ", id)
+		return custom.TransactionsReq{}, ErrTransactionNotFound
+	}
+	log.Info("Successfully Get the transaction by given id", transactions[0])
+	return transactions[0], nil
+}
+
+// RefundDetail gives the nominal of one detail line back to the merchant and marks the line refunded, the other
+// lines of the transaction are left as they are.
+func (r *transactionRepository) RefundDetail(ctx context.Context, detailId string) error {
+	log := r.log.WithContext(ctx)
+	log.Info("Starting to refund a transaction detail in the repository layer", nil)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed start db transaction", err)
+		return fmt.Errorf("start db transaction: %w", err)
+	}
+
+	// the row lock keeps a concurrent refund of the same line waiting until this one is committed
+	var (
+		merchantId string
+		nominal    float64
+		refunded   bool
+	)
+	// the nominal charged at purchase goes back, whatever the product costs now
+	err = tx.QueryRowContext(ctx, `SELECT t.id_merchant, td.nominal, td.refunded_at IS NOT NULL
+		FROM transaction_detail td
+		JOIN transactions t ON t.transaction_id = td.transaction_id
+		WHERE td.transaction_detail_id = $1
+		FOR UPDATE OF td`, detailId).Scan(&merchantId, &nominal, &refunded)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		log.Error("Transaction detail not found: ", detailId)
+		return ErrTransactionDetailNotFound
+	}
+	if err != nil {
+		tx.Rollback()
+		log.Error("Failed to fetch the transaction detail", err)
+		return fmt.Errorf("fetch the transaction detail: %w", err)
+	}
+	if refunded {
+		tx.Rollback()
+		log.Error("Transaction detail is already refunded: ", detailId)
+		return ErrDetailAlreadyRefunded
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE transaction_detail SET refunded_at = NOW() WHERE transaction_detail_id = $1`, detailId); err != nil {
+		tx.Rollback()
+		log.Error("Failed to mark the transaction detail refunded", err)
+		return fmt.Errorf("mark the transaction detail refunded: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE mst_merchant SET balance = balance + $1 WHERE id_merchant = $2`, nominal, merchantId); err != nil {
+		tx.Rollback()
+		log.Error("Failed to refund the merchant balance", err)
+		return fmt.Errorf("refund the merchant balance: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Failed to commit transaction", err)
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	log.Info("Transaction detail refunded to the merchant balance", map[string]interface{}{
+		"detailId":   detailId,
+		"merchantId": merchantId,
+		"nominal":    nominal,
+	})
+	return nil
+}
+
+// transactionColumns and transactionJoins are shared by every query that is read with scanTransactionRows,
+// the column order has to match the Scan call there. The nominal and price are the ones stored with the detail, so
+// the history keeps showing what was paid after the product price changes.
+const (
+	transactionColumns = `
+		t.transaction_id, t.customer_name, t.destination_number, t.transaction_date, t.created_at, t.updated_at, t.status,
+		u.id_user, u.username, u.role,
+		m.id_merchant, m.name_merchant, m.address,
+		td.transaction_detail_id, td.transaction_id, td.refunded_at, p.id_product, pv.id_provider, pv.name_provider, td.nominal, td.price`
+	transactionJoins = `transactions t
+		JOIN mst_user u ON t.id_user = u.id_user
+		JOIN mst_merchant m ON t.id_merchant = m.id_merchant
+		JOIN transaction_detail td ON t.transaction_id = td.transaction_id
+		JOIN mst_product p ON td.id_product = p.id_product
+		JOIN mst_provider pv ON p.id_provider = pv.id_provider`
+)
+
+// scanTransactionRows reads one row per transaction detail and groups the details under their transaction.
+// The transactions keep the order in which they first appear in the rows.
+func scanTransactionRows(rows *sql.Rows) ([]custom.TransactionsReq, error) {
+	transactionMap := make(map[string]*custom.TransactionsReq)
+	var order []string
 
-	//loop and process the first row after checking
 	for rows.Next() {
 		var (
-			user              custom.UserRes
-			merchant          custom.MerchantRes
+			transaction       custom.TransactionsReq
 			transactionDetail custom.TransactionDetailReq
 			product           custom.ProductRes
+			// nullable in databases created before the columns were NOT NULL, one such row must not fail the page
+			transactionDate sql.NullTime
+			address         sql.NullString
 		)
+
 		if err := rows.Scan(
-			&transaction.TransactionsId, &transaction.CustomerName, &transaction.DestinationNumber, &transaction.TransactionDate,
-			&user.Id_user, &user.Username, &user.Role,
-			&merchant.IdMerchant, &merchant.NameMerchant, &merchant.Address,
-			&transactionDetail.TransactionDetailId,
-			&product.IdProduct, &product.NameProvider, &product.Nominal, &product.Price); err != nil {
-			r.log.Error("Failed to scan transaction", err)
-			return custom.TransactionsReq{}, err
+			&transaction.TransactionsId, &transaction.CustomerName, &transaction.DestinationNumber, &transactionDate,
+			&transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Status,
+			&transaction.User.Id_user, &transaction.User.Username, &transaction.User.Role,
+			&transaction.Merchant.IdMerchant, &transaction.Merchant.NameMerchant, &address,
+			&transactionDetail.TransactionDetailId, &transactionDetail.TransactionsId, &transactionDetail.RefundedAt,
+			&product.IdProduct, &product.IdProvider, &product.NameProvider, &product.Nominal, &product.Price,
+		); err != nil {
+			return nil, err
+		}
+		// a transaction without a business date is shown on the day it was recorded, a missing address as empty
+		transaction.TransactionDate = transactionDate.Time
+		if !transactionDate.Valid {
+			transaction.TransactionDate = transaction.CreatedAt
 		}
-		transaction.User = user
-		transaction.Merchant = merchant
+		transaction.Merchant.Address = address.String
 		transactionDetail.Product = product
 
-		//store transaction detail in the map
-		transactionDetailMap[transactionDetail.TransactionDetailId] = transactionDetail
-
-		//continue iterating if more row are present
-		if !rows.Next() {
-			break
+		if existingTransaction, ok := transactionMap[transaction.TransactionsId]; ok {
+			existingTransaction.TransactionDetail = append(existingTransaction.TransactionDetail, transactionDetail)
+			continue
 		}
+		transaction.TransactionDetail = []custom.TransactionDetailReq{transactionDetail}
+		transactionMap[transaction.TransactionsId] = &transaction
+		order = append(order, transaction.TransactionsId)
 	}
-	for _, detail := range transactionDetailMap {
-		transaction.TransactionDetail = append(transaction.TransactionDetail, detail)
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	r.log.Info("Successfully Get the transaction by given id", transaction)
-	return transaction, nil
+
+	transactions := make([]custom.TransactionsReq, 0, len(order))
+	for _, id := range order {
+		transactions = append(transactions, *transactionMap[id])
+	}
+	return transactions, nil
 }
 
 // func (r *transactionRepository) Update(payload entity.Transactions) (entity.Transactions, error) {
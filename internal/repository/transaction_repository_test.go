@@ -1,15 +1,20 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"regexp"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/service_mock"
 	"server-pulsa-app/internal/shared/custom"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -36,6 +41,8 @@ var (
 		CustomerName:      "John Doe",
 		DestinationNumber: "081234567890",
 		TransactionDate:   time.Now(),
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
 		User: custom.UserRes{
 			Id_user:  "user-uuid",
 			Username: "testuser",
@@ -61,11 +68,20 @@ var (
 	}
 )
 
+// transactionRowColumns are the columns read by scanTransactionRows.
+var transactionRowColumns = []string{
+	"transaction_id", "customer_name", "destination_number", "transaction_date", "created_at", "updated_at", "status",
+	"id_user", "username", "role",
+	"id_merchant", "name_merchant", "address",
+	"transaction_detail_id", "transaction_id", "refunded_at", "id_product", "id_provider", "name_provider", "nominal", "price",
+}
+
 type transactionRepositoryTestSuite struct {
 	suite.Suite
 	mockDb          *sql.DB
 	mockSql         sqlmock.Sqlmock
 	transactionRepo TransactionRepository
+	notifier        *service_mock.NotifierMock
 	log             logger.Logger
 }
 
@@ -79,7 +95,9 @@ func (s *transactionRepositoryTestSuite) SetupTest() {
 
 	s.mockDb = mockDb
 	s.mockSql = mockSql
-	s.transactionRepo = NewTransactionRepository(mockDb, &s.log)
+	s.log = logger.NewLogger()
+	s.notifier = new(service_mock.NotifierMock)
+	s.transactionRepo = NewTransactionRepository(mockDb, &s.log, s.notifier)
 }
 
 func (s *transactionRepositoryTestSuite) TearDownTest() {
@@ -87,94 +105,448 @@ func (s *transactionRepositoryTestSuite) TearDownTest() {
 }
 
 func (s *transactionRepositoryTestSuite) TestCreate_Success() {
-	// Mock merchant existence check
-	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM mst_merchant WHERE id_merchant = $1)`)).
-		WithArgs(expectedTransaction.MerchantId).
-		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	s.expectCreate(100000, 0, 10000)
 
-	// Mock user existence check
-	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM mst_user WHERE id_user = $1)`)).
-		WithArgs(expectedTransaction.UserId).
-		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
-
-	// Mock transaction begin
-	s.mockSql.ExpectBegin()
+	result, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
 
-	// Mock transaction insert
-	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transactions`)).
-		WithArgs(
-			expectedTransaction.MerchantId,
-			expectedTransaction.UserId,
-			expectedTransaction.CustomerName,
-			expectedTransaction.DestinationNumber,
-			sqlmock.AnyArg(), // For the parsed date
-		).
-		WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}).AddRow(expectedTransaction.TransactionsId))
-
-	// Mock product price query
-	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM mst_product WHERE id_product = $1)`)).
-		WithArgs(expectedTransaction.TransactionDetail[0].ProductId).
-		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
-
-	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT price FROM mst_product WHERE id_product = $1`)).
-		WithArgs(expectedTransaction.TransactionDetail[0].ProductId).
-		WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(50000))
-
-	// Mock transaction detail insert
-	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transaction_detail`)).
-		WithArgs(
-			expectedTransaction.TransactionsId,
-			expectedTransaction.TransactionDetail[0].ProductId,
-			50000,
-		).
-		WillReturnRows(sqlmock.NewRows([]string{"transaction_detail_id"}).AddRow("detail-uuid"))
+	s.NoError(err)
+	s.Equal(expectedTransaction.TransactionsId, result.TransactionsId)
+	s.Equal(expectedTransaction.CustomerName, result.CustomerName)
+	s.Equal(float64(10500), result.TransactionDetail[0].Price)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
 
-	// Mock commit
-	s.mockSql.ExpectCommit()
+func (s *transactionRepositoryTestSuite) TestCreate_ReturnsBalanceAfterDebit() {
+	s.expectCreate(75000, 0, 20000)
 
-	result, err := s.transactionRepo.Create(expectedTransaction)
+	result, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
 
 	s.NoError(err)
-	s.Equal(expectedTransaction.TransactionsId, result.TransactionsId)
-	s.Equal(expectedTransaction.CustomerName, result.CustomerName)
+	// the committed balance from RETURNING, the pre-balance minus the total nominal
+	s.Equal(float64(75000-20000), result.Balance)
+	s.NoError(s.mockSql.ExpectationsWereMet())
 }
 
 func (s *transactionRepositoryTestSuite) TestCreate_InvalidDate() {
 	invalidTransaction := expectedTransaction
 	invalidTransaction.TransactionDate = "invalid-date"
 
-	result, err := s.transactionRepo.Create(invalidTransaction)
+	result, err := s.transactionRepo.Create(context.Background(), invalidTransaction, "user-uuid")
 
 	s.Error(err)
 	s.Contains(err.Error(), "invalid date format")
 	s.Equal(entity.Transactions{}, result)
 }
 
+func (s *transactionRepositoryTestSuite) TestCreate_OmittedDateIsToday() {
+	undated := expectedTransaction
+	undated.TransactionDate = ""
+	s.expectCreate(100000, 0, 10000)
+
+	before := time.Now()
+	result, err := s.transactionRepo.Create(context.Background(), undated, "user-uuid")
+	after := time.Now()
+
+	s.NoError(err)
+	s.Contains([]string{before.Format("02-01-2006"), after.Format("02-01-2006")}, result.TransactionDate)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
 func (s *transactionRepositoryTestSuite) TestCreate_MerchantNotFound() {
 	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM mst_merchant WHERE id_merchant = $1)`)).
 		WithArgs(expectedTransaction.MerchantId).
 		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
 
-	result, err := s.transactionRepo.Create(expectedTransaction)
+	result, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
 
 	s.Error(err)
 	s.Equal("merchant not found", err.Error())
 	s.Equal(entity.Transactions{}, result)
 }
 
+// expectQuote expects the reads of Quote for one product of the nominal, nothing is begun, inserted or updated.
+func (s *transactionRepositoryTestSuite) expectQuote(balance, nominal float64) {
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT balance FROM mst_merchant WHERE id_merchant = $1`)).
+		WithArgs("merchant-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(balance))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT p.nominal, COALESCE(mpp.price, p.price)`)).
+		WithArgs("product-uuid", "merchant-uuid", entity.PriceSourceCatalog, entity.PriceSourceMerchant).
+		WillReturnRows(sqlmock.NewRows([]string{"nominal", "price", "price_source", "status"}).AddRow(nominal, nominal+500, entity.PriceSourceCatalog, entity.ProductStatusActive))
+}
+
+var quoteRequest = entity.TransactionQuoteReq{
+	MerchantId:        "merchant-uuid",
+	TransactionDetail: []entity.TransactionQuoteLine{{ProductId: "product-uuid"}},
+}
+
+func (s *transactionRepositoryTestSuite) TestQuote_SufficientBalance() {
+	s.expectQuote(100000, 10000)
+
+	quote, err := s.transactionRepo.Quote(context.Background(), quoteRequest)
+
+	s.NoError(err)
+	s.Equal(custom.TransactionQuote{MerchantId: "merchant-uuid", TotalNominal: 10000, Balance: 100000, Sufficient: true}, quote)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestQuote_InsufficientBalance() {
+	s.expectQuote(5000, 10000)
+
+	quote, err := s.transactionRepo.Quote(context.Background(), quoteRequest)
+
+	s.NoError(err)
+	s.Equal(custom.TransactionQuote{MerchantId: "merchant-uuid", TotalNominal: 10000, Balance: 5000, Sufficient: false}, quote)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestQuote_MerchantNotFound() {
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT balance FROM mst_merchant WHERE id_merchant = $1`)).
+		WithArgs("merchant-uuid").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := s.transactionRepo.Quote(context.Background(), quoteRequest)
+
+	s.ErrorIs(err, ErrMerchantNotFound)
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_UserNotFound() {
+	s.expectReferences(true, false)
+
+	result, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.ErrorIs(err, ErrUserNotFound)
+	s.Equal(entity.Transactions{}, result)
+	// nothing is locked for an unknown user
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_ProductNotFound() {
+	s.expectReferences(true, true)
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(balance, 0), low_balance_threshold, daily_limit_count, daily_limit_amount FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE`)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(100000, 0, 0, 0))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT p.nominal, COALESCE(mpp.price, p.price)`)).
+		WithArgs(expectedTransaction.TransactionDetail[0].ProductId, expectedTransaction.MerchantId, entity.PriceSourceCatalog, entity.PriceSourceMerchant).
+		WillReturnError(sql.ErrNoRows)
+	s.mockSql.ExpectRollback()
+
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.ErrorIs(err, ErrProductNotFound)
+	s.Contains(err.Error(), expectedTransaction.TransactionDetail[0].ProductId)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) expectReferences(merchantExists, userExists bool) {
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM mst_merchant WHERE id_merchant = $1)`)).
+		WithArgs(expectedTransaction.MerchantId).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(merchantExists))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM mst_user WHERE id_user = $1)`)).
+		WithArgs(expectedTransaction.UserId).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(userExists))
+}
+
+func (s *transactionRepositoryTestSuite) expectCreate(balance, threshold, nominal float64) {
+	s.expectReferences(true, true)
+	s.expectCreateTx(balance, threshold, nominal)
+}
+
+// expectCreateTx expects the db transaction of Create, without the reference checks that run once before it.
+func (s *transactionRepositoryTestSuite) expectCreateTx(balance, threshold, nominal float64) {
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(balance, 0), low_balance_threshold, daily_limit_count, daily_limit_amount FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE`)).
+		WithArgs(expectedTransaction.MerchantId).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(balance, threshold, 0, 0))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT p.nominal, COALESCE(mpp.price, p.price)`)).
+		WithArgs(expectedTransaction.TransactionDetail[0].ProductId, expectedTransaction.MerchantId, entity.PriceSourceCatalog, entity.PriceSourceMerchant).
+		WillReturnRows(sqlmock.NewRows([]string{"nominal", "price", "price_source", "status"}).AddRow(nominal, nominal+500, entity.PriceSourceCatalog, entity.ProductStatusActive))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transactions`)).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "created_at", "updated_at"}).AddRow(expectedTransaction.TransactionsId, time.Now(), time.Now()))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transaction_detail`)).
+		WithArgs(expectedTransaction.TransactionsId, expectedTransaction.TransactionDetail[0].ProductId, nominal, nominal+500, entity.PriceSourceCatalog).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_detail_id"}).AddRow("detail-uuid"))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`UPDATE mst_merchant`)).
+		WithArgs(nominal, expectedTransaction.MerchantId).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(balance - nominal))
+	s.mockSql.ExpectCommit()
+}
+
+// expectSerializationFailure expects a db transaction of Create that the database aborts on the merchant lock.
+func (s *transactionRepositoryTestSuite) expectSerializationFailure() {
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(balance, 0), low_balance_threshold`)).
+		WithArgs(expectedTransaction.MerchantId).
+		WillReturnError(&pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"})
+	s.mockSql.ExpectRollback()
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_RetriesSerializationFailure() {
+	s.expectReferences(true, true)
+	s.expectSerializationFailure()
+	s.expectCreateTx(100000, 0, 10000)
+
+	result, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.NoError(err)
+	s.Equal(expectedTransaction.TransactionsId, result.TransactionsId)
+	s.Equal(float64(90000), result.Balance)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_GivesUpAfterMaxSerializationRetries() {
+	s.expectReferences(true, true)
+	for i := 0; i <= maxSerializationRetries; i++ {
+		s.expectSerializationFailure()
+	}
+
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.True(isSerializationFailure(err), "the last serialization failure is returned, got %v", err)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_DoesNotRetryOtherErrors() {
+	s.expectReferences(true, true)
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(balance, 0), low_balance_threshold`)).
+		WillReturnError(&pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"})
+	s.mockSql.ExpectRollback()
+
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.Error(err)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_LowBalanceAlertFiresOnce() {
+	s.notifier.On("Notify", expectedTransaction.MerchantId, "Low merchant balance", mock.Anything).Return(nil).Once()
+
+	// 15000 -> 5000 crosses the 10000 threshold
+	s.expectCreate(15000, 10000, 10000)
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+	s.NoError(err)
+
+	// 5000 -> 0 is already below the threshold, no new alert
+	s.expectCreate(5000, 10000, 5000)
+	_, err = s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+	s.NoError(err)
+
+	s.NoError(s.mockSql.ExpectationsWereMet())
+	s.notifier.AssertNumberOfCalls(s.T(), "Notify", 1)
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_NoLowBalanceAlertAboveThreshold() {
+	s.expectCreate(50000, 10000, 10000)
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+	s.NoError(err)
+
+	s.notifier.AssertNotCalled(s.T(), "Notify")
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_PriceOverride() {
+	overridePrice := 9800.0
+	discounted := expectedTransaction
+	discounted.TransactionDetail = []entity.TransactionDetail{{ProductId: "product-uuid", OverridePrice: &overridePrice, OverrideReason: "loyal customer"}}
+
+	s.expectReferences(true, true)
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(balance, 0), low_balance_threshold, daily_limit_count, daily_limit_amount FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE`)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(100000, 0, 0, 0))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT p.nominal, COALESCE(mpp.price, p.price)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nominal", "price", "price_source", "status"}).AddRow(10000, 10500, entity.PriceSourceCatalog, entity.ProductStatusActive))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transactions`)).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "created_at", "updated_at"}).AddRow(expectedTransaction.TransactionsId, time.Now(), time.Now()))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transaction_detail`)).
+		WithArgs(expectedTransaction.TransactionsId, "product-uuid", 10000.0, 9800.0, entity.PriceSourceOverride).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_detail_id"}).AddRow("detail-uuid"))
+	s.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log (actor_id, action, target_id, detail) VALUES ($1, $2, $3, $4)")).
+		WithArgs("uuid-cashier", entity.AuditActionPriceOverride, "detail-uuid", "price 9800 instead of 10500: loyal customer").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// the balance still pays the nominal, not the discounted price
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`UPDATE mst_merchant`)).
+		WithArgs(10000.0, expectedTransaction.MerchantId).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(90000))
+	s.mockSql.ExpectCommit()
+
+	result, err := s.transactionRepo.Create(context.Background(), discounted, "uuid-cashier")
+
+	s.NoError(err)
+	s.Equal(9800.0, result.TransactionDetail[0].Price)
+	s.Equal(entity.PriceSourceOverride, result.TransactionDetail[0].PriceSource)
+	s.Equal(90000.0, result.Balance)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_SetsCreatedAtFromDatabase() {
+	// the client backdates the transaction, created_at still has to be the insert time from the database
+	backdated := expectedTransaction
+	backdated.TransactionDate = "01-01-2020"
+	insertedAt := time.Date(2024, 10, 25, 14, 5, 9, 0, time.UTC)
+
+	s.expectReferences(true, true)
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(balance, 0), low_balance_threshold, daily_limit_count, daily_limit_amount FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE`)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(100000, 0, 0, 0))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT p.nominal, COALESCE(mpp.price, p.price)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nominal", "price", "price_source", "status"}).AddRow(10000, 10500, entity.PriceSourceCatalog, entity.ProductStatusActive))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transactions (id_merchant, id_user, customer_name, destination_number, transaction_date) VALUES ($1, $2, $3, $4, $5) RETURNING transaction_id, created_at, updated_at`)).
+		WithArgs(backdated.MerchantId, backdated.UserId, backdated.CustomerName, backdated.DestinationNumber, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "created_at", "updated_at"}).AddRow(backdated.TransactionsId, insertedAt, insertedAt))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transaction_detail`)).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_detail_id"}).AddRow("detail-uuid"))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`UPDATE mst_merchant`)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(90000))
+	s.mockSql.ExpectCommit()
+
+	result, err := s.transactionRepo.Create(context.Background(), backdated, "user-uuid")
+
+	s.NoError(err)
+	s.Equal("01-01-2020", result.TransactionDate)
+	s.Equal(insertedAt, result.CreatedAt)
+	s.Equal(insertedAt, result.UpdatedAt)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+// expectDailyLimitCheck expects Create up to the daily usage query of a merchant with a 100000 balance.
+func (s *transactionRepositoryTestSuite) expectDailyLimitCheck(limitCount int, limitAmount float64, todayCount int, todayAmount, nominal float64) {
+	s.expectReferences(true, true)
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(balance, 0), low_balance_threshold, daily_limit_count, daily_limit_amount FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE`)).
+		WithArgs(expectedTransaction.MerchantId).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(100000, 0, limitCount, limitAmount))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT p.nominal, COALESCE(mpp.price, p.price)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nominal", "price", "price_source", "status"}).AddRow(nominal, nominal+500, entity.PriceSourceCatalog, entity.ProductStatusActive))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT t.transaction_id)`)).
+		WithArgs(expectedTransaction.MerchantId).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(todayCount, todayAmount))
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_WithinDailyLimit() {
+	// the last transaction the count allows, and exactly the remaining amount
+	s.expectDailyLimitCheck(3, 50000, 2, 40000, 10000)
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transactions`)).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "created_at", "updated_at"}).AddRow(expectedTransaction.TransactionsId, time.Now(), time.Now()))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transaction_detail`)).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_detail_id"}).AddRow("detail-uuid"))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`UPDATE mst_merchant`)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(90000))
+	s.mockSql.ExpectCommit()
+
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.NoError(err)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_DailyCountExceeded() {
+	s.expectDailyLimitCheck(3, 0, 3, 30000, 10000)
+	s.mockSql.ExpectRollback()
+
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.ErrorIs(err, ErrDailyLimitExceeded)
+	s.Contains(err.Error(), "3 of 3 transactions")
+	// nothing was inserted or debited
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_DailyAmountExceeded() {
+	s.expectDailyLimitCheck(0, 50000, 4, 45000, 10000)
+	s.mockSql.ExpectRollback()
+
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.ErrorIs(err, ErrDailyLimitExceeded)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_InsufficientBalance() {
+	s.expectReferences(true, true)
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(5000, 0, 0, 0))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT p.nominal, COALESCE(mpp.price, p.price)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"nominal", "price", "price_source", "status"}).AddRow(10000, 10500, entity.PriceSourceCatalog, entity.ProductStatusActive))
+	s.mockSql.ExpectRollback()
+
+	_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.ErrorIs(err, ErrInsufficientBalance)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_UsesMerchantPriceOverride() {
+	productId := expectedTransaction.TransactionDetail[0].ProductId
+
+	s.expectReferences(true, true)
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(balance, 0), low_balance_threshold, daily_limit_count, daily_limit_amount FROM mst_merchant WHERE id_merchant = $1 FOR UPDATE`)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(100000, 0, 0, 0))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`LEFT JOIN merchant_product_price mpp`)).
+		WithArgs(productId, expectedTransaction.MerchantId, entity.PriceSourceCatalog, entity.PriceSourceMerchant).
+		WillReturnRows(sqlmock.NewRows([]string{"nominal", "price", "price_source", "status"}).AddRow(10000, 10200, entity.PriceSourceMerchant, entity.ProductStatusActive))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transactions`)).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "created_at", "updated_at"}).AddRow(expectedTransaction.TransactionsId, time.Now(), time.Now()))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transaction_detail (transaction_id, id_product, nominal, price, price_source)`)).
+		WithArgs(expectedTransaction.TransactionsId, productId, float64(10000), float64(10200), entity.PriceSourceMerchant).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_detail_id"}).AddRow("detail-uuid"))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`UPDATE mst_merchant`)).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(90000))
+	s.mockSql.ExpectCommit()
+
+	result, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.NoError(err)
+	s.Equal(float64(10200), result.TransactionDetail[0].Price)
+	s.Equal(entity.PriceSourceMerchant, result.TransactionDetail[0].PriceSource)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_InactiveProductRejected() {
+	for _, status := range []string{entity.ProductStatusInactive, entity.ProductStatusDraft} {
+		s.expectReferences(true, true)
+		s.mockSql.ExpectBegin()
+		s.mockSql.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "low_balance_threshold", "daily_limit_count", "daily_limit_amount"}).AddRow(100000, 0, 0, 0))
+		s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT p.nominal, COALESCE(mpp.price, p.price)`)).
+			WillReturnRows(sqlmock.NewRows([]string{"nominal", "price", "price_source", "status"}).AddRow(10000, 10500, entity.PriceSourceCatalog, status))
+		s.mockSql.ExpectRollback()
+
+		_, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+		s.ErrorIs(err, ErrProductUnavailable, status)
+		s.Contains(err.Error(), "line 1, product "+expectedTransaction.TransactionDetail[0].ProductId+" is "+status)
+		// nothing was inserted or debited
+		s.NoError(s.mockSql.ExpectationsWereMet(), status)
+	}
+}
+
+func (s *transactionRepositoryTestSuite) TestCreate_ActiveProductSucceeds() {
+	s.expectCreate(100000, 0, 10000)
+
+	result, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+
+	s.NoError(err)
+	s.Equal(float64(90000), result.Balance)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
 // GetAll Tests
 func (s *transactionRepositoryTestSuite) TestGetAll_Success() {
 	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"transaction_id", "customer_name", "destination_number", "transaction_date",
+			"transaction_id", "customer_name", "destination_number", "transaction_date", "created_at", "updated_at", "status",
 			"id_user", "username", "role",
 			"id_merchant", "name_merchant", "address",
-			"transaction_detail_id", "transaction_id", "id_product", "name_provider", "nominal", "price",
+			"transaction_detail_id", "transaction_id", "refunded_at", "id_product", "id_provider", "name_provider", "nominal", "price",
 		}).AddRow(
 			expectedTransactionReq.TransactionsId,
 			expectedTransactionReq.CustomerName,
 			expectedTransactionReq.DestinationNumber,
 			expectedTransactionReq.TransactionDate,
+			expectedTransactionReq.CreatedAt,
+			expectedTransactionReq.UpdatedAt,
+			entity.TransactionStatusSuccess,
 			expectedTransactionReq.User.Id_user,
 			expectedTransactionReq.User.Username,
 			expectedTransactionReq.User.Role,
@@ -183,32 +555,233 @@ func (s *transactionRepositoryTestSuite) TestGetAll_Success() {
 			expectedTransactionReq.Merchant.Address,
 			expectedTransactionReq.TransactionDetail[0].TransactionDetailId,
 			expectedTransactionReq.TransactionsId,
+			nil,
 			expectedTransactionReq.TransactionDetail[0].Product.IdProduct,
+			expectedTransactionReq.TransactionDetail[0].Product.IdProvider,
 			expectedTransactionReq.TransactionDetail[0].Product.NameProvider,
 			expectedTransactionReq.TransactionDetail[0].Product.Nominal,
 			expectedTransactionReq.TransactionDetail[0].Product.Price,
 		))
 
-	result, err := s.transactionRepo.GetAll("")
+	result, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_desc"})
 
 	s.NoError(err)
-	s.Len(result, 1)
-	s.Equal(expectedTransactionReq.TransactionsId, result[0].TransactionsId)
+	s.Len(result.Transactions, 1)
+	s.Equal(expectedTransactionReq.TransactionsId, result.Transactions[0].TransactionsId)
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_NullableMerchantAddressAndDate() {
+	createdAt := time.Date(2024, 10, 25, 9, 30, 0, 0, time.UTC)
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
+		WillReturnRows(sqlmock.NewRows(transactionRowColumns).
+			AddRow("tx-1", "John", "081234567890", nil, createdAt, createdAt, entity.TransactionStatusSuccess,
+				"user-uuid", "testuser", "admin", "merchant-uuid", "Merchant Without Address", nil,
+				"detail-1", "tx-1", nil, "product-uuid", "provider-uuid", "Test Provider", 50000, 51000).
+			AddRow("tx-2", "Jane", "081234567891", createdAt, createdAt, createdAt, entity.TransactionStatusSuccess,
+				"user-uuid", "testuser", "admin", "merchant-uuid-2", "Test Merchant", "Test Address",
+				"detail-2", "tx-2", nil, "product-uuid", "provider-uuid", "Test Provider", 50000, 51000))
+
+	result, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid", "merchant-uuid-2"}, Sort: "date_desc"})
+
+	s.Require().NoError(err, "one merchant without an address must not fail the whole history")
+	s.Require().Len(result.Transactions, 2)
+	s.Equal("", result.Transactions[0].Merchant.Address)
+	s.Equal(createdAt, result.Transactions[0].TransactionDate, "a missing business date falls back to the creation time")
+	s.Equal("Test Address", result.Transactions[1].Merchant.Address)
 }
 
 func (s *transactionRepositoryTestSuite) TestGetAll_EmptyResult() {
 	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"transaction_id", "customer_name", "destination_number", "transaction_date",
+			"transaction_id", "customer_name", "destination_number", "transaction_date", "created_at", "updated_at", "status",
 			"id_user", "username", "role",
 			"id_merchant", "name_merchant", "address",
-			"transaction_detail_id", "transaction_id", "id_product", "name_provider", "nominal", "price",
+			"transaction_detail_id", "transaction_id", "refunded_at", "id_product", "id_provider", "name_provider", "nominal", "price",
 		}))
 
-	result, err := s.transactionRepo.GetAll("")
+	result, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_desc"})
 
 	s.NoError(err)
-	s.Empty(result)
+	s.Empty(result.Transactions)
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_SortOrders() {
+	columns := []string{
+		"transaction_id", "customer_name", "destination_number", "transaction_date", "created_at", "updated_at", "status",
+		"id_user", "username", "role",
+		"id_merchant", "name_merchant", "address",
+		"transaction_detail_id", "transaction_id", "refunded_at", "id_product", "id_provider", "name_provider", "nominal", "price",
+	}
+	row := func(id, customer string, date time.Time) []driver.Value {
+		return []driver.Value{id, customer, "081234567890", date, date, date, entity.TransactionStatusSuccess, "user-uuid", "eko", "employee", "merchant-uuid", "Konter", "Jombang",
+			"detail-" + id, id, nil, "product-uuid", "provider-uuid", "Telkomsel", 10000, 11000}
+	}
+	older := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	for sort, orderBy := range map[string]string{
+		"date_desc": "ORDER BY t.transaction_date DESC, t.transaction_id",
+		"date_asc":  "ORDER BY t.transaction_date ASC, t.transaction_id",
+		"customer":  "ORDER BY t.customer_name ASC, t.transaction_date DESC, t.transaction_id",
+	} {
+		// the rows come back in the database order, the result has to keep it
+		s.mockSql.ExpectQuery(regexp.QuoteMeta(orderBy)).
+			WithArgs(pq.Array([]string{"merchant-uuid"}), "").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(row("tx-b", "Budi", newer)...).AddRow(row("tx-a", "Ani", older)...))
+
+		result, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: sort})
+
+		s.NoError(err, sort)
+		s.Len(result.Transactions, 2, sort)
+		s.Equal("tx-b", result.Transactions[0].TransactionsId, sort)
+		s.Equal("tx-a", result.Transactions[1].TransactionsId, sort)
+	}
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_FiltersByTokenMerchants() {
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`WHERE t.id_merchant = ANY($1)`)).
+		WithArgs(pq.Array([]string{"merchant-a", "merchant-b"}), "").
+		WillReturnRows(sqlmock.NewRows(transactionRowColumns))
+
+	result, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-a", "merchant-b"}, Sort: "date_desc"})
+
+	s.NoError(err)
+	s.Empty(result.Transactions)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_InvalidSort() {
+	_, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "transaction_date; DROP TABLE transactions"})
+
+	s.ErrorIs(err, ErrInvalidTransactionSort)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_FiltersByStatus() {
+	for _, status := range []string{entity.TransactionStatusPending, entity.TransactionStatusSuccess, entity.TransactionStatusFailed} {
+		date := time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC)
+		s.mockSql.ExpectQuery(regexp.QuoteMeta(`WHERE t.id_merchant = ANY($1) AND ($2 = '' OR t.status = $2)`)).
+			WithArgs(pq.Array([]string{"merchant-uuid"}), status).
+			WillReturnRows(sqlmock.NewRows(transactionRowColumns).AddRow("tx-a", "Ani", "081234567890", date, date, date, status,
+				"user-uuid", "eko", "employee", "merchant-uuid", "Konter", "Jombang",
+				"detail-a", "tx-a", nil, "product-uuid", "provider-uuid", "Telkomsel", 10000, 11000))
+
+		result, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_desc", Status: status})
+
+		s.NoError(err, status)
+		s.Len(result.Transactions, 1, status)
+		s.Equal(status, result.Transactions[0].Status, status)
+	}
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_InvalidStatus() {
+	_, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_desc", Status: "refunded"})
+
+	s.ErrorIs(err, ErrInvalidTransactionStatus)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+// historyRow is one detail row of a transaction as the history query returns it.
+func historyRow(id string, date time.Time, detail string) []driver.Value {
+	return []driver.Value{id, "Ani", "081234567890", date, date, date, entity.TransactionStatusSuccess,
+		"user-uuid", "eko", "employee", "merchant-uuid", "Konter", "Jombang",
+		detail, id, nil, "product-uuid", "provider-uuid", "Telkomsel", 10000, 11000}
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_CursorWalksEveryTransactionOnce() {
+	day := func(d int) time.Time { return time.Date(2024, 10, d, 0, 0, 0, 0, time.UTC) }
+	// the history newest first, tx-2 and tx-3 share a date and tx-3 has two detail lines
+	history := []struct {
+		id   string
+		date time.Time
+	}{{"tx-1", day(5)}, {"tx-2", day(4)}, {"tx-3", day(4)}, {"tx-4", day(3)}, {"tx-5", day(1)}}
+	rowsFrom := func(from int) *sqlmock.Rows {
+		rows := sqlmock.NewRows(transactionRowColumns)
+		// the database returns at most limit+1 transactions
+		for _, tx := range history[from:min(from+3, len(history))] {
+			rows.AddRow(historyRow(tx.id, tx.date, "detail-"+tx.id)...)
+			if tx.id == "tx-3" {
+				rows.AddRow(historyRow(tx.id, tx.date, "detail-"+tx.id+"-b")...)
+			}
+		}
+		return rows
+	}
+
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`ORDER BY t.transaction_date DESC, t.transaction_id LIMIT $3)`)).
+		WithArgs(pq.Array([]string{"merchant-uuid"}), "", 3).
+		WillReturnRows(rowsFrom(0))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`AND (t.transaction_date < $3::date OR (t.transaction_date = $3::date AND t.transaction_id > $4::uuid)) ORDER BY t.transaction_date DESC, t.transaction_id LIMIT $5)`)).
+		WithArgs(pq.Array([]string{"merchant-uuid"}), "", "2024-10-04", "tx-2", 3).
+		WillReturnRows(rowsFrom(2))
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`LIMIT $5)`)).
+		WithArgs(pq.Array([]string{"merchant-uuid"}), "", "2024-10-03", "tx-4", 3).
+		WillReturnRows(rowsFrom(4))
+
+	var seen []string
+	filter := custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_desc", Limit: 2}
+	for pages := 0; ; pages++ {
+		s.Require().Less(pages, 3, "the cursor should run out after three pages")
+		page, err := s.transactionRepo.GetAll(context.Background(), filter)
+		s.Require().NoError(err)
+		s.LessOrEqual(len(page.Transactions), 2)
+		for _, tx := range page.Transactions {
+			seen = append(seen, tx.TransactionsId)
+			if tx.TransactionsId == "tx-3" {
+				s.Len(tx.TransactionDetail, 2, "a transaction is not split across pages")
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	s.Equal([]string{"tx-1", "tx-2", "tx-3", "tx-4", "tx-5"}, seen)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_OffsetPaging() {
+	date := time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC)
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`ORDER BY t.transaction_date ASC, t.transaction_id LIMIT $3 OFFSET $4)`)).
+		WithArgs(pq.Array([]string{"merchant-uuid"}), "", 11, 20).
+		WillReturnRows(sqlmock.NewRows(transactionRowColumns).AddRow(historyRow("tx-21", date, "detail-21")...))
+
+	result, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_asc", Page: 3, Limit: 10})
+
+	s.NoError(err)
+	s.Len(result.Transactions, 1)
+	s.Empty(result.NextCursor)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_CustomerSortHasNoCursor() {
+	date := time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC)
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`LIMIT $3)`)).
+		WithArgs(pq.Array([]string{"merchant-uuid"}), "", 2).
+		WillReturnRows(sqlmock.NewRows(transactionRowColumns).
+			AddRow(historyRow("tx-a", date, "detail-a")...).AddRow(historyRow("tx-b", date, "detail-b")...))
+
+	result, err := s.transactionRepo.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "customer", Limit: 1})
+
+	s.NoError(err)
+	s.Len(result.Transactions, 1)
+	s.Empty(result.NextCursor)
+}
+
+func (s *transactionRepositoryTestSuite) TestGetAll_InvalidCursor() {
+	otherSort := encodeTransactionCursor("date_asc", time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC), "tx-a")
+	for _, filter := range []custom.TransactionFilter{
+		{Sort: "date_desc", Limit: 10, Cursor: "not a cursor"},
+		{Sort: "date_desc", Limit: 10, Cursor: otherSort},
+		{Sort: "customer", Limit: 10, Cursor: otherSort},
+	} {
+		_, err := s.transactionRepo.GetAll(context.Background(), filter)
+
+		s.ErrorIs(err, ErrInvalidTransactionCursor, filter.Cursor)
+	}
+	s.NoError(s.mockSql.ExpectationsWereMet())
 }
 
 // GetById Tests
@@ -216,15 +789,18 @@ func (s *transactionRepositoryTestSuite) TestGetById_Success() {
 	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
 		WithArgs(expectedTransactionReq.TransactionsId).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"transaction_id", "customer_name", "destination_number", "transaction_date",
+			"transaction_id", "customer_name", "destination_number", "transaction_date", "created_at", "updated_at", "status",
 			"id_user", "username", "role",
 			"id_merchant", "name_merchant", "address",
-			"transaction_detail_id", "id_product", "name_provider", "nominal", "price",
+			"transaction_detail_id", "transaction_id", "refunded_at", "id_product", "id_provider", "name_provider", "nominal", "price",
 		}).AddRow(
 			expectedTransactionReq.TransactionsId,
 			expectedTransactionReq.CustomerName,
 			expectedTransactionReq.DestinationNumber,
 			expectedTransactionReq.TransactionDate,
+			expectedTransactionReq.CreatedAt,
+			expectedTransactionReq.UpdatedAt,
+			entity.TransactionStatusSuccess,
 			expectedTransactionReq.User.Id_user,
 			expectedTransactionReq.User.Username,
 			expectedTransactionReq.User.Role,
@@ -232,31 +808,206 @@ func (s *transactionRepositoryTestSuite) TestGetById_Success() {
 			expectedTransactionReq.Merchant.NameMerchant,
 			expectedTransactionReq.Merchant.Address,
 			expectedTransactionReq.TransactionDetail[0].TransactionDetailId,
+			expectedTransactionReq.TransactionsId,
+			nil,
 			expectedTransactionReq.TransactionDetail[0].Product.IdProduct,
+			expectedTransactionReq.TransactionDetail[0].Product.IdProvider,
 			expectedTransactionReq.TransactionDetail[0].Product.NameProvider,
 			expectedTransactionReq.TransactionDetail[0].Product.Nominal,
 			expectedTransactionReq.TransactionDetail[0].Product.Price,
 		))
 
-	result, err := s.transactionRepo.GetById(expectedTransactionReq.TransactionsId)
+	result, err := s.transactionRepo.GetById(context.Background(), expectedTransactionReq.TransactionsId)
 
 	s.NoError(err)
 	s.Equal(expectedTransactionReq.TransactionsId, result.TransactionsId)
 }
 
+func (s *transactionRepositoryTestSuite) TestGetById_KeepsPriceAtPurchase() {
+	s.expectCreate(100000, 0, 10000)
+	created, err := s.transactionRepo.Create(context.Background(), expectedTransaction, "user-uuid")
+	s.NoError(err)
+
+	// the product gets more expensive after the purchase
+	s.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_product SET")).WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = NewProductRepository(s.mockDb, &s.log).Update(entity.Product{IdProduct: "product-uuid", Nominal: 12000, Price: 12500, Version: 1})
+	s.NoError(err)
+
+	// the history reads the nominal and price stored with the detail, never the ones of the product
+	row := []driver.Value{
+		created.TransactionsId, "John", "081234567890", time.Now(), time.Now(), time.Now(), entity.TransactionStatusSuccess,
+		"user-uuid", "testuser", "admin",
+		"merchant-uuid", "Test Merchant", "Test Address",
+		"detail-uuid", created.TransactionsId, nil, "product-uuid", "provider-uuid", "Test Provider", float64(10000), float64(10500),
+	}
+	s.mockSql.ExpectQuery(regexp.QuoteMeta("pv.name_provider, td.nominal, td.price")).WithArgs(created.TransactionsId).
+		WillReturnRows(sqlmock.NewRows(transactionRowColumns).AddRow(row...))
+
+	history, err := s.transactionRepo.GetById(context.Background(), created.TransactionsId)
+
+	s.NoError(err)
+	s.Equal(created.TransactionDetail[0].Nominal, history.TransactionDetail[0].Product.Nominal)
+	s.Equal(created.TransactionDetail[0].Price, history.TransactionDetail[0].Product.Price)
+	s.Equal(float64(10500), history.TransactionDetail[0].Product.Price)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestGetById_MultipleDetails() {
+	rows := sqlmock.NewRows([]string{
+		"transaction_id", "customer_name", "destination_number", "transaction_date", "created_at", "updated_at", "status",
+		"id_user", "username", "role",
+		"id_merchant", "name_merchant", "address",
+		"transaction_detail_id", "transaction_id", "refunded_at", "id_product", "id_provider", "name_provider", "nominal", "price",
+	})
+	for _, detail := range []string{"detail-1", "detail-2", "detail-3"} {
+		rows.AddRow("test-uuid", "John Doe", "081234567890", time.Now(), time.Now(), time.Now(), entity.TransactionStatusSuccess,
+			"user-uuid", "testuser", "employee",
+			"merchant-uuid", "Test Merchant", "Test Address",
+			detail, "test-uuid", nil, "product-uuid", "provider-uuid", "Test Provider", 10000, 10900)
+	}
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs("test-uuid").WillReturnRows(rows)
+
+	result, err := s.transactionRepo.GetById(context.Background(), "test-uuid")
+
+	s.NoError(err)
+	s.Len(result.TransactionDetail, 3)
+	s.Equal("detail-3", result.TransactionDetail[2].TransactionDetailId)
+}
+
+// scanTransactionRows Tests
+func (s *transactionRepositoryTestSuite) TestScanTransactionRows_GroupsDetailsPerTransaction() {
+	date := time.Date(2024, 10, 25, 0, 0, 0, 0, time.UTC)
+	row := func(id, detail string, price float64) []driver.Value {
+		return []driver.Value{id, "Customer " + id, "081234567890", date, date, date, entity.TransactionStatusSuccess, "user-uuid", "eko", "employee", "merchant-uuid", "Konter", "Jombang",
+			detail, id, nil, "product-uuid", "provider-uuid", "Telkomsel", 10000, price}
+	}
+	refundedAt := time.Date(2024, 10, 26, 9, 0, 0, 0, time.UTC)
+	refundedRow := row("tx-b", "detail-b2", 10500)
+	refundedRow[15] = refundedAt
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
+		WillReturnRows(sqlmock.NewRows(transactionRowColumns).
+			AddRow(row("tx-b", "detail-b1", 11000)...).
+			AddRow(row("tx-a", "detail-a1", 12000)...).
+			AddRow(refundedRow...).
+			AddRow(row("tx-a", "detail-a2", 13000)...).
+			AddRow(row("tx-a", "detail-a3", 14000)...))
+
+	rows, err := s.mockDb.Query("SELECT")
+	s.Require().NoError(err)
+	defer rows.Close()
+
+	result, err := scanTransactionRows(rows)
+
+	s.NoError(err)
+	s.Len(result, 2)
+	s.Equal("tx-b", result[0].TransactionsId)
+	s.Equal("tx-a", result[1].TransactionsId)
+	s.Equal("Customer tx-a", result[1].CustomerName)
+	s.Equal("eko", result[1].User.Username)
+	s.Equal("Konter", result[1].Merchant.NameMerchant)
+
+	s.Len(result[0].TransactionDetail, 2)
+	s.Equal("detail-b2", result[0].TransactionDetail[1].TransactionDetailId)
+	s.Nil(result[0].TransactionDetail[0].RefundedAt)
+	s.Require().NotNil(result[0].TransactionDetail[1].RefundedAt)
+	s.Equal(refundedAt, *result[0].TransactionDetail[1].RefundedAt)
+	s.Equal(10500.0, result[0].TransactionDetail[1].Product.Price)
+
+	s.Len(result[1].TransactionDetail, 3)
+	for i, detail := range []string{"detail-a1", "detail-a2", "detail-a3"} {
+		s.Equal(detail, result[1].TransactionDetail[i].TransactionDetailId)
+		s.Equal("tx-a", result[1].TransactionDetail[i].TransactionsId)
+	}
+}
+
+func (s *transactionRepositoryTestSuite) TestScanTransactionRows_Empty() {
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WillReturnRows(sqlmock.NewRows(transactionRowColumns))
+
+	rows, err := s.mockDb.Query("SELECT")
+	s.Require().NoError(err)
+	defer rows.Close()
+
+	result, err := scanTransactionRows(rows)
+
+	s.NoError(err)
+	s.Empty(result)
+}
+
+func (s *transactionRepositoryTestSuite) TestScanTransactionRows_ScanError() {
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}).AddRow("tx-a"))
+
+	rows, err := s.mockDb.Query("SELECT")
+	s.Require().NoError(err)
+	defer rows.Close()
+
+	result, err := scanTransactionRows(rows)
+
+	s.Error(err)
+	s.Nil(result)
+}
+
 func (s *transactionRepositoryTestSuite) TestGetById_NotFound() {
 	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
 		WithArgs("non-existent-id").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"transaction_id", "customer_name", "destination_number", "transaction_date",
+			"transaction_id", "customer_name", "destination_number", "transaction_date", "created_at", "updated_at", "status",
 			"id_user", "username", "role",
 			"id_merchant", "name_merchant", "address",
-			"transaction_detail_id", "id_product", "name_provider", "nominal", "price",
+			"transaction_detail_id", "transaction_id", "refunded_at", "id_product", "id_provider", "name_provider", "nominal", "price",
 		}))
 
-	result, err := s.transactionRepo.GetById("non-existent-id")
+	result, err := s.transactionRepo.GetById(context.Background(), "non-existent-id")
 
 	s.Error(err)
 	s.Equal("transaction not found", err.Error())
 	s.Equal(custom.TransactionsReq{}, result)
 }
+
+// RefundDetail Tests
+func (s *transactionRepositoryTestSuite) expectRefundLookup(refunded bool) {
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT t.id_merchant, td.nominal, td.refunded_at IS NOT NULL`)).
+		WithArgs("detail-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id_merchant", "nominal", "refunded"}).AddRow("merchant-uuid", 10000, refunded))
+}
+
+func (s *transactionRepositoryTestSuite) TestRefundDetail_RefundsOnlyThatLine() {
+	s.expectRefundLookup(false)
+	s.mockSql.ExpectExec(regexp.QuoteMeta(`UPDATE transaction_detail SET refunded_at = NOW() WHERE transaction_detail_id = $1`)).
+		WithArgs("detail-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mockSql.ExpectExec(regexp.QuoteMeta(`UPDATE mst_merchant SET balance = balance + $1 WHERE id_merchant = $2`)).
+		WithArgs(float64(10000), "merchant-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mockSql.ExpectCommit()
+
+	err := s.transactionRepo.RefundDetail(context.Background(), "detail-2")
+
+	s.NoError(err)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestRefundDetail_AlreadyRefunded() {
+	s.expectRefundLookup(true)
+	s.mockSql.ExpectRollback()
+
+	err := s.transactionRepo.RefundDetail(context.Background(), "detail-2")
+
+	s.ErrorIs(err, ErrDetailAlreadyRefunded)
+	// neither the line nor the balance is touched a second time
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
+
+func (s *transactionRepositoryTestSuite) TestRefundDetail_NotFound() {
+	s.mockSql.ExpectBegin()
+	s.mockSql.ExpectQuery(regexp.QuoteMeta(`SELECT t.id_merchant, td.nominal, td.refunded_at IS NOT NULL`)).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	s.mockSql.ExpectRollback()
+
+	err := s.transactionRepo.RefundDetail(context.Background(), "missing")
+
+	s.ErrorIs(err, ErrTransactionDetailNotFound)
+	s.NoError(s.mockSql.ExpectationsWereMet())
+}
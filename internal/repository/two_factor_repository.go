@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"time"
+)
+
+var (
+	// ErrTwoFactorNotSetUp is returned when the user has no TOTP secret yet.
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication has not been set up")
+	// ErrTwoFactorEnabled is returned when setting up or enabling a user whose 2FA is already on.
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrInvalidChallenge is returned for unknown and expired login challenges and those with too many attempts.
+	ErrInvalidChallenge = errors.New("invalid or expired two-factor challenge, please login again")
+)
+
+// TwoFactorRepository keeps the TOTP secrets, the backup codes and the pending login challenges.
+type TwoFactorRepository interface {
+	SaveSecret(userId, secret string) error
+	Get(userId string) (entity.TwoFactor, error)
+	IsEnabled(userId string) (bool, error)
+	Enable(userId string, step int64, backupCodeHashes []string) error
+	UseStep(userId string, step int64) (bool, error)
+	UseBackupCode(userId, codeHash string) (bool, error)
+	CreateChallenge(userId, tokenHash string, expiresAt time.Time, rememberMe bool) error
+	UseChallenge(tokenHash string, maxAttempts int) (string, bool, error)
+	DeleteChallenge(tokenHash string) error
+}
+
+type twoFactorRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// SaveSecret stores a new secret that is not enabled yet, a previous setup that was never enabled is replaced.
+func (r *twoFactorRepository) SaveSecret(userId, secret string) error {
+	r.log.Info("Starting to save a totp secret in the repository layer", nil)
+
+	result, err := r.db.Exec(`INSERT INTO user_totp (id_user, secret) VALUES ($1, $2)
+		ON CONFLICT (id_user) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0 WHERE user_totp.enabled_at IS NULL`, userId, secret)
+	if err != nil {
+		r.log.Error("Failed to save the totp secret: ", err)
+		return err
+	}
+	saved, err := result.RowsAffected()
+	if err != nil {
+		r.log.Error("Failed to save the totp secret: ", err)
+		return err
+	}
+	if saved == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	r.log.Info("Totp secret has been saved successfully", userId)
+	return nil
+}
+
+func (r *twoFactorRepository) Get(userId string) (entity.TwoFactor, error) {
+	var twoFactor entity.TwoFactor
+
+	err := r.db.QueryRow("SELECT secret, enabled_at IS NOT NULL, last_used_step FROM user_totp WHERE id_user = $1", userId).
+		Scan(&twoFactor.Secret, &twoFactor.Enabled, &twoFactor.LastUsedStep)
+	if err == sql.ErrNoRows {
+		return entity.TwoFactor{}, ErrTwoFactorNotSetUp
+	}
+	if err != nil {
+		r.log.Error("Failed to retrieve the totp secret: ", err)
+		return entity.TwoFactor{}, err
+	}
+
+	return twoFactor, nil
+}
+
+// IsEnabled is checked on every login.
+func (r *twoFactorRepository) IsEnabled(userId string) (bool, error) {
+	var enabled bool
+
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM user_totp WHERE id_user = $1 AND enabled_at IS NOT NULL)", userId).Scan(&enabled)
+	if err != nil {
+		r.log.Error("Failed to check whether two-factor authentication is enabled: ", err)
+		return false, err
+	}
+
+	return enabled, nil
+}
+
+// Enable turns 2FA on with step as the last used code and replaces the backup codes, in one transaction.
+func (r *twoFactorRepository) Enable(userId string, step int64, backupCodeHashes []string) error {
+	r.log.Info("Starting to enable two-factor authentication in the repository layer", nil)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.log.Error("Failed to begin the two-factor transaction: ", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec("UPDATE user_totp SET enabled_at = NOW(), last_used_step = $2 WHERE id_user = $1 AND enabled_at IS NULL", userId, step)
+	if err != nil {
+		r.log.Error("Failed to enable two-factor authentication: ", err)
+		return err
+	}
+	enabled, err := result.RowsAffected()
+	if err != nil {
+		r.log.Error("Failed to enable two-factor authentication: ", err)
+		return err
+	}
+	if enabled == 0 {
+		err = ErrTwoFactorEnabled
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM totp_backup_code WHERE id_user = $1", userId); err != nil {
+		r.log.Error("Failed to delete the old backup codes: ", err)
+		return err
+	}
+	for _, codeHash := range backupCodeHashes {
+		if _, err = tx.Exec("INSERT INTO totp_backup_code (id_user, code_hash) VALUES ($1, $2)", userId, codeHash); err != nil {
+			r.log.Error("Failed to store the backup code: ", err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("Failed to commit the two-factor transaction: ", err)
+		return err
+	}
+
+	r.log.Info("Two-factor authentication has been enabled successfully", userId)
+	return nil
+}
+
+// UseStep records the time step of an accepted code, it reports false when that step or a later one was used already.
+func (r *twoFactorRepository) UseStep(userId string, step int64) (bool, error) {
+	result, err := r.db.Exec("UPDATE user_totp SET last_used_step = $2 WHERE id_user = $1 AND last_used_step < $2", userId, step)
+	if err != nil {
+		r.log.Error("Failed to record the used totp step: ", err)
+		return false, err
+	}
+	used, err := result.RowsAffected()
+	if err != nil {
+		r.log.Error("Failed to record the used totp step: ", err)
+		return false, err
+	}
+
+	return used == 1, nil
+}
+
+// UseBackupCode consumes a backup code, it reports false for unknown and already used codes.
+func (r *twoFactorRepository) UseBackupCode(userId, codeHash string) (bool, error) {
+	result, err := r.db.Exec("UPDATE totp_backup_code SET used_at = NOW() WHERE id_user = $1 AND code_hash = $2 AND used_at IS NULL", userId, codeHash)
+	if err != nil {
+		r.log.Error("Failed to use the backup code: ", err)
+		return false, err
+	}
+	used, err := result.RowsAffected()
+	if err != nil {
+		r.log.Error("Failed to use the backup code: ", err)
+		return false, err
+	}
+
+	if used == 1 {
+		r.log.Info("Backup code has been used", userId)
+	}
+	return used == 1, nil
+}
+
+// CreateChallenge keeps whether the login asked for remember me, the tokens issued for the challenge use it.
+func (r *twoFactorRepository) CreateChallenge(userId, tokenHash string, expiresAt time.Time, rememberMe bool) error {
+	r.log.Info("Starting to create a login challenge in the repository layer", nil)
+
+	_, err := r.db.Exec("INSERT INTO login_challenge (token_hash, id_user, expires_at, remember_me) VALUES ($1, $2, $3, $4)", tokenHash, userId, expiresAt, rememberMe)
+	if err != nil {
+		r.log.Error("Failed to create the login challenge: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// UseChallenge counts an attempt on the challenge and returns its user and whether the login asked for remember me,
+// a challenge is refused once it has expired or had maxAttempts attempts.
+func (r *twoFactorRepository) UseChallenge(tokenHash string, maxAttempts int) (string, bool, error) {
+	r.log.Info("Starting to use a login challenge in the repository layer", nil)
+
+	var userId string
+	var rememberMe bool
+	err := r.db.QueryRow(`UPDATE login_challenge SET attempts = attempts + 1
+		WHERE token_hash = $1 AND expires_at > NOW() AND attempts < $2
+		RETURNING id_user, remember_me`, tokenHash, maxAttempts).Scan(&userId, &rememberMe)
+	if err == sql.ErrNoRows {
+		err = ErrInvalidChallenge
+	}
+	if err != nil {
+		r.log.Error("Failed to use the login challenge: ", err)
+		return "", false, err
+	}
+
+	return userId, rememberMe, nil
+}
+
+// DeleteChallenge drops a challenge once it was exchanged for tokens, expired ones are refused anyway.
+func (r *twoFactorRepository) DeleteChallenge(tokenHash string) error {
+	if _, err := r.db.Exec("DELETE FROM login_challenge WHERE token_hash = $1 OR expires_at < NOW()", tokenHash); err != nil {
+		r.log.Error("Failed to delete the login challenge: ", err)
+		return err
+	}
+	return nil
+}
+
+func NewTwoFactorRepository(db *sql.DB, log *logger.Logger) TwoFactorRepository {
+	return &twoFactorRepository{db: db, log: log}
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type twoFactorRepositoryTestSuite struct {
+	suite.Suite
+	mockDb  *sql.DB
+	mockSql sqlmock.Sqlmock
+	repo    TwoFactorRepository
+	log     logger.Logger
+}
+
+func TestTwoFactorRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(twoFactorRepositoryTestSuite))
+}
+
+func (r *twoFactorRepositoryTestSuite) SetupTest() {
+	mockDb, mockSql, err := sqlmock.New()
+	r.NoError(err)
+
+	r.mockDb = mockDb
+	r.mockSql = mockSql
+	r.log = logger.NewLogger()
+	r.repo = NewTwoFactorRepository(mockDb, &r.log)
+}
+
+func (r *twoFactorRepositoryTestSuite) TestSaveSecret() {
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO user_totp (id_user, secret)")).WithArgs("uuid-user", "SECRET").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r.NoError(r.repo.SaveSecret("uuid-user", "SECRET"))
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *twoFactorRepositoryTestSuite) TestSaveSecret_AlreadyEnabled() {
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO user_totp (id_user, secret)")).WithArgs("uuid-user", "SECRET").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	r.ErrorIs(r.repo.SaveSecret("uuid-user", "SECRET"), ErrTwoFactorEnabled)
+}
+
+func (r *twoFactorRepositoryTestSuite) TestGet_NotSetUp() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("FROM user_totp WHERE id_user = $1")).WithArgs("uuid-user").WillReturnError(sql.ErrNoRows)
+
+	_, err := r.repo.Get("uuid-user")
+
+	r.ErrorIs(err, ErrTwoFactorNotSetUp)
+}
+
+func (r *twoFactorRepositoryTestSuite) TestGet() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("FROM user_totp WHERE id_user = $1")).WithArgs("uuid-user").
+		WillReturnRows(sqlmock.NewRows([]string{"secret", "enabled", "last_used_step"}).AddRow("SECRET", true, 57000000))
+
+	twoFactor, err := r.repo.Get("uuid-user")
+
+	r.NoError(err)
+	r.Equal(entity.TwoFactor{Secret: "SECRET", Enabled: true, LastUsedStep: 57000000}, twoFactor)
+}
+
+func (r *twoFactorRepositoryTestSuite) TestEnable_ReplacesBackupCodes() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE user_totp SET enabled_at = NOW()")).WithArgs("uuid-user", int64(57000000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("DELETE FROM totp_backup_code")).WithArgs("uuid-user").WillReturnResult(sqlmock.NewResult(0, 0))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO totp_backup_code")).WithArgs("uuid-user", "hash-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO totp_backup_code")).WithArgs("uuid-user", "hash-2").WillReturnResult(sqlmock.NewResult(0, 1))
+	r.mockSql.ExpectCommit()
+
+	r.NoError(r.repo.Enable("uuid-user", 57000000, []string{"hash-1", "hash-2"}))
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *twoFactorRepositoryTestSuite) TestEnable_AlreadyEnabledRollsBack() {
+	r.mockSql.ExpectBegin()
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE user_totp SET enabled_at = NOW()")).WithArgs("uuid-user", int64(57000000)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	r.mockSql.ExpectRollback()
+
+	r.ErrorIs(r.repo.Enable("uuid-user", 57000000, []string{"hash-1"}), ErrTwoFactorEnabled)
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *twoFactorRepositoryTestSuite) TestUseStep_RefusesUsedStep() {
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE user_totp SET last_used_step = $2 WHERE id_user = $1 AND last_used_step < $2")).
+		WithArgs("uuid-user", int64(57000000)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	used, err := r.repo.UseStep("uuid-user", 57000000)
+
+	r.NoError(err)
+	r.False(used)
+}
+
+func (r *twoFactorRepositoryTestSuite) TestUseBackupCode() {
+	r.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE totp_backup_code SET used_at = NOW()")).WithArgs("uuid-user", "hash-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	used, err := r.repo.UseBackupCode("uuid-user", "hash-1")
+
+	r.NoError(err)
+	r.True(used)
+}
+
+func (r *twoFactorRepositoryTestSuite) TestCreateChallenge() {
+	expiresAt := time.Now().Add(5 * time.Minute)
+	r.mockSql.ExpectExec(regexp.QuoteMeta("INSERT INTO login_challenge (token_hash, id_user, expires_at, remember_me)")).
+		WithArgs("challenge-hash", "uuid-user", expiresAt, false).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r.NoError(r.repo.CreateChallenge("uuid-user", "challenge-hash", expiresAt, false))
+	r.NoError(r.mockSql.ExpectationsWereMet())
+}
+
+func (r *twoFactorRepositoryTestSuite) TestUseChallenge() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE login_challenge SET attempts = attempts + 1")).WithArgs("challenge-hash", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id_user", "remember_me"}).AddRow("uuid-user", true))
+
+	userId, rememberMe, err := r.repo.UseChallenge("challenge-hash", 5)
+
+	r.NoError(err)
+	r.Equal("uuid-user", userId)
+	r.True(rememberMe)
+}
+
+func (r *twoFactorRepositoryTestSuite) TestUseChallenge_ExpiredOrExhausted() {
+	r.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE login_challenge SET attempts = attempts + 1")).WithArgs("challenge-hash", 5).
+		WillReturnError(sql.ErrNoRows)
+
+	_, _, err := r.repo.UseChallenge("challenge-hash", 5)
+
+	r.ErrorIs(err, ErrInvalidChallenge)
+}
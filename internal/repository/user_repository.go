@@ -2,17 +2,78 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/custom"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrUsernameTaken is returned when another user already has the username, compared case-insensitively.
+	ErrUsernameTaken = errors.New("username is already taken")
+	// ErrEmailTaken is returned when another user already has the email, compared case-insensitively.
+	ErrEmailTaken = errors.New("email already used by another user")
+	// ErrInvalidEmail is returned when the email is set but is not a plain address like eko@example.com.
+	ErrInvalidEmail = errors.New("email is not a valid address")
+	// ErrLastAdmin is returned by SoftDeleteUser for the only active admin, nobody could manage the users after it.
+	ErrLastAdmin = errors.New("cannot delete the last admin")
 )
 
+// emailIndex is the unique index on LOWER(email), every other unique violation on mst_user is the username.
+const emailIndex = "mst_user_email_lower_idx"
+
+// mapUserError translates the unique violations on LOWER(username) and LOWER(email) into ErrUsernameTaken and ErrEmailTaken.
+func mapUserError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		if pqErr.Constraint == emailIndex {
+			return ErrEmailTaken
+		}
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+// nullTime turns a nullable timestamp column into the nil-able field of the entities.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// validEmail accepts an empty email, users created before emails existed have none.
+func validEmail(email string) bool {
+	if email == "" {
+		return true
+	}
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// insertUser is shared with the merchant onboarding, which creates the user in its own db transaction.
+const insertUser = `INSERT INTO mst_user (username, password, role, email, must_change_password) VALUES ($1, $2, $3, NULLIF($4, ''), $5) RETURNING id_user`
+
 type UserRepository interface {
 	CreateUser(user entity.User) (entity.User, error)
-	ListUser() ([]entity.User, error)
+	ListUser(filter custom.UserFilter) ([]custom.UserListItem, int, error)
 	GetUserByID(id string) (entity.User, error)
 	GetUserByUsername(username string) (entity.User, error)
+	GetUserByEmail(email string) (entity.User, error)
 	UpdateUser(payload entity.User) (entity.User, error)
-	DeleteUser(id string) error
+	UpdatePassword(id, hash string) error
+	SetPassword(id, hash string, mustChange bool) error
+	SoftDeleteUser(id string) error
+	ActivateUser(id string) error
+	IsActive(id string) (bool, error)
+	UpdateLastLogin(id string, at time.Time) error
+	FindDuplicateUsernames() ([]custom.DuplicateUsername, error)
 }
 
 type userRepository struct {
@@ -23,64 +84,143 @@ type userRepository struct {
 func (u *userRepository) CreateUser(user entity.User) (entity.User, error) {
 	u.log.Info("Starting to create a new user in the repository layer", nil)
 
-	err := u.db.QueryRow(`INSERT INTO mst_user (username, password, role) VALUES ($1, $2, $3) RETURNING id_user`, user.Username, user.Password, user.Role).Scan(&user.Id_user)
+	if !validEmail(user.Email) {
+		u.log.Error("Invalid email for the new user: ", user.Email)
+		return entity.User{}, ErrInvalidEmail
+	}
+
+	err := u.db.QueryRow(insertUser, user.Username, user.Password, user.Role, user.Email, user.MustChangePassword).Scan(&user.Id_user)
+	user.Active = true
 
 	if err != nil {
 		u.log.Error("Failed to create the user: ", err)
-		return entity.User{}, err
+		return entity.User{}, mapUserError(err)
 	}
 
 	u.log.Info("User has been created successfully", user)
 	return user, nil
 }
 
-func (u *userRepository) ListUser() ([]entity.User, error) {
-	var users []entity.User
+// userFilterWhere is shared by the page and the count of ListUser, $1 is the ILIKE pattern, $2 the role, $3
+// whether deactivated users are listed too and $4 the days without a login.
+const userFilterWhere = `WHERE ($3::boolean OR deleted_at IS NULL)
+	AND ($1::text IS NULL OR username ILIKE $1 OR email ILIKE $1)
+	AND ($2::text IS NULL OR role = $2)
+	AND ($4::int IS NULL OR last_login_at IS NULL OR last_login_at < NOW() - make_interval(days => $4::int))
+	AND ($5::text IS NULL OR username ILIKE $5)`
+
+// likePattern matches q anywhere, the LIKE wildcards in q itself are matched literally.
+func likePattern(q string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q) + "%"
+}
+
+// ListUser returns one page of the users matching the filter ordered by username, together with the number of
+// users matching it. The password hash is never selected.
+func (u *userRepository) ListUser(filter custom.UserFilter) ([]custom.UserListItem, int, error) {
+	u.log.Info("Starting to retrive the user list in the repository layer", nil)
+
+	pattern := sql.NullString{String: likePattern(filter.Q), Valid: filter.Q != ""}
+	role := sql.NullString{String: filter.Role, Valid: filter.Role != ""}
+	inactiveDays := sql.NullInt64{Int64: int64(filter.InactiveDays), Valid: filter.InactiveDays > 0}
+	username := sql.NullString{String: likePattern(filter.Username), Valid: filter.Username != ""}
 
-	rows, err := u.db.Query(`SELECT id_user, username, password, role FROM mst_user`)
+	var total int
+	if err := u.db.QueryRow("SELECT COUNT(*) FROM mst_user "+userFilterWhere, pattern, role, filter.IncludeInactive, inactiveDays, username).Scan(&total); err != nil {
+		u.log.Error("Failed to count the users: ", err)
+		return nil, 0, fmt.Errorf("count the users: %w", err)
+	}
+
+	rows, err := u.db.Query(`SELECT id_user, username, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at FROM mst_user `+userFilterWhere+`
+		ORDER BY username, id_user
+		LIMIT $6 OFFSET $7`,
+		pattern, role, filter.IncludeInactive, inactiveDays, username, filter.Size, (filter.Page-1)*filter.Size)
 	if err != nil {
-		u.log.Error("UserRepository.ListUser: %v \n", err.Error())
-		return nil, err
+		u.log.Error("Failed to retrive the user list: ", err)
+		return nil, 0, fmt.Errorf("retrieve the user list: %w", err)
 	}
 	defer rows.Close()
+
+	users := []custom.UserListItem{}
 	for rows.Next() {
-		var user entity.User
-		err := rows.Scan(&user.Id_user, &user.Username, &user.Password, &user.Role)
-		if err != nil {
-			return nil, err
+		var (
+			user      custom.UserListItem
+			lastLogin sql.NullTime
+		)
+		if err := rows.Scan(&user.IdUser, &user.Username, &user.Role, &user.Email, &user.Active, &lastLogin); err != nil {
+			u.log.Error("Failed to scan the user list: ", err)
+			return nil, 0, fmt.Errorf("scan the user list: %w", err)
 		}
+		user.LastLoginAt = nullTime(lastLogin)
 		users = append(users, user)
 	}
-	return users, nil
+	if err := rows.Err(); err != nil {
+		u.log.Error("Failed to retrive the user list: ", err)
+		return nil, 0, fmt.Errorf("retrieve the user list: %w", err)
+	}
+
+	return users, total, nil
 }
 
+// GetUserByUsername also finds deactivated users, so logins can tell them apart and their usernames stay taken.
 func (u *userRepository) GetUserByUsername(username string) (entity.User, error) {
-	var user entity.User
+	var (
+		user      entity.User
+		lastLogin sql.NullTime
+	)
 
 	u.log.Info("Starting to retrive a user by username in the repository layer", nil)
 
-	err := u.db.QueryRow(`SELECT id_user, username, password, role FROM mst_user WHERE username = $1`, username).Scan(&user.Id_user, &user.Username, &user.Password, &user.Role)
+	err := u.db.QueryRow(`SELECT id_user, username, password, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at, must_change_password FROM mst_user WHERE LOWER(username) = LOWER($1)`,
+		username).Scan(&user.Id_user, &user.Username, &user.Password, &user.Role, &user.Email, &user.Active, &lastLogin, &user.MustChangePassword)
 
 	if err != nil {
 		u.log.Error("Failed to retrive the user: ", err)
-		return entity.User{}, err
+		return entity.User{}, fmt.Errorf("retrieve the user: %w", err)
 	}
+	user.LastLoginAt = nullTime(lastLogin)
 
 	u.log.Info("Getting user by username was successfully", user)
 	return user, nil
 }
 
+// GetUserByEmail also finds deactivated users, like GetUserByUsername.
+func (u *userRepository) GetUserByEmail(email string) (entity.User, error) {
+	var (
+		user      entity.User
+		lastLogin sql.NullTime
+	)
+
+	u.log.Info("Starting to retrive a user by email in the repository layer", nil)
+
+	err := u.db.QueryRow(`SELECT id_user, username, password, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at, must_change_password FROM mst_user WHERE LOWER(email) = LOWER($1)`,
+		email).Scan(&user.Id_user, &user.Username, &user.Password, &user.Role, &user.Email, &user.Active, &lastLogin, &user.MustChangePassword)
+
+	if err != nil {
+		u.log.Error("Failed to retrive the user: ", err)
+		return entity.User{}, fmt.Errorf("retrieve the user: %w", err)
+	}
+	user.LastLoginAt = nullTime(lastLogin)
+
+	u.log.Info("Getting user by email was successfully", user)
+	return user, nil
+}
+
 func (u *userRepository) GetUserByID(id string) (entity.User, error) {
-	var user entity.User
+	var (
+		user      entity.User
+		lastLogin sql.NullTime
+	)
 
 	u.log.Info("Starting to retrive a user by id in the repository layer", nil)
 
-	err := u.db.QueryRow(`SELECT id_user, username, password, role FROM mst_user WHERE id_user = $1`, id).Scan(&user.Id_user, &user.Username, &user.Password, &user.Role)
+	err := u.db.QueryRow(`SELECT id_user, username, password, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at, must_change_password FROM mst_user WHERE id_user = $1 AND deleted_at IS NULL`,
+		id).Scan(&user.Id_user, &user.Username, &user.Password, &user.Role, &user.Email, &user.Active, &lastLogin, &user.MustChangePassword)
 
 	if err != nil {
 		u.log.Error("Failed to retrive the user: ", err)
-		return entity.User{}, err
+		return entity.User{}, fmt.Errorf("retrieve the user: %w", err)
 	}
+	user.LastLoginAt = nullTime(lastLogin)
 
 	u.log.Info("Getting user by id was successfully", user)
 	return user, nil
@@ -89,30 +229,192 @@ func (u *userRepository) GetUserByID(id string) (entity.User, error) {
 func (u *userRepository) UpdateUser(user entity.User) (entity.User, error) {
 	u.log.Info("Starting to update user in the repository layer", nil)
 
-	_, err := u.db.Exec(`UPDATE mst_user SET username = $2, password = $3, role = $4 WHERE id_user = $1`, user.Id_user, user.Username, user.Password, user.Role)
+	if !validEmail(user.Email) {
+		u.log.Error("Invalid email for the user: ", user.Email)
+		return entity.User{}, ErrInvalidEmail
+	}
+
+	_, err := u.db.Exec(`UPDATE mst_user SET username = $2, password = $3, role = $4, email = NULLIF($5, '') WHERE id_user = $1`,
+		user.Id_user, user.Username, user.Password, user.Role, user.Email)
 
 	if err != nil {
 		u.log.Error("Failed to update the user: ", err)
-		return entity.User{}, err
+		return entity.User{}, mapUserError(err)
 	}
 
 	u.log.Info("User has been updated successfully", user)
 	return user, nil
 }
-func (u *userRepository) DeleteUser(id string) error {
-	u.log.Info("Starting to delete user in the repository layer", nil)
 
-	_, err := u.db.Exec(`DELETE FROM mst_user WHERE id_user = $1`, id)
+// UpdatePassword only replaces the hash, unlike UpdateUser it leaves the rest of the row alone.
+func (u *userRepository) UpdatePassword(id, hash string) error {
+	u.log.Info("Starting to update the user password in the repository layer", nil)
+
+	_, err := u.db.Exec(`UPDATE mst_user SET password = $2 WHERE id_user = $1 AND deleted_at IS NULL`, id, hash)
+
+	if err != nil {
+		u.log.Error("Failed to update the user password: ", err)
+		return fmt.Errorf("update the user password: %w", err)
+	}
+
+	u.log.Info("User password has been updated successfully", nil)
+	return nil
+}
+
+// SetPassword replaces the hash of an active user and sets whether they must change the password before anything
+// else, an admin reset sets it and the change of the user clears it.
+func (u *userRepository) SetPassword(id, hash string, mustChange bool) error {
+	u.log.Info("Starting to set the user password in the repository layer", nil)
 
+	result, err := u.db.Exec(`UPDATE mst_user SET password = $2, must_change_password = $3 WHERE id_user = $1 AND deleted_at IS NULL`,
+		id, hash, mustChange)
 	if err != nil {
-		u.log.Error("Failed to delete the user: ", err)
+		u.log.Error("Failed to set the user password: ", err)
+		return fmt.Errorf("set the user password: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		u.log.Error("Failed to set the user password: ", err)
+		return fmt.Errorf("set the user password: %w", err)
+	}
+	if affected == 0 {
+		u.log.Error("Failed to set the user password, unknown id: ", id)
+		return ErrUserNotFound
+	}
+
+	u.log.Info("User password has been set successfully", nil)
+	return nil
+}
+
+// SoftDeleteUser deactivates the user but keeps the row, so the transaction history still joins. Deactivated users
+// are left out of GetUserByID and the user list, and ActivateUser brings them back. The active admins are locked and
+// counted in the same transaction, so two admins deleting each other at once can't leave no admin behind.
+func (u *userRepository) SoftDeleteUser(id string) error {
+	u.log.Info("Starting to soft delete user in the repository layer", nil)
+
+	tx, err := u.db.Begin()
+	if err != nil {
+		u.log.Error("Failed to begin the user deletion transaction: ", err)
+		return fmt.Errorf("begin the user deletion transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var admins int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM (SELECT 1 FROM mst_user WHERE role = 'admin' AND deleted_at IS NULL FOR UPDATE) admins`).Scan(&admins)
+	if err != nil {
+		u.log.Error("Failed to count the active admins: ", err)
+		return fmt.Errorf("count the active admins: %w", err)
+	}
+
+	var role string
+	err = tx.QueryRow(`UPDATE mst_user SET deleted_at = NOW() WHERE id_user = $1 AND deleted_at IS NULL RETURNING role`, id).Scan(&role)
+	if err == sql.ErrNoRows {
+		// already deleted, nothing changed
+		err = nil
+	}
+	if err != nil {
+		u.log.Error("Failed to soft delete the user: ", err)
+		return fmt.Errorf("soft delete the user: %w", err)
+	}
+	if role == "admin" && admins <= 1 {
+		err = ErrLastAdmin
+		u.log.Error("Refused to delete the last admin: ", id)
 		return err
 	}
 
-	u.log.Info("User has been deleted successfully", nil)
+	if err = tx.Commit(); err != nil {
+		u.log.Error("Failed to commit the user deletion: ", err)
+		return fmt.Errorf("commit the user deletion: %w", err)
+	}
+
+	u.log.Info("User has been soft deleted successfully", nil)
+	return nil
+}
+
+// ActivateUser reinstates a soft deleted user, activating an active user is not an error.
+func (u *userRepository) ActivateUser(id string) error {
+	u.log.Info("Starting to activate user in the repository layer", nil)
+
+	result, err := u.db.Exec(`UPDATE mst_user SET deleted_at = NULL WHERE id_user = $1`, id)
+	if err != nil {
+		u.log.Error("Failed to activate the user: ", err)
+		return fmt.Errorf("activate the user: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		u.log.Error("Failed to activate the user: ", err)
+		return fmt.Errorf("activate the user: %w", err)
+	}
+	if affected == 0 {
+		u.log.Error("Failed to activate the user, unknown id: ", id)
+		return ErrUserNotFound
+	}
+
+	u.log.Info("User has been activated successfully", nil)
+	return nil
+}
+
+// IsActive reports whether the user exists and is not soft deleted, it is checked on every authenticated request.
+func (u *userRepository) IsActive(id string) (bool, error) {
+	var active bool
+
+	err := u.db.QueryRow(`SELECT deleted_at IS NULL FROM mst_user WHERE id_user = $1`, id).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		u.log.Error("Failed to check whether the user is active: ", err)
+		return false, fmt.Errorf("check whether the user is active: %w", err)
+	}
+
+	return active, nil
+}
+
+// UpdateLastLogin records a successful login, used to find dormant accounts.
+func (u *userRepository) UpdateLastLogin(id string, at time.Time) error {
+	_, err := u.db.Exec(`UPDATE mst_user SET last_login_at = $2 WHERE id_user = $1`, id, at)
+	if err != nil {
+		u.log.Error("Failed to update the last login of the user: ", err)
+		return fmt.Errorf("update the last login of the user: %w", err)
+	}
 	return nil
 }
 
+// FindDuplicateUsernames lists the usernames that only differ in case, with the ids of every user holding one.
+func (u *userRepository) FindDuplicateUsernames() ([]custom.DuplicateUsername, error) {
+	u.log.Info("Starting to find duplicate usernames in the repository layer", nil)
+
+	rows, err := u.db.Query(`SELECT LOWER(username), array_agg(id_user::text ORDER BY id_user)
+		FROM mst_user GROUP BY LOWER(username) HAVING COUNT(*) > 1 ORDER BY LOWER(username)`)
+	if err != nil {
+		u.log.Error("Failed to find duplicate usernames: ", err)
+		return nil, fmt.Errorf("find duplicate usernames: %w", err)
+	}
+	defer rows.Close()
+
+	var duplicates []custom.DuplicateUsername
+	for rows.Next() {
+		var duplicate custom.DuplicateUsername
+		if err := rows.Scan(&duplicate.Username, pq.Array(&duplicate.IdUsers)); err != nil {
+			u.log.Error("Failed to scan the duplicate usernames: ", err)
+			return nil, fmt.Errorf("scan the duplicate usernames: %w", err)
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+	if err := rows.Err(); err != nil {
+		u.log.Error("Failed to read the duplicate usernames: ", err)
+		return nil, fmt.Errorf("read the duplicate usernames: %w", err)
+	}
+
+	return duplicates, nil
+}
+
 func NewUserRepository(db *sql.DB, log *logger.Logger) UserRepository {
 	return &userRepository{db: db, log: log}
 }
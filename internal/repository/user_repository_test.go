@@ -5,9 +5,12 @@ import (
 	"regexp"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/shared/custom"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -16,6 +19,8 @@ var expectedUser = entity.User{
 	Username: "username-test",
 	Password: "password-test",
 	Role:     "test",
+	Email:    "user@example.com",
+	Active:   true,
 }
 
 type userRepositoryTestSuite struct {
@@ -41,7 +46,8 @@ func (u *userRepositoryTestSuite) SetupTest() {
 }
 
 func (u *userRepositoryTestSuite) TestCreate_success() {
-	u.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_user (username, password, role) VALUES ($1, $2, $3) RETURNING id_user")).WillReturnRows(
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_user (username, password, role, email, must_change_password) VALUES ($1, $2, $3, NULLIF($4, ''), $5) RETURNING id_user")).
+		WithArgs(expectedUser.Username, expectedUser.Password, expectedUser.Role, expectedUser.Email, expectedUser.MustChangePassword).WillReturnRows(
 		sqlmock.NewRows([]string{"id_user"}).AddRow(expectedUser.Id_user),
 	)
 
@@ -59,14 +65,18 @@ func (u *userRepositoryTestSuite) TestCreate_fail() {
 }
 func (u *userRepositoryTestSuite) TestGetId_success() {
 
-	userRows := sqlmock.NewRows([]string{"id_user", "username", "password", "role"}).AddRow(
+	userRows := sqlmock.NewRows([]string{"id_user", "username", "password", "role", "email", "active", "last_login_at", "must_change_password"}).AddRow(
 		expectedUser.Id_user,
 		expectedUser.Username,
 		expectedUser.Password,
 		expectedUser.Role,
+		expectedUser.Email,
+		expectedUser.Active,
+		nil,
+		false,
 	)
 
-	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role FROM mst_user WHERE id_user = $1")).
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at, must_change_password FROM mst_user WHERE id_user = $1")).
 		WithArgs(expectedUser.Id_user).WillReturnRows(
 		userRows,
 	)
@@ -78,7 +88,7 @@ func (u *userRepositoryTestSuite) TestGetId_success() {
 }
 
 func (u *userRepositoryTestSuite) TestGetId_fail() {
-	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role FROM mst_user WHERE id_user = $1")).
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at, must_change_password FROM mst_user WHERE id_user = $1")).
 		WithArgs(expectedUser.Id_user).WillReturnError(sql.ErrNoRows)
 
 	_, err := u.ur.GetUserByID("uuid-merchant-test")
@@ -86,16 +96,30 @@ func (u *userRepositoryTestSuite) TestGetId_fail() {
 	u.NotNil(err)
 }
 
+func (u *userRepositoryTestSuite) TestGetId_notFoundKeepsErrNoRows() {
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("FROM mst_user WHERE id_user = $1")).
+		WithArgs(expectedUser.Id_user).WillReturnError(sql.ErrNoRows)
+
+	_, err := u.ur.GetUserByID(expectedUser.Id_user)
+
+	u.ErrorIs(err, sql.ErrNoRows)
+	u.ErrorContains(err, "retrieve the user")
+}
+
 func (u *userRepositoryTestSuite) TestGetUsername_success() {
 
-	userRows := sqlmock.NewRows([]string{"id_user", "username", "password", "role"}).AddRow(
+	userRows := sqlmock.NewRows([]string{"id_user", "username", "password", "role", "email", "active", "last_login_at", "must_change_password"}).AddRow(
 		expectedUser.Id_user,
 		expectedUser.Username,
 		expectedUser.Password,
 		expectedUser.Role,
+		expectedUser.Email,
+		expectedUser.Active,
+		nil,
+		false,
 	)
 
-	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role FROM mst_user WHERE username = $1")).
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at, must_change_password FROM mst_user WHERE LOWER(username) = LOWER($1)")).
 		WithArgs(expectedUser.Username).WillReturnRows(
 		userRows,
 	)
@@ -106,8 +130,25 @@ func (u *userRepositoryTestSuite) TestGetUsername_success() {
 	u.Equal(expectedUser, user)
 }
 
+func (u *userRepositoryTestSuite) TestGetUsername_deactivated() {
+	lastLogin := time.Date(2024, 7, 1, 8, 30, 0, 0, time.UTC)
+	userRows := sqlmock.NewRows([]string{"id_user", "username", "password", "role", "email", "active", "last_login_at", "must_change_password"}).
+		AddRow(expectedUser.Id_user, expectedUser.Username, expectedUser.Password, expectedUser.Role, expectedUser.Email, false, lastLogin, true)
+
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("FROM mst_user WHERE LOWER(username) = LOWER($1)")).
+		WithArgs(expectedUser.Username).WillReturnRows(userRows)
+
+	user, err := u.ur.GetUserByUsername(expectedUser.Username)
+
+	u.Nil(err)
+	u.Equal(expectedUser.Id_user, user.Id_user)
+	u.False(user.Active)
+	u.Equal(&lastLogin, user.LastLoginAt)
+	u.True(user.MustChangePassword)
+}
+
 func (u *userRepositoryTestSuite) TestGetUsername_fail() {
-	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role FROM mst_user WHERE username = $2")).
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at, must_change_password FROM mst_user WHERE username = $2")).
 		WithArgs(expectedUser.Username).WillReturnError(sql.ErrNoRows)
 
 	_, err := u.ur.GetUserByUsername("username-test")
@@ -116,37 +157,210 @@ func (u *userRepositoryTestSuite) TestGetUsername_fail() {
 }
 
 func (u *userRepositoryTestSuite) TestList_success() {
-	userRows := sqlmock.NewRows([]string{"id_user", "username", "password", "role"}).AddRow(
-		expectedUser.Id_user,
-		expectedUser.Username,
-		expectedUser.Password,
-		expectedUser.Role,
-	)
+	filter := custom.UserFilter{Page: 2, Size: 10}
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM mst_user")).
+		WithArgs(sql.NullString{}, sql.NullString{}, false, sql.NullInt64{}, sql.NullString{}).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at FROM mst_user")).
+		WithArgs(sql.NullString{}, sql.NullString{}, false, sql.NullInt64{}, sql.NullString{}, 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id_user", "username", "role", "email", "active", "last_login_at"}).AddRow(
+			expectedUser.Id_user,
+			expectedUser.Username,
+			expectedUser.Role,
+			expectedUser.Email,
+			true,
+			nil,
+		))
+
+	users, total, err := u.ur.ListUser(filter)
 
-	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role FROM mst_user")).WillReturnRows(
-		userRows,
-	)
+	u.Nil(err)
+	u.Equal(11, total)
+	u.Equal([]custom.UserListItem{{
+		IdUser:   expectedUser.Id_user,
+		Username: expectedUser.Username,
+		Role:     expectedUser.Role,
+		Email:    expectedUser.Email,
+		Active:   true,
+	}}, users)
+	u.Nil(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestList_filtered() {
+	filter := custom.UserFilter{Page: 1, Size: 20, Q: "ek_o%", Role: "employee", IncludeInactive: true, InactiveDays: 30}
+	pattern := sql.NullString{String: `%ek\_o\%%`, Valid: true}
+	role := sql.NullString{String: "employee", Valid: true}
+	days := sql.NullInt64{Int64: 30, Valid: true}
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM mst_user")).
+		WithArgs(pattern, role, true, days, sql.NullString{}).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at FROM mst_user")).
+		WithArgs(pattern, role, true, days, sql.NullString{}, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id_user", "username", "role", "email", "active", "last_login_at"}))
+
+	users, total, err := u.ur.ListUser(filter)
 
-	users, err := u.ur.ListUser()
+	u.Nil(err)
+	u.Equal(0, total)
+	u.Equal([]custom.UserListItem{}, users)
+	u.Nil(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestList_roleFilter() {
+	role := sql.NullString{String: "admin", Valid: true}
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM mst_user")).
+		WithArgs(sql.NullString{}, role, false, sql.NullInt64{}, sql.NullString{}).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("AND ($2::text IS NULL OR role = $2)")).
+		WithArgs(sql.NullString{}, role, false, sql.NullInt64{}, sql.NullString{}, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id_user", "username", "role", "email", "active", "last_login_at"}).
+			AddRow("uuid-admin", "admin", "admin", "", true, nil))
+
+	users, total, err := u.ur.ListUser(custom.UserFilter{Page: 1, Size: 20, Role: "admin"})
 
 	u.Nil(err)
-	u.Equal([]entity.User{expectedUser}, users)
+	u.Equal(1, total)
+	u.Equal([]custom.UserListItem{{IdUser: "uuid-admin", Username: "admin", Role: "admin", Active: true}}, users)
+	u.Nil(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestList_usernameSearch() {
+	username := sql.NullString{String: "%eko%", Valid: true}
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM mst_user")).
+		WithArgs(sql.NullString{}, sql.NullString{}, false, sql.NullInt64{}, username).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("AND ($5::text IS NULL OR username ILIKE $5)")).
+		WithArgs(sql.NullString{}, sql.NullString{}, false, sql.NullInt64{}, username, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id_user", "username", "role", "email", "active", "last_login_at"}).
+			AddRow("uuid-eko", "eko", "employee", "eko@example.com", true, nil))
+
+	users, total, err := u.ur.ListUser(custom.UserFilter{Page: 1, Size: 20, Username: "eko"})
+
+	u.Nil(err)
+	u.Equal(1, total)
+	u.Equal("eko", users[0].Username)
+	u.Nil(u.mockSql.ExpectationsWereMet())
 }
 
 func (u *userRepositoryTestSuite) TestList_fail() {
-	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role FROM mst_user")).WillReturnError(sql.ErrNoRows)
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM mst_user")).WillReturnError(sql.ErrConnDone)
 
-	_, err := u.ur.ListUser()
+	_, _, err := u.ur.ListUser(custom.UserFilter{Page: 1, Size: 20})
 
 	u.NotNil(err)
 }
 
-func (u *userRepositoryTestSuite) TestDelete_fail() {
-	u.mockSql.ExpectQuery(regexp.QuoteMeta("DELETE FROM mst_user WHERE id_user = $1")).WillReturnError(sql.ErrNoRows)
+func (u *userRepositoryTestSuite) expectAdminCount(admins int) {
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM (SELECT 1 FROM mst_user WHERE role = 'admin' AND deleted_at IS NULL FOR UPDATE) admins")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(admins))
+}
 
-	err := u.ur.DeleteUser(expectedUser.Id_user)
+func (u *userRepositoryTestSuite) TestSoftDeleteUser() {
+	u.mockSql.ExpectBegin()
+	u.expectAdminCount(1)
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE mst_user SET deleted_at = NOW() WHERE id_user = $1 AND deleted_at IS NULL RETURNING role")).
+		WithArgs(expectedUser.Id_user).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("employee"))
+	u.mockSql.ExpectCommit()
 
-	u.NotNil(err)
+	err := u.ur.SoftDeleteUser(expectedUser.Id_user)
+
+	u.NoError(err)
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestSoftDeleteUser_OneOfSeveralAdmins() {
+	u.mockSql.ExpectBegin()
+	u.expectAdminCount(2)
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE mst_user SET deleted_at = NOW()")).WithArgs("uuid-admin").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin"))
+	u.mockSql.ExpectCommit()
+
+	u.NoError(u.ur.SoftDeleteUser("uuid-admin"))
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestSoftDeleteUser_LastAdmin() {
+	u.mockSql.ExpectBegin()
+	u.expectAdminCount(1)
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE mst_user SET deleted_at = NOW()")).WithArgs("uuid-admin").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin"))
+	u.mockSql.ExpectRollback()
+
+	err := u.ur.SoftDeleteUser("uuid-admin")
+
+	u.ErrorIs(err, ErrLastAdmin)
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestSoftDeleteUser_AlreadyDeleted() {
+	u.mockSql.ExpectBegin()
+	u.expectAdminCount(1)
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("UPDATE mst_user SET deleted_at = NOW()")).WithArgs("uuid-gone").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}))
+	u.mockSql.ExpectCommit()
+
+	u.NoError(u.ur.SoftDeleteUser("uuid-gone"))
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestActivateUser() {
+	u.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_user SET deleted_at = NULL WHERE id_user = $1")).
+		WithArgs(expectedUser.Id_user).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := u.ur.ActivateUser(expectedUser.Id_user)
+
+	u.NoError(err)
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestActivateUser_NotFound() {
+	u.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_user SET deleted_at = NULL WHERE id_user = $1")).
+		WithArgs("uuid-unknown").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := u.ur.ActivateUser("uuid-unknown")
+
+	u.ErrorIs(err, ErrUserNotFound)
+}
+
+func (u *userRepositoryTestSuite) TestUpdateLastLogin() {
+	at := time.Date(2024, 7, 1, 8, 30, 0, 0, time.UTC)
+	u.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_user SET last_login_at = $2 WHERE id_user = $1")).
+		WithArgs(expectedUser.Id_user, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	u.NoError(u.ur.UpdateLastLogin(expectedUser.Id_user, at))
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestFindDuplicateUsernames() {
+	u.mockSql.ExpectQuery("SELECT LOWER\\(username\\), array_agg").
+		WillReturnRows(sqlmock.NewRows([]string{"username", "id_users"}).AddRow("budi", "{uuid-1,uuid-2}"))
+
+	duplicates, err := u.ur.FindDuplicateUsernames()
+
+	u.NoError(err)
+	u.Equal([]custom.DuplicateUsername{{Username: "budi", IdUsers: []string{"uuid-1", "uuid-2"}}}, duplicates)
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestIsActive() {
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT deleted_at IS NULL FROM mst_user WHERE id_user = $1")).
+		WithArgs(expectedUser.Id_user).
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(false))
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT deleted_at IS NULL FROM mst_user WHERE id_user = $1")).
+		WithArgs("uuid-unknown").
+		WillReturnError(sql.ErrNoRows)
+
+	active, err := u.ur.IsActive(expectedUser.Id_user)
+	u.NoError(err)
+	u.False(active)
+
+	active, err = u.ur.IsActive("uuid-unknown")
+	u.NoError(err)
+	u.False(active)
 }
 
 func (u *userRepositoryTestSuite) TestUpdate_fail() {
@@ -163,3 +377,100 @@ func (u *userRepositoryTestSuite) TestUpdate_fail() {
 
 	u.NotNil(err)
 }
+
+func (u *userRepositoryTestSuite) TestCreate_usernameTakenIgnoringCase() {
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_user (username, password, role, email, must_change_password) VALUES ($1, $2, $3, NULLIF($4, ''), $5) RETURNING id_user")).
+		WithArgs("User", "password-test", "employee", "", false).WillReturnError(&pq.Error{Code: "23505"})
+
+	_, err := u.ur.CreateUser(entity.User{Username: "User", Password: "password-test", Role: "employee"})
+
+	u.ErrorIs(err, ErrUsernameTaken)
+}
+
+func (u *userRepositoryTestSuite) TestUpdate_usernameTakenIgnoringCase() {
+	u.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_user SET username = $2, password = $3, role = $4, email = NULLIF($5, '') WHERE id_user = $1")).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	_, err := u.ur.UpdateUser(entity.User{Id_user: "uuid-user-test", Username: "ADMIN", Password: "password-test", Role: "employee"})
+
+	u.ErrorIs(err, ErrUsernameTaken)
+}
+
+func (u *userRepositoryTestSuite) TestGetEmail_success() {
+	userRows := sqlmock.NewRows([]string{"id_user", "username", "password", "role", "email", "active", "last_login_at", "must_change_password"}).AddRow(
+		expectedUser.Id_user,
+		expectedUser.Username,
+		expectedUser.Password,
+		expectedUser.Role,
+		expectedUser.Email,
+		expectedUser.Active,
+		nil,
+		false,
+	)
+
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("SELECT id_user, username, password, role, COALESCE(email, ''), deleted_at IS NULL, last_login_at, must_change_password FROM mst_user WHERE LOWER(email) = LOWER($1)")).
+		WithArgs("USER@example.com").WillReturnRows(userRows)
+
+	user, err := u.ur.GetUserByEmail("USER@example.com")
+
+	u.NoError(err)
+	u.Equal(expectedUser, user)
+}
+
+func (u *userRepositoryTestSuite) TestCreate_invalidEmail() {
+	for _, email := range []string{"eko", "eko@", "Eko <eko@example.com>", "eko@example.com "} {
+		_, err := u.ur.CreateUser(entity.User{Username: "eko", Password: "password-test", Role: "employee", Email: email})
+
+		u.ErrorIs(err, ErrInvalidEmail, email)
+	}
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestCreate_emailTakenIgnoringCase() {
+	u.mockSql.ExpectQuery(regexp.QuoteMeta("INSERT INTO mst_user (username, password, role, email, must_change_password)")).
+		WithArgs("eko", "password-test", "employee", "EKO@example.com", false).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "mst_user_email_lower_idx"})
+
+	_, err := u.ur.CreateUser(entity.User{Username: "eko", Password: "password-test", Role: "employee", Email: "EKO@example.com"})
+
+	u.ErrorIs(err, ErrEmailTaken)
+}
+
+func (u *userRepositoryTestSuite) TestUpdate_invalidEmail() {
+	_, err := u.ur.UpdateUser(entity.User{Id_user: "uuid-user-test", Username: "eko", Password: "password-test", Role: "employee", Email: "not-an-email"})
+
+	u.ErrorIs(err, ErrInvalidEmail)
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestUpdatePassword() {
+	u.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_user SET password = $2 WHERE id_user = $1 AND deleted_at IS NULL")).
+		WithArgs(expectedUser.Id_user, "new-hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := u.ur.UpdatePassword(expectedUser.Id_user, "new-hash")
+
+	u.NoError(err)
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestSetPassword() {
+	u.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_user SET password = $2, must_change_password = $3 WHERE id_user = $1 AND deleted_at IS NULL")).
+		WithArgs(expectedUser.Id_user, "temporary-hash", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := u.ur.SetPassword(expectedUser.Id_user, "temporary-hash", true)
+
+	u.NoError(err)
+	u.NoError(u.mockSql.ExpectationsWereMet())
+}
+
+func (u *userRepositoryTestSuite) TestSetPassword_NotFound() {
+	u.mockSql.ExpectExec(regexp.QuoteMeta("UPDATE mst_user SET password = $2, must_change_password = $3")).
+		WithArgs("uuid-unknown", "temporary-hash", true).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := u.ur.SetPassword("uuid-unknown", "temporary-hash", true)
+
+	u.ErrorIs(err, ErrUserNotFound)
+}
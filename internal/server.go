@@ -1,8 +1,13 @@
 package internal
 
 import (
-	"database/sql"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/handler"
 	"server-pulsa-app/internal/logger"
@@ -10,6 +15,10 @@ import (
 	"server-pulsa-app/internal/repository"
 	"server-pulsa-app/internal/shared/service"
 	"server-pulsa-app/internal/usecase"
+	"server-pulsa-app/internal/version"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 	swaggerFiles "github.com/swaggo/files"
@@ -18,6 +27,7 @@ import (
 	_ "server-pulsa-app/docs"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // @title Server Pulsa API
@@ -33,84 +43,422 @@ import (
 // @BasePath /api/v1
 // @schemes http https
 type Server struct {
-	jwtService    service.JwtService
-	authUc        usecase.AuthUseCase
-	productUc     usecase.ProductUseCase
-	merchantUc    usecase.MerchantUseCase
-	transactionUc usecase.TransactionUseCase
-	userUc        usecase.UserUsecase
-	reportUc      usecase.ReportUseCase
-	topupUc       usecase.TopupUseCase
-
-	engine *gin.Engine
-	host   string
+	jwtService       service.JwtService
+	revokedTokenRepo repository.RevokedTokenRepository
+	sessionRepo      repository.SessionRepository
+	authUc           usecase.AuthUseCase
+	passwordUc       usecase.PasswordResetUseCase
+	productUc        usecase.ProductUseCase
+	providerUc       usecase.ProviderUseCase
+	productSyncUc    usecase.ProductSyncUseCase
+	merchantUc       usecase.MerchantUseCase
+	transactionUc    usecase.TransactionUseCase
+	userUc           usecase.UserUsecase
+	reportUc         usecase.ReportUseCase
+	topupUc          usecase.TopupUseCase
+	auditUc          usecase.AuditUseCase
+	sessionUc        usecase.SessionUseCase
+	twoFactorUc      usecase.TwoFactorUseCase
+	permissionUc     usecase.PermissionUseCase
+	dbStatsRepo      repository.DbStatsRepository
+	readiness        []service.DependencyChecker
+	database         service.DependencyChecker
+	metrics          *service.Metrics
+	userRepo         repository.UserRepository
+
+	engine      *gin.Engine
+	host        string
+	tlsCertFile string
+	tlsKeyFile  string
+	// tlsRedirectAddr is the plain HTTP address redirecting to HTTPS, empty does not listen on it
+	tlsRedirectAddr string
+	metricsAddr     string
+	docsEnabled     bool
+	basePath        string
+	basePathV2      string
+	syncInterval    time.Duration
+	publicLimit     int
+	userLimit       middleware.UserRateLimit
+	userStatusTTL   time.Duration
+	readyCritical   []string
+	readyTimeout    time.Duration
+	readyCacheFor   time.Duration
+	startedAt       time.Time
+	// draining is set once shutdown began, readiness fails from then on
+	draining        atomic.Bool
+	drainDelay      time.Duration
+	shutdownTimeout time.Duration
+	timeouts        config.HTTPTimeoutConfig
 }
 
 var log = logger.NewLogger()
 
+const revokedTokenCleanupInterval = time.Hour
+
 func (s *Server) initRoute() {
-	rg := s.engine.Group(config.ApiGroup)
-	authMiddleware := middleware.NewAuthMiddleware(s.jwtService)
+	rg := s.engine.Group(s.basePath)
+	// every limiter counts in a store of its own, an IP of the public catalog would otherwise share the count of the
+	// same IP against REQUEST_RATE_LIMIT
+	authMiddleware := middleware.NewAuthMiddleware(s.jwtService, s.revokedTokenRepo, s.sessionRepo, s.userRepo, repository.NewMemoryRateLimitRepository(),
+		s.userStatusTTL, s.userLimit)
 
 	handler.NewMerchantHandler(s.merchantUc, authMiddleware, rg, &log).Route()
-	handler.NewAuthController(s.authUc, rg, &log).Route()
+	handler.NewAuthController(s.authUc, authMiddleware, rg, &log).Route()
+	handler.NewPasswordResetHandler(s.passwordUc, rg, &log).Route()
 	handler.NewProductController(s.productUc, rg, authMiddleware, &log).Route()
+	handler.NewProviderController(s.providerUc, rg, authMiddleware, &log).Route()
+	handler.NewProductSyncHandler(s.productSyncUc, rg, authMiddleware, &log).Route()
+	// public catalog is served without the auth middleware, rate limited per client IP instead
+	handler.NewPublicProductHandler(s.productUc, rg, middleware.NewIPRateLimitMiddleware(middleware.NewWindowLimiter(repository.NewMemoryRateLimitRepository(), s.publicLimit, time.Minute), &log), &log).Route()
 	handler.NewTransactionHandler(s.transactionUc, authMiddleware, rg, &log).Route()
 	handler.NewUserHandler(s.userUc, authMiddleware, rg, &log).Route()
 	handler.NewReportHandler(s.reportUc, authMiddleware, rg, &log).Route()
 	handler.NewTopupHandler(s.topupUc, authMiddleware, rg, &log).Route()
+	handler.NewAuditHandler(s.auditUc, authMiddleware, rg, &log).Route()
+	handler.NewSessionHandler(s.sessionUc, authMiddleware, rg, &log).Route()
+	handler.NewTwoFactorHandler(s.twoFactorUc, authMiddleware, rg, &log).Route()
+	handler.NewPermissionHandler(s.permissionUc, authMiddleware, rg, &log).Route()
+	handler.NewDbStatsHandler(s.dbStatsRepo, authMiddleware, rg, &log).Route()
+
+	// v2 shares the usecases with v1, only the response shape differs
+	rgV2 := s.engine.Group(s.basePathV2)
+	handler.NewTransactionHandlerV2(s.transactionUc, authMiddleware, rgV2, &log).Route()
+
+	handler.NewReadinessHandler(s.readiness, s.readyCritical, s.readyTimeout, s.readyCacheFor, &s.draining, &s.engine.RouterGroup, &log).Route()
+	handler.NewHealthHandler(s.database, s.startedAt, &s.engine.RouterGroup, &log).Route()
+	if s.metricsAddr == "" {
+		s.engine.GET(config.GetMetrics, gin.WrapH(s.metrics.Handler()))
+	}
 
-	s.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	if s.docsEnabled {
+		handler.NewDocsHandler(rg).Route()
+		// the address the README pointed to before the UI moved under the api base path
+		s.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+	s.registerPanicRoute()
+}
+
+// runPriceSync periodically applies the supplier price list, failures are logged and retried on the next tick.
+func (s *Server) runPriceSync() {
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), s.syncInterval)
+		if _, err := s.productSyncUc.SyncSupplierPrices(ctx); err != nil {
+			log.Error("Scheduled supplier price sync failed: ", err)
+		}
+		cancel()
+	}
+}
+
+// runRevokedTokenCleanup periodically drops revocations of tokens that have expired on their own.
+func (s *Server) runRevokedTokenCleanup() {
+	ticker := time.NewTicker(revokedTokenCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.revokedTokenRepo.DeleteExpired(); err != nil {
+			log.Error("Scheduled revoked token cleanup failed: ", err)
+		}
+	}
 }
 
 func (s *Server) Run() {
+	log.Info("Starting server-pulsa-app", map[string]string{"version": version.Version, "commit": version.Commit, "buildTime": version.BuildTime})
 	s.initRoute()
-	if err := s.engine.Run(s.host); err != nil {
+	// duplicates are only logged, the server still starts so operators can resolve them through the API
+	if err := s.userUc.ReportDuplicateUsernames(); err != nil {
+		log.Error("Startup check for duplicate usernames failed: ", err)
+	}
+	if s.syncInterval > 0 {
+		go s.runPriceSync()
+	}
+	go s.runRevokedTokenCleanup()
+
+	server := s.apiServer()
+	metricsServer := s.metricsServer()
+	if metricsServer != nil {
+		go func() {
+			log.Info("Serving metrics on: ", metricsServer.Addr)
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("Metrics server stopped: ", err)
+			}
+		}()
+	}
+	redirectServer := s.redirectServer()
+	if redirectServer != nil {
+		go func() {
+			log.Info("Redirecting HTTP to HTTPS on: ", redirectServer.Addr)
+			if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("HTTPS redirect server stopped: ", err)
+			}
+		}()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+		if err := s.shutdown(server); err != nil {
+			log.Error("Graceful shutdown did not finish: ", err)
+		}
+		// the metrics stay up until the API is drained, so the last scrape still sees the shutdown
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+		if redirectServer != nil {
+			redirectServer.Close()
+		}
+		close(stopped)
+	}()
+
+	if err := s.listen(server); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		panic(fmt.Errorf("server not running on host %s, becauce error %v", s.host, err.Error()))
 	}
+	// listen returns as soon as shutdown begins, the open requests are still being drained
+	<-stopped
+}
+
+// shutdown takes the server out of rotation first: readiness answers 503 for the drain delay so the load balancer
+// stops sending traffic, only then the listener is closed and the open requests get shutdownTimeout to finish.
+func (s *Server) shutdown(server *http.Server) error {
+	s.draining.Store(true)
+	log.Info("Shutting down, readiness reports draining for: ", s.drainDelay.String())
+	time.Sleep(s.drainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// apiServer serves the API with the configured timeouts, the defaults of http.Server let a slow client hold a
+// connection forever.
+func (s *Server) apiServer() *http.Server {
+	return &http.Server{
+		Addr:              s.host,
+		Handler:           s.engine,
+		ReadHeaderTimeout: s.timeouts.ReadHeaderTimeout,
+		ReadTimeout:       s.timeouts.ReadTimeout,
+		WriteTimeout:      s.timeouts.WriteTimeout,
+		IdleTimeout:       s.timeouts.IdleTimeout,
+	}
+}
+
+// metricsServer serves /metrics on its own address, it is nil when the metrics share the API listener.
+func (s *Server) metricsServer() *http.Server {
+	if s.metricsAddr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(config.GetMetrics, s.metrics.Handler())
+	return &http.Server{Addr: s.metricsAddr, Handler: mux}
 }
 
-func NewServer() *Server {
-	cfg, _ := config.NewConfig()
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name)
+// redirectServer redirects plain HTTP to the API over HTTPS, it is nil when no redirect address is configured.
+func (s *Server) redirectServer() *http.Server {
+	if s.tlsRedirectAddr == "" {
+		return nil
+	}
+	return &http.Server{Addr: s.tlsRedirectAddr, Handler: httpsRedirect(s.host), ReadHeaderTimeout: 10 * time.Second}
+}
+
+// listen serves HTTPS when a certificate is configured and plain HTTP otherwise, it blocks until server is closed.
+// A SIGHUP reloads the certificate for the new connections, the open ones keep the one they started with.
+func (s *Server) listen(server *http.Server) error {
+	if s.tlsCertFile != "" {
+		certificates, err := newCertificateReloader(s.tlsCertFile, s.tlsKeyFile)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = &tls.Config{GetCertificate: certificates.getCertificate}
+
+		hangups := make(chan os.Signal, 1)
+		signal.Notify(hangups, syscall.SIGHUP)
+		server.RegisterOnShutdown(func() { signal.Stop(hangups) })
+		go func() {
+			for range hangups {
+				if err := certificates.reload(); err != nil {
+					log.Error("TLS certificate reload failed, the previous one stays in use: ", err)
+					continue
+				}
+				log.Info("TLS certificate reloaded from: ", s.tlsCertFile)
+			}
+		}()
 
-	db, err := sql.Open(cfg.Driver, dsn)
+		log.Info("Serving HTTPS on: ", server.Addr)
+		return server.ListenAndServeTLS("", "")
+	}
+	log.Info("Serving HTTP on: ", server.Addr)
+	return server.ListenAndServe()
+}
+
+// newEngine is gin without its default middlewares. The client IP of a request is only taken from X-Forwarded-For or
+// X-Real-IP when it comes from one of trustedProxies, gin trusts every proxy otherwise.
+func newEngine(trustedProxies []string) (*gin.Engine, error) {
+	engine := gin.New()
+	if err := engine.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("trust the proxies: %w", err)
+	}
+	return engine, nil
+}
+
+// NewServer reads the config and connects to the database, an error of either is returned instead of surfacing on
+// the first request. A --help on the command line is flag.ErrHelp.
+func NewServer() (*Server, error) {
+	cfg, err := config.NewConfig()
 	if err != nil {
-		fmt.Println("connection error", err)
+		return nil, fmt.Errorf("read the config: %w", err)
+	}
+	log.SetOutput(cfg.LogOutput)
+	log.Info("Effective config", cfg.Effective())
+
+	db, err := openDatabase(cfg.DBConfig)
+	if err != nil {
+		log.Error("Failed to connect to the database", err)
+		return nil, err
 	}
 
 	//inject dependencies repo layer
 	userRepo := repository.NewUserRepository(db, &log)
 	productRepo := repository.NewProductRepository(db, &log)
+	providerRepo := repository.NewProviderRepository(db, &log)
 	merchantRepo := repository.NewMerchantRepository(db, &log)
-	transactionRepo := repository.NewTransactionRepository(db, &log)
+	notifier := service.NewLogNotifier(&log)
+	transactionRepo := repository.NewTransactionRepository(db, &log, notifier)
 	reportRepo := repository.NewReportRepository(db, &log)
+	passwordResetRepo := repository.NewPasswordResetRepository(db, &log)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db, &log)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db, &log)
+	sessionRepo := repository.NewSessionRepository(db, &log)
+	twoFactorRepo := repository.NewTwoFactorRepository(db, &log)
+	permissionRepo := repository.NewRolePermissionRepository(db, &log)
+	auditRepo := repository.NewAuditLogRepository(db, &log)
+	authEventRepo := repository.NewAuthEventRepository(db, &log)
 	topupRepo := repository.NewTopupRepository(db)
 
 	//inject dependencies usecase layer
 	jwtService := service.NewJwtService(cfg.TokenConfig)
-	userUc := usecase.NewUserUsecase(userRepo, &log)
-	authUc := usecase.NewAuthUseCase(userUc, jwtService, &log)
-	productUc := usecase.NewProductUseCase(productRepo, &log)
+	passwordPolicy := usecase.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireLetter: cfg.PasswordRequireLetter,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		Blocklist:     cfg.PasswordBlocklist,
+	}
+	passwordHasher := service.NewPasswordHasher(cfg.PasswordHashConfig)
+	userUc := usecase.NewUserUsecase(userRepo, merchantRepo, auditRepo, authEventRepo, sessionRepo, passwordPolicy, passwordHasher, &log)
+	loginAttemptRepo := repository.NewMemoryLoginAttemptRepository(cfg.LoginFailureWindow)
+	authUc := usecase.NewAuthUseCase(userUc, jwtService, refreshTokenRepo, sessionRepo, twoFactorRepo, permissionRepo, revokedTokenRepo, loginAttemptRepo, authEventRepo, cfg.LoginMaxFailures, cfg.LoginFailureWindow, &log)
+	passwordUc := usecase.NewPasswordResetUseCase(userRepo, passwordResetRepo, authEventRepo, notifier, cfg.PasswordResetTTL, passwordPolicy, passwordHasher, &log)
+	productUc := usecase.NewProductUseCase(productRepo, providerRepo, auditRepo, &log)
+	providerUc := usecase.NewProviderUseCase(providerRepo, &log)
+	productSyncUc := usecase.NewProductSyncUseCase(productRepo, service.NewSupplierCatalogClient(cfg.SupplierConfig), cfg.SupplierId, &log)
 	merchantUc := usecase.NewMerchantUseCase(merchantRepo, &log)
-	transactionUc := usecase.NewTransactionUseCase(transactionRepo, &log)
+	metrics := service.NewMetrics()
+	transactionUc := usecase.NewTransactionUseCase(transactionRepo, metrics, &log)
 	reportUc := usecase.NewReportUseCase(reportRepo, &log)
 	topupUc := usecase.NewTopupUsecase(topupRepo)
+	auditUc := usecase.NewAuditUseCase(auditRepo, authEventRepo, &log)
+	sessionUc := usecase.NewSessionUseCase(sessionRepo, auditRepo, &log)
+	twoFactorUc := usecase.NewTwoFactorUseCase(userUc, twoFactorRepo, cfg.TwoFactorIssuer, &log)
+	permissionUc := usecase.NewPermissionUseCase(permissionRepo, auditRepo, &log)
 
-	engine := gin.Default()
-	host := fmt.Sprintf(":%s", cfg.ApiPort)
-	return &Server{
-		jwtService:    jwtService,
-		authUc:        authUc,
-		productUc:     productUc,
-		merchantUc:    merchantUc,
-		transactionUc: transactionUc,
-		userUc:        userUc,
-		reportUc:      reportUc,
-		topupUc:       topupUc,
-
-		engine: engine,
-		host:   host,
+	// the integrations are only checked once they are configured
+	database := service.NewDatabaseChecker(db)
+	readiness := []service.DependencyChecker{database, service.NewMigrationChecker(db)}
+	if cfg.ProviderHealthURL != "" {
+		readiness = append(readiness, service.NewHTTPDependencyChecker(service.DependencyProvider, cfg.ProviderHealthURL))
 	}
+	if cfg.SmsHealthURL != "" {
+		readiness = append(readiness, service.NewHTTPDependencyChecker(service.DependencySms, cfg.SmsHealthURL))
+	}
+
+	accessLogSkip := make(map[string]bool, len(cfg.AccessLogSkipPaths))
+	for _, path := range cfg.AccessLogSkipPaths {
+		accessLogSkip[path] = true
+	}
+
+	// unknown fields are a validation error of the field instead of being dropped
+	binding.EnableDecoderDisallowUnknownFields = cfg.StrictJSON
+	engine, err := newEngine(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	// first, so the requests the other middlewares reject are logged, counted and carry a request id too
+	engine.Use(middleware.NewRequestIDMiddleware())
+	if cfg.AccessLogEnabled {
+		engine.Use(middleware.NewAccessLogMiddleware(&log, accessLogSkip))
+	}
+	engine.Use(middleware.NewMetricsMiddleware(metrics))
+	// inside the access log and the metrics, so a panicking request is logged and counted as the 500 it answered
+	engine.Use(middleware.NewRecoveryMiddleware(&log, metrics))
+	// the probes and the scrape still answer while clients are limited and requests are being shed
+	probeRoutes := map[string]bool{
+		config.GetReady: true, config.GetReadyz: true, config.GetLivez: true, config.GetHealth: true, config.GetMetrics: true,
+	}
+	requestLimiter := middleware.NewWindowLimiter(repository.NewMemoryRateLimitRepository(), cfg.RequestRateLimit, time.Minute)
+	engine.Use(middleware.NewRateLimitMiddleware(requestLimiter, jwtService, probeRoutes, &log))
+	engine.Use(middleware.NewConcurrencyLimitMiddleware(cfg.MaxConcurrentRequests, probeRoutes))
+	uploadLimits := make(map[string]int64, 2*len(config.UploadRoutes))
+	uploadRoutes := make(map[string]bool, 2*len(config.UploadRoutes))
+	for path := range config.UploadRoutes {
+		for _, basePath := range []string{cfg.ApiBasePath, cfg.ApiV2BasePath} {
+			uploadLimits[basePath+path] = cfg.UploadMaxBodyBytes
+			uploadRoutes[basePath+path] = true
+		}
+	}
+	engine.Use(middleware.NewBodyLimitMiddleware(cfg.MaxBodyBytes, uploadLimits))
+	engine.Use(middleware.NewJSONContentTypeMiddleware(uploadRoutes))
+	if cfg.GzipEnabled {
+		engine.Use(middleware.NewGzipMiddleware(cfg.GzipMinSize))
+	}
+	// last, so the error bodies it writes for the handlers are compressed and see the body limit like any other
+	engine.Use(middleware.NewErrorMiddleware(&log))
+	return &Server{
+		jwtService:       jwtService,
+		revokedTokenRepo: revokedTokenRepo,
+		sessionRepo:      sessionRepo,
+		authUc:           authUc,
+		passwordUc:       passwordUc,
+		productUc:        productUc,
+		providerUc:       providerUc,
+		productSyncUc:    productSyncUc,
+		merchantUc:       merchantUc,
+		transactionUc:    transactionUc,
+		userUc:           userUc,
+		reportUc:         reportUc,
+		topupUc:          topupUc,
+		auditUc:          auditUc,
+		sessionUc:        sessionUc,
+		twoFactorUc:      twoFactorUc,
+		permissionUc:     permissionUc,
+		dbStatsRepo:      repository.NewDbStatsRepository(db),
+		readiness:        readiness,
+		database:         database,
+		metrics:          metrics,
+		userRepo:         userRepo,
+
+		engine:          engine,
+		host:            cfg.Address(),
+		tlsCertFile:     cfg.TLSCertFile,
+		tlsKeyFile:      cfg.TLSKeyFile,
+		tlsRedirectAddr: cfg.TLSRedirectAddr,
+		metricsAddr:     cfg.MetricsAddr,
+		docsEnabled:     cfg.DocsEnabled,
+		basePath:        cfg.ApiBasePath,
+		basePathV2:      cfg.ApiV2BasePath,
+		syncInterval:    cfg.SyncInterval,
+		publicLimit:     cfg.PublicRateLimit,
+		userLimit:       middleware.UserRateLimit{PerMinute: cfg.UserRateLimit, Burst: cfg.UserRateBurst},
+
+		userStatusTTL: cfg.UserStatusCacheTTL,
+		readyCritical: cfg.CriticalDependencies,
+		readyTimeout:  cfg.ReadinessTimeout,
+		readyCacheFor: cfg.ReadinessCacheTTL,
+		startedAt:     time.Now(),
+
+		drainDelay:      cfg.ShutdownDrainDelay,
+		shutdownTimeout: cfg.ShutdownTimeout,
+		timeouts:        cfg.HTTPTimeoutConfig,
+	}, nil
 }
@@ -0,0 +1,436 @@
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/middleware"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/service"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type serverTestSuite struct {
+	suite.Suite
+}
+
+func TestServerTestSuite(t *testing.T) {
+	suite.Run(t, new(serverTestSuite))
+}
+
+func (s *serverTestSuite) routePaths(basePath string) []string {
+	gin.SetMode(gin.TestMode)
+	server := &Server{engine: gin.New(), basePath: basePath, basePathV2: "/api/v2", metrics: service.NewMetrics()}
+	server.initRoute()
+
+	var paths []string
+	for _, route := range server.engine.Routes() {
+		paths = append(paths, route.Method+" "+route.Path)
+	}
+	return paths
+}
+
+func (s *serverTestSuite) TestInitRoute_CustomBasePath() {
+	paths := s.routePaths("/pulsa/api")
+
+	s.Contains(paths, "GET /pulsa/api/merchants")
+	s.Contains(paths, "POST /pulsa/api/auth/login")
+	s.Contains(paths, "GET /pulsa/api/transaction/:id")
+	s.NotContains(paths, "GET /api/v1/merchants")
+}
+
+func (s *serverTestSuite) TestInitRoute_DefaultBasePath() {
+	paths := s.routePaths("/api/v1")
+
+	s.Contains(paths, "GET /api/v1/merchants")
+	s.Contains(paths, "POST /api/v1/auth/register")
+}
+
+func (s *serverTestSuite) TestInitRoute_PublicCatalog() {
+	paths := s.routePaths("/api/v1")
+
+	s.Contains(paths, "GET /api/v1/public/products")
+}
+
+func (s *serverTestSuite) TestInitRoute_RegistersV2Transactions() {
+	paths := s.routePaths("/api/v1")
+
+	s.Contains(paths, "GET /api/v1/transactions")
+	s.Contains(paths, "GET /api/v2/transactions")
+	s.Contains(paths, "GET /api/v2/transaction/:id")
+	s.NotContains(paths, "GET /api/v2/merchants")
+}
+
+func (s *serverTestSuite) TestInitRoute_ReadinessOutsideBasePath() {
+	paths := s.routePaths("/pulsa/api")
+
+	s.Contains(paths, "GET /ready")
+	s.Contains(paths, "GET /healthz")
+	s.Contains(paths, "GET /readyz")
+	s.Contains(paths, "GET /livez")
+}
+
+func (s *serverTestSuite) TestInitRoute_MetricsWithoutAuth() {
+	gin.SetMode(gin.TestMode)
+	server := &Server{engine: gin.New(), basePath: "/api/v1", basePathV2: "/api/v2", metrics: service.NewMetrics()}
+	server.initRoute()
+
+	w := httptest.NewRecorder()
+	server.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "pulsa_transactions_created_total")
+	s.Nil(server.metricsServer())
+}
+
+func (s *serverTestSuite) TestInitRoute_DocsDisabled() {
+	gin.SetMode(gin.TestMode)
+	server := &Server{engine: gin.New(), basePath: "/api/v1", basePathV2: "/api/v2", metrics: service.NewMetrics()}
+	server.initRoute()
+
+	for _, path := range []string{"/api/v1/openapi.json", "/api/v1/docs", "/swagger/index.html"} {
+		w := httptest.NewRecorder()
+		server.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		s.Equal(http.StatusNotFound, w.Code, path)
+	}
+}
+
+// undocumentedRoutes have no godoc annotations yet, the spec is checked to cover every other route of the base path.
+var undocumentedRoutes = []string{"/topup", "/report"}
+
+func (s *serverTestSuite) TestInitRoute_DocsCoverEveryRoute() {
+	gin.SetMode(gin.TestMode)
+	server := &Server{engine: gin.New(), basePath: "/api/v1", basePathV2: "/api/v2", metrics: service.NewMetrics(), docsEnabled: true}
+	server.initRoute()
+
+	w := httptest.NewRecorder()
+	server.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	s.Require().Equal(http.StatusOK, w.Code)
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &spec))
+
+	param := regexp.MustCompile(`:(\w+)`)
+	for _, route := range server.engine.Routes() {
+		path, found := strings.CutPrefix(route.Path, "/api/v1")
+		if !found || strings.HasPrefix(path, "/docs") || path == "/openapi.json" {
+			continue
+		}
+		undocumented := false
+		for _, prefix := range undocumentedRoutes {
+			undocumented = undocumented || strings.HasPrefix(path, prefix)
+		}
+		if undocumented {
+			continue
+		}
+		// the spec writes :id as {id}
+		operations := spec.Paths[param.ReplaceAllString(path, "{$1}")]
+		s.Contains(operations, strings.ToLower(route.Method), "%s %s has no swag annotations", route.Method, route.Path)
+	}
+}
+
+func (s *serverTestSuite) TestInitRoute_MetricsOnSeparateAddress() {
+	gin.SetMode(gin.TestMode)
+	server := &Server{engine: gin.New(), basePath: "/api/v1", basePathV2: "/api/v2", metrics: service.NewMetrics(), metricsAddr: "127.0.0.1:9090"}
+	server.initRoute()
+
+	w := httptest.NewRecorder()
+	server.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	s.Equal(http.StatusNotFound, w.Code)
+
+	metricsServer := server.metricsServer()
+	s.Require().NotNil(metricsServer)
+	s.Equal("127.0.0.1:9090", metricsServer.Addr)
+	w = httptest.NewRecorder()
+	metricsServer.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func (s *serverTestSuite) TestApiServer_Timeouts() {
+	timeouts := config.HTTPTimeoutConfig{
+		ReadHeaderTimeout: 10 * time.Second, ReadTimeout: 30 * time.Second, WriteTimeout: time.Minute, IdleTimeout: 2 * time.Minute,
+	}
+	server := &Server{engine: gin.New(), host: "127.0.0.1:8080", timeouts: timeouts}
+
+	apiServer := server.apiServer()
+
+	s.Equal("127.0.0.1:8080", apiServer.Addr)
+	s.Equal(10*time.Second, apiServer.ReadHeaderTimeout)
+	s.Equal(30*time.Second, apiServer.ReadTimeout)
+	s.Equal(time.Minute, apiServer.WriteTimeout)
+	s.Equal(2*time.Minute, apiServer.IdleTimeout)
+}
+
+// freeAddr returns a local address with a free port, released again for the server to take.
+func (s *serverTestSuite) freeAddr() string {
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	defer probe.Close()
+	return probe.Addr().String()
+}
+
+// writeSelfSignedCert writes a certificate for 127.0.0.1 and its key to dir.
+func (s *serverTestSuite) writeSelfSignedCert(dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	s.Require().NoError(err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	s.Require().NoError(err)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	s.Require().NoError(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	s.Require().NoError(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func (s *serverTestSuite) TestListen_ServesTLSWhenCertConfigured() {
+	certFile, keyFile := s.writeSelfSignedCert(s.T().TempDir())
+	addr := s.freeAddr()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/ping", func(ctx *gin.Context) { ctx.String(http.StatusOK, "pong") })
+	server := &Server{engine: engine, host: addr, tlsCertFile: certFile, tlsKeyFile: keyFile}
+	httpServer := &http.Server{Addr: addr, Handler: engine}
+	done := make(chan error, 1)
+	go func() { done <- server.listen(httpServer) }()
+	defer func() {
+		httpServer.Close()
+		s.ErrorIs(<-done, http.ErrServerClosed)
+	}()
+
+	client := &http.Client{Timeout: time.Second, Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	var resp *http.Response
+	var err error
+	s.Eventually(func() bool {
+		resp, err = client.Get("https://" + addr + "/ping")
+		return err == nil
+	}, 3*time.Second, 20*time.Millisecond)
+	s.Require().NotNil(resp)
+	defer resp.Body.Close()
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.NotNil(resp.TLS)
+}
+
+func (s *serverTestSuite) TestShutdown_ReadinessFailsWhileDraining() {
+	gin.SetMode(gin.TestMode)
+	addr := s.freeAddr()
+	server := &Server{engine: gin.New(), host: addr, basePath: "/api/v1", basePathV2: "/api/v2", drainDelay: 300 * time.Millisecond, shutdownTimeout: time.Second, metrics: service.NewMetrics()}
+	server.initRoute()
+	httpServer := &http.Server{Addr: addr, Handler: server.engine}
+	done := make(chan error, 1)
+	go func() { done <- server.listen(httpServer) }()
+
+	// no keep-alive, an idle connection the client never used would hold up the shutdown
+	client := &http.Client{Timeout: time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+	s.Eventually(func() bool {
+		resp, err := client.Get("http://" + addr + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 3*time.Second, 20*time.Millisecond)
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- server.shutdown(httpServer) }()
+
+	// the listener stays open during the drain delay, readiness already fails and liveness still passes
+	s.Eventually(func() bool { return server.draining.Load() }, time.Second, 5*time.Millisecond)
+	resp, err := client.Get("http://" + addr + "/readyz")
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusServiceUnavailable, resp.StatusCode)
+	resp, err = client.Get("http://" + addr + "/livez")
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	s.NoError(<-shutdownErr)
+	s.ErrorIs(<-done, http.ErrServerClosed)
+}
+
+func (s *serverTestSuite) TestCertificateReloader_KeepsTheLastGoodCertificate() {
+	dir := s.T().TempDir()
+	certFile, keyFile := s.writeSelfSignedCert(dir)
+	certificates, err := newCertificateReloader(certFile, keyFile)
+	s.Require().NoError(err)
+	first, _ := certificates.getCertificate(nil)
+
+	// rotated on disk, served once reloaded
+	s.writeSelfSignedCert(dir)
+	s.NoError(certificates.reload())
+	rotated, _ := certificates.getCertificate(nil)
+	s.NotEqual(first.Certificate[0], rotated.Certificate[0])
+
+	// a half written pair is refused, the rotated one stays
+	s.Require().NoError(os.WriteFile(keyFile, []byte("not a key"), 0600))
+	s.Error(certificates.reload())
+	current, _ := certificates.getCertificate(nil)
+	s.Same(rotated, current)
+
+	_, err = newCertificateReloader(certFile, keyFile)
+	s.Error(err)
+}
+
+func (s *serverTestSuite) TestHTTPSRedirect() {
+	for _, tc := range []struct {
+		apiAddr, host, target, expected string
+	}{
+		{":8443", "pulsa.example.com", "/api/v1/products?page=2", "https://pulsa.example.com:8443/api/v1/products?page=2"},
+		{":443", "pulsa.example.com:80", "/api/v1/products", "https://pulsa.example.com/api/v1/products"},
+		{"0.0.0.0:443", "[::1]", "/healthz", "https://[::1]/healthz"},
+		{":8443", "[::1]:80", "/healthz", "https://[::1]:8443/healthz"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+
+		httpsRedirect(tc.apiAddr).ServeHTTP(w, req)
+
+		s.Equal(http.StatusMovedPermanently, w.Code)
+		s.Equal(tc.expected, w.Header().Get("Location"))
+	}
+}
+
+// flakyPinger fails as many pings as failures, then answers.
+type flakyPinger struct {
+	failures int
+	pings    int
+}
+
+func (p *flakyPinger) PingContext(context.Context) error {
+	p.pings++
+	if p.pings <= p.failures {
+		return errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	}
+	return nil
+}
+
+func (s *serverTestSuite) TestPingDatabase_RetriesWithBackoff() {
+	db := &flakyPinger{failures: 2}
+	s.NoError(pingDatabase(db, 3, time.Millisecond))
+	s.Equal(3, db.pings)
+
+	db = &flakyPinger{failures: 10}
+	err := pingDatabase(db, 2, time.Millisecond)
+	s.ErrorContains(err, "database unreachable after 3 attempts")
+	s.Equal(3, db.pings)
+}
+
+// loginAttempts sends attempts to a route limited to one request a minute per client IP, every one from the same
+// proxy claiming another client in X-Forwarded-For, and returns the status of the last.
+func (s *serverTestSuite) loginAttempts(trustedProxies []string, attempts int) int {
+	gin.SetMode(gin.TestMode)
+	engine, err := newEngine(trustedProxies)
+	s.Require().NoError(err)
+	engine.POST("/login", middleware.NewIPRateLimitMiddleware(middleware.NewWindowLimiter(repository.NewMemoryRateLimitRepository(), 1, time.Minute), &log), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	status := 0
+	for i := 0; i < attempts; i++ {
+		request := httptest.NewRequest(http.MethodPost, "/login", nil)
+		request.RemoteAddr = "203.0.113.7:40000"
+		request.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		status = recorder.Code
+	}
+	return status
+}
+
+func (s *serverTestSuite) TestNewEngine_SpoofedForwardedForKeepsTheClientIP() {
+	s.Equal(http.StatusTooManyRequests, s.loginAttempts(nil, 2))
+}
+
+func (s *serverTestSuite) TestNewEngine_TrustedProxyForwardsTheClientIP() {
+	s.Equal(http.StatusOK, s.loginAttempts([]string{"203.0.113.0/24"}, 2))
+}
+
+func (s *serverTestSuite) TestNewEngine_InvalidTrustedProxy() {
+	_, err := newEngine([]string{"load-balancer"})
+	s.ErrorContains(err, "trust the proxies")
+}
+
+// startServer runs NewServer with no command-line flags and without the settings of the machine running the test.
+func (s *serverTestSuite) startServer(env map[string]string) error {
+	for _, key := range []string{"CONFIG_FILE", "DB_HOST", "DB_PORT", "DB_USER", "DB_NAME", "DB_DRIVER", "API_PORT", "TOKEN_ISSUE", "TOKEN_SECRET"} {
+		s.T().Setenv(key, "")
+	}
+	for key, value := range env {
+		s.T().Setenv(key, value)
+	}
+	args := os.Args
+	os.Args = []string{"server-pulsa-app"}
+	defer func() { os.Args = args }()
+
+	server, err := NewServer()
+	s.Nil(server)
+	return err
+}
+
+func (s *serverTestSuite) TestNewServer_MissingConfig() {
+	err := s.startServer(nil)
+
+	s.ErrorContains(err, "read the config")
+	s.ErrorContains(err, "DB_HOST missing")
+	s.ErrorContains(err, "TOKEN_SECRET must not be empty")
+}
+
+func (s *serverTestSuite) TestNewServer_UnreachableDatabase() {
+	// a port nothing listens on any more
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	err = s.startServer(map[string]string{
+		"DB_HOST":            "127.0.0.1",
+		"DB_PORT":            port,
+		"DB_USER":            "postgres",
+		"DB_NAME":            "server_pulsa_db",
+		"DB_DRIVER":          "postgres",
+		"API_PORT":           "8080",
+		"TOKEN_ISSUE":        "Server Pulsa",
+		"TOKEN_SECRET":       "test-signing-key",
+		"DB_CONNECT_RETRIES": "1",
+		"DB_CONNECT_BACKOFF": "0",
+		"LOG_OUTPUT":         filepath.Join(s.T().TempDir(), "server.log"),
+	})
+
+	s.ErrorContains(err, "database unreachable after 2 attempts")
+}
@@ -0,0 +1,135 @@
+// Package apierror is the error every handler answers with. Handlers pass it to ctx.Error and the error middleware
+// writes it as a Response with its status, so all the error bodies of the API have the same shape.
+package apierror
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// The codes clients branch on, the messages are for people and may change.
+const (
+	CodeValidation      = "validation_failed"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeTooManyRequests = "too_many_requests"
+	CodeUnavailable     = "unavailable"
+	CodeInternal        = "internal_error"
+)
+
+// internalMessage is the only message a 500 ever carries, the cause is logged instead.
+const internalMessage = "internal server error"
+
+// Error is answered with Status and a Response of Code, Message and Details. Err is the cause, it is logged and never
+// sent to the client.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithCode is a copy of e with a more specific code than the one of its status, such as "username_taken".
+func (e *Error) WithCode(code string) *Error {
+	copied := *e
+	copied.Code = code
+	return &copied
+}
+
+// WithDetails is a copy of e with details, such as the fields that failed validation.
+func (e *Error) WithDetails(details any) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// Response is the body of every error answer.
+type Response struct {
+	Code      string `json:"code" example:"not_found"`
+	Message   string `json:"message" example:"merchant not found"`
+	Details   any    `json:"details,omitempty"`
+	RequestId string `json:"request_id,omitempty" example:"4f1c2a9e-0b7d-4d3a-9d55-2f0f6a7c9e21"`
+}
+
+// Response is the body of e, the request id is set by the middleware writing it.
+func (e *Error) Response() Response {
+	return Response{Code: e.Code, Message: e.Message, Details: e.Details}
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Validation is a 400, details are usually the {"field": "message"} map of common.ValidationErrors.
+func Validation(message string, details any) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeValidation, Message: message, Details: details}
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// Internal is a 500 for err, whose text stays in the logs.
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: internalMessage, Err: err}
+}
+
+// FromRepository maps an error of the repository layer: sql.ErrNoRows is a 404 with notFound as message, a unique
+// violation is a 409 and anything else a 500, so the text of a database error never reaches the client.
+func FromRepository(err error, notFound string) *Error {
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: notFound, Err: err}
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		return &Error{Status: http.StatusConflict, Code: CodeConflict, Message: "already exists", Err: err}
+	}
+	return Internal(err)
+}
+
+// As is err as an *Error, any other error is Internal. The message of a 500 is always the generic one, even when a
+// handler built the error itself.
+func As(err error) *Error {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return Internal(err)
+	}
+	if apiErr.Status == http.StatusInternalServerError && apiErr.Message != internalMessage {
+		copied := *apiErr
+		copied.Message = internalMessage
+		if copied.Err == nil {
+			copied.Err = errors.New(apiErr.Message)
+		}
+		return &copied
+	}
+	return apiErr
+}
@@ -0,0 +1,74 @@
+package apierror
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/suite"
+)
+
+type apierrorTestSuite struct {
+	suite.Suite
+}
+
+func (s *apierrorTestSuite) TestFromRepository_NoRows() {
+	err := FromRepository(fmt.Errorf("retrieve the merchant: %w", sql.ErrNoRows), "merchant not found")
+
+	s.Equal(http.StatusNotFound, err.Status)
+	s.Equal(Response{Code: CodeNotFound, Message: "merchant not found"}, err.Response())
+	s.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (s *apierrorTestSuite) TestFromRepository_UniqueViolation() {
+	pqErr := &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "mst_provider_name_key"`}
+
+	err := FromRepository(fmt.Errorf("create the provider: %w", pqErr), "provider not found")
+
+	s.Equal(http.StatusConflict, err.Status)
+	s.Equal(Response{Code: CodeConflict, Message: "already exists"}, err.Response())
+}
+
+func (s *apierrorTestSuite) TestFromRepository_OtherErrors() {
+	for _, cause := range []error{
+		&pq.Error{Code: "42P01", Message: `relation "mst_merchant" does not exist`},
+		sql.ErrConnDone,
+	} {
+		err := FromRepository(fmt.Errorf("retrieve the merchant: %w", cause), "merchant not found")
+
+		s.Equal(http.StatusInternalServerError, err.Status)
+		s.Equal(Response{Code: CodeInternal, Message: "internal server error"}, err.Response())
+		s.ErrorIs(err, cause, "the cause is kept for the log")
+	}
+}
+
+func (s *apierrorTestSuite) TestAs() {
+	notFound := NotFound("merchant not found")
+	s.Same(notFound, As(fmt.Errorf("wrapped: %w", notFound)))
+
+	plain := As(errors.New("pq: connection refused"))
+	s.Equal(Response{Code: CodeInternal, Message: "internal server error"}, plain.Response())
+	s.EqualError(plain, "internal server error: pq: connection refused")
+
+	// a 500 built with a message of its own still answers the generic one
+	custom := As(New(http.StatusInternalServerError, "export_failed", "write /tmp/export.csv: no space left on device"))
+	s.Equal(Response{Code: "export_failed", Message: "internal server error"}, custom.Response())
+	s.ErrorContains(custom, "no space left on device")
+}
+
+func (s *apierrorTestSuite) TestWithCodeAndDetailsCopy() {
+	base := Validation("invalid payload", nil)
+
+	withCode := base.WithCode("weak_password").WithDetails(map[string]string{"password": "too short"})
+
+	s.Equal(CodeValidation, base.Code)
+	s.Nil(base.Details)
+	s.Equal(Response{Code: "weak_password", Message: "invalid payload", Details: map[string]string{"password": "too short"}}, withCode.Response())
+}
+
+func TestApierrorTestSuite(t *testing.T) {
+	suite.Run(t, new(apierrorTestSuite))
+}
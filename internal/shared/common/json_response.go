@@ -1,8 +1,13 @@
 package common
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
+	"server-pulsa-app/internal/shared/apierror"
 	"server-pulsa-app/internal/shared/model"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,9 +32,26 @@ func SendSingleResponseOk(ctx *gin.Context, data interface{}, message string) {
 	})
 }
 
-func SendErrorResponse(ctx *gin.Context, code int, message string) {
-	ctx.AbortWithStatusJSON(code, &model.Status{
-		Code:    code,
-		Message: message,
-	})
+// SendJSONWithETag writes data as JSON together with an ETag computed from the body. When the
+// request carries a matching If-None-Match header only 304 Not Modified is returned.
+func SendJSONWithETag(ctx *gin.Context, code int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		ctx.Error(apierror.Internal(err))
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	ctx.Header("ETag", etag)
+
+	for _, candidate := range strings.Split(ctx.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	ctx.Data(code, "application/json; charset=utf-8", body)
 }
@@ -0,0 +1,123 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"server-pulsa-app/internal/entity"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// DateLayout is the dd-mm-yyyy format of the dates clients send, such as the transaction date.
+const DateLayout = "02-01-2006"
+
+// phonePattern matches Indonesian mobile numbers, 08xx, 628xx or +628xx, short enough for the 15 characters the
+// destination number column holds.
+var phonePattern = regexp.MustCompile(`^(\+62|62|0)8[1-9][0-9]{5,10}$`)
+
+// report validation failures with the json names clients send instead of the Go field names, and register the
+// binding tags of the request payloads: phone, ddmmyyyy and role
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+		v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+			return phonePattern.MatchString(fl.Field().String())
+		})
+		v.RegisterValidation("ddmmyyyy", func(fl validator.FieldLevel) bool {
+			_, err := time.Parse(DateLayout, fl.Field().String())
+			return err == nil
+		})
+		v.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+			return slices.Contains(entity.Roles, fl.Field().String())
+		})
+	}
+}
+
+// ValidationErrors turns a binding error into a {"field":"message"} map keyed by the json field path. A field
+// rejected in strict mode is reported under its name, other errors that are not validation errors (malformed json,
+// wrong types) under "body".
+func ValidationErrors(err error) map[string]string {
+	fields := map[string]string{}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		// the decoder has no error type for it, only the message: json: unknown field "name"
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			field = strings.Trim(field, `"`)
+			fields[field] = fmt.Sprintf("%s is not a known field (unknown)", field)
+			return fields
+		}
+		fields["body"] = err.Error()
+		return fields
+	}
+
+	for _, fieldErr := range validationErrs {
+		fields[fieldPath(fieldErr)] = validationMessage(fieldErr)
+	}
+	return fields
+}
+
+// fieldPath drops the root struct name from the namespace, so "Transactions.transactionDetail[0].productId"
+// becomes "transactionDetail[0].productId".
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fieldErr.Field()
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required (required)", fieldErr.Field())
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s (%s)", fieldErr.Field(), fieldErr.Param(), fieldErr.Tag())
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s (%s)", fieldErr.Field(), fieldErr.Param(), fieldErr.Tag())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s (gt)", fieldErr.Field(), fieldErr.Param())
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s (lt)", fieldErr.Field(), fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s] (oneof)", fieldErr.Field(), fieldErr.Param())
+	case "required_with":
+		return fmt.Sprintf("%s is required with %s (required_with)", fieldErr.Field(), lowerFirst(fieldErr.Param()))
+	case "required_without":
+		return fmt.Sprintf("%s is required when %s is empty (required_without)", fieldErr.Field(), lowerFirst(fieldErr.Param()))
+	case "email":
+		return fmt.Sprintf("%s must be an email address (email)", fieldErr.Field())
+	case "uuid":
+		return fmt.Sprintf("%s must be a UUID (uuid)", fieldErr.Field())
+	case "phone":
+		return fmt.Sprintf("%s must be a mobile number like 081234567890 (phone)", fieldErr.Field())
+	case "ddmmyyyy":
+		return fmt.Sprintf("%s must be a date in dd-mm-yyyy format (ddmmyyyy)", fieldErr.Field())
+	case "role":
+		return fmt.Sprintf("%s must be one of [%s] (role)", fieldErr.Field(), strings.Join(entity.Roles, " "))
+	}
+	return fmt.Sprintf("%s is invalid (%s)", fieldErr.Field(), fieldErr.Tag())
+}
+
+// lowerFirst turns the Go field name of a tag parameter, such as NameProvider, into its json name.
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/entity/dto"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/suite"
+)
+
+type validationErrorTestSuite struct {
+	suite.Suite
+}
+
+func (s *validationErrorTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+}
+
+// bind binds body into payload the way the handlers do and returns the field errors, nil when it is valid.
+func (s *validationErrorTestSuite) bind(body string, payload any) map[string]string {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	if err := ctx.ShouldBindJSON(payload); err != nil {
+		return ValidationErrors(err)
+	}
+	return nil
+}
+
+func (s *validationErrorTestSuite) TestTransactionPhoneAndDate() {
+	valid := `{"userId":"u","merchantId":"m","customerName":"eko","destinationNumber":"%s","transactionDate":"%s","transactionDetail":[{"productId":"p"}]}`
+
+	for _, number := range []string{"081234567890", "6281234567890", "+6281234567890"} {
+		s.Nil(s.bind(fmt.Sprintf(valid, number, "27-10-2024"), &entity.TransactionReq{}), number)
+	}
+
+	s.Equal(map[string]string{
+		"destinationNumber": "destinationNumber must be a mobile number like 081234567890 (phone)",
+		"transactionDate":   "transactionDate must be a date in dd-mm-yyyy format (ddmmyyyy)",
+	}, s.bind(fmt.Sprintf(valid, "0212345678", "2024-10-27"), &entity.TransactionReq{}))
+
+	s.Equal(map[string]string{
+		"transactionDate": "transactionDate must be a date in dd-mm-yyyy format (ddmmyyyy)",
+	}, s.bind(fmt.Sprintf(valid, "081234567890", "31-02-2024"), &entity.TransactionReq{}))
+}
+
+func (s *validationErrorTestSuite) TestRole() {
+	s.Nil(s.bind(`{"name":"eko","password":"secret123","role":"employee"}`, &entity.UserCreateRequest{}))
+
+	s.Equal(map[string]string{
+		"role": "role must be one of [admin employee] (role)",
+	}, s.bind(`{"name":"eko","password":"secret123","role":"owner"}`, &entity.UserCreateRequest{}))
+}
+
+func (s *validationErrorTestSuite) TestRegister() {
+	s.Equal(map[string]string{
+		"username": "username must be at least 3 (min)",
+		"email":    "email must be an email address (email)",
+	}, s.bind(`{"username":"ek","password":"secret123","email":"eko"}`, &dto.AuthRequestDto{}))
+}
+
+func (s *validationErrorTestSuite) TestProductProviderRequiredWithoutName() {
+	s.Nil(s.bind(`{"nameProvider":"Indosat","nominal":5000,"price":6000,"idSupliyer":"s"}`, &entity.ProductRequest{}))
+
+	s.Equal(map[string]string{
+		"idProvider": "idProvider is required when nameProvider is empty (required_without)",
+	}, s.bind(`{"nominal":5000,"price":6000,"idSupliyer":"s"}`, &entity.ProductRequest{}))
+}
+
+func (s *validationErrorTestSuite) TestUnknownFieldInStrictMode() {
+	body := `{"identifier":"eko","password":"secret123","admin":true}`
+	s.Nil(s.bind(body, &dto.LoginRequestDto{}), "unknown fields are dropped by default")
+
+	binding.EnableDecoderDisallowUnknownFields = true
+	defer func() { binding.EnableDecoderDisallowUnknownFields = false }()
+
+	s.Equal(map[string]string{"admin": "admin is not a known field (unknown)"}, s.bind(body, &dto.LoginRequestDto{}))
+}
+
+func (s *validationErrorTestSuite) TestMalformedBody() {
+	s.Contains(s.bind(`{"identifier":`, &dto.LoginRequestDto{}), "body")
+}
+
+func TestValidationErrorTestSuite(t *testing.T) {
+	suite.Run(t, new(validationErrorTestSuite))
+}
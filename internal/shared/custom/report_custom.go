@@ -4,3 +4,32 @@ type ReportResp struct {
 	ProviderName string `json:"providerName"`
 	Count        string `json:"count"`
 }
+
+type (
+	// MarginFilter narrows the product margin report. Empty dates mean no bound.
+	MarginFilter struct {
+		StartDate string
+		EndDate   string
+		SortBy    string
+		Limit     int
+	}
+
+	// StatsOverview are the totals of the admin dashboard. Products and users are the active ones and
+	// TransactionsToday the transactions created since midnight, whatever business date they carry.
+	StatsOverview struct {
+		Products          int64 `json:"products" example:"120"`
+		Merchants         int64 `json:"merchants" example:"35"`
+		Users             int64 `json:"users" example:"48"`
+		TransactionsToday int64 `json:"transactionsToday" example:"210"`
+	}
+
+	ProductMarginResp struct {
+		IdProduct    string  `json:"idProduct"`
+		NameProvider string  `json:"nameProvider"`
+		Nominal      float64 `json:"nominal"`
+		UnitsSold    int     `json:"unitsSold"`
+		TotalRevenue float64 `json:"totalRevenue"`
+		TotalCost    float64 `json:"totalCost"`
+		Margin       float64 `json:"margin"`
+	}
+)
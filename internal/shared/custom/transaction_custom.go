@@ -1,6 +1,9 @@
 package custom
 
-import "time"
+import (
+	"server-pulsa-app/internal/entity"
+	"time"
+)
 
 type (
 	TransactionsReq struct {
@@ -10,12 +13,16 @@ type (
 		User              UserRes                `json:"user"`
 		Merchant          MerchantRes            `json:"merchant"`
 		TransactionDate   time.Time              `json:"transactionDate"`
+		CreatedAt         time.Time              `json:"createdAt"`
+		UpdatedAt         time.Time              `json:"updatedAt"`
+		Status            string                 `json:"status"`
 		TransactionDetail []TransactionDetailReq `json:"transactionDetail"`
 	}
 
 	TransactionDetailReq struct {
 		TransactionDetailId string     `json:"transactionDetailId"`
 		TransactionsId      string     `json:"transactionId,omitempty"`
+		RefundedAt          *time.Time `json:"refundedAt,omitempty"`
 		Product             ProductRes `json:"product"`
 	}
 
@@ -33,8 +40,71 @@ type (
 
 	ProductRes struct {
 		IdProduct    string  ` json:"idProduct"`
+		IdProvider   string  ` json:"idProvider"`
 		NameProvider string  ` json:"nameProvider"`
 		Nominal      float64 ` json:"nominal"`
 		Price        float64 ` json:"price"`
 	}
+
+	// TransactionFilter narrows and pages the transaction history. An empty Status lists every status. Limit above
+	// zero pages the history, by Page counted from 1 or, for the date sorts, from Cursor, the NextCursor of the
+	// previous page. Cursor wins over Page when both are set.
+	TransactionFilter struct {
+		MerchantIds []string
+		Sort        string
+		Status      string
+		Page        int
+		Limit       int
+		Cursor      string
+	}
+
+	// TransactionPage is one page of the transaction history, NextCursor is empty on the last page and when the
+	// history is sorted by customer.
+	TransactionPage struct {
+		Transactions []TransactionsReq
+		NextCursor   string
+	}
+
+	TransactionSummary struct {
+		TransactionsReq
+		ItemCount    int     `json:"itemCount"`
+		TotalNominal float64 `json:"totalNominal"`
+		TotalPrice   float64 `json:"totalPrice"`
+	}
+
+	TransactionCreatedSummary struct {
+		entity.Transactions
+		ItemCount  int     `json:"itemCount"`
+		TotalPrice float64 `json:"totalPrice"`
+	}
+
+	// TransactionQuote is what a transaction of the products would debit from the merchant balance when it was
+	// quoted, Sufficient tells whether the balance covers it.
+	TransactionQuote struct {
+		MerchantId   string  `json:"merchantId" example:"5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60"`
+		TotalNominal float64 `json:"totalNominal" example:"15000"`
+		Balance      float64 `json:"balance" example:"50000"`
+		Sufficient   bool    `json:"sufficient" example:"true"`
+	}
+
+	// TransactionReceipt is a flat, print-ready view of one transaction for POS printers.
+	TransactionReceipt struct {
+		TransactionId     string        `json:"transactionId" example:"eyJhbGciOiJIUzI1NiIs..."`
+		MerchantId        string        `json:"merchantId" example:"5f0c6a9e-2b1d-4c3e-9a7f-1b2c3d4e5f60"`
+		MerchantName      string        `json:"merchantName" example:"Konter Pak Eko"`
+		MerchantAddress   string        `json:"merchantAddress" example:"Jombang"`
+		Cashier           string        `json:"cashier" example:"john_doe"`
+		CustomerName      string        `json:"customerName" example:"customer a"`
+		DestinationNumber string        `json:"destinationNumber" example:"08123456789"`
+		Date              string        `json:"date" example:"25-10-2024"`
+		Time              string        `json:"time" example:"14:05:09"`
+		Items             []ReceiptLine `json:"items"`
+		GrandTotal        float64       `json:"grandTotal" example:"16900"`
+	}
+
+	ReceiptLine struct {
+		Product string  `json:"product" example:"Telkomsel"`
+		Nominal float64 `json:"nominal" example:"10000"`
+		Price   float64 `json:"price" example:"10900"`
+	}
 )
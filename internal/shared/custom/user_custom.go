@@ -0,0 +1,44 @@
+package custom
+
+import "time"
+
+type (
+	// UserFilter narrows and pages the user list. Q matches a part of the username or email and Username a part of
+	// the username only, empty fields are not filtered on. Deactivated users are only listed with IncludeInactive. InactiveDays above zero only lists users
+	// that did not log in for that many days, including users that never logged in.
+	UserFilter struct {
+		Page            int
+		Size            int
+		Q               string
+		Username        string
+		Role            string
+		IncludeInactive bool
+		InactiveDays    int
+	}
+
+	// UserListItem is a user as listed to admins, it has no password field so the hash cannot leak.
+	UserListItem struct {
+		IdUser   string `json:"id_user" example:"eyJhbGciOiJIUzI1NiIs..."`
+		Username string `json:"name" example:"eko"`
+		Role     string `json:"role" example:"employee"`
+		Email    string `json:"email" example:"eko@example.com"`
+		Active   bool   `json:"active" example:"true"`
+		// LastLoginAt is null for users that never logged in.
+		LastLoginAt *time.Time `json:"last_login_at" example:"2024-07-01T08:30:00Z"`
+	}
+
+	// UserPage is one page of the user list, Total counts every user matching the filter.
+	UserPage struct {
+		Users []UserListItem `json:"users"`
+		Page  int            `json:"page" example:"1"`
+		Size  int            `json:"size" example:"20"`
+		Total int            `json:"total" example:"42"`
+	}
+
+	// DuplicateUsername is a username held by several users once the case is ignored, left over from before the
+	// usernames were stored lowercase.
+	DuplicateUsername struct {
+		Username string
+		IdUsers  []string
+	}
+)
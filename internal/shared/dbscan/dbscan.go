@@ -0,0 +1,71 @@
+// Package dbscan scans query results into structs by the db tags of their fields, so a repository does not list the
+// fields of every row in the column order of its query by hand.
+package dbscan
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// All scans every row into a T and closes rows. The columns are matched to the fields of T by their db tag, name the
+// computed columns with AS so they match too. A column without a field is an error rather than a field left zero.
+// NULL scans into pointer and sql.Null fields, a NULL in a plain field fails the scan like it does with rows.Scan.
+// All returns a nil slice when there are no rows.
+func All[T any](rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := fieldIndexes(reflect.TypeFor[T](), columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []T
+	dest := make([]any, len(columns))
+	for rows.Next() {
+		var item T
+		value := reflect.ValueOf(&item).Elem()
+		for i, index := range indexes {
+			dest[i] = value.FieldByIndex(index).Addr().Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// fieldIndexes finds the field of every column in t, fields of embedded structs included.
+func fieldIndexes(t reflect.Type, columns []string) ([][]int, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dbscan: %s is not a struct", t)
+	}
+
+	byTag := map[string][]int{}
+	for _, field := range reflect.VisibleFields(t) {
+		tag, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+		if tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+		byTag[tag] = field.Index
+	}
+
+	indexes := make([][]int, len(columns))
+	for i, column := range columns {
+		index, ok := byTag[column]
+		if !ok {
+			return nil, fmt.Errorf("dbscan: no field of %s is tagged db:%q", t, column)
+		}
+		indexes[i] = index
+	}
+	return indexes, nil
+}
@@ -0,0 +1,132 @@
+package dbscan
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type provider struct {
+	ProviderId   string `db:"id_provider"`
+	ProviderName string `db:"name_provider"`
+}
+
+// product embeds provider and has nullable columns, Note has no db tag and is never scanned
+type product struct {
+	provider
+	Id         string         `db:"id_product"`
+	Price      float64        `db:"price"`
+	Code       sql.NullString `db:"code"`
+	MerchantId *string        `db:"id_merchant"`
+	DeletedAt  *time.Time     `db:"deleted_at"`
+	Note       string
+}
+
+type dbscanTestSuite struct {
+	suite.Suite
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+}
+
+func (s *dbscanTestSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	s.Require().NoError(err)
+	s.db = db
+	s.mock = mock
+}
+
+func (s *dbscanTestSuite) TearDownTest() {
+	s.NoError(s.mock.ExpectationsWereMet())
+	s.db.Close()
+}
+
+func (s *dbscanTestSuite) query(rows *sqlmock.Rows) *sql.Rows {
+	s.mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	result, err := s.db.Query("SELECT")
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *dbscanTestSuite) TestAll_FlatStruct() {
+	rows := s.query(sqlmock.NewRows([]string{"name_provider", "id_provider"}).
+		AddRow("Telkomsel", "provider-1").
+		AddRow("Indosat", "provider-2"))
+
+	providers, err := All[provider](rows)
+
+	s.NoError(err)
+	s.Equal([]provider{{ProviderId: "provider-1", ProviderName: "Telkomsel"}, {ProviderId: "provider-2", ProviderName: "Indosat"}}, providers)
+}
+
+func (s *dbscanTestSuite) TestAll_EmbeddedAndNullableFields() {
+	deletedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	rows := s.query(sqlmock.NewRows([]string{"id_product", "id_provider", "name_provider", "price", "code", "id_merchant", "deleted_at"}).
+		AddRow("product-1", "provider-1", "Telkomsel", 10900, "TSEL10", "merchant-1", deletedAt).
+		AddRow("product-2", "provider-1", "Telkomsel", 20900, nil, nil, nil))
+
+	products, err := All[product](rows)
+
+	s.NoError(err)
+	s.Require().Len(products, 2)
+	merchantId := "merchant-1"
+	s.Equal(product{
+		provider:   provider{ProviderId: "provider-1", ProviderName: "Telkomsel"},
+		Id:         "product-1",
+		Price:      10900,
+		Code:       sql.NullString{String: "TSEL10", Valid: true},
+		MerchantId: &merchantId,
+		DeletedAt:  &deletedAt,
+	}, products[0])
+	s.Equal(product{provider: provider{ProviderId: "provider-1", ProviderName: "Telkomsel"}, Id: "product-2", Price: 20900}, products[1])
+}
+
+func (s *dbscanTestSuite) TestAll_NullInPlainField() {
+	rows := s.query(sqlmock.NewRows([]string{"id_provider", "name_provider"}).AddRow("provider-1", nil))
+
+	_, err := All[provider](rows)
+
+	s.Error(err)
+}
+
+func (s *dbscanTestSuite) TestAll_NoRows() {
+	rows := s.query(sqlmock.NewRows([]string{"id_provider", "name_provider"}))
+
+	providers, err := All[provider](rows)
+
+	s.NoError(err)
+	s.Nil(providers)
+}
+
+func (s *dbscanTestSuite) TestAll_ColumnWithoutField() {
+	rows := s.query(sqlmock.NewRows([]string{"id_provider", "coalesce"}).AddRow("provider-1", "Telkomsel"))
+
+	_, err := All[provider](rows)
+
+	s.ErrorContains(err, `db:"coalesce"`)
+}
+
+func (s *dbscanTestSuite) TestAll_RowError() {
+	rows := s.query(sqlmock.NewRows([]string{"id_provider", "name_provider"}).
+		AddRow("provider-1", "Telkomsel").
+		AddRow("provider-2", "Indosat").
+		RowError(1, sql.ErrConnDone))
+
+	_, err := All[provider](rows)
+
+	s.ErrorIs(err, sql.ErrConnDone)
+}
+
+func (s *dbscanTestSuite) TestAll_NotAStruct() {
+	rows := s.query(sqlmock.NewRows([]string{"id_provider"}).AddRow("provider-1"))
+
+	_, err := All[string](rows)
+
+	s.ErrorContains(err, "not a struct")
+}
+
+func TestDbscanTestSuite(t *testing.T) {
+	suite.Run(t, new(dbscanTestSuite))
+}
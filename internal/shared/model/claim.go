@@ -2,8 +2,20 @@ package model
 
 import "github.com/golang-jwt/jwt/v5"
 
+// Claim is the payload of an access token. MerchantIds are the merchants the user owned when the token was issued,
+// a changed assignment applies from the next login or refresh. SessionId is the login the token was issued for,
+// tokens issued before sessions existed have none. Permissions are the ones of the role at issue time, tokens issued
+// before permissions existed have none and are checked against the defaults of their role. RememberMe marks tokens
+// of a login with remember me, they are issued with the longer expiry.
 type Claim struct {
 	jwt.RegisteredClaims
-	UserId string `json:"userId"`
-	Role   string `json:"role"`
+	UserId      string   `json:"userId"`
+	Role        string   `json:"role"`
+	MerchantIds []string `json:"merchantIds,omitempty"`
+	SessionId   string   `json:"sid,omitempty"`
+	// Permissions has no omitempty, an empty list must stay distinguishable from a token without the claim.
+	Permissions []string `json:"permissions"`
+	RememberMe  bool     `json:"rememberMe,omitempty"`
+	// MustChangePassword limits the token to changing the password, logging out and the profile.
+	MustChangePassword bool `json:"mustChangePassword,omitempty"`
 }
@@ -9,3 +9,9 @@ type SingleResponse struct {
 	Status Status      `json:"status"`
 	Data   interface{} `json:"data"`
 }
+
+type ListResponse struct {
+	Status Status      `json:"status"`
+	Data   interface{} `json:"data"`
+	Total  int         `json:"total"`
+}
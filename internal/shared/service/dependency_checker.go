@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+)
+
+// Names of the dependencies the readiness check knows, READY_CRITICAL_DEPENDENCIES refers to them.
+const (
+	DependencyDatabase   = "database"
+	DependencyMigrations = "migrations"
+	DependencyProvider   = "provider"
+	DependencySms        = "sms"
+)
+
+// DependencyChecker reports whether a dependency can be reached, Check must return once ctx is done.
+type DependencyChecker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type databaseChecker struct {
+	db *sql.DB
+}
+
+func (d *databaseChecker) Name() string {
+	return DependencyDatabase
+}
+
+func (d *databaseChecker) Check(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+// migrationColumns recognizes every migration of internal/assets by a column it adds, there is no table recording
+// the applied migrations. A new migration file belongs in here too.
+var migrationColumns = []struct {
+	migration string
+	table     string
+	column    string
+}{
+	{migration: "migration_provider.sql", table: "mst_product", column: "id_provider"},
+}
+
+// migrationChecker is down while a migration is still pending, the code would fail on the old schema.
+type migrationChecker struct {
+	db *sql.DB
+}
+
+func (m *migrationChecker) Name() string {
+	return DependencyMigrations
+}
+
+func (m *migrationChecker) Check(ctx context.Context) error {
+	for _, migration := range migrationColumns {
+		var applied bool
+		err := m.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)`,
+			migration.table, migration.column).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			return fmt.Errorf("%s is not applied", migration.migration)
+		}
+	}
+	return nil
+}
+
+// httpChecker only tests reachability: any answer below 500 counts as up, so an endpoint that refuses HEAD or
+// wants credentials is still reachable.
+type httpChecker struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func (h *httpChecker) Name() string {
+	return h.name
+}
+
+func (h *httpChecker) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, h.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is unreachable: %v", h.name, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%s returned status %d", h.name, resp.StatusCode)
+	}
+	return nil
+}
+
+func NewDatabaseChecker(db *sql.DB) DependencyChecker {
+	return &databaseChecker{db: db}
+}
+
+func NewMigrationChecker(db *sql.DB) DependencyChecker {
+	return &migrationChecker{db: db}
+}
+
+// NewHTTPDependencyChecker checks url with a HEAD request, the timeout comes from the context of Check.
+func NewHTTPDependencyChecker(name, url string) DependencyChecker {
+	return &httpChecker{name: name, url: url, client: &http.Client{}}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPDependencyChecker_Up(t *testing.T) {
+	// a reachable endpoint that refuses HEAD still counts as up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+
+	checker := NewHTTPDependencyChecker(DependencyProvider, server.URL)
+
+	assert.Equal(t, DependencyProvider, checker.Name())
+	assert.NoError(t, checker.Check(context.Background()))
+}
+
+func TestHTTPDependencyChecker_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := NewHTTPDependencyChecker(DependencySms, server.URL).Check(context.Background())
+
+	assert.EqualError(t, err, "sms returned status 502")
+}
+
+func TestHTTPDependencyChecker_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.Error(t, NewHTTPDependencyChecker(DependencyProvider, server.URL).Check(ctx))
+}
+
+func TestMigrationChecker_Applied(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM information_schema.columns WHERE table_name = \$1 AND column_name = \$2\)`).
+		WithArgs("mst_product", "id_provider").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	checker := NewMigrationChecker(db)
+
+	assert.Equal(t, DependencyMigrations, checker.Name())
+	assert.NoError(t, checker.Check(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationChecker_Pending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("mst_product", "id_provider").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	err = NewMigrationChecker(db).Check(context.Background())
+
+	assert.EqualError(t, err, "migration_provider.sql is not applied")
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"server-pulsa-app/config"
 	"server-pulsa-app/internal/entity"
@@ -12,22 +14,41 @@ import (
 )
 
 type JwtService interface {
-	CreateToken(user entity.User) (dto.AuthResponseDto, error)
+	CreateToken(user entity.User, merchantIds []string, sessionId string, permissions []string, rememberMe bool) (dto.AuthResponseDto, error)
 	ValidateToken(tokenString string) (*model.Claim, error)
+	CreateRefreshToken(rememberMe bool) (string, time.Time, error)
 }
 type jwtService struct {
 	cfgToken config.TokenConfig
 }
 
-func (j *jwtService) CreateToken(user entity.User) (dto.AuthResponseDto, error) {
+// CreateToken issues an access token, with the longer remember me expiry when rememberMe is set.
+func (j *jwtService) CreateToken(user entity.User, merchantIds []string, sessionId string, permissions []string, rememberMe bool) (dto.AuthResponseDto, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return dto.AuthResponseDto{}, fmt.Errorf("failed to create token: %v", err)
+	}
+
+	expiresIn := j.cfgToken.JwtExpiresTime
+	if rememberMe {
+		expiresIn = j.cfgToken.JwtExpiresTimeRemember
+	}
+
 	claims := model.Claim{
 		RegisteredClaims: jwt.RegisteredClaims{
+			// the jti identifies this token when it is revoked on logout
+			ID:        hex.EncodeToString(jti),
 			Issuer:    j.cfgToken.IssuerName,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.cfgToken.JwtExpiresTime)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 		},
-		UserId: user.Id_user,
-		Role:   user.Role,
+		UserId:             user.Id_user,
+		Role:               user.Role,
+		MerchantIds:        merchantIds,
+		SessionId:          sessionId,
+		Permissions:        permissions,
+		RememberMe:         rememberMe,
+		MustChangePassword: user.MustChangePassword,
 	}
 
 	token := jwt.NewWithClaims(j.cfgToken.JwtSigningMethod, claims)
@@ -45,7 +66,7 @@ func (j *jwtService) ValidateToken(tokenString string) (*model.Claim, error) {
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("unauthorized : %v", err)
+		return nil, fmt.Errorf("unauthorized : %w", err)
 	}
 
 	claim, ok := token.Claims.(*model.Claim)
@@ -56,6 +77,21 @@ func (j *jwtService) ValidateToken(tokenString string) (*model.Claim, error) {
 	return claim, nil
 }
 
+// CreateRefreshToken returns an opaque random refresh token and its expiry. Refresh tokens are not JWTs, they are
+// only meaningful together with the hash the caller stores. A remembered login gets the longer lifetime.
+func (j *jwtService) CreateRefreshToken(rememberMe bool) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create refresh token: %v", err)
+	}
+
+	ttl := j.cfgToken.RefreshTokenTTL
+	if rememberMe {
+		ttl = j.cfgToken.RefreshTokenTTLRemember
+	}
+	return hex.EncodeToString(raw), time.Now().Add(ttl), nil
+}
+
 func NewJwtService(cfgToken config.TokenConfig) JwtService {
 	return &jwtService{cfgToken: cfgToken}
 }
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTokenConfig = config.TokenConfig{
+	IssuerName:              "test",
+	JwtSignatureKy:          []byte("secret"),
+	JwtSigningMethod:        jwt.SigningMethodHS256,
+	JwtExpiresTime:          time.Hour,
+	RefreshTokenTTL:         24 * time.Hour,
+	JwtExpiresTimeRemember:  12 * time.Hour,
+	RefreshTokenTTLRemember: 12 * 24 * time.Hour,
+}
+
+func TestCreateToken_RememberMeUsesLongerExpiry(t *testing.T) {
+	jwtService := NewJwtService(testTokenConfig)
+	user := entity.User{Id_user: "uuid-user", Role: "employee"}
+
+	token, err := jwtService.CreateToken(user, nil, "uuid-session", []string{}, false)
+	require.NoError(t, err)
+	claim, err := jwtService.ValidateToken(token.Token)
+	require.NoError(t, err)
+	assert.False(t, claim.RememberMe)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claim.ExpiresAt.Time, time.Minute)
+
+	token, err = jwtService.CreateToken(user, nil, "uuid-session", []string{}, true)
+	require.NoError(t, err)
+	claim, err = jwtService.ValidateToken(token.Token)
+	require.NoError(t, err)
+	assert.True(t, claim.RememberMe)
+	assert.WithinDuration(t, time.Now().Add(12*time.Hour), claim.ExpiresAt.Time, time.Minute)
+}
+
+func TestCreateToken_CarriesMustChangePassword(t *testing.T) {
+	jwtService := NewJwtService(testTokenConfig)
+
+	token, err := jwtService.CreateToken(entity.User{Id_user: "uuid-user", Role: "employee", MustChangePassword: true}, nil, "uuid-session", []string{}, false)
+	require.NoError(t, err)
+	claim, err := jwtService.ValidateToken(token.Token)
+	require.NoError(t, err)
+	assert.True(t, claim.MustChangePassword)
+}
+
+func TestCreateRefreshToken_RememberMeUsesLongerTTL(t *testing.T) {
+	jwtService := NewJwtService(testTokenConfig)
+
+	_, expiresAt, err := jwtService.CreateRefreshToken(false)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), expiresAt, time.Minute)
+
+	_, expiresAt, err = jwtService.CreateRefreshToken(true)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(12*24*time.Hour), expiresAt, time.Minute)
+}
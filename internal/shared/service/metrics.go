@@ -0,0 +1,108 @@
+package service
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// TransactionMetrics counts the outcomes of creating transactions, the transaction usecase records them.
+type TransactionMetrics interface {
+	TransactionCreated()
+	TransactionFailed()
+	InsufficientBalance()
+}
+
+// Metrics are the Prometheus metrics of the server. They live in their own registry rather than the global one, so
+// every server and every test starts from zero.
+type Metrics struct {
+	registry            *prometheus.Registry
+	requests            *prometheus.CounterVec
+	requestDuration     *prometheus.HistogramVec
+	inFlight            *prometheus.GaugeVec
+	panics              prometheus.Counter
+	transactionsCreated prometheus.Counter
+	transactionFailures prometheus.Counter
+	insufficientBalance prometheus.Counter
+}
+
+// ObserveRequest records a finished request, route is the route pattern such as "/api/v1/transaction/:id" so the
+// ids in paths do not make a series each.
+func (m *Metrics) ObserveRequest(route, method, status string, duration time.Duration) {
+	m.requests.WithLabelValues(route, method, status).Inc()
+	m.requestDuration.WithLabelValues(route, method, status).Observe(duration.Seconds())
+}
+
+// InFlight is the gauge of the requests being served on route, the status is not known yet.
+func (m *Metrics) InFlight(route string) prometheus.Gauge {
+	return m.inFlight.WithLabelValues(route)
+}
+
+// PanicRecovered counts a handler panic that was answered with 500 instead of taking the server down.
+func (m *Metrics) PanicRecovered() {
+	m.panics.Inc()
+}
+
+func (m *Metrics) TransactionCreated() {
+	m.transactionsCreated.Inc()
+}
+
+// TransactionFailed counts every transaction that was not created, insufficient balance included.
+func (m *Metrics) TransactionFailed() {
+	m.transactionFailures.Inc()
+}
+
+func (m *Metrics) InsufficientBalance() {
+	m.insufficientBalance.Inc()
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// NewMetrics registers the metrics together with the Go runtime and process collectors.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latencies by route, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being served by route.",
+		}, []string{"route"}),
+		panics: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "http_panics_total",
+			Help: "Handler panics recovered and answered with 500.",
+		}),
+		transactionsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pulsa_transactions_created_total",
+			Help: "Transactions created.",
+		}),
+		transactionFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pulsa_transaction_failures_total",
+			Help: "Transactions that could not be created.",
+		}),
+		insufficientBalance: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pulsa_transaction_insufficient_balance_total",
+			Help: "Transactions rejected because the merchant balance was too low.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.inFlight, m.panics,
+		m.transactionsCreated, m.transactionFailures, m.insufficientBalance,
+	)
+	return m
+}
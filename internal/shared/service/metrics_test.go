@@ -0,0 +1,29 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_TransactionCounters(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.TransactionCreated()
+	metrics.TransactionCreated()
+	metrics.TransactionFailed()
+	metrics.InsufficientBalance()
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, string(body), "pulsa_transactions_created_total 2")
+	assert.Contains(t, string(body), "pulsa_transaction_failures_total 1")
+	assert.Contains(t, string(body), "pulsa_transaction_insufficient_balance_total 1")
+	assert.Contains(t, string(body), "go_goroutines")
+}
@@ -0,0 +1,20 @@
+package service
+
+import "server-pulsa-app/internal/logger"
+
+type Notifier interface {
+	Notify(recipient, subject string, data any) error
+}
+
+type logNotifier struct {
+	log *logger.Logger
+}
+
+func (n *logNotifier) Notify(recipient, subject string, data any) error {
+	n.log.Info("Notification to "+recipient+": "+subject, data)
+	return nil
+}
+
+func NewLogNotifier(log *logger.Logger) Notifier {
+	return &logNotifier{log: log}
+}
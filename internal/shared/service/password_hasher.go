@@ -0,0 +1,171 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"server-pulsa-app/config"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms that can be configured with PASSWORD_HASH_ALGORITHM.
+const (
+	HashAlgorithmBcrypt   = "bcrypt"
+	HashAlgorithmArgon2id = "argon2id"
+)
+
+var (
+	// ErrPasswordMismatch is returned by Compare when the password does not match the hash.
+	ErrPasswordMismatch = errors.New("password doesn't match")
+	// ErrUnknownHash is returned by Compare for a hash that none of the supported algorithms produced.
+	ErrUnknownHash = errors.New("unknown password hash format")
+)
+
+// PasswordHasher hashes new passwords with the configured algorithm. Compare accepts hashes of every supported
+// algorithm, so users keep logging in after a switch until NeedsRehash moves them to the current one.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hash, password string) error
+	// NeedsRehash reports whether hash was made by another algorithm or with weaker parameters than configured.
+	NeedsRehash(hash string) bool
+}
+
+// Argon2idParams are the cost parameters of argon2id, Memory is in KiB.
+type Argon2idParams struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+const (
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+// comparePassword picks the algorithm from the hash itself.
+func comparePassword(hash, password string) error {
+	switch {
+	case isArgon2idHash(hash):
+		params, salt, key, err := decodeArgon2idHash(hash)
+		if err != nil {
+			return err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	case isBcryptHash(hash):
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+	return ErrUnknownHash
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func isArgon2idHash(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+func (b *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b *bcryptHasher) Compare(hash, password string) error {
+	return comparePassword(hash, password)
+}
+
+func (b *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < b.cost
+}
+
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+// Hash encodes the hash in the PHC string format, $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>.
+func (a *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.Time, a.params.Memory, a.params.Threads, argon2idKeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.params.Memory, a.params.Time, a.params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *argon2idHasher) Compare(hash, password string) error {
+	return comparePassword(hash, password)
+}
+
+func (a *argon2idHasher) NeedsRehash(hash string) bool {
+	if !isArgon2idHash(hash) {
+		return true
+	}
+	params, _, _, err := decodeArgon2idHash(hash)
+	return err != nil || params.Time < a.params.Time || params.Memory < a.params.Memory || params.Threads < a.params.Threads
+}
+
+func decodeArgon2idHash(hash string) (Argon2idParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=1,p=4", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return Argon2idParams{}, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, ErrUnknownHash
+	}
+
+	var params Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return Argon2idParams{}, nil, nil, ErrUnknownHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2idParams{}, nil, nil, ErrUnknownHash
+	}
+	return params, salt, key, nil
+}
+
+func NewBcryptHasher(cost int) PasswordHasher {
+	return &bcryptHasher{cost: cost}
+}
+
+func NewArgon2idHasher(params Argon2idParams) PasswordHasher {
+	return &argon2idHasher{params: params}
+}
+
+// NewPasswordHasher returns the hasher of the configured algorithm, anything but argon2id is bcrypt.
+func NewPasswordHasher(cfg config.PasswordHashConfig) PasswordHasher {
+	if cfg.PasswordHashAlgorithm == HashAlgorithmArgon2id {
+		return NewArgon2idHasher(Argon2idParams{Time: cfg.Argon2Time, Memory: cfg.Argon2Memory, Threads: cfg.Argon2Threads})
+	}
+	return NewBcryptHasher(cfg.BcryptCost)
+}
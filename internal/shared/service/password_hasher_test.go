@@ -0,0 +1,97 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"server-pulsa-app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// small argon2id parameters keep the tests fast
+var testArgon2idParams = Argon2idParams{Time: 1, Memory: 1024, Threads: 1}
+
+func TestBcryptHasher_HashAndCompare(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+
+	hash, err := hasher.Hash("secret-pass1")
+	require.NoError(t, err)
+
+	assert.NoError(t, hasher.Compare(hash, "secret-pass1"))
+	assert.ErrorIs(t, hasher.Compare(hash, "wrong-pass1"), ErrPasswordMismatch)
+	assert.False(t, hasher.NeedsRehash(hash))
+}
+
+func TestBcryptHasher_NeedsRehashForLowerCost(t *testing.T) {
+	weak, err := NewBcryptHasher(bcrypt.MinCost).Hash("secret-pass1")
+	require.NoError(t, err)
+
+	assert.True(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(weak))
+	assert.False(t, NewBcryptHasher(bcrypt.MinCost).NeedsRehash(weak))
+}
+
+func TestArgon2idHasher_HashAndCompare(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	hash, err := hasher.Hash("secret-pass1")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+	assert.NoError(t, hasher.Compare(hash, "secret-pass1"))
+	assert.ErrorIs(t, hasher.Compare(hash, "wrong-pass1"), ErrPasswordMismatch)
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	stronger := NewArgon2idHasher(Argon2idParams{Time: 2, Memory: 1024, Threads: 1})
+	assert.True(t, stronger.NeedsRehash(hash))
+}
+
+func TestHashers_CompareAcrossAlgorithms(t *testing.T) {
+	bcryptHash, err := NewBcryptHasher(bcrypt.MinCost).Hash("secret-pass1")
+	require.NoError(t, err)
+	argon2idHash, err := NewArgon2idHasher(testArgon2idParams).Hash("secret-pass1")
+	require.NoError(t, err)
+
+	// a switched algorithm still verifies the stored hashes and asks for them to be upgraded
+	argon2id := NewArgon2idHasher(testArgon2idParams)
+	assert.NoError(t, argon2id.Compare(bcryptHash, "secret-pass1"))
+	assert.True(t, argon2id.NeedsRehash(bcryptHash))
+
+	bcryptHasher := NewBcryptHasher(bcrypt.MinCost)
+	assert.NoError(t, bcryptHasher.Compare(argon2idHash, "secret-pass1"))
+	assert.True(t, bcryptHasher.NeedsRehash(argon2idHash))
+}
+
+func TestHashers_UnknownHash(t *testing.T) {
+	for _, hash := range []string{"", "plain-text", "$argon2id$v=19$broken", "$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5"} {
+		assert.ErrorIs(t, NewBcryptHasher(bcrypt.MinCost).Compare(hash, "secret-pass1"), ErrUnknownHash, hash)
+		assert.True(t, NewArgon2idHasher(testArgon2idParams).NeedsRehash(hash), hash)
+	}
+}
+
+func TestNewPasswordHasher_UsesConfiguredAlgorithm(t *testing.T) {
+	hasher := NewPasswordHasher(config.PasswordHashConfig{PasswordHashAlgorithm: HashAlgorithmArgon2id, Argon2Time: 1, Argon2Memory: 1024, Argon2Threads: 1})
+	hash, err := hasher.Hash("secret-pass1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$"))
+
+	hasher = NewPasswordHasher(config.PasswordHashConfig{PasswordHashAlgorithm: HashAlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	hash, err = hasher.Hash("secret-pass1")
+	require.NoError(t, err)
+	cost, err := bcrypt.Cost([]byte(hash))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost)
+}
+
+func TestBcryptHasher_AppliesConfiguredCost(t *testing.T) {
+	for _, configured := range []int{bcrypt.MinCost, bcrypt.MinCost + 2} {
+		hash, err := NewPasswordHasher(config.PasswordHashConfig{PasswordHashAlgorithm: HashAlgorithmBcrypt, BcryptCost: configured}).Hash("secret-pass1")
+		require.NoError(t, err)
+
+		cost, err := bcrypt.Cost([]byte(hash))
+		require.NoError(t, err)
+		assert.Equal(t, configured, cost)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"server-pulsa-app/internal/shared/custom"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// receiptWidth is the page width in mm, 80 mm is the paper roll of most receipt printers.
+const (
+	receiptWidth  = 80
+	receiptMargin = 5
+	receiptLine   = 5
+)
+
+// RenderReceiptPDF lays the receipt out on a single page as long as its lines need.
+func RenderReceiptPDF(receipt custom.TransactionReceipt) ([]byte, error) {
+	height := float64(2*receiptMargin + receiptLine*(12+2*len(receipt.Items)))
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "mm", Size: gofpdf.SizeType{Wd: receiptWidth, Ht: height}})
+	pdf.SetMargins(receiptMargin, receiptMargin, receiptMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle("Receipt "+receipt.TransactionId, true)
+	pdf.AddPage()
+	// the core fonts only know cp1252, names outside of it are written with the closest characters
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	width := float64(receiptWidth - 2*receiptMargin)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(width, receiptLine, tr(receipt.MerchantName), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(width, receiptLine, tr(receipt.MerchantAddress), "", 1, "C", false, 0, "")
+	pdf.Ln(receiptLine / 2)
+
+	for _, row := range [][2]string{
+		{"Date", receipt.Date + " " + receipt.Time},
+		{"Cashier", receipt.Cashier},
+		{"Customer", receipt.CustomerName},
+		{"Number", receipt.DestinationNumber},
+		{"Transaction", receipt.TransactionId},
+	} {
+		pdf.CellFormat(width*0.3, receiptLine, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(width*0.7, receiptLine, tr(row[1]), "", 1, "R", false, 0, "")
+	}
+	pdf.CellFormat(width, receiptLine/2, "", "B", 1, "", false, 0, "")
+
+	for _, item := range receipt.Items {
+		pdf.CellFormat(width, receiptLine, tr(item.Product), "", 1, "L", false, 0, "")
+		pdf.CellFormat(width/2, receiptLine, "  "+formatAmount(item.Nominal), "", 0, "L", false, 0, "")
+		pdf.CellFormat(width/2, receiptLine, formatAmount(item.Price), "", 1, "R", false, 0, "")
+	}
+	pdf.CellFormat(width, receiptLine/2, "", "B", 1, "", false, 0, "")
+
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(width/2, receiptLine, "Total", "", 0, "L", false, 0, "")
+	pdf.CellFormat(width/2, receiptLine, formatAmount(receipt.GrandTotal), "", 1, "R", false, 0, "")
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, fmt.Errorf("failed to render the receipt: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// formatAmount writes rupiah with dots between the thousands, as they are printed in Indonesia.
+func formatAmount(amount float64) string {
+	digits := fmt.Sprintf("%.0f", amount)
+	sign := ""
+	if digits[0] == '-' {
+		sign, digits = "-", digits[1:]
+	}
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "." + digits[i:]
+	}
+	return "Rp " + sign + digits
+}
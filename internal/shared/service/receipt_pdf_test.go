@@ -0,0 +1,31 @@
+package service
+
+import (
+	"bytes"
+	"server-pulsa-app/internal/shared/custom"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderReceiptPDF(t *testing.T) {
+	pdf, err := RenderReceiptPDF(custom.TransactionReceipt{
+		TransactionId: "tx-uuid",
+		MerchantName:  "Konter Bu Sïti",
+		Items: []custom.ReceiptLine{
+			{Product: "Telkomsel", Nominal: 10000, Price: 10900},
+			{Product: "XL", Nominal: 5000, Price: 6000},
+		},
+		GrandTotal: 16900,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "Rp 900", formatAmount(900))
+	assert.Equal(t, "Rp 16.900", formatAmount(16900))
+	assert.Equal(t, "Rp 1.250.000", formatAmount(1250000))
+	assert.Equal(t, "Rp -10.000", formatAmount(-10000))
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server-pulsa-app/config"
+	"server-pulsa-app/internal/entity"
+)
+
+type SupplierCatalogClient interface {
+	FetchPriceList(ctx context.Context) ([]entity.SupplierPriceItem, error)
+}
+
+type httpSupplierCatalogClient struct {
+	cfg    config.SupplierConfig
+	client *http.Client
+}
+
+func (s *httpSupplierCatalogClient) FetchPriceList(ctx context.Context) ([]entity.SupplierPriceItem, error) {
+	if s.cfg.PriceListURL == "" {
+		return nil, fmt.Errorf("supplier price list url is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.PriceListURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch supplier price list: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("supplier price list returned status %d", resp.StatusCode)
+	}
+
+	var items []entity.SupplierPriceItem
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("invalid supplier price list: %v", err)
+	}
+
+	return items, nil
+}
+
+func NewSupplierCatalogClient(cfg config.SupplierConfig) SupplierCatalogClient {
+	return &httpSupplierCatalogClient{cfg: cfg, client: &http.Client{Timeout: cfg.RequestTimeout}}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters of RFC 6238 as authenticator apps use them by default.
+const (
+	totpDigits       = 6
+	totpModulo       = 1000000
+	totpPeriod       = 30
+	totpSecretLength = 20
+	// totpSkew also accepts the codes of the neighbouring time steps, for phones whose clock drifts a little.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTotpSecret returns a random secret in the base32 form authenticator apps expect.
+func GenerateTotpSecret() (string, error) {
+	raw := make([]byte, totpSecretLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate the totp secret: %v", err)
+	}
+	return totpEncoding.EncodeToString(raw), nil
+}
+
+// TotpURL is the otpauth:// URL that authenticator apps read from a QR code.
+func TotpURL(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// ValidateTotp reports whether code is the code of secret at now or a neighbouring time step, together with the
+// matched step so callers can refuse a code that was already used.
+func ValidateTotp(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode is the HOTP value of RFC 4226 for the time step.
+func totpCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulo)
+}
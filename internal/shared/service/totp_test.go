@@ -0,0 +1,65 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the SHA1 secret of the RFC 6238 test vectors, "12345678901234567890" in base32
+const rfcTotpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTotp_RfcVectors(t *testing.T) {
+	// the RFC lists 8 digit codes, authenticator apps use their last 6 digits
+	vectors := map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"}
+	for unix, code := range vectors {
+		step, ok := ValidateTotp(rfcTotpSecret, code, time.Unix(unix, 0))
+		assert.True(t, ok, unix)
+		assert.Equal(t, unix/totpPeriod, step, unix)
+	}
+}
+
+func TestValidateTotp_AcceptsNeighbouringStep(t *testing.T) {
+	_, ok := ValidateTotp(rfcTotpSecret, "287082", time.Unix(59+totpPeriod, 0))
+	assert.True(t, ok)
+
+	_, ok = ValidateTotp(rfcTotpSecret, "287082", time.Unix(59+3*totpPeriod, 0))
+	assert.False(t, ok)
+}
+
+func TestValidateTotp_RejectsMalformedInput(t *testing.T) {
+	_, ok := ValidateTotp(rfcTotpSecret, "28708", time.Unix(59, 0))
+	assert.False(t, ok)
+	_, ok = ValidateTotp("not base32!", "287082", time.Unix(59, 0))
+	assert.False(t, ok)
+}
+
+func TestGenerateTotpSecret(t *testing.T) {
+	secret, err := GenerateTotpSecret()
+	require.NoError(t, err)
+
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	assert.Len(t, key, totpSecretLength)
+
+	// a code made from the secret validates
+	now := time.Now()
+	_, ok := ValidateTotp(secret, totpCode(key, now.Unix()/totpPeriod), now)
+	assert.True(t, ok)
+}
+
+func TestTotpURL(t *testing.T) {
+	raw := TotpURL("Server Pulsa", "budi", rfcTotpSecret)
+
+	parsed, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", parsed.Scheme)
+	assert.Equal(t, "totp", parsed.Host)
+	assert.True(t, strings.HasSuffix(parsed.Path, "Server Pulsa:budi"))
+	assert.Equal(t, rfcTotpSecret, parsed.Query().Get("secret"))
+	assert.Equal(t, "Server Pulsa", parsed.Query().Get("issuer"))
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 20
+	maxAuditLimit     = 100
+)
+
+// ErrInvalidAuthEventFilter wraps validation errors of the auth event filter.
+var ErrInvalidAuthEventFilter = errors.New("invalid auth event filter")
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type AuditUseCase interface {
+	FindAuditLog(query entity.AuditQuery) ([]entity.AuditLog, error)
+	FindAuthEvents(query entity.AuthEventQuery) ([]entity.AuthEvent, error)
+}
+
+type auditUseCase struct {
+	repo      repository.AuditLogRepository
+	eventRepo repository.AuthEventRepository
+	log       *logger.Logger
+}
+
+func (a *auditUseCase) FindAuditLog(query entity.AuditQuery) ([]entity.AuditLog, error) {
+	a.log.Info("Starting to retrive the audit log in the usecase layer", nil)
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultAuditLimit
+	}
+	if query.Limit > maxAuditLimit {
+		query.Limit = maxAuditLimit
+	}
+
+	return a.repo.List(query)
+}
+
+func (a *auditUseCase) FindAuthEvents(query entity.AuthEventQuery) ([]entity.AuthEvent, error) {
+	a.log.Info("Starting to retrive the auth events in the usecase layer", nil)
+
+	if query.UserId != "" && !uuidPattern.MatchString(query.UserId) {
+		return nil, fmt.Errorf("%w: invalid userId %s", ErrInvalidAuthEventFilter, query.UserId)
+	}
+
+	for _, date := range []string{query.StartDate, query.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("%w: invalid date %s, use YYYY-MM-DD", ErrInvalidAuthEventFilter, date)
+		}
+	}
+
+	if query.StartDate != "" && query.EndDate != "" && query.StartDate > query.EndDate {
+		return nil, fmt.Errorf("%w: startDate must not be after endDate", ErrInvalidAuthEventFilter)
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultAuditLimit
+	}
+	if query.Limit > maxAuditLimit {
+		query.Limit = maxAuditLimit
+	}
+
+	return a.eventRepo.List(query)
+}
+
+func NewAuditUseCase(repo repository.AuditLogRepository, eventRepo repository.AuthEventRepository, log *logger.Logger) AuditUseCase {
+	return &auditUseCase{repo: repo, eventRepo: eventRepo, log: log}
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type auditUsecaseTestSuite struct {
+	suite.Suite
+	mockAuditRepo *repositorymock.MockAuditLogRepository
+	mockEventRepo *repositorymock.MockAuthEventRepository
+	auditUseCase  AuditUseCase
+	log           logger.Logger
+}
+
+func (a *auditUsecaseTestSuite) SetupTest() {
+	a.mockAuditRepo = new(repositorymock.MockAuditLogRepository)
+	a.mockEventRepo = new(repositorymock.MockAuthEventRepository)
+	a.log = logger.NewLogger()
+	a.auditUseCase = NewAuditUseCase(a.mockAuditRepo, a.mockEventRepo, &a.log)
+}
+
+func (a *auditUsecaseTestSuite) TestFindAuditLog_Defaults() {
+	a.mockAuditRepo.On("List", entity.AuditQuery{Page: 1, Limit: 20}).Return([]entity.AuditLog{}, nil).Once()
+
+	_, err := a.auditUseCase.FindAuditLog(entity.AuditQuery{})
+
+	a.Nil(err)
+	a.mockAuditRepo.AssertExpectations(a.T())
+}
+
+func (a *auditUsecaseTestSuite) TestFindAuditLog_CapsLimit() {
+	a.mockAuditRepo.On("List", entity.AuditQuery{Page: 3, Limit: 100}).Return([]entity.AuditLog{}, nil).Once()
+
+	_, err := a.auditUseCase.FindAuditLog(entity.AuditQuery{Page: 3, Limit: 500})
+
+	a.Nil(err)
+	a.mockAuditRepo.AssertExpectations(a.T())
+}
+
+func (a *auditUsecaseTestSuite) TestFindAuthEvents_Defaults() {
+	query := entity.AuthEventQuery{UserId: "0b8f0ae4-1d5c-4a0e-9a39-3f0ad2f0c7d1", StartDate: "2024-08-01", EndDate: "2024-08-31", Page: 1, Limit: 20}
+	a.mockEventRepo.On("List", query).Return([]entity.AuthEvent{{Event: entity.AuthEventLogout}}, nil).Once()
+
+	events, err := a.auditUseCase.FindAuthEvents(entity.AuthEventQuery{UserId: query.UserId, StartDate: "2024-08-01", EndDate: "2024-08-31"})
+
+	a.Nil(err)
+	a.Len(events, 1)
+	a.mockEventRepo.AssertExpectations(a.T())
+}
+
+func (a *auditUsecaseTestSuite) TestFindAuthEvents_InvalidFilter() {
+	for _, query := range []entity.AuthEventQuery{
+		{UserId: "not-a-uuid"},
+		{StartDate: "01-08-2024"},
+		{StartDate: "2024-09-01", EndDate: "2024-08-01"},
+	} {
+		_, err := a.auditUseCase.FindAuthEvents(query)
+
+		a.ErrorIs(err, ErrInvalidAuthEventFilter)
+	}
+	a.mockEventRepo.AssertNotCalled(a.T(), "List", mock.Anything)
+}
+
+func TestAuditUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(auditUsecaseTestSuite))
+}
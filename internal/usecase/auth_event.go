@@ -0,0 +1,16 @@
+package usecase
+
+import (
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+)
+
+// recordAuthEvent is shared by every usecase that authenticates or changes a password. Like the audit log, a failed
+// write is only logged, it never fails the login or the password change itself.
+func recordAuthEvent(repo repository.AuthEventRepository, log *logger.Logger, event string, userId, identifier string, client entity.ClientInfo) {
+	err := repo.Record(entity.AuthEvent{UserId: userId, Event: event, Identifier: identifier, Ip: client.Ip, UserAgent: client.UserAgent})
+	if err != nil {
+		log.Error("Failed to record the auth event: ", err)
+	}
+}
@@ -1,52 +1,350 @@
 package usecase
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/entity/dto"
 	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
 	"server-pulsa-app/internal/shared/service"
+	"strings"
+	"time"
 )
 
+// LoginLockedError is returned by Login while the username or the client IP has too many recent failures.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
 type AuthUseCase interface {
-	Login(payload dto.AuthRequestDto) (dto.AuthResponseDto, error)
+	Login(payload dto.LoginRequestDto, client entity.ClientInfo) (dto.AuthResponseDto, error)
 	Register(payload dto.AuthRequestDto) (entity.User, error)
+	VerifyTwoFactor(payload dto.TwoFactorVerifyRequestDto, client entity.ClientInfo) (dto.AuthResponseDto, error)
+	Refresh(refreshToken string) (dto.AuthResponseDto, error)
+	Logout(userId, jti string, expiresAt time.Time, refreshToken string, client entity.ClientInfo) error
 }
 
 type authUseCase struct {
-	useCase    UserUsecase
-	jwtService service.JwtService
-	log        *logger.Logger
+	useCase        UserUsecase
+	jwtService     service.JwtService
+	refreshRepo    repository.RefreshTokenRepository
+	sessionRepo    repository.SessionRepository
+	twoFactorRepo  repository.TwoFactorRepository
+	permissionRepo repository.RolePermissionRepository
+	revokedRepo    repository.RevokedTokenRepository
+	attemptRepo    repository.LoginAttemptRepository
+	eventRepo      repository.AuthEventRepository
+	maxFailures    int
+	window         time.Duration
+	log            *logger.Logger
 }
 
-func (a *authUseCase) Login(payload dto.AuthRequestDto) (dto.AuthResponseDto, error) {
+// Login counts failures per username and per client IP, either one reaching maxFailures within the window
+// refuses further attempts with a LoginLockedError until the oldest counted failure leaves the window.
+// An identifier containing "@" is looked up as an email, anything else as a username. Both count on the username
+// of the account, so switching between them does not give more attempts.
+func (a *authUseCase) Login(payload dto.LoginRequestDto, client entity.ClientInfo) (dto.AuthResponseDto, error) {
 	a.log.Info("Starting to authenticate user in the use case layer", nil)
 
-	user, err := a.useCase.FindUserByUsernamePassword(payload.Username, payload.Password)
+	// emails are compared case-insensitively too, so the identifier is normalized whatever it is
+	payload.Identifier = normalizeUsername(payload.Identifier)
+	now := time.Now()
+	keys := []string{"user:" + a.accountName(payload.Identifier), "ip:" + client.Ip}
+	if retryAfter := a.lockedFor(keys, now); retryAfter > 0 {
+		a.log.Error("Login refused, too many failed attempts: ", map[string]string{"identifier": payload.Identifier, "ip": client.Ip})
+		recordAuthEvent(a.eventRepo, a.log, entity.AuthEventLoginFailure, "", payload.Identifier, client)
+		return dto.AuthResponseDto{}, &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	find := a.useCase.FindUserByUsernamePassword
+	if strings.Contains(payload.Identifier, "@") {
+		find = a.useCase.FindUserByEmailPassword
+	}
+	user, err := find(payload.Identifier, payload.Password)
 	if err != nil {
 		a.log.Error("Failed to authenticate user: ", err)
+		// user is only set when the identifier exists and the password was wrong
+		recordAuthEvent(a.eventRepo, a.log, entity.AuthEventLoginFailure, user.Id_user, payload.Identifier, client)
+		// the password of a deactivated user was right, so it does not count towards the lockout
+		if !errors.Is(err, ErrUserDeactivated) {
+			a.recordFailure(keys, now, user.Id_user, payload.Identifier, client)
+		}
 		return dto.AuthResponseDto{}, err
 	}
 
+	if a.maxFailures > 0 {
+		if err := a.attemptRepo.Reset(keys[0]); err != nil {
+			a.log.Error("Failed to reset the failed login attempts: ", err)
+		}
+	}
+
+	// with two-factor authentication the password only earns a challenge, the tokens are issued by VerifyTwoFactor.
+	// A failing check refuses the login rather than skipping the second factor.
+	enabled, err := a.twoFactorRepo.IsEnabled(user.Id_user)
+	if err != nil {
+		a.log.Error("Failed to check two-factor authentication: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+	if enabled {
+		return a.createChallenge(user.Id_user, payload.RememberMe, now)
+	}
+
 	a.log.Info("User has been authenticated successfully", nil)
-	token, err := a.jwtService.CreateToken(user)
+	return a.issueTokens(user, payload.Identifier, client, payload.RememberMe, now)
+}
+
+// VerifyTwoFactor exchanges the challenge of a login and a TOTP or backup code for the tokens. A challenge allows
+// maxTwoFactorAttempts codes, after that the user has to login again.
+func (a *authUseCase) VerifyTwoFactor(payload dto.TwoFactorVerifyRequestDto, client entity.ClientInfo) (dto.AuthResponseDto, error) {
+	a.log.Info("Starting to verify a two-factor code in the use case layer", nil)
+
+	now := time.Now()
+	challengeHash := hashToken(payload.ChallengeToken)
+	userId, rememberMe, err := a.twoFactorRepo.UseChallenge(challengeHash, maxTwoFactorAttempts)
 	if err != nil {
-		a.log.Error("Failed to create token: ", err)
+		a.log.Error("Failed to use the login challenge: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	user, err := a.useCase.GetUserByID(userId)
+	if err != nil {
+		a.log.Error("Failed to retrieve the user of the login challenge: ", err)
+		return dto.AuthResponseDto{}, repository.ErrInvalidChallenge
+	}
+	if !user.Active {
+		return dto.AuthResponseDto{}, ErrUserDeactivated
+	}
+
+	ok, err := verifySecondFactor(a.twoFactorRepo, userId, payload.Code, now)
+	if err != nil {
+		a.log.Error("Failed to verify the two-factor code: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+	if !ok {
+		a.log.Error("Failed to verify the two-factor code: ", ErrInvalidTwoFactorCode)
+		recordAuthEvent(a.eventRepo, a.log, entity.AuthEventLoginFailure, userId, user.Username, client)
+		return dto.AuthResponseDto{}, ErrInvalidTwoFactorCode
+	}
+
+	if err := a.twoFactorRepo.DeleteChallenge(challengeHash); err != nil {
+		a.log.Error("Failed to delete the login challenge: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	a.log.Info("User has been authenticated successfully", nil)
+	return a.issueTokens(user, user.Username, client, rememberMe, now)
+}
+
+func (a *authUseCase) createChallenge(userId string, rememberMe bool, now time.Time) (dto.AuthResponseDto, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		a.log.Error("Failed to generate the login challenge: ", err)
+		return dto.AuthResponseDto{}, fmt.Errorf("failed to generate login challenge: %v", err)
+	}
+	challenge := hex.EncodeToString(raw)
+
+	if err := a.twoFactorRepo.CreateChallenge(userId, hashToken(challenge), now.Add(twoFactorChallengeTTL), rememberMe); err != nil {
+		a.log.Error("Failed to store the login challenge: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	a.log.Info("Two-factor code required for user", userId)
+	return dto.AuthResponseDto{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+}
+
+// issueTokens starts a session for the authenticated user, a remembered session keeps the longer token lifetimes on
+// every refresh.
+func (a *authUseCase) issueTokens(user entity.User, identifier string, client entity.ClientInfo, rememberMe bool, now time.Time) (dto.AuthResponseDto, error) {
+	sessionId, err := a.sessionRepo.Create(user.Id_user, client, rememberMe)
+	if err != nil {
+		a.log.Error("Failed to create the session: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	token, err := a.createToken(user, sessionId, rememberMe)
+	if err != nil {
+		return dto.AuthResponseDto{}, err
+	}
+
+	refreshToken, expiresAt, err := a.jwtService.CreateRefreshToken(rememberMe)
+	if err != nil {
+		a.log.Error("Failed to create refresh token: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	if err := a.refreshRepo.Create(user.Id_user, sessionId, hashToken(refreshToken), expiresAt); err != nil {
+		a.log.Error("Failed to store refresh token: ", err)
 		return dto.AuthResponseDto{}, err
 	}
 
 	response := dto.AuthResponseDto{
-		Token: token.Token,
+		Token:              token.Token,
+		RefreshToken:       refreshToken,
+		MustChangePassword: user.MustChangePassword,
 	}
 
+	// the write is not waited for, a slow or failing update must not delay the login
+	go a.useCase.RecordLogin(user.Id_user, now)
+	recordAuthEvent(a.eventRepo, a.log, entity.AuthEventLoginSuccess, user.Id_user, identifier, client)
 	a.log.Info("User ID %s has been authenticated successfully", user.Id_user)
 	return response, nil
 }
 
+// accountName is the normalized username the failures of identifier count on. An email of an account is resolved
+// to its username, an unknown email is counted on itself.
+func (a *authUseCase) accountName(identifier string) string {
+	if a.maxFailures <= 0 || !strings.Contains(identifier, "@") {
+		return identifier
+	}
+
+	user, err := a.useCase.GetUserByEmail(identifier)
+	if err != nil {
+		return identifier
+	}
+	return normalizeUsername(user.Username)
+}
+
+// lockedFor returns how long the most restrictive of keys stays locked, zero when none is. A failing attempt store
+// does not lock anyone out.
+func (a *authUseCase) lockedFor(keys []string, now time.Time) time.Duration {
+	if a.maxFailures <= 0 {
+		return 0
+	}
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		failures, err := a.attemptRepo.Failures(key, now.Add(-a.window))
+		if err != nil {
+			a.log.Error("Failed to read the failed login attempts: ", err)
+			continue
+		}
+		if len(failures) < a.maxFailures {
+			continue
+		}
+
+		// the key unlocks once enough failures have left the window to drop below maxFailures
+		if until := failures[len(failures)-a.maxFailures].Add(a.window).Sub(now); until > retryAfter {
+			retryAfter = until
+		}
+	}
+	return retryAfter
+}
+
+func (a *authUseCase) recordFailure(keys []string, now time.Time, userId, identifier string, client entity.ClientInfo) {
+	if a.maxFailures <= 0 {
+		return
+	}
+
+	for _, key := range keys {
+		if err := a.attemptRepo.RecordFailure(key, now); err != nil {
+			a.log.Error("Failed to record the failed login attempt: ", err)
+		}
+	}
+
+	if a.lockedFor(keys, now) > 0 {
+		a.log.Error("Login locked out after too many failed attempts: ", map[string]string{"identifier": identifier, "ip": client.Ip})
+		recordAuthEvent(a.eventRepo, a.log, entity.AuthEventLockout, userId, identifier, client)
+	}
+}
+
+// Refresh exchanges a refresh token for a new access token and rotates the refresh token, the old one stops working.
+func (a *authUseCase) Refresh(refreshToken string) (dto.AuthResponseDto, error) {
+	a.log.Info("Starting to refresh a token in the use case layer", nil)
+
+	rememberMe, err := a.refreshRepo.IsRememberMe(hashToken(refreshToken))
+	if err != nil {
+		a.log.Error("Failed to check the remember me of the refresh token: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	newRefreshToken, expiresAt, err := a.jwtService.CreateRefreshToken(rememberMe)
+	if err != nil {
+		a.log.Error("Failed to create refresh token: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	userId, sessionId, err := a.refreshRepo.Rotate(hashToken(refreshToken), hashToken(newRefreshToken), expiresAt)
+	if err != nil {
+		a.log.Error("Failed to rotate refresh token: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	// the role and merchants are read again so changes apply from the next access token, deleted users cannot refresh
+	user, err := a.useCase.GetUserByID(userId)
+	if err != nil {
+		a.log.Error("Failed to retrieve the user of the refresh token: ", err)
+		return dto.AuthResponseDto{}, repository.ErrInvalidRefreshToken
+	}
+
+	token, err := a.createToken(user, sessionId, rememberMe)
+	if err != nil {
+		return dto.AuthResponseDto{}, err
+	}
+
+	a.log.Info("Token has been refreshed successfully", user.Id_user)
+	return dto.AuthResponseDto{Token: token.Token, RefreshToken: newRefreshToken, MustChangePassword: user.MustChangePassword}, nil
+}
+
+// createToken issues the access token of the session with the merchants the user owns and the permissions of the
+// role right now, so handlers can authorize from the token alone.
+func (a *authUseCase) createToken(user entity.User, sessionId string, rememberMe bool) (dto.AuthResponseDto, error) {
+	merchantIds, err := a.useCase.FindMerchantIds(user.Id_user)
+	if err != nil {
+		a.log.Error("Failed to retrieve the merchants for the token: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	permissions, err := a.permissionRepo.ListByRole(user.Role)
+	if err != nil {
+		a.log.Error("Failed to retrieve the permissions for the token: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+
+	token, err := a.jwtService.CreateToken(user, merchantIds, sessionId, permissions, rememberMe)
+	if err != nil {
+		a.log.Error("Failed to create token: ", err)
+		return dto.AuthResponseDto{}, err
+	}
+	return token, nil
+}
+
 func (a *authUseCase) Register(payload dto.AuthRequestDto) (entity.User, error) {
 	a.log.Info("Starting to register a new user in the use case layer", nil)
-	return a.useCase.RegisterUser(entity.User{Username: payload.Username, Password: payload.Password})
+	return a.useCase.RegisterUser(entity.User{Username: payload.Username, Password: payload.Password, Email: payload.Email})
+}
+
+// Logout revokes the access token until it expires. The refresh token is optional, when given its whole family is
+// revoked too so the session cannot be continued through /auth/refresh.
+func (a *authUseCase) Logout(userId, jti string, expiresAt time.Time, refreshToken string, client entity.ClientInfo) error {
+	a.log.Info("Starting to logout in the use case layer", nil)
+
+	if err := a.revokedRepo.Revoke(jti, expiresAt); err != nil {
+		a.log.Error("Failed to revoke token: ", err)
+		return fmt.Errorf("failed to logout: %v", err)
+	}
+
+	if refreshToken != "" {
+		if err := a.refreshRepo.RevokeFamily(hashToken(refreshToken)); err != nil {
+			a.log.Error("Failed to revoke refresh token: ", err)
+			return fmt.Errorf("failed to logout: %v", err)
+		}
+	}
+
+	recordAuthEvent(a.eventRepo, a.log, entity.AuthEventLogout, userId, "", client)
+	a.log.Info("User has been logged out successfully", jti)
+	return nil
 }
 
-func NewAuthUseCase(uc UserUsecase, jwtService service.JwtService, log *logger.Logger) AuthUseCase {
-	return &authUseCase{useCase: uc, jwtService: jwtService, log: log}
+func NewAuthUseCase(uc UserUsecase, jwtService service.JwtService, refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository,
+	twoFactorRepo repository.TwoFactorRepository, permissionRepo repository.RolePermissionRepository, revokedRepo repository.RevokedTokenRepository,
+	attemptRepo repository.LoginAttemptRepository, eventRepo repository.AuthEventRepository, maxFailures int, window time.Duration, log *logger.Logger) AuthUseCase {
+	return &authUseCase{useCase: uc, jwtService: jwtService, refreshRepo: refreshRepo, sessionRepo: sessionRepo, twoFactorRepo: twoFactorRepo,
+		permissionRepo: permissionRepo, revokedRepo: revokedRepo, attemptRepo: attemptRepo, eventRepo: eventRepo, maxFailures: maxFailures, window: window, log: log}
 }
@@ -1,45 +1,189 @@
 package usecase
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/entity/dto"
 	"server-pulsa-app/internal/logger"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
 	"server-pulsa-app/internal/mock/service_mock"
 	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
 type AuthUseCaseTestSuite struct {
 	suite.Suite
-	authUC          AuthUseCase
-	mockUserUsecase *usecase_mock.UserUseCaseMock
-	mockJwtService  *service_mock.JwtServiceMock
-	log             logger.Logger
+	authUC             AuthUseCase
+	mockUserUsecase    *usecase_mock.UserUseCaseMock
+	mockJwtService     *service_mock.JwtServiceMock
+	mockRefreshRepo    *repositorymock.MockRefreshTokenRepository
+	mockSessionRepo    *repositorymock.MockSessionRepository
+	mockTwoFactorRepo  *repositorymock.MockTwoFactorRepository
+	mockPermissionRepo *repositorymock.MockRolePermissionRepository
+	mockRevokedRepo    *repositorymock.MockRevokedTokenRepository
+	mockEventRepo      *repositorymock.MockAuthEventRepository
+	log                logger.Logger
 }
 
 func (suite *AuthUseCaseTestSuite) SetupTest() {
 	suite.mockUserUsecase = new(usecase_mock.UserUseCaseMock)
 	suite.mockJwtService = new(service_mock.JwtServiceMock)
+	suite.mockRefreshRepo = new(repositorymock.MockRefreshTokenRepository)
+	suite.mockRefreshRepo.On("IsRememberMe", mock.Anything).Return(false, nil).Maybe()
+	suite.mockSessionRepo = new(repositorymock.MockSessionRepository)
+	suite.mockSessionRepo.On("Create", "uuid-user", mock.Anything, mock.Anything).Return("uuid-session", nil).Maybe()
+	suite.mockTwoFactorRepo = new(repositorymock.MockTwoFactorRepository)
+	suite.mockPermissionRepo = new(repositorymock.MockRolePermissionRepository)
+	suite.mockPermissionRepo.On("ListByRole", mock.Anything).Return([]string{}, nil).Maybe()
+	suite.mockTwoFactorRepo.On("IsEnabled", "uuid-user").Return(false, nil).Maybe()
+	suite.mockRevokedRepo = new(repositorymock.MockRevokedTokenRepository)
+	suite.mockEventRepo = new(repositorymock.MockAuthEventRepository)
+	suite.mockEventRepo.On("Record", mock.Anything).Return(nil).Maybe()
+	suite.mockUserUsecase.On("FindMerchantIds", "uuid-user").Return([]string{"uuid-merchant"}, nil).Maybe()
+	suite.mockUserUsecase.On("RecordLogin", mock.Anything, mock.Anything).Maybe()
 	suite.log = logger.NewLogger()
-	suite.authUC = NewAuthUseCase(suite.mockUserUsecase, suite.mockJwtService, &suite.log)
+	suite.authUC = NewAuthUseCase(suite.mockUserUsecase, suite.mockJwtService, suite.mockRefreshRepo, suite.mockSessionRepo, suite.mockTwoFactorRepo, suite.mockPermissionRepo, suite.mockRevokedRepo,
+		repository.NewMemoryLoginAttemptRepository(15*time.Minute), suite.mockEventRepo, 3, 15*time.Minute, &suite.log)
 }
 
 func (suite *AuthUseCaseTestSuite) TestLogin() {
-	user := entity.User{Username: "testuser", Password: "password"}
+	user := entity.User{Id_user: "uuid-user", Username: "testuser", Password: "password"}
+	expiresAt := time.Now().Add(time.Hour)
 	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "password").Return(user, nil)
-	suite.mockJwtService.On("CreateToken", user).Return(dto.AuthResponseDto{Token: "mockToken"}, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", []string{}, false).Return(dto.AuthResponseDto{Token: "mockToken"}, nil)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Create", "uuid-user", "uuid-session", hashToken("refresh-token"), expiresAt).Return(nil)
 
-	response, err := suite.authUC.Login(dto.AuthRequestDto{Username: "testuser", Password: "password"})
+	response, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.1"})
 
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), "mockToken", response.Token)
+	assert.Equal(suite.T(), "refresh-token", response.RefreshToken)
 
 	suite.mockUserUsecase.AssertExpectations(suite.T())
 	suite.mockJwtService.AssertExpectations(suite.T())
+	suite.mockRefreshRepo.AssertExpectations(suite.T())
+	suite.mockSessionRepo.AssertCalled(suite.T(), "Create", "uuid-user", entity.ClientInfo{Ip: "10.0.0.1"}, false)
+	suite.mockEventRepo.AssertCalled(suite.T(), "Record", entity.AuthEvent{UserId: "uuid-user", Event: entity.AuthEventLoginSuccess,
+		Identifier: "testuser", Ip: "10.0.0.1"})
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_PutsRolePermissionsInToken() {
+	user := entity.User{Id_user: "uuid-user", Username: "admin", Password: "password", Role: "admin"}
+	expiresAt := time.Now().Add(time.Hour)
+	permissions := []string{entity.PermissionProductWrite, entity.PermissionUserRead}
+	suite.mockPermissionRepo.ExpectedCalls = nil
+	suite.mockPermissionRepo.On("ListByRole", "admin").Return(permissions, nil)
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "admin", "password").Return(user, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", permissions, false).Return(dto.AuthResponseDto{Token: "mockToken"}, nil)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Create", "uuid-user", "uuid-session", hashToken("refresh-token"), expiresAt).Return(nil)
+
+	_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "admin", Password: "password"}, entity.ClientInfo{})
+
+	assert.NoError(suite.T(), err)
+	suite.mockPermissionRepo.AssertExpectations(suite.T())
+	suite.mockJwtService.AssertExpectations(suite.T())
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_ByEmail() {
+	user := entity.User{Id_user: "uuid-user", Username: "testuser", Email: "test@example.com"}
+	expiresAt := time.Now().Add(time.Hour)
+	// the identifier is lowercased before the lookup
+	suite.mockUserUsecase.On("GetUserByEmail", "test@example.com").Return(user, nil)
+	suite.mockUserUsecase.On("FindUserByEmailPassword", "test@example.com", "password").Return(user, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", []string{}, false).Return(dto.AuthResponseDto{Token: "mockToken"}, nil)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Create", "uuid-user", "uuid-session", hashToken("refresh-token"), expiresAt).Return(nil)
+
+	response, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "Test@example.com", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.1"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "mockToken", response.Token)
+	suite.mockUserUsecase.AssertNotCalled(suite.T(), "FindUserByUsernamePassword", "test@example.com", "password")
+}
+
+func (suite *AuthUseCaseTestSuite) TestRefresh_RotatesToken() {
+	user := entity.User{Id_user: "uuid-user", Username: "testuser", Role: "employee"}
+	expiresAt := time.Now().Add(time.Hour)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("new-refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Rotate", hashToken("refresh-token"), hashToken("new-refresh-token"), expiresAt).Return("uuid-user", "uuid-session", nil)
+	suite.mockUserUsecase.On("GetUserByID", "uuid-user").Return(user, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", []string{}, false).Return(dto.AuthResponseDto{Token: "new-access-token"}, nil)
+
+	response, err := suite.authUC.Refresh("refresh-token")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "new-access-token", response.Token)
+	assert.Equal(suite.T(), "new-refresh-token", response.RefreshToken)
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_RememberMe() {
+	user := entity.User{Id_user: "uuid-user", Username: "testuser", Password: "password"}
+	expiresAt := time.Now().Add(30 * 24 * time.Hour)
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "password").Return(user, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", []string{}, true).Return(dto.AuthResponseDto{Token: "mockToken"}, nil)
+	suite.mockJwtService.On("CreateRefreshToken", true).Return("refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Create", "uuid-user", "uuid-session", hashToken("refresh-token"), expiresAt).Return(nil)
+
+	_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "password", RememberMe: true}, entity.ClientInfo{})
+
+	assert.NoError(suite.T(), err)
+	suite.mockJwtService.AssertExpectations(suite.T())
+	suite.mockSessionRepo.AssertCalled(suite.T(), "Create", "uuid-user", entity.ClientInfo{}, true)
+}
+
+func (suite *AuthUseCaseTestSuite) TestRefresh_KeepsRememberMe() {
+	user := entity.User{Id_user: "uuid-user", Username: "testuser", Role: "employee"}
+	expiresAt := time.Now().Add(30 * 24 * time.Hour)
+	suite.mockRefreshRepo.ExpectedCalls = nil
+	suite.mockRefreshRepo.On("IsRememberMe", hashToken("refresh-token")).Return(true, nil)
+	suite.mockJwtService.On("CreateRefreshToken", true).Return("new-refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Rotate", hashToken("refresh-token"), hashToken("new-refresh-token"), expiresAt).Return("uuid-user", "uuid-session", nil)
+	suite.mockUserUsecase.On("GetUserByID", "uuid-user").Return(user, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", []string{}, true).Return(dto.AuthResponseDto{Token: "new-access-token"}, nil)
+
+	response, err := suite.authUC.Refresh("refresh-token")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "new-access-token", response.Token)
+	suite.mockRefreshRepo.AssertExpectations(suite.T())
+	suite.mockJwtService.AssertExpectations(suite.T())
+}
+
+func (suite *AuthUseCaseTestSuite) TestRefresh_RebuildsMerchantClaims() {
+	user := entity.User{Id_user: "uuid-reassigned", Username: "testuser", Role: "employee"}
+	expiresAt := time.Now().Add(time.Hour)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("new-refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Rotate", hashToken("refresh-token"), hashToken("new-refresh-token"), expiresAt).Return("uuid-reassigned", "uuid-session", nil)
+	suite.mockUserUsecase.On("GetUserByID", "uuid-reassigned").Return(user, nil)
+	// the merchant was assigned after the last login, the new access token carries it
+	suite.mockUserUsecase.On("FindMerchantIds", "uuid-reassigned").Return([]string{"uuid-new-merchant"}, nil).Once()
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-new-merchant"}, "uuid-session", []string{}, false).Return(dto.AuthResponseDto{Token: "new-access-token"}, nil)
+
+	response, err := suite.authUC.Refresh("refresh-token")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "new-access-token", response.Token)
+	suite.mockUserUsecase.AssertExpectations(suite.T())
+}
+
+func (suite *AuthUseCaseTestSuite) TestRefresh_ReusedToken() {
+	expiresAt := time.Now().Add(time.Hour)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("new-refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Rotate", hashToken("refresh-token"), hashToken("new-refresh-token"), expiresAt).Return("", "", repository.ErrRefreshTokenReused)
+
+	_, err := suite.authUC.Refresh("refresh-token")
+
+	assert.ErrorIs(suite.T(), err, repository.ErrRefreshTokenReused)
+	suite.mockJwtService.AssertNotCalled(suite.T(), "CreateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func (suite *AuthUseCaseTestSuite) TestRegister() {
@@ -54,6 +198,265 @@ func (suite *AuthUseCaseTestSuite) TestRegister() {
 	suite.mockUserUsecase.AssertExpectations(suite.T())
 }
 
+func (suite *AuthUseCaseTestSuite) TestLogout_RevokesAccessAndRefreshToken() {
+	expiresAt := time.Now().Add(time.Hour)
+	suite.mockRevokedRepo.On("Revoke", "token-jti", expiresAt).Return(nil)
+	suite.mockRefreshRepo.On("RevokeFamily", hashToken("refresh-token")).Return(nil)
+
+	err := suite.authUC.Logout("uuid-user", "token-jti", expiresAt, "refresh-token", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	assert.NoError(suite.T(), err)
+	suite.mockRevokedRepo.AssertExpectations(suite.T())
+	suite.mockRefreshRepo.AssertExpectations(suite.T())
+	suite.mockEventRepo.AssertCalled(suite.T(), "Record", entity.AuthEvent{UserId: "uuid-user", Event: entity.AuthEventLogout, Ip: "10.0.0.1"})
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogout_AccessTokenOnly() {
+	expiresAt := time.Now().Add(time.Hour)
+	suite.mockRevokedRepo.On("Revoke", "token-jti", expiresAt).Return(nil)
+
+	err := suite.authUC.Logout("uuid-user", "token-jti", expiresAt, "", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	assert.NoError(suite.T(), err)
+	suite.mockRefreshRepo.AssertNotCalled(suite.T(), "RevokeFamily")
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_LocksUsernameAfterFailures() {
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "wrong").Return(entity.User{}, fmt.Errorf("password doesn't match"))
+
+	// every attempt comes from another IP, only the username counter reaches the limit
+	for i := 0; i < 3; i++ {
+		_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "wrong"}, entity.ClientInfo{Ip: fmt.Sprintf("10.0.0.%d", i)})
+		assert.EqualError(suite.T(), err, "password doesn't match")
+	}
+
+	_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "TestUser", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.9"})
+
+	var locked *LoginLockedError
+	assert.ErrorAs(suite.T(), err, &locked)
+	assert.InDelta(suite.T(), (15 * time.Minute).Seconds(), locked.RetryAfter.Seconds(), 5)
+	suite.mockUserUsecase.AssertNumberOfCalls(suite.T(), "FindUserByUsernamePassword", 3)
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_UsernameAndEmailShareTheLockout() {
+	suite.mockUserUsecase.On("GetUserByEmail", "test@example.com").Return(entity.User{Id_user: "uuid-user", Username: "TestUser"}, nil)
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "wrong").Return(entity.User{}, fmt.Errorf("password doesn't match"))
+	suite.mockUserUsecase.On("FindUserByEmailPassword", "test@example.com", "wrong").Return(entity.User{}, fmt.Errorf("password doesn't match"))
+
+	// every attempt comes from another IP and alternates the identifier, together they reach the limit
+	for i, identifier := range []string{"testuser", "Test@example.com", "testuser"} {
+		_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: identifier, Password: "wrong"}, entity.ClientInfo{Ip: fmt.Sprintf("10.0.0.%d", i)})
+		assert.EqualError(suite.T(), err, "password doesn't match")
+	}
+
+	_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "test@example.com", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.9"})
+
+	var locked *LoginLockedError
+	assert.ErrorAs(suite.T(), err, &locked)
+	suite.mockUserUsecase.AssertNotCalled(suite.T(), "FindUserByEmailPassword", "test@example.com", "password")
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_UnknownEmailCountsOnItself() {
+	suite.mockUserUsecase.On("GetUserByEmail", "nobody@example.com").Return(entity.User{}, fmt.Errorf("user not found"))
+	suite.mockUserUsecase.On("FindUserByEmailPassword", "nobody@example.com", "wrong").Return(entity.User{}, fmt.Errorf("user doesn't exists"))
+
+	for i := 0; i < 3; i++ {
+		_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "nobody@example.com", Password: "wrong"}, entity.ClientInfo{Ip: fmt.Sprintf("10.0.0.%d", i)})
+		assert.EqualError(suite.T(), err, "user doesn't exists")
+	}
+
+	_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "nobody@example.com", Password: "wrong"}, entity.ClientInfo{Ip: "10.0.0.9"})
+
+	var locked *LoginLockedError
+	assert.ErrorAs(suite.T(), err, &locked)
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_LocksClientIpAfterFailures() {
+	for _, username := range []string{"a", "b", "c"} {
+		suite.mockUserUsecase.On("FindUserByUsernamePassword", username, "wrong").Return(entity.User{}, fmt.Errorf("user doesn't exists")).Once()
+		_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: username, Password: "wrong"}, entity.ClientInfo{Ip: "10.0.0.1"})
+		assert.Error(suite.T(), err)
+	}
+
+	_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "d", Password: "wrong"}, entity.ClientInfo{Ip: "10.0.0.1"})
+
+	var locked *LoginLockedError
+	assert.ErrorAs(suite.T(), err, &locked)
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_SuccessResetsUsernameFailures() {
+	user := entity.User{Id_user: "uuid-user", Username: "testuser"}
+	expiresAt := time.Now().Add(time.Hour)
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "wrong").Return(entity.User{}, fmt.Errorf("password doesn't match"))
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "password").Return(user, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", []string{}, false).Return(dto.AuthResponseDto{Token: "mockToken"}, nil)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Create", "uuid-user", "uuid-session", hashToken("refresh-token"), expiresAt).Return(nil)
+
+	for i := 0; i < 2; i++ {
+		_, _ = suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "wrong"}, entity.ClientInfo{Ip: fmt.Sprintf("10.0.0.%d", i)})
+	}
+	_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.5"})
+	assert.NoError(suite.T(), err)
+
+	// the counter starts over, two more failures do not reach the limit of three
+	for i := 0; i < 2; i++ {
+		_, err = suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "wrong"}, entity.ClientInfo{Ip: fmt.Sprintf("10.0.1.%d", i)})
+		assert.EqualError(suite.T(), err, "password doesn't match")
+	}
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_RecordsFailureAndLockout() {
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "wrong").
+		Return(entity.User{Id_user: "uuid-user"}, fmt.Errorf("password doesn't match"))
+	client := entity.ClientInfo{Ip: "10.0.0.1", UserAgent: "curl/8.0"}
+
+	for i := 0; i < 3; i++ {
+		_, _ = suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "wrong"}, client)
+	}
+	_, _ = suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "wrong"}, client)
+
+	failure := entity.AuthEvent{UserId: "uuid-user", Event: entity.AuthEventLoginFailure, Identifier: "testuser", Ip: "10.0.0.1", UserAgent: "curl/8.0"}
+	suite.mockEventRepo.AssertNumberOfCalls(suite.T(), "Record", 5)
+	suite.mockEventRepo.AssertCalled(suite.T(), "Record", failure)
+	suite.mockEventRepo.AssertCalled(suite.T(), "Record", entity.AuthEvent{UserId: "uuid-user", Event: entity.AuthEventLockout,
+		Identifier: "testuser", Ip: "10.0.0.1", UserAgent: "curl/8.0"})
+	// the refused attempt is recorded too, the user is not looked up while locked
+	suite.mockEventRepo.AssertCalled(suite.T(), "Record", entity.AuthEvent{Event: entity.AuthEventLoginFailure,
+		Identifier: "testuser", Ip: "10.0.0.1", UserAgent: "curl/8.0"})
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_RecordsLastLogin() {
+	userUsecase := new(usecase_mock.UserUseCaseMock)
+	userUsecase.On("FindUserByUsernamePassword", "testuser", "password").Return(entity.User{Id_user: "uuid-user", Role: "employee"}, nil)
+	userUsecase.On("FindMerchantIds", "uuid-user").Return([]string{}, nil)
+	recorded := make(chan string, 1)
+	userUsecase.On("RecordLogin", "uuid-user", mock.AnythingOfType("time.Time")).Run(func(args mock.Arguments) {
+		recorded <- args.String(0)
+	}).Once()
+	suite.mockJwtService.On("CreateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, false).Return(dto.AuthResponseDto{Token: "token"}, nil)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("refresh", time.Now().Add(time.Hour), nil)
+	suite.mockRefreshRepo.On("Create", "uuid-user", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	authUC := NewAuthUseCase(userUsecase, suite.mockJwtService, suite.mockRefreshRepo, suite.mockSessionRepo, suite.mockTwoFactorRepo, suite.mockPermissionRepo, suite.mockRevokedRepo,
+		repository.NewMemoryLoginAttemptRepository(15*time.Minute), suite.mockEventRepo, 3, 15*time.Minute, &suite.log)
+
+	_, err := authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "password"}, entity.ClientInfo{})
+	assert.NoError(suite.T(), err)
+
+	select {
+	case id := <-recorded:
+		assert.Equal(suite.T(), "uuid-user", id)
+	case <-time.After(time.Second):
+		suite.T().Fatal("the last login was not recorded")
+	}
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_DeactivatedUserIsNotLockedOut() {
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "password").
+		Return(entity.User{Id_user: "uuid-user"}, ErrUserDeactivated)
+
+	for i := 0; i < 4; i++ {
+		_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.1"})
+		assert.ErrorIs(suite.T(), err, ErrUserDeactivated)
+	}
+	suite.mockEventRepo.AssertNotCalled(suite.T(), "Record", entity.AuthEvent{UserId: "uuid-user", Event: entity.AuthEventLockout,
+		Identifier: "testuser", Ip: "10.0.0.1"})
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_EventRepositoryErrorDoesNotFailLogin() {
+	eventRepo := new(repositorymock.MockAuthEventRepository)
+	eventRepo.On("Record", mock.Anything).Return(fmt.Errorf("db down"))
+	authUC := NewAuthUseCase(suite.mockUserUsecase, suite.mockJwtService, suite.mockRefreshRepo, suite.mockSessionRepo, suite.mockTwoFactorRepo, suite.mockPermissionRepo, suite.mockRevokedRepo,
+		repository.NewMemoryLoginAttemptRepository(15*time.Minute), eventRepo, 3, 15*time.Minute, &suite.log)
+	user := entity.User{Id_user: "uuid-user", Username: "testuser"}
+	expiresAt := time.Now().Add(time.Hour)
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "testuser", "password").Return(user, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", []string{}, false).Return(dto.AuthResponseDto{Token: "mockToken"}, nil)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Create", "uuid-user", "uuid-session", hashToken("refresh-token"), expiresAt).Return(nil)
+
+	response, err := authUC.Login(dto.LoginRequestDto{Identifier: "testuser", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.1"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "mockToken", response.Token)
+	eventRepo.AssertExpectations(suite.T())
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_TwoFactorReturnsChallenge() {
+	suite.mockTwoFactorRepo.ExpectedCalls = nil
+	suite.mockTwoFactorRepo.On("IsEnabled", "uuid-user").Return(true, nil)
+	suite.mockTwoFactorRepo.On("CreateChallenge", "uuid-user", mock.AnythingOfType("string"), mock.AnythingOfType("time.Time"), false).Return(nil)
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "admin", "password").Return(entity.User{Id_user: "uuid-user", Username: "admin"}, nil)
+
+	response, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "admin", Password: "password"}, entity.ClientInfo{Ip: "10.0.0.1"})
+
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), response.TwoFactorRequired)
+	assert.NotEmpty(suite.T(), response.ChallengeToken)
+	assert.Empty(suite.T(), response.Token)
+	assert.Empty(suite.T(), response.RefreshToken)
+	// only the hash of the challenge is stored
+	suite.mockTwoFactorRepo.AssertCalled(suite.T(), "CreateChallenge", "uuid-user", hashToken(response.ChallengeToken), mock.AnythingOfType("time.Time"), false)
+	suite.mockSessionRepo.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything)
+	suite.mockJwtService.AssertNotCalled(suite.T(), "CreateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthUseCaseTestSuite) TestLogin_TwoFactorCheckErrorRefusesLogin() {
+	suite.mockTwoFactorRepo.ExpectedCalls = nil
+	suite.mockTwoFactorRepo.On("IsEnabled", "uuid-user").Return(false, fmt.Errorf("db down"))
+	suite.mockUserUsecase.On("FindUserByUsernamePassword", "admin", "password").Return(entity.User{Id_user: "uuid-user", Username: "admin"}, nil)
+
+	_, err := suite.authUC.Login(dto.LoginRequestDto{Identifier: "admin", Password: "password"}, entity.ClientInfo{})
+
+	assert.Error(suite.T(), err)
+	suite.mockJwtService.AssertNotCalled(suite.T(), "CreateToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthUseCaseTestSuite) TestVerifyTwoFactor_BackupCodeIssuesTokens() {
+	user := entity.User{Id_user: "uuid-user", Username: "admin", Active: true}
+	expiresAt := time.Now().Add(time.Hour)
+	suite.mockTwoFactorRepo.On("UseChallenge", hashToken("challenge"), maxTwoFactorAttempts).Return("uuid-user", false, nil)
+	suite.mockTwoFactorRepo.On("Get", "uuid-user").Return(entity.TwoFactor{Secret: "JBSWY3DPEHPK3PXP", Enabled: true}, nil)
+	suite.mockTwoFactorRepo.On("UseBackupCode", "uuid-user", hashToken("abcde12345")).Return(true, nil)
+	suite.mockTwoFactorRepo.On("DeleteChallenge", hashToken("challenge")).Return(nil)
+	suite.mockUserUsecase.On("GetUserByID", "uuid-user").Return(user, nil)
+	suite.mockJwtService.On("CreateToken", user, []string{"uuid-merchant"}, "uuid-session", []string{}, false).Return(dto.AuthResponseDto{Token: "mockToken"}, nil)
+	suite.mockJwtService.On("CreateRefreshToken", false).Return("refresh-token", expiresAt, nil)
+	suite.mockRefreshRepo.On("Create", "uuid-user", "uuid-session", hashToken("refresh-token"), expiresAt).Return(nil)
+
+	response, err := suite.authUC.VerifyTwoFactor(dto.TwoFactorVerifyRequestDto{ChallengeToken: "challenge", Code: "ABCDE-12345"}, entity.ClientInfo{Ip: "10.0.0.1"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "mockToken", response.Token)
+	assert.Equal(suite.T(), "refresh-token", response.RefreshToken)
+	suite.mockTwoFactorRepo.AssertExpectations(suite.T())
+	suite.mockEventRepo.AssertCalled(suite.T(), "Record", entity.AuthEvent{UserId: "uuid-user", Event: entity.AuthEventLoginSuccess,
+		Identifier: "admin", Ip: "10.0.0.1"})
+}
+
+func (suite *AuthUseCaseTestSuite) TestVerifyTwoFactor_InvalidCode() {
+	suite.mockTwoFactorRepo.On("UseChallenge", hashToken("challenge"), maxTwoFactorAttempts).Return("uuid-user", false, nil)
+	suite.mockTwoFactorRepo.On("Get", "uuid-user").Return(entity.TwoFactor{Secret: "JBSWY3DPEHPK3PXP", Enabled: true}, nil)
+	suite.mockTwoFactorRepo.On("UseBackupCode", "uuid-user", hashToken("000000")).Return(false, nil)
+	suite.mockUserUsecase.On("GetUserByID", "uuid-user").Return(entity.User{Id_user: "uuid-user", Username: "admin", Active: true}, nil)
+
+	_, err := suite.authUC.VerifyTwoFactor(dto.TwoFactorVerifyRequestDto{ChallengeToken: "challenge", Code: "000000"}, entity.ClientInfo{})
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidTwoFactorCode)
+	// the challenge stays usable for the remaining attempts
+	suite.mockTwoFactorRepo.AssertNotCalled(suite.T(), "DeleteChallenge", mock.Anything)
+	suite.mockSessionRepo.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AuthUseCaseTestSuite) TestVerifyTwoFactor_InvalidChallenge() {
+	suite.mockTwoFactorRepo.On("UseChallenge", hashToken("expired"), maxTwoFactorAttempts).Return("", false, repository.ErrInvalidChallenge)
+
+	_, err := suite.authUC.VerifyTwoFactor(dto.TwoFactorVerifyRequestDto{ChallengeToken: "expired", Code: "123456"}, entity.ClientInfo{})
+
+	assert.ErrorIs(suite.T(), err, repository.ErrInvalidChallenge)
+	suite.mockTwoFactorRepo.AssertNotCalled(suite.T(), "Get", mock.Anything)
+}
+
 func TestAuthUseCaseTestSuite(t *testing.T) {
 	suite.Run(t, new(AuthUseCaseTestSuite))
 }
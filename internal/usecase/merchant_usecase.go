@@ -1,18 +1,34 @@
 package usecase
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/repository"
+	"slices"
+	"time"
+)
+
+var (
+	ErrMerchantNotFound     = errors.New("merchant not found")
+	ErrMerchantForbidden    = errors.New("you are not allowed to access this merchant")
+	ErrInvalidBalanceAdjust = errors.New("adjustments must contain at least one non zero amount")
+	ErrInvalidPriceOverride = errors.New("price override must be greater than zero")
 )
 
 type MerchantUseCase interface {
 	RegisterNewMerchant(payload entity.Merchant) (entity.Merchant, error)
 	FindAllMerchant() ([]entity.Merchant, error)
 	FindMerchantByID(id string) (entity.Merchant, error)
-	UpdateMerchant(payload entity.Merchant) (entity.Merchant, error)
+	UpdateMerchant(id string, payload entity.MerchantUpdateRequest) (entity.Merchant, error)
 	DeleteMerchant(id string) error
+	FindMerchantBalance(id string, merchantIds []string, role string) (entity.MerchantBalance, error)
+	AdjustMerchantBalances(adjustments map[string]float64, actorId string) error
+	SetMerchantProductPrice(price entity.MerchantProductPrice) error
+	ClearMerchantProductPrice(merchantId, productId string) error
+	FindMerchantCatalog(id string, merchantIds []string, role string) ([]entity.MerchantCatalogItem, error)
 }
 
 type merchantUseCase struct {
@@ -35,39 +51,120 @@ func (m *merchantUseCase) FindMerchantByID(id string) (entity.Merchant, error) {
 	return m.repo.Get(id)
 }
 
-func (m *merchantUseCase) UpdateMerchant(payload entity.Merchant) (entity.Merchant, error) {
+func (m *merchantUseCase) UpdateMerchant(id string, payload entity.MerchantUpdateRequest) (entity.Merchant, error) {
 	m.log.Info("Starting to retrive a merchant by id in the usecase layer", nil)
 
-	merchant, err := m.repo.Get(payload.IdMerchant)
+	merchant, err := m.repo.Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.log.Error("Merchant ID %s not found: ", id)
+		return entity.Merchant{}, ErrMerchantNotFound
+	}
 	if err != nil {
-		m.log.Error("Merchant ID %s not found: ", payload.IdMerchant)
-		return entity.Merchant{}, fmt.Errorf("merchant ID of \\%s\\ not found", payload.IdMerchant)
+		m.log.Error("Failed to retrieve the merchant to update: ", err)
+		return entity.Merchant{}, err
 	}
 
 	m.log.Info("Starting to update merchant in the usecase layer", nil)
 	_, err = m.repo.Update(merchant, payload)
 	if err != nil {
 		m.log.Error("Failed to update the merchant: ", err)
-		return entity.Merchant{}, fmt.Errorf("merchant ID of \\%s\\ not updated", payload.IdMerchant)
+		return entity.Merchant{}, fmt.Errorf("merchant ID of \\%s\\ not updated", id)
 	}
 
-	m.log.Info("Merchant ID %s has been updated successfully: ", payload.IdMerchant)
-	return m.repo.Get(payload.IdMerchant)
+	m.log.Info("Merchant ID %s has been updated successfully: ", id)
+	return m.repo.Get(id)
 }
 
 func (m *merchantUseCase) DeleteMerchant(id string) error {
 	m.log.Info("Starting to retrive a merchant by id in the usecase layer", nil)
 
 	_, err := m.repo.Get(id)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
 		m.log.Error("Merchant ID %s not found: %v", id)
-		return fmt.Errorf("merchant ID of \\%s\\ not found", id)
+		return ErrMerchantNotFound
+	}
+	if err != nil {
+		m.log.Error("Failed to retrieve the merchant to delete: ", err)
+		return err
 	}
 
 	m.log.Info("Merchant has been deleted successfully: ", id)
 	return m.repo.Delete(id)
 }
 
+// checkMerchantOwner lets admins through and only allows other roles to access the merchants they own, merchantIds
+// are the ones of the access token.
+func (m *merchantUseCase) checkMerchantOwner(id string, merchantIds []string, role string) error {
+	if role == "admin" {
+		return nil
+	}
+
+	if !slices.Contains(merchantIds, id) {
+		m.log.Error("User is not the owner of the merchant: ", id)
+		return ErrMerchantForbidden
+	}
+	return nil
+}
+
+func (m *merchantUseCase) FindMerchantBalance(id string, merchantIds []string, role string) (entity.MerchantBalance, error) {
+	m.log.Info("Starting to retrive a merchant balance in the usecase layer", nil)
+
+	if err := m.checkMerchantOwner(id, merchantIds, role); err != nil {
+		return entity.MerchantBalance{}, err
+	}
+
+	balance, err := m.repo.GetBalance(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.log.Error("Merchant ID %s not found: ", id)
+		return entity.MerchantBalance{}, ErrMerchantNotFound
+	}
+	if err != nil {
+		m.log.Error("Failed to retrive the merchant balance: ", err)
+		return entity.MerchantBalance{}, err
+	}
+
+	m.log.Info("Merchant balance has been retrieved successfully: ", id)
+	return entity.MerchantBalance{IdMerchant: id, Balance: balance, CheckedAt: time.Now()}, nil
+}
+
+func (m *merchantUseCase) AdjustMerchantBalances(adjustments map[string]float64, actorId string) error {
+	m.log.Info("Starting to adjust merchant balances in the usecase layer", nil)
+
+	if len(adjustments) == 0 {
+		return ErrInvalidBalanceAdjust
+	}
+	for _, amount := range adjustments {
+		if amount == 0 {
+			return ErrInvalidBalanceAdjust
+		}
+	}
+
+	return m.repo.AdjustBalances(adjustments, actorId)
+}
+
+func (m *merchantUseCase) SetMerchantProductPrice(price entity.MerchantProductPrice) error {
+	m.log.Info("Starting to set a merchant product price in the usecase layer", nil)
+
+	if price.Price <= 0 {
+		return ErrInvalidPriceOverride
+	}
+	return m.repo.SetProductPrice(price)
+}
+
+func (m *merchantUseCase) ClearMerchantProductPrice(merchantId, productId string) error {
+	m.log.Info("Starting to clear a merchant product price in the usecase layer", nil)
+	return m.repo.ClearProductPrice(merchantId, productId)
+}
+
+func (m *merchantUseCase) FindMerchantCatalog(id string, merchantIds []string, role string) ([]entity.MerchantCatalogItem, error) {
+	m.log.Info("Starting to retrive the merchant catalog in the usecase layer", nil)
+
+	if err := m.checkMerchantOwner(id, merchantIds, role); err != nil {
+		return nil, err
+	}
+	return m.repo.ListCatalog(id)
+}
+
 func NewMerchantUseCase(repo repository.MerchantRepository, log *logger.Logger) MerchantUseCase {
 	return &merchantUseCase{repo: repo, log: log}
 }
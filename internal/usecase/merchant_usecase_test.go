@@ -1,7 +1,9 @@
 package usecase
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"testing"
 
 	"server-pulsa-app/internal/entity"
@@ -99,10 +101,12 @@ func (m *merchantUsecaseSuite) TestUpdateMerchant_success() {
 		Balance:      10000,
 	}
 
+	payload := entity.MerchantUpdateRequest{NameMerchant: "name-merchant-test"}
+
 	m.merchantRepo.On("Get", merchant.IdMerchant).Return(merchant, nil)
-	m.merchantRepo.On("Update", merchant, merchant).Return(merchant, nil)
+	m.merchantRepo.On("Update", merchant, payload).Return(merchant, nil)
 
-	result, err := m.merchantUsecase.UpdateMerchant(merchant)
+	result, err := m.merchantUsecase.UpdateMerchant(merchant.IdMerchant, payload)
 	m.NoError(err)
 	m.Equal(merchant.IdMerchant, result.IdMerchant)
 }
@@ -117,14 +121,22 @@ func (m *merchantUsecaseSuite) TestUpdateMerchant_failed() {
 		Balance:      10000,
 	}
 
-	m.merchantRepo.On("Get", merchant.IdMerchant).Return(entity.Merchant{}, errors.New("merchant ID of \\uuid-merchant-test\\ not found"))
+	m.merchantRepo.On("Get", merchant.IdMerchant).Return(entity.Merchant{}, fmt.Errorf("retrieve the merchant: %w", sql.ErrNoRows))
 
-	result, err := m.merchantUsecase.UpdateMerchant(merchant)
-	m.Error(err)
-	m.EqualError(err, "merchant ID of \\uuid-merchant-test\\ not found")
+	result, err := m.merchantUsecase.UpdateMerchant(merchant.IdMerchant, entity.MerchantUpdateRequest{NameMerchant: "name-merchant-test"})
+	m.ErrorIs(err, ErrMerchantNotFound)
 	m.Equal(entity.Merchant{}, result)
 }
 
+func (m *merchantUsecaseSuite) TestUpdateMerchant_getFailed() {
+	dbErr := errors.New("connection refused")
+	m.merchantRepo.On("Get", "uuid-merchant-test").Return(entity.Merchant{}, dbErr)
+
+	_, err := m.merchantUsecase.UpdateMerchant("uuid-merchant-test", entity.MerchantUpdateRequest{})
+	m.ErrorIs(err, dbErr)
+	m.NotErrorIs(err, ErrMerchantNotFound)
+}
+
 func (m *merchantUsecaseSuite) TestDeleteMerchant_success() {
 	merchant := entity.Merchant{
 		IdMerchant:   "uuid-merchant-test",
@@ -147,9 +159,85 @@ func (m *merchantUsecaseSuite) TestDeleteMerchant_failed() {
 		IdMerchant: "uuid-merchant-test",
 	}
 
-	m.merchantRepo.On("Get", merchant.IdMerchant).Return(entity.Merchant{}, errors.New("merchant not found"))
+	m.merchantRepo.On("Get", merchant.IdMerchant).Return(entity.Merchant{}, fmt.Errorf("retrieve the merchant: %w", sql.ErrNoRows))
 
 	err := m.merchantUsecase.DeleteMerchant(merchant.IdMerchant)
-	m.Error(err)
-	m.EqualError(err, "merchant ID of \\uuid-merchant-test\\ not found")
+	m.ErrorIs(err, ErrMerchantNotFound)
+}
+
+func (m *merchantUsecaseSuite) TestFindMerchantBalance_owner() {
+	merchant := entity.Merchant{
+		IdMerchant: "uuid-merchant-test",
+		IdUser:     "uuid-user-test",
+		Balance:    10000,
+	}
+
+	m.merchantRepo.On("GetBalance", merchant.IdMerchant).Return(merchant.Balance, nil)
+
+	result, err := m.merchantUsecase.FindMerchantBalance(merchant.IdMerchant, []string{"uuid-other-merchant", merchant.IdMerchant}, "employee")
+	m.NoError(err)
+	m.Equal(merchant.Balance, result.Balance)
+	m.False(result.CheckedAt.IsZero())
+	// ownership comes from the token, the merchant is not looked up
+	m.merchantRepo.AssertNotCalled(m.T(), "Get", merchant.IdMerchant)
+}
+
+func (m *merchantUsecaseSuite) TestFindMerchantBalance_admin() {
+	m.merchantRepo.On("GetBalance", "uuid-merchant-test").Return(float64(10000), nil)
+
+	result, err := m.merchantUsecase.FindMerchantBalance("uuid-merchant-test", nil, "admin")
+	m.NoError(err)
+	m.Equal(float64(10000), result.Balance)
+	m.merchantRepo.AssertNotCalled(m.T(), "Get", "uuid-merchant-test")
+}
+
+func (m *merchantUsecaseSuite) TestFindMerchantBalance_forbidden() {
+	merchant := entity.Merchant{
+		IdMerchant: "uuid-merchant-test",
+		IdUser:     "uuid-user-test",
+	}
+
+	_, err := m.merchantUsecase.FindMerchantBalance(merchant.IdMerchant, []string{"uuid-other-merchant"}, "employee")
+	m.ErrorIs(err, ErrMerchantForbidden)
+	m.merchantRepo.AssertNotCalled(m.T(), "GetBalance", merchant.IdMerchant)
+}
+
+func (m *merchantUsecaseSuite) TestSetMerchantProductPrice_invalidPrice() {
+	price := entity.MerchantProductPrice{IdMerchant: "uuid-merchant-test", IdProduct: "uuid-product-test", Price: -1}
+
+	err := m.merchantUsecase.SetMerchantProductPrice(price)
+	m.ErrorIs(err, ErrInvalidPriceOverride)
+	m.merchantRepo.AssertNotCalled(m.T(), "SetProductPrice", price)
+}
+
+func (m *merchantUsecaseSuite) TestFindMerchantCatalog_forbidden() {
+	_, err := m.merchantUsecase.FindMerchantCatalog("uuid-merchant-test", nil, "employee")
+	m.ErrorIs(err, ErrMerchantForbidden)
+	m.merchantRepo.AssertNotCalled(m.T(), "ListCatalog", "uuid-merchant-test")
+}
+
+func (m *merchantUsecaseSuite) TestFindMerchantCatalog_admin() {
+	items := []entity.MerchantCatalogItem{{IdProduct: "uuid-product-test", Price: 10500, PriceSource: entity.PriceSourceMerchant}}
+	m.merchantRepo.On("ListCatalog", "uuid-merchant-test").Return(items, nil)
+
+	result, err := m.merchantUsecase.FindMerchantCatalog("uuid-merchant-test", nil, "admin")
+	m.NoError(err)
+	m.Equal(items, result)
+}
+
+func (m *merchantUsecaseSuite) TestAdjustMerchantBalances_success() {
+	adjustments := map[string]float64{"uuid-merchant-test": 15000}
+
+	m.merchantRepo.On("AdjustBalances", adjustments, "uuid-admin-test").Return(nil)
+
+	err := m.merchantUsecase.AdjustMerchantBalances(adjustments, "uuid-admin-test")
+	m.Nil(err)
+}
+
+func (m *merchantUsecaseSuite) TestAdjustMerchantBalances_zeroAmount() {
+	adjustments := map[string]float64{"uuid-merchant-test": 0}
+
+	err := m.merchantUsecase.AdjustMerchantBalances(adjustments, "uuid-admin-test")
+	m.ErrorIs(err, ErrInvalidBalanceAdjust)
+	m.merchantRepo.AssertNotCalled(m.T(), "AdjustBalances", adjustments, "uuid-admin-test")
 }
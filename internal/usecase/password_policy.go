@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// temporaryPasswordAlphabet leaves out the characters that are easily misread when a password is read out, 0/O and
+// 1/l/I.
+const temporaryPasswordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// commonPasswords are refused whatever the policy settings, compared case-insensitively.
+var commonPasswords = []string{
+	"password", "password1", "password123", "12345678", "123456789", "1234567890", "qwerty123",
+	"qwertyuiop", "abc12345", "11111111", "iloveyou", "admin123", "welcome1", "letmein1",
+}
+
+// PasswordPolicy is the strength policy every new password is checked against, on registration,
+// admin user creation, password change and password reset.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireLetter bool
+	RequireDigit  bool
+	// Blocklist extends commonPasswords.
+	Blocklist []string
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: 8, RequireLetter: true, RequireDigit: true}
+}
+
+// WeakPasswordError lists every rule the password failed, errors.Is(err, ErrWeakPassword) holds for it.
+type WeakPasswordError struct {
+	Rules []string
+}
+
+func (e *WeakPasswordError) Error() string {
+	return "password is too weak: " + strings.Join(e.Rules, ", ")
+}
+
+func (e *WeakPasswordError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// Validate returns a WeakPasswordError with the failed rules, the username may be empty when it is not known.
+func (p PasswordPolicy) Validate(username, password string) error {
+	var rules []string
+
+	if len([]rune(password)) < p.MinLength {
+		rules = append(rules, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if p.RequireLetter && !hasLetter {
+		rules = append(rules, "must contain a letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		rules = append(rules, "must contain a digit")
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		rules = append(rules, "must not be the username")
+	}
+
+	for _, common := range append(commonPasswords, p.Blocklist...) {
+		if strings.EqualFold(password, common) {
+			rules = append(rules, "is a commonly used password")
+			break
+		}
+	}
+
+	if len(rules) > 0 {
+		return &WeakPasswordError{Rules: rules}
+	}
+	return nil
+}
+
+// Generate makes a random password that passes Validate, it is at least 16 characters long or MinLength when that is
+// longer.
+func (p PasswordPolicy) Generate(username string) (string, error) {
+	length := max(16, p.MinLength)
+	alphabetSize := big.NewInt(int64(len(temporaryPasswordAlphabet)))
+	for {
+		password := make([]byte, length)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return "", fmt.Errorf("generate password: %w", err)
+			}
+			password[i] = temporaryPasswordAlphabet[n.Int64()]
+		}
+		// a password without a digit or a letter is rare and simply drawn again
+		if p.Validate(username, string(password)) == nil {
+			return string(password), nil
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	policy.Blocklist = []string{"konterku1"}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		rules    []string
+	}{
+		{name: "strong", username: "eko", password: "n3wSecret", rules: nil},
+		{name: "every rule fails", username: "eko", password: "123", rules: []string{"must be at least 8 characters", "must contain a letter"}},
+		{name: "letters only", username: "eko", password: "abcdefgh", rules: []string{"must contain a digit"}},
+		{name: "username", username: "Eko12345", password: "eko12345", rules: []string{"must not be the username"}},
+		{name: "common password", username: "eko", password: "Password123", rules: []string{"is a commonly used password"}},
+		{name: "configured blocklist", username: "eko", password: "KONTERKU1", rules: []string{"is a commonly used password"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.username, tt.password)
+
+			if tt.rules == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var weak *WeakPasswordError
+			assert.ErrorAs(t, err, &weak)
+			assert.ErrorIs(t, err, ErrWeakPassword)
+			assert.Equal(t, tt.rules, weak.Rules)
+		})
+	}
+}
+
+func TestPasswordPolicy_GeneratePassesValidate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 20, RequireLetter: true, RequireDigit: true}
+
+	first, err := policy.Generate("eko")
+	assert.NoError(t, err)
+	second, err := policy.Generate("eko")
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 20)
+	assert.NoError(t, policy.Validate("eko", first))
+	assert.NotEqual(t, first, second)
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/service"
+	"time"
+)
+
+type PasswordResetUseCase interface {
+	RequestPasswordReset(username string) error
+	ResetPassword(token, newPassword string, client entity.ClientInfo) error
+}
+
+type passwordResetUseCase struct {
+	userRepo  repository.UserRepository
+	resetRepo repository.PasswordResetRepository
+	eventRepo repository.AuthEventRepository
+	notifier  service.Notifier
+	ttl       time.Duration
+	policy    PasswordPolicy
+	hasher    service.PasswordHasher
+	log       *logger.Logger
+}
+
+// hashToken is what gets stored for reset and refresh tokens, the plain token is only ever handed to the user.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestPasswordReset sends a single-use reset token to the user. Unknown usernames are not an error,
+// so the endpoint cannot be used to find out which accounts exist.
+func (p *passwordResetUseCase) RequestPasswordReset(username string) error {
+	p.log.Info("Starting to request a password reset in the usecase layer", nil)
+
+	user, err := p.userRepo.GetUserByUsername(username)
+	if err != nil || !user.Active {
+		p.log.Info("Password reset requested for an unknown or deactivated username", nil)
+		return nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		p.log.Error("Failed to generate the password reset token: ", err)
+		return fmt.Errorf("failed to generate reset token: %v", err)
+	}
+	token := hex.EncodeToString(raw)
+	expiresAt := time.Now().Add(p.ttl)
+
+	if err := p.resetRepo.CreateToken(user.Id_user, hashToken(token), expiresAt); err != nil {
+		p.log.Error("Failed to store the password reset token: ", err)
+		return err
+	}
+
+	data := map[string]interface{}{
+		"token":     token,
+		"expiresAt": expiresAt,
+	}
+	if err := p.notifier.Notify(user.Id_user, "Password reset", data); err != nil {
+		p.log.Error("Failed to send the password reset token: ", err)
+		return err
+	}
+
+	p.log.Info("Password reset token has been sent", user.Id_user)
+	return nil
+}
+
+func (p *passwordResetUseCase) ResetPassword(token, newPassword string, client entity.ClientInfo) error {
+	p.log.Info("Starting to reset a password in the usecase layer", nil)
+
+	// the token is only looked up here, it is consumed together with the password update below
+	username, err := p.resetRepo.FindUsername(hashToken(token))
+	if err != nil {
+		p.log.Error("Failed to find the user of the password reset token: ", err)
+		return err
+	}
+
+	if err := p.policy.Validate(username, newPassword); err != nil {
+		return err
+	}
+
+	hash, err := p.hasher.Hash(newPassword)
+	if err != nil {
+		p.log.Error("Failed to hash password: ", err)
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	userId, err := p.resetRepo.ResetPassword(hashToken(token), hash)
+	if err != nil {
+		p.log.Error("Failed to reset the password: ", err)
+		return err
+	}
+
+	recordAuthEvent(p.eventRepo, p.log, entity.AuthEventPasswordChange, userId, username, client)
+	p.log.Info("Password has been reset successfully", userId)
+	return nil
+}
+
+func NewPasswordResetUseCase(userRepo repository.UserRepository, resetRepo repository.PasswordResetRepository, eventRepo repository.AuthEventRepository,
+	notifier service.Notifier, ttl time.Duration, policy PasswordPolicy, hasher service.PasswordHasher, log *logger.Logger) PasswordResetUseCase {
+	return &passwordResetUseCase{userRepo: userRepo, resetRepo: resetRepo, eventRepo: eventRepo, notifier: notifier, ttl: ttl,
+		policy: policy, hasher: hasher, log: log}
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"database/sql"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/mock/repo_mock"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/mock/service_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type passwordResetUsecaseTestSuite struct {
+	suite.Suite
+	userRepo  *repo_mock.UserRepoMock
+	resetRepo *repositorymock.MockPasswordResetRepository
+	eventRepo *repositorymock.MockAuthEventRepository
+	notifier  *service_mock.NotifierMock
+	useCase   PasswordResetUseCase
+	log       logger.Logger
+}
+
+func TestPasswordResetUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(passwordResetUsecaseTestSuite))
+}
+
+func (p *passwordResetUsecaseTestSuite) SetupTest() {
+	p.userRepo = new(repo_mock.UserRepoMock)
+	p.resetRepo = new(repositorymock.MockPasswordResetRepository)
+	p.eventRepo = new(repositorymock.MockAuthEventRepository)
+	p.notifier = new(service_mock.NotifierMock)
+	p.log = logger.NewLogger()
+	p.useCase = NewPasswordResetUseCase(p.userRepo, p.resetRepo, p.eventRepo, p.notifier, 30*time.Minute, DefaultPasswordPolicy(), service.NewBcryptHasher(bcrypt.MinCost), &p.log)
+}
+
+func (p *passwordResetUsecaseTestSuite) TestRequestPasswordReset_StoresOnlyTheHash() {
+	p.userRepo.On("GetUserByUsername", "john").Return(entity.User{Id_user: "uuid-user", Active: true}, nil)
+
+	var storedHash string
+	p.resetRepo.On("CreateToken", "uuid-user", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		storedHash = args.String(1)
+	}).Return(nil)
+
+	var sentToken string
+	p.notifier.On("Notify", "uuid-user", "Password reset", mock.Anything).Run(func(args mock.Arguments) {
+		sentToken = args.Get(2).(map[string]interface{})["token"].(string)
+	}).Return(nil)
+
+	err := p.useCase.RequestPasswordReset("john")
+
+	p.NoError(err)
+	p.NotEmpty(sentToken)
+	p.NotEqual(sentToken, storedHash)
+	p.Equal(hashToken(sentToken), storedHash)
+}
+
+func (p *passwordResetUsecaseTestSuite) TestRequestPasswordReset_UnknownUsername() {
+	p.userRepo.On("GetUserByUsername", "ghost").Return(entity.User{}, sql.ErrNoRows)
+
+	err := p.useCase.RequestPasswordReset("ghost")
+
+	p.NoError(err)
+	p.resetRepo.AssertNotCalled(p.T(), "CreateToken", mock.Anything, mock.Anything, mock.Anything)
+	p.notifier.AssertNotCalled(p.T(), "Notify", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (p *passwordResetUsecaseTestSuite) TestRequestPasswordReset_DeactivatedUser() {
+	p.userRepo.On("GetUserByUsername", "john").Return(entity.User{Id_user: "uuid-user"}, nil)
+
+	err := p.useCase.RequestPasswordReset("john")
+
+	p.NoError(err)
+	p.resetRepo.AssertNotCalled(p.T(), "CreateToken", mock.Anything, mock.Anything, mock.Anything)
+	p.notifier.AssertNotCalled(p.T(), "Notify", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (p *passwordResetUsecaseTestSuite) TestResetPassword_Success() {
+	p.resetRepo.On("FindUsername", hashToken("plain-token")).Return("john", nil)
+	p.resetRepo.On("ResetPassword", hashToken("plain-token"), mock.Anything).Return("uuid-user", nil)
+	p.eventRepo.On("Record", entity.AuthEvent{UserId: "uuid-user", Event: entity.AuthEventPasswordChange, Identifier: "john", Ip: "10.0.0.1"}).Return(nil).Once()
+
+	err := p.useCase.ResetPassword("plain-token", "new-pass1", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	p.NoError(err)
+	p.eventRepo.AssertExpectations(p.T())
+}
+
+func (p *passwordResetUsecaseTestSuite) TestResetPassword_UsedOrExpiredToken() {
+	p.resetRepo.On("FindUsername", hashToken("used-token")).Return("", repository.ErrInvalidResetToken)
+
+	err := p.useCase.ResetPassword("used-token", "new-pass1", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	p.ErrorIs(err, repository.ErrInvalidResetToken)
+	p.resetRepo.AssertNotCalled(p.T(), "ResetPassword", mock.Anything, mock.Anything)
+}
+
+func (p *passwordResetUsecaseTestSuite) TestResetPassword_WeakPassword() {
+	p.resetRepo.On("FindUsername", hashToken("plain-token")).Return("john", nil)
+
+	err := p.useCase.ResetPassword("plain-token", "weak", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	p.ErrorIs(err, ErrWeakPassword)
+	p.resetRepo.AssertNotCalled(p.T(), "ResetPassword", mock.Anything, mock.Anything)
+}
+
+func (p *passwordResetUsecaseTestSuite) TestResetPassword_RejectsUsername() {
+	p.resetRepo.On("FindUsername", hashToken("plain-token")).Return("john12345", nil)
+
+	err := p.useCase.ResetPassword("plain-token", "John12345", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	var weak *WeakPasswordError
+	p.ErrorAs(err, &weak)
+	p.Equal([]string{"must not be the username"}, weak.Rules)
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+	"slices"
+)
+
+// ErrUnknownPermission is returned when a mapping names a permission that is not one of entity.Permissions.
+var ErrUnknownPermission = errors.New("unknown permission")
+
+type PermissionUseCase interface {
+	ListRolePermissions() ([]entity.RolePermissions, error)
+	UpdateRolePermissions(role string, permissions []string, actorId string) (entity.RolePermissions, error)
+}
+
+type permissionUseCase struct {
+	permissionRepo repository.RolePermissionRepository
+	auditRepo      repository.AuditLogRepository
+	log            *logger.Logger
+}
+
+// ListRolePermissions returns every role in allowedRoles order, a role without permissions has an empty list.
+func (p *permissionUseCase) ListRolePermissions() ([]entity.RolePermissions, error) {
+	p.log.Info("Starting to retrieve the role permissions in the usecase layer", nil)
+
+	mappings, err := p.permissionRepo.List()
+	if err != nil {
+		p.log.Error("Failed to retrieve the role permissions: ", err)
+		return nil, err
+	}
+
+	roles := make([]entity.RolePermissions, 0, len(allowedRoles))
+	for _, role := range allowedRoles {
+		permissions := []string{}
+		for _, mapping := range mappings {
+			if mapping.Role == role {
+				permissions = mapping.Permissions
+			}
+		}
+		roles = append(roles, entity.RolePermissions{Role: role, Permissions: permissions})
+	}
+	return roles, nil
+}
+
+// UpdateRolePermissions replaces the permissions of role. Tokens that were already issued keep their permissions
+// until they are refreshed.
+func (p *permissionUseCase) UpdateRolePermissions(role string, permissions []string, actorId string) (entity.RolePermissions, error) {
+	p.log.Info("Starting to update the permissions of a role in the usecase layer", nil)
+
+	if !slices.Contains(allowedRoles, role) {
+		return entity.RolePermissions{}, ErrInvalidRole
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(entity.Permissions, permission) {
+			return entity.RolePermissions{}, fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
+		}
+	}
+	permissions = slices.Compact(slices.Sorted(slices.Values(permissions)))
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	if err := p.permissionRepo.Replace(role, permissions); err != nil {
+		p.log.Error("Failed to update the permissions of the role: ", err)
+		return entity.RolePermissions{}, err
+	}
+
+	// the mapping is already changed at this point, a failed audit write is logged instead of failing the request
+	if err := p.auditRepo.Record(entity.AuditLog{ActorId: actorId, Action: entity.AuditActionRolePermissions, TargetId: role}); err != nil {
+		p.log.Error("Failed to record the role permission audit log: ", err)
+	}
+
+	p.log.Info("Permissions of the role have been updated successfully", role)
+	return entity.RolePermissions{Role: role, Permissions: permissions}, nil
+}
+
+func NewPermissionUseCase(permissionRepo repository.RolePermissionRepository, auditRepo repository.AuditLogRepository, log *logger.Logger) PermissionUseCase {
+	return &permissionUseCase{permissionRepo: permissionRepo, auditRepo: auditRepo, log: log}
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"errors"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type permissionUsecaseTestSuite struct {
+	suite.Suite
+	mockPermissionRepo *repositorymock.MockRolePermissionRepository
+	mockAuditRepo      *repositorymock.MockAuditLogRepository
+	permissionUseCase  PermissionUseCase
+	log                logger.Logger
+}
+
+func (s *permissionUsecaseTestSuite) SetupTest() {
+	s.mockPermissionRepo = new(repositorymock.MockRolePermissionRepository)
+	s.mockAuditRepo = new(repositorymock.MockAuditLogRepository)
+	s.log = logger.NewLogger()
+	s.permissionUseCase = NewPermissionUseCase(s.mockPermissionRepo, s.mockAuditRepo, &s.log)
+}
+
+func (s *permissionUsecaseTestSuite) TestListRolePermissions_IncludesRolesWithoutPermissions() {
+	s.mockPermissionRepo.On("List").Return([]entity.RolePermissions{{Role: "admin", Permissions: []string{"user:read"}}}, nil).Once()
+
+	roles, err := s.permissionUseCase.ListRolePermissions()
+
+	s.NoError(err)
+	s.Equal([]entity.RolePermissions{
+		{Role: "admin", Permissions: []string{"user:read"}},
+		{Role: "employee", Permissions: []string{}},
+	}, roles)
+}
+
+func (s *permissionUsecaseTestSuite) TestUpdateRolePermissions_SortsAndRecordsAudit() {
+	s.mockPermissionRepo.On("Replace", "employee", []string{"product:write", "user:read"}).Return(nil).Once()
+	s.mockAuditRepo.On("Record", entity.AuditLog{ActorId: "uuid-admin", Action: entity.AuditActionRolePermissions, TargetId: "employee"}).Return(nil).Once()
+
+	role, err := s.permissionUseCase.UpdateRolePermissions("employee", []string{"user:read", "product:write", "user:read"}, "uuid-admin")
+
+	s.NoError(err)
+	s.Equal(entity.RolePermissions{Role: "employee", Permissions: []string{"product:write", "user:read"}}, role)
+	s.mockPermissionRepo.AssertExpectations(s.T())
+	s.mockAuditRepo.AssertExpectations(s.T())
+}
+
+func (s *permissionUsecaseTestSuite) TestUpdateRolePermissions_Empty() {
+	s.mockPermissionRepo.On("Replace", "employee", []string{}).Return(nil).Once()
+	s.mockAuditRepo.On("Record", mock.Anything).Return(nil).Once()
+
+	role, err := s.permissionUseCase.UpdateRolePermissions("employee", []string{}, "uuid-admin")
+
+	s.NoError(err)
+	s.Equal([]string{}, role.Permissions)
+}
+
+func (s *permissionUsecaseTestSuite) TestUpdateRolePermissions_InvalidRole() {
+	_, err := s.permissionUseCase.UpdateRolePermissions("owner", []string{"user:read"}, "uuid-admin")
+
+	s.ErrorIs(err, ErrInvalidRole)
+	s.mockPermissionRepo.AssertNotCalled(s.T(), "Replace", mock.Anything, mock.Anything)
+}
+
+func (s *permissionUsecaseTestSuite) TestUpdateRolePermissions_UnknownPermission() {
+	_, err := s.permissionUseCase.UpdateRolePermissions("employee", []string{"product:write", "product:delete"}, "uuid-admin")
+
+	s.ErrorIs(err, ErrUnknownPermission)
+	s.ErrorContains(err, "product:delete")
+	s.mockPermissionRepo.AssertNotCalled(s.T(), "Replace", mock.Anything, mock.Anything)
+}
+
+func (s *permissionUsecaseTestSuite) TestUpdateRolePermissions_AuditFailureIsLogged() {
+	s.mockPermissionRepo.On("Replace", "employee", []string{"user:read"}).Return(nil).Once()
+	s.mockAuditRepo.On("Record", mock.Anything).Return(errors.New("audit failed")).Once()
+
+	_, err := s.permissionUseCase.UpdateRolePermissions("employee", []string{"user:read"}, "uuid-admin")
+
+	s.NoError(err)
+}
+
+func TestPermissionUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(permissionUsecaseTestSuite))
+}
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/service"
+	"strings"
+)
+
+type ProductSyncUseCase interface {
+	SyncSupplierPrices(ctx context.Context) (entity.ProductSyncReport, error)
+}
+
+type productSyncUseCase struct {
+	repo       repository.ProductRepository
+	client     service.SupplierCatalogClient
+	supplierId string
+	log        *logger.Logger
+}
+
+// validatePriceItem returns why an entry of the price list cannot be applied, or an empty string when it can.
+func validatePriceItem(item entity.SupplierPriceItem) string {
+	switch {
+	case item.Code == "":
+		return "code is required"
+	case item.Provider == "":
+		return "provider is required"
+	case item.Nominal <= 0:
+		return "nominal must be greater than zero"
+	case item.Price < item.Nominal:
+		return "price must be greater than nominal"
+	}
+	return ""
+}
+
+func (p *productSyncUseCase) SyncSupplierPrices(ctx context.Context) (entity.ProductSyncReport, error) {
+	p.log.Info("Starting to sync supplier prices in the usecase layer", nil)
+
+	// The whole list is fetched before touching the database, so a network failure leaves the catalog as it was.
+	items, err := p.client.FetchPriceList(ctx)
+	if err != nil {
+		p.log.Error("Failed to fetch the supplier price list: ", err)
+		return entity.ProductSyncReport{}, err
+	}
+
+	var (
+		valid  []entity.SupplierPriceItem
+		failed []entity.ProductSyncItem
+		seen   = make(map[string]bool)
+	)
+
+	for _, item := range items {
+		item.Code = strings.TrimSpace(item.Code)
+		item.Provider = strings.TrimSpace(item.Provider)
+
+		if reason := validatePriceItem(item); reason != "" {
+			failed = append(failed, entity.ProductSyncItem{Code: item.Code, Reason: reason})
+			continue
+		}
+		if seen[item.Code] {
+			failed = append(failed, entity.ProductSyncItem{Code: item.Code, Reason: fmt.Sprintf("duplicate code %s in price list", item.Code)})
+			continue
+		}
+
+		seen[item.Code] = true
+		valid = append(valid, item)
+	}
+
+	report, err := p.repo.SyncPrices(valid, p.supplierId)
+	if err != nil {
+		return entity.ProductSyncReport{}, err
+	}
+
+	report.Failed = append(report.Failed, failed...)
+	p.log.Info("Supplier prices have been synced successfully: ", report)
+	return report, nil
+}
+
+func NewProductSyncUseCase(repo repository.ProductRepository, client service.SupplierCatalogClient, supplierId string, log *logger.Logger) ProductSyncUseCase {
+	return &productSyncUseCase{repo: repo, client: client, supplierId: supplierId, log: log}
+}
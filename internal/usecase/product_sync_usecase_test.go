@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	servicemock "server-pulsa-app/internal/mock/service_mock"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type productSyncUsecaseTestSuite struct {
+	suite.Suite
+	mockProductRepository *repositorymock.MockProductRepository
+	mockCatalogClient     *servicemock.SupplierCatalogClientMock
+	productSyncUseCase    ProductSyncUseCase
+	log                   logger.Logger
+}
+
+func (p *productSyncUsecaseTestSuite) SetupTest() {
+	p.mockProductRepository = new(repositorymock.MockProductRepository)
+	p.mockCatalogClient = new(servicemock.SupplierCatalogClientMock)
+	p.log = logger.NewLogger()
+	p.productSyncUseCase = NewProductSyncUseCase(p.mockProductRepository, p.mockCatalogClient, "supplier-1", &p.log)
+}
+
+func (p *productSyncUsecaseTestSuite) TestSyncSupplierPrices_SkipsInvalidItems() {
+	items := []entity.SupplierPriceItem{
+		{Code: " TSEL10 ", Provider: "Telkomsel", Nominal: 10000, Price: 10800},
+		{Code: "TSEL10", Provider: "Telkomsel", Nominal: 10000, Price: 10900},
+		{Code: "BAD", Provider: "Axis", Nominal: 10000, Price: 9000},
+		{Provider: "Xl", Nominal: 5000, Price: 5500},
+	}
+	valid := []entity.SupplierPriceItem{{Code: "TSEL10", Provider: "Telkomsel", Nominal: 10000, Price: 10800}}
+
+	p.mockCatalogClient.On("FetchPriceList", mock.Anything).Return(items, nil).Once()
+	p.mockProductRepository.On("SyncPrices", valid, "supplier-1").
+		Return(entity.ProductSyncReport{Updated: []entity.ProductSyncItem{{Code: "TSEL10", IdProduct: "1"}}}, nil).Once()
+
+	report, err := p.productSyncUseCase.SyncSupplierPrices(context.Background())
+
+	p.Nil(err)
+	p.Len(report.Updated, 1)
+	p.Len(report.Failed, 3)
+	p.Equal("price must be greater than nominal", report.Failed[1].Reason)
+}
+
+func (p *productSyncUsecaseTestSuite) TestSyncSupplierPrices_NetworkFailure() {
+	p.mockCatalogClient.On("FetchPriceList", mock.Anything).Return([]entity.SupplierPriceItem(nil), errors.New("connection refused")).Once()
+
+	_, err := p.productSyncUseCase.SyncSupplierPrices(context.Background())
+
+	p.EqualError(err, "connection refused")
+	p.mockProductRepository.AssertNotCalled(p.T(), "SyncPrices", mock.Anything, mock.Anything)
+}
+
+func TestProductSyncUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(productSyncUsecaseTestSuite))
+}
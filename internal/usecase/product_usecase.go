@@ -1,35 +1,85 @@
 package usecase
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/repository"
+	"slices"
 )
 
 // var logProduct = logger.GetLogger()
 
+// ErrProductNotFound is returned by DeleteProduct when there is no product with the id.
+var ErrProductNotFound = errors.New("product not found")
+
 type ProductUseCase interface {
 	CreateNewProduct(Product entity.Product) (entity.Product, error)
-	FindAllProduct() ([]entity.Product, error)
+	FindAllProduct(query entity.ProductQuery) ([]entity.Product, error)
+	FindPublicCatalog() ([]entity.PublicProduct, error)
 	FindProductById(id string) (entity.Product, error)
+	FindProductByCode(code string) (entity.Product, error)
+	FindProductsByIds(ids, merchantIds []string, role string) (entity.ProductBatch, error)
 	UpdateProduct(Product entity.Product) (entity.Product, error)
-	DeleteProduct(id string) error
+	DeleteProduct(id, actorId string) error
 }
 
 type productUseCase struct {
-	repo repository.ProductRepository
-	log  *logger.Logger
+	repo         repository.ProductRepository
+	providerRepo repository.ProviderRepository
+	auditRepo    repository.AuditLogRepository
+	log          *logger.Logger
+}
+
+// resolveProvider fills the provider of a product. Payloads that only carry a provider name, such as imported
+// price lists, are matched against mst_provider and a new provider is created when none exists yet.
+func (p *productUseCase) resolveProvider(product entity.Product) (entity.Product, error) {
+	var (
+		provider entity.Provider
+		err      error
+	)
+
+	switch {
+	case product.IdProvider != "":
+		provider, err = p.providerRepo.Get(product.IdProvider)
+		if err != nil {
+			return entity.Product{}, fmt.Errorf("provider with ID %s not found", product.IdProvider)
+		}
+	case product.NameProvider != "":
+		provider, err = p.providerRepo.FindOrCreateByName(product.NameProvider)
+		if err != nil {
+			return entity.Product{}, err
+		}
+	default:
+		return entity.Product{}, fmt.Errorf("provider is required")
+	}
+
+	product.IdProvider = provider.IdProvider
+	product.NameProvider = provider.NameProvider
+	return product, nil
 }
 
 func (p *productUseCase) CreateNewProduct(Product entity.Product) (entity.Product, error) {
 	p.log.Info("Starting to create a new product in the usecase layer", nil)
+
+	Product, err := p.resolveProvider(Product)
+	if err != nil {
+		return entity.Product{}, err
+	}
+
 	return p.repo.Create(Product)
 }
 
-func (p *productUseCase) FindAllProduct() ([]entity.Product, error) {
+func (p *productUseCase) FindAllProduct(query entity.ProductQuery) ([]entity.Product, error) {
 	p.log.Info("Starting to retrive all product in the usecase layer", nil)
-	return p.repo.List()
+	return p.repo.List(query)
+}
+
+func (p *productUseCase) FindPublicCatalog() ([]entity.PublicProduct, error) {
+	p.log.Info("Starting to retrive the public product catalog in the usecase layer", nil)
+	return p.repo.ListPublicCatalog()
 }
 
 func (p *productUseCase) FindProductById(id string) (entity.Product, error) {
@@ -37,30 +87,99 @@ func (p *productUseCase) FindProductById(id string) (entity.Product, error) {
 	return p.repo.Get(id)
 }
 
+func (p *productUseCase) FindProductByCode(code string) (entity.Product, error) {
+	p.log.Info("Starting to retrive a product by code in the usecase layer", nil)
+	return p.repo.GetByCode(code)
+}
+
+// FindProductsByIds looks the ids up in one query and reports the ids without a product. Like the product list,
+// callers other than admins only see the global products and the private products of their own merchants.
+func (p *productUseCase) FindProductsByIds(ids, merchantIds []string, role string) (entity.ProductBatch, error) {
+	p.log.Info("Starting to retrive products by ids in the usecase layer", nil)
+
+	requested := []string{}
+	lookup := []string{}
+	for _, id := range ids {
+		if slices.Contains(requested, id) {
+			continue
+		}
+		requested = append(requested, id)
+		// ids that are not uuids cannot match a product and would fail the whole query
+		if uuidPattern.MatchString(id) {
+			lookup = append(lookup, id)
+		}
+	}
+
+	found := map[string]entity.Product{}
+	if len(lookup) > 0 {
+		products, err := p.repo.GetByIds(lookup)
+		if err != nil {
+			return entity.ProductBatch{}, err
+		}
+		for _, product := range products {
+			if role == "admin" || product.IdMerchant == "" || slices.Contains(merchantIds, product.IdMerchant) {
+				found[product.IdProduct] = product
+			}
+		}
+	}
+
+	batch := entity.ProductBatch{Products: []entity.Product{}, Missing: []string{}}
+	for _, id := range requested {
+		if product, ok := found[id]; ok {
+			batch.Products = append(batch.Products, product)
+		} else {
+			batch.Missing = append(batch.Missing, id)
+		}
+	}
+	return batch, nil
+}
+
 func (p *productUseCase) UpdateProduct(product entity.Product) (entity.Product, error) {
 	p.log.Info("Starting to retrive a product by id in the usecase layer", nil)
 
-	_, err := p.repo.Get(product.IdProduct)
+	current, err := p.repo.Get(product.IdProduct)
 	if err != nil {
 		return entity.Product{}, fmt.Errorf("product with ID %s not found", product.IdProduct)
 	}
 
+	// Reject stale versions before resolving providers, the repository still guards the race between Get and Update.
+	if current.Version != product.Version {
+		return entity.Product{}, repository.ErrProductVersionConflict
+	}
+
+	product, err = p.resolveProvider(product)
+	if err != nil {
+		return entity.Product{}, err
+	}
+
 	p.log.Info("Product ID %s has been updated successfully: ", product.IdProduct)
 	return p.repo.Update(product)
 }
 
-func (p *productUseCase) DeleteProduct(id string) error {
+func (p *productUseCase) DeleteProduct(id, actorId string) error {
 	p.log.Info("Starting to retrive a product by id in the usecase layer", nil)
 
 	_, err := p.repo.Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("product with ID %s not found", id)
+		return err
+	}
+
+	if err := p.repo.Delete(id); err != nil {
+		return err
+	}
+
+	// the product cannot be brought back, so a missing audit row is reported in the log only
+	if err := p.auditRepo.Record(entity.AuditLog{ActorId: actorId, Action: entity.AuditActionProductDelete, TargetId: id}); err != nil {
+		p.log.Error("Failed to record the product deletion audit log: ", err)
 	}
 
 	p.log.Info("Product has been deleted successfully: ", id)
-	return p.repo.Delete(id)
+	return nil
 }
 
-func NewProductUseCase(repo repository.ProductRepository, log *logger.Logger) ProductUseCase {
-	return &productUseCase{repo: repo, log: log}
+func NewProductUseCase(repo repository.ProductRepository, providerRepo repository.ProviderRepository, auditRepo repository.AuditLogRepository, log *logger.Logger) ProductUseCase {
+	return &productUseCase{repo: repo, providerRepo: providerRepo, auditRepo: auditRepo, log: log}
 }
@@ -1,25 +1,32 @@
 package usecase
 
 import (
+	"database/sql"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/repository"
 	"testing"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
 type productUsecaseTestSuite struct {
 	suite.Suite
-	mockProductRepository *repositorymock.MockProductRepository
-	ProductUseCase        ProductUseCase
-	log                   logger.Logger
+	mockProductRepository  *repositorymock.MockProductRepository
+	mockProviderRepository *repositorymock.MockProviderRepository
+	mockAuditRepository    *repositorymock.MockAuditLogRepository
+	ProductUseCase         ProductUseCase
+	log                    logger.Logger
 }
 
 func (p *productUsecaseTestSuite) SetupTest() {
 	p.mockProductRepository = new(repositorymock.MockProductRepository)
+	p.mockProviderRepository = new(repositorymock.MockProviderRepository)
+	p.mockAuditRepository = new(repositorymock.MockAuditLogRepository)
 	p.log = logger.NewLogger()
-	p.ProductUseCase = NewProductUseCase(p.mockProductRepository, &p.log)
+	p.ProductUseCase = NewProductUseCase(p.mockProductRepository, p.mockProviderRepository, p.mockAuditRepository, &p.log)
 }
 
 func (p *productUsecaseTestSuite) TestCreateNewProduct_Success() {
@@ -30,15 +37,20 @@ func (p *productUsecaseTestSuite) TestCreateNewProduct_Success() {
 		IdSupliyer:   "1",
 	}
 
+	resolvedProduct := newProduct
+	resolvedProduct.IdProvider = "provider-1"
+
 	CreatedProduct := entity.Product{
 		IdProduct:    "1",
+		IdProvider:   "provider-1",
 		NameProvider: "Test Product",
 		Nominal:      1000,
 		Price:        1000,
 		IdSupliyer:   "1",
 	}
 
-	p.mockProductRepository.On("Create", newProduct).Return(CreatedProduct, nil).Once()
+	p.mockProviderRepository.On("FindOrCreateByName", "Test Product").Return(entity.Provider{IdProvider: "provider-1", NameProvider: "Test Product"}, nil).Once()
+	p.mockProductRepository.On("Create", resolvedProduct).Return(CreatedProduct, nil).Once()
 
 	product, err := p.ProductUseCase.CreateNewProduct(newProduct)
 
@@ -46,6 +58,22 @@ func (p *productUsecaseTestSuite) TestCreateNewProduct_Success() {
 	p.Equal(CreatedProduct, product)
 }
 
+func (p *productUsecaseTestSuite) TestCreateNewProduct_UnknownProvider() {
+	newProduct := entity.Product{
+		IdProvider: "missing",
+		Nominal:    1000,
+		Price:      1000,
+		IdSupliyer: "1",
+	}
+
+	p.mockProviderRepository.On("Get", "missing").Return(entity.Provider{}, sql.ErrNoRows).Once()
+
+	_, err := p.ProductUseCase.CreateNewProduct(newProduct)
+
+	p.EqualError(err, "provider with ID missing not found")
+	p.mockProductRepository.AssertNotCalled(p.T(), "Create")
+}
+
 func (p *productUsecaseTestSuite) TestListAllProducts_Success() {
 	products := []entity.Product{
 		{
@@ -64,9 +92,10 @@ func (p *productUsecaseTestSuite) TestListAllProducts_Success() {
 		},
 	}
 
-	p.mockProductRepository.On("List").Return(products, nil).Once()
+	query := entity.ProductQuery{Sort: "nominal", Order: "asc", Page: 2, Limit: 10}
+	p.mockProductRepository.On("List", query).Return(products, nil).Once()
 
-	productsList, err := p.ProductUseCase.FindAllProduct()
+	productsList, err := p.ProductUseCase.FindAllProduct(query)
 
 	p.Nil(err)
 	p.Equal(products, productsList)
@@ -91,11 +120,55 @@ func (p *productUsecaseTestSuite) TestFindProductById_Success() {
 	p.Equal(product, productFound)
 }
 
+func (p *productUsecaseTestSuite) TestFindProductByCode_Success() {
+	product := entity.Product{IdProduct: "1", Code: "TSEL10", Nominal: 10000, Price: 10900}
+
+	p.mockProductRepository.On("GetByCode", "TSEL10").Return(product, nil).Once()
+
+	productFound, err := p.ProductUseCase.FindProductByCode("TSEL10")
+
+	p.Nil(err)
+	p.Equal(product, productFound)
+}
+
+const (
+	batchProductA = "8f14e45f-ceea-467a-9f4b-1d2c3e4f5a6b"
+	batchProductB = "c9f0f895-fb98-4b91-8a3b-2d3e4f5a6b7c"
+	batchProductC = "45c48cce-2e2d-4fbd-9a1b-3e4f5a6b7c8d"
+)
+
+func (p *productUsecaseTestSuite) TestFindProductsByIds_AllFound() {
+	products := []entity.Product{{IdProduct: batchProductB}, {IdProduct: batchProductA}}
+	p.mockProductRepository.On("GetByIds", []string{batchProductA, batchProductB}).Return(products, nil).Once()
+
+	batch, err := p.ProductUseCase.FindProductsByIds([]string{batchProductA, batchProductB, batchProductA}, nil, "admin")
+
+	p.Nil(err)
+	p.Equal([]entity.Product{{IdProduct: batchProductA}, {IdProduct: batchProductB}}, batch.Products)
+	p.Empty(batch.Missing)
+}
+
+func (p *productUsecaseTestSuite) TestFindProductsByIds_SomeMissing() {
+	products := []entity.Product{
+		{IdProduct: batchProductA},
+		{IdProduct: batchProductB, IdMerchant: "uuid-merchant-other"},
+	}
+	p.mockProductRepository.On("GetByIds", []string{batchProductA, batchProductB, batchProductC}).Return(products, nil).Once()
+
+	batch, err := p.ProductUseCase.FindProductsByIds([]string{batchProductA, batchProductB, batchProductC, "not-a-uuid"}, []string{"uuid-merchant-own"}, "employee")
+
+	p.Nil(err)
+	p.Equal([]entity.Product{{IdProduct: batchProductA}}, batch.Products)
+	// the private product of another merchant is reported like an unknown id
+	p.Equal([]string{batchProductB, batchProductC, "not-a-uuid"}, batch.Missing)
+}
+
 func (p *productUsecaseTestSuite) TestUpdateProduct_Success() {
 	id := "1"
 
 	updatedProduct := entity.Product{
 		IdProduct:    "1",
+		IdProvider:   "provider-1",
 		NameProvider: "Updated Product",
 		Nominal:      2000,
 		Price:        2000,
@@ -103,6 +176,7 @@ func (p *productUsecaseTestSuite) TestUpdateProduct_Success() {
 	}
 
 	p.mockProductRepository.On("Get", id).Return(updatedProduct, nil).Once()
+	p.mockProviderRepository.On("Get", "provider-1").Return(entity.Provider{IdProvider: "provider-1", NameProvider: "Updated Product"}, nil).Once()
 	p.mockProductRepository.On("Update", updatedProduct).Return(updatedProduct, nil).Once()
 
 	productUpdated, err := p.ProductUseCase.UpdateProduct(updatedProduct)
@@ -111,15 +185,31 @@ func (p *productUsecaseTestSuite) TestUpdateProduct_Success() {
 	p.Equal(updatedProduct, productUpdated)
 }
 
+func (p *productUsecaseTestSuite) TestUpdateProduct_StaleVersion() {
+	stored := entity.Product{IdProduct: "1", IdProvider: "provider-1", Nominal: 2000, Price: 2500, IdSupliyer: "1", Version: 3}
+	payload := stored
+	payload.Version = 2
+
+	p.mockProductRepository.On("Get", "1").Return(stored, nil).Once()
+
+	_, err := p.ProductUseCase.UpdateProduct(payload)
+
+	p.ErrorIs(err, repository.ErrProductVersionConflict)
+	p.mockProductRepository.AssertNotCalled(p.T(), "Update", mock.Anything)
+	p.mockProviderRepository.AssertNotCalled(p.T(), "Get", mock.Anything)
+}
+
 func (p *productUsecaseTestSuite) TestDeleteProduct_Success() {
 	id := "1"
 
 	p.mockProductRepository.On("Get", id).Return(entity.Product{}, nil).Once()
 	p.mockProductRepository.On("Delete", id).Return(nil).Once()
+	p.mockAuditRepository.On("Record", entity.AuditLog{ActorId: "uuid-admin", Action: entity.AuditActionProductDelete, TargetId: id}).Return(nil).Once()
 
-	err := p.ProductUseCase.DeleteProduct(id)
+	err := p.ProductUseCase.DeleteProduct(id, "uuid-admin")
 
 	p.Nil(err)
+	p.mockAuditRepository.AssertExpectations(p.T())
 }
 
 func TestProductUsecaseTestSuite(t *testing.T) {
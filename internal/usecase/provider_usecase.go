@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+	"strings"
+)
+
+// ErrProviderNotFound is returned by DeleteProvider when there is no provider with the id.
+var ErrProviderNotFound = errors.New("provider not found")
+
+type ProviderUseCase interface {
+	CreateNewProvider(provider entity.Provider) (entity.Provider, error)
+	FindAllProvider() ([]entity.Provider, error)
+	FindProviderById(id string) (entity.Provider, error)
+	UpdateProvider(provider entity.Provider) (entity.Provider, error)
+	DeleteProvider(id string) error
+	ResolveProviderByName(name string) (entity.Provider, error)
+}
+
+type providerUseCase struct {
+	repo repository.ProviderRepository
+	log  *logger.Logger
+}
+
+func (p *providerUseCase) CreateNewProvider(provider entity.Provider) (entity.Provider, error) {
+	p.log.Info("Starting to create a new provider in the usecase layer", nil)
+
+	provider.NameProvider = strings.TrimSpace(provider.NameProvider)
+	if provider.NameProvider == "" {
+		return entity.Provider{}, fmt.Errorf("provider name is required")
+	}
+
+	return p.repo.Create(provider)
+}
+
+func (p *providerUseCase) FindAllProvider() ([]entity.Provider, error) {
+	p.log.Info("Starting to retrive all provider in the usecase layer", nil)
+	return p.repo.List()
+}
+
+func (p *providerUseCase) FindProviderById(id string) (entity.Provider, error) {
+	p.log.Info("Starting to retrive a provider by id in the usecase layer", nil)
+	return p.repo.Get(id)
+}
+
+func (p *providerUseCase) UpdateProvider(provider entity.Provider) (entity.Provider, error) {
+	p.log.Info("Starting to retrive a provider by id in the usecase layer", nil)
+
+	_, err := p.repo.Get(provider.IdProvider)
+	if err != nil {
+		return entity.Provider{}, fmt.Errorf("provider with ID %s not found", provider.IdProvider)
+	}
+
+	provider.NameProvider = strings.TrimSpace(provider.NameProvider)
+	if provider.NameProvider == "" {
+		return entity.Provider{}, fmt.Errorf("provider name is required")
+	}
+
+	p.log.Info("Provider ID %s has been updated successfully: ", provider.IdProvider)
+	return p.repo.Update(provider)
+}
+
+func (p *providerUseCase) DeleteProvider(id string) error {
+	p.log.Info("Starting to retrive a provider by id in the usecase layer", nil)
+
+	_, err := p.repo.Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProviderNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	p.log.Info("Provider has been deleted successfully: ", id)
+	return p.repo.Delete(id)
+}
+
+func (p *providerUseCase) ResolveProviderByName(name string) (entity.Provider, error) {
+	p.log.Info("Starting to resolve a provider by name in the usecase layer", nil)
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return entity.Provider{}, fmt.Errorf("provider name is required")
+	}
+
+	return p.repo.FindOrCreateByName(name)
+}
+
+func NewProviderUseCase(repo repository.ProviderRepository, log *logger.Logger) ProviderUseCase {
+	return &providerUseCase{repo: repo, log: log}
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"database/sql"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type providerUsecaseTestSuite struct {
+	suite.Suite
+	mockProviderRepository *repositorymock.MockProviderRepository
+	providerUseCase        ProviderUseCase
+	log                    logger.Logger
+}
+
+func (p *providerUsecaseTestSuite) SetupTest() {
+	p.mockProviderRepository = new(repositorymock.MockProviderRepository)
+	p.log = logger.NewLogger()
+	p.providerUseCase = NewProviderUseCase(p.mockProviderRepository, &p.log)
+}
+
+func (p *providerUsecaseTestSuite) TestCreateNewProvider_TrimsName() {
+	created := entity.Provider{IdProvider: "1", NameProvider: "Indosat"}
+	p.mockProviderRepository.On("Create", entity.Provider{NameProvider: "Indosat"}).Return(created, nil).Once()
+
+	provider, err := p.providerUseCase.CreateNewProvider(entity.Provider{NameProvider: "  Indosat "})
+
+	p.Nil(err)
+	p.Equal(created, provider)
+}
+
+func (p *providerUsecaseTestSuite) TestCreateNewProvider_EmptyName() {
+	_, err := p.providerUseCase.CreateNewProvider(entity.Provider{NameProvider: "   "})
+
+	p.EqualError(err, "provider name is required")
+	p.mockProviderRepository.AssertNotCalled(p.T(), "Create")
+}
+
+func (p *providerUsecaseTestSuite) TestUpdateProvider_NotFound() {
+	p.mockProviderRepository.On("Get", "1").Return(entity.Provider{}, sql.ErrNoRows).Once()
+
+	_, err := p.providerUseCase.UpdateProvider(entity.Provider{IdProvider: "1", NameProvider: "Tri"})
+
+	p.EqualError(err, "provider with ID 1 not found")
+}
+
+func (p *providerUsecaseTestSuite) TestResolveProviderByName_Success() {
+	resolved := entity.Provider{IdProvider: "1", NameProvider: "Telkomsel"}
+	p.mockProviderRepository.On("FindOrCreateByName", "telkomsel").Return(resolved, nil).Once()
+
+	provider, err := p.providerUseCase.ResolveProviderByName(" telkomsel ")
+
+	p.Nil(err)
+	p.Equal(resolved, provider)
+}
+
+func TestProviderUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(providerUsecaseTestSuite))
+}
@@ -1,19 +1,31 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/repository"
 	"server-pulsa-app/internal/shared/custom"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 )
 
 type ReportUseCase interface {
 	FindAllTransactions(userId, startDate, endDate string) error
+	FindProductMargin(filter custom.MarginFilter) ([]custom.ProductMarginResp, error)
+	FindOverview() (custom.StatsOverview, error)
 }
 
+// ErrInvalidMarginFilter wraps validation errors of the product margin report filter.
+var ErrInvalidMarginFilter = errors.New("invalid margin filter")
+
+const (
+	defaultMarginLimit = 50
+	maxMarginLimit     = 500
+)
+
 type reportUseCase struct {
 	repo repository.ReportRepository
 	log  *logger.Logger
@@ -112,6 +124,44 @@ func (r *reportUseCase) FindAllTransactions(userId, startDate, endDate string) e
 	return nil
 }
 
+func (r *reportUseCase) FindProductMargin(filter custom.MarginFilter) ([]custom.ProductMarginResp, error) {
+	r.log.Info("Starting to retrive product margin report in the usecase layer", nil)
+
+	if filter.SortBy == "" {
+		filter.SortBy = "margin"
+	}
+	if filter.SortBy != "margin" && filter.SortBy != "units" {
+		return nil, fmt.Errorf("%w: sortBy must be margin or units", ErrInvalidMarginFilter)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultMarginLimit
+	}
+	if filter.Limit > maxMarginLimit {
+		filter.Limit = maxMarginLimit
+	}
+
+	for _, date := range []string{filter.StartDate, filter.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("%w: invalid date %s, use YYYY-MM-DD", ErrInvalidMarginFilter, date)
+		}
+	}
+
+	if filter.StartDate != "" && filter.EndDate != "" && filter.StartDate > filter.EndDate {
+		return nil, fmt.Errorf("%w: startDate must not be after endDate", ErrInvalidMarginFilter)
+	}
+
+	return r.repo.ProductMargin(filter)
+}
+
+func (r *reportUseCase) FindOverview() (custom.StatsOverview, error) {
+	r.log.Info("Starting to retrive the stats overview in the usecase layer", nil)
+	return r.repo.Overview()
+}
+
 func NewReportUseCase(repo repository.ReportRepository, log *logger.Logger) ReportUseCase {
 	return &reportUseCase{repo: repo, log: log}
 }
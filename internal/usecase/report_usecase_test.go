@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"server-pulsa-app/internal/logger"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/shared/custom"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type reportUsecaseTestSuite struct {
+	suite.Suite
+	mockReportRepo *repositorymock.MockReportRepository
+	reportUseCase  ReportUseCase
+	log            logger.Logger
+}
+
+func (r *reportUsecaseTestSuite) SetupTest() {
+	r.mockReportRepo = new(repositorymock.MockReportRepository)
+	r.log = logger.NewLogger()
+	r.reportUseCase = NewReportUseCase(r.mockReportRepo, &r.log)
+}
+
+func (r *reportUsecaseTestSuite) TestFindProductMargin_Defaults() {
+	r.mockReportRepo.On("ProductMargin", custom.MarginFilter{SortBy: "margin", Limit: 50}).Return([]custom.ProductMarginResp{}, nil).Once()
+
+	_, err := r.reportUseCase.FindProductMargin(custom.MarginFilter{})
+
+	r.Nil(err)
+	r.mockReportRepo.AssertExpectations(r.T())
+}
+
+func (r *reportUsecaseTestSuite) TestFindProductMargin_InvalidFilter() {
+	_, err := r.reportUseCase.FindProductMargin(custom.MarginFilter{SortBy: "price"})
+	r.ErrorIs(err, ErrInvalidMarginFilter)
+
+	_, err = r.reportUseCase.FindProductMargin(custom.MarginFilter{StartDate: "2024-10-31", EndDate: "2024-10-01"})
+	r.ErrorIs(err, ErrInvalidMarginFilter)
+
+	r.mockReportRepo.AssertNotCalled(r.T(), "ProductMargin")
+}
+
+func (r *reportUsecaseTestSuite) TestFindOverview() {
+	expected := custom.StatsOverview{Products: 3, Merchants: 2, Users: 4, TransactionsToday: 1}
+	r.mockReportRepo.On("Overview").Return(expected, nil).Once()
+
+	overview, err := r.reportUseCase.FindOverview()
+
+	r.Nil(err)
+	r.Equal(expected, overview)
+	r.mockReportRepo.AssertExpectations(r.T())
+}
+
+func TestReportUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(reportUsecaseTestSuite))
+}
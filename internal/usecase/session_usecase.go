@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+)
+
+type SessionUseCase interface {
+	ListSessions(userId, currentSessionId string) ([]entity.Session, error)
+	RevokeSession(userId, sessionId string) error
+	RevokeUserSessions(userId, actorId string) (int64, error)
+}
+
+type sessionUseCase struct {
+	sessionRepo repository.SessionRepository
+	auditRepo   repository.AuditLogRepository
+	log         *logger.Logger
+}
+
+// ListSessions returns the active sessions of the user, the one of currentSessionId is marked as current.
+func (s *sessionUseCase) ListSessions(userId, currentSessionId string) ([]entity.Session, error) {
+	s.log.Info("Starting to retrieve the sessions in the usecase layer", nil)
+
+	sessions, err := s.sessionRepo.ListActive(userId)
+	if err != nil {
+		s.log.Error("Failed to retrieve the sessions: ", err)
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == currentSessionId
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs one of the user's own sessions out, a session of another user is not found.
+func (s *sessionUseCase) RevokeSession(userId, sessionId string) error {
+	s.log.Info("Starting to revoke a session in the usecase layer", nil)
+
+	// ids that are not uuids cannot match a session and would fail the query
+	if !uuidPattern.MatchString(sessionId) {
+		return repository.ErrSessionNotFound
+	}
+	if err := s.sessionRepo.Revoke(userId, sessionId); err != nil {
+		s.log.Error("Failed to revoke the session: ", err)
+		return err
+	}
+	return nil
+}
+
+// RevokeUserSessions lets an admin log every session of a compromised account out, it returns how many were open.
+func (s *sessionUseCase) RevokeUserSessions(userId, actorId string) (int64, error) {
+	s.log.Info("Starting to revoke every session of a user in the usecase layer", nil)
+
+	if !uuidPattern.MatchString(userId) {
+		return 0, ErrUserNotFound
+	}
+	revoked, err := s.sessionRepo.RevokeAll(userId)
+	if err != nil {
+		s.log.Error("Failed to revoke the sessions of the user: ", err)
+		return 0, err
+	}
+
+	// the sessions are already revoked at this point, a failed audit write is logged instead of failing the request
+	if err := s.auditRepo.Record(entity.AuditLog{ActorId: actorId, Action: entity.AuditActionUserSessions, TargetId: userId}); err != nil {
+		s.log.Error("Failed to record the session revocation audit log: ", err)
+	}
+	return revoked, nil
+}
+
+func NewSessionUseCase(sessionRepo repository.SessionRepository, auditRepo repository.AuditLogRepository, log *logger.Logger) SessionUseCase {
+	return &sessionUseCase{sessionRepo: sessionRepo, auditRepo: auditRepo, log: log}
+}
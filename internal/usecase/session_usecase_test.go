@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"errors"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testSessionId = "6c1f4c52-7a0e-4f8b-9d6e-2b3a1c0d9e8f"
+	testUserId    = "0b8f0ae4-1d5c-4a0e-9a39-3f0ad2f0c7d1"
+)
+
+type sessionUsecaseTestSuite struct {
+	suite.Suite
+	mockSessionRepo *repositorymock.MockSessionRepository
+	mockAuditRepo   *repositorymock.MockAuditLogRepository
+	sessionUseCase  SessionUseCase
+	log             logger.Logger
+}
+
+func (s *sessionUsecaseTestSuite) SetupTest() {
+	s.mockSessionRepo = new(repositorymock.MockSessionRepository)
+	s.mockAuditRepo = new(repositorymock.MockAuditLogRepository)
+	s.log = logger.NewLogger()
+	s.sessionUseCase = NewSessionUseCase(s.mockSessionRepo, s.mockAuditRepo, &s.log)
+}
+
+func (s *sessionUsecaseTestSuite) TestListSessions_MarksCurrent() {
+	s.mockSessionRepo.On("ListActive", testUserId).Return([]entity.Session{{Id: "uuid-other"}, {Id: testSessionId}}, nil).Once()
+
+	sessions, err := s.sessionUseCase.ListSessions(testUserId, testSessionId)
+
+	s.NoError(err)
+	s.False(sessions[0].Current)
+	s.True(sessions[1].Current)
+}
+
+func (s *sessionUsecaseTestSuite) TestRevokeSession() {
+	s.mockSessionRepo.On("Revoke", testUserId, testSessionId).Return(nil).Once()
+
+	s.NoError(s.sessionUseCase.RevokeSession(testUserId, testSessionId))
+	s.mockSessionRepo.AssertExpectations(s.T())
+}
+
+func (s *sessionUsecaseTestSuite) TestRevokeSession_InvalidId() {
+	err := s.sessionUseCase.RevokeSession(testUserId, "not-a-uuid")
+
+	s.ErrorIs(err, repository.ErrSessionNotFound)
+	s.mockSessionRepo.AssertNotCalled(s.T(), "Revoke", testUserId, "not-a-uuid")
+}
+
+func (s *sessionUsecaseTestSuite) TestRevokeUserSessions_RecordsAudit() {
+	s.mockSessionRepo.On("RevokeAll", testUserId).Return(int64(2), nil).Once()
+	s.mockAuditRepo.On("Record", entity.AuditLog{ActorId: "uuid-admin", Action: entity.AuditActionUserSessions, TargetId: testUserId}).Return(nil).Once()
+
+	revoked, err := s.sessionUseCase.RevokeUserSessions(testUserId, "uuid-admin")
+
+	s.NoError(err)
+	s.Equal(int64(2), revoked)
+	s.mockAuditRepo.AssertExpectations(s.T())
+}
+
+func (s *sessionUsecaseTestSuite) TestRevokeUserSessions_AuditFailureIsLogged() {
+	s.mockSessionRepo.On("RevokeAll", testUserId).Return(int64(1), nil).Once()
+	s.mockAuditRepo.On("Record", entity.AuditLog{ActorId: "uuid-admin", Action: entity.AuditActionUserSessions, TargetId: testUserId}).Return(errors.New("db error")).Once()
+
+	revoked, err := s.sessionUseCase.RevokeUserSessions(testUserId, "uuid-admin")
+
+	s.NoError(err)
+	s.Equal(int64(1), revoked)
+}
+
+func (s *sessionUsecaseTestSuite) TestRevokeUserSessions_InvalidUser() {
+	_, err := s.sessionUseCase.RevokeUserSessions("not-a-uuid", "uuid-admin")
+
+	s.ErrorIs(err, ErrUserNotFound)
+}
+
+func TestSessionUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(sessionUsecaseTestSuite))
+}
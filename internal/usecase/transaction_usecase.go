@@ -1,38 +1,128 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/repository"
 	"server-pulsa-app/internal/shared/custom"
+	"server-pulsa-app/internal/shared/service"
 )
 
 type transactionUseCase struct {
-	repo repository.TransactionRepository
-	log  *logger.Logger
+	repo    repository.TransactionRepository
+	metrics service.TransactionMetrics
+	log     *logger.Logger
 }
 
 type TransactionUseCase interface {
-	Create(payload entity.Transactions) (entity.Transactions, error)
-	GetAll(userId string) ([]custom.TransactionsReq, error)
-	GetById(id string) (custom.TransactionsReq, error)
+	// Create charges the transaction to the merchant, actorId is the caller, the detail lines with an override price
+	// are audited under it.
+	Create(ctx context.Context, payload entity.Transactions, actorId string) (entity.Transactions, error)
+	GetAll(ctx context.Context, filter custom.TransactionFilter) (custom.TransactionPage, error)
+	GetById(ctx context.Context, id string) (custom.TransactionsReq, error)
+	GetReceipt(ctx context.Context, id string) (custom.TransactionReceipt, error)
+	RefundDetail(ctx context.Context, transactionId, detailId string) error
+	Quote(ctx context.Context, payload entity.TransactionQuoteReq) (custom.TransactionQuote, error)
 }
 
-func NewTransactionUseCase(repo repository.TransactionRepository, log *logger.Logger) TransactionUseCase {
-	return &transactionUseCase{repo: repo, log: log}
+func NewTransactionUseCase(repo repository.TransactionRepository, metrics service.TransactionMetrics, log *logger.Logger) TransactionUseCase {
+	return &transactionUseCase{repo: repo, metrics: metrics, log: log}
 }
 
-func (u *transactionUseCase) Create(payload entity.Transactions) (entity.Transactions, error) {
-	u.log.Info("Starting to create a new transaction in the usecase layer", nil)
-	return u.repo.Create(payload)
+func (u *transactionUseCase) Create(ctx context.Context, payload entity.Transactions, actorId string) (entity.Transactions, error) {
+	u.log.WithContext(ctx).Info("Starting to create a new transaction in the usecase layer", nil)
+
+	transaction, err := u.repo.Create(ctx, payload, actorId)
+	if err != nil {
+		u.metrics.TransactionFailed()
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			u.metrics.InsufficientBalance()
+		}
+		return transaction, err
+	}
+
+	u.metrics.TransactionCreated()
+	return transaction, nil
+}
+
+// defaultTransactionPageSize is the page size of a cursor sent without a limit.
+const defaultTransactionPageSize = 20
+
+// GetAll lists the transactions of the user's merchants, newest first unless sort asks otherwise, only the ones with
+// status when it is set.
+func (u *transactionUseCase) GetAll(ctx context.Context, filter custom.TransactionFilter) (custom.TransactionPage, error) {
+	u.log.WithContext(ctx).Info("Starting to get all transactions in the usecase layer", nil)
+	if filter.Sort == "" {
+		filter.Sort = "date_desc"
+	}
+	if filter.Cursor != "" && filter.Limit <= 0 {
+		filter.Limit = defaultTransactionPageSize
+	}
+	return u.repo.GetAll(ctx, filter)
+}
+
+func (u *transactionUseCase) GetById(ctx context.Context, id string) (custom.TransactionsReq, error) {
+	u.log.WithContext(ctx).Info("Starting to get transaction by id in the usecase layer", nil)
+	return u.repo.GetById(ctx, id)
+}
+
+// GetReceipt reshapes a transaction into the flat receipt printed at the counter.
+func (u *transactionUseCase) GetReceipt(ctx context.Context, id string) (custom.TransactionReceipt, error) {
+	log := u.log.WithContext(ctx)
+	log.Info("Starting to build transaction receipt in the usecase layer", nil)
+	transaction, err := u.repo.GetById(ctx, id)
+	if err != nil {
+		log.Error("Failed to get transaction for receipt: ", err)
+		return custom.TransactionReceipt{}, err
+	}
+
+	receipt := custom.TransactionReceipt{
+		TransactionId:     transaction.TransactionsId,
+		MerchantId:        transaction.Merchant.IdMerchant,
+		MerchantName:      transaction.Merchant.NameMerchant,
+		MerchantAddress:   transaction.Merchant.Address,
+		Cashier:           transaction.User.Username,
+		CustomerName:      transaction.CustomerName,
+		DestinationNumber: transaction.DestinationNumber,
+		Date:              transaction.TransactionDate.Format("02-01-2006"),
+		Time:              transaction.TransactionDate.Format("15:04:05"),
+		Items:             make([]custom.ReceiptLine, 0, len(transaction.TransactionDetail)),
+	}
+	for _, detail := range transaction.TransactionDetail {
+		receipt.Items = append(receipt.Items, custom.ReceiptLine{
+			Product: detail.Product.NameProvider,
+			Nominal: detail.Product.Nominal,
+			Price:   detail.Product.Price,
+		})
+		receipt.GrandTotal += detail.Product.Price
+	}
+	return receipt, nil
 }
 
-func (u *transactionUseCase) GetAll(userId string) ([]custom.TransactionsReq, error) {
-	u.log.Info("Starting to get all transactions in the usecase layer", nil)
-	return u.repo.GetAll(userId)
+// RefundDetail refunds a single line of the transaction, a line of another transaction is reported as not found.
+func (u *transactionUseCase) RefundDetail(ctx context.Context, transactionId, detailId string) error {
+	log := u.log.WithContext(ctx)
+	log.Info("Starting to refund a transaction detail in the usecase layer", nil)
+	transaction, err := u.repo.GetById(ctx, transactionId)
+	if err != nil {
+		log.Error("Failed to get transaction for refund: ", err)
+		return err
+	}
+
+	for _, detail := range transaction.TransactionDetail {
+		if detail.TransactionDetailId == detailId {
+			return u.repo.RefundDetail(ctx, detailId)
+		}
+	}
+
+	log.Error("Transaction detail is not part of the transaction: ", detailId)
+	return repository.ErrTransactionDetailNotFound
 }
 
-func (u *transactionUseCase) GetById(id string) (custom.TransactionsReq, error) {
-	u.log.Info("Starting to get transaction by id in the usecase layer", nil)
-	return u.repo.GetById(id)
+// Quote tells the cashier what the transaction would cost before it is made.
+func (u *transactionUseCase) Quote(ctx context.Context, payload entity.TransactionQuoteReq) (custom.TransactionQuote, error) {
+	u.log.WithContext(ctx).Info("Starting to quote a transaction in the usecase layer", nil)
+	return u.repo.Quote(ctx, payload)
 }
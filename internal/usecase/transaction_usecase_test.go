@@ -1,9 +1,18 @@
 package usecase
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/mock/service_mock"
+	"server-pulsa-app/internal/repository"
 	"server-pulsa-app/internal/shared/custom"
 	"testing"
 	"time"
@@ -14,6 +23,7 @@ import (
 type transactionUsecaseTestSuite struct {
 	suite.Suite
 	mockTransactionRepo *repositorymock.MockTransactionRepository
+	mockMetrics         *service_mock.TransactionMetricsMock
 	transactionUseCase  TransactionUseCase
 	log                 logger.Logger
 }
@@ -21,7 +31,11 @@ type transactionUsecaseTestSuite struct {
 func (tx *transactionUsecaseTestSuite) SetupTest() {
 	tx.mockTransactionRepo = new(repositorymock.MockTransactionRepository)
 	tx.log = logger.NewLogger()
-	tx.transactionUseCase = NewTransactionUseCase(tx.mockTransactionRepo, &tx.log)
+	tx.mockMetrics = new(service_mock.TransactionMetricsMock)
+	tx.mockMetrics.On("TransactionCreated").Maybe()
+	tx.mockMetrics.On("TransactionFailed").Maybe()
+	tx.mockMetrics.On("InsufficientBalance").Maybe()
+	tx.transactionUseCase = NewTransactionUseCase(tx.mockTransactionRepo, tx.mockMetrics, &tx.log)
 }
 
 func (tx *transactionUsecaseTestSuite) TestCreate_Success() {
@@ -56,12 +70,66 @@ func (tx *transactionUsecaseTestSuite) TestCreate_Success() {
 		},
 	}
 
-	tx.mockTransactionRepo.On("Create", newTx).Return(CreatedTx, nil).Once()
+	tx.mockTransactionRepo.On("Create", newTx, "user-uuid").Return(CreatedTx, nil).Once()
 
-	transaction, err := tx.transactionUseCase.Create(newTx)
+	transaction, err := tx.transactionUseCase.Create(context.Background(), newTx, "user-uuid")
 
 	tx.Nil(err)
 	tx.Equal(CreatedTx, transaction)
+	tx.mockMetrics.AssertCalled(tx.T(), "TransactionCreated")
+	tx.mockMetrics.AssertNotCalled(tx.T(), "TransactionFailed")
+}
+
+func (tx *transactionUsecaseTestSuite) TestCreate_InsufficientBalanceCounted() {
+	newTx := entity.Transactions{MerchantId: "uuid-test", TransactionDetail: []entity.TransactionDetail{{ProductId: "uuid-test"}}}
+	tx.mockTransactionRepo.On("Create", newTx, "user-uuid").Return(entity.Transactions{}, fmt.Errorf("%w: required 10000, current balance 5000", repository.ErrInsufficientBalance)).Once()
+
+	_, err := tx.transactionUseCase.Create(context.Background(), newTx, "user-uuid")
+
+	tx.ErrorIs(err, repository.ErrInsufficientBalance)
+	tx.mockMetrics.AssertCalled(tx.T(), "TransactionFailed")
+	tx.mockMetrics.AssertCalled(tx.T(), "InsufficientBalance")
+	tx.mockMetrics.AssertNotCalled(tx.T(), "TransactionCreated")
+}
+
+func (tx *transactionUsecaseTestSuite) TestCreate_FailureCounted() {
+	newTx := entity.Transactions{MerchantId: "uuid-test", TransactionDetail: []entity.TransactionDetail{{ProductId: "uuid-test"}}}
+	tx.mockTransactionRepo.On("Create", newTx, "user-uuid").Return(entity.Transactions{}, repository.ErrMerchantNotFound).Once()
+
+	_, err := tx.transactionUseCase.Create(context.Background(), newTx, "user-uuid")
+
+	tx.ErrorIs(err, repository.ErrMerchantNotFound)
+	tx.mockMetrics.AssertCalled(tx.T(), "TransactionFailed")
+	tx.mockMetrics.AssertNotCalled(tx.T(), "InsufficientBalance")
+}
+
+func (tx *transactionUsecaseTestSuite) TestCreate_LogsTheRequestId() {
+	logFile := filepath.Join(tx.T().TempDir(), "usecase.log")
+	log := logger.NewLogger()
+	log.SetOutput(logFile)
+	transactionUseCase := NewTransactionUseCase(tx.mockTransactionRepo, tx.mockMetrics, &log)
+	newTx := entity.Transactions{MerchantId: "uuid-test", TransactionDetail: []entity.TransactionDetail{{ProductId: "uuid-test"}}}
+	tx.mockTransactionRepo.On("Create", newTx, "user-uuid").Return(entity.Transactions{TransactionsId: "uuid-test"}, nil).Once()
+
+	_, err := transactionUseCase.Create(logger.ContextWithRequestID(context.Background(), "req-123"), newTx, "user-uuid")
+	tx.NoError(err)
+
+	content, err := os.ReadFile(logFile)
+	tx.Require().NoError(err)
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		var entry map[string]any
+		if err := decoder.Decode(&entry); err == io.EOF {
+			tx.Fail("no usecase log line", string(content))
+			return
+		} else {
+			tx.Require().NoError(err)
+		}
+		if entry["msg"] == "Starting to create a new transaction in the usecase layer" {
+			tx.Equal("req-123", entry["requestId"])
+			return
+		}
+	}
 }
 
 func (tx *transactionUsecaseTestSuite) TestList_Success() {
@@ -129,10 +197,30 @@ func (tx *transactionUsecaseTestSuite) TestList_Success() {
 
 	tx.mockTransactionRepo.On("List").Return(transactions, nil).Once()
 
-	txList, err := tx.transactionUseCase.GetAll("")
+	txList, err := tx.transactionUseCase.GetAll(context.Background(), custom.TransactionFilter{})
 
 	tx.Nil(err)
-	tx.Equal(transactions, txList)
+	tx.Equal(transactions, txList.Transactions)
+}
+
+func (tx *transactionUsecaseTestSuite) TestGetAll_DefaultsToNewestFirst() {
+	tx.mockTransactionRepo.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_desc"}).
+		Return(custom.TransactionPage{}, nil).Once()
+
+	_, err := tx.transactionUseCase.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}})
+
+	tx.Nil(err)
+	tx.mockTransactionRepo.AssertExpectations(tx.T())
+}
+
+func (tx *transactionUsecaseTestSuite) TestGetAll_CursorWithoutLimitUsesDefaultPageSize() {
+	tx.mockTransactionRepo.On("GetAll", custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Sort: "date_desc", Limit: 20, Cursor: "next"}).
+		Return(custom.TransactionPage{}, nil).Once()
+
+	_, err := tx.transactionUseCase.GetAll(context.Background(), custom.TransactionFilter{MerchantIds: []string{"merchant-uuid"}, Cursor: "next"})
+
+	tx.Nil(err)
+	tx.mockTransactionRepo.AssertExpectations(tx.T())
 }
 
 func (tx *transactionUsecaseTestSuite) TestGetById_Success() {
@@ -172,12 +260,88 @@ func (tx *transactionUsecaseTestSuite) TestGetById_Success() {
 
 	tx.mockTransactionRepo.On("Get", id).Return(transaction, nil).Once()
 
-	txFound, err := tx.transactionUseCase.GetById(id)
+	txFound, err := tx.transactionUseCase.GetById(context.Background(), id)
 
 	tx.Nil(err)
 	tx.Equal(transaction, txFound)
 }
 
+func (tx *transactionUsecaseTestSuite) TestGetReceipt_Success() {
+	transaction := custom.TransactionsReq{
+		TransactionsId:    "uuid-test",
+		CustomerName:      "custtest",
+		DestinationNumber: "087654321",
+		User:              custom.UserRes{Id_user: "uuid-user", Username: "cashier"},
+		Merchant:          custom.MerchantRes{IdMerchant: "uuid-merchant", NameMerchant: "Konter Pak Eko", Address: "Jombang"},
+		TransactionDate:   time.Date(2024, 10, 25, 14, 5, 9, 0, time.UTC),
+		TransactionDetail: []custom.TransactionDetailReq{
+			{TransactionDetailId: "detail-1", Product: custom.ProductRes{NameProvider: "Telkomsel", Nominal: 10000, Price: 10900}},
+			{TransactionDetailId: "detail-2", Product: custom.ProductRes{NameProvider: "Indosat", Nominal: 5000, Price: 6000}},
+		},
+	}
+	tx.mockTransactionRepo.On("GetById", "uuid-test").Return(transaction, nil).Once()
+
+	receipt, err := tx.transactionUseCase.GetReceipt(context.Background(), "uuid-test")
+
+	tx.NoError(err)
+	tx.Equal("uuid-merchant", receipt.MerchantId)
+	tx.Equal("Konter Pak Eko", receipt.MerchantName)
+	tx.Equal("Jombang", receipt.MerchantAddress)
+	tx.Equal("cashier", receipt.Cashier)
+	tx.Equal("25-10-2024", receipt.Date)
+	tx.Equal("14:05:09", receipt.Time)
+	tx.Equal([]custom.ReceiptLine{
+		{Product: "Telkomsel", Nominal: 10000, Price: 10900},
+		{Product: "Indosat", Nominal: 5000, Price: 6000},
+	}, receipt.Items)
+	tx.Equal(float64(16900), receipt.GrandTotal)
+}
+
+func (tx *transactionUsecaseTestSuite) TestGetReceipt_NotFound() {
+	tx.mockTransactionRepo.On("GetById", "missing").Return(custom.TransactionsReq{}, repository.ErrTransactionNotFound).Once()
+
+	_, err := tx.transactionUseCase.GetReceipt(context.Background(), "missing")
+
+	tx.ErrorIs(err, repository.ErrTransactionNotFound)
+}
+
+func (tx *transactionUsecaseTestSuite) TestRefundDetail_PartialRefund() {
+	transaction := custom.TransactionsReq{
+		TransactionsId: "uuid-test",
+		TransactionDetail: []custom.TransactionDetailReq{
+			{TransactionDetailId: "detail-1"},
+			{TransactionDetailId: "detail-2"},
+		},
+	}
+	tx.mockTransactionRepo.On("GetById", "uuid-test").Return(transaction, nil).Once()
+	tx.mockTransactionRepo.On("RefundDetail", "detail-2").Return(nil).Once()
+
+	err := tx.transactionUseCase.RefundDetail(context.Background(), "uuid-test", "detail-2")
+
+	tx.NoError(err)
+	tx.mockTransactionRepo.AssertNotCalled(tx.T(), "RefundDetail", "detail-1")
+}
+
+func (tx *transactionUsecaseTestSuite) TestRefundDetail_AlreadyRefunded() {
+	transaction := custom.TransactionsReq{TransactionsId: "uuid-test", TransactionDetail: []custom.TransactionDetailReq{{TransactionDetailId: "detail-1"}}}
+	tx.mockTransactionRepo.On("GetById", "uuid-test").Return(transaction, nil).Once()
+	tx.mockTransactionRepo.On("RefundDetail", "detail-1").Return(repository.ErrDetailAlreadyRefunded).Once()
+
+	err := tx.transactionUseCase.RefundDetail(context.Background(), "uuid-test", "detail-1")
+
+	tx.ErrorIs(err, repository.ErrDetailAlreadyRefunded)
+}
+
+func (tx *transactionUsecaseTestSuite) TestRefundDetail_DetailOfAnotherTransaction() {
+	transaction := custom.TransactionsReq{TransactionsId: "uuid-test", TransactionDetail: []custom.TransactionDetailReq{{TransactionDetailId: "detail-1"}}}
+	tx.mockTransactionRepo.On("GetById", "uuid-test").Return(transaction, nil).Once()
+
+	err := tx.transactionUseCase.RefundDetail(context.Background(), "uuid-test", "detail-of-other-transaction")
+
+	tx.ErrorIs(err, repository.ErrTransactionDetailNotFound)
+	tx.mockTransactionRepo.AssertNotCalled(tx.T(), "RefundDetail", "detail-of-other-transaction")
+}
+
 func TestTransactionUsecaseTestSuite(t *testing.T) {
 	suite.Run(t, new(transactionUsecaseTestSuite))
 }
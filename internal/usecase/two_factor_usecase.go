@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/service"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTwoFactorCode is returned for a code that is neither the current TOTP code nor an unused backup code.
+var ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+const (
+	backupCodeCount = 10
+	// maxTwoFactorAttempts is how many codes can be tried with one login challenge before the password is needed again.
+	maxTwoFactorAttempts  = 5
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
+type TwoFactorUseCase interface {
+	Setup(userId string) (entity.TwoFactorSetup, error)
+	Enable(userId, code string) ([]string, error)
+}
+
+type twoFactorUseCase struct {
+	useCase       UserUsecase
+	twoFactorRepo repository.TwoFactorRepository
+	issuer        string
+	log           *logger.Logger
+}
+
+// Setup generates a new secret for the user. Until Enable confirms it, logins do not ask for a code and calling
+// Setup again replaces the secret.
+func (t *twoFactorUseCase) Setup(userId string) (entity.TwoFactorSetup, error) {
+	t.log.Info("Starting to set up two-factor authentication in the usecase layer", nil)
+
+	user, err := t.useCase.GetUserByID(userId)
+	if err != nil {
+		t.log.Error("Failed to retrieve the user: ", err)
+		return entity.TwoFactorSetup{}, err
+	}
+
+	secret, err := service.GenerateTotpSecret()
+	if err != nil {
+		t.log.Error("Failed to generate the totp secret: ", err)
+		return entity.TwoFactorSetup{}, err
+	}
+	if err := t.twoFactorRepo.SaveSecret(userId, secret); err != nil {
+		t.log.Error("Failed to save the totp secret: ", err)
+		return entity.TwoFactorSetup{}, err
+	}
+
+	return entity.TwoFactorSetup{Secret: secret, OtpauthURL: service.TotpURL(t.issuer, user.Username, secret)}, nil
+}
+
+// Enable turns two-factor authentication on once code shows the authenticator app has the secret. It returns the
+// backup codes, they are only stored hashed and cannot be shown again.
+func (t *twoFactorUseCase) Enable(userId, code string) ([]string, error) {
+	t.log.Info("Starting to enable two-factor authentication in the usecase layer", nil)
+
+	twoFactor, err := t.twoFactorRepo.Get(userId)
+	if err != nil {
+		t.log.Error("Failed to retrieve the totp secret: ", err)
+		return nil, err
+	}
+	if twoFactor.Enabled {
+		return nil, repository.ErrTwoFactorEnabled
+	}
+
+	step, ok := service.ValidateTotp(twoFactor.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		t.log.Error("Failed to enable two-factor authentication: ", ErrInvalidTwoFactorCode)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, backupCodeCount)
+	hashes := make([]string, backupCodeCount)
+	for i := range codes {
+		if codes[i], err = generateBackupCode(); err != nil {
+			t.log.Error("Failed to generate the backup codes: ", err)
+			return nil, err
+		}
+		hashes[i] = hashToken(normalizeBackupCode(codes[i]))
+	}
+
+	// the step of the confirming code counts as used, so it cannot also complete a login
+	if err := t.twoFactorRepo.Enable(userId, step, hashes); err != nil {
+		t.log.Error("Failed to enable two-factor authentication: ", err)
+		return nil, err
+	}
+
+	t.log.Info("Two-factor authentication has been enabled successfully", userId)
+	return codes, nil
+}
+
+// verifySecondFactor accepts the current TOTP code once, a code of an already used time step is refused so an
+// observed code cannot be replayed. Anything else is tried as a backup code, which is consumed by the check.
+func verifySecondFactor(repo repository.TwoFactorRepository, userId, code string, now time.Time) (bool, error) {
+	twoFactor, err := repo.Get(userId)
+	if err != nil {
+		return false, err
+	}
+	if !twoFactor.Enabled {
+		return false, nil
+	}
+
+	if step, ok := service.ValidateTotp(twoFactor.Secret, strings.TrimSpace(code), now); ok {
+		return repo.UseStep(userId, step)
+	}
+	return repo.UseBackupCode(userId, hashToken(normalizeBackupCode(code)))
+}
+
+// generateBackupCode returns ten hex digits grouped as xxxxx-xxxxx to be easier to type.
+func generateBackupCode() (string, error) {
+	raw := make([]byte, 5)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate backup code: %v", err)
+	}
+	code := hex.EncodeToString(raw)
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeBackupCode lets users type a backup code with or without the dash, in any case.
+func normalizeBackupCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+}
+
+func NewTwoFactorUseCase(uc UserUsecase, twoFactorRepo repository.TwoFactorRepository, issuer string, log *logger.Logger) TwoFactorUseCase {
+	return &twoFactorUseCase{useCase: uc, twoFactorRepo: twoFactorRepo, issuer: issuer, log: log}
+}
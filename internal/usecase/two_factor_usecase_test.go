@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"server-pulsa-app/internal/entity"
+	"server-pulsa-app/internal/logger"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/mock/usecase_mock"
+	"server-pulsa-app/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testTotpSecret = "JBSWY3DPEHPK3PXP"
+
+// currentTotpCode computes the code an authenticator app shows for the secret right now.
+func currentTotpCode(secret string) (string, int64) {
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	step := time.Now().Unix() / 30
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000), step
+}
+
+type twoFactorUsecaseTestSuite struct {
+	suite.Suite
+	mockUserUsecase   *usecase_mock.UserUseCaseMock
+	mockTwoFactorRepo *repositorymock.MockTwoFactorRepository
+	twoFactorUseCase  TwoFactorUseCase
+	log               logger.Logger
+}
+
+func (s *twoFactorUsecaseTestSuite) SetupTest() {
+	s.mockUserUsecase = new(usecase_mock.UserUseCaseMock)
+	s.mockTwoFactorRepo = new(repositorymock.MockTwoFactorRepository)
+	s.log = logger.NewLogger()
+	s.twoFactorUseCase = NewTwoFactorUseCase(s.mockUserUsecase, s.mockTwoFactorRepo, "Server Pulsa", &s.log)
+}
+
+func (s *twoFactorUsecaseTestSuite) TestSetup() {
+	s.mockUserUsecase.On("GetUserByID", testUserId).Return(entity.User{Id_user: testUserId, Username: "admin"}, nil).Once()
+	s.mockTwoFactorRepo.On("SaveSecret", testUserId, mock.AnythingOfType("string")).Return(nil).Once()
+
+	setup, err := s.twoFactorUseCase.Setup(testUserId)
+
+	s.NoError(err)
+	s.Len(setup.Secret, 32)
+	s.True(strings.HasPrefix(setup.OtpauthURL, "otpauth://totp/Server%20Pulsa:admin?"))
+	s.Contains(setup.OtpauthURL, "secret="+setup.Secret)
+	s.mockTwoFactorRepo.AssertCalled(s.T(), "SaveSecret", testUserId, setup.Secret)
+}
+
+func (s *twoFactorUsecaseTestSuite) TestSetup_AlreadyEnabled() {
+	s.mockUserUsecase.On("GetUserByID", testUserId).Return(entity.User{Id_user: testUserId, Username: "admin"}, nil).Once()
+	s.mockTwoFactorRepo.On("SaveSecret", testUserId, mock.AnythingOfType("string")).Return(repository.ErrTwoFactorEnabled).Once()
+
+	_, err := s.twoFactorUseCase.Setup(testUserId)
+
+	s.ErrorIs(err, repository.ErrTwoFactorEnabled)
+}
+
+func (s *twoFactorUsecaseTestSuite) TestEnable_ReturnsBackupCodes() {
+	code, step := currentTotpCode(testTotpSecret)
+	s.mockTwoFactorRepo.On("Get", testUserId).Return(entity.TwoFactor{Secret: testTotpSecret}, nil).Once()
+	s.mockTwoFactorRepo.On("Enable", testUserId, mock.AnythingOfType("int64"), mock.AnythingOfType("[]string")).Return(nil).Once()
+
+	codes, err := s.twoFactorUseCase.Enable(testUserId, code)
+
+	s.NoError(err)
+	s.Len(codes, backupCodeCount)
+	hashes := s.mockTwoFactorRepo.Calls[1].Arguments.Get(2).([]string)
+	for i, backupCode := range codes {
+		s.Regexp(`^[0-9a-f]{5}-[0-9a-f]{5}$`, backupCode)
+		s.Equal(hashToken(normalizeBackupCode(backupCode)), hashes[i])
+	}
+	// the confirming code counts as used
+	s.InDelta(step, s.mockTwoFactorRepo.Calls[1].Arguments.Get(1).(int64), 1)
+}
+
+func (s *twoFactorUsecaseTestSuite) TestEnable_InvalidCode() {
+	s.mockTwoFactorRepo.On("Get", testUserId).Return(entity.TwoFactor{Secret: testTotpSecret}, nil).Once()
+
+	_, err := s.twoFactorUseCase.Enable(testUserId, "not-a-code")
+
+	s.ErrorIs(err, ErrInvalidTwoFactorCode)
+	s.mockTwoFactorRepo.AssertNotCalled(s.T(), "Enable", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *twoFactorUsecaseTestSuite) TestEnable_AlreadyEnabled() {
+	s.mockTwoFactorRepo.On("Get", testUserId).Return(entity.TwoFactor{Secret: testTotpSecret, Enabled: true}, nil).Once()
+
+	_, err := s.twoFactorUseCase.Enable(testUserId, "123456")
+
+	s.ErrorIs(err, repository.ErrTwoFactorEnabled)
+}
+
+func (s *twoFactorUsecaseTestSuite) TestVerifySecondFactor_RefusesReplayedCode() {
+	code, _ := currentTotpCode(testTotpSecret)
+	s.mockTwoFactorRepo.On("Get", testUserId).Return(entity.TwoFactor{Secret: testTotpSecret, Enabled: true}, nil)
+	s.mockTwoFactorRepo.On("UseStep", testUserId, mock.AnythingOfType("int64")).Return(false, nil).Once()
+
+	ok, err := verifySecondFactor(s.mockTwoFactorRepo, testUserId, code, time.Now())
+
+	s.NoError(err)
+	s.False(ok)
+	// a valid totp code is never tried as a backup code
+	s.mockTwoFactorRepo.AssertNotCalled(s.T(), "UseBackupCode", mock.Anything, mock.Anything)
+}
+
+func (s *twoFactorUsecaseTestSuite) TestVerifySecondFactor_NotEnabled() {
+	s.mockTwoFactorRepo.On("Get", testUserId).Return(entity.TwoFactor{Secret: testTotpSecret}, nil)
+
+	ok, err := verifySecondFactor(s.mockTwoFactorRepo, testUserId, "abcde-12345", time.Now())
+
+	s.NoError(err)
+	s.False(ok)
+}
+
+func TestTwoFactorUsecaseTestSuite(t *testing.T) {
+	suite.Run(t, new(twoFactorUsecaseTestSuite))
+}
@@ -1,63 +1,222 @@
 package usecase
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/custom"
+	"server-pulsa-app/internal/shared/service"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCredentials hides whether the user exists or only the password was wrong.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrWeakPassword is matched by every WeakPasswordError.
+	ErrWeakPassword = errors.New("password is too weak")
+	// ErrUserDeactivated is returned by the password checks of a deleted user, only once the password matched so the
+	// message does not reveal deactivated accounts to someone guessing.
+	ErrUserDeactivated = errors.New("account has been deactivated, contact an admin to reactivate it")
+	// ErrInvalidRole is returned by CreateUser and ListUser when the role is not one of allowedRoles.
+	ErrInvalidRole = errors.New("role must be admin or employee")
+	// ErrUserNotFound is returned by GetProfile when the user was deleted after the token was issued.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrDeleteSelf is returned by DeleteUser when the admin targets their own account.
+	ErrDeleteSelf = errors.New("cannot delete yourself")
+	// ErrAdminRoleForbidden is returned by CreateUser and UpdateUser when a caller who is not an admin gives the admin
+	// role or changes an admin, user:write can be granted to other roles and must not be a way to become one.
+	ErrAdminRoleForbidden = errors.New("only an admin can give the admin role or change an admin")
+)
+
+// allowedRoles are the roles an admin can give a user, the public registration always gets defaultRole.
+var allowedRoles = entity.Roles
+
+const (
+	defaultRole = "employee"
+	adminRole   = "admin"
+)
+
+// normalizeUsername is applied on registration and login, so "Budi" and "budi" are the same account.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
 
-	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
 )
 
 type UserUsecase interface {
 	RegisterUser(user entity.User) (entity.User, error)
+	CreateUser(user entity.User, actorRole string) (entity.User, error)
+	OnboardMerchant(owner entity.User, merchant entity.Merchant) (entity.MerchantOnboarding, error)
 	GetUserByID(id string) (entity.User, error)
-	ListUser() ([]entity.User, error)
+	ListUser(filter custom.UserFilter) (custom.UserPage, error)
 	GetUserByUsername(username string) (entity.User, error)
+	GetUserByEmail(email string) (entity.User, error)
 	FindUserByUsernamePassword(username, password string) (entity.User, error)
-	UpdateUser(payload entity.User) (entity.User, error)
-	DeleteUser(id string) error
+	FindUserByEmailPassword(email, password string) (entity.User, error)
+	UpdateUser(payload entity.User, actorId, actorRole string) (entity.User, error)
+	DeleteUser(id, actorId string) error
+	ActivateUser(id string) error
+	ChangePassword(id, currentPassword, newPassword string, client entity.ClientInfo) error
+	AdminResetPassword(id, newPassword, actorId string) (string, error)
+	GetProfile(id string) (entity.UserProfile, error)
+	FindMerchantIds(id string) ([]string, error)
+	RecordLogin(id string, at time.Time)
+	ReportDuplicateUsernames() error
 }
 
 type userUsecase struct {
 	UserRepository repository.UserRepository
+	merchantRepo   repository.MerchantRepository
+	auditRepo      repository.AuditLogRepository
+	eventRepo      repository.AuthEventRepository
+	sessionRepo    repository.SessionRepository
+	policy         PasswordPolicy
+	hasher         service.PasswordHasher
 	log            *logger.Logger
 }
 
 func (u *userUsecase) RegisterUser(user entity.User) (entity.User, error) {
-	u.log.Info("Starting to create a new user in the usecase layer", nil)
+	u.log.Info("Starting to register a new user in the usecase layer", nil)
+
+	u.log.Info("Starting to set default role for new user", nil)
+	user.Role = defaultRole
+	return u.createUser(user)
+}
+
+// CreateUser lets an admin provision a user with any of allowedRoles, a caller of actorRole other than admin only
+// the roles below it. The caller chose the password, so the user must change it at the first login like after
+// AdminResetPassword.
+func (u *userUsecase) CreateUser(user entity.User, actorRole string) (entity.User, error) {
+	u.log.Info("Starting to create a new user with a role in the usecase layer", nil)
+
+	if err := u.checkRole(user.Role); err != nil {
+		return entity.User{}, err
+	}
+	if user.Role == adminRole && actorRole != adminRole {
+		return entity.User{}, ErrAdminRoleForbidden
+	}
+
+	user.MustChangePassword = true
+	return u.createUser(user)
+}
 
+// OnboardMerchant creates a user the way CreateUser does together with their merchant, in one db transaction so a
+// failure of either leaves neither.
+func (u *userUsecase) OnboardMerchant(owner entity.User, merchant entity.Merchant) (entity.MerchantOnboarding, error) {
+	u.log.Info("Starting to onboard a new merchant in the usecase layer", nil)
+
+	if err := u.checkRole(owner.Role); err != nil {
+		return entity.MerchantOnboarding{}, err
+	}
+
+	owner.MustChangePassword = true
+	owner, err := u.prepareUser(owner)
+	if err != nil {
+		return entity.MerchantOnboarding{}, err
+	}
+
+	owner, merchant, err = u.merchantRepo.Onboard(owner, merchant)
+	if err != nil {
+		return entity.MerchantOnboarding{}, err
+	}
+
+	return entity.MerchantOnboarding{
+		User:     entity.UserResponse{Id_user: owner.Id_user, Username: owner.Username, Role: owner.Role, Email: owner.Email},
+		Merchant: merchant,
+	}, nil
+}
+
+func (u *userUsecase) checkRole(role string) error {
+	for _, allowed := range allowedRoles {
+		if role == allowed {
+			return nil
+		}
+	}
+	u.log.Error("Invalid role for the new user: ", role)
+	return ErrInvalidRole
+}
+
+func (u *userUsecase) createUser(user entity.User) (entity.User, error) {
+	user, err := u.prepareUser(user)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	u.log.Info("Starting to create a new user in the repository layer", nil)
+	return u.UserRepository.CreateUser(user)
+}
+
+// prepareUser normalizes the username, refuses a taken one or a weak password and replaces the password with its
+// hash, ready to be stored.
+func (u *userUsecase) prepareUser(user entity.User) (entity.User, error) {
+	user.Username = normalizeUsername(user.Username)
+	// the lookup is case-insensitive, so usernames saved before the normalization still count as taken
 	existUser, _ := u.UserRepository.GetUserByUsername(user.Username)
 	u.log.Info("Starting to validate a new user", nil)
-	if existUser.Username == user.Username {
-		u.log.Error("Username already exist", existUser.Username)
-		return entity.User{}, fmt.Errorf("username already exist")
+	if strings.EqualFold(existUser.Username, user.Username) {
+		u.log.Error("Username is already taken: ", existUser.Username)
+		return entity.User{}, repository.ErrUsernameTaken
+	}
+
+	if err := u.policy.Validate(user.Username, user.Password); err != nil {
+		u.log.Error("Password of the new user is too weak: ", err)
+		return entity.User{}, err
 	}
 
-	u.log.Info("Starting to set default role for new user", nil)
-	user.Role = "employee"
 	u.log.Info("Starting to hash the password", nil)
-	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hash, err := u.hasher.Hash(user.Password)
 	if err != nil {
 		u.log.Error("Failed to hash password: ", err)
 		return entity.User{}, err
 	}
 
-	user.Password = string(hash)
-
-	u.log.Info("Starting to create a new user in the repository layer", nil)
-	return u.UserRepository.CreateUser(user)
+	user.Password = hash
+	return user, nil
 }
 
 func (u *userUsecase) GetUserByUsername(username string) (entity.User, error) {
 	u.log.Info("Starting to retrieve a user by username in the usecase layer", nil)
-	return u.UserRepository.GetUserByUsername(username)
+	return u.UserRepository.GetUserByUsername(normalizeUsername(username))
 }
 
-func (u *userUsecase) ListUser() ([]entity.User, error) {
-	logrus.Info("Starting to get list user in the usecase layer")
-	return u.UserRepository.ListUser()
+func (u *userUsecase) GetUserByEmail(email string) (entity.User, error) {
+	u.log.Info("Starting to retrieve a user by email in the usecase layer", nil)
+	return u.UserRepository.GetUserByEmail(email)
+}
+
+// ListUser pages the users like the audit log, a page below 1 is the first page and the size defaults to 20 and
+// is capped at 100.
+func (u *userUsecase) ListUser(filter custom.UserFilter) (custom.UserPage, error) {
+	u.log.Info("Starting to retrieve the user list in the usecase layer", nil)
+
+	if filter.Role != "" && !slices.Contains(allowedRoles, filter.Role) {
+		u.log.Error("Invalid role to filter the users on: ", filter.Role)
+		return custom.UserPage{}, ErrInvalidRole
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Size <= 0 {
+		filter.Size = defaultUserPageSize
+	}
+	if filter.Size > maxUserPageSize {
+		filter.Size = maxUserPageSize
+	}
+
+	users, total, err := u.UserRepository.ListUser(filter)
+	if err != nil {
+		u.log.Error("Failed to retrieve the user list: ", err)
+		return custom.UserPage{}, err
+	}
+	return custom.UserPage{Users: users, Page: filter.Page, Size: filter.Size, Total: total}, nil
 }
 
 func (u *userUsecase) GetUserByID(id string) (entity.User, error) {
@@ -68,68 +227,308 @@ func (u *userUsecase) GetUserByID(id string) (entity.User, error) {
 func (u *userUsecase) FindUserByUsernamePassword(username, password string) (entity.User, error) {
 	u.log.Info("Starting to authenticate a user in the usecase layer", nil)
 
-	userExist, err := u.UserRepository.GetUserByUsername(username)
+	userExist, err := u.UserRepository.GetUserByUsername(normalizeUsername(username))
+	return u.checkPassword(userExist, err, password)
+}
+
+func (u *userUsecase) FindUserByEmailPassword(email, password string) (entity.User, error) {
+	u.log.Info("Starting to authenticate a user by email in the usecase layer", nil)
+
+	userExist, err := u.UserRepository.GetUserByEmail(email)
+	return u.checkPassword(userExist, err, password)
+}
+
+// checkPassword finishes both lookups of a login, err is the error of the lookup. On a wrong password the user is
+// returned together with the error, so the failed attempt can be attributed to the account.
+func (u *userUsecase) checkPassword(userExist entity.User, err error, password string) (entity.User, error) {
 	if err != nil {
 		u.log.Error("User ID %s not found: %v", userExist.Id_user)
 		return entity.User{}, fmt.Errorf("user doesn't exists")
 	}
 
 	u.log.Info("Starting to validate password", nil)
-	err = bcrypt.CompareHashAndPassword([]byte(userExist.Password), []byte(password))
+	err = u.hasher.Compare(userExist.Password, password)
 	if err != nil {
 		u.log.Error("Password doesn't match", err)
-		return entity.User{}, fmt.Errorf("password doesn't match")
+		return entity.User{Id_user: userExist.Id_user, Username: userExist.Username}, fmt.Errorf("password doesn't match")
+	}
+
+	if !userExist.Active {
+		u.log.Error("Login refused, the user is deactivated: ", userExist.Id_user)
+		return entity.User{Id_user: userExist.Id_user, Username: userExist.Username}, ErrUserDeactivated
+	}
+
+	// the plain password is only known here, so hashes from a weaker cost or an older algorithm are upgraded on login
+	if u.hasher.NeedsRehash(userExist.Password) {
+		u.rehash(userExist, password)
 	}
 
 	u.log.Info("User ID %s has been authenticated successfully: ", userExist.Id_user)
 	return userExist, nil
 }
 
-func (u *userUsecase) UpdateUser(user entity.User) (entity.User, error) {
+// rehash failures are only logged, the login itself already succeeded and the next one tries again.
+func (u *userUsecase) rehash(user entity.User, password string) {
+	hash, err := u.hasher.Hash(password)
+	if err != nil {
+		u.log.Error("Failed to rehash the password: ", err)
+		return
+	}
+
+	if err := u.UserRepository.UpdatePassword(user.Id_user, hash); err != nil {
+		u.log.Error("Failed to store the rehashed password: ", err)
+		return
+	}
+
+	u.log.Info("Password hash has been upgraded for user ID: ", user.Id_user)
+}
+
+// UpdateUser saves the user as given, password included. A caller of actorRole other than admin can neither make the
+// user an admin nor change one, the password of an admin would be theirs to set otherwise.
+func (u *userUsecase) UpdateUser(user entity.User, actorId, actorRole string) (entity.User, error) {
 	u.log.Info("Starting to update a user in the usecase layer", nil)
 
-	_, err := u.UserRepository.GetUserByID(user.Id_user)
-	if err != nil {
+	existing, err := u.UserRepository.GetUserByID(user.Id_user)
+	if errors.Is(err, sql.ErrNoRows) {
 		u.log.Error("User ID %s not found: %v", user.Id_user)
-		return entity.User{}, fmt.Errorf("user ID %s not found", user.Id_user)
+		return entity.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		u.log.Error("Failed to retrieve the user to update: ", err)
+		return entity.User{}, err
 	}
+	if (user.Role == adminRole || existing.Role == adminRole) && actorRole != adminRole {
+		u.log.Error("Refused a change of an admin by a caller who is not one: ", map[string]string{"actor": actorId, "user": user.Id_user})
+		return entity.User{}, ErrAdminRoleForbidden
+	}
+	user.Username = normalizeUsername(user.Username)
 	u.log.Info("Starting to hash the password", nil)
-	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hash, err := u.hasher.Hash(user.Password)
 	if err != nil {
 		u.log.Error("Failed to hash password: ", err)
 		return entity.User{}, fmt.Errorf("failed to hash password: %v", err)
 	}
-	user.Password = string(hash)
+	user.Password = hash
 
 	updatedUser, err := u.UserRepository.UpdateUser(user)
 	if err != nil {
 		u.log.Error("Failed to update user: ", err)
-		return entity.User{}, fmt.Errorf("failed to update user: %v", err)
+		return entity.User{}, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// the change is already saved at this point, a failed audit write is logged instead of failing the request
+	if existing.Role != user.Role {
+		if err := u.auditRepo.Record(entity.AuditLog{ActorId: actorId, Action: entity.AuditActionUserRole, TargetId: user.Id_user}); err != nil {
+			u.log.Error("Failed to record the role change audit log: ", err)
+		}
 	}
 
 	u.log.Info("User ID %s has been updated successfully: ", user.Id_user)
 	return updatedUser, nil
 }
 
-func (u *userUsecase) DeleteUser(id string) error {
+// DeleteUser deactivates the user, the row is kept so the transactions of the user stay intact. Admins can't delete
+// themselves, and the repository refuses to delete the last admin.
+func (u *userUsecase) DeleteUser(id, actorId string) error {
 	u.log.Info("Starting to delete a user in the usecase layer", nil)
 
+	if id == actorId {
+		return ErrDeleteSelf
+	}
+
 	_, err := u.UserRepository.GetUserByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		u.log.Error("User ID %s not found: %v", id)
+		return ErrUserNotFound
+	}
+	if err != nil {
+		u.log.Error("Failed to retrieve the user to delete: ", err)
+		return err
+	}
+
+	if err := u.UserRepository.SoftDeleteUser(id); err != nil {
+		u.log.Error("Failed to soft delete user: ", err)
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	u.log.Info("User ID %s has been deactivated: ", id)
+	return nil
+}
+
+// RecordLogin stores the time of a successful login. It only logs failures, the login itself already succeeded.
+func (u *userUsecase) RecordLogin(id string, at time.Time) {
+	if err := u.UserRepository.UpdateLastLogin(id, at); err != nil {
+		u.log.Error("Failed to record the last login: ", err)
+	}
+}
+
+// ReportDuplicateUsernames logs every username that several users hold once the case is ignored, so operators can
+// rename or delete the extra accounts. Only one of them can log in, the lookups return the first match.
+func (u *userUsecase) ReportDuplicateUsernames() error {
+	u.log.Info("Starting to check for duplicate usernames in the usecase layer", nil)
+
+	duplicates, err := u.UserRepository.FindDuplicateUsernames()
+	if err != nil {
+		u.log.Error("Failed to check for duplicate usernames: ", err)
+		return err
+	}
+	for _, duplicate := range duplicates {
+		u.log.Error("Username is held by several users, resolve it by renaming or deleting the extra accounts: ",
+			map[string]any{"username": duplicate.Username, "id_users": duplicate.IdUsers})
+	}
+	return nil
+}
+
+// ActivateUser reinstates a deleted user, they can log in again right away.
+func (u *userUsecase) ActivateUser(id string) error {
+	u.log.Info("Starting to activate a user in the usecase layer", nil)
+
+	if err := u.UserRepository.ActivateUser(id); err != nil {
+		u.log.Error("Failed to activate user: ", err)
+		return err
+	}
+
+	u.log.Info("User ID %s has been activated: ", id)
+	return nil
+}
+
+func (u *userUsecase) ChangePassword(id, currentPassword, newPassword string, client entity.ClientInfo) error {
+	u.log.Info("Starting to change a user password in the usecase layer", nil)
+
+	user, err := u.UserRepository.GetUserByID(id)
 	if err != nil {
 		u.log.Error("User ID %s not found: %v", id)
-		return fmt.Errorf("user ID %s not found", id)
+		return ErrInvalidCredentials
 	}
 
-	err = u.UserRepository.DeleteUser(id)
+	if err := u.hasher.Compare(user.Password, currentPassword); err != nil {
+		u.log.Error("Current password doesn't match", id)
+		return ErrInvalidCredentials
+	}
+
+	if err := u.policy.Validate(user.Username, newPassword); err != nil {
+		return err
+	}
+
+	hash, err := u.hasher.Hash(newPassword)
 	if err != nil {
-		u.log.Error("Failed to delete user: ", err)
-		return fmt.Errorf("failed to delete user: %v", err)
+		u.log.Error("Failed to hash password: ", err)
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	// a password an admin reset is changed here, so the flag is cleared and the next token is not held to the change
+	if err := u.UserRepository.SetPassword(id, hash, false); err != nil {
+		u.log.Error("Failed to change the user password: ", err)
+		return fmt.Errorf("failed to change password: %w", err)
 	}
 
-	u.log.Info("User ID %s has been deleted successfully: ", id)
+	// every session ends with the old password, the one changing it included, so a stolen token or refresh token is
+	// not good for anything after the change
+	if _, err := u.sessionRepo.RevokeAll(id); err != nil {
+		u.log.Error("Failed to end the sessions after the password change: ", err)
+		return fmt.Errorf("failed to end the sessions: %w", err)
+	}
+
+	recordAuthEvent(u.eventRepo, u.log, entity.AuthEventPasswordChange, id, user.Username, client)
+	u.log.Info("User ID %s has changed the password successfully: ", id)
 	return nil
 }
 
-func NewUserUsecase(userRepository repository.UserRepository, log *logger.Logger) UserUsecase {
-	return &userUsecase{UserRepository: userRepository, log: log}
+// AdminResetPassword sets the password of a user who lost it, the user must change it at their next login. An empty
+// newPassword has a temporary one generated, it is returned so the admin can hand it over and is not stored anywhere.
+func (u *userUsecase) AdminResetPassword(id, newPassword, actorId string) (string, error) {
+	u.log.Info("Starting to reset a user password in the usecase layer", nil)
+
+	user, err := u.UserRepository.GetUserByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		u.log.Error("Failed to retrieve the user of the password reset: ", err)
+		return "", err
+	}
+
+	var temporary string
+	if newPassword == "" {
+		if temporary, err = u.policy.Generate(user.Username); err != nil {
+			u.log.Error("Failed to generate a temporary password: ", err)
+			return "", err
+		}
+		newPassword = temporary
+	} else if err := u.policy.Validate(user.Username, newPassword); err != nil {
+		return "", err
+	}
+
+	hash, err := u.hasher.Hash(newPassword)
+	if err != nil {
+		u.log.Error("Failed to hash password: ", err)
+		return "", fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	if err := u.UserRepository.SetPassword(id, hash, true); err != nil {
+		u.log.Error("Failed to reset the user password: ", err)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	// whoever holds a session of the user, a thief of the lost password included, is logged out by the reset
+	if _, err := u.sessionRepo.RevokeAll(id); err != nil {
+		u.log.Error("Failed to end the sessions after the password reset: ", err)
+		return "", fmt.Errorf("failed to end the sessions: %w", err)
+	}
+
+	// the password is already reset at this point, a failed audit write is logged instead of failing the request
+	if err := u.auditRepo.Record(entity.AuditLog{ActorId: actorId, Action: entity.AuditActionUserPassword, TargetId: id}); err != nil {
+		u.log.Error("Failed to record the password reset audit log: ", err)
+	}
+
+	u.log.Info("User ID %s has had the password reset by an admin: ", id)
+	return temporary, nil
+}
+
+func (u *userUsecase) GetProfile(id string) (entity.UserProfile, error) {
+	u.log.Info("Starting to retrieve the user profile in the usecase layer", nil)
+
+	user, err := u.UserRepository.GetUserByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.UserProfile{}, ErrUserNotFound
+	}
+	if err != nil {
+		u.log.Error("Failed to retrieve the user of the profile: ", err)
+		return entity.UserProfile{}, err
+	}
+
+	merchants, err := u.merchantRepo.ListByUser(id)
+	if err != nil {
+		u.log.Error("Failed to retrieve the merchants of the profile: ", err)
+		return entity.UserProfile{}, err
+	}
+
+	return entity.UserProfile{Id_user: user.Id_user, Username: user.Username, Role: user.Role, Email: user.Email, Merchants: merchants}, nil
+}
+
+// FindMerchantIds lists the ids of the merchants the user owns, they are embedded in the access token.
+func (u *userUsecase) FindMerchantIds(id string) ([]string, error) {
+	u.log.Info("Starting to retrieve the merchant ids of the user in the usecase layer", nil)
+
+	merchants, err := u.merchantRepo.ListByUser(id)
+	if err != nil {
+		u.log.Error("Failed to retrieve the merchants of the user: ", err)
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(merchants))
+	for _, merchant := range merchants {
+		ids = append(ids, merchant.IdMerchant)
+	}
+	return ids, nil
+}
+
+func NewUserUsecase(userRepository repository.UserRepository, merchantRepo repository.MerchantRepository, auditRepo repository.AuditLogRepository,
+	eventRepo repository.AuthEventRepository, sessionRepo repository.SessionRepository, policy PasswordPolicy, hasher service.PasswordHasher,
+	log *logger.Logger) UserUsecase {
+	return &userUsecase{UserRepository: userRepository, merchantRepo: merchantRepo, auditRepo: auditRepo, eventRepo: eventRepo,
+		sessionRepo: sessionRepo, policy: policy, hasher: hasher, log: log}
 }
@@ -1,9 +1,17 @@
 package usecase
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"server-pulsa-app/internal/entity"
 	"server-pulsa-app/internal/logger"
 	"server-pulsa-app/internal/mock/repo_mock"
+	repositorymock "server-pulsa-app/internal/mock/repository_mock"
+	"server-pulsa-app/internal/repository"
+	"server-pulsa-app/internal/shared/custom"
+	"server-pulsa-app/internal/shared/service"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -14,14 +22,22 @@ import (
 type userUsecaseTestSuite struct {
 	suite.Suite
 	mockUserRepository *repo_mock.UserRepoMock
+	mockMerchantRepo   *repo_mock.MerchantRepoMock
+	mockAuditRepo      *repositorymock.MockAuditLogRepository
+	mockEventRepo      *repositorymock.MockAuthEventRepository
+	mockSessionRepo    *repositorymock.MockSessionRepository
 	UserUseCase        UserUsecase
 	log                logger.Logger
 }
 
 func (u *userUsecaseTestSuite) SetupTest() {
 	u.mockUserRepository = new(repo_mock.UserRepoMock)
+	u.mockMerchantRepo = new(repo_mock.MerchantRepoMock)
+	u.mockAuditRepo = new(repositorymock.MockAuditLogRepository)
+	u.mockEventRepo = new(repositorymock.MockAuthEventRepository)
+	u.mockSessionRepo = new(repositorymock.MockSessionRepository)
 	u.log = logger.NewLogger()
-	u.UserUseCase = NewUserUsecase(u.mockUserRepository, &u.log)
+	u.UserUseCase = NewUserUsecase(u.mockUserRepository, u.mockMerchantRepo, u.mockAuditRepo, u.mockEventRepo, u.mockSessionRepo, DefaultPasswordPolicy(), service.NewBcryptHasher(bcrypt.DefaultCost), &u.log)
 }
 
 func (u *userUsecaseTestSuite) TestRegisterUser_Success() {
@@ -29,13 +45,15 @@ func (u *userUsecaseTestSuite) TestRegisterUser_Success() {
 	user := entity.User{
 		Id_user:  "1",
 		Username: username,
-		Password: "Test Password",
+		Password: "Test Password1",
 		Role:     "Test Role",
 	}
 
-	u.mockUserRepository.On("GetUserByUsername", username).Return(entity.User{}, nil).Once()
+	u.mockUserRepository.On("GetUserByUsername", "test user").Return(entity.User{}, nil).Once()
 
-	u.mockUserRepository.On("CreateUser", mock.Anything).Return(user, nil).Once()
+	u.mockUserRepository.On("CreateUser", mock.MatchedBy(func(created entity.User) bool {
+		return created.Username == "test user"
+	})).Return(user, nil).Once()
 
 	user, err := u.UserUseCase.RegisterUser(user)
 
@@ -43,28 +61,191 @@ func (u *userUsecaseTestSuite) TestRegisterUser_Success() {
 	u.Equal("1", user.Id_user)
 }
 
+func (u *userUsecaseTestSuite) TestRegisterUser_DuplicateIgnoringCase() {
+	first := entity.User{Username: "User", Password: "Test Password1"}
+	u.mockUserRepository.On("GetUserByUsername", "user").Return(entity.User{}, sql.ErrNoRows).Once()
+	u.mockUserRepository.On("CreateUser", mock.Anything).Return(entity.User{Id_user: "1", Username: "user"}, nil).Once()
+
+	_, err := u.UserUseCase.RegisterUser(first)
+	u.NoError(err)
+
+	// "USER" is looked up as "user", which finds the account, even one stored as "User" before the normalization
+	second := entity.User{Username: "USER", Password: "Other Password1"}
+	u.mockUserRepository.On("GetUserByUsername", "user").Return(entity.User{Id_user: "1", Username: "User"}, nil).Once()
+
+	_, err = u.UserUseCase.RegisterUser(second)
+	u.ErrorIs(err, repository.ErrUsernameTaken)
+	u.mockUserRepository.AssertNumberOfCalls(u.T(), "CreateUser", 1)
+}
+
+func (u *userUsecaseTestSuite) TestFindUserByUsernamePassword_NormalizesUsername() {
+	user := entity.User{Id_user: "1", Username: "budi", Password: hashPassword("Test Password1"), Active: true}
+	u.mockUserRepository.On("GetUserByUsername", "budi").Return(user, nil).Once()
+
+	found, err := u.UserUseCase.FindUserByUsernamePassword("  Budi ", "Test Password1")
+
+	u.NoError(err)
+	u.Equal("1", found.Id_user)
+}
+
+func (u *userUsecaseTestSuite) TestReportDuplicateUsernames() {
+	u.mockUserRepository.On("FindDuplicateUsernames").Return([]custom.DuplicateUsername{{Username: "budi", IdUsers: []string{"1", "2"}}}, nil).Once()
+
+	u.NoError(u.UserUseCase.ReportDuplicateUsernames())
+}
+
+func (u *userUsecaseTestSuite) TestReportDuplicateUsernames_Error() {
+	u.mockUserRepository.On("FindDuplicateUsernames").Return([]custom.DuplicateUsername(nil), errors.New("db error")).Once()
+
+	u.Error(u.UserUseCase.ReportDuplicateUsernames())
+}
+
+func (u *userUsecaseTestSuite) TestChangePassword_Success() {
+	user := entity.User{Id_user: "1", Username: "Test User", Password: hashPassword("old-pass1"), Role: "employee"}
+	u.mockUserRepository.On("GetUserByID", "1").Return(user, nil).Once()
+	u.mockUserRepository.On("SetPassword", "1", mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-pass1")) == nil
+	}), false).Return(nil).Once()
+	u.mockSessionRepo.On("RevokeAll", "1").Return(int64(2), nil).Once()
+	u.mockEventRepo.On("Record", entity.AuthEvent{UserId: "1", Event: entity.AuthEventPasswordChange, Identifier: "Test User", Ip: "10.0.0.1"}).Return(nil).Once()
+
+	err := u.UserUseCase.ChangePassword("1", "old-pass1", "new-pass1", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	u.NoError(err)
+	u.mockUserRepository.AssertExpectations(u.T())
+	u.mockSessionRepo.AssertExpectations(u.T())
+	u.mockEventRepo.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestChangePassword_SessionsNotEnded() {
+	user := entity.User{Id_user: "1", Username: "Test User", Password: hashPassword("old-pass1"), Role: "employee"}
+	u.mockUserRepository.On("GetUserByID", "1").Return(user, nil).Once()
+	u.mockUserRepository.On("SetPassword", "1", mock.AnythingOfType("string"), false).Return(nil).Once()
+	u.mockSessionRepo.On("RevokeAll", "1").Return(int64(0), errors.New("db error")).Once()
+
+	err := u.UserUseCase.ChangePassword("1", "old-pass1", "new-pass1", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	u.ErrorContains(err, "failed to end the sessions")
+	u.mockEventRepo.AssertNotCalled(u.T(), "Record", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestChangePassword_WrongCurrentPassword() {
+	user := entity.User{Id_user: "1", Password: hashPassword("old-pass1")}
+	u.mockUserRepository.On("GetUserByID", "1").Return(user, nil).Once()
+
+	err := u.UserUseCase.ChangePassword("1", "not-the-pass1", "new-pass1", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	u.ErrorIs(err, ErrInvalidCredentials)
+	u.mockUserRepository.AssertNotCalled(u.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything)
+	u.mockEventRepo.AssertNotCalled(u.T(), "Record", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestChangePassword_UnknownUserLooksLikeWrongPassword() {
+	u.mockUserRepository.On("GetUserByID", "missing").Return(entity.User{}, sql.ErrNoRows).Once()
+
+	err := u.UserUseCase.ChangePassword("missing", "old-pass1", "new-pass1", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	u.ErrorIs(err, ErrInvalidCredentials)
+}
+
+func (u *userUsecaseTestSuite) TestChangePassword_WeakPassword() {
+	user := entity.User{Id_user: "1", Password: hashPassword("old-pass1")}
+	u.mockUserRepository.On("GetUserByID", "1").Return(user, nil).Once()
+
+	err := u.UserUseCase.ChangePassword("1", "old-pass1", "short", entity.ClientInfo{Ip: "10.0.0.1"})
+
+	u.ErrorIs(err, ErrWeakPassword)
+	u.mockUserRepository.AssertNotCalled(u.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestAdminResetPassword_GeneratesTemporaryPassword() {
+	u.mockUserRepository.On("GetUserByID", "1").Return(entity.User{Id_user: "1", Username: "eko"}, nil).Once()
+	var storedHash string
+	u.mockUserRepository.On("SetPassword", "1", mock.AnythingOfType("string"), true).
+		Run(func(args mock.Arguments) { storedHash = args.String(1) }).Return(nil).Once()
+	u.mockSessionRepo.On("RevokeAll", "1").Return(int64(1), nil).Once()
+	u.mockAuditRepo.On("Record", entity.AuditLog{ActorId: "uuid-admin", Action: entity.AuditActionUserPassword, TargetId: "1"}).Return(nil).Once()
+
+	temporary, err := u.UserUseCase.AdminResetPassword("1", "", "uuid-admin")
+
+	u.NoError(err)
+	u.NoError(DefaultPasswordPolicy().Validate("eko", temporary))
+	u.NoError(bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(temporary)))
+	u.mockSessionRepo.AssertExpectations(u.T())
+	u.mockAuditRepo.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestAdminResetPassword_SessionsNotEnded() {
+	u.mockUserRepository.On("GetUserByID", "1").Return(entity.User{Id_user: "1", Username: "eko"}, nil).Once()
+	u.mockUserRepository.On("SetPassword", "1", mock.AnythingOfType("string"), true).Return(nil).Once()
+	u.mockSessionRepo.On("RevokeAll", "1").Return(int64(0), errors.New("db error")).Once()
+
+	_, err := u.UserUseCase.AdminResetPassword("1", "", "uuid-admin")
+
+	u.ErrorContains(err, "failed to end the sessions")
+}
+
+func (u *userUsecaseTestSuite) TestAdminResetPassword_ChosenPassword() {
+	u.mockUserRepository.On("GetUserByID", "1").Return(entity.User{Id_user: "1", Username: "eko"}, nil).Once()
+	u.mockUserRepository.On("SetPassword", "1", mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("n3wSecret123")) == nil
+	}), true).Return(nil).Once()
+	u.mockSessionRepo.On("RevokeAll", "1").Return(int64(0), nil).Once()
+	u.mockAuditRepo.On("Record", mock.Anything).Return(nil).Once()
+
+	temporary, err := u.UserUseCase.AdminResetPassword("1", "n3wSecret123", "uuid-admin")
+
+	u.NoError(err)
+	u.Empty(temporary, "a chosen password is not echoed back")
+	u.mockUserRepository.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestAdminResetPassword_WeakChosenPassword() {
+	u.mockUserRepository.On("GetUserByID", "1").Return(entity.User{Id_user: "1", Username: "eko"}, nil).Once()
+
+	_, err := u.UserUseCase.AdminResetPassword("1", "short", "uuid-admin")
+
+	u.ErrorIs(err, ErrWeakPassword)
+	u.mockUserRepository.AssertNotCalled(u.T(), "SetPassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestAdminResetPassword_UnknownUser() {
+	u.mockUserRepository.On("GetUserByID", "missing").Return(entity.User{}, fmt.Errorf("retrieve the user: %w", sql.ErrNoRows)).Once()
+
+	_, err := u.UserUseCase.AdminResetPassword("missing", "", "uuid-admin")
+
+	u.ErrorIs(err, ErrUserNotFound)
+}
+
 func (u *userUsecaseTestSuite) TestListAll_Success() {
-	user := []entity.User{
-		{
-			Id_user:  "1",
-			Username: "Test User",
-			Password: "Test Password",
-			Role:     "Test Role",
-		},
-		{
-			Id_user:  "2",
-			Username: "Test User",
-			Password: "Test Password",
-			Role:     "Test Role",
-		},
+	users := []custom.UserListItem{
+		{IdUser: "1", Username: "Test User", Role: "employee"},
+		{IdUser: "2", Username: "Test User", Role: "employee"},
 	}
+	filter := custom.UserFilter{Q: "test", Role: "employee"}
+
+	u.mockUserRepository.On("ListUser", custom.UserFilter{Page: 1, Size: 20, Q: "test", Role: "employee"}).Return(users, 42, nil).Once()
+
+	page, err := u.UserUseCase.ListUser(filter)
 
-	u.mockUserRepository.On("ListUser").Return(user, nil).Once()
+	u.Nil(err)
+	u.Equal(custom.UserPage{Users: users, Page: 1, Size: 20, Total: 42}, page)
+}
+
+func (u *userUsecaseTestSuite) TestListUser_CapsSize() {
+	u.mockUserRepository.On("ListUser", custom.UserFilter{Page: 3, Size: 100}).Return([]custom.UserListItem{}, 0, nil).Once()
 
-	userList, err := u.UserUseCase.ListUser()
+	page, err := u.UserUseCase.ListUser(custom.UserFilter{Page: 3, Size: 1000})
 
 	u.Nil(err)
-	u.Equal(user, userList)
+	u.Equal(100, page.Size)
+}
+
+func (u *userUsecaseTestSuite) TestListUser_InvalidRole() {
+	_, err := u.UserUseCase.ListUser(custom.UserFilter{Role: "root"})
+
+	u.ErrorIs(err, ErrInvalidRole)
+	u.mockUserRepository.AssertNotCalled(u.T(), "ListUser", mock.Anything)
 }
 
 func (u *userUsecaseTestSuite) TestGetUserById_Success() {
@@ -103,10 +284,38 @@ func (u *userUsecaseTestSuite) TestUpdateUser_Success() {
 
 	u.mockUserRepository.On("UpdateUser", mock.Anything).Return(updatedUser, nil).Once()
 
-	userUpdated, err := u.UserUseCase.UpdateUser(updatedUser)
+	userUpdated, err := u.UserUseCase.UpdateUser(updatedUser, "uuid-admin", "admin")
 
 	u.Nil(err)
 	u.Equal(updatedUser.Id_user, userUpdated.Id_user)
+	u.mockAuditRepo.AssertNotCalled(u.T(), "Record", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestUpdateUser_RoleChangeIsAudited() {
+	payload := entity.User{Id_user: "1", Username: "cashier", Password: "Test Password", Role: "admin"}
+
+	u.mockUserRepository.On("GetUserByID", "1").Return(entity.User{Id_user: "1", Username: "cashier", Role: "employee"}, nil).Once()
+	u.mockUserRepository.On("UpdateUser", mock.Anything).Return(payload, nil).Once()
+	u.mockAuditRepo.On("Record", entity.AuditLog{ActorId: "uuid-admin", Action: entity.AuditActionUserRole, TargetId: "1"}).Return(nil).Once()
+
+	_, err := u.UserUseCase.UpdateUser(payload, "uuid-admin", "admin")
+
+	u.Nil(err)
+	u.mockAuditRepo.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestUpdateUser_AdminRoleOnlyByAnAdmin() {
+	u.mockUserRepository.On("GetUserByID", "1").Return(entity.User{Id_user: "1", Username: "cashier", Role: "employee"}, nil).Once()
+	u.mockUserRepository.On("GetUserByID", "2").Return(entity.User{Id_user: "2", Username: "owner", Role: "admin"}, nil).Once()
+
+	_, err := u.UserUseCase.UpdateUser(entity.User{Id_user: "1", Username: "cashier", Password: "Test Password", Role: "admin"}, "uuid-supervisor", "supervisor")
+	u.ErrorIs(err, ErrAdminRoleForbidden)
+
+	_, err = u.UserUseCase.UpdateUser(entity.User{Id_user: "2", Username: "owner", Password: "Test Password", Role: "employee"}, "uuid-supervisor", "supervisor")
+	u.ErrorIs(err, ErrAdminRoleForbidden)
+
+	u.mockUserRepository.AssertNotCalled(u.T(), "UpdateUser", mock.Anything)
+	u.mockAuditRepo.AssertNotCalled(u.T(), "Record", mock.Anything)
 }
 
 func (u *userUsecaseTestSuite) TestDeleteUser_Success() {
@@ -117,13 +326,52 @@ func (u *userUsecaseTestSuite) TestDeleteUser_Success() {
 		Username: "Test User",
 		Password: hashPassword("Test Password"),
 		Role:     "Test Role",
+		Active:   true,
 	}, nil).Once()
 
-	u.mockUserRepository.On("DeleteUser", id).Return(nil).Once()
+	u.mockUserRepository.On("SoftDeleteUser", id).Return(nil).Once()
 
-	err := u.UserUseCase.DeleteUser(id)
+	err := u.UserUseCase.DeleteUser(id, "uuid-admin")
 
 	u.Nil(err)
+	u.mockUserRepository.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestDeleteUser_Self() {
+	err := u.UserUseCase.DeleteUser("uuid-admin", "uuid-admin")
+
+	u.ErrorIs(err, ErrDeleteSelf)
+	u.mockUserRepository.AssertNotCalled(u.T(), "SoftDeleteUser", "uuid-admin")
+}
+
+func (u *userUsecaseTestSuite) TestDeleteUser_LastAdmin() {
+	u.mockUserRepository.On("GetUserByID", "uuid-other-admin").Return(entity.User{Id_user: "uuid-other-admin", Role: "admin"}, nil).Once()
+	u.mockUserRepository.On("SoftDeleteUser", "uuid-other-admin").Return(repository.ErrLastAdmin).Once()
+
+	err := u.UserUseCase.DeleteUser("uuid-other-admin", "uuid-admin")
+
+	u.ErrorIs(err, repository.ErrLastAdmin)
+}
+
+func (u *userUsecaseTestSuite) TestDeleteUser_NotFound() {
+	u.mockUserRepository.On("GetUserByID", "1").Return(entity.User{}, sql.ErrNoRows).Once()
+
+	err := u.UserUseCase.DeleteUser("1", "uuid-admin")
+
+	u.NotNil(err)
+	u.mockUserRepository.AssertNotCalled(u.T(), "SoftDeleteUser", "1")
+}
+
+func (u *userUsecaseTestSuite) TestActivateUser() {
+	u.mockUserRepository.On("ActivateUser", "1").Return(nil).Once()
+
+	u.Nil(u.UserUseCase.ActivateUser("1"))
+}
+
+func (u *userUsecaseTestSuite) TestActivateUser_NotFound() {
+	u.mockUserRepository.On("ActivateUser", "1").Return(repository.ErrUserNotFound).Once()
+
+	u.ErrorIs(u.UserUseCase.ActivateUser("1"), repository.ErrUserNotFound)
 }
 
 func hashPassword(password string) string {
@@ -134,6 +382,183 @@ func hashPassword(password string) string {
 	return string(hashedPassword)
 }
 
+func (u *userUsecaseTestSuite) TestRegisterUser_IgnoresRequestedRole() {
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{}, sql.ErrNoRows).Once()
+	u.mockUserRepository.On("CreateUser", mock.MatchedBy(func(user entity.User) bool { return user.Role == "employee" && !user.MustChangePassword })).
+		Return(entity.User{Id_user: "1", Username: "eko", Role: "employee"}, nil).Once()
+
+	user, err := u.UserUseCase.RegisterUser(entity.User{Username: "eko", Password: "secret123", Role: "admin"})
+
+	u.NoError(err)
+	u.Equal("employee", user.Role)
+	u.mockUserRepository.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestCreateUser_WithRole() {
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{}, sql.ErrNoRows).Once()
+	u.mockUserRepository.On("CreateUser", mock.MatchedBy(func(user entity.User) bool {
+		return user.Role == "admin" && user.MustChangePassword && bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("secret123")) == nil
+	})).Return(entity.User{Id_user: "1", Username: "eko", Role: "admin"}, nil).Once()
+
+	user, err := u.UserUseCase.CreateUser(entity.User{Username: "eko", Password: "secret123", Role: "admin"}, "admin")
+
+	u.NoError(err)
+	u.Equal("admin", user.Role)
+	u.mockUserRepository.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestRegisterUser_WeakPassword() {
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{}, sql.ErrNoRows).Once()
+
+	_, err := u.UserUseCase.RegisterUser(entity.User{Username: "eko", Password: "123"})
+
+	u.ErrorIs(err, ErrWeakPassword)
+	u.mockUserRepository.AssertNotCalled(u.T(), "CreateUser", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestCreateUser_InvalidRole() {
+	_, err := u.UserUseCase.CreateUser(entity.User{Username: "eko", Password: "secret123", Role: "owner"}, "admin")
+
+	u.ErrorIs(err, ErrInvalidRole)
+	u.mockUserRepository.AssertNotCalled(u.T(), "CreateUser", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestCreateUser_AdminRoleOnlyByAnAdmin() {
+	_, err := u.UserUseCase.CreateUser(entity.User{Username: "eko", Password: "secret123", Role: "admin"}, "supervisor")
+
+	u.ErrorIs(err, ErrAdminRoleForbidden)
+	u.mockUserRepository.AssertNotCalled(u.T(), "CreateUser", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestOnboardMerchant() {
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{}, sql.ErrNoRows).Once()
+	merchant := entity.Merchant{NameMerchant: "Konter Pak Eko", Address: "Jombang", IdProduct: "uuid-product"}
+	u.mockMerchantRepo.On("Onboard", mock.MatchedBy(func(owner entity.User) bool {
+		return owner.Username == "eko" && owner.MustChangePassword && bcrypt.CompareHashAndPassword([]byte(owner.Password), []byte("secret123")) == nil
+	}), merchant).Return(entity.User{Id_user: "uuid-eko", Username: "eko", Password: "hash", Role: "employee"},
+		entity.Merchant{IdMerchant: "uuid-merchant", IdUser: "uuid-eko", NameMerchant: "Konter Pak Eko"}, nil).Once()
+
+	onboarding, err := u.UserUseCase.OnboardMerchant(entity.User{Username: " Eko ", Password: "secret123", Role: "employee"}, merchant)
+
+	u.NoError(err)
+	u.Equal(entity.UserResponse{Id_user: "uuid-eko", Username: "eko", Role: "employee"}, onboarding.User)
+	u.Equal("uuid-eko", onboarding.Merchant.IdUser)
+	u.mockMerchantRepo.AssertExpectations(u.T())
+	u.mockUserRepository.AssertNotCalled(u.T(), "CreateUser", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestOnboardMerchant_InvalidRole() {
+	_, err := u.UserUseCase.OnboardMerchant(entity.User{Username: "eko", Password: "secret123", Role: "owner"}, entity.Merchant{})
+
+	u.ErrorIs(err, ErrInvalidRole)
+	u.mockMerchantRepo.AssertNotCalled(u.T(), "Onboard", mock.Anything, mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestGetProfile_Success() {
+	u.mockUserRepository.On("GetUserByID", "uuid-user").Return(entity.User{Id_user: "uuid-user", Username: "eko", Password: "hash", Role: "employee"}, nil).Once()
+	merchants := []entity.Merchant{{IdMerchant: "uuid-merchant", IdUser: "uuid-user", NameMerchant: "Konter Pak Eko"}}
+	u.mockMerchantRepo.On("ListByUser", "uuid-user").Return(merchants, nil).Once()
+
+	profile, err := u.UserUseCase.GetProfile("uuid-user")
+
+	u.NoError(err)
+	u.Equal(entity.UserProfile{Id_user: "uuid-user", Username: "eko", Role: "employee", Merchants: merchants}, profile)
+}
+
+func (u *userUsecaseTestSuite) TestFindMerchantIds() {
+	merchants := []entity.Merchant{{IdMerchant: "uuid-merchant-a"}, {IdMerchant: "uuid-merchant-b"}}
+	u.mockMerchantRepo.On("ListByUser", "uuid-user").Return(merchants, nil).Once()
+
+	ids, err := u.UserUseCase.FindMerchantIds("uuid-user")
+
+	u.NoError(err)
+	u.Equal([]string{"uuid-merchant-a", "uuid-merchant-b"}, ids)
+}
+
+func (u *userUsecaseTestSuite) TestGetProfile_UserDeleted() {
+	u.mockUserRepository.On("GetUserByID", "uuid-user").Return(entity.User{}, sql.ErrNoRows).Once()
+
+	_, err := u.UserUseCase.GetProfile("uuid-user")
+
+	u.ErrorIs(err, ErrUserNotFound)
+	u.mockMerchantRepo.AssertNotCalled(u.T(), "ListByUser", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestFindUserByEmailPassword() {
+	user := entity.User{Id_user: "uuid-user", Username: "eko", Password: hashPassword("secret-pass1"), Email: "eko@example.com", Active: true}
+	u.mockUserRepository.On("GetUserByEmail", "EKO@example.com").Return(user, nil)
+
+	found, err := u.UserUseCase.FindUserByEmailPassword("EKO@example.com", "secret-pass1")
+	u.NoError(err)
+	u.Equal("uuid-user", found.Id_user)
+
+	_, err = u.UserUseCase.FindUserByEmailPassword("EKO@example.com", "wrong-pass1")
+	u.Error(err)
+	u.mockUserRepository.AssertNotCalled(u.T(), "GetUserByUsername", mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestFindUserByUsernamePassword_RehashesWeakerHash() {
+	weak, err := bcrypt.GenerateFromPassword([]byte("secret-pass1"), bcrypt.MinCost)
+	u.Require().NoError(err)
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{Id_user: "uuid-user", Username: "eko", Password: string(weak), Active: true}, nil)
+	u.mockUserRepository.On("UpdatePassword", "uuid-user", mock.MatchedBy(func(hash string) bool {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && cost == bcrypt.DefaultCost && bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret-pass1")) == nil
+	})).Return(nil).Once()
+
+	_, err = u.UserUseCase.FindUserByUsernamePassword("eko", "secret-pass1")
+
+	u.NoError(err)
+	u.mockUserRepository.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestFindUserByUsernamePassword_UpgradesToArgon2id() {
+	useCase := NewUserUsecase(u.mockUserRepository, u.mockMerchantRepo, u.mockAuditRepo, u.mockEventRepo, u.mockSessionRepo, DefaultPasswordPolicy(),
+		service.NewArgon2idHasher(service.Argon2idParams{Time: 1, Memory: 1024, Threads: 1}), &u.log)
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{Id_user: "uuid-user", Username: "eko", Password: hashPassword("secret-pass1"), Active: true}, nil)
+	u.mockUserRepository.On("UpdatePassword", "uuid-user", mock.MatchedBy(func(hash string) bool {
+		return strings.HasPrefix(hash, "$argon2id$")
+	})).Return(nil).Once()
+
+	_, err := useCase.FindUserByUsernamePassword("eko", "secret-pass1")
+
+	u.NoError(err)
+	u.mockUserRepository.AssertExpectations(u.T())
+}
+
+func (u *userUsecaseTestSuite) TestFindUserByUsernamePassword_CurrentHashIsKept() {
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{Id_user: "uuid-user", Username: "eko", Password: hashPassword("secret-pass1"), Active: true}, nil)
+
+	_, err := u.UserUseCase.FindUserByUsernamePassword("eko", "secret-pass1")
+
+	u.NoError(err)
+	u.mockUserRepository.AssertNotCalled(u.T(), "UpdatePassword", mock.Anything, mock.Anything)
+}
+
+func (u *userUsecaseTestSuite) TestFindUserByUsernamePassword_RehashFailureStillLogsIn() {
+	weak, err := bcrypt.GenerateFromPassword([]byte("secret-pass1"), bcrypt.MinCost)
+	u.Require().NoError(err)
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{Id_user: "uuid-user", Username: "eko", Password: string(weak), Active: true}, nil)
+	u.mockUserRepository.On("UpdatePassword", "uuid-user", mock.Anything).Return(sql.ErrConnDone).Once()
+
+	user, err := u.UserUseCase.FindUserByUsernamePassword("eko", "secret-pass1")
+
+	u.NoError(err)
+	u.Equal("uuid-user", user.Id_user)
+}
+
+func (u *userUsecaseTestSuite) TestFindUserByUsernamePassword_Deactivated() {
+	u.mockUserRepository.On("GetUserByUsername", "eko").Return(entity.User{Id_user: "uuid-user", Username: "eko", Password: hashPassword("secret-pass1")}, nil)
+
+	user, err := u.UserUseCase.FindUserByUsernamePassword("eko", "secret-pass1")
+	u.ErrorIs(err, ErrUserDeactivated)
+	u.Equal("uuid-user", user.Id_user)
+
+	// a wrong password does not reveal that the account exists but is deactivated
+	_, err = u.UserUseCase.FindUserByUsernamePassword("eko", "wrong-pass1")
+	u.NotErrorIs(err, ErrUserDeactivated)
+}
+
 func TestUserUsecaseTestSuite(t *testing.T) {
 	suite.Run(t, new(userUsecaseTestSuite))
 }
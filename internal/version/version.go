@@ -0,0 +1,14 @@
+// Package version holds what the running binary was built from. Release builds set the variables with -ldflags:
+//
+//	go build -ldflags "-X server-pulsa-app/internal/version.Version=1.4.0 \
+//		-X server-pulsa-app/internal/version.Commit=$(git rev-parse --short HEAD) \
+//		-X server-pulsa-app/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+// Version is the release, Commit the git commit and BuildTime when the binary was built, in RFC 3339. A plain
+// go build or go run leaves the defaults.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 	_ "server-pulsa-app/docs"
 	"server-pulsa-app/internal"
 )
@@ -18,6 +22,14 @@ import (
 // @BasePath /api/v1
 // @schemes http https
 func main() {
+	server, err := internal.NewServer()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "server not started:", err)
+		os.Exit(1)
+	}
 
-	internal.NewServer().Run()
+	server.Run()
 }